@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/apidiff"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type APIDiffArgs struct {
+	Base     string `json:"base,omitempty" jsonschema:"description=Git revision (branch or tag or commit) to compare against; defaults to HEAD"`
+	Snapshot string `json:"snapshot,omitempty" jsonschema:"description=Snapshot file written by scope export to compare against instead of a git revision"`
+	Package  string `json:"package,omitempty" jsonschema:"description=Only compare this package (import path or package name)" session:"package"`
+	ResponseBudget
+}
+
+// APIDiffResult is the response of api_diff
+type APIDiffResult struct {
+	Base string `json:"base"`
+	*apidiff.Report
+}
+
+func apiDiffHandler(ctx context.Context, args APIDiffArgs) (*mcp.ToolResponse, error) {
+	if args.Base != "" && args.Snapshot != "" {
+		return nil, fmt.Errorf("base and snapshot are mutually exclusive")
+	}
+	base := args.Base
+	if base == "" {
+		base = "HEAD"
+	}
+	if args.Snapshot != "" {
+		base = args.Snapshot
+	}
+	slog.InfoContext(ctx, "Diffing API", "base", base)
+
+	start := time.Now()
+	var old apidiff.API
+	var err error
+	if args.Snapshot != "" {
+		old, err = apidiff.FromSnapshot(args.Snapshot)
+	} else {
+		config := analyzerInstance.Config()
+		old, err = apidiff.AtRef(ctx, analyzerInstance.RepoPath(), base, &config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, err := apidiff.Current(ctx, analyzerInstance)
+	if err != nil {
+		return nil, err
+	}
+	report := apidiff.Compare(old.Filter(args.Package), current.Filter(args.Package))
+	metrics.AnalyzerDuration.ObserveDuration(start, "api_diff")
+
+	jsonData, err := json.Marshal(APIDiffResult{Base: base, Report: report})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal API diff: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
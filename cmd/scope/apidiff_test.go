@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIDiffHandler(t *testing.T) {
+	snap, err := analyzerInstance.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	snapshotPath := filepath.Join(t.TempDir(), "scope.snapshot")
+	if err := snap.WriteFile(snapshotPath); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	response, err := apiDiffHandler(context.Background(), APIDiffArgs{Snapshot: snapshotPath})
+	if err != nil {
+		t.Fatalf("apiDiffHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"breaking":[]`) {
+		t.Errorf("Expected no breaking changes against a snapshot of the same tree, got %s", text)
+	}
+
+	if _, err := apiDiffHandler(context.Background(), APIDiffArgs{Base: "HEAD", Snapshot: snapshotPath}); err == nil {
+		t.Error("Expected error when both base and snapshot are given")
+	}
+}
+
+func TestReleaseReportHandler(t *testing.T) {
+	if _, err := releaseReportHandler(context.Background(), ReleaseReportArgs{}); err == nil {
+		t.Error("Expected error without a from tag")
+	}
+	if _, err := releaseReportHandler(context.Background(), ReleaseReportArgs{From: "no-such-tag"}); err == nil {
+		t.Error("Expected error for an unknown tag")
+	}
+}
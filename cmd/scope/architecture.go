@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// ArchitectureConfig is the optional .scope/architecture.json file defining
+// components and private packages by hand
+type ArchitectureConfig struct {
+	Strategy   string              `json:"strategy,omitempty"`
+	Depth      int                 `json:"depth,omitempty"`
+	Threshold  float64             `json:"threshold,omitempty"`
+	Components map[string][]string `json:"components,omitempty"`
+	// Private lists the packages the boundaries tool keeps as private as
+	// internal ones
+	Private []analyzer.PrivateRule `json:"private,omitempty"`
+}
+
+// loadArchitectureConfig reads the repository's architecture config, if any
+func loadArchitectureConfig(repoPath string) (*ArchitectureConfig, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, ".scope", "architecture.json"))
+	if os.IsNotExist(err) {
+		return &ArchitectureConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read architecture config: %w", err)
+	}
+	var config ArchitectureConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse architecture config: %w", err)
+	}
+	return &config, nil
+}
+
+type GenerateArchitectureArgs struct {
+	Strategy string `json:"strategy,omitempty" jsonschema:"enum=directory,enum=cohesion,enum=config,description=How to cluster packages into components; defaults to config when .scope/architecture.json defines components and directory otherwise"`
+	Format   string `json:"format,omitempty" jsonschema:"enum=mermaid,enum=dot,enum=json,description=Output format (default mermaid)"`
+	Depth    int    `json:"depth,omitempty" jsonschema:"description=Leading directory elements naming a component in the directory strategy (default 2)"`
+	ResponseBudget
+}
+
+func generateArchitectureHandler(ctx context.Context, args GenerateArchitectureArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Generating architecture diagram", "strategy", args.Strategy, "format", args.Format)
+	config, err := loadArchitectureConfig(analyzerInstance.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := analyzer.ArchitectureOptions{
+		Strategy:   config.Strategy,
+		Depth:      config.Depth,
+		Threshold:  config.Threshold,
+		Components: config.Components,
+	}
+	if opts.Strategy == "" && len(opts.Components) > 0 {
+		opts.Strategy = analyzer.ClusterByConfig
+	}
+	if args.Strategy != "" {
+		opts.Strategy = args.Strategy
+	}
+	if args.Depth > 0 {
+		opts.Depth = args.Depth
+	}
+
+	start := time.Now()
+	arch, err := analyzerInstance.Architecture(ctx, opts)
+	metrics.AnalyzerDuration.ObserveDuration(start, "architecture")
+	if err != nil {
+		return nil, err
+	}
+
+	switch args.Format {
+	case "", "mermaid":
+		return mcp.NewToolResponse(mcp.NewTextContent(arch.Mermaid())), nil
+	case "dot":
+		return mcp.NewToolResponse(mcp.NewTextContent(arch.DOT())), nil
+	case "json":
+		jsonData, err := json.Marshal(arch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal architecture: %w", err)
+		}
+		return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", args.Format)
+	}
+}
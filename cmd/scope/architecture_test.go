@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestGenerateArchitectureHandler(t *testing.T) {
+	response, err := generateArchitectureHandler(context.Background(), GenerateArchitectureArgs{})
+	if err != nil {
+		t.Fatalf("generateArchitectureHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.HasPrefix(text, "flowchart LR") {
+		t.Errorf("Expected Mermaid output, got %s", text)
+	}
+
+	response, err = generateArchitectureHandler(context.Background(), GenerateArchitectureArgs{Format: "json"})
+	if err != nil {
+		t.Fatalf("generateArchitectureHandler failed: %v", err)
+	}
+	var arch analyzer.Architecture
+	if err := json.Unmarshal([]byte(responseText(t, response)), &arch); err != nil {
+		t.Fatalf("Failed to decode architecture: %v", err)
+	}
+	if arch.Strategy != analyzer.ClusterByDirectory || len(arch.Components) != 1 {
+		t.Errorf("Unexpected architecture: %+v", arch)
+	}
+
+	if _, err := generateArchitectureHandler(context.Background(), GenerateArchitectureArgs{Format: "svg"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := generateArchitectureHandler(context.Background(), GenerateArchitectureArgs{Strategy: "config"}); err == nil {
+		t.Error("Expected error for config strategy without components")
+	}
+}
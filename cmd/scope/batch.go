@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TFMV/scope/internal/jobs"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// maxBatchRequests bounds the tool calls of one batch
+const maxBatchRequests = 100
+
+// batchConcurrency is how many tool calls of a batch run at once
+const batchConcurrency = 8
+
+// batchTools are the tools batch can call by name, taking their arguments
+// as JSON. instrument adds every tool it wraps.
+var batchTools = make(map[string]func(context.Context, []byte) (*mcp.ToolResponse, error))
+
+type BatchRequest struct {
+	Tool      string         `json:"tool" jsonschema:"required,description=Name of the tool to call"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"description=Arguments of the tool as it takes them when called on its own"`
+}
+
+type BatchArgs struct {
+	Requests []BatchRequest `json:"requests" jsonschema:"required,description=Tool calls to run concurrently; results come back in the same order"`
+	ResponseBudget
+}
+
+// BatchResult is the response of batch
+type BatchResult struct {
+	Results []BatchItem `json:"results"`
+}
+
+// BatchItem is the outcome of one tool call of a batch. Result holds a JSON
+// response as is and Text any other response.
+type BatchItem struct {
+	Tool   string          `json:"tool"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func batchHandler(ctx context.Context, args BatchArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Running batch", "calls", len(args.Requests))
+	if len(args.Requests) == 0 {
+		return nil, fmt.Errorf("requests are required")
+	}
+	if len(args.Requests) > maxBatchRequests {
+		return nil, fmt.Errorf("a batch holds at most %d requests, got %d", maxBatchRequests, len(args.Requests))
+	}
+
+	result := BatchResult{Results: make([]BatchItem, len(args.Requests))}
+	var done atomic.Int64
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, request := range args.Requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.Results[i] = BatchItem{Tool: request.Tool, Error: ctx.Err().Error()}
+				return
+			}
+			result.Results[i] = runBatchRequest(ctx, request)
+			// Batches run as jobs report each finished call
+			jobs.ReportProgress(ctx, int(done.Add(1)), len(args.Requests), "ran "+request.Tool)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// runBatchRequest calls one tool of a batch
+func runBatchRequest(ctx context.Context, request BatchRequest) BatchItem {
+	item := BatchItem{Tool: request.Tool}
+	if request.Tool == "batch" {
+		item.Error = fmt.Sprintf("unknown tool %s", request.Tool)
+		return item
+	}
+	var arguments []byte
+	if request.Arguments != nil {
+		var err error
+		if arguments, err = json.Marshal(request.Arguments); err != nil {
+			item.Error = fmt.Sprintf("invalid arguments for %s: %v", request.Tool, err)
+			return item
+		}
+	}
+
+	texts, err := callTool(ctx, request.Tool, arguments)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	text := strings.Join(texts, "\n")
+	if len(texts) == 1 && json.Valid([]byte(text)) {
+		item.Result = json.RawMessage(text)
+	} else {
+		item.Text = text
+	}
+	return item
+}
+
+// callTool calls a tool by name with its arguments as JSON and returns the
+// texts of its response
+func callTool(ctx context.Context, tool string, arguments []byte) ([]string, error) {
+	call, ok := batchTools[tool]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %s", tool)
+	}
+	response, err := call(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+	var texts []string
+	if response != nil {
+		for _, content := range response.Content {
+			if content != nil && content.TextContent != nil {
+				texts = append(texts, content.TextContent.Text)
+			}
+		}
+	}
+	return texts, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatchHandler(t *testing.T) {
+	batch := instrument("batch", batchHandler)
+	instrument("lookup_type", lookupTypeHandler)
+	instrument("list_methods", listMethodsHandler)
+
+	requests := []BatchRequest{
+		{Tool: "lookup_type", Arguments: map[string]any{"type_name": "TestStruct"}},
+		{Tool: "list_methods", Arguments: map[string]any{"type_name": "TestStruct"}},
+		{Tool: "lookup_type", Arguments: map[string]any{"type_name": "DoesNotExist"}},
+		{Tool: "missing"},
+		{Tool: "batch"},
+		{Tool: "lookup_type", Arguments: map[string]any{"type_name": 42}},
+	}
+	response, err := batch(context.Background(), BatchArgs{Requests: requests})
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	var result BatchResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to decode batch result: %v", err)
+	}
+	if len(result.Results) != len(requests) {
+		t.Fatalf("Expected %d results, got %d", len(requests), len(result.Results))
+	}
+	for i, item := range result.Results {
+		if item.Tool != requests[i].Tool {
+			t.Errorf("Expected result %d for %s, got %s", i, requests[i].Tool, item.Tool)
+		}
+	}
+
+	var info struct{ Name string }
+	if err := json.Unmarshal(result.Results[0].Result, &info); err != nil || info.Name != "TestStruct" {
+		t.Errorf("Expected TestStruct, got %s (%v)", result.Results[0].Result, err)
+	}
+	var methods []struct{ Name string }
+	if err := json.Unmarshal(result.Results[1].Result, &methods); err != nil || len(methods) != 1 || methods[0].Name != "TestMethod" {
+		t.Errorf("Expected TestMethod, got %s (%v)", result.Results[1].Result, err)
+	}
+	if result.Results[2].Error == "" || result.Results[2].Result != nil {
+		t.Errorf("Expected an error for an unknown type, got %+v", result.Results[2])
+	}
+	for _, i := range []int{3, 4} {
+		if !strings.Contains(result.Results[i].Error, "unknown tool") {
+			t.Errorf("Expected %s to be refused, got %+v", requests[i].Tool, result.Results[i])
+		}
+	}
+	if !strings.Contains(result.Results[5].Error, "invalid arguments") {
+		t.Errorf("Expected invalid arguments to be reported, got %+v", result.Results[5])
+	}
+
+	if _, err := batch(context.Background(), BatchArgs{}); err == nil {
+		t.Error("Expected an error for an empty batch")
+	}
+	if _, err := batch(context.Background(), BatchArgs{Requests: make([]BatchRequest, maxBatchRequests+1)}); err == nil {
+		t.Error("Expected an error for too many requests")
+	}
+}
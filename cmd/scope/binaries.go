@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type BinariesArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only describe this main package (import path); omit for every binary" session:"package"`
+	ResponseBudget
+}
+
+func binariesHandler(ctx context.Context, args BinariesArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing binaries", "package", args.Package)
+	start := time.Now()
+	report, err := analyzerInstance.Binaries(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "binaries")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal binaries: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBinariesHandler(t *testing.T) {
+	// The test package is a library
+	response, err := binariesHandler(context.Background(), BinariesArgs{})
+	if err != nil {
+		t.Fatalf("binariesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != `{"binaries":[],"shared":[]}` {
+		t.Errorf("Expected no binaries, got %s", text)
+	}
+
+	if _, err := binariesHandler(context.Background(), BinariesArgs{Package: "cmd/missing"}); err == nil {
+		t.Error("Expected an unknown package to fail")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type BoundariesArgs struct {
+	Package string   `json:"package,omitempty" jsonschema:"description=Only check the imports of this package (import path or package name); omit for all packages" session:"package"`
+	Private []string `json:"private,omitempty" jsonschema:"description=Packages to treat as private besides those of .scope/architecture.json; directories or import paths with /... for trees; only packages matching the same pattern may import them"`
+	Moves   []string `json:"moves,omitempty" jsonschema:"description=Planned moves as from=to directories or import paths; e.g. internal/cache=pkg/cache; to report the imports that would break"`
+	ResponseBudget
+}
+
+func boundariesHandler(ctx context.Context, args BoundariesArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Checking package boundaries", "package", args.Package, "moves", args.Moves)
+	config, err := loadArchitectureConfig(analyzerInstance.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := analyzer.BoundaryOptions{Package: args.Package, Private: config.Private}
+	for _, pattern := range args.Private {
+		opts.Private = append(opts.Private, analyzer.PrivateRule{Packages: pattern})
+	}
+	if len(args.Moves) > 0 {
+		opts.Moves = make(map[string]string, len(args.Moves))
+		for _, move := range args.Moves {
+			from, to, ok := strings.Cut(move, "=")
+			if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+				return nil, fmt.Errorf("invalid move %q (expected from=to)", move)
+			}
+			opts.Moves[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+
+	start := time.Now()
+	report, err := analyzerInstance.Boundaries(ctx, opts)
+	metrics.AnalyzerDuration.ObserveDuration(start, "boundaries")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal boundary report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
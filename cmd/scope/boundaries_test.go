@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestBoundariesHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                   "module example.com/shop\n\ngo 1.21\n",
+		".scope/architecture.json": `{"private": [{"packages": "legacy/...", "reason": "being removed"}]}`,
+		"legacy/prices/prices.go":  "package prices\n",
+		"cart/internal/tax/tax.go": "package tax\n",
+		"cart/cart.go":             "package cart\n\nimport _ \"example.com/shop/cart/internal/tax\"\n",
+		"checkout/checkout.go":     "package checkout\n\nimport _ \"example.com/shop/legacy/prices\"\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory of %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	ctx := context.Background()
+
+	response, err := boundariesHandler(ctx, BoundariesArgs{Moves: []string{"cart/internal/tax = checkout/internal/tax"}})
+	if err != nil {
+		t.Fatalf("boundariesHandler failed: %v", err)
+	}
+	var report analyzer.BoundaryReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, v := range report.Violations {
+		statuses[v.Importer] = v.Status
+	}
+	// The configured legacy rule applies, and moving tax under checkout
+	// breaks its import from cart
+	if len(report.Violations) != 2 || statuses["example.com/shop/checkout"] != analyzer.BoundaryCompiles ||
+		statuses["example.com/shop/cart"] != analyzer.BoundaryBreaksOnMove {
+		t.Errorf("Unexpected violations %+v", report.Violations)
+	}
+
+	if _, err := boundariesHandler(ctx, BoundariesArgs{Moves: []string{"cart"}}); err == nil {
+		t.Error("Expected a move without a destination to fail")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// BuildReport is the result of check_build
+type BuildReport struct {
+	// OK is true when the packages compile and vet found nothing
+	OK       bool `json:"ok"`
+	Errors   int  `json:"errors"`
+	Warnings int  `json:"warnings"`
+	// VetSkipped is set when vet did not run because the build failed
+	VetSkipped  bool                `json:"vet_skipped,omitempty"`
+	Diagnostics []checks.Diagnostic `json:"diagnostics"`
+}
+
+type CheckBuildArgs struct {
+	Packages string `json:"packages,omitempty" jsonschema:"description=Space-separated package patterns relative to the repository (default ./...)"`
+	SkipVet  bool   `json:"skip_vet,omitempty" jsonschema:"description=Only compile; do not run go vet"`
+	ResponseBudget
+}
+
+func checkBuildHandler(ctx context.Context, args CheckBuildArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Checking build", "packages", args.Packages)
+	start := time.Now()
+	report, err := checkBuild(ctx, analyzerInstance.RepoPath(), strings.Fields(args.Packages), !args.SkipVet)
+	metrics.AnalyzerDuration.ObserveDuration(start, "check_build")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal build report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// checkBuild compiles pkgs in dir and, once they compile, vets them
+func checkBuild(ctx context.Context, dir string, pkgs []string, vet bool) (*BuildReport, error) {
+	diagnostics, err := checks.Build(ctx, dir, pkgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BuildReport{}
+	// Vet repeats every compile error, so it only runs on code that builds
+	if len(diagnostics) > 0 {
+		report.VetSkipped = vet
+	} else if vet {
+		diagnostics, err = checks.Vet(ctx, dir, pkgs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	checks.Sort(diagnostics)
+	report.Diagnostics = append([]checks.Diagnostic{}, diagnostics...)
+	for _, d := range diagnostics {
+		if d.Severity == checks.SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.OK = len(diagnostics) == 0
+	return report, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckBuild(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	write("go.mod", "module example.com/app\n\ngo 1.21\n")
+	write("main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Printf(\"%d\\n\", \"x\")\n}\n")
+
+	report, err := checkBuild(context.Background(), dir, nil, true)
+	if err != nil {
+		t.Fatalf("checkBuild failed: %v", err)
+	}
+	if report.OK || report.Warnings != 1 || report.Errors != 0 || report.Diagnostics[0].Analyzer != "printf" {
+		t.Errorf("Expected one printf warning, got %+v", report)
+	}
+
+	write("main.go", "package main\n\nfunc main() {\n\tundefinedFunc()\n}\n")
+	report, err = checkBuild(context.Background(), dir, nil, true)
+	if err != nil {
+		t.Fatalf("checkBuild failed: %v", err)
+	}
+	if report.OK || report.Errors != 1 || !report.VetSkipped || report.Diagnostics[0].Line != 4 {
+		t.Errorf("Expected one compile error with vet skipped, got %+v", report)
+	}
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	report, err = checkBuild(context.Background(), dir, []string{"."}, true)
+	if err != nil {
+		t.Fatalf("checkBuild failed: %v", err)
+	}
+	if !report.OK || len(report.Diagnostics) != 0 {
+		t.Errorf("Expected a clean build, got %+v", report)
+	}
+}
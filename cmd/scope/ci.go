@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/hooks"
+)
+
+// Exit codes returned by `scope ci`
+const (
+	ciExitClean    = 0
+	ciExitFindings = 1
+	ciExitFailure  = 2
+)
+
+// runCI implements `scope ci`: it runs the configured checks and reports the
+// findings in a format CI systems understand, exiting non-zero on findings
+func runCI(args []string) int {
+	fs := flag.NewFlagSet("ci", flag.ContinueOnError)
+	repo := fs.String("repo", os.Getenv("GO_REPO_PATH"), "repository to check (defaults to GO_REPO_PATH or the current directory)")
+	checkList := fs.String("checks", "format,build_check,lint,test", "comma-separated checks to run")
+	format := fs.String("format", defaultCIFormat(), "output format: text, github, or gitlab")
+	output := fs.String("output", "", "file to write the report to (defaults to stdout)")
+	base := fs.String("base", "", "git ref to compare against; when set only files changed since it and their impacted packages are checked")
+	failOn := fs.String("fail-on", "warning", "lowest severity that fails the run: error, warning, or never")
+	notifyConfig := fs.String("notify", "", "notification config file (defaults to .scope/notify.json in the repository)")
+	if err := fs.Parse(args); err != nil {
+		return ciExitFailure
+	}
+
+	repoPath := *repo
+	if repoPath == "" {
+		repoPath = "."
+	}
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return ciExitFailure
+	}
+
+	ctx := context.Background()
+	target := checks.Target{Dir: repoPath, Base: *base}
+	if *base != "" {
+		files, err := hooks.FilesChangedSince(ctx, repoPath, *base)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list changed files: %v\n", err)
+			return ciExitFailure
+		}
+		if target, err = hooks.NewTarget(ctx, repoPath, files); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to determine impacted packages: %v\n", err)
+			return ciExitFailure
+		}
+		target.Base = *base
+		if len(target.Files) == 0 {
+			fmt.Fprintf(os.Stderr, "No Go files changed since %s\n", *base)
+			return ciExitClean
+		}
+	}
+
+	diagnostics, err := checks.Run(ctx, target, splitList(*checkList)...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scope ci: %v\n", err)
+		return ciExitFailure
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create report file: %v\n", err)
+			return ciExitFailure
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := checks.WriteReport(w, *format, repoPath, diagnostics); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return ciExitFailure
+	}
+
+	// Notification problems are reported but never change the outcome of the run
+	notifier, err := loadDispatcher(repoPath, *notifyConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure notifications: %v\n", err)
+	} else if notifier != nil {
+		if _, err := notifier.Dispatch(ctx, "ci", repoPath, diagnostics); err != nil {
+			fmt.Fprintf(os.Stderr, "scope ci: %v\n", err)
+		}
+	}
+
+	return ciExitCode(diagnostics, *failOn)
+}
+
+// ciExitCode decides the exit code from the diagnostics and the -fail-on threshold
+func ciExitCode(diagnostics []checks.Diagnostic, failOn string) int {
+	for _, d := range diagnostics {
+		switch failOn {
+		case "never":
+			return ciExitClean
+		case "error":
+			if d.Severity == checks.SeverityError {
+				return ciExitFindings
+			}
+		default:
+			return ciExitFindings
+		}
+	}
+	return ciExitClean
+}
+
+// defaultCIFormat picks the annotation format for the CI system we run in
+func defaultCIFormat() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return checks.FormatGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return checks.FormatGitLab
+	default:
+		return checks.FormatText
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TFMV/scope/internal/checks"
+)
+
+func TestCIExitCode(t *testing.T) {
+	warning := checks.Diagnostic{Message: "w", Check: "lint", Severity: checks.SeverityWarning}
+	failure := checks.Diagnostic{Message: "e", Check: "build", Severity: checks.SeverityError}
+
+	tests := []struct {
+		name        string
+		diagnostics []checks.Diagnostic
+		failOn      string
+		want        int
+	}{
+		{"clean", nil, "warning", ciExitClean},
+		{"warning fails by default", []checks.Diagnostic{warning}, "warning", ciExitFindings},
+		{"warning ignored when failing on errors", []checks.Diagnostic{warning}, "error", ciExitClean},
+		{"error fails when failing on errors", []checks.Diagnostic{warning, failure}, "error", ciExitFindings},
+		{"never fails", []checks.Diagnostic{failure}, "never", ciExitClean},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ciExitCode(tt.diagnostics, tt.failOn); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" format, lint,,test ")
+	if len(got) != 3 || got[0] != "format" || got[1] != "lint" || got[2] != "test" {
+		t.Errorf("Unexpected split: %v", got)
+	}
+}
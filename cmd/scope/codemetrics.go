@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type CodeMetricsArgs struct {
+	Package          string `json:"package,omitempty" jsonschema:"description=Only report this package (import path or package name); omit for all packages" session:"package"`
+	ExcludeGenerated bool   `json:"exclude_generated,omitempty" jsonschema:"description=Leave generated files (Code generated headers; .pb.go and _gen.go files) out of the metrics; packages made only of them are not reported"`
+	ResponseBudget
+}
+
+// CodeMetricsResult is the response of code_metrics
+type CodeMetricsResult struct {
+	Packages []analyzer.PackageMetrics `json:"packages"`
+	// AverageDistance is the mean distance of the packages from the main
+	// sequence
+	AverageDistance float64 `json:"average_distance"`
+	// Zones counts the packages in each zone
+	Zones map[string]int `json:"zones,omitempty"`
+}
+
+func codeMetricsHandler(ctx context.Context, args CodeMetricsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Computing code metrics", "package", args.Package, "exclude_generated", args.ExcludeGenerated)
+	start := time.Now()
+	packages, err := analyzerInstance.PackageMetrics(ctx, args.Package, args.ExcludeGenerated)
+	metrics.AnalyzerDuration.ObserveDuration(start, "code_metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	result := CodeMetricsResult{Packages: packages}
+	var distance float64
+	for _, pkg := range packages {
+		distance += pkg.Distance
+		if pkg.Zone != "" {
+			if result.Zones == nil {
+				result.Zones = make(map[string]int)
+			}
+			result.Zones[pkg.Zone]++
+		}
+	}
+	if len(packages) > 0 {
+		result.AverageDistance = math.Round(distance/float64(len(packages))*100) / 100
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code metrics: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestCodeMetricsHandler(t *testing.T) {
+	response, err := codeMetricsHandler(context.Background(), CodeMetricsArgs{})
+	if err != nil {
+		t.Fatalf("codeMetricsHandler failed: %v", err)
+	}
+	var result CodeMetricsResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to decode code metrics: %v", err)
+	}
+	if len(result.Packages) != 1 || result.Packages[0].Declarations != 2 || result.Packages[0].ExportedTypes != 1 {
+		t.Errorf("Expected the metrics of the test package, got %+v", result.Packages)
+	}
+
+	if _, err := codeMetricsHandler(context.Background(), CodeMetricsArgs{Package: "nosuchpkg"}); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
+
+func TestCodeMetricsHandlerExcludeGenerated(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":      "module example.com/gen\n\ngo 1.21\n",
+		"types.go":    "package gen\n\ntype Message struct{}\n",
+		"types.pb.go": "package gen\n\ntype MessageProto struct{}\n\nfunc (m *MessageProto) Reset() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	generated, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer generated.Close()
+	previous := analyzerInstance
+	analyzerInstance = generated
+	defer func() { analyzerInstance = previous }()
+
+	for _, tc := range []struct {
+		exclude      bool
+		declarations int
+	}{{false, 2}, {true, 1}} {
+		response, err := codeMetricsHandler(context.Background(), CodeMetricsArgs{ExcludeGenerated: tc.exclude})
+		if err != nil {
+			t.Fatalf("codeMetricsHandler failed: %v", err)
+		}
+		var result CodeMetricsResult
+		if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+			t.Fatalf("Failed to decode code metrics: %v", err)
+		}
+		if len(result.Packages) != 1 || result.Packages[0].Declarations != tc.declarations {
+			t.Errorf("Expected %d declarations with exclude_generated=%v, got %+v", tc.declarations, tc.exclude, result.Packages)
+		}
+	}
+}
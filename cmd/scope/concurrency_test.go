@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConcurrencyReportHandler(t *testing.T) {
+	// The test package starts no goroutines
+	response, err := concurrencyReportHandler(context.Background(), ConcurrencyReportArgs{})
+	if err != nil {
+		t.Fatalf("concurrencyReportHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != `{"packages":[],"issues":0}` {
+		t.Errorf("Expected an empty report, got %s", text)
+	}
+
+	if _, err := concurrencyReportHandler(context.Background(), ConcurrencyReportArgs{Package: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown package")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type GenerateConstructorArgs struct {
+	Type     string   `json:"type" jsonschema:"required,description=Struct to generate the constructor for (Name or pkg.Name)"`
+	Style    string   `json:"style,omitempty" jsonschema:"enum=params,enum=options,enum=config,description=How fields are passed: params takes every field as a parameter; options sets optional fields with functional options; config takes them in a config struct (default params)"`
+	Required []string `json:"required,omitempty" jsonschema:"description=Fields passed as parameters; defaults to every field for params and none otherwise"`
+	Name     string   `json:"name,omitempty" jsonschema:"description=Name of the constructor; defaults to New or NewX following the package's conventions"`
+	Write    bool     `json:"write,omitempty" jsonschema:"description=Append the declarations to the file declaring the struct"`
+	DryRun   bool     `json:"dry_run,omitempty" jsonschema:"description=Only return the diff of writing the declarations"`
+	ResponseBudget
+}
+
+// GenerateConstructorResult is the generated constructor and, when
+// written, the edit of its file
+type GenerateConstructorResult struct {
+	*analyzer.Constructor
+	Edit *edit.Result `json:"edit,omitempty"`
+}
+
+func generateConstructorHandler(ctx context.Context, args GenerateConstructorArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Generating constructor", "type", args.Type, "style", args.Style, "required", args.Required, "write", args.Write)
+	start := time.Now()
+	ctor, err := analyzerInstance.GenerateConstructor(ctx, args.Type, analyzer.ConstructorOptions{
+		Style:    args.Style,
+		Required: args.Required,
+		Name:     args.Name,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "generate_constructor")
+	if err != nil {
+		return nil, err
+	}
+
+	result := GenerateConstructorResult{Constructor: ctor}
+	if args.Write || args.DryRun {
+		if result.Edit, err = writeConstructor(ctor, args.DryRun); err != nil {
+			return nil, err
+		}
+		if result.Edit.Applied {
+			refreshAfterWrite(ctx, "constructor generation")
+		}
+	}
+	ctor.File = relPath(analyzerInstance.RepoPath(), ctor.File)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal constructor: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// writeConstructor appends a generated constructor, with the imports it
+// needs, to the file declaring its struct
+func writeConstructor(ctor *analyzer.Constructor, dryRun bool) (*edit.Result, error) {
+	names := make([]string, 0, len(ctor.Imports))
+	for name := range ctor.Imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var edits []edit.Edit
+	for _, name := range names {
+		importEdit := edit.Edit{Op: edit.AddImport, Path: ctor.Imports[name]}
+		if name != path.Base(ctor.Imports[name]) {
+			importEdit.Name = name
+		}
+		edits = append(edits, importEdit)
+	}
+	edits = append(edits, edit.Edit{Op: edit.AddDecl, Code: ctor.Code})
+
+	if err := checkWrite("generate_constructor", []string{ctor.File}, dryRun, edits); err != nil {
+		return nil, err
+	}
+	result, err := edit.Apply(ctor.File, edits, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	result.File = relPath(analyzerInstance.RepoPath(), result.File)
+	return result, nil
+}
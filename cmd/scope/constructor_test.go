@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestGenerateConstructorHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/shop\n\ngo 1.21\n",
+		"server/server.go": "package server\n\nimport \"time\"\n\ntype Server struct {\n\taddr    string\n\ttimeout time.Duration\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	args := GenerateConstructorArgs{Type: "Server", Style: "options", Required: []string{"addr"}, DryRun: true}
+	response, err := generateConstructorHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("generateConstructorHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"name":"New"`) || !strings.Contains(text, `"file":"server/server.go"`) || !strings.Contains(text, `"applied":false`) {
+		t.Errorf("Expected a dry run of New in server/server.go, got %s", text)
+	}
+
+	args.DryRun, args.Write = false, true
+	if _, err := generateConstructorHandler(context.Background(), args); err != nil {
+		t.Fatalf("generateConstructorHandler failed: %v", err)
+	}
+	expected := `package server
+
+import "time"
+
+type Server struct {
+	addr    string
+	timeout time.Duration
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithTimeout sets the timeout of a Server
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// New creates a Server
+func New(addr string, opts ...Option) *Server {
+	s := &Server{
+		addr: addr,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+`
+	if data, err := os.ReadFile(filepath.Join(dir, "server", "server.go")); err != nil || string(data) != expected {
+		t.Errorf("Expected server.go:\n%s\ngot (%v):\n%s", expected, err, data)
+	}
+
+	// The analysis sees the written declarations: New is taken and the
+	// option type and its function are reused
+	args.Write = false
+	response, err = generateConstructorHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("generateConstructorHandler failed: %v", err)
+	}
+	text = responseText(t, response)
+	if !strings.Contains(text, `"name":"NewServer"`) || strings.Contains(text, "type Option func") || strings.Contains(text, "func WithTimeout") {
+		t.Errorf("Expected NewServer reusing the written options, got %s", text)
+	}
+}
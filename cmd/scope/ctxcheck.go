@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ContextCheckArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only check this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func contextCheckHandler(ctx context.Context, args ContextCheckArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Checking context usage", "package", args.Package)
+	start := time.Now()
+	report, err := analyzerInstance.ContextCheck(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "ctx_check")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal context report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
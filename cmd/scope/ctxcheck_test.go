@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextCheckHandler(t *testing.T) {
+	response, err := contextCheckHandler(context.Background(), ContextCheckArgs{})
+	if err != nil {
+		t.Fatalf("contextCheckHandler failed: %v", err)
+	}
+	// The test package does not use contexts
+	if text := responseText(t, response); text != `{"issues":[],"rules":{}}` {
+		t.Errorf("Expected no issues, got %s", text)
+	}
+
+	if _, err := contextCheckHandler(context.Background(), ContextCheckArgs{Package: "nosuchpkg"}); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type FindDeadConfigArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Package name or import path to analyze; omit to analyze every package" session:"package"`
+	ResponseBudget
+}
+
+func findDeadConfigHandler(ctx context.Context, args FindDeadConfigArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Finding dead configuration", "package", args.Package)
+	start := time.Now()
+	report, err := analyzerInstance.DeadConfig(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "dead_config")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dead configuration report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestFindDeadConfigHandler(t *testing.T) {
+	response, err := findDeadConfigHandler(context.Background(), FindDeadConfigArgs{Package: "testpkg"})
+	if err != nil {
+		t.Fatalf("findDeadConfigHandler failed: %v", err)
+	}
+	var report analyzer.DeadConfigReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Branches == nil || len(report.Branches) != 0 || len(report.Settings) != 0 {
+		t.Errorf("Expected an empty report for testpkg, got %+v", report)
+	}
+
+	if _, err := findDeadConfigHandler(context.Background(), FindDeadConfigArgs{Package: "nonexistent"}); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
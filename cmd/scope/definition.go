@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type GoToDefinitionArgs struct {
+	Position string `json:"position" jsonschema:"required,description=Position of the identifier as file:line:column; the file is relative to the repository or absolute and the column counts bytes from 1"`
+	ResponseBudget
+}
+
+func goToDefinitionHandler(ctx context.Context, args GoToDefinitionArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Going to definition", "position", args.Position)
+	file, line, column, err := parsePosition(args.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	def, err := analyzerInstance.Definition(ctx, file, line, column)
+	metrics.AnalyzerDuration.ObserveDuration(start, "definition")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal definition: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// parsePosition splits a file:line:column position. The file is split off
+// at the last two colons, so it may contain colons itself.
+func parsePosition(position string) (string, int, int, error) {
+	rest, col, ok := cutLast(position, ":")
+	if !ok {
+		return "", 0, 0, fmt.Errorf("invalid position %q: expected file:line:column", position)
+	}
+	file, ln, ok := cutLast(rest, ":")
+	if !ok || file == "" {
+		return "", 0, 0, fmt.Errorf("invalid position %q: expected file:line:column", position)
+	}
+	line, err := strconv.Atoi(ln)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid line in position %q: %w", position, err)
+	}
+	column, err := strconv.Atoi(col)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid column in position %q: %w", position, err)
+	}
+	return file, line, column, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	file, line, column, err := parsePosition(`C:\repo\main.go:12:5`)
+	if err != nil || file != `C:\repo\main.go` || line != 12 || column != 5 {
+		t.Errorf("Expected C:\\repo\\main.go 12 5, got %s %d %d (%v)", file, line, column, err)
+	}
+	for _, position := range []string{"main.go", "main.go:12", ":1:2", "main.go:x:1", "main.go:1:y"} {
+		if _, _, _, err := parsePosition(position); err == nil {
+			t.Errorf("Expected error for position %q", position)
+		}
+	}
+}
+
+func TestGoToDefinitionHandler(t *testing.T) {
+	// The receiver type of TestMethod in the test package
+	response, err := goToDefinitionHandler(context.Background(), GoToDefinitionArgs{Position: "test.go:9:13"})
+	if err != nil {
+		t.Fatalf("goToDefinitionHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"name":"TestStruct"`) || !strings.Contains(text, `"doc":"TestStruct is a test struct\n"`) {
+		t.Errorf("Expected the TestStruct definition, got %s", text)
+	}
+}
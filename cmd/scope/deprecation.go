@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ListDeprecatedArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only list symbols declared in this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func listDeprecatedHandler(ctx context.Context, args ListDeprecatedArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing deprecated symbols", "package", args.Package)
+	start := time.Now()
+	symbols, err := analyzerInstance.Deprecated(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "list_deprecated")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deprecated symbols: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type PlanMigrationArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only plan the migration away from symbols declared in this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func planMigrationHandler(ctx context.Context, args PlanMigrationArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Planning migration off deprecated symbols", "package", args.Package)
+	start := time.Now()
+	plan, err := analyzerInstance.PlanMigration(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "plan_migration")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migration plan: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/lsp"
+)
+
+func TestDeprecationHandlers(t *testing.T) {
+	response, err := listDeprecatedHandler(context.Background(), ListDeprecatedArgs{})
+	if err != nil {
+		t.Fatalf("listDeprecatedHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != "[]" {
+		t.Errorf("Expected no deprecated symbols, got %s", text)
+	}
+
+	response, err = planMigrationHandler(context.Background(), PlanMigrationArgs{})
+	if err != nil {
+		t.Fatalf("planMigrationHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"groups":[]`) || !strings.Contains(text, `"sites":0`) {
+		t.Errorf("Expected an empty migration plan, got %s", text)
+	}
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	// Neither an undeprecated symbol nor one the analyzer does not know
+	// gets a warning
+	for _, symbol := range []string{"TestStruct", "TestStruct.TestMethod", "Missing"} {
+		if warnings := deprecationWarnings(context.Background(), symbol); warnings != nil {
+			t.Errorf("Expected no warnings for %s, got %v", symbol, warnings)
+		}
+	}
+}
+
+// fakeGopls resolves every symbol to one location of a file and finds one
+// reference to it
+type fakeGopls struct {
+	conn *lsp.Conn
+	file string
+}
+
+func (f *fakeGopls) handle(method string, params json.RawMessage) (interface{}, error) {
+	loc := lsp.Location{URI: lsp.PathToURI(f.file), Range: lsp.Range{Start: lsp.Position{Line: 4, Character: 5}}}
+	switch method {
+	case "initialize":
+		return map[string]interface{}{"capabilities": map[string]interface{}{}}, nil
+	case "workspace/symbol":
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.Unmarshal(params, &req)
+		return []lsp.SymbolInformation{{Name: req.Query, Kind: 12, ContainerName: "example.com/shop", Location: loc}}, nil
+	case "textDocument/references":
+		return []lsp.Location{loc}, nil
+	default:
+		return nil, fmt.Errorf("unexpected method %s", method)
+	}
+}
+
+func TestFindUsagesDeprecationWarning(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"shop.go": "package shop\n\n// OldTotal sums the cart.\n//\n// Deprecated: use Total instead.\nfunc OldTotal() int { return Total() }\n\n" +
+			"// Total sums the cart\nfunc Total() int { return 0 }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	clientSide, serverSide := net.Pipe()
+	fake := &fakeGopls{file: filepath.Join(dir, "shop.go")}
+	fake.conn = lsp.NewConn(serverSide, serverSide, fake.handle)
+	client, err := lsp.NewClient(context.Background(), clientSide, clientSide, clientSide, dir)
+	if err != nil {
+		t.Fatalf("Failed to create LSP client: %v", err)
+	}
+	defer serverSide.Close()
+	defer client.Close()
+	lspBridge = lsp.NewBridge(client)
+	defer func() { lspBridge = nil }()
+
+	response, err := findUsagesHandler(context.Background(), FindUsagesArgs{Symbol: "OldTotal"})
+	if err != nil {
+		t.Fatalf("findUsagesHandler failed: %v", err)
+	}
+	var result FindUsagesResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(result.Usages) != 1 {
+		t.Errorf("Expected one usage, got %v", result.Usages)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "OldTotal is deprecated") || !strings.Contains(result.Warnings[0], "use Total instead") {
+		t.Errorf("Expected a deprecation warning with the note, got %v", result.Warnings)
+	}
+
+	response, err = findUsagesHandler(context.Background(), FindUsagesArgs{Symbol: "Total"})
+	if err != nil {
+		t.Fatalf("findUsagesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); strings.Contains(text, "warnings") {
+		t.Errorf("Expected no warnings for Total, got %s", text)
+	}
+}
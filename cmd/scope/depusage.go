@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type DepUsageArgs struct {
+	Dependency string `json:"dependency" jsonschema:"required,description=Import path of the module or package such as github.com/pkg/errors; a module path covers the packages below it"`
+	ResponseBudget
+}
+
+func depUsageHandler(ctx context.Context, args DepUsageArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reporting dependency usage", "dependency", args.Dependency)
+	start := time.Now()
+	usage, err := analyzerInstance.DependencyUsage(ctx, args.Dependency)
+	metrics.AnalyzerDuration.ObserveDuration(start, "dep_usage")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dependency usage: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
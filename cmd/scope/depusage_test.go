@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDepUsageHandler(t *testing.T) {
+	if _, err := depUsageHandler(context.Background(), DepUsageArgs{Dependency: "github.com/pkg/errors"}); err == nil || !strings.Contains(err.Error(), "no package of the repository imports") {
+		t.Errorf("Expected an error for a dependency the repository does not import, got %v", err)
+	}
+	if _, err := depUsageHandler(context.Background(), DepUsageArgs{}); err == nil {
+		t.Errorf("Expected an error without a dependency")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type DynamicTypingReportArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only report this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func dynamicTypingReportHandler(ctx context.Context, args DynamicTypingReportArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reporting dynamic typing", "package", args.Package)
+	start := time.Now()
+	report, err := analyzerInstance.DynamicTypingReport(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "dynamic_typing_report")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dynamic typing report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
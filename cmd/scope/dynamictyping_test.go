@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestDynamicTypingReportHandler(t *testing.T) {
+	// The test package has no assertions or reflection
+	response, err := dynamicTypingReportHandler(context.Background(), DynamicTypingReportArgs{})
+	if err != nil {
+		t.Fatalf("dynamicTypingReportHandler failed: %v", err)
+	}
+	var report analyzer.DynamicTypingReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to unmarshal dynamic typing report: %v", err)
+	}
+	if len(report.Packages) != 0 || report.Unchecked != 0 {
+		t.Errorf("Expected an empty report, got %+v", report)
+	}
+
+	if _, err := dynamicTypingReportHandler(context.Background(), DynamicTypingReportArgs{Package: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown package")
+	}
+}
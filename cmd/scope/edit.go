@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/llm"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type CodeEditOperation struct {
+	Op     string `json:"op" jsonschema:"required,description=add_field; add_method; replace_body; add_import; add_decl; replace; remove_import or replace_chunk"`
+	Type   string `json:"type,omitempty" jsonschema:"description=Struct of add_field or receiver type of add_method"`
+	Func   string `json:"func,omitempty" jsonschema:"description=Function (Name) or method (Type.Name) of replace_body"`
+	Code   string `json:"code,omitempty" jsonschema:"description=Field declarations of add_field; method declaration of add_method; the new body statements of replace_body without braces; the package-level declarations of add_decl; or the text replacing old or chunk"`
+	Path   string `json:"path,omitempty" jsonschema:"description=Import path of add_import or remove_import"`
+	Name   string `json:"name,omitempty" jsonschema:"description=Optional import name of add_import"`
+	Old    string `json:"old,omitempty" jsonschema:"description=Text replace replaces"`
+	Line   int    `json:"line,omitempty" jsonschema:"description=Line (1-based) the text of replace starts on"`
+	Column int    `json:"column,omitempty" jsonschema:"description=Column of the text of replace when the line holds it more than once"`
+	Chunk  string `json:"chunk,omitempty" jsonschema:"description=ID of the chunk replace_chunk replaces; from read_file"`
+}
+
+type CodeEditArgs struct {
+	File    string              `json:"file" jsonschema:"required,description=The file to edit; relative to the repository root"`
+	Edits   []CodeEditOperation `json:"edits,omitempty" jsonschema:"description=Structural edits applied in order; nothing is written unless all succeed"`
+	DryRun  bool                `json:"dry_run,omitempty" jsonschema:"description=Only return the diff of the edits"`
+	Changes string              `json:"changes,omitempty" jsonschema:"description=Free-form changes for the language model or the external code_edit tool; used when no edits are given"`
+	Sandbox bool                `json:"sandbox,omitempty" jsonschema:"description=Make the edits in a copy of the repository and check it builds; nothing reaches the repository until confirm_edit"`
+	// SandboxID continues a sandbox so that edits to several files are
+	// checked and confirmed together
+	SandboxID string `json:"sandbox_id,omitempty" jsonschema:"description=Sandbox of an earlier sandboxed edit to add these edits to"`
+	RunTests  bool   `json:"run_tests,omitempty" jsonschema:"description=Also run the tests of the edited package in the sandbox"`
+	ResponseBudget
+}
+
+func codeEditHandler(ctx context.Context, args CodeEditArgs) (*mcp.ToolResponse, error) {
+	if len(args.Edits) == 0 && (llmClient == nil || args.Changes == "") {
+		return externalCodeEdit(ctx, args)
+	}
+	slog.InfoContext(ctx, "Applying edits", "file", args.File, "edits", len(args.Edits), "dry_run", args.DryRun)
+
+	filename, err := repoFile(args.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args.Edits) == 0 {
+		// The model rewrites the file, and the rewrite goes through the same
+		// checks, dry run and sandbox as structural edits
+		source := filename
+		if args.SandboxID != "" && sandboxes != nil {
+			// Build on the edits the sandbox already holds
+			if sb, err := sandboxes.Get(args.SandboxID); err == nil {
+				source = sb.Path(relPath(analyzerInstance.RepoPath(), filename))
+			}
+		}
+		// A write of changes a dry run previewed applies the edit the model
+		// made then, since asking again would get a different answer. Each
+		// dry run asks the model anew.
+		key := modelEditKey{file: filename, sandbox: args.SandboxID, changes: args.Changes}
+		var op CodeEditOperation
+		ok := false
+		if !args.DryRun {
+			op, ok = takeModelEdit(key, source)
+		}
+		if !ok {
+			op, err = modelCodeEdit(ctx, source, relPath(analyzerInstance.RepoPath(), filename), args.Changes)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if args.DryRun {
+			storeModelEdit(key, op)
+		}
+		args.Edits = []CodeEditOperation{op}
+	}
+
+	edits := make([]edit.Edit, len(args.Edits))
+	for i, op := range args.Edits {
+		edits[i] = edit.Edit(op)
+	}
+	if args.Sandbox || args.SandboxID != "" {
+		return sandboxCodeEdit(ctx, args, filename, edits)
+	}
+	if err := checkWrite("code_edit", []string{filename}, args.DryRun, args.Edits); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := edit.Apply(filename, edits, args.DryRun)
+	metrics.AnalyzerDuration.ObserveDuration(start, "code_edit")
+	if err != nil {
+		return nil, err
+	}
+	result.File = relPath(analyzerInstance.RepoPath(), result.File)
+	if result.Applied {
+		refreshAfterWrite(ctx, "code edit")
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code edit result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// externalCodeEdit passes free-form changes to the configured external
+// code_edit tool
+func externalCodeEdit(ctx context.Context, args CodeEditArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Executing code edit", "file", args.File)
+	if args.Changes == "" {
+		return nil, fmt.Errorf("either edits or changes are required")
+	}
+	tool, ok := toolManager.GetTool("code_edit")
+	if !ok {
+		return nil, fmt.Errorf("code_edit tool not found")
+	}
+	if writePolicy != nil {
+		// The command writes the file itself, so there is no dry run to
+		// preview it with
+		filename, err := repoFile(args.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkWrite("code_edit", []string{filename}, false, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	input := fmt.Sprintf("%s\n%s", args.File, args.Changes)
+	output, err := tool.Execute(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("code edit failed: %w", err)
+	}
+
+	return mcp.NewToolResponse(mcp.NewTextContent(output)), nil
+}
+
+// codeEditPrompt instructs the language model making free-form changes
+const codeEditPrompt = `You edit a file of a Go repository as asked. Answer with the complete new content of the file ` +
+	`in a single fenced code block and nothing else. Keep everything the changes do not touch as it is.`
+
+// modelCodeEdit asks the language model to make free-form changes to the
+// file name read from filename, returning an edit that replaces the file
+// with its answer
+func modelCodeEdit(ctx context.Context, filename, name, changes string) (CodeEditOperation, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return CodeEditOperation{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(src) == 0 {
+		return CodeEditOperation{}, fmt.Errorf("%s is empty", filename)
+	}
+	resp, err := llmClient.Complete(ctx, llm.Request{
+		System: codeEditPrompt,
+		Messages: []llm.Message{{
+			Role:    llm.RoleUser,
+			Content: fmt.Sprintf("File %s:\n\n```go\n%s```\n\nChanges:\n\n%s", name, src, changes),
+		}},
+	})
+	if err != nil {
+		return CodeEditOperation{}, fmt.Errorf("code edit failed: %w", err)
+	}
+	code, ok := codeBlock(resp.Text)
+	if !ok {
+		return CodeEditOperation{}, fmt.Errorf("the model's answer holds no code block")
+	}
+	return CodeEditOperation{Op: edit.Replace, Old: string(src), Line: 1, Code: code}, nil
+}
+
+// modelEditTTL is how long the edit the model made for a dry run is kept
+// for the write of the same changes
+const modelEditTTL = time.Hour
+
+// modelEditKey identifies free-form changes to a file, in a sandbox or not
+type modelEditKey struct {
+	file, sandbox, changes string
+}
+
+// modelEdit is an edit the model made for a dry run
+type modelEdit struct {
+	op CodeEditOperation
+	at time.Time
+}
+
+var (
+	modelEditsMu sync.Mutex
+	// modelEdits holds the edits of dry runs until the same changes are
+	// written
+	modelEdits = make(map[modelEditKey]modelEdit)
+)
+
+// storeModelEdit keeps the edit the model made for a dry run of changes
+func storeModelEdit(key modelEditKey, op CodeEditOperation) {
+	modelEditsMu.Lock()
+	defer modelEditsMu.Unlock()
+	now := time.Now()
+	for k, e := range modelEdits {
+		if now.Sub(e.at) > modelEditTTL {
+			delete(modelEdits, k)
+		}
+	}
+	modelEdits[key] = modelEdit{op: op, at: now}
+}
+
+// takeModelEdit returns and forgets the edit a dry run of changes made, as
+// long as it is recent and the file read from source has not changed since
+func takeModelEdit(key modelEditKey, source string) (CodeEditOperation, bool) {
+	modelEditsMu.Lock()
+	e, ok := modelEdits[key]
+	delete(modelEdits, key)
+	modelEditsMu.Unlock()
+	if !ok || time.Since(e.at) > modelEditTTL {
+		return CodeEditOperation{}, false
+	}
+	if src, err := os.ReadFile(source); err != nil || string(src) != e.op.Old {
+		return CodeEditOperation{}, false
+	}
+	return e.op, true
+}
+
+// codeBlock returns the content of the first fenced code block of text
+func codeBlock(text string) (string, bool) {
+	_, rest, ok := strings.Cut(text, "```")
+	if !ok {
+		return "", false
+	}
+	// Skip the language tag on the opening line
+	_, rest, ok = strings.Cut(rest, "\n")
+	if !ok {
+		return "", false
+	}
+	code, _, ok := strings.Cut(rest, "```")
+	return code, ok
+}
+
+// repoFile resolves a file relative to the repository, refusing files
+// outside it
+func repoFile(file string) (string, error) {
+	repoPath := analyzerInstance.RepoPath()
+	filename := file
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(repoPath, filename)
+	}
+	if rel, err := filepath.Rel(repoPath, filename); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository", file)
+	}
+	return filepath.Clean(filename), nil
+}
+
+// refreshAfterWrite brings the analysis up to date with files a tool
+// wrote. The files are on disk, so it runs even if the client stops
+// waiting.
+func refreshAfterWrite(ctx context.Context, what string) {
+	ctx = context.WithoutCancel(ctx)
+	if err := analyzerInstance.Refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to refresh analyzer", "after", what, "error", err)
+	}
+	invalidateAnalysisCache(ctx)
+}
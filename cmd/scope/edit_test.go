@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/llm"
+)
+
+func TestCodeEditHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/shop\n\ngo 1.21\n",
+		"cart.go": "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	args := CodeEditArgs{
+		File: "cart.go",
+		Edits: []CodeEditOperation{
+			{Op: "add_field", Type: "Cart", Code: "Owner string"},
+			{Op: "add_method", Type: "Cart", Code: "// Len returns the number of items\nfunc (c *Cart) Len() int { return len(c.Items) }"},
+		},
+		DryRun: true,
+	}
+	response, err := codeEditHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"file":"cart.go"`) || !strings.Contains(text, `"applied":false`) || !strings.Contains(text, `+\tOwner string`) {
+		t.Errorf("Expected a dry-run diff adding Owner, got %s", text)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); string(data) != files["cart.go"] {
+		t.Error("Expected a dry run to leave the file unchanged")
+	}
+
+	args.DryRun = false
+	response, err = codeEditHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"applied":true`) {
+		t.Errorf("Expected the edits to be applied, got %s", text)
+	}
+
+	// The analyzer is refreshed with the edited file
+	info, err := shop.LookupType(context.Background(), "Cart")
+	if err != nil {
+		t.Fatalf("Failed to look up Cart: %v", err)
+	}
+	if len(info.Fields) != 2 || len(info.Methods) != 1 || info.Methods[0].Name != "Len" {
+		t.Errorf("Expected Cart to have 2 fields and the Len method, got %d fields and %v", len(info.Fields), info.Methods)
+	}
+
+	if _, err := codeEditHandler(context.Background(), CodeEditArgs{File: "../outside.go", Edits: args.Edits}); err == nil || !strings.Contains(err.Error(), "outside the repository") {
+		t.Errorf("Expected an error for a file outside the repository, got %v", err)
+	}
+	if _, err := codeEditHandler(context.Background(), CodeEditArgs{File: "cart.go"}); err == nil {
+		t.Error("Expected an error without edits or changes")
+	}
+}
+
+func TestCodeEditHandlerWithModel(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/shop\n\ngo 1.21\n",
+		"cart.go": "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	model := &fakeModel{answer: "Here it is:\n\n```go\npackage shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n\tOwner   string\n}\n```\n"}
+	llmClient = llm.NewClient(model, 1000, 0)
+	defer func() { llmClient = nil }()
+
+	args := CodeEditArgs{File: "cart.go", Changes: "add an Owner field to Cart", DryRun: true}
+	response, err := codeEditHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"applied":false`) || !strings.Contains(text, `+\tOwner string`) {
+		t.Errorf("Expected a formatted dry-run diff adding Owner, got %s", text)
+	}
+	if input := model.last.Messages[0].Content; !strings.Contains(input, "File cart.go") || !strings.Contains(input, "type Cart struct") || !strings.Contains(input, args.Changes) {
+		t.Errorf("Expected the file and changes in the request, got %s", input)
+	}
+
+	model.answer = "type Cart struct {"
+	if _, err := codeEditHandler(context.Background(), args); err == nil || !strings.Contains(err.Error(), "no code block") {
+		t.Errorf("Expected an error for an answer without code, got %v", err)
+	}
+	model.answer = "```go\npackage shop\n\ntype Cart struct {\n```"
+	if _, err := codeEditHandler(context.Background(), args); err == nil || !strings.Contains(err.Error(), "not valid Go") {
+		t.Errorf("Expected an error for invalid Go, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); string(data) != files["cart.go"] {
+		t.Error("Expected failed edits to leave the file unchanged")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type EmbedsArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only list embeds of this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func embedsHandler(ctx context.Context, args EmbedsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing embeds", "package", args.Package)
+	start := time.Now()
+	report, err := analyzerInstance.Embeds(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "embeds")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embed report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
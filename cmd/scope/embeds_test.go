@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEmbedsHandler(t *testing.T) {
+	response, err := embedsHandler(context.Background(), EmbedsArgs{})
+	if err != nil {
+		t.Fatalf("embedsHandler failed: %v", err)
+	}
+	// The test package embeds no files
+	if text := responseText(t, response); text != `{"embeds":[],"problems":0}` {
+		t.Errorf("Expected no embeds, got %s", text)
+	}
+
+	if _, err := embedsHandler(context.Background(), EmbedsArgs{Package: "nosuchpkg"}); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ListEnumsArgs struct {
+	Package       string `json:"package,omitempty" jsonschema:"description=Only list enums declared in this package (import path or package name); omit for all packages" session:"package"`
+	MissingString bool   `json:"missing_string,omitempty" jsonschema:"description=Only list enums without a String method; e.g. to find candidates for stringer"`
+	ExportedOnly  bool   `json:"exported_only,omitempty" jsonschema:"description=Only list exported enums" session:"exported_only"`
+	ResponseBudget
+}
+
+func listEnumsHandler(ctx context.Context, args ListEnumsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing enums", "package", args.Package)
+	start := time.Now()
+	enums, err := analyzerInstance.ListEnums(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "list_enums")
+	if err != nil {
+		return nil, err
+	}
+
+	if args.MissingString || args.ExportedOnly {
+		selected := []analyzer.EnumInfo{}
+		for _, enum := range enums {
+			if (!args.MissingString || !enum.HasString) && (!args.ExportedOnly || enum.Exported) {
+				selected = append(selected, enum)
+			}
+		}
+		enums = selected
+	}
+
+	jsonData, err := json.Marshal(enums)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal enums: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListEnumsHandler(t *testing.T) {
+	// The test package declares no constants
+	response, err := listEnumsHandler(context.Background(), ListEnumsArgs{MissingString: true})
+	if err != nil {
+		t.Fatalf("listEnumsHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != "[]" {
+		t.Errorf("Expected no enums, got %s", text)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ErrorPathsArgs struct {
+	Function string `json:"function" jsonschema:"required,description=Function or Type.Method to analyze; qualify it as pkg.Name when ambiguous"`
+	Depth    int    `json:"depth,omitempty" jsonschema:"description=How many calls deep to search for reachable panics (default 5)"`
+	ResponseBudget
+}
+
+func errorPathsHandler(ctx context.Context, args ErrorPathsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Analyzing error paths", "function", args.Function)
+	start := time.Now()
+	paths, err := analyzerInstance.ErrorPaths(ctx, args.Function, args.Depth)
+	metrics.AnalyzerDuration.ObserveDuration(start, "error_paths")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal error paths: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestErrorPathsHandler(t *testing.T) {
+	response, err := errorPathsHandler(context.Background(), ErrorPathsArgs{Function: "TestStruct.TestMethod"})
+	if err != nil {
+		t.Fatalf("errorPathsHandler failed: %v", err)
+	}
+	var paths analyzer.ErrorPaths
+	if err := json.Unmarshal([]byte(responseText(t, response)), &paths); err != nil {
+		t.Fatalf("Failed to decode error paths: %v", err)
+	}
+	if paths.Function != "testpkg.TestStruct.TestMethod" || len(paths.Returns) != 0 || len(paths.Panics) != 0 {
+		t.Errorf("Unexpected error paths: %+v", paths)
+	}
+
+	if _, err := errorPathsHandler(context.Background(), ErrorPathsArgs{Function: "DoesNotExist"}); err == nil {
+		t.Error("Expected error for unknown function")
+	}
+}
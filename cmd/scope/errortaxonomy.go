@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ErrorTaxonomyArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Only inventory this package (import path or package name); omit for all packages" session:"package"`
+	ResponseBudget
+}
+
+func errorTaxonomyHandler(ctx context.Context, args ErrorTaxonomyArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Building error taxonomy", "package", args.Package)
+	start := time.Now()
+	taxonomy, err := analyzerInstance.ErrorTaxonomy(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "error_taxonomy")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(taxonomy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal error taxonomy: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
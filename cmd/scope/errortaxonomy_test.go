@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestErrorTaxonomyHandler(t *testing.T) {
+	response, err := errorTaxonomyHandler(context.Background(), ErrorTaxonomyArgs{})
+	if err != nil {
+		t.Fatalf("errorTaxonomyHandler failed: %v", err)
+	}
+	var taxonomy analyzer.ErrorTaxonomy
+	if err := json.Unmarshal([]byte(responseText(t, response)), &taxonomy); err != nil {
+		t.Fatalf("Failed to decode error taxonomy: %v", err)
+	}
+	if taxonomy.Sentinels == nil || taxonomy.Types == nil || taxonomy.WrapSites == nil || taxonomy.Edges == nil || taxonomy.Comparisons == nil {
+		t.Errorf("Expected every list of the taxonomy, got %+v", taxonomy)
+	}
+
+	if _, err := errorTaxonomyHandler(context.Background(), ErrorTaxonomyArgs{Package: "nosuchpkg"}); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ExplainSymbolArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Symbol to explain (Name; pkg.Name; or Type.Method)"`
+	Tokens int    `json:"tokens,omitempty" jsonschema:"description=Token budget of the answer; defaults to 1500"`
+	Usages int    `json:"usages,omitempty" jsonschema:"description=Number of referencing declarations to list; defaults to 5"`
+	ResponseBudget
+}
+
+func explainSymbolHandler(ctx context.Context, args ExplainSymbolArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Explaining symbol", "symbol", args.Symbol, "tokens", args.Tokens)
+	start := time.Now()
+	explanation, err := analyzerInstance.ExplainSymbol(ctx, args.Symbol, analyzer.ExplainOptions{
+		Tokens: args.Tokens,
+		Usages: args.Usages,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "explain_symbol")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(explanation.Markdown)), nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExplainSymbolHandler(t *testing.T) {
+	response, err := explainSymbolHandler(context.Background(), ExplainSymbolArgs{Symbol: "TestStruct"})
+	if err != nil {
+		t.Fatalf("explainSymbolHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	for _, want := range []string{"# testpkg.TestStruct", "TestStruct is a test struct", "- `TestMethod() string`", "`testpkg.NewTestStruct`"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected explanation to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	if _, err := explainSymbolHandler(context.Background(), ExplainSymbolArgs{Symbol: "Missing"}); err == nil {
+		t.Error("Expected an error for an unknown symbol")
+	}
+}
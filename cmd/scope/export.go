@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// runExport implements `scope export`: it analyzes the repository and writes
+// a snapshot the server can load with -snapshot, so analysis of large
+// repositories can be done ahead of time, e.g. in CI
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	repo := fs.String("repo", os.Getenv("GO_REPO_PATH"), "repository to analyze (defaults to GO_REPO_PATH or the current directory)")
+	output := fs.String("o", "scope-snapshot.json.gz", "file to write the snapshot to")
+	includeTests := fs.Bool("tests", false, "include _test.go files in the analysis")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	repoPath := *repo
+	if repoPath == "" {
+		repoPath = "."
+	}
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return 1
+	}
+
+	config := analyzer.DefaultConfig()
+	config.IncludeTests = *includeTests
+	a, err := analyzer.NewAnalyzerWithConfig(repoPath, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to analyze repository: %v\n", err)
+		return 1
+	}
+	defer a.Close()
+
+	snap, err := a.Snapshot(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create snapshot: %v\n", err)
+		return 1
+	}
+	if err := snap.WriteFile(*output); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write snapshot: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote snapshot of %d packages and %d types to %s\n", len(snap.Result.Packages), len(snap.Result.Types), *output)
+	return 0
+}
+
+// openSnapshot creates an analyzer answering queries from the snapshot at
+// snapshotPath while it analyzes the repository in the background
+func openSnapshot(repoPath string, config *analyzer.Config, snapshotPath string) (*analyzer.Analyzer, error) {
+	snap, err := analyzer.ReadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	return analyzer.NewAnalyzerFromSnapshot(repoPath, config, snap)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestRunExport(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "snapshot.json.gz")
+	if code := runExport([]string{"-repo", analyzerInstance.RepoPath(), "-o", output}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	snap, err := analyzer.ReadSnapshot(output)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if len(snap.Index["TestStruct"]) != 1 {
+		t.Errorf("Expected TestStruct in the snapshot index, got %v", snap.Index)
+	}
+
+	if code := runExport([]string{"-repo", filepath.Join(t.TempDir(), "missing"), "-o", output}); code == 0 {
+		t.Error("Expected failure for a missing repository")
+	}
+}
+
+func TestOpenSnapshot(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "snapshot.json.gz")
+	if code := runExport([]string{"-repo", analyzerInstance.RepoPath(), "-o", output}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	a, err := openSnapshot(analyzerInstance.RepoPath(), analyzer.DefaultConfig(), output)
+	if err != nil || a == nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	a.Close()
+
+	// A repository that does not exist fails instead of leaving no analyzer
+	a, err = openSnapshot(filepath.Join(t.TempDir(), "missing"), analyzer.DefaultConfig(), output)
+	if err == nil || a != nil {
+		t.Errorf("Expected failure for a missing repository, got %v", err)
+	}
+	if _, err := openSnapshot(analyzerInstance.RepoPath(), analyzer.DefaultConfig(), filepath.Join(t.TempDir(), "missing.json.gz")); err == nil {
+		t.Error("Expected failure for a missing snapshot")
+	}
+}
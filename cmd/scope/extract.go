@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ExtractInterfaceArgs struct {
+	Type    string   `json:"type" jsonschema:"required,description=Concrete type to extract the interface from (Name or pkg.Name)"`
+	Methods []string `json:"methods,omitempty" jsonschema:"description=Methods of the interface; defaults to every exported method"`
+	Name    string   `json:"name,omitempty" jsonschema:"description=Name of the interface; suggested when empty"`
+	Package string   `json:"package,omitempty" jsonschema:"description=Package to declare the interface in (import path or package name); defaults to the type's package"`
+	Write   bool     `json:"write,omitempty" jsonschema:"description=Write the interface to the package"`
+	File    string   `json:"file,omitempty" jsonschema:"description=File of the package to write to; relative to the repository root; defaults to the lower-cased interface name with .go"`
+	Assert  bool     `json:"assert,omitempty" jsonschema:"description=Also write a compile-time assertion that the type implements the interface"`
+	DryRun  bool     `json:"dry_run,omitempty" jsonschema:"description=Only return the diff of writing the interface"`
+	ResponseBudget
+}
+
+// ExtractInterfaceResult is the generated interface and, when written, the
+// edit of its file
+type ExtractInterfaceResult struct {
+	*analyzer.ExtractedInterface
+	Edit *edit.Result `json:"edit,omitempty"`
+}
+
+func extractInterfaceHandler(ctx context.Context, args ExtractInterfaceArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Extracting interface", "type", args.Type, "methods", args.Methods, "package", args.Package, "write", args.Write)
+	start := time.Now()
+	extracted, err := analyzerInstance.ExtractInterface(ctx, args.Type, analyzer.ExtractOptions{
+		Methods: args.Methods,
+		Name:    args.Name,
+		Package: args.Package,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "extract_interface")
+	if err != nil {
+		return nil, err
+	}
+
+	result := ExtractInterfaceResult{ExtractedInterface: extracted}
+	if args.Write || args.DryRun {
+		if result.Edit, err = writeInterface(extracted, args); err != nil {
+			return nil, err
+		}
+		if result.Edit.Applied {
+			refreshAfterWrite(ctx, "interface extraction")
+		}
+	}
+	repoPath := analyzerInstance.RepoPath()
+	extracted.Dir = relPath(repoPath, extracted.Dir)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal extracted interface: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// writeInterface adds an extracted interface, with the imports it needs, to
+// a file of its package, creating the file if it does not exist
+func writeInterface(extracted *analyzer.ExtractedInterface, args ExtractInterfaceArgs) (*edit.Result, error) {
+	filename := filepath.Join(extracted.Dir, strings.ToLower(extracted.Name)+".go")
+	if args.File != "" {
+		var err error
+		if filename, err = repoFile(args.File); err != nil {
+			return nil, err
+		}
+		if filepath.Dir(filename) != extracted.Dir {
+			return nil, fmt.Errorf("%s is not in the directory of package %s", args.File, extracted.ImportPath)
+		}
+	}
+	if filepath.Ext(filename) != ".go" || strings.HasSuffix(filename, "_test.go") {
+		return nil, fmt.Errorf("%s is not a non-test Go file", filepath.Base(filename))
+	}
+
+	imports := extracted.Imports
+	code := extracted.Code
+	if args.Assert {
+		imports = make(map[string]string, len(extracted.Imports)+1)
+		for name, importPath := range extracted.Imports {
+			imports[name] = importPath
+		}
+		if extracted.TypePackage != extracted.ImportPath {
+			name, _, _ := strings.Cut(extracted.Type, ".")
+			imports[name] = extracted.TypePackage
+		}
+		code += "\n" + extracted.Assertion
+	}
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var edits []edit.Edit
+	for _, name := range names {
+		importEdit := edit.Edit{Op: edit.AddImport, Path: imports[name]}
+		if name != path.Base(imports[name]) {
+			importEdit.Name = name
+		}
+		edits = append(edits, importEdit)
+	}
+	edits = append(edits, edit.Edit{Op: edit.AddDecl, Code: code})
+
+	if err := checkWrite("extract_interface", []string{filename}, args.DryRun, edits); err != nil {
+		return nil, err
+	}
+
+	var result *edit.Result
+	var err error
+	if _, statErr := os.Stat(filename); statErr == nil {
+		result, err = edit.Apply(filename, edits, args.DryRun)
+	} else {
+		result, err = edit.Create(filename, extracted.PackageName, edits, args.DryRun)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.File = relPath(analyzerInstance.RepoPath(), result.File)
+	return result, nil
+}
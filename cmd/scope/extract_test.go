@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestExtractInterfaceHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go":     "package store\n\ntype Item struct{}\n\ntype Store struct{}\n\n// Get returns the named item\nfunc (s *Store) Get(name string) (*Item, error) { return nil, nil }\n",
+		"service/service.go": "package service\n\ntype Service struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	// Without write nothing changes
+	response, err := extractInterfaceHandler(context.Background(), ExtractInterfaceArgs{Type: "Store", Package: "service"})
+	if err != nil {
+		t.Fatalf("extractInterfaceHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"name":"Getter"`) || !strings.Contains(text, `"dir":"service"`) || strings.Contains(text, `"edit"`) {
+		t.Errorf("Expected Getter for the service package without an edit, got %s", text)
+	}
+
+	args := ExtractInterfaceArgs{Type: "Store", Package: "service", Write: true, Assert: true}
+	response, err = extractInterfaceHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("extractInterfaceHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"file":"service/getter.go"`) || !strings.Contains(text, `"applied":true`) {
+		t.Errorf("Expected service/getter.go to be created, got %s", text)
+	}
+	expected := `package service
+
+import "example.com/shop/store"
+
+// Getter is implemented by store.Store
+type Getter interface {
+	// Get returns the named item
+	Get(name string) (*store.Item, error)
+}
+
+var _ Getter = (*store.Store)(nil)
+`
+	if data, err := os.ReadFile(filepath.Join(dir, "service", "getter.go")); err != nil || string(data) != expected {
+		t.Errorf("Expected getter.go:\n%s\ngot (%v):\n%s", expected, err, data)
+	}
+
+	// The analysis sees the new interface, so it cannot be declared twice
+	if _, err := extractInterfaceHandler(context.Background(), args); err == nil || !strings.Contains(err.Error(), "already declares Getter") {
+		t.Errorf("Expected a conflict with the written interface, got %v", err)
+	}
+
+	// Existing files are edited in place
+	response, err = extractInterfaceHandler(context.Background(), ExtractInterfaceArgs{Type: "Store", Name: "Items", Package: "service", File: "service/service.go", DryRun: true})
+	if err != nil {
+		t.Fatalf("extractInterfaceHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"file":"service/service.go"`) || !strings.Contains(text, `+type Items interface {`) {
+		t.Errorf("Expected a dry-run diff of service.go, got %s", text)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "service", "service.go")); string(data) != files["service/service.go"] {
+		t.Error("Expected a dry run to leave the file unchanged")
+	}
+
+	if _, err := extractInterfaceHandler(context.Background(), ExtractInterfaceArgs{Type: "Store", Name: "Items", Package: "service", File: "store/items.go", Write: true}); err == nil ||
+		!strings.Contains(err.Error(), "not in the directory") {
+		t.Errorf("Expected a file outside the package to be refused, got %v", err)
+	}
+}
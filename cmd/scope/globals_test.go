@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestGlobalsReportHandler(t *testing.T) {
+	// The test package declares no variables or init functions
+	response, err := globalsReportHandler(context.Background(), GlobalsReportArgs{})
+	if err != nil {
+		t.Fatalf("globalsReportHandler failed: %v", err)
+	}
+	var report analyzer.GlobalsReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to unmarshal globals report: %v", err)
+	}
+	if len(report.Packages) != 0 || len(report.Shared) != 0 || len(report.InitOrder) != 1 {
+		t.Errorf("Expected an empty report with one package to initialize, got %+v", report)
+	}
+
+	if _, err := globalsReportHandler(context.Background(), GlobalsReportArgs{Package: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown package")
+	}
+}
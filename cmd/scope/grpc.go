@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type GRPCMapArgs struct {
+	Service string `json:"service,omitempty" jsonschema:"description=Only map the service of this name as declared in the .proto file; omit to map every file"`
+	ResponseBudget
+}
+
+func grpcMapHandler(ctx context.Context, args GRPCMapArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Mapping gRPC services", "service", args.Service)
+	start := time.Now()
+	grpcMap, err := analyzerInstance.GRPCMap(ctx, args.Service)
+	metrics.AnalyzerDuration.ObserveDuration(start, "grpc_map")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(grpcMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal gRPC map: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGRPCMapHandler(t *testing.T) {
+	response, err := grpcMapHandler(context.Background(), GRPCMapArgs{})
+	if err != nil {
+		t.Fatalf("grpcMapHandler failed: %v", err)
+	}
+	// The test repository has no .proto files
+	if text := responseText(t, response); text != `{"files":[]}` {
+		t.Errorf("Expected no files, got %s", text)
+	}
+
+	if _, err := grpcMapHandler(context.Background(), GRPCMapArgs{Service: "Greeter"}); err == nil {
+		t.Error("Expected error for an unknown service")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/hooks"
+)
+
+// runHooks implements `scope hooks install`, `scope hooks uninstall` and
+// `scope hooks run <hook>`
+func runHooks(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: scope hooks <install|uninstall|run> [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "install":
+		return runHooksInstall(args[1:])
+	case "uninstall":
+		return runHooksUninstall(args[1:])
+	case "run":
+		return runHooksRun(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown hooks command %q\n", args[0])
+		return 2
+	}
+}
+
+func runHooksInstall(args []string) int {
+	fs := flag.NewFlagSet("hooks install", flag.ContinueOnError)
+	repo := fs.String("repo", ".", "repository to install hooks into")
+	hookList := fs.String("hooks", hooks.PreCommit+","+hooks.PrePush, "comma-separated hooks to install")
+	force := fs.Bool("force", false, "replace existing hooks not installed by scope (a .bak copy is kept)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	repoPath, err := filepath.Abs(*repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return 1
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get executable path: %v\n", err)
+		return 1
+	}
+
+	installed, err := hooks.Install(repoPath, binary, splitList(*hookList), *force)
+	for _, path := range installed {
+		fmt.Printf("Installed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install hooks: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Configure the checks each hook runs in %s\n", hooks.ConfigPath(repoPath))
+	return 0
+}
+
+func runHooksUninstall(args []string) int {
+	fs := flag.NewFlagSet("hooks uninstall", flag.ContinueOnError)
+	repo := fs.String("repo", ".", "repository to remove hooks from")
+	hookList := fs.String("hooks", hooks.PreCommit+","+hooks.PrePush, "comma-separated hooks to remove")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	repoPath, err := filepath.Abs(*repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return 1
+	}
+
+	removed, err := hooks.Uninstall(repoPath, splitList(*hookList))
+	for _, path := range removed {
+		fmt.Printf("Removed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to uninstall hooks: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runHooksRun(args []string) int {
+	fs := flag.NewFlagSet("hooks run", flag.ContinueOnError)
+	repo := fs.String("repo", ".", "repository to check")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: scope hooks run [-repo path] <pre-commit|pre-push>")
+		return 2
+	}
+
+	repoPath, err := filepath.Abs(*repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return 1
+	}
+
+	diagnostics, err := hooks.Run(context.Background(), repoPath, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scope %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return reportDiagnostics(os.Stderr, fs.Arg(0), diagnostics)
+}
+
+// reportDiagnostics prints diagnostics and returns the exit code for a hook:
+// non-zero when any error-severity diagnostic was found
+func reportDiagnostics(w io.Writer, hook string, diagnostics []checks.Diagnostic) int {
+	errors := 0
+	for _, d := range diagnostics {
+		fmt.Fprintln(w, d)
+		if d.Severity == checks.SeverityError {
+			errors++
+		}
+	}
+	if errors > 0 {
+		fmt.Fprintf(w, "scope %s: %d problem(s) found\n", hook, errors)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/hooks"
+)
+
+// gitCommit commits the files of a repository, returning the output and
+// whether the commit went through
+func gitCommit(t *testing.T, repo, message string) (string, bool) {
+	t.Helper()
+	add := exec.Command("git", "add", "-A")
+	add.Dir = repo
+	if output, err := add.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v: %s", err, output)
+	}
+	commit := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", message)
+	commit.Dir = repo
+	output, err := commit.CombinedOutput()
+	return string(output), err == nil
+}
+
+func TestHooksCommand(t *testing.T) {
+	repo := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module example.com/hooked\n\ngo 1.21\n",
+		"lib/lib.go":        "package lib\n\n// Hello greets\nfunc Hello() string { return \"hi\" }\n",
+		".scope/hooks.json": `{"hooks": {"pre-commit": ["format"]}}`,
+		".gitignore":        ".scope/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory of %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, output)
+	}
+	if output, ok := gitCommit(t, repo, "initial"); !ok {
+		t.Fatalf("Initial commit failed: %s", output)
+	}
+
+	if code := runHooks([]string{"install", "-repo", repo, "-hooks", hooks.PreCommit}); code != 0 {
+		t.Fatalf("Expected install to succeed, got exit code %d", code)
+	}
+
+	// The hook runs this binary as scope, which refuses a badly formatted
+	// file
+	bad := filepath.Join(repo, "lib", "bad.go")
+	if err := os.WriteFile(bad, []byte("package lib\nfunc   Bad( ) {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write bad.go: %v", err)
+	}
+	output, ok := gitCommit(t, repo, "bad")
+	if ok {
+		t.Fatal("Expected the pre-commit hook to refuse the commit")
+	}
+	if !strings.Contains(output, "bad.go") || !strings.Contains(output, "scope pre-commit: 1 problem(s) found") {
+		t.Errorf("Expected the hook to report bad.go, got %s", output)
+	}
+
+	// The same command runs the hook's checks directly
+	if code := runHooks([]string{"run", "-repo", repo, hooks.PreCommit}); code != 1 {
+		t.Errorf("Expected run to fail on the staged file, got exit code %d", code)
+	}
+
+	if code := runHooks([]string{"uninstall", "-repo", repo}); code != 0 {
+		t.Fatalf("Expected uninstall to succeed, got exit code %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", hooks.PreCommit)); !os.IsNotExist(err) {
+		t.Errorf("Expected the hook to be removed, got %v", err)
+	}
+	if output, ok := gitCommit(t, repo, "bad"); !ok {
+		t.Errorf("Expected the commit to go through without the hook, got %s", output)
+	}
+
+	if code := runHooks([]string{"remove"}); code != 2 {
+		t.Errorf("Expected an unknown command to be a usage error, got exit code %d", code)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// defaultInventoryFiles is the number of files repo_inventory lists as
+// largest and as most recently changed unless asked for another
+const defaultInventoryFiles = 10
+
+type RepoInventoryArgs struct {
+	Largest int `json:"largest,omitempty" jsonschema:"description=Number of largest files to list; default 10"`
+	Recent  int `json:"recent,omitempty" jsonschema:"description=Number of most recently modified files to list; default 10"`
+	ResponseBudget
+}
+
+func repoInventoryHandler(ctx context.Context, args RepoInventoryArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Building repository inventory", "largest", args.Largest, "recent", args.Recent)
+	opts := analyzer.InventoryOptions{Largest: args.Largest, Recent: args.Recent}
+	if opts.Largest <= 0 {
+		opts.Largest = defaultInventoryFiles
+	}
+	if opts.Recent <= 0 {
+		opts.Recent = defaultInventoryFiles
+	}
+
+	start := time.Now()
+	inventory, err := analyzerInstance.Inventory(ctx, opts)
+	metrics.AnalyzerDuration.ObserveDuration(start, "repo_inventory")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(inventory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
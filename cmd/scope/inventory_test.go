@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestRepoInventoryHandler(t *testing.T) {
+	response, err := repoInventoryHandler(context.Background(), RepoInventoryArgs{Largest: 1})
+	if err != nil {
+		t.Fatalf("repoInventoryHandler failed: %v", err)
+	}
+	var inventory analyzer.Inventory
+	if err := json.Unmarshal([]byte(responseText(t, response)), &inventory); err != nil {
+		t.Fatalf("Failed to decode inventory: %v", err)
+	}
+	if inventory.Files == 0 || len(inventory.Languages) == 0 || len(inventory.Directories) == 0 {
+		t.Errorf("Expected the files of the test repository, got %+v", inventory)
+	}
+	if len(inventory.Largest) != 1 || len(inventory.Recent) != min(defaultInventoryFiles, inventory.Files) {
+		t.Errorf("Expected 1 largest file and the default number of recent files, got %d and %d", len(inventory.Largest), len(inventory.Recent))
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/jobs"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// jobWorkers is how many jobs run at once
+const jobWorkers = 2
+
+// jobMaxAge is how long finished jobs and their results are kept
+const jobMaxAge = 7 * 24 * time.Hour
+
+// maxJobWait bounds how long job_result waits for a job to finish
+const maxJobWait = 60 * time.Second
+
+// jobQueue runs the tool calls of start_job in the background
+var jobQueue *jobs.Queue
+
+// jobManagementTools cannot run as jobs
+var jobManagementTools = map[string]bool{
+	"start_job":  true,
+	"job_status": true,
+	"job_result": true,
+	"cancel_job": true,
+}
+
+type StartJobArgs struct {
+	Tool      string         `json:"tool" jsonschema:"required,description=Name of the tool to run in the background"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"description=Arguments of the tool as it takes them when called on its own"`
+	ResponseBudget
+}
+
+type JobStatusArgs struct {
+	ID string `json:"id,omitempty" jsonschema:"description=Job ID returned by start_job; omit to list every job"`
+	ResponseBudget
+}
+
+// JobList is the response of job_status without an ID
+type JobList struct {
+	Jobs []jobs.Job `json:"jobs"`
+}
+
+type JobResultArgs struct {
+	ID          string `json:"id" jsonschema:"required,description=Job ID returned by start_job"`
+	WaitSeconds int    `json:"wait_seconds,omitempty" jsonschema:"description=Seconds to wait for the job to finish before giving up; at most 60 (default 0)"`
+	ResponseBudget
+}
+
+type CancelJobArgs struct {
+	ID string `json:"id" jsonschema:"required,description=Job ID returned by start_job"`
+	ResponseBudget
+}
+
+// runJob calls the tool of a job and returns the text of its response
+func runJob(ctx context.Context, tool string, arguments json.RawMessage) (string, error) {
+	slog.InfoContext(ctx, "Running job", "tool", tool)
+	texts, err := callTool(ctx, tool, arguments)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// queue returns the job queue, or an error when jobs are not available
+func queue() (*jobs.Queue, error) {
+	if jobQueue == nil {
+		return nil, fmt.Errorf("background jobs are not available")
+	}
+	return jobQueue, nil
+}
+
+func startJobHandler(ctx context.Context, args StartJobArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Starting job", "tool", args.Tool)
+	q, err := queue()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := batchTools[args.Tool]; !ok || jobManagementTools[args.Tool] {
+		return nil, fmt.Errorf("unknown tool %s", args.Tool)
+	}
+	var arguments []byte
+	if args.Arguments != nil {
+		if arguments, err = json.Marshal(args.Arguments); err != nil {
+			return nil, fmt.Errorf("invalid arguments for %s: %w", args.Tool, err)
+		}
+	}
+
+	job, err := q.Start(args.Tool, arguments)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+func jobStatusHandler(ctx context.Context, args JobStatusArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Getting job status", "id", args.ID)
+	q, err := queue()
+	if err != nil {
+		return nil, err
+	}
+
+	var result any = JobList{Jobs: q.List()}
+	if args.ID != "" {
+		if result, err = q.Get(args.ID); err != nil {
+			return nil, err
+		}
+	}
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job status: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+func jobResultHandler(ctx context.Context, args JobResultArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Getting job result", "id", args.ID, "wait_seconds", args.WaitSeconds)
+	q, err := queue()
+	if err != nil {
+		return nil, err
+	}
+	if args.WaitSeconds > 0 {
+		wait := min(time.Duration(args.WaitSeconds)*time.Second, maxJobWait)
+		waitCtx, cancel := context.WithTimeout(ctx, wait)
+		defer cancel()
+		if _, err := q.Wait(waitCtx, args.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The result is the job's tool response as the tool returned it
+	result, err := q.Result(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(result)), nil
+}
+
+func cancelJobHandler(ctx context.Context, args CancelJobArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Cancelling job", "id", args.ID)
+	q, err := queue()
+	if err != nil {
+		return nil, err
+	}
+	job, err := q.Cancel(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/jobs"
+)
+
+func TestJobHandlers(t *testing.T) {
+	instrument("lookup_type", lookupTypeHandler)
+	ctx := context.Background()
+
+	if _, err := startJobHandler(ctx, StartJobArgs{Tool: "lookup_type"}); err == nil {
+		t.Error("Expected error without a job queue")
+	}
+	queue, err := jobs.Open(t.TempDir(), runJob, 1)
+	if err != nil {
+		t.Fatalf("Failed to open job queue: %v", err)
+	}
+	jobQueue = queue
+	defer func() {
+		jobQueue = nil
+		queue.Close()
+	}()
+
+	response, err := startJobHandler(ctx, StartJobArgs{Tool: "lookup_type", Arguments: map[string]any{"type_name": "TestStruct"}})
+	if err != nil {
+		t.Fatalf("startJobHandler failed: %v", err)
+	}
+	var job jobs.Job
+	if err := json.Unmarshal([]byte(responseText(t, response)), &job); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if job.ID == "" || job.Tool != "lookup_type" {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	response, err = jobResultHandler(ctx, JobResultArgs{ID: job.ID, WaitSeconds: 10})
+	if err != nil {
+		t.Fatalf("jobResultHandler failed: %v", err)
+	}
+	var info struct{ Name string }
+	if err := json.Unmarshal([]byte(responseText(t, response)), &info); err != nil || info.Name != "TestStruct" {
+		t.Errorf("Expected the lookup of TestStruct, got %s (%v)", responseText(t, response), err)
+	}
+
+	// A failing call fails its job
+	response, err = startJobHandler(ctx, StartJobArgs{Tool: "lookup_type", Arguments: map[string]any{"type_name": "DoesNotExist"}})
+	if err != nil {
+		t.Fatalf("startJobHandler failed: %v", err)
+	}
+	var failed jobs.Job
+	json.Unmarshal([]byte(responseText(t, response)), &failed)
+	if _, err := jobResultHandler(ctx, JobResultArgs{ID: failed.ID, WaitSeconds: 10}); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Expected the job's failure, got %v", err)
+	}
+
+	response, err = jobStatusHandler(ctx, JobStatusArgs{})
+	if err != nil {
+		t.Fatalf("jobStatusHandler failed: %v", err)
+	}
+	var list JobList
+	if err := json.Unmarshal([]byte(responseText(t, response)), &list); err != nil || len(list.Jobs) != 2 {
+		t.Errorf("Expected 2 jobs, got %s (%v)", responseText(t, response), err)
+	}
+	response, err = jobStatusHandler(ctx, JobStatusArgs{ID: job.ID})
+	if err != nil {
+		t.Fatalf("jobStatusHandler failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(responseText(t, response)), &job); err != nil || job.State != jobs.StateDone {
+		t.Errorf("Expected a finished job, got %s (%v)", responseText(t, response), err)
+	}
+	response, err = cancelJobHandler(ctx, CancelJobArgs{ID: job.ID})
+	if err != nil || !strings.Contains(responseText(t, response), jobs.StateDone) {
+		t.Errorf("Expected cancelling a finished job to leave it done, got %v", err)
+	}
+
+	for _, tool := range []string{"missing", "start_job"} {
+		if _, err := startJobHandler(ctx, StartJobArgs{Tool: tool}); err == nil {
+			t.Errorf("Expected %s to be refused", tool)
+		}
+	}
+	if _, err := jobStatusHandler(ctx, JobStatusArgs{ID: "nosuchjob"}); err == nil {
+		t.Error("Expected error for an unknown job")
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/TFMV/scope/internal/llm"
+)
+
+// llmClient is the language model code_edit and code_review call; nil
+// unless SCOPE_LLM_PROVIDER is set, leaving them to the external commands of
+// tools.json
+var llmClient *llm.Client
+
+// connectLLM creates the language model client the environment configures,
+// or returns nil when none is configured
+func connectLLM() (*llm.Client, error) {
+	config, err := llm.ConfigFromEnv()
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return llm.New(config)
+}
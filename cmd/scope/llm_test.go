@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/tools"
+)
+
+// clearLLMEnv unsets the variables connectLLM reads for the test
+func clearLLMEnv(t *testing.T) {
+	for _, name := range []string{
+		"SCOPE_LLM_PROVIDER", "SCOPE_LLM_MODEL", "SCOPE_LLM_BASE_URL", "SCOPE_LLM_API_KEY",
+		"SCOPE_LLM_MAX_TOKENS", "SCOPE_LLM_TIMEOUT", "SCOPE_LLM_MAX_RETRIES",
+		"OPENAI_API_KEY", "ANTHROPIC_API_KEY",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConnectLLM(t *testing.T) {
+	clearLLMEnv(t)
+	client, err := connectLLM()
+	if err != nil || client != nil {
+		t.Fatalf("Expected no client without SCOPE_LLM_PROVIDER, got %v, %v", client, err)
+	}
+
+	t.Setenv("SCOPE_LLM_PROVIDER", "anthropic")
+	if _, err := connectLLM(); err == nil || !strings.Contains(err.Error(), "SCOPE_LLM_MODEL") {
+		t.Errorf("Expected an error without a model, got %v", err)
+	}
+	t.Setenv("SCOPE_LLM_MODEL", "claude")
+	if _, err := connectLLM(); err == nil || !strings.Contains(err.Error(), "API key") {
+		t.Errorf("Expected an error without an API key, got %v", err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "secret")
+	client, err = connectLLM()
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if stats := client.Stats(); stats.Provider != "anthropic" || stats.Model != "claude" {
+		t.Errorf("Expected the configured provider and model, got %+v", stats)
+	}
+
+	t.Setenv("SCOPE_LLM_PROVIDER", "unknown")
+	if _, err := connectLLM(); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
+
+func TestCodeReviewHandlerWithProvider(t *testing.T) {
+	previousManager := toolManager
+	defer func() { toolManager = previousManager }()
+	toolManager = tools.NewToolManager()
+
+	// An OpenAI-compatible API refusing requests without its key
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"message": "missing API key"}}`))
+			return
+		}
+		w.Write([]byte(`{"model": "fake", "choices": [{"message": {"role": "assistant", "content": "looks good"}}], "usage": {"prompt_tokens": 10, "completion_tokens": 2}}`))
+	}))
+	defer provider.Close()
+
+	clearLLMEnv(t)
+	t.Setenv("SCOPE_LLM_PROVIDER", "openai")
+	t.Setenv("SCOPE_LLM_MODEL", "fake")
+	t.Setenv("SCOPE_LLM_BASE_URL", provider.URL)
+	t.Setenv("SCOPE_LLM_MAX_RETRIES", "0")
+	defer func() { llmClient = nil }()
+
+	// The provider's error reaches the caller
+	var err error
+	if llmClient, err = connectLLM(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	_, err = codeReviewHandler(context.Background(), CodeReviewArgs{Changes: "no diff"})
+	if err == nil || !strings.Contains(err.Error(), "missing API key") {
+		t.Errorf("Expected the missing API key error, got %v", err)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "secret")
+	if llmClient, err = connectLLM(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	response, err := codeReviewHandler(context.Background(), CodeReviewArgs{Changes: "no diff"})
+	if err != nil {
+		t.Fatalf("codeReviewHandler failed: %v", err)
+	}
+	var result CodeReviewResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result.Review != "looks good" || result.Model != "fake" || result.Usage == nil || result.Usage.InputTokens != 10 {
+		t.Errorf("Expected the provider's review, got %+v", result)
+	}
+}
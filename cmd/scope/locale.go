@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/TFMV/scope/internal/i18n"
+)
+
+// localizer translates summaries, errors and reports; nil means English
+var localizer *i18n.Localizer
+
+// loadLocalizer picks the locale from the -locale flag (or SCOPE_LOCALE),
+// falling back to the repository's .scope/i18n.json. An unusable locale is
+// logged and English is used instead.
+func loadLocalizer(locale, repoPath string) *i18n.Localizer {
+	if locale == "" {
+		config, err := i18n.LoadConfig(repoPath)
+		if err != nil {
+			slog.Warn("Using English", "error", err)
+			return nil
+		}
+		locale = config.Locale
+	}
+
+	l, err := i18n.New(locale, i18n.CatalogDir(repoPath))
+	if err != nil {
+		slog.Warn("Using English", "error", err, "locales", i18n.Supported())
+		return nil
+	}
+	return l
+}
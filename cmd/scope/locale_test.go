@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/i18n"
+)
+
+func TestLoadLocalizer(t *testing.T) {
+	repo := t.TempDir()
+	if l := loadLocalizer("", repo); l.Locale() != "en" {
+		t.Errorf("Expected English without configuration, got %s", l.Locale())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i18n.ConfigPath(repo)), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(i18n.ConfigPath(repo), []byte(`{"locale": "es"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if l := loadLocalizer("", repo); l.Locale() != "es" {
+		t.Errorf("Expected locale from config, got %s", l.Locale())
+	}
+	if l := loadLocalizer("de", repo); l.Locale() != "de" {
+		t.Errorf("Expected flag to override config, got %s", l.Locale())
+	}
+	if l := loadLocalizer("xx", repo); l != nil {
+		t.Errorf("Expected unknown locale to fall back to English, got %s", l.Locale())
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/lsp"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// lspTimeout bounds each request made to gopls on behalf of a tool call
+const lspTimeout = 30 * time.Second
+
+var lspBridge *lsp.Bridge
+
+// connectLSP starts or connects to gopls according to mode: "spawn" runs
+// gopls from PATH, anything else is the address of a gopls started with
+// -listen (host:port or unix;path)
+func connectLSP(mode, repoPath string) (*lsp.Bridge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var client *lsp.Client
+	var err error
+	if mode == "spawn" {
+		client, err = lsp.Spawn(ctx, repoPath, "")
+	} else {
+		client, err = lsp.Dial(ctx, mode, repoPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lsp.NewBridge(client), nil
+}
+
+// lookupTypeViaLSP answers lookup_type with gopls, for types the in-process
+// analyzer cannot resolve (for example in packages that do not type-check)
+func lookupTypeViaLSP(ctx context.Context, name string) (*analyzer.TypeInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, lspTimeout)
+	defer cancel()
+
+	start := time.Now()
+	sym, err := lspBridge.Lookup(ctx, name)
+	metrics.AnalyzerDuration.ObserveDuration(start, "lsp_lookup")
+	if err != nil {
+		return nil, err
+	}
+
+	ident := sym.Name
+	if i := strings.LastIndex(ident, "."); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return &analyzer.TypeInfo{
+		Name:       ident,
+		Kind:       sym.Kind,
+		Package:    path.Base(sym.Container),
+		ImportPath: sym.Container,
+		Doc:        sym.Hover,
+		Position:   lspPosition(sym.Location),
+		Exported:   token.IsExported(ident),
+	}, nil
+}
+
+// deprecationWarnings warns when a symbol is deprecated. Symbols the
+// analyzer cannot resolve get no warning; gopls may still know them.
+func deprecationWarnings(ctx context.Context, symbol string) []string {
+	note, deprecated, err := analyzerInstance.Deprecation(ctx, symbol)
+	if err != nil || !deprecated {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s is deprecated: %s", symbol, note)}
+}
+
+// lspPosition converts an LSP location into a one-based analyzer position
+func lspPosition(loc lsp.Location) analyzer.Position {
+	return analyzer.Position{
+		Filename: lsp.URIToPath(loc.URI),
+		Line:     loc.Range.Start.Line + 1,
+		Column:   loc.Range.Start.Character + 1,
+	}
+}
+
+type FindUsagesArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Name of the type, function, method (Type.Method) or field to find; qualify it as pkg.Name when ambiguous"`
+	ResponseBudget
+}
+
+// FindUsagesResult lists the references to a symbol. Warnings flag a
+// symbol whose doc comment marks it deprecated, so callers are not added.
+type FindUsagesResult struct {
+	Usages   []analyzer.Position `json:"usages"`
+	Warnings []string            `json:"warnings,omitempty"`
+}
+
+func findUsagesHandler(ctx context.Context, args FindUsagesArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Finding usages", "symbol", args.Symbol)
+	ctx, cancel := context.WithTimeout(ctx, lspTimeout)
+	defer cancel()
+
+	start := time.Now()
+	locations, err := lspBridge.FindUsages(ctx, args.Symbol)
+	metrics.AnalyzerDuration.ObserveDuration(start, "lsp_references")
+	if err != nil {
+		return nil, err
+	}
+
+	result := FindUsagesResult{Usages: make([]analyzer.Position, len(locations))}
+	for i, loc := range locations {
+		result.Usages[i] = lspPosition(loc)
+	}
+	result.Warnings = deprecationWarnings(ctx, args.Symbol)
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal usages: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type RenameArgs struct {
+	Symbol      string `json:"symbol" jsonschema:"required,description=Name of the symbol to rename; qualify it as pkg.Name or Type.Method when ambiguous"`
+	NewName     string `json:"new_name" jsonschema:"required,description=The new identifier"`
+	Apply       bool   `json:"apply,omitempty" jsonschema:"description=Write the edits to disk instead of only returning them"`
+	IncludeText bool   `json:"include_text,omitempty" jsonschema:"description=Also find the name (and its lowerCamel and snake_case forms) in comments; struct tags and string literals for manual review"`
+	ResponseBudget
+}
+
+// RenameResult reports the edits computed for a rename and whether they
+// were written. ManualReview holds the mentions of the name the edits leave
+// alone, which are never written.
+type RenameResult struct {
+	Edits        []lsp.FileEdit          `json:"edits"`
+	Applied      bool                    `json:"applied"`
+	ManualReview *analyzer.MentionResult `json:"manual_review,omitempty"`
+}
+
+func renameHandler(ctx context.Context, args RenameArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Renaming symbol", "symbol", args.Symbol, "new_name", args.NewName, "apply", args.Apply)
+	if !token.IsIdentifier(args.NewName) {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", args.NewName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lspTimeout)
+	defer cancel()
+
+	start := time.Now()
+	edits, err := lspBridge.Rename(ctx, args.Symbol, args.NewName)
+	metrics.AnalyzerDuration.ObserveDuration(start, "lsp_rename")
+	if err != nil {
+		return nil, err
+	}
+
+	// Returning the edits without applying them is the rename's dry run
+	files := make([]string, len(edits))
+	for i, e := range edits {
+		files[i] = e.File
+	}
+	if err := checkWrite("rename", files, !args.Apply, edits); err != nil {
+		return nil, err
+	}
+
+	result := RenameResult{Edits: edits}
+	if args.IncludeText {
+		mentions, err := analyzerInstance.FindMentions(ctx, args.Symbol, args.NewName, 0)
+		if err != nil {
+			return nil, err
+		}
+		mentions.Mentions = uneditedMentions(mentions.Mentions, edits)
+		result.ManualReview = mentions
+	}
+	if args.Apply {
+		for _, edit := range edits {
+			if err := edit.Apply(); err != nil {
+				return nil, err
+			}
+		}
+		result.Applied = true
+		// The edits are on disk, so the analysis must catch up even if the
+		// client stops waiting
+		ctx := context.WithoutCancel(ctx)
+		if err := analyzerInstance.Refresh(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to refresh analyzer", "after", "rename", "error", err)
+		}
+		invalidateAnalysisCache(ctx)
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rename result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// uneditedMentions drops the mentions a rename's edits already change, such
+// as doc links gopls renames
+func uneditedMentions(mentions []analyzer.Mention, edits []lsp.FileEdit) []analyzer.Mention {
+	byFile := make(map[string][]lsp.TextEdit)
+	for _, edit := range edits {
+		byFile[edit.File] = append(byFile[edit.File], edit.Edits...)
+	}
+	kept := mentions[:0]
+	for _, mention := range mentions {
+		if !editedAt(byFile[mention.Position.Filename], mention.Position) {
+			kept = append(kept, mention)
+		}
+	}
+	return kept
+}
+
+// editedAt reports whether an edit covers a one-based position. Characters
+// are compared as columns, which holds for ASCII lines.
+func editedAt(edits []lsp.TextEdit, pos analyzer.Position) bool {
+	line, char := pos.Line-1, pos.Column-1
+	for _, edit := range edits {
+		start, end := edit.Range.Start, edit.Range.End
+		afterStart := line > start.Line || line == start.Line && char >= start.Character
+		beforeEnd := line < end.Line || line == end.Line && char < end.Character
+		if afterStart && beforeEnd {
+			return true
+		}
+	}
+	return false
+}
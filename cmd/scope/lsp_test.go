@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/lsp"
+)
+
+func TestUneditedMentions(t *testing.T) {
+	mention := func(file string, line, column int) analyzer.Mention {
+		return analyzer.Mention{Position: analyzer.Position{Filename: file, Line: line, Column: column}}
+	}
+	mentions := []analyzer.Mention{
+		mention("/repo/a.go", 3, 8),  // Inside the doc link gopls renames
+		mention("/repo/a.go", 3, 20), // Later on the same line
+		mention("/repo/b.go", 3, 8),  // In a file without edits
+	}
+	edits := []lsp.FileEdit{{
+		File: "/repo/a.go",
+		Edits: []lsp.TextEdit{{
+			Range:   lsp.Range{Start: lsp.Position{Line: 2, Character: 7}, End: lsp.Position{Line: 2, Character: 15}},
+			NewText: "Login",
+		}},
+	}}
+
+	kept := uneditedMentions(mentions, edits)
+	if len(kept) != 2 || kept[0].Position.Column != 20 || kept[1].Position.Filename != "/repo/b.go" {
+		t.Errorf("Expected the edited mention to be dropped, got %+v", kept)
+	}
+}
@@ -3,17 +3,35 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/TFMV/scope/internal/analyzer"
 	"github.com/TFMV/scope/internal/cache"
+	"github.com/TFMV/scope/internal/docserver"
+	"github.com/TFMV/scope/internal/jobs"
+	"github.com/TFMV/scope/internal/logging"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/notes"
+	"github.com/TFMV/scope/internal/remote"
+	"github.com/TFMV/scope/internal/replica"
+	"github.com/TFMV/scope/internal/report"
+	"github.com/TFMV/scope/internal/sandbox"
+	"github.com/TFMV/scope/internal/semantic"
+	"github.com/TFMV/scope/internal/session"
+	"github.com/TFMV/scope/internal/spill"
 	"github.com/TFMV/scope/internal/tools"
+	"github.com/TFMV/scope/internal/tracing"
 	mcp "github.com/metoro-io/mcp-golang"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 )
@@ -21,7 +39,10 @@ import (
 var (
 	analyzerInstance *analyzer.Analyzer
 	cacheInstance    *cache.Cache
+	cacheNamespace   string // Prefix of this repository's analysis result keys
 	toolManager      *tools.ToolManager
+	toolsConfigPath  string   // The tools.json reload_tools and the config watcher read
+	registeredTools  []string // Names of the tools wrapped by instrument, in registration order
 )
 
 // TypeInfo represents the extracted type information
@@ -33,56 +54,276 @@ type TypeInfo struct {
 	Methods []string `json:"methods,omitempty"`
 }
 
+// commands maps subcommand names to their entry points; without a
+// subcommand scope runs the MCP server
+var commands = map[string]func(args []string) int{
+	"watch":  runWatch,
+	"hooks":  runHooks,
+	"ci":     runCI,
+	"export": runExport,
+}
+
 func main() {
-	// Initialize logging to write to stderr
-	log.SetOutput(os.Stderr)
-	log.SetFlags(log.LstdFlags)
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	metricsAddr := flag.String("metrics-addr", os.Getenv("SCOPE_METRICS_ADDR"), "address to serve Prometheus metrics on (e.g. 127.0.0.1:9090); disabled when empty")
+	lspMode := flag.String("lsp", os.Getenv("SCOPE_LSP"), "gopls bridge: \"spawn\" to start gopls, or the address of a gopls started with -listen (host:port or unix;path); disabled when empty")
+	loadDeps := flag.Bool("deps", os.Getenv("SCOPE_LOAD_DEPENDENCIES") != "", "resolve standard library and module dependency types (e.g. context.Context) with go list; requires the go command")
+	locale := flag.String("locale", os.Getenv("SCOPE_LOCALE"), "language of summaries, errors and reports (e.g. de, es); overrides .scope/i18n.json")
+	maxResponse := flag.Int("max-response-bytes", envInt("SCOPE_MAX_RESPONSE_BYTES", defaultMaxResponseBytes), "largest tool response in bytes; larger results are spilled to a file and returned as a preview with a continuation cursor (0 disables)")
+	maxTokens := flag.Int("max-response-tokens", envInt("SCOPE_MAX_RESPONSE_TOKENS", 0), "largest tool response in estimated tokens of 4 bytes; the smaller of this and -max-response-bytes applies (0 disables)")
+	snapshotPath := flag.String("snapshot", os.Getenv("SCOPE_SNAPSHOT"), "snapshot written by scope export to answer queries from while the repository is analyzed in the background")
+	replicaAddr := flag.String("replica-addr", os.Getenv("SCOPE_REPLICA_ADDR"), "address to publish index snapshots and updates on for standby servers (e.g. 127.0.0.1:9091); disabled when empty")
+	replicateFrom := flag.String("replicate-from", os.Getenv("SCOPE_REPLICATE_FROM"), "URL of a primary started with -replica-addr to run as its warm standby")
+	pluginPaths := flag.String("plugins", os.Getenv("SCOPE_PLUGINS"), "Go plugins (built with -buildmode=plugin) adding per-package analyses, separated like PATH")
+	cacheBackend := flag.String("cache-backend", os.Getenv("SCOPE_CACHE_BACKEND"), "cache storage: \"json\" (one file rewritten on every write), \"bolt\" (bbolt database for large caches) or \"memory\" (not persisted); defaults to json")
+	lazy := flag.Bool("lazy", os.Getenv("SCOPE_LAZY") != "", "load packages when a query first needs them instead of analyzing the whole repository at startup")
+	memoryBudget := flag.Int("memory-budget-mb", envInt("SCOPE_MEMORY_BUDGET_MB", 0), "with -lazy, heap size in MiB above which the least recently used packages are evicted (0 never evicts)")
+	excludeGenerated := flag.Bool("exclude-generated", os.Getenv("SCOPE_EXCLUDE_GENERATED") != "", "leave generated files (Code generated headers, .pb.go and _gen.go) out of the analysis")
+	docsAddr := flag.String("docs-http", os.Getenv("SCOPE_DOCS_HTTP"), "address to serve browsable HTML documentation of the analyzed repository on (e.g. 127.0.0.1:6060); disabled when empty")
+	goVersion := flag.String("go-version", os.Getenv("SCOPE_GO_VERSION"), "language version to type check with (e.g. 1.21); newer language features are reported by parse_diagnostics")
+	failover := flag.Duration("failover", envDuration("SCOPE_FAILOVER", 30*time.Second), "how long the primary may be unreachable before a standby analyzes the repository itself")
+	logFormat := flag.String("log-format", os.Getenv("SCOPE_LOG_FORMAT"), "log record format on stderr: \"text\" (the default) or \"json\"")
+	warmup := flag.Int("warmup", envInt("SCOPE_WARMUP", 0), "at startup, cache lookup_type results of every exported type and list_methods results of this many most referenced types (0 disables)")
+	proxyDocsSetting := flag.String("proxy-docs", os.Getenv("SCOPE_PROXY_DOCS"), "when lookup_type misses locally, look the symbol up in a module proxy: \"on\" for the proxy GOPROXY names (proxy.golang.org by default) or a proxy URL; disabled when empty")
+	semanticSearch := flag.Bool("semantic-search", os.Getenv("SCOPE_SEMANTIC_SEARCH") != "", "answer code_search from a built-in index of the repository's declarations, kept current as files change, instead of the code_search command of tools.json")
+	logLevel := flag.String("log-level", os.Getenv("SCOPE_LOG_LEVEL"), "lowest level logged: debug, info (the default), warn or error")
+	flag.Parse()
+
+	// Log structured records to stderr; stdout carries the MCP protocol
+	logger, err := logging.New(os.Stderr, *logFormat, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scope: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
 
 	// Initialize the cache
 	cacheDir := filepath.Join(os.TempDir(), "scope")
-	var err error
-	cacheInstance, err = cache.New(cacheDir)
+	cacheInstance, err = cache.Open(*cacheBackend, cacheDir)
+	if err != nil && *cacheBackend == cache.BackendBolt {
+		// The database is locked while another server uses it
+		slog.Warn("Using an in-memory cache", "error", err)
+		cacheInstance, err = cache.Open(cache.BackendMemory, cacheDir)
+	}
+	if err != nil {
+		fatal("Failed to initialize cache", err)
+	}
+	defer cacheInstance.Close()
+
+	// Oversized responses are spilled next to the cache
+	maxResponseBytes, maxResponseTokens = *maxResponse, *maxTokens
+	spillStore, err = spill.NewStore(filepath.Join(cacheDir, "spill"))
+	if err != nil {
+		fatal("Failed to initialize spill store", err)
+	}
+	if removed, err := spillStore.Prune(24 * time.Hour); err != nil {
+		slog.Warn("Failed to prune spilled results", "error", err)
+	} else if removed > 0 {
+		slog.Info("Removed expired spilled results", "count", removed)
+	}
+
+	// Export traces when an OTLP endpoint is configured
+	traceConfig, err := tracing.ConfigFromEnv()
+	if err != nil {
+		fatal("Failed to configure tracing", err)
+	}
+	if traceConfig != nil {
+		traceConfig.OnError = func(err error) {
+			slog.Warn("Tracing failed", "error", err)
+		}
+		tracer := tracing.NewTracer(*traceConfig)
+		tracing.SetDefault(tracer)
+		defer shutdownTracing(tracer)
+		slog.Info("Exporting traces", "endpoint", traceConfig.Endpoint)
+	}
+
+	// Call a language model from native tools when one is configured
+	llmClient, err = connectLLM()
 	if err != nil {
-		log.Fatalf("Failed to initialize cache: %v", err)
+		fatal("Failed to configure language model", err)
+	}
+	if llmClient != nil {
+		stats := llmClient.Stats()
+		slog.Info("Using language model", "provider", stats.Provider, "model", stats.Model)
 	}
 
 	// Initialize the analyzer
 	repoPath := os.Getenv("GO_REPO_PATH")
 	if repoPath == "" {
-		log.Fatal("GO_REPO_PATH environment variable not set")
+		fatal("GO_REPO_PATH environment variable not set", nil)
+	}
+	// A git URL is cloned into the cache and analyzed like a local checkout
+	if remote.IsURL(repoPath) {
+		repoPath, err = openRemote(context.Background(), repoPath, filepath.Join(cacheDir, "repos"))
+		if err != nil {
+			fatal("Failed to open remote repository", err)
+		}
+	}
+
+	// Plugins register themselves before the first analysis
+	if err := loadPlugins(splitPluginPaths(*pluginPaths)); err != nil {
+		fatal("Failed to load plugins", err)
+	}
+
+	analyzerStart := time.Now()
+	config := analyzer.DefaultConfig()
+	config.LoadDependencies = *loadDeps
+	config.LazyLoading = *lazy
+	if *lazy {
+		// Restarts only parse the files that changed since the last discovery.
+		// Eager loading parses everything anyway, so it keeps no index.
+		config.IndexPath = filepath.Join(cacheDir, "index", cache.RepoNamespace(repoPath), "files.json")
+	}
+	config.ExcludeGenerated = *excludeGenerated
+	config.GoVersion = *goVersion
+	if *memoryBudget > 0 {
+		config.MemoryBudget = uint64(*memoryBudget) << 20
+	}
+	var follower *replica.Replica
+	if *replicateFrom != "" {
+		follower = replica.NewReplica(*replicateFrom, *failover)
+		analyzerInstance, err = follower.Start(context.Background(), repoPath, config)
+	} else if *snapshotPath != "" {
+		analyzerInstance, err = openSnapshot(repoPath, config, *snapshotPath)
+	} else {
+		analyzerInstance, err = analyzer.NewAnalyzerWithConfig(repoPath, config)
+	}
+	if err != nil {
+		fatal("Failed to initialize analyzer", err)
+	}
+	metrics.AnalyzerDuration.ObserveDuration(analyzerStart, "initialize")
+	cacheNamespace = analysisNamespace(repoPath)
+	dropStaleEntries(repoPath)
+
+	// Track symbols pinned during this session and keep them fresh as files change
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pinSet = session.NewPinSet(analyzerInstance)
+	go pinSet.Watch(ctx, repoPath, 2*time.Second, analyzer.DefaultConfig().ExcludePatterns)
+
+	// Keep the semantic index current as files change; a restart only
+	// embeds the declarations that changed since the last run
+	if *semanticSearch {
+		indexPath := filepath.Join(cacheDir, "semantic", cache.RepoNamespace(repoPath), "index.log")
+		semanticIndex, err = semantic.Open(repoPath, indexPath, semantic.NewHashEmbedder(semantic.DefaultDims))
+		if err != nil {
+			fatal("Failed to open semantic index", err)
+		}
+		defer semanticIndex.Close()
+		go func() {
+			err := semanticIndex.Watch(ctx, 2*time.Second, analyzer.DefaultConfig().ExcludePatterns, logSemanticUpdate)
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("Stopped updating the semantic index", "error", err)
+			}
+		}()
+	}
+
+	// Precompute the lookups agents make first while the server starts
+	if *warmup > 0 {
+		go func() {
+			if _, err := warmCache(ctx, *warmup); err != nil && ctx.Err() == nil {
+				slog.Warn("Failed to warm cache", "error", err)
+			}
+		}()
+	}
+
+	// Follow the primary as a standby, or publish to standbys
+	if follower != nil {
+		go follower.Run(ctx)
+	}
+	if *replicaAddr != "" {
+		primary := replica.NewPrimary(analyzerInstance, 0)
+		go primary.Run(ctx, 2*time.Second)
+		go serveReplica(*replicaAddr, primary)
 	}
 
-	analyzerInstance, err = analyzer.NewAnalyzer(repoPath)
+	// Notes persist in the cache, or in the repository when shared
+	noteStore = notes.NewStore(cacheInstance, cache.RepoNamespace(repoPath), notes.FilePath(repoPath))
+
+	// Connect the optional gopls bridge
+	if *lspMode != "" {
+		lspBridge, err = connectLSP(*lspMode, repoPath)
+		if err != nil {
+			fatal("Failed to connect to gopls", err)
+		}
+		defer lspBridge.Close()
+		slog.Info("Connected to gopls", "mode", *lspMode)
+	}
+
+	// Answer lookups of dependencies from a module proxy
+	if *proxyDocsSetting != "" {
+		proxyDocs = newProxyDocs(*proxyDocsSetting)
+		slog.Info("Looking up missing symbols in module proxy", "proxy", proxyDocs.Proxy())
+	}
+
+	// Load message catalogs and report templates
+	localizer = loadLocalizer(*locale, repoPath)
+	rendererInstance, err = report.NewRenderer(templateDirs(repoPath)...)
 	if err != nil {
-		log.Fatalf("Failed to initialize analyzer: %v", err)
+		fatal("Failed to load report templates", err)
+	}
+	rendererInstance.SetLocalizer(localizer)
+	slog.Info("Using locale", "locale", localizer.Locale())
+
+	// Start the optional metrics endpoint
+	if *metricsAddr != "" {
+		registerCacheMetrics(cacheInstance)
+		go serveMetrics(*metricsAddr)
+	}
+
+	// Start the optional documentation server
+	if *docsAddr != "" {
+		docs, err := docserver.New(analyzerInstance)
+		if err != nil {
+			fatal("Failed to initialize documentation server", err)
+		}
+		go serveDocs(*docsAddr, docs)
 	}
 
 	// Initialize tool manager
 	toolManager = tools.NewToolManager()
-	log.Printf("Tool manager initialized")
+	toolManager.SetBaseDir(repoPath)
+	slog.Debug("Tool manager initialized")
 
 	// Get the directory of the executable
 	execPath, err := os.Executable()
 	if err != nil {
-		log.Fatalf("Failed to get executable path: %v", err)
+		fatal("Failed to get executable path", err)
 	}
 	execDir := filepath.Dir(execPath)
-	log.Printf("Looking for config files in: %s", execDir)
+	slog.Debug("Looking for config files", "dir", execDir)
 
 	// Load tool configurations
-	toolsConfig, err := tools.LoadToolsConfig(execDir)
+	toolsConfigPath, err = tools.ConfigPath(execDir)
+	if err != nil {
+		fatal("Failed to locate tools configuration", err)
+	}
+	toolsConfig, err := tools.LoadToolsConfig(toolsConfigPath)
 	if err != nil {
-		log.Fatalf("Failed to load tools configuration: %v", err)
+		fatal("Failed to load tools configuration", err)
 	}
-	log.Printf("Loaded tools configuration with %d tools", len(toolsConfig.Tools))
+	slog.Info("Loaded tools configuration", "path", toolsConfigPath, "tools", len(toolsConfig.Tools), "pipelines", len(toolsConfig.Pipelines))
 
 	// Register all tools from config
 	for _, toolConfig := range toolsConfig.Tools {
-		log.Printf("Attempting to register tool: %s", toolConfig.Name)
 		toolManager.RegisterTool(toolConfig)
-		log.Printf("Registered tool: %s", toolConfig.Name)
+		slog.Debug("Registered external tool", "tool", toolConfig.Name)
+	}
+	for _, pipeline := range toolsConfig.Pipelines {
+		if err := toolManager.RegisterPipeline(pipeline); err != nil {
+			fatal("Failed to register pipeline", err, "pipeline", pipeline.Name)
+		}
+		slog.Debug("Registered pipeline", "pipeline", pipeline.Name)
 	}
 
+	// Pick up edits to the tools configuration without a restart
+	go toolManager.WatchConfig(ctx, toolsConfigPath, 2*time.Second, logToolsReload)
+
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -90,95 +331,729 @@ func main() {
 	// Start the MCP server with HTTP transport
 	server := mcp.NewServer(stdio.NewStdioServerTransport())
 
-	log.Println("Scope server initialized...")
+	slog.Info("Scope server initialized")
 
-	log.Println("Registering tools...")
+	slog.Debug("Registering tools")
 
 	if err := registerTools(server); err != nil {
-		log.Fatalf("Failed to register tools: %v", err)
+		fatal("Failed to register tools", err)
+	}
+
+	slog.Debug("Registering prompts")
+
+	if err := registerPrompts(server); err != nil {
+		fatal("Failed to register prompts", err)
 	}
 
-	log.Println("Starting server...")
+	// Sandboxed edits live in copies of the repository until confirmed
+	sandboxes = sandbox.NewStore(repoPath, filepath.Join(cacheDir, "sandboxes", cache.RepoNamespace(repoPath)))
+	defer sandboxes.Close()
+
+	// Enforce the repository's write policy on the editing tools
+	writePolicy, err = loadWritePolicy(repoPath)
+	if err != nil {
+		fatal("Failed to load write policy", err)
+	}
+
+	// Run background jobs, resuming those queued before a restart. The
+	// write policy and sandboxes are set up first, since resumed jobs may
+	// write files.
+	jobQueue, err = jobs.Open(filepath.Join(cacheDir, "jobs", cache.RepoNamespace(repoPath)), runJob, jobWorkers)
+	if err != nil {
+		fatal("Failed to open job queue", err)
+	}
+	defer jobQueue.Close()
+	if removed, err := jobQueue.Prune(jobMaxAge); err != nil {
+		slog.Warn("Failed to prune jobs", "error", err)
+	} else if removed > 0 {
+		slog.Info("Removed expired jobs", "count", removed)
+	}
+
+	slog.Info("Starting server")
 
 	// Start server in a goroutine
 	go func() {
 		if err := server.Serve(); err != nil {
-			log.Printf("Server error: %v", err)
+			slog.Error("Server error", "error", err)
 		}
 	}()
 
 	// Wait for shutdown signal
 	<-sigChan
-	log.Println("Shutting down Scope server...")
+	slog.Info("Shutting down Scope server")
+}
+
+// shutdownTracing exports the spans that have not been sent yet
+func shutdownTracing(tracer *tracing.Tracer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+}
+
+// fatal logs a startup failure with its error, if any, and exits
+func fatal(msg string, err error, args ...any) {
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// registerCacheMetrics exposes the cache hit and miss counters on the default registry
+func registerCacheMetrics(c *cache.Cache) {
+	metrics.Default.NewCounterFunc("scope_cache_hits_total", "Total number of cache hits", func() float64 {
+		return float64(c.Stats().Hits)
+	})
+	metrics.Default.NewCounterFunc("scope_cache_misses_total", "Total number of cache misses", func() float64 {
+		return float64(c.Stats().Misses)
+	})
+	metrics.Default.NewGaugeFunc("scope_cache_hit_ratio", "Ratio of cache hits to total cache lookups", func() float64 {
+		stats := c.Stats()
+		if total := stats.Hits + stats.Misses; total > 0 {
+			return float64(stats.Hits) / float64(total)
+		}
+		return 0
+	})
+}
+
+// serveMetrics serves the default metrics registry over HTTP at /metrics
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+
+	slog.Info("Serving metrics", "url", "http://"+addr+"/metrics")
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("Metrics server error", "error", err)
+	}
+}
+
+// serveReplica serves index snapshots and updates to standby servers
+func serveReplica(addr string, primary *replica.Primary) {
+	slog.Info("Serving replica updates", "url", "http://"+addr+replica.UpdatesPath)
+	if err := http.ListenAndServe(addr, primary.Handler()); err != nil {
+		slog.Error("Replica server error", "error", err)
+	}
+}
+
+// serveDocs serves the HTML documentation of the analyzed repository
+func serveDocs(addr string, docs *docserver.Server) {
+	slog.Info("Serving documentation", "url", "http://"+addr+"/")
+	if err := http.ListenAndServe(addr, docs.Handler()); err != nil {
+		slog.Error("Documentation server error", "error", err)
+	}
+}
+
+// instrument wraps a tool handler so that every invocation is counted and
+// timed, arguments left unset are filled from the session preferences, its
+// errors are reported in the configured locale, and results are formatted
+// as the session asks and spilled when over the response size limit of the
+// call or the server. The handler gets the context of the MCP request,
+// which is cancelled when the client cancels the call. The tool also
+// becomes callable from batch.
+func instrument[T any](name string, handler func(context.Context, T) (*mcp.ToolResponse, error)) func(context.Context, T) (*mcp.ToolResponse, error) {
+	registeredTools = append(registeredTools, name)
+	call := func(ctx context.Context, args T) (*mcp.ToolResponse, error) {
+		// Calls made by batch share its request ID
+		requestID := logging.RequestID(ctx)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+			ctx = logging.WithRequestID(ctx, requestID)
+		}
+		ctx, span := tracing.Start(ctx, "tool "+name, tracing.KindServer,
+			tracing.String("scope.tool", name), tracing.String("scope.request_id", requestID))
+		defer span.End()
+		if applied := preferences.Apply(&args); len(applied) > 0 {
+			slog.DebugContext(ctx, "Applied session preferences", "tool", name, "preferences", applied)
+		}
+		start := time.Now()
+		response, err := handler(ctx, args)
+		metrics.ToolDuration.ObserveDuration(start, name)
+
+		status := "ok"
+		switch {
+		case err != nil && ctx.Err() != nil:
+			status = "cancelled"
+		case err != nil:
+			status = "error"
+		}
+		metrics.ToolInvocations.Inc(name, status)
+		span.SetAttributes(tracing.String("scope.status", status))
+		span.SetError(err)
+		duration := time.Since(start)
+		if err != nil {
+			slog.WarnContext(ctx, "Tool call failed", "tool", name, "status", status, "duration", duration, "error", err)
+			return response, &requestError{err: localizer.Error(err), requestID: requestID}
+		}
+		slog.DebugContext(ctx, "Tool call finished", "tool", name, "duration", duration)
+		return response, nil
+	}
+	batchTools[name] = func(ctx context.Context, arguments []byte) (*mcp.ToolResponse, error) {
+		var args T
+		if len(arguments) > 0 {
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments for %s: %w", name, err)
+			}
+		}
+		return call(ctx, args)
+	}
+	return func(ctx context.Context, args T) (*mcp.ToolResponse, error) {
+		response, err := call(ctx, args)
+		if err != nil {
+			return response, err
+		}
+		return versionResponse(limitResponse(formatResponse(response), responseLimit(args))), nil
+	}
+}
+
+// requestError is a failed tool call's error carrying the call's request
+// ID, which its message names so that clients can report it
+type requestError struct {
+	err       error
+	requestID string
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("%v (request_id: %s)", e.err, e.requestID)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
 }
 
 func registerTools(server *mcp.Server) error {
+	mcpServer = server
+
 	// Register lookup_type tool
-	if err := server.RegisterTool("lookup_type", "Get documentation and definition of a Go type", lookupTypeHandler); err != nil {
+	if err := server.RegisterTool("lookup_type", "Get documentation and definition of a Go type", instrument("lookup_type", lookupTypeHandler)); err != nil {
 		return fmt.Errorf("failed to register lookup_type tool: %w", err)
 	}
-	log.Printf("Registered lookup_type tool")
+	slog.Debug("Registered tool", "tool", "lookup_type")
 
 	// Register list_methods tool
-	if err := server.RegisterTool("list_methods", "List public methods for a Go type", listMethodsHandler); err != nil {
+	if err := server.RegisterTool("list_methods", "List public methods for a Go type", instrument("list_methods", listMethodsHandler)); err != nil {
 		return fmt.Errorf("failed to register list_methods tool: %w", err)
 	}
-	log.Printf("Registered list_methods tool")
+	slog.Debug("Registered tool", "tool", "list_methods")
+
+	// Register type_hierarchy tool
+	if err := server.RegisterTool("type_hierarchy", "Show the embedded types, implemented interfaces, implementations and embedders of a Go type", instrument("type_hierarchy", typeHierarchyHandler)); err != nil {
+		return fmt.Errorf("failed to register type_hierarchy tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "type_hierarchy")
 
 	// Register show_example tool
-	if err := server.RegisterTool("show_example", "Return a code example for a Go type or topic", showExampleHandler); err != nil {
+	if err := server.RegisterTool("show_example", "Return a code example for a Go type or topic", instrument("show_example", showExampleHandler)); err != nil {
 		return fmt.Errorf("failed to register show_example tool: %w", err)
 	}
-	log.Printf("Registered show_example tool")
+	slog.Debug("Registered tool", "tool", "show_example")
+
+	// Register go_to_definition tool
+	if err := server.RegisterTool("go_to_definition", "Resolve the identifier at file:line:column and return its definition position, kind, declaration and doc", instrument("go_to_definition", goToDefinitionHandler)); err != nil {
+		return fmt.Errorf("failed to register go_to_definition tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "go_to_definition")
+
+	// Register search_types tool
+	if err := server.RegisterTool("search_types", "Find Go types by name and filter them by tags such as deprecated, generated, test-only or experimental", instrument("search_types", searchTypesHandler)); err != nil {
+		return fmt.Errorf("failed to register search_types tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "search_types")
+
+	// Register search_code tool
+	if err := server.RegisterTool("search_code", "Grep Go source by text or regular expression, with each match annotated with its package and enclosing function, method or type and its line range", instrument("search_code", searchCodeHandler)); err != nil {
+		return fmt.Errorf("failed to register search_code tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "search_code")
+
+	// Register read_range tool
+	if err := server.RegisterTool("read_range", "Read a range of lines of a file with optional context, snapped to complete declarations on request", instrument("read_range", readRangeHandler)); err != nil {
+		return fmt.Errorf("failed to register read_range tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "read_range")
+
+	// Register read_file tool
+	if err := server.RegisterTool("read_file", "Read a file in chunks identified by a hash of their content, which code_edit's replace_chunk replaces even after the lines around them moved", instrument("read_file", readFileHandler)); err != nil {
+		return fmt.Errorf("failed to register read_file tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "read_file")
+
+	// Register set_overlay tool
+	if err := server.RegisterTool("set_overlay", "Analyze unsaved file contents in place of the files on disk until cleared so that proposed edits can be checked before they are written", instrument("set_overlay", setOverlayHandler)); err != nil {
+		return fmt.Errorf("failed to register set_overlay tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "set_overlay")
 
 	// Register code_search tool
-	if err := server.RegisterTool("code_search", "Search through codebase using semantic search", codeSearchHandler); err != nil {
+	if err := server.RegisterTool("code_search", "Search through codebase using semantic search", instrument("code_search", codeSearchHandler)); err != nil {
 		return fmt.Errorf("failed to register code_search tool: %w", err)
 	}
-	log.Printf("Registered code_search tool")
+	slog.Debug("Registered tool", "tool", "code_search")
 
 	// Register code_edit tool
-	if err := server.RegisterTool("code_edit", "Edit code files with AI assistance", codeEditHandler); err != nil {
+	if err := server.RegisterTool("code_edit", "Edit a Go file structurally: add struct fields, methods, imports or declarations, or replace function bodies, with a dry-run diff", instrument("code_edit", codeEditHandler)); err != nil {
 		return fmt.Errorf("failed to register code_edit tool: %w", err)
 	}
-	log.Printf("Registered code_edit tool")
+	slog.Debug("Registered tool", "tool", "code_edit")
+
+	// Register confirm_edit tool
+	if err := server.RegisterTool("confirm_edit", "Write the files edited in a code_edit sandbox to the repository once they build and pass their tests, or discard the sandbox", instrument("confirm_edit", confirmEditHandler)); err != nil {
+		return fmt.Errorf("failed to register confirm_edit tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "confirm_edit")
+
+	// Register extract_interface tool
+	if err := server.RegisterTool("extract_interface", "Generate an interface declaration from the methods of a concrete type with a suggested name and the methods' docs; optionally write it to a package", instrument("extract_interface", extractInterfaceHandler)); err != nil {
+		return fmt.Errorf("failed to register extract_interface tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "extract_interface")
+
+	// Register generate_mock tool
+	if err := server.RegisterTool("generate_mock", "Generate a compilable mock implementation of an interface for tests in a simple function-field or gomock style", instrument("generate_mock", generateMockHandler)); err != nil {
+		return fmt.Errorf("failed to register generate_mock tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "generate_mock")
+
+	// Register add_method tool
+	if err := server.RegisterTool("add_method", "Generate a method stub for a type with the receiver name and kind of its other methods; optionally insert it after the type's last method", instrument("add_method", addMethodHandler)); err != nil {
+		return fmt.Errorf("failed to register add_method tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "add_method")
+
+	// Register generate_constructor tool
+	if err := server.RegisterTool("generate_constructor", "Generate a constructor for a struct with required fields as parameters and optional ones as functional options or a config struct following the package's naming conventions", instrument("generate_constructor", generateConstructorHandler)); err != nil {
+		return fmt.Errorf("failed to register generate_constructor tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "generate_constructor")
 
 	// Register code_review tool
-	if err := server.RegisterTool("code_review", "Review code changes and provide feedback", codeReviewHandler); err != nil {
+	if err := server.RegisterTool("code_review", "Review code changes and provide feedback", instrument("code_review", codeReviewHandler)); err != nil {
 		return fmt.Errorf("failed to register code_review tool: %w", err)
 	}
-	log.Printf("Registered code_review tool")
+	slog.Debug("Registered tool", "tool", "code_review")
+
+	// Register reload_tools tool
+	if err := server.RegisterTool("reload_tools", "Re-read tools.json and register added tools, update changed ones and unregister removed ones without restarting the server", instrument("reload_tools", reloadToolsHandler)); err != nil {
+		return fmt.Errorf("failed to register reload_tools tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "reload_tools")
+
+	// Register run_pipeline tool
+	if err := server.RegisterTool("run_pipeline", "Run a pipeline from tools.json: its tools in order with each step's output feeding the next", instrument("run_pipeline", runPipelineHandler)); err != nil {
+		return fmt.Errorf("failed to register run_pipeline tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "run_pipeline")
+
+	// Register render_report tool
+	if err := server.RegisterTool("render_report", "Render an analysis result with a built-in or user-supplied Go template", instrument("render_report", renderReportHandler)); err != nil {
+		return fmt.Errorf("failed to register render_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "render_report")
+
+	// Register pin_symbol tool
+	if err := server.RegisterTool("pin_symbol", "Pin a symbol so its definition is kept warm, refreshed on file changes and included in summarize", instrument("pin_symbol", pinSymbolHandler)); err != nil {
+		return fmt.Errorf("failed to register pin_symbol tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "pin_symbol")
+
+	// Register unpin_symbol tool
+	if err := server.RegisterTool("unpin_symbol", "Remove a symbol from the pinned working set", instrument("unpin_symbol", unpinSymbolHandler)); err != nil {
+		return fmt.Errorf("failed to register unpin_symbol tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "unpin_symbol")
+
+	// Register explain_symbol tool
+	if err := server.RegisterTool("explain_symbol", "Explain a Go symbol in one Markdown answer: declaration, doc, methods, interfaces, top usages and an example, within a token budget", instrument("explain_symbol", explainSymbolHandler)); err != nil {
+		return fmt.Errorf("failed to register explain_symbol tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "explain_symbol")
+
+	// Register get_package_docs tool
+	if err := server.RegisterTool("get_package_docs", "Return the human-written documentation of packages: package comments (such as doc.go) and README files in package directories; truncated at paragraph boundaries", instrument("get_package_docs", getPackageDocsHandler)); err != nil {
+		return fmt.Errorf("failed to register get_package_docs tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "get_package_docs")
+
+	// Register summarize tool
+	if err := server.RegisterTool("summarize", "Summarize the repository and the definitions pinned in this session", instrument("summarize", summarizeHandler)); err != nil {
+		return fmt.Errorf("failed to register summarize tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "summarize")
+
+	// Register set_session tool
+	if err := server.RegisterTool("set_session", "Set sticky session preferences (default package, exported-only filtering, result limit and response format) that later tool calls use whenever they leave those arguments out", instrument("set_session", setSessionHandler)); err != nil {
+		return fmt.Errorf("failed to register set_session tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "set_session")
 
-	log.Printf("Successfully registered %d tools", 6)
+	// Register who_owns tool
+	if err := server.RegisterTool("who_owns", "Return the CODEOWNERS owners of a file, symbol or package", instrument("who_owns", whoOwnsHandler)); err != nil {
+		return fmt.Errorf("failed to register who_owns tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "who_owns")
+
+	// Register annotate_symbol tool
+	if err := server.RegisterTool("annotate_symbol", "Attach a persistent note to a package, type, function or member", instrument("annotate_symbol", annotateSymbolHandler)); err != nil {
+		return fmt.Errorf("failed to register annotate_symbol tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "annotate_symbol")
+
+	// Register get_annotations tool
+	if err := server.RegisterTool("get_annotations", "Return the notes attached to a symbol, or all notes", instrument("get_annotations", getAnnotationsHandler)); err != nil {
+		return fmt.Errorf("failed to register get_annotations tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "get_annotations")
+
+	// Register generate_architecture tool
+	if err := server.RegisterTool("generate_architecture", "Cluster packages into components and render a Mermaid or DOT component diagram", instrument("generate_architecture", generateArchitectureHandler)); err != nil {
+		return fmt.Errorf("failed to register generate_architecture tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "generate_architecture")
+
+	// Register boundaries tool
+	if err := server.RegisterTool("boundaries", "List imports violating Go's internal package rule or the private packages of .scope/architecture.json, and with planned moves the imports that compile today but would break once packages move", instrument("boundaries", boundariesHandler)); err != nil {
+		return fmt.Errorf("failed to register boundaries tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "boundaries")
+
+	// Register binaries tool
+	if err := server.RegisterTool("binaries", "List the main packages of the repository with the flags they define (flag, pflag and cobra, urfave/cli), the environment variables they read with os.Getenv and the repository packages and dependencies they import", instrument("binaries", binariesHandler)); err != nil {
+		return fmt.Errorf("failed to register binaries tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "binaries")
+
+	// Register code_metrics tool
+	if err := server.RegisterTool("code_metrics", "Compute per-package coupling (afferent and efferent; instability), abstractness, distance from the main sequence and relational cohesion for architecture reviews", instrument("code_metrics", codeMetricsHandler)); err != nil {
+		return fmt.Errorf("failed to register code_metrics tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "code_metrics")
+
+	// Register repo_inventory tool
+	if err := server.RegisterTool("repo_inventory", "Summarize the repository's files of every language (Go, SQL, proto, YAML, shell and more) by count and size per language and top-level directory, with the largest and most recently changed files", instrument("repo_inventory", repoInventoryHandler)); err != nil {
+		return fmt.Errorf("failed to register repo_inventory tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "repo_inventory")
+
+	// Register grpc_map tool
+	if err := server.RegisterTool("grpc_map", "Map the messages, enums and services of the repository's .proto files to their generated Go types and link each RPC to the server types implementing it", instrument("grpc_map", grpcMapHandler)); err != nil {
+		return fmt.Errorf("failed to register grpc_map tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "grpc_map")
+
+	// Register embeds tool
+	if err := server.RegisterTool("embeds", "List the //go:embed directives of each package with the variable, its type, the patterns and the files they embed, flagging patterns that match no files and other directives go build would reject", instrument("embeds", embedsHandler)); err != nil {
+		return fmt.Errorf("failed to register embeds tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "embeds")
+
+	// Register find_dead_config tool
+	if err := server.RegisterTool("find_dead_config", "Find constants and never-changed variables that fix conditions, and the branches that can therefore never execute", instrument("find_dead_config", findDeadConfigHandler)); err != nil {
+		return fmt.Errorf("failed to register find_dead_config tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "find_dead_config")
+
+	// Register error_paths tool
+	if err := server.RegisterTool("error_paths", "Report where a function returns errors (wrapped, created or propagated), where it discards them, and the panics reachable from it", instrument("error_paths", errorPathsHandler)); err != nil {
+		return fmt.Errorf("failed to register error_paths tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "error_paths")
+
+	// Register error_taxonomy tool
+	if err := server.RegisterTool("error_taxonomy", "Inventory sentinel errors and error types and wrap sites with a graph of which errors wrap which and comparisons that should use errors.Is", instrument("error_taxonomy", errorTaxonomyHandler)); err != nil {
+		return fmt.Errorf("failed to register error_taxonomy tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "error_taxonomy")
+
+	// Register interface_usage tool
+	if err := server.RegisterTool("interface_usage", "List every parameter, result, struct field and variable whose type is a given interface", instrument("interface_usage", interfaceUsageHandler)); err != nil {
+		return fmt.Errorf("failed to register interface_usage tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "interface_usage")
+
+	// Register analyze_interface tool
+	if err := server.RegisterTool("analyze_interface", "Find which methods of an interface its consumers call and suggest smaller interfaces with code for them", instrument("analyze_interface", analyzeInterfaceHandler)); err != nil {
+		return fmt.Errorf("failed to register analyze_interface tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "analyze_interface")
+
+	// Register list_deprecated tool
+	if err := server.RegisterTool("list_deprecated", "List symbols marked Deprecated: in their doc comments with the call sites that still use them", instrument("list_deprecated", listDeprecatedHandler)); err != nil {
+		return fmt.Errorf("failed to register list_deprecated tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "list_deprecated")
+
+	// Register plan_migration tool
+	if err := server.RegisterTool("plan_migration", "Group the remaining uses of deprecated symbols by the replacement their deprecation notes suggest, in per-package stages", instrument("plan_migration", planMigrationHandler)); err != nil {
+		return fmt.Errorf("failed to register plan_migration tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "plan_migration")
+
+	// Register dep_usage tool
+	if err := server.RegisterTool("dep_usage", "List every symbol of an imported module or package the repository uses and where; for planning dependency migrations", instrument("dep_usage", depUsageHandler)); err != nil {
+		return fmt.Errorf("failed to register dep_usage tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "dep_usage")
+
+	// Register modernize tool
+	if err := server.RegisterTool("modernize", "Find uses of deprecated standard library APIs and outdated patterns (ioutil; strings.Title; rand.Seed; interface{}; golang.org/x/exp/slices) with per-site rewrites; optionally apply them", instrument("modernize", modernizeHandler)); err != nil {
+		return fmt.Errorf("failed to register modernize tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "modernize")
+
+	// Register list_enums tool
+	if err := server.RegisterTool("list_enums", "List enums (typed constant groups and iota blocks) with their values and whether they have a String method", instrument("list_enums", listEnumsHandler)); err != nil {
+		return fmt.Errorf("failed to register list_enums tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "list_enums")
+
+	// Register check_marshalers tool
+	if err := server.RegisterTool("check_marshalers", "Report which exported types implement fmt.Stringer, json.Marshaler and Unmarshaler, encoding.TextMarshaler, sql.Scanner, driver.Valuer and the other printing and serialization interfaces, as a matrix for serialization audits, flagging decoders on value receivers and encoders without their decoders", instrument("check_marshalers", checkMarshalersHandler)); err != nil {
+		return fmt.Errorf("failed to register check_marshalers tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "check_marshalers")
+
+	// Register type_report tool
+	if err := server.RegisterTool("type_report", "Report per-type method counts, method lines, fields, fan-in and fan-out, flagging god objects that exceed configurable thresholds", instrument("type_report", typeReportHandler)); err != nil {
+		return fmt.Errorf("failed to register type_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "type_report")
+
+	// Register api_diff tool
+	if err := server.RegisterTool("api_diff", "Compare the exported API against a git revision or a snapshot and report breaking changes (removed symbols, changed signatures, narrowed interfaces)", instrument("api_diff", apiDiffHandler)); err != nil {
+		return fmt.Errorf("failed to register api_diff tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "api_diff")
+
+	// Register release_report tool
+	if err := server.RegisterTool("release_report", "Summarize the exported API changes between two git tags as changelog-ready markdown: removals and signature and struct field changes and additions", instrument("release_report", releaseReportHandler)); err != nil {
+		return fmt.Errorf("failed to register release_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "release_report")
+
+	// Register run_tests tool
+	if err := server.RegisterTool("run_tests", "Run go test for a package or test name pattern and return structured pass/fail results with failure output, durations and coverage", instrument("run_tests", runTestsHandler)); err != nil {
+		return fmt.Errorf("failed to register run_tests tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "run_tests")
+
+	// Register check_build tool
+	if err := server.RegisterTool("check_build", "Compile packages with go build and check them with go vet, returning structured diagnostics (file, line, message, analyzer)", instrument("check_build", checkBuildHandler)); err != nil {
+		return fmt.Errorf("failed to register check_build tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "check_build")
+
+	// Register security_scan tool
+	if err := server.RegisterTool("security_scan", "Run security checks (hardcoded credentials, math/rand used for secrets, commands built from input, SQL string concatenation, insecure TLS) and return findings with severity and remediation hints", instrument("security_scan", securityScanHandler)); err != nil {
+		return fmt.Errorf("failed to register security_scan tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "security_scan")
+
+	// Register concurrency_report tool
+	if err := server.RegisterTool("concurrency_report", "Report goroutine launches, channel operations and sync primitive usage per package, flagging loop variable capture before Go 1.22, unbuffered channels in selects with default and WaitGroup.Add without Done", instrument("concurrency_report", concurrencyReportHandler)); err != nil {
+		return fmt.Errorf("failed to register concurrency_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "concurrency_report")
+
+	// Register ctx_check tool
+	if err := server.RegisterTool("ctx_check", "Flag context.Context misuse: contexts stored in structs, context.Background in functions that receive a ctx, loops making network, database or subprocess calls without checking ctx, and exported functions making such calls without a ctx parameter", instrument("ctx_check", contextCheckHandler)); err != nil {
+		return fmt.Errorf("failed to register ctx_check tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "ctx_check")
+
+	// Register globals_report tool
+	if err := server.RegisterTool("globals_report", "List package-level variables and init functions with the writes to each variable from anywhere in the repository and the package initialization order; flags variables written from more than one package", instrument("globals_report", globalsReportHandler)); err != nil {
+		return fmt.Errorf("failed to register globals_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "globals_report")
+
+	// Register dynamic_typing_report tool
+	if err := server.RegisterTool("dynamic_typing_report", "List type assertions and type switches and calls into package reflect with positions and the asserted types; flags unchecked single-result assertions that panic on a mismatch", instrument("dynamic_typing_report", dynamicTypingReportHandler)); err != nil {
+		return fmt.Errorf("failed to register dynamic_typing_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "dynamic_typing_report")
+
+	// Register profile_report tool
+	if err := server.RegisterTool("profile_report", "Read a pprof CPU or heap profile and return its hottest functions with flat and cumulative values; the repository's functions come with their positions and docs", instrument("profile_report", profileReportHandler)); err != nil {
+		return fmt.Errorf("failed to register profile_report tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "profile_report")
+
+	// Register parse_diagnostics tool
+	if err := server.RegisterTool("parse_diagnostics", "List files with syntax errors whose declarations are partly or wholly missing from the analysis, and uses of language features newer than the configured Go version", instrument("parse_diagnostics", parseDiagnosticsHandler)); err != nil {
+		return fmt.Errorf("failed to register parse_diagnostics tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "parse_diagnostics")
+
+	// Register continue_response tool
+	if err := server.RegisterTool("continue_response", "Return the next part of a tool result that was truncated for exceeding the response size limit", instrument("continue_response", continueResponseHandler)); err != nil {
+		return fmt.Errorf("failed to register continue_response tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "continue_response")
+
+	// Register server_status tool
+	if err := server.RegisterTool("server_status", "Report the state of the analyzer, the cache, memory usage and the registered tools, to tell whether answers are stale", instrument("server_status", serverStatusHandler)); err != nil {
+		return fmt.Errorf("failed to register server_status tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "server_status")
+
+	// Register refresh_repo tool
+	if err := server.RegisterTool("refresh_repo", "Re-analyze the repository; when it was cloned from a git URL, first fetch the latest commit of its branch or tag", instrument("refresh_repo", refreshRepoHandler)); err != nil {
+		return fmt.Errorf("failed to register refresh_repo tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "refresh_repo")
+
+	// Register get_schemas tool
+	if err := server.RegisterTool("get_schemas", "Get JSON Schemas of tool outputs and result types such as TypeInfo and AnalysisResult; every response carries the schema_version they describe", instrument("get_schemas", getSchemasHandler)); err != nil {
+		return fmt.Errorf("failed to register get_schemas tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "get_schemas")
+
+	// Register batch tool
+	if err := server.RegisterTool("batch", "Run several tool calls concurrently and return their results in order; cuts round trips for many small lookups", instrument("batch", batchHandler)); err != nil {
+		return fmt.Errorf("failed to register batch tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "batch")
+
+	// Register start_job tool
+	if err := server.RegisterTool("start_job", "Run a tool call in the background and return a job ID at once; for analyses that take minutes such as render_report or code_search on large repositories", instrument("start_job", startJobHandler)); err != nil {
+		return fmt.Errorf("failed to register start_job tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "start_job")
+
+	// Register job_status tool
+	if err := server.RegisterTool("job_status", "Get the state and progress of a background job, or list every job", instrument("job_status", jobStatusHandler)); err != nil {
+		return fmt.Errorf("failed to register job_status tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "job_status")
+
+	// Register job_result tool
+	if err := server.RegisterTool("job_result", "Get the response of a finished background job as the tool returned it, optionally waiting for the job to finish", instrument("job_result", jobResultHandler)); err != nil {
+		return fmt.Errorf("failed to register job_result tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "job_result")
+
+	// Register cancel_job tool
+	if err := server.RegisterTool("cancel_job", "Cancel a queued or running background job", instrument("cancel_job", cancelJobHandler)); err != nil {
+		return fmt.Errorf("failed to register cancel_job tool: %w", err)
+	}
+	slog.Debug("Registered tool", "tool", "cancel_job")
+
+	// The gopls bridge adds tools the in-process analyzer cannot answer
+	if lspBridge != nil {
+		if err := server.RegisterTool("find_usages", "Find every reference to a Go symbol using gopls", instrument("find_usages", findUsagesHandler)); err != nil {
+			return fmt.Errorf("failed to register find_usages tool: %w", err)
+		}
+		slog.Debug("Registered tool", "tool", "find_usages")
+
+		if err := server.RegisterTool("rename", "Rename a Go symbol across the workspace using gopls", instrument("rename", renameHandler)); err != nil {
+			return fmt.Errorf("failed to register rename tool: %w", err)
+		}
+		slog.Debug("Registered tool", "tool", "rename")
+	}
+
+	slog.Info("Registered tools", "count", len(registeredTools))
 	return nil
 }
 
+// analysisNamespace returns the cache key prefix for analysis results of a
+// repository at the current result schema version
+func analysisNamespace(repoPath string) string {
+	return fmt.Sprintf("%sv%d/", cache.RepoNamespace(repoPath), analyzer.SchemaVersion)
+}
+
+// dropStaleEntries evicts the cache entries this server never reads: keys
+// written before entries were namespaced, and the repository's analysis
+// results under any other schema version. Other repositories' entries and
+// the repository's notes are kept.
+func dropStaleEntries(repoPath string) {
+	repo, current := cache.RepoNamespace(repoPath), analysisNamespace(repoPath)
+	removed, err := cacheInstance.InvalidateFunc(context.Background(), func(key string) bool {
+		if !cache.InRepoNamespace(key) {
+			return true
+		}
+		rest, ok := strings.CutPrefix(key, repo)
+		return ok && isSchemaVersion(rest) && !strings.HasPrefix(key, current)
+	})
+	if err != nil {
+		slog.Warn("Failed to evict stale cache entries", "error", err)
+	} else if removed > 0 {
+		slog.Info("Evicted stale cache entries", "count", removed, "schema_version", analyzer.SchemaVersion)
+	}
+}
+
+// isSchemaVersion reports whether a key within a repository's namespace
+// starts with a schema version segment such as v3/
+func isSchemaVersion(key string) bool {
+	version, _, ok := strings.Cut(key, "/")
+	if !ok || len(version) < 2 || version[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(version[1:])
+	return err == nil
+}
+
+// cacheKey builds the namespaced cache key for an analysis result
+func cacheKey(kind, name string) string {
+	return cacheNamespace + kind + ":" + name
+}
+
+// cachedResult returns a cached analysis result decoded as T unless the
+// analyzed sources changed after it was written
+func cachedResult[T any](ctx context.Context, key string) (T, bool) {
+	return cache.NewTyped[T](cacheInstance).GetFresh(ctx, key, analyzerInstance.LastChange())
+}
+
+// invalidateAnalysisCache evicts every cached analysis result for the repository
+func invalidateAnalysisCache(ctx context.Context) {
+	if _, err := cacheInstance.InvalidatePrefix(ctx, cacheNamespace); err != nil {
+		slog.WarnContext(ctx, "Failed to invalidate cache", "error", err)
+	}
+}
+
 type LookupTypeArgs struct {
-	TypeName string `json:"type_name" jsonschema:"required,description=The name of the Go type"`
+	TypeName string `json:"type_name" jsonschema:"required,description=The name of the Go type; qualify it as pkg.Type or import/path.Type when the name is ambiguous"`
+	ResponseBudget
 }
 
-func lookupTypeHandler(args LookupTypeArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Looking up type: %s", args.TypeName)
+func lookupTypeHandler(ctx context.Context, args LookupTypeArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Looking up type", "type", args.TypeName)
 	// Check cache first
-	if cached, found := cacheInstance.Get(fmt.Sprintf("type:%s", args.TypeName)); found {
-		if typeInfo, ok := cached.(*analyzer.TypeInfo); ok {
-			jsonData, err := json.Marshal(typeInfo)
+	if typeInfo, found := cachedResult[*analyzer.TypeInfo](ctx, cacheKey("type", args.TypeName)); found {
+		jsonData, err := json.Marshal(typeInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal type info: %w", err)
+		}
+		return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+	}
+
+	// Not in cache, look it up
+	start := time.Now()
+	typeInfo, err := analyzerInstance.LookupType(ctx, args.TypeName)
+	metrics.AnalyzerDuration.ObserveDuration(start, "lookup_type")
+	var ambiguous *analyzer.AmbiguousError
+	if err != nil && lspBridge != nil && !errors.As(err, &ambiguous) {
+		slog.InfoContext(ctx, "Analyzer lookup failed; asking gopls", "error", err)
+		typeInfo, err = lookupTypeViaLSP(ctx, args.TypeName)
+	}
+	if err != nil && proxyDocs != nil && !errors.As(err, &ambiguous) {
+		sym, proxyErr := lookupViaProxy(ctx, args.TypeName)
+		if proxyErr == nil {
+			jsonData, err := json.Marshal(sym)
 			if err != nil {
-				return nil, fmt.Errorf("failed to marshal type info: %w", err)
+				return nil, fmt.Errorf("failed to marshal external symbol: %w", err)
 			}
 			return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
 		}
+		slog.InfoContext(ctx, "Module proxy lookup failed", "error", proxyErr)
 	}
-
-	// Not in cache, look it up
-	typeInfo, err := analyzerInstance.LookupType(args.TypeName)
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result
-	if err := cacheInstance.Set(fmt.Sprintf("type:%s", args.TypeName), typeInfo, 24*time.Hour); err != nil {
-		log.Printf("Warning: failed to cache type info: %v", err)
+	if err := cacheInstance.Set(ctx, cacheKey("type", args.TypeName), typeInfo, 24*time.Hour); err != nil {
+		slog.WarnContext(ctx, "Failed to cache type info", "error", err)
 	}
 
 	jsonData, err := json.Marshal(typeInfo)
@@ -189,31 +1064,32 @@ func lookupTypeHandler(args LookupTypeArgs) (*mcp.ToolResponse, error) {
 }
 
 type ListMethodsArgs struct {
-	TypeName string `json:"type_name" jsonschema:"required,description=Name of the type"`
+	TypeName string `json:"type_name" jsonschema:"required,description=Name of the type; qualify it as pkg.Type or import/path.Type when the name is ambiguous"`
+	ResponseBudget
 }
 
-func listMethodsHandler(args ListMethodsArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Listing methods for type: %s", args.TypeName)
+func listMethodsHandler(ctx context.Context, args ListMethodsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing methods", "type", args.TypeName)
 	// Check cache first
-	if cached, found := cacheInstance.Get(fmt.Sprintf("methods:%s", args.TypeName)); found {
-		if methods, ok := cached.([]string); ok {
-			jsonData, err := json.Marshal(methods)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal methods: %w", err)
-			}
-			return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+	if methods, found := cachedResult[[]analyzer.MethodInfo](ctx, cacheKey("methods", args.TypeName)); found {
+		jsonData, err := json.Marshal(methods)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal methods: %w", err)
 		}
+		return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
 	}
 
 	// Not in cache, look it up
-	methods, err := analyzerInstance.ListMethods(args.TypeName)
+	start := time.Now()
+	methods, err := analyzerInstance.ListMethods(ctx, args.TypeName)
+	metrics.AnalyzerDuration.ObserveDuration(start, "list_methods")
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result
-	if err := cacheInstance.Set(fmt.Sprintf("methods:%s", args.TypeName), methods, 24*time.Hour); err != nil {
-		log.Printf("Warning: failed to cache methods: %v", err)
+	if err := cacheInstance.Set(ctx, cacheKey("methods", args.TypeName), methods, 24*time.Hour); err != nil {
+		slog.WarnContext(ctx, "Failed to cache methods", "error", err)
 	}
 
 	jsonData, err := json.Marshal(methods)
@@ -223,87 +1099,89 @@ func listMethodsHandler(args ListMethodsArgs) (*mcp.ToolResponse, error) {
 	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
 }
 
-type ShowExampleArgs struct {
-	Topic string `json:"topic" jsonschema:"required,description=What to show an example for"`
+type TypeHierarchyArgs struct {
+	TypeName string `json:"type_name" jsonschema:"required,description=Name of the type; qualify it as pkg.Type or import/path.Type when the name is ambiguous"`
+	ResponseBudget
 }
 
-func showExampleHandler(args ShowExampleArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Showing example for topic: %s", args.Topic)
+func typeHierarchyHandler(ctx context.Context, args TypeHierarchyArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Building type hierarchy", "type", args.TypeName)
 	// Check cache first
-	if cached, found := cacheInstance.Get(fmt.Sprintf("example:%s", args.Topic)); found {
-		if example, ok := cached.(string); ok {
-			return mcp.NewToolResponse(mcp.NewTextContent(example)), nil
+	if hierarchy, found := cachedResult[*analyzer.HierarchyInfo](ctx, cacheKey("hierarchy", args.TypeName)); found {
+		jsonData, err := json.Marshal(hierarchy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal type hierarchy: %w", err)
 		}
+		return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
 	}
 
-	// Not in cache, look it up
-	example, err := analyzerInstance.GetExample(args.Topic)
+	// Not in cache, build it
+	start := time.Now()
+	hierarchy, err := analyzerInstance.TypeHierarchy(ctx, args.TypeName)
+	metrics.AnalyzerDuration.ObserveDuration(start, "type_hierarchy")
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result
-	if err := cacheInstance.Set(fmt.Sprintf("example:%s", args.Topic), example, 24*time.Hour); err != nil {
-		log.Printf("Warning: failed to cache example: %v", err)
+	if err := cacheInstance.Set(ctx, cacheKey("hierarchy", args.TypeName), hierarchy, 24*time.Hour); err != nil {
+		slog.WarnContext(ctx, "Failed to cache type hierarchy", "error", err)
 	}
 
-	return mcp.NewToolResponse(mcp.NewTextContent(example)), nil
-}
-
-type CodeSearchArgs struct {
-	Query string `json:"query" jsonschema:"required,description=The search query"`
-}
-
-func codeSearchHandler(args CodeSearchArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Executing code search: %s", args.Query)
-	tool, ok := toolManager.GetTool("code_search")
-	if !ok {
-		return nil, fmt.Errorf("code_search tool not found")
-	}
-
-	output, err := tool.Execute(context.Background(), args.Query)
+	jsonData, err := json.Marshal(hierarchy)
 	if err != nil {
-		return nil, fmt.Errorf("code search failed: %w", err)
+		return nil, fmt.Errorf("failed to marshal type hierarchy: %w", err)
 	}
-
-	return mcp.NewToolResponse(mcp.NewTextContent(output)), nil
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
 }
 
-type CodeEditArgs struct {
-	File    string `json:"file" jsonschema:"required,description=The file to edit"`
-	Changes string `json:"changes" jsonschema:"required,description=The changes to apply"`
+type ShowExampleArgs struct {
+	Topic string `json:"topic" jsonschema:"required,description=What to show an example for"`
+	ResponseBudget
 }
 
-func codeEditHandler(args CodeEditArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Executing code edit for file: %s", args.File)
-	tool, ok := toolManager.GetTool("code_edit")
-	if !ok {
-		return nil, fmt.Errorf("code_edit tool not found")
+func showExampleHandler(ctx context.Context, args ShowExampleArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Showing example", "topic", args.Topic)
+	// Check cache first
+	if example, found := cachedResult[string](ctx, cacheKey("example", args.Topic)); found {
+		return mcp.NewToolResponse(mcp.NewTextContent(example)), nil
 	}
 
-	input := fmt.Sprintf("%s\n%s", args.File, args.Changes)
-	output, err := tool.Execute(context.Background(), input)
+	// Not in cache, look it up
+	start := time.Now()
+	example, err := analyzerInstance.GetExample(ctx, args.Topic)
+	metrics.AnalyzerDuration.ObserveDuration(start, "get_example")
 	if err != nil {
-		return nil, fmt.Errorf("code edit failed: %w", err)
+		return nil, err
 	}
 
-	return mcp.NewToolResponse(mcp.NewTextContent(output)), nil
+	// Cache the result
+	if err := cacheInstance.Set(ctx, cacheKey("example", args.Topic), example, 24*time.Hour); err != nil {
+		slog.WarnContext(ctx, "Failed to cache example", "error", err)
+	}
+
+	return mcp.NewToolResponse(mcp.NewTextContent(example)), nil
 }
 
-type CodeReviewArgs struct {
-	Changes string `json:"changes" jsonschema:"required,description=The code changes to review"`
+type CodeSearchArgs struct {
+	Query string `json:"query" jsonschema:"required,description=The search query"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Maximum number of declarations returned by the built-in semantic index (default 10)"`
+	ResponseBudget
 }
 
-func codeReviewHandler(args CodeReviewArgs) (*mcp.ToolResponse, error) {
-	log.Printf("Executing code review")
-	tool, ok := toolManager.GetTool("code_review")
+func codeSearchHandler(ctx context.Context, args CodeSearchArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Executing code search", "query", args.Query)
+	if semanticIndex != nil {
+		return semanticSearchHandler(ctx, args)
+	}
+	tool, ok := toolManager.GetTool("code_search")
 	if !ok {
-		return nil, fmt.Errorf("code_review tool not found")
+		return nil, fmt.Errorf("code_search tool not found")
 	}
 
-	output, err := tool.Execute(context.Background(), args.Changes)
+	output, err := tool.Execute(ctx, args.Query)
 	if err != nil {
-		return nil, fmt.Errorf("code review failed: %w", err)
+		return nil, fmt.Errorf("code search failed: %w", err)
 	}
 
 	return mcp.NewToolResponse(mcp.NewTextContent(output)), nil
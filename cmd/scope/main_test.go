@@ -1,15 +1,35 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/TFMV/scope/internal/analyzer"
 	"github.com/TFMV/scope/internal/cache"
+	"github.com/TFMV/scope/internal/logging"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/notes"
+	"github.com/TFMV/scope/internal/report"
+	"github.com/TFMV/scope/internal/session"
 )
 
 func TestMain(m *testing.M) {
+	// Git hooks installed by the tests run the test binary as scope
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	// Set up test environment
 	tempDir, err := os.MkdirTemp("", "featherhead-test")
 	if err != nil {
@@ -36,6 +56,11 @@ type TestStruct struct {
 func (t *TestStruct) TestMethod() string {
 	return t.Field
 }
+
+// NewTestStruct returns a TestStruct with Field set
+func NewTestStruct(field string) *TestStruct {
+	return &TestStruct{Field: field}
+}
 `
 	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
 		panic(err)
@@ -56,6 +81,15 @@ func (t *TestStruct) TestMethod() string {
 		panic(err2)
 	}
 
+	pinSet = session.NewPinSet(analyzerInstance)
+	cacheNamespace = analysisNamespace(pkgDir)
+	noteStore = notes.NewStore(cacheInstance, cache.RepoNamespace(pkgDir), notes.FilePath(tempDir))
+
+	rendererInstance, err2 = report.NewRenderer()
+	if err2 != nil {
+		panic(err2)
+	}
+
 	// Run tests
 	code := m.Run()
 
@@ -70,7 +104,7 @@ func TestLookupTypeHandler(t *testing.T) {
 		TypeName: "TestStruct",
 	}
 
-	response, err := lookupTypeHandler(args)
+	response, err := lookupTypeHandler(context.Background(), args)
 	if err != nil {
 		t.Errorf("lookupTypeHandler failed: %v", err)
 	}
@@ -78,6 +112,22 @@ func TestLookupTypeHandler(t *testing.T) {
 	if response == nil {
 		t.Error("response should not be nil")
 	}
+
+	key := cacheKey("type", "TestStruct")
+	if !strings.HasPrefix(key, cacheNamespace) || cacheNamespace == "" {
+		t.Errorf("Expected namespaced key, got %s", key)
+	}
+	if _, found := cacheInstance.Get(context.Background(), key); !found {
+		t.Error("Expected lookup result to be cached")
+	}
+	if _, found := cachedResult[*analyzer.TypeInfo](context.Background(), key); !found {
+		t.Error("Expected cached result to be fresh")
+	}
+
+	invalidateAnalysisCache(context.Background())
+	if _, found := cacheInstance.Get(context.Background(), key); found {
+		t.Error("Expected cached result to be invalidated")
+	}
 }
 
 func TestListMethodsHandler(t *testing.T) {
@@ -85,7 +135,7 @@ func TestListMethodsHandler(t *testing.T) {
 		TypeName: "TestStruct",
 	}
 
-	response, err := listMethodsHandler(args)
+	response, err := listMethodsHandler(context.Background(), args)
 	if err != nil {
 		t.Errorf("listMethodsHandler failed: %v", err)
 	}
@@ -95,12 +145,22 @@ func TestListMethodsHandler(t *testing.T) {
 	}
 }
 
+func TestTypeHierarchyHandler(t *testing.T) {
+	response, err := typeHierarchyHandler(context.Background(), TypeHierarchyArgs{TypeName: "TestStruct"})
+	if err != nil {
+		t.Fatalf("typeHierarchyHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"name":"TestStruct"`) {
+		t.Errorf("Expected TestStruct in hierarchy, got %s", text)
+	}
+}
+
 func TestShowExampleHandler(t *testing.T) {
 	args := ShowExampleArgs{
 		Topic: "TestStruct",
 	}
 
-	response, err := showExampleHandler(args)
+	response, err := showExampleHandler(context.Background(), args)
 	if err != nil {
 		t.Errorf("showExampleHandler failed: %v", err)
 	}
@@ -109,3 +169,117 @@ func TestShowExampleHandler(t *testing.T) {
 		t.Error("response should not be nil")
 	}
 }
+
+func TestInstrumentRecordsInvocations(t *testing.T) {
+	handler := instrument("lookup_type_test", lookupTypeHandler)
+
+	if _, err := handler(context.Background(), LookupTypeArgs{TypeName: "TestStruct"}); err != nil {
+		t.Fatalf("instrumented handler failed: %v", err)
+	}
+	if _, err := handler(context.Background(), LookupTypeArgs{TypeName: "DoesNotExist"}); err == nil {
+		t.Error("expected error for unknown type")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := handler(ctx, LookupTypeArgs{TypeName: "TestStruct"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled lookup, got %v", err)
+	}
+
+	if got := metrics.ToolInvocations.Value("lookup_type_test", "ok"); got != 1 {
+		t.Errorf("expected 1 successful invocation, got %v", got)
+	}
+	if got := metrics.ToolInvocations.Value("lookup_type_test", "error"); got != 1 {
+		t.Errorf("expected 1 failed invocation, got %v", got)
+	}
+	if got := metrics.ToolInvocations.Value("lookup_type_test", "cancelled"); got != 1 {
+		t.Errorf("expected 1 cancelled invocation, got %v", got)
+	}
+	if got := metrics.ToolDuration.Count("lookup_type_test"); got != 3 {
+		t.Errorf("expected 3 duration observations, got %d", got)
+	}
+}
+
+func TestInstrumentLogsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, "debug")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	handler := instrument("lookup_type_request_id_test", lookupTypeHandler)
+	_, err = handler(context.Background(), LookupTypeArgs{TypeName: "DoesNotExist"})
+	if err == nil {
+		t.Fatal("Expected error for unknown type")
+	}
+	_, id, ok := strings.Cut(err.Error(), "(request_id: ")
+	id = strings.TrimSuffix(id, ")")
+	if !ok || id == "" {
+		t.Fatalf("Expected the request ID in the error, got %v", err)
+	}
+
+	// Every record of the call carries the ID
+	var records int
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse record: %v", err)
+		}
+		if record[logging.RequestIDKey] != id {
+			t.Errorf("Expected request ID %s in %v", id, record)
+		}
+		records++
+	}
+	if records < 2 || !strings.Contains(buf.String(), `"msg":"Tool call failed"`) {
+		t.Errorf("Expected the lookup and its failure to be logged, got %s", buf.String())
+	}
+
+	// A batch's calls share its request ID
+	ctx := logging.WithRequestID(context.Background(), "batch")
+	if _, err := handler(ctx, LookupTypeArgs{TypeName: "DoesNotExist"}); err == nil || !strings.HasSuffix(err.Error(), "(request_id: batch)") {
+		t.Errorf("Expected the caller's request ID, got %v", err)
+	}
+}
+
+func TestDropStaleEntries(t *testing.T) {
+	previous := cacheInstance
+	defer func() { cacheInstance = previous }()
+	var err error
+	if cacheInstance, err = cache.Open(cache.BackendMemory, ""); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+
+	repoPath := analyzerInstance.RepoPath()
+	repo := cache.RepoNamespace(repoPath)
+	keep := []string{
+		analysisNamespace(repoPath) + "type:TestStruct",
+		repo + "notes",
+		cache.RepoNamespace("/elsewhere") + "v1/type:Other",
+	}
+	drop := []string{
+		"type:TestStruct",
+		"methods:TestStruct",
+		fmt.Sprintf("%sv%d/type:TestStruct", repo, analyzer.SchemaVersion-1),
+		fmt.Sprintf("%sv%d/type:TestStruct", repo, analyzer.SchemaVersion+1),
+	}
+	ctx := context.Background()
+	for _, key := range append(keep, drop...) {
+		if err := cacheInstance.Set(ctx, key, "value", time.Hour); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	dropStaleEntries(repoPath)
+	for _, key := range keep {
+		if _, found := cacheInstance.Get(ctx, key); !found {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+	for _, key := range drop {
+		if _, found := cacheInstance.Get(ctx, key); found {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type CheckMarshalersArgs struct {
+	Package          string   `json:"package,omitempty" jsonschema:"description=Only check types declared in this package (import path or package name); omit for all packages" session:"package"`
+	Interfaces       []string `json:"interfaces,omitempty" jsonschema:"description=Only check these interfaces; e.g. json.Marshaler and json.Unmarshaler; omit for all of fmt.Stringer; fmt.GoStringer; error; json; encoding; gob; sql.Scanner and driver.Valuer"`
+	ImplementingOnly bool     `json:"implementing_only,omitempty" jsonschema:"description=Only list types implementing at least one of the interfaces"`
+	IssuesOnly       bool     `json:"issues_only,omitempty" jsonschema:"description=Only list types with issues; such as a decoding method on a value receiver"`
+	ResponseBudget
+}
+
+func checkMarshalersHandler(ctx context.Context, args CheckMarshalersArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Checking marshalers", "package", args.Package, "interfaces", args.Interfaces)
+	start := time.Now()
+	report, err := analyzerInstance.CheckMarshalers(ctx, args.Package, args.Interfaces)
+	metrics.AnalyzerDuration.ObserveDuration(start, "check_marshalers")
+	if err != nil {
+		return nil, err
+	}
+
+	if args.ImplementingOnly || args.IssuesOnly {
+		selected := []analyzer.MarshalerType{}
+		for _, row := range report.Types {
+			if (!args.ImplementingOnly || len(row.Implements) > 0) && (!args.IssuesOnly || len(row.Issues) > 0) {
+				selected = append(selected, row)
+			}
+		}
+		report.Types = selected
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal marshaler report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestCheckMarshalersHandler(t *testing.T) {
+	response, err := checkMarshalersHandler(context.Background(), CheckMarshalersArgs{Package: "testpkg"})
+	if err != nil {
+		t.Fatalf("checkMarshalersHandler failed: %v", err)
+	}
+	var report analyzer.MarshalerReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Types) != 1 || report.Types[0].Name != "testpkg.TestStruct" || len(report.Types[0].Implements) != 0 {
+		t.Errorf("Expected TestStruct implementing nothing, got %+v", report.Types)
+	}
+
+	// TestStruct implements none of the interfaces
+	response, err = checkMarshalersHandler(context.Background(), CheckMarshalersArgs{ImplementingOnly: true})
+	if err != nil {
+		t.Fatalf("checkMarshalersHandler failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Types) != 0 {
+		t.Errorf("Expected no implementing types, got %+v", report.Types)
+	}
+
+	if _, err := checkMarshalersHandler(context.Background(), CheckMarshalersArgs{Interfaces: []string{"Stringer"}}); err == nil {
+		t.Error("Expected an unknown interface to fail")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type AddMethodArgs struct {
+	Type      string `json:"type" jsonschema:"required,description=Type to add the method to (Name or pkg.Name)"`
+	Name      string `json:"name,omitempty" jsonschema:"description=Name of the method; may be given in the signature instead"`
+	Signature string `json:"signature" jsonschema:"required,description=Parameters and results of the method such as (ctx context.Context) error; a leading func keyword and method name are allowed"`
+	Doc       string `json:"doc,omitempty" jsonschema:"description=Doc comment of the method without the comment markers"`
+	Receiver  string `json:"receiver,omitempty" jsonschema:"enum=pointer,enum=value,description=Receiver kind; defaults to that of the type's other methods"`
+	Write     bool   `json:"write,omitempty" jsonschema:"description=Insert the method after the type's last method in its file"`
+	DryRun    bool   `json:"dry_run,omitempty" jsonschema:"description=Only return the diff of inserting the method"`
+	ResponseBudget
+}
+
+// AddMethodResult is the generated method and, when inserted, the edit of
+// its file
+type AddMethodResult struct {
+	*analyzer.MethodStub
+	Edit *edit.Result `json:"edit,omitempty"`
+}
+
+func addMethodHandler(ctx context.Context, args AddMethodArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Adding method", "type", args.Type, "name", args.Name, "signature", args.Signature, "write", args.Write)
+	start := time.Now()
+	stub, err := analyzerInstance.GenerateMethodStub(ctx, args.Type, analyzer.MethodStubOptions{
+		Name:      args.Name,
+		Signature: args.Signature,
+		Doc:       args.Doc,
+		Receiver:  args.Receiver,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "add_method")
+	if err != nil {
+		return nil, err
+	}
+
+	result := AddMethodResult{MethodStub: stub}
+	if args.Write || args.DryRun {
+		if result.Edit, err = writeMethod(stub, args.DryRun); err != nil {
+			return nil, err
+		}
+		if result.Edit.Applied {
+			refreshAfterWrite(ctx, "method addition")
+		}
+	}
+	stub.File = relPath(analyzerInstance.RepoPath(), stub.File)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal method: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// writeMethod inserts a generated method, with the imports it needs, after
+// the last method of its type in its file
+func writeMethod(stub *analyzer.MethodStub, dryRun bool) (*edit.Result, error) {
+	names := make([]string, 0, len(stub.Imports))
+	for name := range stub.Imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var edits []edit.Edit
+	for _, name := range names {
+		importEdit := edit.Edit{Op: edit.AddImport, Path: stub.Imports[name]}
+		if name != path.Base(stub.Imports[name]) {
+			importEdit.Name = name
+		}
+		edits = append(edits, importEdit)
+	}
+	_, typeName, _ := strings.Cut(stub.Type, ".")
+	edits = append(edits, edit.Edit{Op: edit.AddMethod, Type: typeName, Code: stub.Code})
+
+	if err := checkWrite("add_method", []string{stub.File}, dryRun, edits); err != nil {
+		return nil, err
+	}
+	result, err := edit.Apply(stub.File, edits, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	result.File = relPath(analyzerInstance.RepoPath(), result.File)
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestAddMethodHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": "package store\n\ntype Store struct{}\n\nfunc NewStore() *Store { return &Store{} }\n",
+		"store/get.go":   "package store\n\nfunc (st *Store) Get(name string) string { return name }\n\nfunc helper() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	args := AddMethodArgs{Type: "Store", Signature: "func Put(ctx context.Context, name string) error", Doc: "Put stores an item"}
+	response, err := addMethodHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("addMethodHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"receiver":"st *Store"`) || !strings.Contains(text, `"file":"store/get.go"`) || strings.Contains(text, `"edit"`) {
+		t.Errorf("Expected a pointer receiver named st in get.go without an edit, got %s", text)
+	}
+
+	args.Write = true
+	response, err = addMethodHandler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("addMethodHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"applied":true`) {
+		t.Errorf("Expected the method to be inserted, got %s", text)
+	}
+	expected := `package store
+
+import "context"
+
+func (st *Store) Get(name string) string { return name }
+
+// Put stores an item
+func (st *Store) Put(ctx context.Context, name string) error {
+	panic("not implemented")
+}
+
+func helper() {}
+`
+	if data, err := os.ReadFile(filepath.Join(dir, "store", "get.go")); err != nil || string(data) != expected {
+		t.Errorf("Expected get.go:\n%s\ngot (%v):\n%s", expected, err, data)
+	}
+
+	// The analysis sees the new method, so it cannot be added twice
+	if _, err := addMethodHandler(context.Background(), args); err == nil || !strings.Contains(err.Error(), "already has a method Put") {
+		t.Errorf("Expected a conflict with the inserted method, got %v", err)
+	}
+}
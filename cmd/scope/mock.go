@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type GenerateMockArgs struct {
+	Interface string `json:"interface" jsonschema:"required,description=Interface to mock (Name or pkg.Name)"`
+	Style     string `json:"style,omitempty" jsonschema:"description=simple (function fields; the default) or gomock (mockgen-style recorder)"`
+	Name      string `json:"name,omitempty" jsonschema:"description=Name of the mock type; defaults to Mock and the interface name"`
+	Package   string `json:"package,omitempty" jsonschema:"description=Package the mock is for (import path or package name); defaults to the interface's package"`
+	ResponseBudget
+}
+
+func generateMockHandler(ctx context.Context, args GenerateMockArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Generating mock", "interface", args.Interface, "style", args.Style, "package", args.Package)
+	start := time.Now()
+	mock, err := analyzerInstance.GenerateMock(ctx, args.Interface, analyzer.MockOptions{
+		Style:   args.Style,
+		Name:    args.Name,
+		Package: args.Package,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "generate_mock")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(mock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mock: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestGenerateMockHandler(t *testing.T) {
+	// The test package declares no interfaces
+	if _, err := generateMockHandler(context.Background(), GenerateMockArgs{Interface: "TestStruct"}); err == nil ||
+		!strings.Contains(err.Error(), "not a named interface") {
+		t.Errorf("Expected a struct to be refused, got %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":   "module example.com/shop\n\ngo 1.21\n",
+		"store.go": "package shop\n\ntype Store interface {\n\tGet(name string) (int, error)\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	response, err := generateMockHandler(context.Background(), GenerateMockArgs{Interface: "Store", Style: "gomock"})
+	if err != nil {
+		t.Fatalf("generateMockHandler failed: %v", err)
+	}
+	var mock analyzer.Mock
+	if err := json.Unmarshal([]byte(responseText(t, response)), &mock); err != nil {
+		t.Fatalf("Failed to unmarshal mock: %v", err)
+	}
+	if mock.Name != "MockStore" || mock.Style != "gomock" || !strings.Contains(mock.Code, "func (m *MockStore) Get(name string) (int, error) {") ||
+		!strings.HasPrefix(mock.Source, "package shop\n") {
+		t.Errorf("Unexpected mock: %+v", mock)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ModernizeArgs struct {
+	Package string   `json:"package,omitempty" jsonschema:"description=Only modernize this package (import path or package name); omit for all packages" session:"package"`
+	Rules   []string `json:"rules,omitempty" jsonschema:"description=Rules to check: ioutil; strings_title; rand_seed; any; exp_slices; defaults to all"`
+	Apply   bool     `json:"apply,omitempty" jsonschema:"description=Rewrite the sites whose rewrite needs no review"`
+	DryRun  bool     `json:"dry_run,omitempty" jsonschema:"description=Only return the diffs of applying the rewrites"`
+	ResponseBudget
+}
+
+// ModernizeResult is the modernization report and, when applied, the edits
+// of the rewritten files
+type ModernizeResult struct {
+	*analyzer.ModernizeReport
+	Edits []*edit.Result `json:"edits,omitempty"`
+}
+
+func modernizeHandler(ctx context.Context, args ModernizeArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Modernizing package", "package", args.Package, "rules", args.Rules, "apply", args.Apply)
+	start := time.Now()
+	report, err := analyzerInstance.Modernize(ctx, args.Package, args.Rules)
+	metrics.AnalyzerDuration.ObserveDuration(start, "modernize")
+	if err != nil {
+		return nil, err
+	}
+
+	result := ModernizeResult{ModernizeReport: report}
+	if args.Apply || args.DryRun {
+		if result.Edits, err = applyModernizations(report.Modernizations, args.DryRun); err != nil {
+			return nil, err
+		}
+		for _, e := range result.Edits {
+			if e.Applied {
+				refreshAfterWrite(ctx, "modernization")
+				break
+			}
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal modernizations: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// applyModernizations rewrites the sites of modernizations that need no
+// review, with the edit engine, file by file. Imports the rewrites need
+// are added, and those they leave unused are removed. Every file is
+// checked before any is written.
+func applyModernizations(modernizations []analyzer.Modernization, dryRun bool) ([]*edit.Result, error) {
+	byFile := make(map[string][]analyzer.Modernization)
+	for _, m := range modernizations {
+		if !m.Manual {
+			byFile[m.Position.Filename] = append(byFile[m.Position.Filename], m)
+		}
+	}
+	filenames := make([]string, 0, len(byFile))
+	for filename := range byFile {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	plans := make(map[string][]edit.Edit, len(filenames))
+	for _, filename := range filenames {
+		edits, err := modernizationEdits(filename, byFile[filename])
+		if err != nil {
+			return nil, err
+		}
+		plans[filename] = edits
+	}
+
+	if err := checkWrite("modernize", filenames, dryRun, plans); err != nil {
+		return nil, err
+	}
+
+	results := make([]*edit.Result, 0, len(filenames))
+	for _, filename := range filenames {
+		result, err := edit.Apply(filename, plans[filename], dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("failed to modernize %s: %w", relPath(analyzerInstance.RepoPath(), filename), err)
+		}
+		result.File = relPath(analyzerInstance.RepoPath(), result.File)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// modernizationEdits returns the edits rewriting the sites of a file. Sites
+// are replaced from the end of the file, so each replacement leaves the
+// lines and columns of the others in place.
+func modernizationEdits(filename string, sites []analyzer.Modernization) ([]edit.Edit, error) {
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Position.Line != sites[j].Position.Line {
+			return sites[i].Position.Line > sites[j].Position.Line
+		}
+		return sites[i].Position.Column > sites[j].Position.Column
+	})
+	imports := make(map[string]string)
+	unused := make(map[string]bool)
+	var edits []edit.Edit
+	for _, site := range sites {
+		edits = append(edits, edit.Edit{Op: edit.Replace, Line: site.Position.Line, Column: site.Position.Column, Old: site.Old, Code: site.New})
+		for name, importPath := range site.Imports {
+			imports[name] = importPath
+		}
+		for _, importPath := range site.Unused {
+			unused[importPath] = true
+		}
+	}
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		importEdit := edit.Edit{Op: edit.AddImport, Path: imports[name]}
+		if name != path.Base(imports[name]) {
+			importEdit.Name = name
+		}
+		edits = append(edits, importEdit)
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	out, err := edit.Source(filename, src, edits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to modernize %s: %w", relPath(analyzerInstance.RepoPath(), filename), err)
+	}
+	// Imports still used elsewhere in the file are refused and kept
+	paths := make([]string, 0, len(unused))
+	for importPath := range unused {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	for _, importPath := range paths {
+		remove := edit.Edit{Op: edit.RemoveImport, Path: importPath}
+		if next, err := edit.Source(filename, out, []edit.Edit{remove}); err == nil {
+			edits = append(edits, remove)
+			out = next
+		}
+	}
+	return edits, nil
+}
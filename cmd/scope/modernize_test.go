@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestModernizeHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+var start = time.Now()
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+func Load(name string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(os.ExpandEnv(name))
+	return map[string]interface{}{strings.Title(name): data}, err
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	response, err := modernizeHandler(context.Background(), ModernizeArgs{Apply: true})
+	if err != nil {
+		t.Fatalf("modernizeHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"file":"store/store.go"`) || !strings.Contains(text, `"applied":true`) {
+		t.Errorf("Expected store/store.go to be rewritten, got %s", text)
+	}
+	// strings.Title needs review, and time is still used
+	expected := `package store
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+var start = time.Now()
+
+func init() {
+}
+
+func Load(name string) (map[string]any, error) {
+	data, err := os.ReadFile(os.ExpandEnv(name))
+	return map[string]any{strings.Title(name): data}, err
+}
+`
+	if data, err := os.ReadFile(filepath.Join(dir, "store", "store.go")); err != nil || string(data) != expected {
+		t.Errorf("Expected store.go:\n%s\ngot (%v):\n%s", expected, err, data)
+	}
+
+	// Only the manual rewrite is left
+	response, err = modernizeHandler(context.Background(), ModernizeArgs{DryRun: true})
+	if err != nil {
+		t.Fatalf("modernizeHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"rules":{"strings_title":1}`) || strings.Contains(text, `"edits"`) {
+		t.Errorf("Expected only strings.Title without edits, got %s", text)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/notes"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+var noteStore *notes.Store
+
+type AnnotateSymbolArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Package, type, function, or member (Type.Method or Type.Field) to annotate; qualify it as pkg.Name when ambiguous"`
+	Note   string `json:"note" jsonschema:"required,description=The knowledge to record"`
+	Author string `json:"author,omitempty" jsonschema:"description=Who wrote the note; defaults to $USER"`
+	Shared bool   `json:"shared,omitempty" jsonschema:"description=Store the note in .scope/notes.json in the repository so it can be committed and shared, instead of only in the local cache"`
+	ResponseBudget
+}
+
+func annotateSymbolHandler(ctx context.Context, args AnnotateSymbolArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Annotating symbol", "symbol", args.Symbol, "shared", args.Shared)
+	symbol, err := canonicalSymbol(ctx, args.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	author := args.Author
+	if author == "" {
+		author = os.Getenv("USER")
+	}
+	note := notes.Note{Symbol: symbol, Text: args.Note, Author: author, Shared: args.Shared}
+	if err := noteStore.Add(ctx, note); err != nil {
+		return nil, err
+	}
+
+	annotations, err := noteStore.Get(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(annotations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type GetAnnotationsArgs struct {
+	Symbol string `json:"symbol,omitempty" jsonschema:"description=Symbol whose notes to return; omit to return every note keyed by symbol"`
+	ResponseBudget
+}
+
+func getAnnotationsHandler(ctx context.Context, args GetAnnotationsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Getting annotations", "symbol", args.Symbol)
+	var result interface{}
+	if args.Symbol == "" {
+		all, err := noteStore.All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result = all
+	} else {
+		symbol, err := canonicalSymbol(ctx, args.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		if result, err = noteStore.Get(ctx, symbol); err != nil {
+			return nil, err
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// canonicalSymbol resolves a symbol name to the key its notes are stored
+// under, its fully qualified form (import/path.Name, import/path.Type.Member,
+// or import/path for packages), so differently qualified spellings of the
+// same symbol share their notes
+func canonicalSymbol(ctx context.Context, name string) (string, error) {
+	var ambiguous *analyzer.AmbiguousError
+
+	info, err := analyzerInstance.LookupType(ctx, name)
+	if err == nil {
+		return info.ImportPath + "." + info.Name, nil
+	}
+	if errors.As(err, &ambiguous) {
+		return "", err
+	}
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		if info, err := analyzerInstance.LookupType(ctx, name[:i]); err == nil && hasMember(info, name[i+1:]) {
+			return info.ImportPath + "." + info.Name + "." + name[i+1:], nil
+		}
+	}
+
+	pkg, err := analyzerInstance.GetPackageInfo(ctx, name)
+	if err == nil {
+		return pkg.ImportPath, nil
+	}
+	if errors.As(err, &ambiguous) {
+		return "", err
+	}
+	return "", fmt.Errorf("symbol %s not found", name)
+}
+
+// hasMember reports whether a type has a method or field with the given name
+func hasMember(info *analyzer.TypeInfo, member string) bool {
+	for _, method := range info.Methods {
+		if method.Name == member {
+			return true
+		}
+	}
+	for _, field := range info.Fields {
+		if field.Name == member {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/notes"
+)
+
+func TestAnnotations(t *testing.T) {
+	symbols := []string{"TestStruct", "testpkg.TestStruct.TestMethod", "testpkg"}
+	for _, symbol := range symbols {
+		if _, err := annotateSymbolHandler(context.Background(), AnnotateSymbolArgs{Symbol: symbol, Note: "note on " + symbol, Author: "tester"}); err != nil {
+			t.Fatalf("annotateSymbolHandler(%s) failed: %v", symbol, err)
+		}
+	}
+	if _, err := annotateSymbolHandler(context.Background(), AnnotateSymbolArgs{Symbol: "Missing", Note: "x"}); err == nil {
+		t.Error("Expected error annotating an unknown symbol")
+	}
+
+	// A differently qualified spelling finds the same notes
+	response, err := getAnnotationsHandler(context.Background(), GetAnnotationsArgs{Symbol: "testpkg.TestStruct"})
+	if err != nil {
+		t.Fatalf("getAnnotationsHandler failed: %v", err)
+	}
+	var annotations []notes.Note
+	if err := json.Unmarshal([]byte(responseText(t, response)), &annotations); err != nil {
+		t.Fatalf("Failed to decode annotations: %v", err)
+	}
+	if len(annotations) != 1 || annotations[0].Text != "note on TestStruct" || annotations[0].Author != "tester" {
+		t.Errorf("Unexpected annotations: %+v", annotations)
+	}
+
+	response, err = getAnnotationsHandler(context.Background(), GetAnnotationsArgs{})
+	if err != nil {
+		t.Fatalf("getAnnotationsHandler failed: %v", err)
+	}
+	var all map[string][]notes.Note
+	if err := json.Unmarshal([]byte(responseText(t, response)), &all); err != nil {
+		t.Fatalf("Failed to decode annotations: %v", err)
+	}
+	for _, key := range []string{"testpkg.TestStruct", "testpkg.TestStruct.TestMethod", "testpkg"} {
+		if len(all[key]) != 1 {
+			t.Errorf("Expected one note for %s, got %+v", key, all[key])
+		}
+	}
+}
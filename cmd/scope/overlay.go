@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type OverlayFileArgs struct {
+	File    string `json:"file" jsonschema:"required,description=Go file to overlay; relative to the repository or absolute"`
+	Content string `json:"content" jsonschema:"required,description=The file's unsaved content"`
+}
+
+type SetOverlayArgs struct {
+	Files    []OverlayFileArgs `json:"files,omitempty" jsonschema:"description=Files whose content to analyze in place of the disk's; new files are added to their package"`
+	Clear    []string          `json:"clear,omitempty" jsonschema:"description=Files to read from disk again"`
+	ClearAll bool              `json:"clear_all,omitempty" jsonschema:"description=Remove every file from the overlay"`
+	ResponseBudget
+}
+
+// SetOverlayResult lists the files the call changed and the overlay after it
+type SetOverlayResult struct {
+	Set      []string               `json:"set,omitempty"`
+	Cleared  []string               `json:"cleared,omitempty"`
+	Overlays []analyzer.OverlayFile `json:"overlays"`
+}
+
+func setOverlayHandler(ctx context.Context, args SetOverlayArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Setting overlay", "files", len(args.Files), "clear", args.Clear, "clear_all", args.ClearAll)
+	result := SetOverlayResult{}
+
+	start := time.Now()
+	if args.ClearAll || len(args.Clear) > 0 {
+		clear := args.Clear
+		if args.ClearAll {
+			clear = nil
+		}
+		cleared, err := analyzerInstance.ClearOverlay(clear...)
+		if err != nil {
+			return nil, err
+		}
+		result.Cleared = cleared
+	}
+	if len(args.Files) > 0 {
+		files := make(map[string][]byte, len(args.Files))
+		for _, file := range args.Files {
+			files[file.File] = []byte(file.Content)
+			result.Set = append(result.Set, file.File)
+		}
+		if err := analyzerInstance.SetOverlay(files); err != nil {
+			return nil, err
+		}
+	}
+	if len(result.Set) > 0 || len(result.Cleared) > 0 {
+		refreshAfterWrite(ctx, "overlay")
+	}
+	result.Overlays = analyzerInstance.Overlays()
+	metrics.AnalyzerDuration.ObserveDuration(start, "set_overlay")
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal overlay: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestSetOverlayHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/shop\n\ngo 1.21\n",
+		"cart.go": "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	ctx := context.Background()
+
+	edited := "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n\tOwner string\n}\n"
+	response, err := setOverlayHandler(ctx, SetOverlayArgs{Files: []OverlayFileArgs{{File: "cart.go", Content: edited}}})
+	if err != nil {
+		t.Fatalf("setOverlayHandler failed: %v", err)
+	}
+	var result SetOverlayResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to unmarshal overlay: %v", err)
+	}
+	if len(result.Set) != 1 || len(result.Overlays) != 1 || result.Overlays[0].File != "cart.go" || !result.Overlays[0].OnDisk {
+		t.Errorf("Expected cart.go in the overlay, got %+v", result)
+	}
+	if info, err := shop.LookupType(ctx, "Cart"); err != nil || len(info.Fields) != 2 {
+		t.Errorf("Expected the overlaid Cart to be analyzed, got %+v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); string(data) != files["cart.go"] {
+		t.Error("Expected the file on disk to be unchanged")
+	}
+
+	if _, err := setOverlayHandler(ctx, SetOverlayArgs{Files: []OverlayFileArgs{{File: "go.mod", Content: "module x\n"}}}); err == nil {
+		t.Error("Expected error for a file other than Go")
+	}
+
+	response, err = setOverlayHandler(ctx, SetOverlayArgs{ClearAll: true})
+	if err != nil {
+		t.Fatalf("setOverlayHandler failed: %v", err)
+	}
+	result = SetOverlayResult{}
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to unmarshal overlay: %v", err)
+	}
+	if len(result.Cleared) != 1 || len(result.Overlays) != 0 {
+		t.Errorf("Expected the overlay to be cleared, got %+v", result)
+	}
+	if info, err := shop.LookupType(ctx, "Cart"); err != nil || len(info.Fields) != 1 {
+		t.Errorf("Expected the disk's Cart after clearing, got %+v, %v", info, err)
+	}
+}
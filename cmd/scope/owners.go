@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/owners"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// FileOwnership is the owners of a single file and the CODEOWNERS rule that assigned them
+type FileOwnership struct {
+	Path    string   `json:"path"`
+	Owners  []string `json:"owners"`
+	Pattern string   `json:"pattern,omitempty"`
+	Line    int      `json:"line,omitempty"`
+}
+
+// Ownership answers who_owns for a file, symbol, or package
+type Ownership struct {
+	Target string          `json:"target"`
+	Kind   string          `json:"kind"`
+	Owners []string        `json:"owners"`
+	Files  []FileOwnership `json:"files"`
+	Source string          `json:"source"`
+}
+
+type WhoOwnsArgs struct {
+	Target string `json:"target" jsonschema:"required,description=A file path (absolute or relative to the repository), a symbol name (pkg.Name when ambiguous), or a package name or import path"`
+	ResponseBudget
+}
+
+func whoOwnsHandler(ctx context.Context, args WhoOwnsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Looking up owners", "target", args.Target)
+	repoPath := analyzerInstance.RepoPath()
+	rules, err := owners.Load(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		return nil, fmt.Errorf("no CODEOWNERS file found in %s", strings.Join(owners.Locations, ", "))
+	}
+
+	kind, files, err := ownedFiles(ctx, repoPath, args.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Ownership{Target: args.Target, Kind: kind, Owners: []string{}, Source: relPath(repoPath, rules.Path)}
+	for _, file := range files {
+		ownership := FileOwnership{Path: file, Owners: []string{}}
+		if rule := rules.Match(file); rule != nil {
+			ownership.Owners = rule.Owners
+			ownership.Pattern = rule.Pattern
+			ownership.Line = rule.Line
+		}
+		result.Files = append(result.Files, ownership)
+	}
+	if owned := rules.OwnersOf(files); owned != nil {
+		result.Owners = owned
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal owners: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// ownedFiles resolves a who_owns target to repository-relative file paths.
+// Existing files win, then symbols, then packages.
+func ownedFiles(ctx context.Context, repoPath, target string) (string, []string, error) {
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return "file", []string{relPath(repoPath, path)}, nil
+	}
+
+	typeInfo, err := analyzerInstance.LookupType(ctx, target)
+	var ambiguous *analyzer.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return "", nil, err
+	}
+	if err == nil && typeInfo.Position.Filename != "" {
+		return "symbol", []string{relPath(repoPath, typeInfo.Position.Filename)}, nil
+	}
+
+	pkgInfo, err := analyzerInstance.GetPackageInfo(ctx, target)
+	if errors.As(err, &ambiguous) {
+		return "", nil, err
+	}
+	if err == nil {
+		files := make([]string, len(pkgInfo.Files))
+		for i, file := range pkgInfo.Files {
+			files[i] = relPath(repoPath, file)
+		}
+		return "package", files, nil
+	}
+
+	return "", nil, fmt.Errorf("%s is not a file, symbol, or package in the repository", target)
+}
+
+// relPath returns path relative to the repository root in slash form
+func relPath(repoPath, path string) string {
+	if rel, err := filepath.Rel(repoPath, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
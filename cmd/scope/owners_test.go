@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhoOwnsHandler(t *testing.T) {
+	if _, err := whoOwnsHandler(context.Background(), WhoOwnsArgs{Target: "test.go"}); err == nil {
+		t.Error("Expected error without CODEOWNERS")
+	}
+
+	path := filepath.Join(analyzerInstance.RepoPath(), "CODEOWNERS")
+	if err := os.WriteFile(path, []byte("* @org/core\ntest.go @alice @bob\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CODEOWNERS: %v", err)
+	}
+	defer os.Remove(path)
+
+	tests := []struct {
+		target string
+		kind   string
+	}{
+		{"test.go", "file"},
+		{filepath.Join(analyzerInstance.RepoPath(), "test.go"), "file"},
+		{"TestStruct", "symbol"},
+		{"testpkg", "package"},
+	}
+	for _, tt := range tests {
+		response, err := whoOwnsHandler(context.Background(), WhoOwnsArgs{Target: tt.target})
+		if err != nil {
+			t.Fatalf("whoOwnsHandler(%s) failed: %v", tt.target, err)
+		}
+		var ownership Ownership
+		if err := json.Unmarshal([]byte(responseText(t, response)), &ownership); err != nil {
+			t.Fatalf("Failed to decode ownership: %v", err)
+		}
+		if ownership.Kind != tt.kind {
+			t.Errorf("Expected kind %s for %s, got %s", tt.kind, tt.target, ownership.Kind)
+		}
+		if want := []string{"@alice", "@bob"}; !reflect.DeepEqual(ownership.Owners, want) {
+			t.Errorf("Expected owners %v for %s, got %v", want, tt.target, ownership.Owners)
+		}
+		if len(ownership.Files) != 1 || ownership.Files[0].Path != "test.go" || ownership.Files[0].Line != 2 {
+			t.Errorf("Unexpected files for %s: %+v", tt.target, ownership.Files)
+		}
+	}
+
+	if _, err := whoOwnsHandler(context.Background(), WhoOwnsArgs{Target: "Missing"}); err == nil {
+		t.Error("Expected error for unknown target")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ParseDiagnosticsArgs struct {
+	ResponseBudget
+}
+
+func parseDiagnosticsHandler(ctx context.Context, args ParseDiagnosticsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Listing parse diagnostics")
+	start := time.Now()
+	diags, err := analyzerInstance.ParseDiagnostics(ctx)
+	metrics.AnalyzerDuration.ObserveDuration(start, "parse_diagnostics")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(diags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal parse diagnostics: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
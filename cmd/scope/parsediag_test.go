@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestParseDiagnosticsHandler(t *testing.T) {
+	response, err := parseDiagnosticsHandler(context.Background(), ParseDiagnosticsArgs{})
+	if err != nil {
+		t.Fatalf("parseDiagnosticsHandler failed: %v", err)
+	}
+
+	var diags analyzer.ParseDiagnostics
+	if err := json.Unmarshal([]byte(responseText(t, response)), &diags); err != nil {
+		t.Fatalf("Failed to decode parse diagnostics: %v", err)
+	}
+	if diags.Toolchain != runtime.Version() {
+		t.Errorf("Expected toolchain %s, got %s", runtime.Version(), diags.Toolchain)
+	}
+	// The test package parses cleanly
+	if len(diags.Files) != 0 || len(diags.VersionErrors) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", diags)
+	}
+}
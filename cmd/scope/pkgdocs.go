@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type GetPackageDocsArgs struct {
+	Package  string `json:"package,omitempty" jsonschema:"description=Package import path or name; defaults to every documented package" session:"package"`
+	MaxBytes int    `json:"max_bytes,omitempty" jsonschema:"description=Text budget per package; defaults to 4000"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"description=Maximum number of packages" session:"limit"`
+	ResponseBudget
+}
+
+func getPackageDocsHandler(ctx context.Context, args GetPackageDocsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Getting package docs", "package", args.Package, "max_bytes", args.MaxBytes)
+	start := time.Now()
+	result, err := analyzerInstance.PackageDocs(ctx, analyzer.PackageDocsOptions{
+		Package:  args.Package,
+		MaxBytes: args.MaxBytes,
+		Limit:    args.Limit,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "get_package_docs")
+	if err != nil {
+		return nil, err
+	}
+	repoPath := analyzerInstance.RepoPath()
+	for i := range result.Packages {
+		pkg := &result.Packages[i]
+		pkg.Dir = relPath(repoPath, pkg.Dir)
+		for j, file := range pkg.DocFiles {
+			pkg.DocFiles[j] = relPath(repoPath, file)
+		}
+		if pkg.Readme != "" {
+			pkg.Readme = relPath(repoPath, pkg.Readme)
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal package docs: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestGetPackageDocsHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/shop\n\ngo 1.21\n",
+		"cart/doc.go":  "// Package cart holds the items of an order.\npackage cart\n",
+		"cart/cart.go": "package cart\n\ntype Cart struct{}\n",
+		"cart/README":  "Carts expire after a day.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+
+	response, err := getPackageDocsHandler(context.Background(), GetPackageDocsArgs{Package: "cart"})
+	if err != nil {
+		t.Fatalf("getPackageDocsHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	for _, expected := range []string{`"dir":"cart"`, `"doc_files":["cart/doc.go"]`, `"readme":"cart/README"`, "Package cart holds the items of an order.", "Carts expire after a day."} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %s in the response, got %s", expected, text)
+		}
+	}
+}
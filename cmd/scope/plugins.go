@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// splitPluginPaths splits a list of plugin files separated like PATH
+func splitPluginPaths(list string) []string {
+	var paths []string
+	for _, path := range filepath.SplitList(list) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// loadPlugins opens Go plugins built with -buildmode=plugin. Each registers
+// its analysis plugins with analyzer.RegisterPlugin from an init function,
+// so they must be loaded before the analyzer is created.
+func loadPlugins(paths []string) error {
+	for _, path := range paths {
+		before := len(analyzer.Plugins())
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("failed to load plugin %s: %w", path, err)
+		}
+		if len(analyzer.Plugins()) == before {
+			slog.Warn("Plugin registered no analysis plugins", "plugin", path)
+		}
+	}
+	if names := analyzer.Plugins(); len(names) > 0 {
+		slog.Info("Loaded analysis plugins", "plugins", names)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	list := "a.so" + string(os.PathListSeparator) + " " + string(os.PathListSeparator) + "b.so"
+	if paths := splitPluginPaths(list); !slices.Equal(paths, []string{"a.so", "b.so"}) {
+		t.Errorf("Expected two plugin paths, got %v", paths)
+	}
+	if err := loadPlugins(nil); err != nil {
+		t.Errorf("Expected no error without plugins, got %v", err)
+	}
+	if err := loadPlugins([]string{filepath.Join(t.TempDir(), "missing.so")}); err == nil {
+		t.Error("Expected error for a missing plugin")
+	}
+}
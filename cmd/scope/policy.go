@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/TFMV/scope/internal/policy"
+)
+
+// writePolicy enforces the repository's .scope/policy.json on the tools
+// that write files; nil, allowing every write, when there is none
+var writePolicy *policy.Enforcer
+
+// loadWritePolicy reads the write policy of a repository
+func loadWritePolicy(repoPath string) (*policy.Enforcer, error) {
+	p, err := policy.Load(repoPath)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	slog.Info("Enforcing write policy", "allow", p.Allow, "deny", p.Deny, "max_files", p.MaxFiles, "require_dry_run", p.RequireDryRun)
+	return policy.NewEnforcer(*p), nil
+}
+
+// checkWrite enforces the write policy on a tool about to write files of
+// the repository, given as absolute paths. change identifies the change for
+// matching the write with its dry run; nil when it has none.
+func checkWrite(tool string, files []string, dryRun bool, change any) error {
+	repoPath := analyzerInstance.RepoPath()
+	rel := make([]string, len(files))
+	for i, file := range files {
+		rel[i] = relPath(repoPath, file)
+	}
+	return writePolicy.Check(policy.Write{Tool: tool, Files: rel, DryRun: dryRun, Change: change})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/llm"
+	"github.com/TFMV/scope/internal/policy"
+	"github.com/TFMV/scope/internal/sandbox"
+)
+
+func TestWritePolicy(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module example.com/shop\n\ngo 1.21\n",
+		".scope/policy.json":   `{"allow": ["*.go"], "deny": ["generated"], "require_dry_run": true}`,
+		"cart.go":              "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+		"generated/catalog.go": "package generated\n\n// Catalog lists products\ntype Catalog struct{}\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory of %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	if writePolicy, err = loadWritePolicy(dir); err != nil || writePolicy == nil {
+		t.Fatalf("Failed to load write policy: %v", err)
+	}
+	defer func() { writePolicy = nil }()
+	ctx := context.Background()
+
+	// A denied file is refused even in a dry run, with the violation
+	args := CodeEditArgs{File: "generated/catalog.go", Edits: []CodeEditOperation{{Op: "add_field", Type: "Catalog", Code: "Items []string"}}, DryRun: true}
+	_, err = codeEditHandler(ctx, args)
+	var policyErr *policy.Error
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 || policyErr.Violations[0].Rule != policy.RuleDeny || policyErr.Violations[0].File != "generated/catalog.go" {
+		t.Fatalf("Expected a deny violation, got %v", err)
+	}
+
+	// Writing needs a dry run of the same edits first
+	args = CodeEditArgs{File: "cart.go", Edits: []CodeEditOperation{{Op: "add_field", Type: "Cart", Code: "Owner string"}}}
+	if _, err := codeEditHandler(ctx, args); err == nil || !strings.Contains(err.Error(), `"rule":"require_dry_run"`) {
+		t.Fatalf("Expected a require_dry_run violation, got %v", err)
+	}
+	args.DryRun = true
+	if _, err := codeEditHandler(ctx, args); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	args.DryRun = false
+	if _, err := codeEditHandler(ctx, args); err != nil {
+		t.Fatalf("Expected the previewed edit to be written, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); !strings.Contains(string(data), "Owner string") {
+		t.Errorf("Expected the edit to be written, got %s", data)
+	}
+
+	// A sandbox previews its edits, so confirming it needs no dry run
+	sandboxes = sandbox.NewStore(dir, t.TempDir())
+	defer func() { sandboxes.Close(); sandboxes = nil }()
+	response, err := codeEditHandler(ctx, CodeEditArgs{
+		File:    "cart.go",
+		Edits:   []CodeEditOperation{{Op: "add_field", Type: "Cart", Code: "Total int"}},
+		Sandbox: true,
+	})
+	if err != nil {
+		t.Fatalf("Sandboxed edit failed: %v", err)
+	}
+	_, id, _ := strings.Cut(responseText(t, response), `"sandbox":"`)
+	id, _, _ = strings.Cut(id, `"`)
+	if _, err := confirmEditHandler(ctx, ConfirmEditArgs{Sandbox: id}); err != nil {
+		t.Errorf("Expected the sandbox to be confirmed, got %v", err)
+	}
+}
+
+// rewritingModel answers every request with a different rewrite of cart.go
+type rewritingModel struct {
+	calls int
+}
+
+func (m *rewritingModel) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	m.calls++
+	code := fmt.Sprintf("package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n\tOwner%d string\n}\n", m.calls)
+	return &llm.Response{Text: "```go\n" + code + "```\n", Model: "fake"}, nil
+}
+
+func TestWritePolicyModelEdit(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/shop\n\ngo 1.21\n",
+		".scope/policy.json": `{"require_dry_run": true}`,
+		"cart.go":            "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory of %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	if writePolicy, err = loadWritePolicy(dir); err != nil || writePolicy == nil {
+		t.Fatalf("Failed to load write policy: %v", err)
+	}
+	defer func() { writePolicy = nil }()
+	model := &rewritingModel{}
+	llmClient = llm.NewClient(model, 1000, 0)
+	defer func() { llmClient = nil }()
+	ctx := context.Background()
+
+	// Writing unpreviewed changes is refused
+	args := CodeEditArgs{File: "cart.go", Changes: "add an owner"}
+	if _, err := codeEditHandler(ctx, args); err == nil || !strings.Contains(err.Error(), `"rule":"require_dry_run"`) {
+		t.Fatalf("Expected a require_dry_run violation, got %v", err)
+	}
+
+	// The write applies the edit the dry run previewed, though the model
+	// would answer differently now
+	args.DryRun = true
+	response, err := codeEditHandler(ctx, args)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, "Owner2") {
+		t.Fatalf("Expected the dry run to show the model's edit, got %s", text)
+	}
+	args.DryRun = false
+	if _, err := codeEditHandler(ctx, args); err != nil {
+		t.Fatalf("Expected the previewed edit to be written, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); !strings.Contains(string(data), "Owner2 string") {
+		t.Errorf("Expected the previewed edit to be written, got %s", data)
+	}
+	if model.calls != 2 {
+		t.Errorf("Expected the write not to ask the model again, got %d requests", model.calls)
+	}
+
+	// A preview stands for one write
+	if _, err := codeEditHandler(ctx, args); err == nil || !strings.Contains(err.Error(), `"rule":"require_dry_run"`) {
+		t.Errorf("Expected a second write to need another dry run, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/profile"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// defaultProfileLimit is the number of functions profile_report returns
+// unless asked for another
+const defaultProfileLimit = 20
+
+type ProfileReportArgs struct {
+	File       string `json:"file" jsonschema:"required,description=pprof CPU or heap profile; relative to the repository or absolute"`
+	SampleType string `json:"sample_type,omitempty" jsonschema:"description=Sample type to report such as cpu or alloc_space or inuse_space; omit for the profile's default"`
+	SortBy     string `json:"sort_by,omitempty" jsonschema:"enum=flat,enum=cum,description=Order functions by the samples they ran (flat) or were on the stack for (cum); default flat"`
+	Limit      int    `json:"limit,omitempty" jsonschema:"description=Maximum number of functions to return; default 20"`
+	RepoOnly   bool   `json:"repo_only,omitempty" jsonschema:"description=Only return the repository's functions; leave out the runtime and standard library and dependencies"`
+	ResponseBudget
+}
+
+func profileReportHandler(ctx context.Context, args ProfileReportArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Building profile report", "file", args.File, "sample_type", args.SampleType)
+	path := args.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(analyzerInstance.RepoPath(), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+	prof, err := profile.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultProfileLimit
+	}
+	start := time.Now()
+	report, err := analyzerInstance.ProfileReport(ctx, prof, analyzer.ProfileOptions{
+		SampleType: args.SampleType,
+		SortBy:     args.SortBy,
+		Limit:      limit,
+		RepoOnly:   args.RepoOnly,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "profile_report")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestProfileReportHandler(t *testing.T) {
+	// Sample every allocation of a tool call
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	if _, err := errorTaxonomyHandler(context.Background(), ErrorTaxonomyArgs{}); err != nil {
+		t.Fatalf("errorTaxonomyHandler failed: %v", err)
+	}
+	runtime.GC()
+
+	file := filepath.Join(t.TempDir(), "heap.out")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+	if err := pprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		t.Fatalf("Failed to write heap profile: %v", err)
+	}
+	f.Close()
+
+	response, err := profileReportHandler(context.Background(), ProfileReportArgs{File: file, SampleType: "alloc_space", Limit: 5})
+	if err != nil {
+		t.Fatalf("profileReportHandler failed: %v", err)
+	}
+	var report analyzer.ProfileReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to decode profile report: %v", err)
+	}
+	if report.SampleType != "alloc_space" || report.Unit != "bytes" || len(report.SampleTypes) != 4 {
+		t.Errorf("Unexpected report header: %+v", report)
+	}
+	if len(report.Functions) != 5 || !report.Truncated || report.Total <= 0 {
+		t.Errorf("Expected the 5 functions allocating the most, got %+v", report.Functions)
+	}
+
+	// The test repository's code is not in the profile
+	response, err = profileReportHandler(context.Background(), ProfileReportArgs{File: file, RepoOnly: true})
+	if err != nil {
+		t.Fatalf("profileReportHandler failed: %v", err)
+	}
+	report = analyzer.ProfileReport{}
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to decode profile report: %v", err)
+	}
+	if report.SampleType != "inuse_space" || len(report.Functions) != 0 || report.RepositoryFlat != 0 {
+		t.Errorf("Expected no repository functions, got %+v", report)
+	}
+
+	if _, err := profileReportHandler(context.Background(), ProfileReportArgs{File: "nosuch.out"}); err == nil {
+		t.Error("Expected error for a missing profile")
+	}
+}
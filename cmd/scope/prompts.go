@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/hooks"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// promptTimeout bounds the analysis behind a prompt. Prompt handlers take
+// only their arguments, since mcp-golang builds a prompt's argument list
+// from the handler's first parameter, so they have no request context.
+const promptTimeout = 2 * time.Minute
+
+// maxPromptDiffBytes is the largest diff embedded in a review prompt
+const maxPromptDiffBytes = 64 << 10
+
+// maxPromptExamples is the number of usage examples embedded in a prompt
+const maxPromptExamples = 3
+
+// Prompt arguments are listed to clients under their Go field names, so
+// each is a single word that also matches its JSON key
+type ExplainPackageArgs struct {
+	Package string `json:"package" jsonschema:"required,description=Package name or import path"`
+}
+
+type WriteTestsArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Function; type or method to test (e.g. NewServer or Server.Start)"`
+}
+
+type ReviewDiffArgs struct {
+	Base string `json:"base,omitempty" jsonschema:"description=Git ref to diff the working tree against (default HEAD)"`
+}
+
+// registerPrompts registers the prompt templates filled with analyzer output
+func registerPrompts(server *mcp.Server) error {
+	prompts := []struct {
+		name        string
+		description string
+		handler     any
+	}{
+		{"explain_package", "Explain a package from its documentation, types and function signatures", explainPackagePrompt},
+		{"write_tests", "Write tests for a function, type or method given its signature, documentation and usage examples", writeTestsPrompt},
+		{"review_diff", "Review the changes since a git ref given the diff and build, lint and API compatibility findings", reviewDiffPrompt},
+	}
+	for _, prompt := range prompts {
+		if err := server.RegisterPrompt(prompt.name, prompt.description, prompt.handler); err != nil {
+			return fmt.Errorf("failed to register %s prompt: %w", prompt.name, err)
+		}
+		slog.Debug("Registered prompt", "prompt", prompt.name)
+	}
+	slog.Info("Registered prompts", "count", len(prompts))
+	return nil
+}
+
+// promptResponse wraps the filled template in a single user message
+func promptResponse(description, text string) *mcp.PromptResponse {
+	return mcp.NewPromptResponse(description, mcp.NewPromptMessage(mcp.NewTextContent(text), mcp.RoleUser))
+}
+
+func explainPackagePrompt(args ExplainPackageArgs) (*mcp.PromptResponse, error) {
+	slog.Info("Filling explain_package prompt", "package", args.Package)
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+	start := time.Now()
+	text, err := explainPackageText(ctx, args.Package)
+	metrics.AnalyzerDuration.ObserveDuration(start, "prompt_explain_package")
+	if err != nil {
+		return nil, err
+	}
+	return promptResponse("Explain package "+args.Package, text), nil
+}
+
+// explainPackageText fills the explain_package template
+func explainPackageText(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("package is required")
+	}
+	pkg, err := analyzerInstance.GetPackageInfo(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	result, err := analyzerInstance.AnalyzeRepository(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Explain what the Go package %s does, how its main types and functions fit together, and how a caller would typically use it. Point out anything surprising.\n\n", pkg.ImportPath)
+	fmt.Fprintf(&b, "## Package %s\n\n", pkg.Name)
+	if pkg.Doc != "" {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(pkg.Doc))
+	}
+	files := make([]string, len(pkg.Files))
+	for i, file := range pkg.Files {
+		files[i] = relPath(analyzerInstance.RepoPath(), file)
+	}
+	fmt.Fprintf(&b, "Files: %s\n", strings.Join(files, ", "))
+
+	dir := ""
+	if len(pkg.Files) > 0 {
+		dir = filepath.Dir(pkg.Files[0])
+	}
+	var typeLines []string
+	for _, t := range result.Types {
+		if t.ImportPath != pkg.ImportPath {
+			continue
+		}
+		typeLines = append(typeLines, declLine(fmt.Sprintf("type %s %s", t.Name, t.Kind), t.Doc))
+		for _, m := range t.Methods {
+			if m.Exported {
+				typeLines = append(typeLines, "  "+declLine(funcDecl(m.Name, m.Signature), m.Doc))
+			}
+		}
+	}
+	var funcLines []string
+	for _, fn := range result.Functions {
+		if !fn.IsMethod && filepath.Dir(fn.Position.Filename) == dir {
+			funcLines = append(funcLines, declLine(funcDecl(fn.Name, fn.Signature), fn.Doc))
+		}
+	}
+	writeSection(&b, "Types", typeLines)
+	writeSection(&b, "Functions", funcLines)
+	return b.String(), nil
+}
+
+func writeTestsPrompt(args WriteTestsArgs) (*mcp.PromptResponse, error) {
+	slog.Info("Filling write_tests prompt", "symbol", args.Symbol)
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+	start := time.Now()
+	text, err := writeTestsText(ctx, args.Symbol)
+	metrics.AnalyzerDuration.ObserveDuration(start, "prompt_write_tests")
+	if err != nil {
+		return nil, err
+	}
+	return promptResponse("Write tests for "+args.Symbol, text), nil
+}
+
+// writeTestsText fills the write_tests template
+func writeTestsText(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("symbol is required")
+	}
+
+	var b strings.Builder
+	var file string
+	symbols, err := analyzerInstance.Symbols(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(symbols) > 1:
+		candidates := make([]string, len(symbols))
+		for i, sym := range symbols {
+			candidates[i] = sym.ImportPath + "." + sym.Name
+		}
+		return "", fmt.Errorf("symbol %q is ambiguous; qualify it with one of: %s", name, strings.Join(candidates, ", "))
+	case len(symbols) == 1:
+		file, err = describeSymbol(ctx, &b, symbols[0])
+	default:
+		file, err = describeMethod(ctx, &b, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	examples, err := analyzerInstance.UsageExamples(ctx, name, maxPromptExamples)
+	if err == nil && len(examples) > 0 {
+		b.WriteString("\n## Usage in the repository\n\n")
+		for _, example := range examples {
+			fmt.Fprintf(&b, "From %s (%s:%d):\n\n```go\n%s\n```\n\n", example.Function, relPath(analyzerInstance.RepoPath(), example.Position.Filename), example.Position.Line, example.Code)
+		}
+	}
+
+	testFile := strings.TrimSuffix(file, ".go") + "_test.go"
+	existing := "Create it"
+	if _, err := os.Stat(testFile); err == nil {
+		existing = "It already exists; add to it and follow its conventions"
+	}
+	fmt.Fprintf(&b, "\nWrite Go tests for %s in %s. %s. Use the standard testing package, cover normal use, edge cases and error paths, prefer table-driven tests where cases share a shape, and only exercise behavior the signatures and documentation above promise.\n", name, relPath(analyzerInstance.RepoPath(), testFile), existing)
+	return b.String(), nil
+}
+
+// describeSymbol writes the declaration of a package-level symbol and
+// returns the file declaring it
+func describeSymbol(ctx context.Context, b *strings.Builder, sym analyzer.Symbol) (string, error) {
+	qualified := sym.ImportPath + "." + sym.Name
+	fmt.Fprintf(b, "## %s %s\n\nDeclared in %s:%d\n\n", sym.Kind, qualified, relPath(analyzerInstance.RepoPath(), sym.Position.Filename), sym.Position.Line)
+	switch sym.Kind {
+	case "type":
+		info, err := analyzerInstance.LookupType(ctx, qualified)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(b, "%s\n", declLine(fmt.Sprintf("type %s %s", info.Name, info.Kind), info.Doc))
+		var fields, methods []string
+		for _, field := range info.Fields {
+			fields = append(fields, declLine(field.Name+" "+field.Type, field.Doc))
+		}
+		for _, method := range info.Methods {
+			methods = append(methods, declLine(funcDecl(method.Name, method.Signature), method.Doc))
+		}
+		writeSection(b, "Fields", fields)
+		writeSection(b, "Methods", methods)
+	case "func":
+		result, err := analyzerInstance.AnalyzeRepository(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, fn := range result.Functions {
+			if fn.Position.Filename == sym.Position.Filename && fn.Position.Line == sym.Position.Line {
+				fmt.Fprintf(b, "%s\n", declLine(funcDecl(fn.Name, fn.Signature), fn.Doc))
+				break
+			}
+		}
+	}
+	return sym.Position.Filename, nil
+}
+
+// describeMethod writes the declaration of a method named Type.Method and
+// returns the file declaring it
+func describeMethod(ctx context.Context, b *strings.Builder, name string) (string, error) {
+	typeName, methodName, ok := cutLast(name, ".")
+	if !ok {
+		return "", fmt.Errorf("symbol %q not found", name)
+	}
+	methods, err := analyzerInstance.ListMethods(ctx, typeName)
+	if err != nil {
+		return "", fmt.Errorf("symbol %q not found: %w", name, err)
+	}
+	for _, method := range methods {
+		if method.Name != methodName {
+			continue
+		}
+		fmt.Fprintf(b, "## method %s\n\nDeclared in %s:%d\n\n%s\n", name, relPath(analyzerInstance.RepoPath(), method.Position.Filename), method.Position.Line, declLine(funcDecl(method.Name, method.Signature), method.Doc))
+		if info, err := analyzerInstance.LookupType(ctx, typeName); err == nil {
+			fmt.Fprintf(b, "\nReceiver: %s\n", declLine(fmt.Sprintf("type %s %s", info.Name, info.Kind), info.Doc))
+		}
+		return method.Position.Filename, nil
+	}
+	return "", fmt.Errorf("type %s has no method %s", typeName, methodName)
+}
+
+func reviewDiffPrompt(args ReviewDiffArgs) (*mcp.PromptResponse, error) {
+	slog.Info("Filling review_diff prompt", "base", args.Base)
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+	start := time.Now()
+	text, err := reviewDiffText(ctx, args.Base)
+	metrics.AnalyzerDuration.ObserveDuration(start, "prompt_review_diff")
+	if err != nil {
+		return nil, err
+	}
+	return promptResponse("Review changes since "+args.Base, text), nil
+}
+
+// reviewDiffText fills the review_diff template
+func reviewDiffText(ctx context.Context, base string) (string, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	diff, err := hooks.Diff(ctx, analyzerInstance.RepoPath(), base)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", fmt.Errorf("no changes since %s", base)
+	}
+	truncated := len(diff) > maxPromptDiffBytes
+	if truncated {
+		diff = diff[:maxPromptDiffBytes]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review the following changes to this Go repository since %s. Look for bugs, missing error handling, concurrency problems, API breaks and missing tests, and suggest concrete fixes. Confirm or dismiss each automated finding below.\n\n", base)
+	fmt.Fprintf(&b, "## Diff\n\n```diff\n%s\n```\n", strings.TrimRight(diff, "\n"))
+	if truncated {
+		fmt.Fprintf(&b, "\nThe diff was truncated to %d bytes.\n", maxPromptDiffBytes)
+	}
+
+	diagnostics, err := checkChangesSince(ctx, base, reviewChecks...)
+	if err != nil {
+		fmt.Fprintf(&b, "\nAutomated checks failed: %v\n", err)
+		return b.String(), nil
+	}
+	checks.Sort(diagnostics)
+	var findings []string
+	for _, d := range diagnostics {
+		findings = append(findings, fmt.Sprintf("%s:%d: %s: %s (%s)", relPath(analyzerInstance.RepoPath(), d.File), d.Line, d.Severity, d.Message, d.Check))
+	}
+	if len(findings) == 0 {
+		findings = append(findings, "None")
+	}
+	writeSection(&b, "Automated findings", findings)
+	return b.String(), nil
+}
+
+// declLine renders a declaration followed by the first line of its doc
+func declLine(decl, doc string) string {
+	if synopsis, _, _ := strings.Cut(strings.TrimSpace(doc), "\n"); synopsis != "" {
+		return decl + " // " + synopsis
+	}
+	return decl
+}
+
+// funcDecl names a signature such as func(x int) error
+func funcDecl(name, signature string) string {
+	return "func " + name + strings.TrimPrefix(signature, "func")
+}
+
+// writeSection writes a titled list, or nothing when it is empty
+func writeSection(b *strings.Builder, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, line := range lines {
+		fmt.Fprintf(b, "- %s\n", line)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mcp "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport/stdio"
+)
+
+func TestRegisterPrompts(t *testing.T) {
+	server := mcp.NewServer(stdio.NewStdioServerTransport())
+	if err := registerPrompts(server); err != nil {
+		t.Fatalf("Failed to register prompts: %v", err)
+	}
+	for _, name := range []string{"explain_package", "write_tests", "review_diff"} {
+		if !server.CheckPromptRegistered(name) {
+			t.Errorf("Expected %s prompt to be registered", name)
+		}
+	}
+}
+
+func TestExplainPackagePrompt(t *testing.T) {
+	resp, err := explainPackagePrompt(ExplainPackageArgs{Package: "testpkg"})
+	if err != nil {
+		t.Fatalf("explainPackagePrompt failed: %v", err)
+	}
+	if len(resp.Messages) != 1 || resp.Messages[0].Role != mcp.RoleUser || resp.Messages[0].Content.TextContent == nil {
+		t.Fatalf("Expected a single user message, got %+v", resp.Messages)
+	}
+	text := resp.Messages[0].Content.TextContent.Text
+	for _, want := range []string{"test.go", "type TestStruct struct // TestStruct is a test struct", "TestMethod", "NewTestStruct"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	if _, err := explainPackagePrompt(ExplainPackageArgs{}); err == nil {
+		t.Error("Expected an error without a package")
+	}
+}
+
+func TestWriteTestsText(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		symbol string
+		want   []string
+	}{
+		{"NewTestStruct", []string{"## func", "NewTestStruct returns a TestStruct with Field set", "test_test.go"}},
+		{"TestStruct", []string{"## type", "Field string", "TestMethod"}},
+		{"TestStruct.TestMethod", []string{"## method TestStruct.TestMethod", "TestMethod is a test method", "Receiver: type TestStruct struct"}},
+	}
+	for _, tt := range tests {
+		text, err := writeTestsText(ctx, tt.symbol)
+		if err != nil {
+			t.Errorf("writeTestsText(%s) failed: %v", tt.symbol, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("Expected %s prompt to contain %q, got:\n%s", tt.symbol, want, text)
+			}
+		}
+	}
+
+	for _, symbol := range []string{"", "Missing", "TestStruct.Missing"} {
+		if _, err := writeTestsText(ctx, symbol); err == nil {
+			t.Errorf("Expected an error for %q", symbol)
+		}
+	}
+}
+
+func TestReviewDiffTextOutsideGit(t *testing.T) {
+	// The test repository is not a git repository
+	if _, err := reviewDiffText(context.Background(), ""); err == nil {
+		t.Error("Expected an error outside a git repository")
+	}
+}
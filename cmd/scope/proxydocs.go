@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/modproxy"
+)
+
+// proxyTimeout bounds a module proxy lookup, which may download a module
+const proxyTimeout = 30 * time.Second
+
+// proxyDocs looks up the documentation of dependencies in a module proxy
+// when lookups miss locally; nil unless enabled with -proxy-docs
+var proxyDocs *modproxy.Client
+
+// newProxyDocs creates the client -proxy-docs asks for: "on" uses the proxy
+// GOPROXY names, anything else is the URL of a proxy
+func newProxyDocs(setting string) *modproxy.Client {
+	if setting == "on" {
+		return modproxy.NewClient(modproxy.ProxyFromEnv())
+	}
+	return modproxy.NewClient(setting)
+}
+
+// lookupViaProxy looks a name qualified with a package outside the
+// repository up in the module proxy. The package may be given by import
+// path or by the name the repository imports it under. Modules the
+// repository's go.mod requires are looked up at the required version.
+func lookupViaProxy(ctx context.Context, name string) (*modproxy.Symbol, error) {
+	qualifier, ident, ok := cutLast(name, ".")
+	if !ok {
+		return nil, fmt.Errorf("qualify %s with its package to look it up in the module proxy", name)
+	}
+	if cached, found := cachedResult[*modproxy.Symbol](ctx, cacheKey("proxy", name)); found {
+		return cached, nil
+	}
+	paths, err := analyzerInstance.ExternalPackages(ctx, qualifier)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no imported package matches %s", qualifier)
+	}
+	versions, reqErr := modproxy.Requirements(filepath.Join(analyzerInstance.RepoPath(), "go.mod"))
+	if reqErr != nil {
+		slog.DebugContext(ctx, "Looking up latest module versions", "error", reqErr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyTimeout)
+	defer cancel()
+	start := time.Now()
+	defer metrics.AnalyzerDuration.ObserveDuration(start, "proxy_lookup")
+	for _, importPath := range paths {
+		sym, lookupErr := proxyDocs.Lookup(ctx, importPath, ident, versions)
+		if lookupErr != nil {
+			err = lookupErr
+			continue
+		}
+		if err := cacheInstance.Set(ctx, cacheKey("proxy", name), sym, 24*time.Hour); err != nil {
+			slog.WarnContext(ctx, "Failed to cache proxy lookup", "error", err)
+		}
+		return sym, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/modproxy"
+)
+
+func TestLookupTypeViaProxy(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("example.com/remote@v0.4.0/client/client.go")
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	f.Write([]byte("package client\n\n// Client talks to the service\ntype Client struct{}\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/remote/@latest":
+			w.Write([]byte(`{"Version":"v0.4.0"}`))
+		case "/example.com/remote/@v/v0.4.0.zip":
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	if _, err := lookupTypeHandler(ctx, LookupTypeArgs{TypeName: "example.com/remote/client.Client"}); err == nil {
+		t.Fatal("Expected the lookup to miss without a module proxy")
+	}
+
+	proxyDocs = modproxy.NewClient(server.URL)
+	defer func() { proxyDocs = nil }()
+	response, err := lookupTypeHandler(ctx, LookupTypeArgs{TypeName: "example.com/remote/client.Client"})
+	if err != nil {
+		t.Fatalf("lookupTypeHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"external":true`) || !strings.Contains(text, `"version":"v0.4.0"`) || !strings.Contains(text, "Client talks to the service") {
+		t.Errorf("Expected the external documentation of Client, got %s", text)
+	}
+
+	if _, err := lookupTypeHandler(ctx, LookupTypeArgs{TypeName: "example.com/remote/client.Missing"}); err == nil {
+		t.Error("Expected error for a symbol the proxy does not have either")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ReadFileArgs struct {
+	File    string   `json:"file" jsonschema:"required,description=File to read; relative to the repository or absolute"`
+	Chunks  []string `json:"chunks,omitempty" jsonschema:"description=IDs of the chunks to return; defaults to all"`
+	Outline bool     `json:"outline,omitempty" jsonschema:"description=Only return the IDs; lines and declarations of the chunks without their text"`
+	ResponseBudget
+}
+
+// FileChunks is a file read in chunks
+type FileChunks struct {
+	File       string       `json:"file"`
+	TotalLines int          `json:"total_lines"`
+	Chunks     []edit.Chunk `json:"chunks"`
+	// Missing are the chunks asked for that the file no longer holds
+	Missing []string `json:"missing,omitempty"`
+}
+
+func readFileHandler(ctx context.Context, args ReadFileArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reading file", "file", args.File, "chunks", len(args.Chunks), "outline", args.Outline)
+	filename, err := repoFile(args.File)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	// Chunks are read from disk rather than overlays, as the edits that
+	// refer to them are applied there
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", args.File, err)
+	}
+	chunks := edit.Chunks(filename, src)
+	metrics.AnalyzerDuration.ObserveDuration(start, "read_file")
+
+	result := FileChunks{File: relPath(analyzerInstance.RepoPath(), filename), Chunks: []edit.Chunk{}}
+	if len(chunks) > 0 {
+		result.TotalLines = chunks[len(chunks)-1].EndLine
+	}
+	for _, c := range chunks {
+		if len(args.Chunks) > 0 && !slices.Contains(args.Chunks, c.ID) {
+			continue
+		}
+		if args.Outline {
+			c.Text = ""
+		}
+		result.Chunks = append(result.Chunks, c)
+	}
+	for _, id := range args.Chunks {
+		if !slices.ContainsFunc(chunks, func(c edit.Chunk) bool { return c.ID == id }) {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file chunks: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestReadFileHandler(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/shop\n\ngo 1.21\n",
+		"cart.go": "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n\n// Len returns the number of items\nfunc (c *Cart) Len() int { return len(c.Items) }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	ctx := context.Background()
+
+	read := func(args ReadFileArgs) FileChunks {
+		t.Helper()
+		response, err := readFileHandler(ctx, args)
+		if err != nil {
+			t.Fatalf("readFileHandler failed: %v", err)
+		}
+		var result FileChunks
+		if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+			t.Fatalf("Failed to unmarshal chunks: %v", err)
+		}
+		return result
+	}
+	result := read(ReadFileArgs{File: "cart.go"})
+	if result.File != "cart.go" || result.TotalLines != 9 || len(result.Chunks) != 3 || result.Chunks[2].Name != "Cart.Len" || !strings.Contains(result.Chunks[2].Text, "func (c *Cart) Len") {
+		t.Fatalf("Unexpected chunks %+v", result)
+	}
+	lenChunk := result.Chunks[2].ID
+
+	// A field added above Len shifts its lines but not its ID
+	if _, err := codeEditHandler(ctx, CodeEditArgs{File: "cart.go", Edits: []CodeEditOperation{{Op: "add_field", Type: "Cart", Code: "Owner string"}}}); err != nil {
+		t.Fatalf("Failed to add field: %v", err)
+	}
+	outline := read(ReadFileArgs{File: "cart.go", Chunks: []string{lenChunk, "000000000000"}, Outline: true})
+	if len(outline.Chunks) != 1 || outline.Chunks[0].StartLine != 9 || outline.Chunks[0].Text != "" || len(outline.Missing) != 1 || outline.Missing[0] != "000000000000" {
+		t.Errorf("Expected the moved chunk and a missing one, got %+v", outline)
+	}
+
+	response, err := codeEditHandler(ctx, CodeEditArgs{File: "cart.go", Edits: []CodeEditOperation{{Op: "replace_chunk", Chunk: lenChunk, Code: "// Len counts the items\nfunc (c *Cart) Len() int { return len(c.Items) }"}}})
+	if err != nil {
+		t.Fatalf("Failed to replace chunk: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `+// Len counts the items`) {
+		t.Errorf("Expected the chunk replaced, got %s", text)
+	}
+
+	if _, err := readFileHandler(ctx, ReadFileArgs{File: "../outside.go"}); err == nil {
+		t.Error("Expected a file outside the repository to fail")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ReadRangeArgs struct {
+	File      string `json:"file" jsonschema:"required,description=File to read; relative to the repository or absolute"`
+	StartLine int    `json:"start_line" jsonschema:"required,description=First line to read (1-based)"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema:"description=Last line to read (inclusive); defaults to start_line"`
+	Context   int    `json:"context,omitempty" jsonschema:"description=Lines of context to add before and after the range"`
+	Before    int    `json:"before,omitempty" jsonschema:"description=Lines of context before the range; overrides context"`
+	After     int    `json:"after,omitempty" jsonschema:"description=Lines of context after the range; overrides context"`
+	Snap      bool   `json:"snap,omitempty" jsonschema:"description=Widen the range to the complete declarations it overlaps (Go files only)"`
+	ResponseBudget
+}
+
+func readRangeHandler(ctx context.Context, args ReadRangeArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reading range", "file", args.File, "start_line", args.StartLine, "end_line", args.EndLine, "snap", args.Snap)
+	opts := analyzer.RangeOptions{
+		StartLine: args.StartLine,
+		EndLine:   args.EndLine,
+		Before:    args.Context,
+		After:     args.Context,
+		Snap:      args.Snap,
+	}
+	if args.Before > 0 {
+		opts.Before = args.Before
+	}
+	if args.After > 0 {
+		opts.After = args.After
+	}
+
+	start := time.Now()
+	result, err := analyzerInstance.ReadRange(args.File, opts)
+	metrics.AnalyzerDuration.ObserveDuration(start, "read_range")
+	if err != nil {
+		return nil, err
+	}
+	result.Filename = relPath(analyzerInstance.RepoPath(), result.Filename)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal range: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
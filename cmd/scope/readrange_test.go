@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestReadRangeHandler(t *testing.T) {
+	def, err := analyzerInstance.LookupType(context.Background(), "TestStruct")
+	if err != nil {
+		t.Fatalf("Failed to look up TestStruct: %v", err)
+	}
+
+	response, err := readRangeHandler(context.Background(), ReadRangeArgs{File: def.Position.Filename, StartLine: def.Position.Line, Snap: true})
+	if err != nil {
+		t.Fatalf("readRangeHandler failed: %v", err)
+	}
+	var result analyzer.SourceRange
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to unmarshal range: %v", err)
+	}
+	if result.Filename != "test.go" || !strings.Contains(result.Text, "type TestStruct struct") || !strings.HasSuffix(result.Text, "}\n") {
+		t.Errorf("Expected the TestStruct declaration in test.go, got %+v", result)
+	}
+
+	if _, err := readRangeHandler(context.Background(), ReadRangeArgs{File: "test.go"}); err == nil {
+		t.Error("Expected an error without a start line")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/apidiff"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ReleaseReportArgs struct {
+	From    string `json:"from" jsonschema:"required,description=Git tag (or other revision) of the previous release"`
+	To      string `json:"to,omitempty" jsonschema:"description=Git tag (or other revision) of the new release; defaults to HEAD"`
+	Package string `json:"package,omitempty" jsonschema:"description=Only report this package (import path or package name)" session:"package"`
+	ResponseBudget
+}
+
+func releaseReportHandler(ctx context.Context, args ReleaseReportArgs) (*mcp.ToolResponse, error) {
+	if args.From == "" {
+		return nil, fmt.Errorf("from is required")
+	}
+	to := args.To
+	if to == "" {
+		to = "HEAD"
+	}
+	slog.InfoContext(ctx, "Building release report", "from", args.From, "to", to)
+
+	start := time.Now()
+	config := analyzerInstance.Config()
+	old, err := apidiff.AtRef(ctx, analyzerInstance.RepoPath(), args.From, &config)
+	if err != nil {
+		return nil, err
+	}
+	current, err := apidiff.AtRef(ctx, analyzerInstance.RepoPath(), to, &config)
+	if err != nil {
+		return nil, err
+	}
+	report := apidiff.Compare(old.Filter(args.Package), current.Filter(args.Package))
+	release := apidiff.NewRelease(args.From, to, report)
+	metrics.AnalyzerDuration.ObserveDuration(start, "release_report")
+
+	jsonData, err := json.Marshal(release)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal release report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/remote"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// remoteRepo is the clone being analyzed when GO_REPO_PATH is a git URL;
+// nil for a local repository
+var remoteRepo *remote.Repo
+
+type RefreshRepoArgs struct {
+	ResponseBudget
+}
+
+// RefreshResult reports what refresh_repo fetched and re-analyzed
+type RefreshResult struct {
+	// Remote is the URL of the cloned repository; empty for a local one
+	Remote string `json:"remote,omitempty"`
+	// Before and After are the commits checked out in the clone
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// Updated reports whether the fetch brought new commits
+	Updated  bool          `json:"updated"`
+	Duration time.Duration `json:"duration"`
+}
+
+// openRemote clones the repository a GO_REPO_PATH URL names under root, or
+// updates the clone a previous run made, and returns the directory to
+// analyze. A clone that cannot be updated is analyzed as is.
+func openRemote(ctx context.Context, spec, root string) (string, error) {
+	url, ref := remote.Parse(spec)
+	dir := remote.Dir(root, url, ref)
+	repo, err := remote.Clone(ctx, url, ref, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	if _, _, err := repo.Update(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to update clone, analyzing it as is", "url", url, "error", err)
+	}
+	remoteRepo = repo
+	slog.InfoContext(ctx, "Analyzing clone", "url", url, "ref", ref, "dir", dir)
+	return dir, nil
+}
+
+func refreshRepoHandler(ctx context.Context, args RefreshRepoArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Refreshing repository")
+	start := time.Now()
+	var result RefreshResult
+	if remoteRepo != nil {
+		before, after, err := remoteRepo.Update(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update clone of %s: %w", remoteRepo.URL, err)
+		}
+		result.Remote, result.Before, result.After = remoteRepo.URL, before, after
+		result.Updated = before != after
+	}
+	// A local repository may have changed without the watcher noticing
+	if remoteRepo == nil || result.Updated {
+		if err := analyzerInstance.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("failed to refresh analysis: %w", err)
+		}
+		invalidateAnalysisCache(ctx)
+	}
+	metrics.AnalyzerDuration.ObserveDuration(start, "refresh_repo")
+	result.Duration = time.Since(start)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refresh result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
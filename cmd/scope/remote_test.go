@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestRefreshRepoHandler(t *testing.T) {
+	upstream := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = upstream
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(upstream, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	git("init", "-q")
+	write("go.mod", "module example.com/lib\n\ngo 1.21\n")
+	write("lib.go", "package lib\n\ntype Client struct{}\n")
+	git("add", "-A")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+
+	ctx := context.Background()
+	previousRepo := remoteRepo
+	defer func() { remoteRepo = previousRepo }()
+	dir, err := openRemote(ctx, "file://"+upstream, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open remote: %v", err)
+	}
+	cloned, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer cloned.Close()
+	previous := analyzerInstance
+	analyzerInstance = cloned
+	defer func() { analyzerInstance = previous }()
+
+	write("server.go", "package lib\n\ntype Server struct{}\n")
+	git("add", "-A")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "add server")
+
+	response, err := refreshRepoHandler(ctx, RefreshRepoArgs{})
+	if err != nil {
+		t.Fatalf("refreshRepoHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"updated":true`) {
+		t.Errorf("Expected the clone to be updated, got %s", text)
+	}
+	if _, err := cloned.LookupType(ctx, "Server"); err != nil {
+		t.Errorf("Expected Server after the refresh: %v", err)
+	}
+
+	response, err = refreshRepoHandler(ctx, RefreshRepoArgs{})
+	if err != nil {
+		t.Fatalf("refreshRepoHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"updated":false`) {
+		t.Errorf("Expected nothing new to fetch, got %s", text)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/hooks"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/report"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+var rendererInstance *report.Renderer
+
+// reviewChecks are the checks whose findings make up a review report
+var reviewChecks = []string{"build_check", "lint", "api-compat"}
+
+// templateDirs returns the directories searched for user templates: the
+// repository's .scope/templates followed by SCOPE_TEMPLATE_DIR
+func templateDirs(repoPath string) []string {
+	return []string{
+		filepath.Join(repoPath, ".scope", "templates"),
+		os.Getenv("SCOPE_TEMPLATE_DIR"),
+	}
+}
+
+type RenderReportArgs struct {
+	Template         string `json:"template" jsonschema:"required,description=Template to render (built-in: review, changelog, onboarding, type; or a .tmpl file from .scope/templates)"`
+	Ref              string `json:"ref" jsonschema:"required,description=Result to render: type:<name>, package:<name>, repository, review:<git ref> or changelog:<git ref>"`
+	ExcludeGenerated bool   `json:"exclude_generated,omitempty" jsonschema:"description=For repository: leave declarations and packages of generated files out of the result and its metrics"`
+	ResponseBudget
+}
+
+func renderReportHandler(ctx context.Context, args RenderReportArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Rendering report", "template", args.Template, "ref", args.Ref)
+	start := time.Now()
+	kind, data, err := resolveResult(ctx, args.Ref)
+	metrics.AnalyzerDuration.ObserveDuration(start, "render_report")
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := data.(*analyzer.AnalysisResult); ok && args.ExcludeGenerated {
+		data = result.WithoutGenerated()
+	}
+
+	var buf bytes.Buffer
+	err = rendererInstance.Render(&buf, args.Template, report.Report{
+		Kind: kind,
+		Ref:  strings.TrimPrefix(args.Ref, kind+":"),
+		Root: analyzerInstance.RepoPath(),
+		Data: data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(buf.String())), nil
+}
+
+// resolveResult produces the analysis result a report reference points to
+func resolveResult(ctx context.Context, ref string) (string, interface{}, error) {
+	kind, arg, _ := strings.Cut(ref, ":")
+	switch kind {
+	case "type":
+		data, err := analyzerInstance.LookupType(ctx, arg)
+		return kind, data, err
+	case "package":
+		data, err := analyzerInstance.GetPackageInfo(ctx, arg)
+		return kind, data, err
+	case "repository":
+		data, err := analyzerInstance.AnalyzeRepository(ctx)
+		return kind, data, err
+	case "review":
+		data, err := checkChangesSince(ctx, arg, reviewChecks...)
+		return kind, data, err
+	case "changelog":
+		data, err := checkChangesSince(ctx, arg, "api-compat")
+		return kind, data, err
+	default:
+		return "", nil, fmt.Errorf("unknown result reference %q (expected type:, package:, repository, review: or changelog:)", ref)
+	}
+}
+
+// checkChangesSince runs the named checks against the files that differ
+// between base and the working tree
+func checkChangesSince(ctx context.Context, base string, names ...string) ([]checks.Diagnostic, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	repoPath := analyzerInstance.RepoPath()
+	files, err := hooks.FilesChangedFrom(ctx, repoPath, base)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	target, err := hooks.NewTarget(ctx, repoPath, files)
+	if err != nil {
+		return nil, err
+	}
+	target.Base = base
+	return checks.Run(ctx, target, names...)
+}
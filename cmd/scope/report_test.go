@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+func TestRenderReportHandler(t *testing.T) {
+	response, err := renderReportHandler(context.Background(), RenderReportArgs{Template: "type", Ref: "type:TestStruct"})
+	if err != nil {
+		t.Fatalf("renderReportHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	for _, want := range []string{"# testpkg.TestStruct", "TestMethod"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+
+	if _, err := renderReportHandler(context.Background(), RenderReportArgs{Template: "type", Ref: "bogus:x"}); err == nil {
+		t.Error("Expected error for unknown reference kind")
+	}
+	if _, err := renderReportHandler(context.Background(), RenderReportArgs{Template: "missing", Ref: "type:TestStruct"}); err == nil {
+		t.Error("Expected error for unknown template")
+	}
+}
+
+// responseText returns the text of a single-content tool response
+func responseText(t *testing.T, response *mcp.ToolResponse) string {
+	t.Helper()
+	if response == nil || len(response.Content) != 1 || response.Content[0].TextContent == nil {
+		t.Fatalf("Expected a single text content, got %+v", response)
+	}
+	return response.Content[0].TextContent.Text
+}
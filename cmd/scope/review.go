@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/llm"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/review"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type CodeReviewArgs struct {
+	Changes string `json:"changes" jsonschema:"required,description=The code changes to review; a unified diff of Go files also gets a summary of the declarations it changes"`
+	ResponseBudget
+}
+
+// CodeReviewResult is the review of the code_review tool with a summary of
+// the declarations a diff changes
+type CodeReviewResult struct {
+	Review  string          `json:"review"`
+	Changes *review.Summary `json:"changes,omitempty"`
+	// Model and Usage are set when the review comes from the configured
+	// language model rather than the external command
+	Model string     `json:"model,omitempty"`
+	Usage *llm.Usage `json:"usage,omitempty"`
+}
+
+// reviewPrompt instructs the language model reviewing changes
+const reviewPrompt = `You review changes to a Go repository. Point out bugs, unhandled errors, ` +
+	`races, API changes that break callers and missing tests, citing the file and line of each finding, ` +
+	`most serious first. Do not restate the changes. Answer "No issues found." when there is nothing to fix.`
+
+func codeReviewHandler(ctx context.Context, args CodeReviewArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Executing code review")
+	tool, ok := toolManager.GetTool("code_review")
+	if !ok && llmClient == nil {
+		return nil, fmt.Errorf("code_review tool not found")
+	}
+
+	start := time.Now()
+	summary, err := review.Summarize(analyzerInstance.RepoPath(), args.Changes)
+	metrics.AnalyzerDuration.ObserveDuration(start, "review_summary")
+	if err != nil {
+		// The review itself does not need the changes to be a valid diff
+		slog.WarnContext(ctx, "Failed to summarize changes", "error", err)
+	}
+
+	result := CodeReviewResult{Changes: summary}
+	if llmClient != nil {
+		resp, err := llmClient.Complete(ctx, llm.Request{
+			System:   reviewPrompt,
+			Messages: []llm.Message{{Role: llm.RoleUser, Content: reviewInput(args.Changes, summary)}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("code review failed: %w", err)
+		}
+		result.Review, result.Model, result.Usage = resp.Text, resp.Model, &resp.Usage
+	} else {
+		output, err := tool.Execute(ctx, args.Changes)
+		if err != nil {
+			return nil, fmt.Errorf("code review failed: %w", err)
+		}
+		result.Review = output
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code review: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// reviewInput is the message asking the language model for a review: the
+// changes followed by the summary of the declarations they change, which
+// flags changed signatures and API the diff alone does not show
+func reviewInput(changes string, summary *review.Summary) string {
+	var b strings.Builder
+	b.WriteString("Changes:\n\n")
+	b.WriteString(changes)
+	if summary != nil && len(summary.Files) > 0 {
+		if data, err := json.MarshalIndent(summary, "", "  "); err == nil {
+			b.WriteString("\n\nDeclarations changed:\n\n")
+			b.Write(data)
+		}
+	}
+	return b.String()
+}
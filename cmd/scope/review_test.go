@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/llm"
+	"github.com/TFMV/scope/internal/review"
+	"github.com/TFMV/scope/internal/tools"
+)
+
+func TestCodeReviewHandler(t *testing.T) {
+	previousManager := toolManager
+	defer func() { toolManager = previousManager }()
+	toolManager = tools.NewToolManager()
+	toolManager.RegisterTool(tools.ToolConfig{Name: "code_review", Command: "echo", Args: []string{"looks good"}})
+
+	// A proposed patch against the test package, which is not applied yet
+	old, err := os.ReadFile(filepath.Join(analyzerInstance.RepoPath(), "test.go"))
+	if err != nil {
+		t.Fatalf("Failed to read test.go: %v", err)
+	}
+	patched := string(old) + "\n// Reset clears Field\nfunc (t *TestStruct) Reset() {\n\tt.Field = \"\"\n}\n"
+	diff := edit.Unified("test.go", old, []byte(patched))
+
+	response, err := codeReviewHandler(context.Background(), CodeReviewArgs{Changes: diff})
+	if err != nil {
+		t.Fatalf("codeReviewHandler failed: %v", err)
+	}
+	var result CodeReviewResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if strings.TrimSpace(result.Review) != "looks good" {
+		t.Errorf("Expected the review of the tool, got %q", result.Review)
+	}
+	if result.Changes == nil || len(result.Changes.Files) != 1 {
+		t.Fatalf("Expected a summary of test.go, got %+v", result.Changes)
+	}
+	decls := result.Changes.Files[0].Declarations
+	if len(decls) != 1 || decls[0].Name != "TestStruct.Reset" || decls[0].Change != review.Added || len(decls[0].Annotations) != 1 || decls[0].Annotations[0] != review.NewExportedAPI {
+		t.Errorf("Expected TestStruct.Reset as new exported API, got %+v", decls)
+	}
+
+	// Changes that are not a diff are only reviewed
+	response, err = codeReviewHandler(context.Background(), CodeReviewArgs{Changes: "rename a variable"})
+	if err != nil {
+		t.Fatalf("codeReviewHandler failed: %v", err)
+	}
+	if text := responseText(t, response); strings.Contains(text, `"changes"`) {
+		t.Errorf("Expected no summary without a diff, got %s", text)
+	}
+}
+
+// fakeModel gives the same answer to every request and keeps the last
+// request
+type fakeModel struct {
+	answer string
+	last   llm.Request
+}
+
+func (m *fakeModel) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	m.last = req
+	return &llm.Response{Text: m.answer, Model: "fake", Usage: llm.Usage{InputTokens: 40, OutputTokens: 3}}, nil
+}
+
+func TestCodeReviewHandlerWithModel(t *testing.T) {
+	previousManager := toolManager
+	defer func() { toolManager = previousManager }()
+	toolManager = tools.NewToolManager()
+	model := &fakeModel{answer: "add a test"}
+	llmClient = llm.NewClient(model, 1000, 0)
+	defer func() { llmClient = nil }()
+
+	old, err := os.ReadFile(filepath.Join(analyzerInstance.RepoPath(), "test.go"))
+	if err != nil {
+		t.Fatalf("Failed to read test.go: %v", err)
+	}
+	diff := edit.Unified("test.go", old, append(old, "\nfunc Added() {}\n"...))
+
+	// No external code_review command is configured
+	response, err := codeReviewHandler(context.Background(), CodeReviewArgs{Changes: diff})
+	if err != nil {
+		t.Fatalf("codeReviewHandler failed: %v", err)
+	}
+	var result CodeReviewResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result.Review != "add a test" || result.Model != "fake" || result.Usage == nil || result.Usage.InputTokens != 40 {
+		t.Errorf("Expected the model's review, got %+v", result)
+	}
+	if input := model.last.Messages[0].Content; !strings.Contains(input, diff) || !strings.Contains(input, `"name": "Added"`) {
+		t.Errorf("Expected the diff and its declarations in the request, got %s", input)
+	}
+	if model.last.System == "" || model.last.MaxTokens != 1000 {
+		t.Errorf("Expected the review prompt and token cap, got %+v", model.last)
+	}
+	if stats := llmClient.Stats(); stats.Calls != 1 || stats.OutputTokens != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
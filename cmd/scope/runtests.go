@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/gorun"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type RunTestsArgs struct {
+	Package string `json:"package,omitempty" jsonschema:"description=Package pattern to test relative to the repository, e.g. ./internal/cache or ./... (default ./...)"`
+	Run     string `json:"run,omitempty" jsonschema:"description=Regular expression selecting the tests to run, as for go test -run"`
+	Short   bool   `json:"short,omitempty" jsonschema:"description=Pass -short to skip long-running tests"`
+	Timeout string `json:"timeout,omitempty" jsonschema:"description=Timeout for the whole run, e.g. 2m (default 10m)"`
+	NoCache bool   `json:"no_cache,omitempty" jsonschema:"description=Run tests even when go test has cached results"`
+	ResponseBudget
+}
+
+func runTestsHandler(ctx context.Context, args RunTestsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Running tests", "package", args.Package, "run", args.Run)
+	opts := gorun.Options{Run: args.Run, Short: args.Short, NoCache: args.NoCache, Timeout: 10 * time.Minute}
+	if args.Package != "" {
+		opts.Packages = []string{args.Package}
+	}
+	if args.Timeout != "" {
+		timeout, err := time.ParseDuration(args.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", args.Timeout, err)
+		}
+		opts.Timeout = timeout
+	}
+
+	// Leave go test time to report the timed-out test before it is killed
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout+30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	result, err := gorun.Run(ctx, analyzerInstance.RepoPath(), opts)
+	metrics.AnalyzerDuration.ObserveDuration(start, "run_tests")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal test results: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunTestsHandler(t *testing.T) {
+	if _, err := runTestsHandler(context.Background(), RunTestsArgs{Timeout: "soon"}); err == nil {
+		t.Error("Expected error for an invalid timeout")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/gorun"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/policy"
+	"github.com/TFMV/scope/internal/sandbox"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// sandboxes holds the copies of the repository sandboxed code_edit calls
+// edit
+var sandboxes *sandbox.Store
+
+// SandboxEdit is the response of a sandboxed code_edit
+type SandboxEdit struct {
+	edit.Result
+	Sandbox string `json:"sandbox"`
+	// Files are all files edited in the sandbox so far
+	Files []string     `json:"files"`
+	Build *BuildReport `json:"build"`
+	// Tests are set with run_tests
+	Tests *gorun.Result `json:"tests,omitempty"`
+	// OK is true when the sandbox builds, vets and passes the tests
+	OK bool `json:"ok"`
+}
+
+type ConfirmEditArgs struct {
+	Sandbox string `json:"sandbox" jsonschema:"required,description=Sandbox returned by a sandboxed code_edit"`
+	Discard bool   `json:"discard,omitempty" jsonschema:"description=Drop the sandbox instead of writing its files to the repository"`
+	ResponseBudget
+}
+
+// ConfirmEditResult is the response of confirm_edit
+type ConfirmEditResult struct {
+	Sandbox string `json:"sandbox"`
+	// Promoted are the files written to the repository
+	Promoted  []string `json:"promoted"`
+	Discarded bool     `json:"discarded,omitempty"`
+}
+
+// sandboxCodeEdit applies edits to the copy of filename in a sandbox and
+// checks the sandbox
+func sandboxCodeEdit(ctx context.Context, args CodeEditArgs, filename string, edits []edit.Edit) (*mcp.ToolResponse, error) {
+	if args.DryRun {
+		return nil, fmt.Errorf("dry_run cannot be combined with a sandbox")
+	}
+	if sandboxes == nil {
+		return nil, fmt.Errorf("sandboxes are not available")
+	}
+	rel := relPath(analyzerInstance.RepoPath(), filename)
+
+	start := time.Now()
+	var sb *sandbox.Sandbox
+	var err error
+	if args.SandboxID != "" {
+		sb, err = sandboxes.Get(args.SandboxID)
+	} else {
+		sb, err = sandboxes.Create()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A new sandbox whose edits fail is of no use
+	discard := func() {
+		if args.SandboxID == "" {
+			sandboxes.Discard(sb.ID)
+		}
+	}
+	// Nothing reaches the repository before confirm_edit, which is checked
+	// again, but violations are best known before building on them
+	files := append(sb.Files(), rel)
+	if err := writePolicy.Check(policy.Write{Tool: "code_edit", Files: files, DryRun: true}); err != nil {
+		discard()
+		return nil, err
+	}
+	if err := sb.Track(rel); err != nil {
+		discard()
+		return nil, err
+	}
+	result, err := edit.Apply(sb.Path(rel), edits, false)
+	if err != nil {
+		discard()
+		return nil, err
+	}
+	result.File = filepath.ToSlash(rel)
+
+	response := SandboxEdit{Result: *result, Sandbox: sb.ID, Files: sb.Files()}
+	if response.Build, err = checkBuild(ctx, sb.Dir, nil, true); err != nil {
+		return nil, fmt.Errorf("failed to check sandbox %s: %w", sb.ID, err)
+	}
+	response.OK = response.Build.OK
+	if args.RunTests {
+		pkg := "./" + filepath.ToSlash(filepath.Dir(rel))
+		if response.Tests, err = gorun.Run(ctx, sb.Dir, gorun.Options{Packages: []string{pkg}}); err != nil {
+			return nil, fmt.Errorf("failed to test sandbox %s: %w", sb.ID, err)
+		}
+		response.OK = response.OK && response.Tests.Failed == 0
+	}
+	metrics.AnalyzerDuration.ObserveDuration(start, "code_edit")
+	slog.InfoContext(ctx, "Edited sandbox", "sandbox", sb.ID, "file", rel, "ok", response.OK)
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code edit result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+func confirmEditHandler(ctx context.Context, args ConfirmEditArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Confirming edit", "sandbox", args.Sandbox, "discard", args.Discard)
+	if sandboxes == nil {
+		return nil, fmt.Errorf("sandboxes are not available")
+	}
+
+	result := ConfirmEditResult{Sandbox: args.Sandbox, Promoted: []string{}}
+	if args.Discard {
+		if err := sandboxes.Discard(args.Sandbox); err != nil {
+			return nil, err
+		}
+		result.Discarded = true
+	} else {
+		sb, err := sandboxes.Get(args.Sandbox)
+		if err != nil {
+			return nil, err
+		}
+		// Confirming writes what the sandboxed edits previewed
+		if err := writePolicy.Check(policy.Write{Tool: "confirm_edit", Files: sb.Files(), Previewed: true}); err != nil {
+			return nil, err
+		}
+		promoted, err := sandboxes.Promote(args.Sandbox)
+		if len(promoted) > 0 {
+			refreshAfterWrite(ctx, "confirmed edit")
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Promoted = promoted
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal confirm edit result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
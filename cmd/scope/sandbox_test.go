@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/sandbox"
+)
+
+func TestSandboxCodeEdit(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/shop\n\ngo 1.21\n",
+		"cart.go":      "package shop\n\n// Cart holds items\ntype Cart struct {\n\tItems []string\n}\n",
+		"cart_test.go": "package shop\n\nimport \"testing\"\n\nfunc TestCart(t *testing.T) {\n\tif len((&Cart{}).Items) != 0 {\n\t\tt.Error(\"Expected no items\")\n\t}\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	shop, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer shop.Close()
+	previous := analyzerInstance
+	analyzerInstance = shop
+	defer func() { analyzerInstance = previous }()
+	sandboxes = sandbox.NewStore(dir, t.TempDir())
+	defer func() { sandboxes.Close(); sandboxes = nil }()
+	ctx := context.Background()
+
+	// An edit that does not compile is caught in the sandbox
+	response, err := codeEditHandler(ctx, CodeEditArgs{
+		File:    "cart.go",
+		Edits:   []CodeEditOperation{{Op: "add_decl", Code: "var broken int = \"x\""}},
+		Sandbox: true,
+	})
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	var broken SandboxEdit
+	if err := json.Unmarshal([]byte(responseText(t, response)), &broken); err != nil {
+		t.Fatalf("Failed to unmarshal sandbox edit: %v", err)
+	}
+	if broken.OK || broken.Build == nil || broken.Build.Errors == 0 || broken.Sandbox == "" || broken.Diff == "" {
+		t.Errorf("Expected a failed build in the sandbox, got %+v", broken)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "cart.go")); string(data) != files["cart.go"] {
+		t.Error("Expected a sandboxed edit to leave the repository unchanged")
+	}
+	if _, err := confirmEditHandler(ctx, ConfirmEditArgs{Sandbox: broken.Sandbox, Discard: true}); err != nil {
+		t.Fatalf("Failed to discard sandbox: %v", err)
+	}
+
+	response, err = codeEditHandler(ctx, CodeEditArgs{
+		File:     "cart.go",
+		Edits:    []CodeEditOperation{{Op: "add_method", Type: "Cart", Code: "// Len returns the number of items\nfunc (c *Cart) Len() int { return len(c.Items) }"}},
+		Sandbox:  true,
+		RunTests: true,
+	})
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	var edited SandboxEdit
+	if err := json.Unmarshal([]byte(responseText(t, response)), &edited); err != nil {
+		t.Fatalf("Failed to unmarshal sandbox edit: %v", err)
+	}
+	if !edited.OK || edited.Tests == nil || edited.Tests.Passed != 1 || !slices.Equal(edited.Files, []string{"cart.go"}) || edited.File != "cart.go" {
+		t.Errorf("Expected a passing sandbox, got %+v", edited)
+	}
+
+	// A second file joins the same sandbox
+	response, err = codeEditHandler(ctx, CodeEditArgs{
+		File:      "cart_test.go",
+		Edits:     []CodeEditOperation{{Op: "add_decl", Code: "var _ = (&Cart{}).Len"}},
+		SandboxID: edited.Sandbox,
+	})
+	if err != nil {
+		t.Fatalf("codeEditHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"files":["cart.go","cart_test.go"]`) || !strings.Contains(text, `"ok":true`) {
+		t.Errorf("Expected both files in the sandbox, got %s", text)
+	}
+
+	response, err = confirmEditHandler(ctx, ConfirmEditArgs{Sandbox: edited.Sandbox})
+	if err != nil {
+		t.Fatalf("confirmEditHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"promoted":["cart.go","cart_test.go"]`) {
+		t.Errorf("Expected both files to be promoted, got %s", text)
+	}
+	info, err := shop.LookupType(ctx, "Cart")
+	if err != nil {
+		t.Fatalf("Failed to look up Cart: %v", err)
+	}
+	if len(info.Methods) != 1 || info.Methods[0].Name != "Len" {
+		t.Errorf("Expected the analyzer to see the promoted Len method, got %v", info.Methods)
+	}
+
+	if _, err := confirmEditHandler(ctx, ConfirmEditArgs{Sandbox: edited.Sandbox}); err == nil {
+		t.Error("Expected error for a confirmed sandbox")
+	}
+	if _, err := codeEditHandler(ctx, CodeEditArgs{File: "cart.go", Edits: []CodeEditOperation{{Op: "add_import", Path: "strings"}}, Sandbox: true, DryRun: true}); err == nil {
+		t.Error("Expected error for a dry run in a sandbox")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/apidiff"
+	"github.com/TFMV/scope/internal/edit"
+	"github.com/TFMV/scope/internal/gorun"
+	"github.com/TFMV/scope/internal/jobs"
+	"github.com/TFMV/scope/internal/notes"
+	"github.com/TFMV/scope/internal/schema"
+	"github.com/TFMV/scope/internal/session"
+	"github.com/TFMV/scope/internal/tools"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// schemaVersion is added to every tool response as schema_version. Bump it
+// whenever a tool's output changes in a way a client validating it against
+// the schemas of get_schemas would reject: a field removed, renamed or
+// retyped, or a new required field.
+const schemaVersion = 1
+
+// toolOutputs are the types whose JSON encoding each tool returns. Tools
+// missing here return text, such as Markdown, Go source or the output of an
+// external command, or JSON of varying shape like get_annotations.
+var toolOutputs = map[string]reflect.Type{
+	"lookup_type":           reflect.TypeFor[analyzer.TypeInfo](),
+	"list_methods":          reflect.TypeFor[[]analyzer.MethodInfo](),
+	"type_hierarchy":        reflect.TypeFor[analyzer.HierarchyInfo](),
+	"go_to_definition":      reflect.TypeFor[analyzer.Definition](),
+	"search_types":          reflect.TypeFor[[]TypeMatch](),
+	"search_code":           reflect.TypeFor[analyzer.CodeSearchResult](),
+	"read_range":            reflect.TypeFor[analyzer.SourceRange](),
+	"read_file":             reflect.TypeFor[FileChunks](),
+	"set_overlay":           reflect.TypeFor[SetOverlayResult](),
+	"code_edit":             reflect.TypeFor[edit.Result](),
+	"confirm_edit":          reflect.TypeFor[ConfirmEditResult](),
+	"extract_interface":     reflect.TypeFor[ExtractInterfaceResult](),
+	"generate_mock":         reflect.TypeFor[analyzer.Mock](),
+	"add_method":            reflect.TypeFor[AddMethodResult](),
+	"generate_constructor":  reflect.TypeFor[GenerateConstructorResult](),
+	"code_review":           reflect.TypeFor[CodeReviewResult](),
+	"reload_tools":          reflect.TypeFor[tools.ReloadResult](),
+	"run_pipeline":          reflect.TypeFor[tools.PipelineResult](),
+	"pin_symbol":            reflect.TypeFor[session.Pin](),
+	"unpin_symbol":          reflect.TypeFor[[]string](),
+	"get_package_docs":      reflect.TypeFor[analyzer.PackageDocsResult](),
+	"summarize":             reflect.TypeFor[SessionSummary](),
+	"set_session":           reflect.TypeFor[session.Preferences](),
+	"who_owns":              reflect.TypeFor[Ownership](),
+	"annotate_symbol":       reflect.TypeFor[[]notes.Note](),
+	"generate_architecture": reflect.TypeFor[analyzer.Architecture](),
+	"boundaries":            reflect.TypeFor[analyzer.BoundaryReport](),
+	"binaries":              reflect.TypeFor[analyzer.BinaryReport](),
+	"code_metrics":          reflect.TypeFor[CodeMetricsResult](),
+	"repo_inventory":        reflect.TypeFor[analyzer.Inventory](),
+	"grpc_map":              reflect.TypeFor[analyzer.GRPCMap](),
+	"embeds":                reflect.TypeFor[analyzer.EmbedReport](),
+	"find_dead_config":      reflect.TypeFor[analyzer.DeadConfigReport](),
+	"error_paths":           reflect.TypeFor[analyzer.ErrorPaths](),
+	"error_taxonomy":        reflect.TypeFor[analyzer.ErrorTaxonomy](),
+	"interface_usage":       reflect.TypeFor[analyzer.InterfaceUsage](),
+	"analyze_interface":     reflect.TypeFor[analyzer.InterfaceAnalysis](),
+	"list_deprecated":       reflect.TypeFor[[]analyzer.DeprecatedSymbol](),
+	"plan_migration":        reflect.TypeFor[analyzer.MigrationPlan](),
+	"dep_usage":             reflect.TypeFor[analyzer.DependencyUsage](),
+	"modernize":             reflect.TypeFor[ModernizeResult](),
+	"list_enums":            reflect.TypeFor[[]analyzer.EnumInfo](),
+	"check_marshalers":      reflect.TypeFor[analyzer.MarshalerReport](),
+	"type_report":           reflect.TypeFor[analyzer.TypeReport](),
+	"api_diff":              reflect.TypeFor[APIDiffResult](),
+	"release_report":        reflect.TypeFor[apidiff.Release](),
+	"run_tests":             reflect.TypeFor[gorun.Result](),
+	"check_build":           reflect.TypeFor[BuildReport](),
+	"security_scan":         reflect.TypeFor[SecurityReport](),
+	"concurrency_report":    reflect.TypeFor[analyzer.ConcurrencyReport](),
+	"ctx_check":             reflect.TypeFor[analyzer.ContextReport](),
+	"globals_report":        reflect.TypeFor[analyzer.GlobalsReport](),
+	"dynamic_typing_report": reflect.TypeFor[analyzer.DynamicTypingReport](),
+	"profile_report":        reflect.TypeFor[analyzer.ProfileReport](),
+	"parse_diagnostics":     reflect.TypeFor[analyzer.ParseDiagnostics](),
+	"server_status":         reflect.TypeFor[ServerStatus](),
+	"refresh_repo":          reflect.TypeFor[RefreshResult](),
+	"find_usages":           reflect.TypeFor[FindUsagesResult](),
+	"rename":                reflect.TypeFor[RenameResult](),
+	"batch":                 reflect.TypeFor[BatchResult](),
+	"start_job":             reflect.TypeFor[jobs.Job](),
+	"cancel_job":            reflect.TypeFor[jobs.Job](),
+}
+
+// schemaTypes are defined by get_schemas besides the tool outputs, since
+// resources, prompts and snapshots return them
+var schemaTypes = []reflect.Type{
+	reflect.TypeFor[analyzer.AnalysisResult](),
+	reflect.TypeFor[analyzer.PackageInfo](),
+	reflect.TypeFor[analyzer.FunctionInfo](),
+}
+
+type GetSchemasArgs struct {
+	Tool string `json:"tool,omitempty" jsonschema:"description=Only return the schema of this tool's output; omit for all tools"`
+	ResponseBudget
+}
+
+// Schemas is the response of get_schemas: a JSON Schema document whose
+// $defs hold the named types the tool schemas refer to
+type Schemas struct {
+	SchemaVersion int                       `json:"schema_version"`
+	Schema        string                    `json:"$schema"`
+	Tools         map[string]*schema.Schema `json:"tools"`
+	// TextTools return text, or JSON without a fixed schema
+	TextTools []string                  `json:"text_tools,omitempty"`
+	Defs      map[string]*schema.Schema `json:"$defs"`
+}
+
+func getSchemasHandler(ctx context.Context, args GetSchemasArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Getting schemas", "tool", args.Tool)
+	schemas, err := toolSchemas(args.Tool)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schemas: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// toolSchemas generates the schemas of one tool's output, or of all tools
+// and schemaTypes
+func toolSchemas(tool string) (*Schemas, error) {
+	g := schema.NewGenerator()
+	result := &Schemas{SchemaVersion: schemaVersion, Schema: schema.Draft, Tools: make(map[string]*schema.Schema)}
+	if tool != "" {
+		t, ok := toolOutputs[tool]
+		if !ok {
+			return nil, fmt.Errorf("no schema for tool %s; it returns text or does not exist", tool)
+		}
+		result.Tools[tool] = g.Schema(t)
+		result.Defs = g.Defs()
+		return result, nil
+	}
+
+	for name, t := range toolOutputs {
+		result.Tools[name] = g.Schema(t)
+	}
+	for _, t := range schemaTypes {
+		g.Schema(t)
+	}
+	for _, name := range registeredTools {
+		if _, ok := toolOutputs[name]; !ok && name != "get_schemas" {
+			result.TextTools = append(result.TextTools, name)
+		}
+	}
+	sort.Strings(result.TextTools)
+	result.Defs = g.Defs()
+	return result, nil
+}
+
+// versionResponse adds schema_version to the JSON objects of a response,
+// after their other fields. A response without one, such as text or a JSON
+// array, gets the version as an object of its own after its content.
+func versionResponse(response *mcp.ToolResponse) *mcp.ToolResponse {
+	if response == nil {
+		return response
+	}
+	versioned := false
+	for _, content := range response.Content {
+		if content == nil || content.TextContent == nil {
+			continue
+		}
+		if text, ok := addSchemaVersion(content.TextContent.Text); ok {
+			content.TextContent.Text = text
+			versioned = true
+		}
+	}
+	if !versioned {
+		response.Content = append(response.Content, mcp.NewTextContent(fmt.Sprintf(`{"schema_version":%d}`, schemaVersion)))
+	}
+	return response
+}
+
+// addSchemaVersion adds schema_version as the last field of a JSON object,
+// keeping the indentation of indented objects. It reports false for text
+// that is not a JSON object.
+func addSchemaVersion(text string) (string, bool) {
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return text, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return text, false
+	}
+	if _, ok := fields["schema_version"]; ok {
+		return text, true
+	}
+
+	body := strings.TrimRight(trimmed[:len(trimmed)-1], " \t\r\n")
+	field := fmt.Sprintf(`"schema_version":%d`, schemaVersion)
+	closing := "}"
+	if i := strings.Index(trimmed, "\n"); i >= 0 {
+		// Indented like the first field
+		line := trimmed[i+1:]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		field = fmt.Sprintf("\n%s\"schema_version\": %d", indent, schemaVersion)
+		closing = "\n}"
+	}
+	var buf bytes.Buffer
+	buf.WriteString(body)
+	if len(fields) > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(field)
+	buf.WriteString(closing)
+	return buf.String(), true
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/schema"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+func TestGetSchemasHandler(t *testing.T) {
+	response, err := getSchemasHandler(context.Background(), GetSchemasArgs{})
+	if err != nil {
+		t.Fatalf("getSchemasHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	var schemas Schemas
+	if err := json.Unmarshal([]byte(text), &schemas); err != nil {
+		t.Fatalf("Failed to decode schemas: %v", err)
+	}
+	if schemas.SchemaVersion != schemaVersion || len(schemas.Tools) != len(toolOutputs) {
+		t.Errorf("Expected version %d and %d tools, got %d and %d", schemaVersion, len(toolOutputs), schemas.SchemaVersion, len(schemas.Tools))
+	}
+	for _, name := range []string{"analyzer.TypeInfo", "analyzer.MethodInfo", "analyzer.AnalysisResult", "analyzer.PackageInfo"} {
+		if schemas.Defs[name] == nil {
+			t.Errorf("Expected a definition of %s", name)
+		}
+	}
+	// Every reference resolves
+	for _, ref := range strings.Split(text, `"$ref":"#/$defs/`)[1:] {
+		name := ref[:strings.IndexByte(ref, '"')]
+		if schemas.Defs[name] == nil {
+			t.Errorf("Expected a definition of %s", name)
+		}
+	}
+
+	// The output of lookup_type has the required fields of its schema
+	lookup, err := lookupTypeHandler(context.Background(), LookupTypeArgs{TypeName: "TestStruct"})
+	if err != nil {
+		t.Fatalf("lookupTypeHandler failed: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(responseText(t, lookup)), &fields); err != nil {
+		t.Fatalf("Failed to decode type info: %v", err)
+	}
+	if ref := schemas.Tools["lookup_type"].Ref; ref != "#/$defs/analyzer.TypeInfo" {
+		t.Fatalf("Expected lookup_type to return TypeInfo, got %q", ref)
+	}
+	for _, name := range schemas.Defs["analyzer.TypeInfo"].Required {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("Expected required field %s in the lookup_type output", name)
+		}
+	}
+
+	response, err = getSchemasHandler(context.Background(), GetSchemasArgs{Tool: "list_methods"})
+	if err != nil {
+		t.Fatalf("getSchemasHandler failed: %v", err)
+	}
+	var one Schemas
+	if err := json.Unmarshal([]byte(responseText(t, response)), &one); err != nil {
+		t.Fatalf("Failed to decode schemas: %v", err)
+	}
+	if len(one.Tools) != 1 || one.Tools["list_methods"].Items == nil || one.Defs["analyzer.MethodInfo"] == nil || one.Defs["analyzer.AnalysisResult"] != nil {
+		t.Errorf("Expected only the list_methods schema, got %+v", one)
+	}
+	if _, err := getSchemasHandler(context.Background(), GetSchemasArgs{Tool: "show_example"}); err == nil {
+		t.Error("Expected an error for a tool returning text")
+	}
+	if schema.Draft == "" {
+		t.Error("Expected a JSON Schema dialect")
+	}
+}
+
+func TestVersionResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"object", `{"a":1}`, `{"a":1,"schema_version":1}`},
+		{"empty object", `{}`, `{"schema_version":1}`},
+		{"indented", "{\n  \"a\": 1\n}", "{\n  \"a\": 1,\n  \"schema_version\": 1\n}"},
+		{"versioned", `{"schema_version":1}`, `{"schema_version":1}`},
+	}
+	for _, tt := range tests {
+		response := versionResponse(mcp.NewToolResponse(mcp.NewTextContent(tt.text)))
+		if len(response.Content) != 1 || response.Content[0].TextContent.Text != tt.want {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.want, response.Content[0].TextContent.Text)
+		}
+	}
+
+	// Text and arrays get the version after them
+	for _, text := range []string{"plain text", `[1,2]`, `{"truncated":`} {
+		response := versionResponse(mcp.NewToolResponse(mcp.NewTextContent(text)))
+		if len(response.Content) != 2 || response.Content[0].TextContent.Text != text || response.Content[1].TextContent.Text != `{"schema_version":1}` {
+			t.Errorf("Expected %q followed by the version, got %+v", text, response.Content)
+		}
+	}
+}
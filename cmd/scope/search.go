@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type SearchTypesArgs struct {
+	Query            string   `json:"query,omitempty" jsonschema:"description=Part of the type name to match; qualify it as pkg.Name to search one package or omit it to list every type"`
+	Tags             []string `json:"tags,omitempty" jsonschema:"description=Only return types carrying all of these tags (e.g. deprecated or generated)"`
+	ExcludeTags      []string `json:"exclude_tags,omitempty" jsonschema:"description=Leave out types carrying any of these tags"`
+	ExcludeGenerated bool     `json:"exclude_generated,omitempty" jsonschema:"description=Leave out types declared in generated files (Code generated headers; .pb.go and _gen.go files)"`
+	ExportedOnly     bool     `json:"exported_only,omitempty" jsonschema:"description=Only return exported types" session:"exported_only"`
+	Limit            int      `json:"limit,omitempty" jsonschema:"description=Maximum number of types to return (default all)" session:"limit"`
+	Sort             string   `json:"sort,omitempty" jsonschema:"enum=relevance,enum=name,enum=package,description=Order of the results: relevance ranks exact then prefix then substring then fuzzy matches; name and package sort alphabetically (default relevance)"`
+	ResponseBudget
+}
+
+// TypeMatch is a type returned by search_types
+type TypeMatch struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	ImportPath string            `json:"import_path"`
+	Position   analyzer.Position `json:"position"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Match      string            `json:"match"`
+	Score      int               `json:"score"`
+}
+
+func searchTypesHandler(ctx context.Context, args SearchTypesArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Searching types", "query", args.Query, "tags", args.Tags, "exclude_tags", args.ExcludeTags)
+	start := time.Now()
+	found, err := analyzerInstance.SearchTypes(ctx, args.Query)
+	metrics.AnalyzerDuration.ObserveDuration(start, "search_types")
+	if err != nil {
+		return nil, err
+	}
+	if err := analyzer.SortTypeResults(found, args.Sort); err != nil {
+		return nil, err
+	}
+
+	matches := []TypeMatch{}
+	for _, typeInfo := range found {
+		if !analyzer.HasTags(typeInfo.Tags, args.Tags, args.ExcludeTags) || (args.ExcludeGenerated && typeInfo.Position.Generated) ||
+			(args.ExportedOnly && !typeInfo.Exported) {
+			continue
+		}
+		if args.Limit > 0 && len(matches) == args.Limit {
+			break
+		}
+		matches = append(matches, TypeMatch{
+			Name:       typeInfo.Name,
+			Kind:       typeInfo.Kind,
+			ImportPath: typeInfo.ImportPath,
+			Position:   typeInfo.Position,
+			Tags:       typeInfo.Tags,
+			Match:      typeInfo.Match,
+			Score:      typeInfo.Score,
+		})
+	}
+
+	jsonData, err := json.Marshal(matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal types: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type SearchCodeArgs struct {
+	Pattern    string   `json:"pattern" jsonschema:"required,description=Text to find in each line; a Go regular expression when regex is set"`
+	Regex      bool     `json:"regex,omitempty" jsonschema:"description=Treat the pattern as a regular expression"`
+	IgnoreCase bool     `json:"ignore_case,omitempty" jsonschema:"description=Match case-insensitively"`
+	Package    string   `json:"package,omitempty" jsonschema:"description=Only search this package (import path or package name)" session:"package"`
+	Include    []string `json:"include,omitempty" jsonschema:"description=Only search files matching one of these globs; globs with a slash match the path relative to the repository or a parent directory; others the file name"`
+	Exclude    []string `json:"exclude,omitempty" jsonschema:"description=Skip files matching any of these globs"`
+	Limit      int      `json:"limit,omitempty" jsonschema:"description=Maximum number of matches (default 100)" session:"limit"`
+	ResponseBudget
+}
+
+func searchCodeHandler(ctx context.Context, args SearchCodeArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Searching code", "pattern", args.Pattern, "regex", args.Regex, "package", args.Package)
+	start := time.Now()
+	result, err := analyzerInstance.SearchCode(ctx, analyzer.CodeSearchOptions{
+		Pattern:    args.Pattern,
+		Regex:      args.Regex,
+		IgnoreCase: args.IgnoreCase,
+		Package:    args.Package,
+		Include:    args.Include,
+		Exclude:    args.Exclude,
+		Limit:      args.Limit,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "search_code")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal code matches: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
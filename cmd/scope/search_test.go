@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestSearchTypesHandler(t *testing.T) {
+	response, err := searchTypesHandler(context.Background(), SearchTypesArgs{Query: "TestStr"})
+	if err != nil {
+		t.Fatalf("searchTypesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"name":"TestStruct"`) {
+		t.Errorf("Expected TestStruct in results, got %s", text)
+	}
+
+	response, err = searchTypesHandler(context.Background(), SearchTypesArgs{Query: "TestStr", Tags: []string{"deprecated"}})
+	if err != nil {
+		t.Fatalf("searchTypesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != "[]" {
+		t.Errorf("Expected no deprecated types, got %s", text)
+	}
+
+	response, err = searchTypesHandler(context.Background(), SearchTypesArgs{Query: "TestStruct", Sort: "name"})
+	if err != nil {
+		t.Fatalf("searchTypesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"match":"exact"`) {
+		t.Errorf("Expected an exact match, got %s", text)
+	}
+	if _, err := searchTypesHandler(context.Background(), SearchTypesArgs{Query: "TestStr", Sort: "size"}); err == nil {
+		t.Error("Expected error for unknown sort order")
+	}
+}
+
+func TestSearchTypesHandlerExcludeGenerated(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":      "module example.com/gen\n\ngo 1.21\n",
+		"types.go":    "package gen\n\ntype Message struct{}\n",
+		"types.pb.go": "package gen\n\ntype MessageProto struct{}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	generated, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer generated.Close()
+	previous := analyzerInstance
+	analyzerInstance = generated
+	defer func() { analyzerInstance = previous }()
+
+	response, err := searchTypesHandler(context.Background(), SearchTypesArgs{Query: "MessageProto"})
+	if err != nil {
+		t.Fatalf("searchTypesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"generated":true`) {
+		t.Errorf("Expected a generated position, got %s", text)
+	}
+
+	response, err = searchTypesHandler(context.Background(), SearchTypesArgs{Query: "Message", ExcludeGenerated: true})
+	if err != nil {
+		t.Fatalf("searchTypesHandler failed: %v", err)
+	}
+	if text := responseText(t, response); strings.Contains(text, "MessageProto") || !strings.Contains(text, `"name":"Message"`) {
+		t.Errorf("Expected only hand-written types, got %s", text)
+	}
+}
+
+func TestSearchCodeHandler(t *testing.T) {
+	response, err := searchCodeHandler(context.Background(), SearchCodeArgs{Pattern: `func \(\w+ \*?TestStruct\)`, Regex: true})
+	if err != nil {
+		t.Fatalf("searchCodeHandler failed: %v", err)
+	}
+	text := responseText(t, response)
+	if !strings.Contains(text, `"kind":"method","name":"TestStruct.TestMethod"`) {
+		t.Errorf("Expected a match in TestStruct.TestMethod, got %s", text)
+	}
+
+	if _, err := searchCodeHandler(context.Background(), SearchCodeArgs{}); err == nil {
+		t.Error("Expected an error without a pattern")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/seccheck"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// SecurityReport is the result of security_scan
+type SecurityReport struct {
+	High     int                `json:"high"`
+	Medium   int                `json:"medium"`
+	Low      int                `json:"low"`
+	Findings []seccheck.Finding `json:"findings"`
+	// Rules are the rules that ran, so an empty report says what was checked
+	Rules []string `json:"rules"`
+}
+
+type SecurityScanArgs struct {
+	Files       string   `json:"files,omitempty" jsonschema:"description=Space-separated files relative to the repository to scan (default every non-test Go file)"`
+	Rules       []string `json:"rules,omitempty" jsonschema:"description=Rule IDs to run (hardcoded-credentials; weak-random; command-injection; sql-concatenation; insecure-tls); default all"`
+	MinSeverity string   `json:"min_severity,omitempty" jsonschema:"description=Only report findings of at least this severity (high; medium or low)"`
+	ResponseBudget
+}
+
+func securityScanHandler(ctx context.Context, args SecurityScanArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Running security scan", "files", args.Files, "rules", args.Rules)
+	start := time.Now()
+	report, err := securityScan(ctx, analyzerInstance.RepoPath(), seccheck.Options{
+		Files:       strings.Fields(args.Files),
+		Rules:       args.Rules,
+		MinSeverity: strings.ToLower(args.MinSeverity),
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "security_scan")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal security report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// securityScan runs the security checks over dir and counts the findings
+// by severity
+func securityScan(ctx context.Context, dir string, opts seccheck.Options) (*SecurityReport, error) {
+	findings, err := seccheck.Scan(ctx, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SecurityReport{Findings: findings, Rules: opts.Rules}
+	if len(report.Rules) == 0 {
+		for _, rule := range seccheck.Rules() {
+			report.Rules = append(report.Rules, rule.ID)
+		}
+	}
+	for _, finding := range findings {
+		switch finding.Severity {
+		case seccheck.SeverityHigh:
+			report.High++
+		case seccheck.SeverityMedium:
+			report.Medium++
+		default:
+			report.Low++
+		}
+	}
+	return report, nil
+}
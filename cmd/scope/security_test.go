@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/seccheck"
+)
+
+func TestSecurityScan(t *testing.T) {
+	dir := t.TempDir()
+	src := `package app
+
+import (
+	"crypto/tls"
+	"os/exec"
+)
+
+const dbPassword = "p4ssw0rd-prod-2024"
+
+func run(program string) error {
+	return exec.Command(program).Run()
+}
+
+var insecure = &tls.Config{InsecureSkipVerify: true}
+`
+	if err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write app.go: %v", err)
+	}
+
+	report, err := securityScan(context.Background(), dir, seccheck.Options{})
+	if err != nil {
+		t.Fatalf("securityScan failed: %v", err)
+	}
+	if report.High != 2 || report.Medium != 1 || len(report.Findings) != 3 || len(report.Rules) != 5 {
+		t.Errorf("Expected 2 high and 1 medium findings from every rule, got %+v", report)
+	}
+	if report.Findings[0].File != "app.go" || report.Findings[0].Line != 8 {
+		t.Errorf("Expected the first finding at app.go:8, got %+v", report.Findings[0])
+	}
+
+	report, err = securityScan(context.Background(), dir, seccheck.Options{MinSeverity: seccheck.SeverityHigh, Rules: []string{"command-injection"}})
+	if err != nil {
+		t.Fatalf("securityScan failed: %v", err)
+	}
+	if len(report.Findings) != 0 || len(report.Rules) != 1 {
+		t.Errorf("Expected no findings from the selected rule, got %+v", report)
+	}
+}
+
+func TestSecurityScanHandler(t *testing.T) {
+	resp, err := securityScanHandler(context.Background(), SecurityScanArgs{})
+	if err != nil {
+		t.Fatalf("securityScanHandler failed: %v", err)
+	}
+	var report SecurityReport
+	if err := json.Unmarshal([]byte(responseText(t, resp)), &report); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if report.Findings == nil || len(report.Rules) == 0 {
+		t.Errorf("Expected a report listing the rules, got %+v", report)
+	}
+
+	if _, err := securityScanHandler(context.Background(), SecurityScanArgs{MinSeverity: "urgent"}); err == nil {
+		t.Error("Expected an error for an unknown severity")
+	}
+}
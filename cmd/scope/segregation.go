@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type AnalyzeInterfaceArgs struct {
+	Interface string `json:"interface" jsonschema:"required,description=Interface type to analyze; qualify it as pkg.Name when ambiguous"`
+	ResponseBudget
+}
+
+func analyzeInterfaceHandler(ctx context.Context, args AnalyzeInterfaceArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Analyzing interface", "interface", args.Interface)
+	start := time.Now()
+	analysis, err := analyzerInstance.AnalyzeInterface(ctx, args.Interface)
+	metrics.AnalyzerDuration.ObserveDuration(start, "analyze_interface")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interface analysis: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeInterfaceHandler(t *testing.T) {
+	// The test package declares no interfaces
+	_, err := analyzeInterfaceHandler(context.Background(), AnalyzeInterfaceArgs{Interface: "TestStruct"})
+	if err == nil || !strings.Contains(err.Error(), "not an interface") {
+		t.Errorf("Expected not-an-interface error for TestStruct, got %v", err)
+	}
+	if _, err := analyzeInterfaceHandler(context.Background(), AnalyzeInterfaceArgs{Interface: "DoesNotExist"}); err == nil {
+		t.Error("Expected error for unknown interface")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/semantic"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// semanticIndex answers code_search when -semantic-search is set; nil
+// leaves code_search to the external command of tools.json
+var semanticIndex *semantic.Index
+
+// defaultSearchLimit is how many declarations code_search returns from the
+// semantic index by default
+const defaultSearchLimit = 10
+
+// SemanticSearchResult is the response of code_search from the semantic index
+type SemanticSearchResult struct {
+	Query   string            `json:"query"`
+	Results []semantic.Result `json:"results"`
+	Index   semantic.Stats    `json:"index"`
+}
+
+// semanticSearchHandler answers code_search from the semantic index
+func semanticSearchHandler(ctx context.Context, args CodeSearchArgs) (*mcp.ToolResponse, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	start := time.Now()
+	results, err := semanticIndex.Search(ctx, args.Query, limit)
+	metrics.AnalyzerDuration.ObserveDuration(start, "code_search")
+	if err != nil {
+		return nil, fmt.Errorf("code search failed: %w", err)
+	}
+
+	result := SemanticSearchResult{Query: args.Query, Results: results, Index: semanticIndex.Stats()}
+	if result.Results == nil {
+		result.Results = []semantic.Result{}
+	}
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal search results: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// logSemanticUpdate logs the outcome of an update of the semantic index
+func logSemanticUpdate(changes semantic.Changes, err error) {
+	if err != nil {
+		slog.Warn("Failed to update the semantic index", "error", err)
+	}
+	if changes != (semantic.Changes{}) {
+		slog.Info("Updated the semantic index", "updated", changes.Updated, "removed", changes.Removed, "embedded", changes.Embedded)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/semantic"
+)
+
+func TestCodeSearchHandlerWithSemanticIndex(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"retry.go": "package client\n\n// Backoff returns the wait before retrying a request\nfunc Backoff(attempt int) int { return attempt * 2 }\n",
+		"store.go": "package client\n\n// WriteFile saves data to disk\nfunc WriteFile(path string) error { return nil }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	index, err := semantic.Open(dir, "", semantic.NewHashEmbedder(semantic.DefaultDims))
+	if err != nil {
+		t.Fatalf("Failed to open semantic index: %v", err)
+	}
+	if _, err := index.Sync(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to sync semantic index: %v", err)
+	}
+	previous := semanticIndex
+	defer func() { semanticIndex = previous }()
+	semanticIndex = index
+
+	response, err := codeSearchHandler(context.Background(), CodeSearchArgs{Query: "retry backoff", Limit: 1})
+	if err != nil {
+		t.Fatalf("codeSearchHandler failed: %v", err)
+	}
+	var result SemanticSearchResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Name != "Backoff" || result.Results[0].File != "retry.go" {
+		t.Errorf("Expected Backoff, got %+v", result.Results)
+	}
+	if result.Index.Files != 2 || result.Index.Declarations != 2 {
+		t.Errorf("Expected the index stats, got %+v", result.Index)
+	}
+}
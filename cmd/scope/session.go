@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/session"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+var pinSet *session.PinSet
+
+// preferences are the session's sticky tool arguments
+var preferences = session.NewPreferenceSet()
+
+// SessionSummary describes the repository and the session's pinned working set
+type SessionSummary struct {
+	// Summary is a one-line description in the configured locale
+	Summary    string   `json:"summary"`
+	Repository string   `json:"repository"`
+	Packages   []string `json:"packages"`
+	// Modules lists the workspace modules, or the modules the packages
+	// belong to without a go.work file
+	Modules []analyzer.Module `json:"modules"`
+	Pinned  []session.Pin     `json:"pinned"`
+	// Preferences are those set with set_session
+	Preferences session.Preferences `json:"preferences"`
+}
+
+type PinSymbolArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Name of the symbol to pin; qualify it as pkg.Name when ambiguous"`
+	ResponseBudget
+}
+
+func pinSymbolHandler(ctx context.Context, args PinSymbolArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Pinning symbol", "symbol", args.Symbol)
+	pin, err := pinSet.Pin(ctx, args.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(pin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pin: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type UnpinSymbolArgs struct {
+	Symbol string `json:"symbol" jsonschema:"required,description=Name of the pinned symbol, exactly as it was pinned"`
+	ResponseBudget
+}
+
+func unpinSymbolHandler(ctx context.Context, args UnpinSymbolArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Unpinning symbol", "symbol", args.Symbol)
+	if err := pinSet.Unpin(args.Symbol); err != nil {
+		return nil, err
+	}
+
+	remaining := make([]string, 0, pinSet.Len())
+	for _, pin := range pinSet.List() {
+		remaining = append(remaining, pin.Name)
+	}
+	jsonData, err := json.Marshal(remaining)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pinned symbols: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type SummarizeArgs struct {
+	ResponseBudget
+}
+
+func summarizeHandler(ctx context.Context, args SummarizeArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Summarizing session")
+	summary := SessionSummary{
+		Repository:  analyzerInstance.RepoPath(),
+		Packages:    analyzerInstance.Packages(),
+		Modules:     analyzerInstance.Modules(),
+		Pinned:      pinSet.List(),
+		Preferences: preferences.Get(),
+	}
+	summary.Summary = localizer.Sprintf("%s: %d package(s), %d pinned symbol(s)", filepath.Base(summary.Repository), len(summary.Packages), len(summary.Pinned))
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summary: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+type SetSessionArgs struct {
+	Package      string   `json:"package,omitempty" jsonschema:"description=Default package (import path or package name) of tools scoped to one package"`
+	ExportedOnly bool     `json:"exported_only,omitempty" jsonschema:"description=Leave unexported declarations out of tools listing them"`
+	Limit        int      `json:"limit,omitempty" jsonschema:"description=Default maximum number of results"`
+	Format       string   `json:"format,omitempty" jsonschema:"description=Response format: json (compact; the default) or indented"`
+	Clear        []string `json:"clear,omitempty" jsonschema:"description=Preferences to unset by name (package; exported_only; limit or format); applied before the values above"`
+	ResponseBudget
+}
+
+func setSessionHandler(ctx context.Context, args SetSessionArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Setting session preferences", "clear", args.Clear)
+	prefs, err := preferences.Update(session.Preferences{
+		Package:      args.Package,
+		ExportedOnly: args.ExportedOnly,
+		Limit:        args.Limit,
+		Format:       args.Format,
+	}, args.Clear)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// formatResponse indents the JSON text of a response when the session asks
+// for indented output; other text is left as it is
+func formatResponse(response *mcp.ToolResponse) *mcp.ToolResponse {
+	if response == nil || preferences.Get().Format != session.FormatIndented {
+		return response
+	}
+	for _, content := range response.Content {
+		if content == nil || content.TextContent == nil || !json.Valid([]byte(content.TextContent.Text)) {
+			continue
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, []byte(content.TextContent.Text), "", "  "); err == nil {
+			content.TextContent.Text = indented.String()
+		}
+	}
+	return response
+}
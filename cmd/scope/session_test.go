@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/session"
+)
+
+func TestPinAndSummarize(t *testing.T) {
+	if _, err := pinSymbolHandler(context.Background(), PinSymbolArgs{Symbol: "TestStruct"}); err != nil {
+		t.Fatalf("pinSymbolHandler failed: %v", err)
+	}
+	defer pinSet.Unpin("TestStruct")
+
+	response, err := summarizeHandler(context.Background(), SummarizeArgs{})
+	if err != nil {
+		t.Fatalf("summarizeHandler failed: %v", err)
+	}
+	var summary SessionSummary
+	if err := json.Unmarshal([]byte(responseText(t, response)), &summary); err != nil {
+		t.Fatalf("Failed to decode summary: %v", err)
+	}
+	if len(summary.Packages) != 1 || len(summary.Pinned) != 1 || summary.Pinned[0].Definition.Name != "TestStruct" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if !strings.HasSuffix(summary.Summary, ": 1 package(s), 1 pinned symbol(s)") {
+		t.Errorf("Unexpected summary text: %q", summary.Summary)
+	}
+
+	response, err = unpinSymbolHandler(context.Background(), UnpinSymbolArgs{Symbol: "TestStruct"})
+	if err != nil {
+		t.Fatalf("unpinSymbolHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != "[]" {
+		t.Errorf("Expected no remaining pins, got %s", text)
+	}
+	if _, err := unpinSymbolHandler(context.Background(), UnpinSymbolArgs{Symbol: "TestStruct"}); err == nil {
+		t.Error("Expected error unpinning twice")
+	}
+}
+
+func TestSetSession(t *testing.T) {
+	defer preferences.Update(session.Preferences{}, []string{"package", "exported_only", "limit", "format"})
+
+	response, err := setSessionHandler(context.Background(), SetSessionArgs{Package: "testpkg", Limit: 1, Format: "indented"})
+	if err != nil {
+		t.Fatalf("setSessionHandler failed: %v", err)
+	}
+	if text := responseText(t, response); text != `{"package":"testpkg","limit":1,"format":"indented"}` {
+		t.Errorf("Unexpected preferences: %s", text)
+	}
+
+	// Calls through instrument pick up the preferences
+	handler := instrument("search_code", searchCodeHandler)
+	response, err = handler(context.Background(), SearchCodeArgs{Pattern: "TestStruct"})
+	if err != nil {
+		t.Fatalf("search_code failed: %v", err)
+	}
+	text := responseText(t, response)
+	var result analyzer.CodeSearchResult
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		t.Fatalf("Failed to decode matches: %v", err)
+	}
+	if len(result.Matches) != 1 || !result.Truncated {
+		t.Errorf("Expected the session limit of 1 match, got %+v", result)
+	}
+	if !strings.HasPrefix(text, "{\n  \"matches\": [") {
+		t.Errorf("Expected indented output, got %s", text)
+	}
+
+	response, err = handler(context.Background(), SearchCodeArgs{Pattern: "TestStruct", Package: "missing"})
+	if err != nil {
+		t.Fatalf("search_code failed: %v", err)
+	}
+	if text := responseText(t, response); !strings.Contains(text, `"matches": []`) {
+		t.Errorf("Expected explicit arguments to override the session, got %s", text)
+	}
+
+	if _, err := setSessionHandler(context.Background(), SetSessionArgs{Format: "yaml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/spill"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// defaultMaxResponseBytes keeps responses well below the message limits of
+// common MCP clients
+const defaultMaxResponseBytes = 1 << 20
+
+// noticeReserve is the part of the limit kept free for the spill notice
+const noticeReserve = 1024
+
+// bytesPerToken converts token budgets to bytes. JSON and Go source average
+// about four bytes per token with common tokenizers.
+const bytesPerToken = 4
+
+var (
+	// maxResponseBytes caps the text of a tool response; 0 disables the limit
+	maxResponseBytes = defaultMaxResponseBytes
+	// maxResponseTokens caps it in estimated tokens; 0 disables the limit
+	maxResponseTokens = 0
+	spillStore        *spill.Store
+	// mcpServer exposes spilled results as resources once the server is set up
+	mcpServer *mcp.Server
+)
+
+// SpillNotice follows the preview of a result that exceeded the response
+// size limit and tells the client where to find the rest
+type SpillNotice struct {
+	Truncated bool `json:"truncated"`
+	spill.Entry
+	// LimitBytes is the limit the result exceeded
+	LimitBytes   int    `json:"limit_bytes"`
+	PreviewBytes int    `json:"preview_bytes"`
+	Cursor       string `json:"cursor"`
+	Hint         string `json:"hint"`
+}
+
+// envInt returns the integer value of an environment variable, or fallback
+// when it is unset or malformed
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// envDuration returns the duration value of an environment variable, or
+// fallback when it is unset or malformed
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// ResponseBudget caps the size of one tool response. Embedded in the
+// arguments of a tool, it lets a call tighten or relax the server's limit.
+type ResponseBudget struct {
+	MaxBytes  int `json:"max_bytes,omitempty" jsonschema:"description=Largest response in bytes for this call; larger results end in a truncation notice with a continuation cursor; overrides the server limit"`
+	MaxTokens int `json:"max_tokens,omitempty" jsonschema:"description=Largest response in estimated tokens (4 bytes each) for this call; the smaller of max_bytes and max_tokens applies"`
+}
+
+func (b ResponseBudget) responseBudget() ResponseBudget {
+	return b
+}
+
+// budgeted is implemented by the arguments of tools embedding ResponseBudget
+type budgeted interface {
+	responseBudget() ResponseBudget
+}
+
+// responseLimit returns the limit in bytes of a call's response: that of
+// the call's budget when it sets one, and otherwise the server's. 0 means
+// no limit.
+func responseLimit(args any) int {
+	if b, ok := args.(budgeted); ok {
+		budget := b.responseBudget()
+		if limit := bytesLimit(budget.MaxBytes, budget.MaxTokens); limit > 0 {
+			return limit
+		}
+	}
+	return bytesLimit(maxResponseBytes, maxResponseTokens)
+}
+
+// bytesLimit returns the smaller of a limit in bytes and one in tokens,
+// converted to bytes, ignoring limits that are not positive
+func bytesLimit(bytes, tokens int) int {
+	limit := max(bytes, 0)
+	if tokens > 0 && (limit == 0 || tokens*bytesPerToken < limit) {
+		limit = tokens * bytesPerToken
+	}
+	return limit
+}
+
+// chunkBytes returns how much of a spilled result fits in one response
+// under limit
+func chunkBytes(limit int) int {
+	if limit > 2*noticeReserve {
+		return limit - noticeReserve
+	}
+	return limit / 2
+}
+
+// limitResponse returns response unchanged when its text fits within limit
+// bytes, or when limit is 0. Otherwise the full text is spilled to a file,
+// exposed as a resource, and replaced by a preview and a SpillNotice whose
+// cursor continues the result with continue_response. Spills are named by
+// content, so the same result is always cut at the same place.
+func limitResponse(response *mcp.ToolResponse, limit int) *mcp.ToolResponse {
+	if response == nil || limit <= 0 || spillStore == nil {
+		return response
+	}
+	var texts []string
+	size := 0
+	for _, content := range response.Content {
+		if content == nil || content.TextContent == nil {
+			continue
+		}
+		texts = append(texts, content.TextContent.Text)
+		size += len(content.TextContent.Text)
+	}
+	if size <= limit {
+		return response
+	}
+
+	full := strings.Join(texts, "\n")
+	preview := spill.Truncate(full, chunkBytes(limit))
+	notice := SpillNotice{Truncated: true, LimitBytes: limit, PreviewBytes: len(preview)}
+	entry, err := spillStore.Write(full)
+	if err != nil {
+		slog.Warn("Failed to spill large response", "error", err)
+		notice.Entry = spill.Entry{Size: len(full)}
+		notice.Hint = "The result was truncated and could not be saved; narrow the query."
+	} else {
+		notice.Entry = *entry
+		notice.Cursor = spill.Cursor(entry.ID, len(preview))
+		notice.Hint = "The result was truncated. Call continue_response with the cursor for the next part, raise max_bytes, or read the resource or file for all of it."
+		registerSpillResource(entry)
+	}
+
+	jsonData, err := json.Marshal(notice)
+	if err != nil {
+		slog.Warn("Failed to marshal spill notice", "error", err)
+		return mcp.NewToolResponse(mcp.NewTextContent(preview))
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(preview), mcp.NewTextContent(string(jsonData)))
+}
+
+// registerSpillResource makes a spilled result readable with resources/read
+func registerSpillResource(entry *spill.Entry) {
+	if mcpServer == nil || mcpServer.CheckResourceRegistered(entry.URI) {
+		return
+	}
+	id, uri := entry.ID, entry.URI
+	err := mcpServer.RegisterResource(uri, "spill-"+id, "Full text of a tool result that exceeded the response size limit", "text/plain", func() (*mcp.ResourceResponse, error) {
+		text, err := spillStore.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewResourceResponse(mcp.NewTextEmbeddedResource(uri, text, "text/plain")), nil
+	})
+	if err != nil {
+		slog.Warn("Failed to register spilled result resource", "error", err)
+	}
+}
+
+type ContinueResponseArgs struct {
+	Cursor string `json:"cursor" jsonschema:"required,description=Cursor from a truncated response or from the previous continue_response call"`
+	ResponseBudget
+}
+
+func continueResponseHandler(ctx context.Context, args ContinueResponseArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Continuing spilled response", "cursor", args.Cursor)
+	if spillStore == nil {
+		return nil, fmt.Errorf("no spilled results are available")
+	}
+	// Without a limit the rest of the result is returned at once
+	size := math.MaxInt
+	if limit := responseLimit(args); limit > 0 {
+		size = chunkBytes(limit)
+	}
+	chunk, next, err := spillStore.Read(args.Cursor, size)
+	if err != nil {
+		return nil, err
+	}
+	if next == "" {
+		return mcp.NewToolResponse(mcp.NewTextContent(chunk)), nil
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"cursor": next})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(chunk), mcp.NewTextContent(string(jsonData))), nil
+}
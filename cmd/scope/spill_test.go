@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/spill"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+func TestLimitResponse(t *testing.T) {
+	store, err := spill.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill store: %v", err)
+	}
+	defer func(limit int, previous *spill.Store) {
+		maxResponseBytes, spillStore = limit, previous
+	}(maxResponseBytes, spillStore)
+	maxResponseBytes, spillStore = 4096, store
+
+	full := strings.Repeat("0123456789", 1000)
+	handler := instrument("test", func(ctx context.Context, args struct{}) (*mcp.ToolResponse, error) {
+		return mcp.NewToolResponse(mcp.NewTextContent(full)), nil
+	})
+	response, err := handler(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	if len(response.Content) != 2 {
+		t.Fatalf("Expected preview and notice, got %d contents", len(response.Content))
+	}
+	preview := response.Content[0].TextContent.Text
+	if len(preview) != chunkBytes(4096) || !strings.HasPrefix(full, preview) {
+		t.Errorf("Expected a %d byte preview, got %d bytes", chunkBytes(4096), len(preview))
+	}
+	var notice SpillNotice
+	if err := json.Unmarshal([]byte(response.Content[1].TextContent.Text), &notice); err != nil {
+		t.Fatalf("Failed to decode spill notice: %v", err)
+	}
+	if !notice.Truncated || notice.Size != len(full) || notice.LimitBytes != 4096 || notice.Cursor == "" || !strings.HasPrefix(notice.URI, spill.URIScheme) {
+		t.Errorf("Unexpected spill notice: %+v", notice)
+	}
+
+	// Follow the cursor to the end of the result
+	var rest strings.Builder
+	for cursor := notice.Cursor; cursor != ""; {
+		response, err := continueResponseHandler(context.Background(), ContinueResponseArgs{Cursor: cursor})
+		if err != nil {
+			t.Fatalf("continueResponseHandler failed: %v", err)
+		}
+		rest.WriteString(response.Content[0].TextContent.Text)
+		cursor = ""
+		if len(response.Content) == 2 {
+			var next struct{ Cursor string }
+			if err := json.Unmarshal([]byte(response.Content[1].TextContent.Text), &next); err != nil {
+				t.Fatalf("Failed to decode cursor: %v", err)
+			}
+			cursor = next.Cursor
+		}
+	}
+	if preview+rest.String() != full {
+		t.Errorf("Expected preview and continuations to reassemble the result")
+	}
+
+	small := mcp.NewToolResponse(mcp.NewTextContent("small"))
+	if limitResponse(small, 4096) != small {
+		t.Error("Expected a small response to be returned unchanged")
+	}
+	if _, err := continueResponseHandler(context.Background(), ContinueResponseArgs{Cursor: "bogus"}); err == nil {
+		t.Error("Expected error for an invalid cursor")
+	}
+}
+
+func TestResponseBudget(t *testing.T) {
+	store, err := spill.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill store: %v", err)
+	}
+	defer func(bytes, tokens int, previous *spill.Store) {
+		maxResponseBytes, maxResponseTokens, spillStore = bytes, tokens, previous
+	}(maxResponseBytes, maxResponseTokens, spillStore)
+	maxResponseBytes, maxResponseTokens, spillStore = 1<<20, 0, store
+
+	tests := []struct {
+		name          string
+		bytes, tokens int
+		budget        ResponseBudget
+		want          int
+	}{
+		{"server bytes", 1 << 20, 0, ResponseBudget{}, 1 << 20},
+		{"server tokens", 1 << 20, 1000, ResponseBudget{}, 4000},
+		{"server disabled", 0, 0, ResponseBudget{}, 0},
+		{"call bytes", 1 << 20, 1000, ResponseBudget{MaxBytes: 100000}, 100000},
+		{"call tokens", 1 << 20, 0, ResponseBudget{MaxBytes: 100000, MaxTokens: 5000}, 20000},
+		{"call relaxes", 1 << 20, 0, ResponseBudget{MaxBytes: 4 << 20}, 4 << 20},
+		{"negative ignored", 1 << 20, 0, ResponseBudget{MaxBytes: -1}, 1 << 20},
+	}
+	for _, tt := range tests {
+		maxResponseBytes, maxResponseTokens = tt.bytes, tt.tokens
+		if got := responseLimit(ReadRangeArgs{ResponseBudget: tt.budget}); got != tt.want {
+			t.Errorf("%s: expected a limit of %d bytes, got %d", tt.name, tt.want, got)
+		}
+	}
+	maxResponseBytes, maxResponseTokens = 1<<20, 0
+	if got := responseLimit(struct{}{}); got != 1<<20 {
+		t.Errorf("Expected the server limit for arguments without a budget, got %d", got)
+	}
+
+	// A call's budget truncates deterministically and sizes the continuations
+	full := strings.Repeat("0123456789", 1000)
+	handler := instrument("budget_test", func(ctx context.Context, args ReadRangeArgs) (*mcp.ToolResponse, error) {
+		return mcp.NewToolResponse(mcp.NewTextContent(full)), nil
+	})
+	budget := ResponseBudget{MaxTokens: 1000}
+	var notices []SpillNotice
+	for range 2 {
+		response, err := handler(context.Background(), ReadRangeArgs{ResponseBudget: budget})
+		if err != nil {
+			t.Fatalf("Handler failed: %v", err)
+		}
+		if len(response.Content) != 2 || len(response.Content[0].TextContent.Text) != chunkBytes(4000) {
+			t.Fatalf("Expected a %d byte preview and a notice, got %+v", chunkBytes(4000), response.Content)
+		}
+		var notice SpillNotice
+		if err := json.Unmarshal([]byte(response.Content[1].TextContent.Text), &notice); err != nil {
+			t.Fatalf("Failed to decode spill notice: %v", err)
+		}
+		notices = append(notices, notice)
+	}
+	if notices[0].Cursor != notices[1].Cursor || notices[0].LimitBytes != 4000 {
+		t.Errorf("Expected the same cursor for the same result under a 4000 byte limit, got %+v", notices)
+	}
+	response, err := continueResponseHandler(context.Background(), ContinueResponseArgs{Cursor: notices[0].Cursor, ResponseBudget: budget})
+	if err != nil {
+		t.Fatalf("continueResponseHandler failed: %v", err)
+	}
+	if len(response.Content[0].TextContent.Text) != chunkBytes(4000) {
+		t.Errorf("Expected a %d byte continuation, got %d bytes", chunkBytes(4000), len(response.Content[0].TextContent.Text))
+	}
+}
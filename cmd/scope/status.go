@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/cache"
+	"github.com/TFMV/scope/internal/llm"
+	"github.com/TFMV/scope/internal/metrics"
+	"github.com/TFMV/scope/internal/policy"
+	"github.com/TFMV/scope/internal/remote"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+// serverStart is when the server process started
+var serverStart = time.Now()
+
+type ServerStatusArgs struct {
+	ResponseBudget
+}
+
+// ServerStatus is the state of the server reported by server_status
+type ServerStatus struct {
+	Started       time.Time       `json:"started"`
+	UptimeSeconds float64         `json:"uptime_seconds"`
+	Analyzer      analyzer.Status `json:"analyzer"`
+	Cache         CacheStatus     `json:"cache"`
+	Memory        MemoryStatus    `json:"memory"`
+	Tools         []ToolStatus    `json:"tools"`
+	// ExternalTools are the tools configured in tools.json
+	ExternalTools []string `json:"external_tools,omitempty"`
+	LSP           bool     `json:"lsp"`
+	// Remote is the clone analyzed when the repository was given as a git URL
+	Remote *remote.Repo `json:"remote,omitempty"`
+	// LLM accounts for the calls to the configured language model
+	LLM *llm.Stats `json:"llm,omitempty"`
+	// Policy is the write policy the editing tools enforce
+	Policy *policy.Policy `json:"policy,omitempty"`
+}
+
+// CacheStatus is the effectiveness and size of the result cache
+type CacheStatus struct {
+	cache.Usage
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	// Error is set when the store could not report its usage
+	Error string `json:"error,omitempty"`
+}
+
+// MemoryStatus is the memory use of the server process
+type MemoryStatus struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	Goroutines     int    `json:"goroutines"`
+}
+
+// ToolStatus is a registered tool and how its calls went
+type ToolStatus struct {
+	Name      string `json:"name"`
+	Calls     int    `json:"calls"`
+	Errors    int    `json:"errors,omitempty"`
+	Cancelled int    `json:"cancelled,omitempty"`
+}
+
+func serverStatusHandler(ctx context.Context, args ServerStatusArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reporting server status")
+	start := time.Now()
+	status := ServerStatus{
+		Started:       serverStart,
+		UptimeSeconds: time.Since(serverStart).Seconds(),
+		Analyzer:      analyzerInstance.Status(),
+		Cache:         cacheStatus(),
+		Memory:        memoryStatus(),
+		Tools:         toolStatuses(),
+		LSP:           lspBridge != nil,
+		Remote:        remoteRepo,
+		Policy:        writePolicy.Policy(),
+	}
+	if toolManager != nil {
+		status.ExternalTools = toolManager.ListTools()
+	}
+	if llmClient != nil {
+		stats := llmClient.Stats()
+		status.LLM = &stats
+	}
+	metrics.AnalyzerDuration.ObserveDuration(start, "server_status")
+
+	jsonData, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal server status: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// cacheStatus reports the hit rate and usage of the cache
+func cacheStatus() CacheStatus {
+	var status CacheStatus
+	if cacheInstance == nil {
+		return status
+	}
+	stats := cacheInstance.Stats()
+	status.Hits, status.Misses = stats.Hits, stats.Misses
+	if total := stats.Hits + stats.Misses; total > 0 {
+		status.HitRate = float64(stats.Hits) / float64(total)
+	}
+	usage, err := cacheInstance.Usage()
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Usage = usage
+	return status
+}
+
+// memoryStatus reads the memory statistics of the runtime
+func memoryStatus() MemoryStatus {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return MemoryStatus{
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		Goroutines:     runtime.NumGoroutine(),
+	}
+}
+
+// toolStatuses lists the registered tools with their call counts
+func toolStatuses() []ToolStatus {
+	statuses := make([]ToolStatus, 0, len(registeredTools))
+	for _, name := range registeredTools {
+		ok := int(metrics.ToolInvocations.Value(name, "ok"))
+		errors := int(metrics.ToolInvocations.Value(name, "error"))
+		cancelled := int(metrics.ToolInvocations.Value(name, "cancelled"))
+		statuses = append(statuses, ToolStatus{
+			Name:      name,
+			Calls:     ok + errors + cancelled,
+			Errors:    errors,
+			Cancelled: cancelled,
+		})
+	}
+	return statuses
+}
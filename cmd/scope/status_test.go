@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+func TestServerStatusHandler(t *testing.T) {
+	defer func(previous []string) { registeredTools = previous }(registeredTools)
+	registeredTools = nil
+
+	handler := instrument("status_test", func(ctx context.Context, fail bool) (*mcp.ToolResponse, error) {
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return mcp.NewToolResponse(mcp.NewTextContent("ok")), nil
+	})
+	if _, err := handler(context.Background(), false); err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	if _, err := handler(context.Background(), true); err == nil {
+		t.Fatal("Expected handler to fail")
+	}
+	if err := cacheInstance.Set(context.Background(), cacheKey("status", "test"), "value", time.Hour); err != nil {
+		t.Fatalf("Failed to set cache entry: %v", err)
+	}
+	cacheInstance.Get(context.Background(), cacheKey("status", "test"))
+
+	response, err := serverStatusHandler(context.Background(), ServerStatusArgs{})
+	if err != nil {
+		t.Fatalf("serverStatusHandler failed: %v", err)
+	}
+	var status ServerStatus
+	if err := json.Unmarshal([]byte(responseText(t, response)), &status); err != nil {
+		t.Fatalf("Failed to decode server status: %v", err)
+	}
+
+	if !status.Analyzer.Initialized || status.Analyzer.Files == 0 || status.Analyzer.LastAnalysis.IsZero() {
+		t.Errorf("Expected an initialized analyzer with files, got %+v", status.Analyzer)
+	}
+	if status.Cache.Entries == 0 || status.Cache.Hits == 0 || status.Cache.HitRate <= 0 {
+		t.Errorf("Expected cache entries and hits, got %+v", status.Cache)
+	}
+	if status.Memory.HeapAllocBytes == 0 || status.Memory.Goroutines == 0 {
+		t.Errorf("Expected memory statistics, got %+v", status.Memory)
+	}
+	if len(status.Tools) != 1 {
+		t.Fatalf("Expected 1 registered tool, got %+v", status.Tools)
+	}
+	if tool := status.Tools[0]; tool.Name != "status_test" || tool.Calls != 2 || tool.Errors != 1 {
+		t.Errorf("Expected 2 calls and 1 error for status_test, got %+v", tool)
+	}
+}
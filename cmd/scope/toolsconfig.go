@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/TFMV/scope/internal/tools"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type ReloadToolsArgs struct {
+	ResponseBudget
+}
+
+func reloadToolsHandler(ctx context.Context, args ReloadToolsArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Reloading tools configuration", "path", toolsConfigPath)
+	result, err := toolManager.Reload(toolsConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	logToolsReload(result, nil)
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reload result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
+
+// logToolsReload logs the outcome of a tools configuration reload
+func logToolsReload(result *tools.ReloadResult, err error) {
+	switch {
+	case err != nil:
+		slog.Warn("Keeping the current tools", "error", err)
+	case result.Changed():
+		slog.Info("Reloaded tools configuration", "added", result.Added, "updated", result.Updated, "removed", result.Removed, "pipelines", result.Pipelines)
+	}
+}
+
+type RunPipelineArgs struct {
+	Pipeline string `json:"pipeline" jsonschema:"required,description=Name of a pipeline in tools.json"`
+	Input    string `json:"input,omitempty" jsonschema:"description=Input of the pipeline: what its first step receives by default and .Input in step templates"`
+	ResponseBudget
+}
+
+func runPipelineHandler(ctx context.Context, args RunPipelineArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Running pipeline", "pipeline", args.Pipeline)
+	result, err := toolManager.ExecutePipeline(ctx, args.Pipeline, args.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pipeline result: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/scope/internal/tools"
+)
+
+func TestReloadToolsHandler(t *testing.T) {
+	previousManager, previousPath := toolManager, toolsConfigPath
+	defer func() { toolManager, toolsConfigPath = previousManager, previousPath }()
+
+	toolManager = tools.NewToolManager()
+	toolManager.RegisterTool(tools.ToolConfig{Name: "old", Command: "echo"})
+	toolsConfigPath = filepath.Join(t.TempDir(), "tools.json")
+	data := `{"tools": [{"name": "new", "command": "echo"}]}`
+	if err := os.WriteFile(toolsConfigPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	response, err := reloadToolsHandler(context.Background(), ReloadToolsArgs{})
+	if err != nil {
+		t.Fatalf("reloadToolsHandler failed: %v", err)
+	}
+	var result tools.ReloadResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "new" || len(result.Removed) != 1 || result.Removed[0] != "old" {
+		t.Errorf("Expected new to replace old, got %+v", result)
+	}
+	if _, ok := toolManager.GetTool("new"); !ok {
+		t.Error("Expected the new tool to be registered")
+	}
+}
+
+func TestRunPipelineHandler(t *testing.T) {
+	previousManager, previousPath := toolManager, toolsConfigPath
+	defer func() { toolManager, toolsConfigPath = previousManager, previousPath }()
+
+	toolManager = tools.NewToolManager()
+	toolsConfigPath = filepath.Join(t.TempDir(), "tools.json")
+	data := `{
+  "tools": [
+    {"name": "search", "command": "sed", "args": ["s/^/found: /"]},
+    {"name": "review", "command": "tr", "args": ["a-z", "A-Z"]}
+  ],
+  "pipelines": [
+    {"name": "search_review", "steps": [{"tool": "search"}, {"tool": "review", "input": "{{.Input}} -> {{trim .Steps.search}}"}]}
+  ]
+}`
+	if err := os.WriteFile(toolsConfigPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := reloadToolsHandler(context.Background(), ReloadToolsArgs{}); err != nil {
+		t.Fatalf("reloadToolsHandler failed: %v", err)
+	}
+
+	response, err := runPipelineHandler(context.Background(), RunPipelineArgs{Pipeline: "search_review", Input: "query"})
+	if err != nil {
+		t.Fatalf("runPipelineHandler failed: %v", err)
+	}
+	var result tools.PipelineResult
+	if err := json.Unmarshal([]byte(responseText(t, response)), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if result.Output != "QUERY -> FOUND: QUERY" || len(result.Steps) != 2 {
+		t.Errorf("Expected the review of the search, got %+v", result)
+	}
+
+	if _, err := runPipelineHandler(context.Background(), RunPipelineArgs{Pipeline: "missing"}); err == nil {
+		t.Error("Expected error for an unknown pipeline")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TFMV/scope/internal/tools"
+	"github.com/TFMV/scope/internal/tracing"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+func TestInstrumentTracesToolCalls(t *testing.T) {
+	type span struct {
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Status       struct {
+			Code int `json:"code"`
+		} `json:"status"`
+	}
+	var mu sync.Mutex
+	var spans []span
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	tracer := tracing.NewTracer(tracing.Config{
+		Endpoint:    server.URL,
+		Timeout:     time.Second,
+		SampleRatio: 1,
+		Interval:    time.Hour,
+		BatchSize:   10,
+		QueueSize:   10,
+	})
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	originalManager := toolManager
+	defer func() { toolManager = originalManager }()
+	toolManager = tools.NewToolManager()
+	toolManager.RegisterTool(tools.ToolConfig{Name: "trace_echo", Command: "echo", Args: []string{"traced"}})
+
+	handler := instrument("trace_test", func(ctx context.Context, args struct{}) (*mcp.ToolResponse, error) {
+		tool, _ := toolManager.GetTool("trace_echo")
+		if _, err := tool.Execute(ctx, ""); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("failed after running the tool")
+	})
+	if _, err := handler(context.Background(), struct{}{}); err == nil {
+		t.Fatalf("Expected the handler's error")
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to flush traces: %v", err)
+	}
+
+	byName := make(map[string]span)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	call, ok := byName["tool trace_test"]
+	if !ok {
+		t.Fatalf("Expected a span for the tool call, got %+v", spans)
+	}
+	if call.Status.Code != 2 {
+		t.Errorf("Expected the tool call span to record the error, got %+v", call)
+	}
+	exec, ok := byName["exec trace_echo"]
+	if !ok || exec.ParentSpanID != call.SpanID {
+		t.Errorf("Expected the external tool span inside the tool call, got %+v", spans)
+	}
+}
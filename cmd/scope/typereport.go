@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type TypeReportArgs struct {
+	Package        string `json:"package,omitempty" jsonschema:"description=Only report types declared in this package (import path or package name); omit for all packages" session:"package"`
+	GodObjectsOnly bool   `json:"god_objects_only,omitempty" jsonschema:"description=Only report types exceeding a threshold"`
+	ExportedOnly   bool   `json:"exported_only,omitempty" jsonschema:"description=Only report exported types" session:"exported_only"`
+	SortBy         string `json:"sort_by,omitempty" jsonschema:"description=Order by method_lines (default); methods; fields; fan_in or fan_out; largest first"`
+	Limit          int    `json:"limit,omitempty" jsonschema:"description=Maximum number of types to return (default all)" session:"limit"`
+	MaxMethods     int    `json:"max_methods,omitempty" jsonschema:"description=Flag types with more methods than this (default 20)"`
+	MaxMethodLines int    `json:"max_method_lines,omitempty" jsonschema:"description=Flag types whose methods span more lines than this (default 600)"`
+	MaxFields      int    `json:"max_fields,omitempty" jsonschema:"description=Flag structs with more fields than this (default 20)"`
+	MaxFanOut      int    `json:"max_fan_out,omitempty" jsonschema:"description=Flag types depending on more repository types than this (default 15)"`
+	ResponseBudget
+}
+
+// typeStatsKeys are the sort_by values of type_report
+var typeStatsKeys = map[string]func(analyzer.TypeStats) int{
+	"method_lines": func(s analyzer.TypeStats) int { return s.MethodLines },
+	"methods":      func(s analyzer.TypeStats) int { return s.Methods },
+	"fields":       func(s analyzer.TypeStats) int { return s.Fields },
+	"fan_in":       func(s analyzer.TypeStats) int { return s.FanIn },
+	"fan_out":      func(s analyzer.TypeStats) int { return s.FanOut },
+}
+
+func typeReportHandler(ctx context.Context, args TypeReportArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Building type report", "package", args.Package)
+	key := typeStatsKeys[args.SortBy]
+	if args.SortBy != "" && key == nil {
+		return nil, fmt.Errorf("unknown sort_by %q (expected method_lines, methods, fields, fan_in or fan_out)", args.SortBy)
+	}
+
+	start := time.Now()
+	report, err := analyzerInstance.TypeStatistics(ctx, args.Package, analyzer.TypeThresholds{
+		MaxMethods:     args.MaxMethods,
+		MaxMethodLines: args.MaxMethodLines,
+		MaxFields:      args.MaxFields,
+		MaxFanOut:      args.MaxFanOut,
+	})
+	metrics.AnalyzerDuration.ObserveDuration(start, "type_report")
+	if err != nil {
+		return nil, err
+	}
+
+	if args.GodObjectsOnly || args.ExportedOnly {
+		selected := []analyzer.TypeStats{}
+		for _, s := range report.Types {
+			_, name, _ := strings.Cut(s.Name, ".")
+			if (!args.GodObjectsOnly || s.GodObject) && (!args.ExportedOnly || token.IsExported(name)) {
+				selected = append(selected, s)
+			}
+		}
+		report.Types = selected
+	}
+	if key != nil {
+		sort.SliceStable(report.Types, func(i, j int) bool { return key(report.Types[i]) > key(report.Types[j]) })
+	}
+	if args.Limit > 0 && len(report.Types) > args.Limit {
+		report.Types = report.Types[:args.Limit]
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal type report: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
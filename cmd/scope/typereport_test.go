@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestTypeReportHandler(t *testing.T) {
+	ctx := context.Background()
+	response, err := typeReportHandler(ctx, TypeReportArgs{})
+	if err != nil {
+		t.Fatalf("typeReportHandler failed: %v", err)
+	}
+	var report analyzer.TypeReport
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+	if len(report.Types) != 1 || report.Types[0].Name != "testpkg.TestStruct" || report.Types[0].Methods != 1 || report.Types[0].FanIn != 1 {
+		t.Errorf("Expected TestStruct with one method and NewTestStruct referring to it, got %+v", report.Types)
+	}
+
+	// A threshold of one field flags nothing; TestStruct has exactly one
+	response, err = typeReportHandler(ctx, TypeReportArgs{GodObjectsOnly: true, MaxFields: 1, SortBy: "fan_in"})
+	if err != nil {
+		t.Fatalf("typeReportHandler failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(responseText(t, response)), &report); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+	if len(report.Types) != 0 || report.GodObjects != 0 {
+		t.Errorf("Expected no god objects, got %+v", report)
+	}
+
+	if _, err := typeReportHandler(ctx, TypeReportArgs{SortBy: "size"}); err == nil {
+		t.Error("Expected an error for an unknown sort key")
+	}
+}
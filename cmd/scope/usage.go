@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/TFMV/scope/internal/metrics"
+	mcp "github.com/metoro-io/mcp-golang"
+)
+
+type InterfaceUsageArgs struct {
+	Interface string `json:"interface" jsonschema:"required,description=Interface type to report on; qualify it as pkg.Name when ambiguous"`
+	ResponseBudget
+}
+
+func interfaceUsageHandler(ctx context.Context, args InterfaceUsageArgs) (*mcp.ToolResponse, error) {
+	slog.InfoContext(ctx, "Finding interface usage", "interface", args.Interface)
+	start := time.Now()
+	usage, err := analyzerInstance.InterfaceUsage(ctx, args.Interface)
+	metrics.AnalyzerDuration.ObserveDuration(start, "interface_usage")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interface usage: %w", err)
+	}
+	return mcp.NewToolResponse(mcp.NewTextContent(string(jsonData))), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WarmupStats counts what a cache warmup stored
+type WarmupStats struct {
+	Types   int `json:"types"`
+	Methods int `json:"methods"`
+	// Failed counts the lookups that failed, such as those of ambiguous
+	// qualified names
+	Failed int `json:"failed"`
+}
+
+// warmCache precomputes the lookup_type results of every exported type and
+// the list_methods results of the topN most referenced ones, so that the
+// first queries after startup are answered from the cache. Types are
+// cached under their qualified name and, when no other package declares
+// the name, under the bare name agents usually ask for.
+func warmCache(ctx context.Context, topN int) (WarmupStats, error) {
+	var stats WarmupStats
+	start := time.Now()
+	usages, err := analyzerInstance.ExportedTypes(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	for i, usage := range usages {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		names := []string{usage.Name}
+		if !usage.Ambiguous {
+			_, bare, _ := cutLast(usage.Name, ".")
+			names = append(names, bare)
+		}
+
+		typeInfo, err := analyzerInstance.LookupType(ctx, usage.Name)
+		if err != nil {
+			slog.DebugContext(ctx, "Failed to warm type", "type", usage.Name, "error", err)
+			stats.Failed++
+			continue
+		}
+		for _, name := range names {
+			if err := cacheInstance.Set(ctx, cacheKey("type", name), typeInfo, 24*time.Hour); err != nil {
+				return stats, err
+			}
+		}
+		stats.Types++
+
+		if i >= topN {
+			continue
+		}
+		methods, err := analyzerInstance.ListMethods(ctx, usage.Name)
+		if err != nil {
+			slog.DebugContext(ctx, "Failed to warm methods", "type", usage.Name, "error", err)
+			stats.Failed++
+			continue
+		}
+		for _, name := range names {
+			if err := cacheInstance.Set(ctx, cacheKey("methods", name), methods, 24*time.Hour); err != nil {
+				return stats, err
+			}
+		}
+		stats.Methods++
+	}
+	slog.InfoContext(ctx, "Warmed cache", "types", stats.Types, "methods", stats.Methods, "failed", stats.Failed, "duration", time.Since(start))
+	return stats, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestWarmCache(t *testing.T) {
+	ctx := context.Background()
+	invalidateAnalysisCache(ctx)
+	defer invalidateAnalysisCache(ctx)
+
+	stats, err := warmCache(ctx, 1)
+	if err != nil {
+		t.Fatalf("Failed to warm cache: %v", err)
+	}
+	if stats.Types != 1 || stats.Methods != 1 || stats.Failed != 0 {
+		t.Errorf("Expected TestStruct and its methods to be warmed, got %+v", stats)
+	}
+	for _, name := range []string{"TestStruct", "testpkg.TestStruct"} {
+		if typeInfo, found := cachedResult[*analyzer.TypeInfo](ctx, cacheKey("type", name)); !found || typeInfo.Name != "TestStruct" {
+			t.Errorf("Expected type info cached under %s, got %+v", name, typeInfo)
+		}
+		if methods, found := cachedResult[[]analyzer.MethodInfo](ctx, cacheKey("methods", name)); !found || len(methods) != 1 {
+			t.Errorf("Expected methods cached under %s, got %+v", name, methods)
+		}
+	}
+
+	// Without methods to warm, only types are cached
+	invalidateAnalysisCache(ctx)
+	if stats, err := warmCache(ctx, 0); err != nil || stats.Methods != 0 || stats.Types != 1 {
+		t.Errorf("Expected only types to be warmed, got %+v, %v", stats, err)
+	}
+	if _, found := cachedResult[[]analyzer.MethodInfo](ctx, cacheKey("methods", "TestStruct")); found {
+		t.Error("Expected no cached methods")
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/notify"
+	"github.com/TFMV/scope/internal/watch"
+)
+
+// watchSession holds the state of a `scope watch` run
+type watchSession struct {
+	repoPath string
+	analyzer *analyzer.Analyzer
+	out      io.Writer
+	vet      bool
+	tests    bool
+	notifier *notify.Dispatcher
+	current  []checks.Diagnostic
+}
+
+// runWatch implements `scope watch`: it keeps the analyzer warm and prints new
+// diagnostics for impacted packages whenever files change
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	repo := fs.String("repo", os.Getenv("GO_REPO_PATH"), "repository to watch (defaults to GO_REPO_PATH or the current directory)")
+	interval := fs.Duration("interval", time.Second, "how often to poll for file changes")
+	vet := fs.Bool("vet", true, "run go vet on impacted packages")
+	tests := fs.Bool("tests", true, "run tests of impacted packages")
+	notifyConfig := fs.String("notify", "", "notification config file (defaults to .scope/notify.json in the repository)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	repoPath := *repo
+	if repoPath == "" {
+		repoPath = "."
+	}
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve repository path: %v\n", err)
+		return 1
+	}
+
+	notifier, err := loadDispatcher(repoPath, *notifyConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure notifications: %v\n", err)
+		return 1
+	}
+
+	a, err := analyzer.NewAnalyzer(repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize analyzer: %v\n", err)
+		return 1
+	}
+	defer a.Close()
+
+	session := &watchSession{
+		repoPath: repoPath,
+		analyzer: a,
+		out:      os.Stdout,
+		vet:      *vet,
+		tests:    *tests,
+		notifier: notifier,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(session.out, "Watching %s (Ctrl+C to stop)\n", repoPath)
+	session.update(ctx, nil)
+
+	watcher := watch.New(repoPath, *interval, analyzer.DefaultConfig().ExcludePatterns)
+	if err := watcher.Run(ctx, func(changed []string) {
+		session.update(ctx, changed)
+	}); err != nil && err != context.Canceled {
+		fmt.Fprintf(os.Stderr, "Watch failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// update refreshes the analyzer, re-runs checks for the packages impacted by
+// changed (all packages when changed is nil), and prints the diagnostics that
+// appeared or disappeared since the previous run
+func (s *watchSession) update(ctx context.Context, changed []string) {
+	if changed != nil {
+		fmt.Fprintf(s.out, "\n%d file(s) changed\n", len(changed))
+		start := time.Now()
+		if err := s.analyzer.Refresh(ctx); err != nil {
+			fmt.Fprintf(s.out, "analyzer refresh failed: %v\n", err)
+		} else {
+			fmt.Fprintf(s.out, "analyzer refreshed in %v\n", time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	pkgs, dirs, err := s.impacted(ctx, changed)
+	if err != nil {
+		fmt.Fprintf(s.out, "failed to determine impacted packages: %v\n", err)
+		return
+	}
+	if len(pkgs) == 0 {
+		fmt.Fprintln(s.out, "no packages impacted")
+		return
+	}
+
+	fresh, err := s.runChecks(ctx, pkgs)
+	if err != nil {
+		fmt.Fprintf(s.out, "checks failed: %v\n", err)
+		return
+	}
+
+	// Keep diagnostics from packages that were not re-checked
+	next := fresh
+	if changed != nil {
+		for _, d := range s.current {
+			if d.File != "" && !dirs[filepath.Dir(d.File)] {
+				next = append(next, d)
+			}
+		}
+	}
+	checks.Sort(next)
+
+	added, resolved := checks.Diff(s.current, next)
+	s.current = next
+
+	for _, d := range resolved {
+		fmt.Fprintf(s.out, "FIXED %s\n", d)
+	}
+	for _, d := range added {
+		fmt.Fprintf(s.out, "NEW   %s\n", d)
+	}
+	fmt.Fprintf(s.out, "%d package(s) checked: %d diagnostic(s), %d new, %d fixed\n",
+		len(pkgs), len(s.current), len(added), len(resolved))
+
+	s.notify(ctx, changed == nil)
+}
+
+// notify sends new findings to the configured notifiers. Findings present
+// when watching starts only prime the dispatcher.
+func (s *watchSession) notify(ctx context.Context, initial bool) {
+	if s.notifier == nil {
+		return
+	}
+	if initial {
+		if err := s.notifier.Prime(s.current); err != nil {
+			fmt.Fprintf(s.out, "notification state update failed: %v\n", err)
+		}
+		return
+	}
+	notified, err := s.notifier.Dispatch(ctx, "watch", s.repoPath, s.current)
+	if err != nil {
+		fmt.Fprintf(s.out, "%v\n", err)
+	}
+	if len(notified) > 0 {
+		fmt.Fprintf(s.out, "notified about %d new finding(s)\n", len(notified))
+	}
+}
+
+// loadDispatcher loads the notification config at path, or the repository's
+// default config when path is empty. It returns nil when no config exists.
+func loadDispatcher(repoPath, path string) (*notify.Dispatcher, error) {
+	if path == "" {
+		path = notify.ConfigPath(repoPath)
+	}
+	config, err := notify.LoadConfig(path)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return notify.NewDispatcher(config)
+}
+
+// impacted returns the package patterns to check and the set of their directories
+func (s *watchSession) impacted(ctx context.Context, changed []string) ([]string, map[string]bool, error) {
+	packages, err := checks.ListPackages(ctx, s.repoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pkgs []string
+	if changed == nil || touchesModule(changed) {
+		for _, pkg := range packages {
+			pkgs = append(pkgs, pkg.ImportPath)
+		}
+	} else {
+		pkgs = checks.ImpactedPackages(packages, changed)
+	}
+
+	selected := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		selected[pkg] = true
+	}
+	dirs := make(map[string]bool)
+	for _, pkg := range packages {
+		if selected[pkg.ImportPath] {
+			dirs[pkg.Dir] = true
+		}
+	}
+	return pkgs, dirs, nil
+}
+
+// runChecks runs build, vet, and test checks for pkgs
+func (s *watchSession) runChecks(ctx context.Context, pkgs []string) ([]checks.Diagnostic, error) {
+	diagnostics, err := checks.Build(ctx, s.repoPath, pkgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Vet and tests only make sense once the packages compile
+	if len(diagnostics) > 0 {
+		return diagnostics, nil
+	}
+
+	if s.vet {
+		vetDiagnostics, err := checks.Vet(ctx, s.repoPath, pkgs...)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, vetDiagnostics...)
+	}
+
+	if s.tests {
+		testDiagnostics, err := checks.Test(ctx, s.repoPath, pkgs...)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, testDiagnostics...)
+	}
+
+	return diagnostics, nil
+}
+
+// touchesModule reports whether any changed file is a module definition file
+func touchesModule(changed []string) bool {
+	for _, file := range changed {
+		if base := filepath.Base(file); base == "go.mod" || base == "go.sum" {
+			return true
+		}
+	}
+	return false
+}
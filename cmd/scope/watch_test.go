@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestWatchSessionUpdate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scope-watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	mainFile := filepath.Join(tempDir, "main.go")
+	files := map[string]string{
+		"go.mod":  "module example.com/watched\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	a, err := analyzer.NewAnalyzer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	var out bytes.Buffer
+	session := &watchSession{repoPath: tempDir, analyzer: a, out: &out}
+	ctx := context.Background()
+
+	// Initial run over a clean tree
+	session.update(ctx, nil)
+	if len(session.current) != 0 {
+		t.Fatalf("Expected no diagnostics, got %v", session.current)
+	}
+
+	// Break the build
+	if err := os.WriteFile(mainFile, []byte("package main\n\nfunc main() {\n\tmissing()\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+	out.Reset()
+	session.update(ctx, []string{mainFile})
+	if !strings.Contains(out.String(), "NEW") || !strings.Contains(out.String(), "missing") {
+		t.Errorf("Expected new build diagnostic, got:\n%s", out.String())
+	}
+
+	// Fix it again
+	if err := os.WriteFile(mainFile, []byte(files["main.go"]), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+	out.Reset()
+	session.update(ctx, []string{mainFile})
+	if !strings.Contains(out.String(), "FIXED") {
+		t.Errorf("Expected fixed diagnostic, got:\n%s", out.String())
+	}
+	if len(session.current) != 0 {
+		t.Errorf("Expected no remaining diagnostics, got %v", session.current)
+	}
+}
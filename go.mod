@@ -2,7 +2,10 @@ module github.com/TFMV/scope
 
 go 1.24.3
 
-require github.com/metoro-io/mcp-golang v0.13.0
+require (
+	github.com/metoro-io/mcp-golang v0.13.0
+	go.etcd.io/bbolt v1.4.3
+)
 
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
@@ -15,5 +18,6 @@ require (
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
+	golang.org/x/sys v0.29.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
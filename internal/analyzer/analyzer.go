@@ -9,27 +9,79 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
-	"log"
+	"go/version"
+	"hash/fnv"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/TFMV/scope/internal/tracing"
 )
 
 // Analyzer handles the analysis of Go types and methods with enterprise-grade features
 type Analyzer struct {
 	repoPath    string
 	fset        *token.FileSet
-	pkgs        map[string]*types.Package
-	docPkgs     map[string]*doc.Package
-	info        *types.Info
+	pkgs        map[string]*types.Package // Maps import path to type-checked package
+	docPkgs     map[string]*doc.Package   // Maps import path to package documentation
+	infos       map[string]*types.Info    // Maps import path to type information
+	asts        map[string][]*ast.File    // Maps import path to parsed files
 	mu          sync.RWMutex
-	logger      *log.Logger
+	logger      *slog.Logger
 	initialized bool
 	config      *Config
-	files       map[string][]string // Maps package name to list of files
+	files       map[string][]string     // Maps import path to list of files
+	modules     map[string]string       // Maps directory to the module path declared by its go.mod
+	deps        *depLoader              // Loads standard library and module dependencies; nil unless enabled
+	sources     sourceState             // Snapshot of the analyzed source files
+	lastChange  time.Time               // When the analyzed sources last changed
+	analyzed    time.Time               // When the current analysis completed
+	analysis    time.Duration           // How long the current analysis took
+	snapshot    *Snapshot               // Answers queries until the first analysis completes
+	promoted    bool                    // Whether the analysis replacing the snapshot has started
+	index       symbolIndex             // Package-level objects by name
+	tags        map[string]*packageTags // Plugin tags by import path
+	lazy        *lazyState              // Packages loaded on demand; nil unless enabled
+	workspace   *workspace              // Modules listed by go.work; nil without one
+	generated   map[string]bool         // Generated source files by filename
+	ignore      ignoreRules             // Patterns of the repository's ignore file
+	overlay     *overlay                // File contents analyzed in place of the disk's
+	// parseErrors are the syntax errors by filename
+	parseErrors map[string]FileDiagnostic
+	// versionErrors are the uses of language features newer than
+	// Config.GoVersion by import path
+	versionErrors map[string][]VersionDiagnostic
+	// typeErrors are the errors type checking reported by import path
+	typeErrors map[string][]AnalysisError
+}
+
+// SchemaVersion identifies the shape of the analyzer's result types. It is
+// part of cache keys, so bump it whenever TypeInfo, MethodInfo,
+// HierarchyInfo or PackageInfo change in a way that old cached values would
+// not decode into.
+const SchemaVersion = 6
+
+// sourceState summarizes the analyzed files so that changes between
+// analyses can be detected
+type sourceState struct {
+	files  int
+	hash   uint64
+	newest time.Time
+}
+
+// add records a source file in the state
+func (s *sourceState) add(path string, info os.FileInfo) {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano())
+	s.files++
+	s.hash ^= h.Sum64()
+	if info.ModTime().After(s.newest) {
+		s.newest = info.ModTime()
+	}
 }
 
 // Config holds configuration options for the analyzer
@@ -38,11 +90,41 @@ type Config struct {
 	CacheTimeout    time.Duration // How long to cache results
 	IncludeTests    bool          // Whether to include test files
 	IncludeVendor   bool          // Whether to include vendor directory
-	ExcludePatterns []string      // Patterns to exclude from analysis
+	ExcludePatterns []string      // Patterns to exclude from analysis, in addition to the repository's .scopeignore
 	MaxFileSize     int64         // Maximum file size to analyze (bytes)
 	AnalysisTimeout time.Duration // Timeout for analysis operations
 	EnableProfiling bool          // Enable performance profiling
 	LogLevel        LogLevel      // Logging level
+	// LoadDependencies resolves imports and lookups of standard library and
+	// module dependencies (from GOMODCACHE) on demand
+	LoadDependencies bool
+	// Plugins run for this analyzer in addition to those registered with
+	// RegisterPlugin
+	Plugins []Plugin
+	// Taggers run for this analyzer in addition to the built-in taggers and
+	// the rules of the repository's tag config
+	Taggers []Tagger
+	// LazyLoading defers parsing and type checking each package until a
+	// query first needs it
+	LazyLoading bool
+	// MemoryBudget is the heap size in bytes (runtime.MemStats.HeapAlloc)
+	// above which lazily loaded packages are evicted, least recently used
+	// first. Zero never evicts. Only used with LazyLoading.
+	MemoryBudget uint64
+	// IndexPath is a file persisting what discovery learns from each
+	// source file, so restarts only parse the files that changed. Only
+	// used with LazyLoading: eager loading parses and type checks every
+	// file at startup regardless.
+	IndexPath string
+	// ExcludeGenerated leaves generated files out of the analysis: files
+	// with a "Code generated ... DO NOT EDIT." header and files named
+	// *.pb.go or *_gen.go
+	ExcludeGenerated bool
+	// GoVersion is the language version, such as "go1.21" or "1.21", that
+	// type checking accepts. Uses of newer language features are reported
+	// by ParseDiagnostics. Empty accepts everything the toolchain scope was
+	// built with supports; syntax newer than the toolchain never parses.
+	GoVersion string
 }
 
 // LogLevel represents different logging levels
@@ -57,22 +139,31 @@ const (
 
 // TypeInfo represents comprehensive information about a Go type
 type TypeInfo struct {
-	Name         string            `json:"name"`
-	Kind         string            `json:"kind"`
-	Package      string            `json:"package"`
-	ImportPath   string            `json:"import_path"`
-	Doc          string            `json:"doc"`
-	Methods      []MethodInfo      `json:"methods,omitempty"`
-	Fields       []FieldInfo       `json:"fields,omitempty"`
-	Interfaces   []string          `json:"interfaces,omitempty"`
-	Examples     []ExampleInfo     `json:"examples,omitempty"`
-	Position     Position          `json:"position"`
-	Exported     bool              `json:"exported"`
-	Size         int64             `json:"size,omitempty"`
-	Alignment    int64             `json:"alignment,omitempty"`
-	Dependencies []string          `json:"dependencies,omitempty"`
-	UsedBy       []string          `json:"used_by,omitempty"`
-	Tags         map[string]string `json:"tags,omitempty"`
+	Name         string        `json:"name"`
+	Kind         string        `json:"kind"`
+	Package      string        `json:"package"`
+	ImportPath   string        `json:"import_path"`
+	Doc          string        `json:"doc"`
+	Methods      []MethodInfo  `json:"methods,omitempty"`
+	Fields       []FieldInfo   `json:"fields,omitempty"`
+	Interfaces   []string      `json:"interfaces,omitempty"`
+	Examples     []ExampleInfo `json:"examples,omitempty"`
+	Position     Position      `json:"position"`
+	Exported     bool          `json:"exported"`
+	Size         int64         `json:"size,omitempty"`
+	Alignment    int64         `json:"alignment,omitempty"`
+	Dependencies []string      `json:"dependencies,omitempty"`
+	UsedBy       []string      `json:"used_by,omitempty"`
+	// Deprecated is set when the doc comment has a "Deprecated:"
+	// paragraph, whose text is DeprecationNote
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	DeprecationNote string `json:"deprecation_note,omitempty"`
+	// Tags are attached by taggers, keyed by tag name, and by analysis
+	// plugins, keyed by "<plugin>.<key>"
+	Tags map[string]string `json:"tags,omitempty"`
+	// Unresolved is set when the type's fields, methods or underlying type
+	// refer to types that failed to type check
+	Unresolved bool `json:"unresolved,omitempty"`
 }
 
 // MethodInfo represents information about a method
@@ -86,6 +177,30 @@ type MethodInfo struct {
 	Position   Position    `json:"position"`
 	Exported   bool        `json:"exported"`
 	IsPointer  bool        `json:"is_pointer"`
+	// Deprecated is set when the doc comment has a "Deprecated:"
+	// paragraph, whose text is DeprecationNote
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	DeprecationNote string `json:"deprecation_note,omitempty"`
+	// Unresolved is set when the signature refers to types that failed to
+	// type check; it is then written as in the source
+	Unresolved bool `json:"unresolved,omitempty"`
+	// Promoted is set when an embedded type declares the method rather
+	// than the type itself. Embedding lists the embedded fields, or the
+	// embedded interfaces of an interface, the method is promoted through,
+	// outermost first; the last one's type declares it.
+	Promoted  bool            `json:"promoted,omitempty"`
+	Embedding []EmbeddedField `json:"embedding,omitempty"`
+}
+
+// EmbeddedField is an embedded field, or an embedded interface of an
+// interface, a method is promoted through
+type EmbeddedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Interface is set when the embedded type is an interface: the method
+	// is then implemented by whatever value the field holds
+	Interface bool      `json:"interface,omitempty"`
+	Position  *Position `json:"position,omitempty"`
 }
 
 // FieldInfo represents information about a struct field
@@ -97,12 +212,16 @@ type FieldInfo struct {
 	Position Position `json:"position"`
 	Exported bool     `json:"exported"`
 	Embedded bool     `json:"embedded"`
+	// Unresolved is set when Type failed to type check and is written as
+	// in the source
+	Unresolved bool `json:"unresolved,omitempty"`
 }
 
 // ParamInfo represents parameter or result information
 type ParamInfo struct {
-	Name string `json:"name,omitempty"`
-	Type string `json:"type"`
+	Name       string `json:"name,omitempty"`
+	Type       string `json:"type"`
+	Unresolved bool   `json:"unresolved,omitempty"`
 }
 
 // ExampleInfo represents code example information
@@ -118,6 +237,8 @@ type Position struct {
 	Filename string `json:"filename"`
 	Line     int    `json:"line"`
 	Column   int    `json:"column"`
+	// Generated reports whether the file is generated code
+	Generated bool `json:"generated,omitempty"`
 }
 
 // AnalysisResult represents the result of a comprehensive analysis
@@ -147,6 +268,13 @@ type FunctionInfo struct {
 	Exported   bool        `json:"exported"`
 	IsMethod   bool        `json:"is_method"`
 	Complexity int         `json:"complexity,omitempty"`
+	// Deprecated is set when the doc comment has a "Deprecated:"
+	// paragraph, whose text is DeprecationNote
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	DeprecationNote string `json:"deprecation_note,omitempty"`
+	// Unresolved is set when the signature refers to types that failed to
+	// type check; it is then written as in the source
+	Unresolved bool `json:"unresolved,omitempty"`
 }
 
 // VariableInfo represents information about a variable
@@ -158,6 +286,9 @@ type VariableInfo struct {
 	Position Position `json:"position"`
 	Exported bool     `json:"exported"`
 	Value    string   `json:"value,omitempty"`
+	// Unresolved is set when Type failed to type check and is written as
+	// in the source
+	Unresolved bool `json:"unresolved,omitempty"`
 }
 
 // ConstantInfo represents information about a constant
@@ -189,6 +320,18 @@ type PackageInfo struct {
 	Position   Position `json:"position"`
 	IsMain     bool     `json:"is_main"`
 	Size       int64    `json:"size"`
+	// Module is the path of the module containing the package
+	Module string `json:"module,omitempty"`
+	// Generated reports whether every file of the package is generated code
+	Generated bool `json:"generated,omitempty"`
+	// Tags are attached by analysis plugins
+	Tags map[string]string `json:"tags,omitempty"`
+	// TypeErrors is the number of errors type checking reported. Such a
+	// package is analyzed from what could be resolved, and declarations
+	// referring to types that could not are marked unresolved.
+	TypeErrors int `json:"type_errors,omitempty"`
+	// Embeds are the variables initialized with //go:embed directives
+	Embeds []Embed `json:"embeds,omitempty"`
 }
 
 // AnalysisMetrics represents metrics about the analysis
@@ -200,6 +343,8 @@ type AnalysisMetrics struct {
 	TotalPackages  int           `json:"total_packages"`
 	AnalysisTime   time.Duration `json:"analysis_time"`
 	MemoryUsage    int64         `json:"memory_usage"`
+	// Packages are the coupling and cohesion metrics of each package
+	Packages []PackageMetrics `json:"packages,omitempty"`
 }
 
 // AnalysisError represents an error during analysis
@@ -239,6 +384,22 @@ func NewAnalyzer(repoPath string) (*Analyzer, error) {
 
 // NewAnalyzerWithConfig creates a new Analyzer with custom configuration
 func NewAnalyzerWithConfig(repoPath string, config *Config) (*Analyzer, error) {
+	analyzer, err := newAnalyzer(repoPath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the analyzer
+	if err := analyzer.initialize(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+
+	return analyzer, nil
+}
+
+// newAnalyzer creates an Analyzer for repoPath that has not analyzed
+// anything yet
+func newAnalyzer(repoPath string, config *Config) (*Analyzer, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
@@ -247,114 +408,396 @@ func NewAnalyzerWithConfig(repoPath string, config *Config) (*Analyzer, error) {
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("repository path does not exist: %s", repoPath)
 	}
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve repository path: %w", err)
+	}
 
-	// Initialize logger
-	logger := log.New(os.Stderr, "[ANALYZER] ", log.LstdFlags|log.Lshortfile)
+	if config.GoVersion != "" && !version.IsValid(languageVersion(config.GoVersion)) {
+		return nil, fmt.Errorf("invalid Go version %q", config.GoVersion)
+	}
+
+	// Log through the configured slog handler
+	logger := slog.Default().With("component", "analyzer")
 
 	analyzer := &Analyzer{
-		repoPath: repoPath,
-		fset:     token.NewFileSet(),
-		pkgs:     make(map[string]*types.Package),
-		docPkgs:  make(map[string]*doc.Package),
-		info:     &types.Info{},
-		logger:   logger,
-		config:   config,
-		files:    make(map[string][]string),
+		repoPath:  repoPath,
+		fset:      token.NewFileSet(),
+		pkgs:      make(map[string]*types.Package),
+		docPkgs:   make(map[string]*doc.Package),
+		infos:     make(map[string]*types.Info),
+		asts:      make(map[string][]*ast.File),
+		logger:    logger,
+		config:    config,
+		files:     make(map[string][]string),
+		modules:   make(map[string]string),
+		tags:      make(map[string]*packageTags),
+		generated: make(map[string]bool),
+
+		parseErrors:   make(map[string]FileDiagnostic),
+		versionErrors: make(map[string][]VersionDiagnostic),
+		typeErrors:    make(map[string][]AnalysisError),
+		overlay:       newOverlay(),
 	}
-
-	// Initialize the analyzer
-	if err := analyzer.initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize analyzer: %w", err)
+	if config.LoadDependencies {
+		analyzer.deps = newDepLoader(repoPath, analyzer.fset)
+	}
+	if config.LazyLoading {
+		analyzer.lazy = newLazyState()
 	}
-
 	return analyzer, nil
 }
 
-// initialize performs the initial analysis of the repository
-func (a *Analyzer) initialize() error {
+// initialize performs the initial analysis of the repository. It stops
+// when ctx is done or Config.AnalysisTimeout has passed.
+func (a *Analyzer) initialize(ctx context.Context) error {
+	if a.config.AnalysisTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.config.AnalysisTimeout)
+		defer cancel()
+	}
 	start := time.Now()
-	a.logInfo("Starting repository analysis: %s", a.repoPath)
+	a.logInfo("Starting repository analysis", "repo", a.repoPath)
+	ctx, span := tracing.Start(ctx, "analyze", tracing.KindInternal, tracing.String("scope.repo", a.repoPath), tracing.Bool("scope.lazy", a.lazy != nil))
+	defer span.End()
 
 	// Parse all Go files in the repository
-	if err := a.parseRepository(); err != nil {
+	workspace, err := a.readWorkspace()
+	if err != nil {
+		a.logWarn("Ignoring go.work", "error", err)
+	}
+	a.workspace = workspace
+	ignore, err := readIgnoreFile(a.repoPath)
+	if err != nil {
+		a.logWarn("Ignoring ignore file", "file", IgnoreFile, "error", err)
+	}
+	a.ignore = ignore
+	previous := a.sources
+	a.sources = sourceState{}
+	if a.lazy != nil {
+		a.lazy.index = a.readFileIndex(a.config.IndexPath)
+	}
+	_, parseSpan := tracing.Start(ctx, "parse", tracing.KindInternal)
+	err = a.parseRepository(ctx)
+	parseSpan.SetAttributes(tracing.Int("scope.files", a.sources.files), tracing.Int("scope.packages", len(a.files)))
+	parseSpan.SetError(err)
+	parseSpan.End()
+	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("failed to parse repository: %w", err)
 	}
+	if a.lazy == nil {
+		if err := a.runPlugins(ctx, "after parse", a.sortedImportPaths(), afterParse(ctx)); err != nil {
+			return err
+		}
+	}
+	switch {
+	case a.lastChange.IsZero():
+		a.lastChange = a.sources.newest
+	case a.sources != previous:
+		// Deleted files leave no newer modification time behind
+		a.lastChange = start
+		if a.sources.newest.After(start) {
+			a.lastChange = a.sources.newest
+		}
+	}
+
+	if a.lazy != nil {
+		// Packages are parsed and type checked when a query first needs them
+		if a.config.IndexPath != "" {
+			if err := a.lazy.index.write(a.repoPath); err != nil {
+				a.logWarn("Failed to save file index", "error", err)
+			}
+		}
+		a.buildIndex()
+		a.initialized = true
+		a.snapshot = nil
+		a.analyzed, a.analysis = time.Now(), time.Since(start)
+		a.logInfo("Discovered packages; loading them on demand", "packages", len(a.files), "duration", a.analysis,
+			"parsed", a.lazy.index.parsed, "from_index", a.lazy.index.reused)
+		return nil
+	}
 
 	// Type check all packages
-	if err := a.typeCheckPackages(); err != nil {
+	_, checkSpan := tracing.Start(ctx, "typecheck", tracing.KindInternal, tracing.Int("scope.packages", len(a.asts)))
+	err = a.typeCheckPackages(ctx)
+	checkSpan.SetError(err)
+	checkSpan.End()
+	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("failed to type check packages: %w", err)
 	}
-
-	// Generate documentation
-	if err := a.generateDocumentation(); err != nil {
-		a.logWarn("Failed to generate documentation: %v", err)
+	if err := a.analyzePackages(ctx, a.sortedImportPaths()); err != nil {
+		span.SetError(err)
+		return err
 	}
 
 	a.initialized = true
-	duration := time.Since(start)
-	a.logInfo("Repository analysis completed in %v", duration)
+	a.snapshot = nil
+	a.analyzed, a.analysis = time.Now(), time.Since(start)
+	a.logInfo("Repository analysis completed", "duration", a.analysis)
 
 	return nil
 }
 
-// parseRepository recursively parses all Go files in the repository
-func (a *Analyzer) parseRepository() error {
-	return filepath.Walk(a.repoPath, func(path string, info os.FileInfo, err error) error {
+// parseRepository recursively parses all Go files in the repository or, when
+// it has a go.work file, in the modules of the workspace
+func (a *Analyzer) parseRepository(ctx context.Context) error {
+	if a.workspace == nil {
+		return a.parseTree(ctx, a.repoPath)
+	}
+	for _, module := range a.workspace.modules {
+		if err := a.parseTree(ctx, module.Dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTree parses the Go files under root. In a workspace, directories of
+// other modules are skipped: workspace modules are parsed on their own, and
+// modules the workspace does not use are not part of its build. Files in
+// the overlay are parsed with their overlaid content, and those not on disk
+// are added after the walk.
+func (a *Analyzer) parseTree(ctx context.Context, root string) error {
+	walked := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		// Skip directories and non-Go files
-		if info.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
+		if info.IsDir() && a.workspace != nil && path != root && a.modulePath(path) != "" {
+			return filepath.SkipDir
 		}
 
-		// Skip excluded patterns
-		for _, pattern := range a.config.ExcludePatterns {
-			if strings.Contains(path, pattern) {
-				return nil
+		// Skip paths the ignore file lists
+		if a.ignored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		// Skip test files if not included
-		if !a.config.IncludeTests && strings.HasSuffix(path, "_test.go") {
+		// Skip directories and non-Go files
+		if info.IsDir() || !strings.HasSuffix(path, ".go") {
 			return nil
 		}
+		walked[path] = true
+		a.addSource(path, a.overlay.stat(path, info))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// Skip large files
-		if info.Size() > a.config.MaxFileSize {
-			a.logWarn("Skipping large file: %s (%d bytes)", path, info.Size())
-			return nil
+	for _, path := range a.overlay.under(root) {
+		if walked[path] || !a.overlaid(root, path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			// Skipped by the walk
+			continue
 		}
+		a.addSource(path, a.overlay.stat(path, nil))
+	}
+	return nil
+}
+
+// ignored reports whether the ignore file lists a path of the repository
+func (a *Analyzer) ignored(path string, dir bool) bool {
+	if a.ignore == nil {
+		return false
+	}
+	rel, err := filepath.Rel(a.repoPath, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..") && a.ignore.ignored(filepath.ToSlash(rel), dir)
+}
 
-		// Parse the file
-		if err := a.parseFile(path); err != nil {
-			a.logWarn("Failed to parse file %s: %v", path, err)
+// overlaid reports whether a file only the overlay holds belongs to the
+// tree under root: the walk would have visited its directory
+func (a *Analyzer) overlaid(root, path string) bool {
+	if a.ignored(path, false) {
+		return false
+	}
+	for dir := filepath.Dir(path); dir != root; dir = filepath.Dir(dir) {
+		if a.ignored(dir, true) || a.workspace != nil && a.modulePath(dir) != "" {
+			return false
 		}
+	}
+	return true
+}
 
-		return nil
-	})
+// addSource parses a Go source file found under the repository, unless the
+// configuration excludes it
+func (a *Analyzer) addSource(path string, info os.FileInfo) {
+	// Skip excluded patterns
+	for _, pattern := range a.config.ExcludePatterns {
+		if strings.Contains(path, pattern) {
+			return
+		}
+	}
+
+	// Skip test files if not included
+	if !a.config.IncludeTests && strings.HasSuffix(path, "_test.go") {
+		return
+	}
+
+	// Skip large files
+	if info.Size() > a.config.MaxFileSize {
+		a.logWarn("Skipping large file", "file", path, "bytes", info.Size())
+		return
+	}
+
+	// Parse the file
+	a.sources.add(path, info)
+	var err error
+	if a.lazy != nil {
+		err = a.discoverFile(path, info)
+	} else {
+		err = a.parseFile(path)
+	}
+	if err != nil {
+		a.logWarn("Failed to parse file", "file", path, "error", err)
+	}
 }
 
-// parseFile parses a single Go file
+// parseFile parses a single Go file and adds it to the package of its directory
 func (a *Analyzer) parseFile(filename string) error {
-	src, err := os.ReadFile(filename)
+	src, err := a.readSource(filename)
 	if err != nil {
 		return err
 	}
 
-	// Parse the file
+	// Parse the file, keeping what parsed of a file with syntax errors
 	file, err := parser.ParseFile(a.fset, filename, src, parser.ParseComments)
-	if err != nil {
+	if file, err = a.parsed(filename, file, err); err != nil {
 		return err
 	}
+	if a.skipGenerated(filename, generatedReason(filename, factsOf(file)) != "") {
+		return nil
+	}
+
+	importPath := a.packageOf(filename, file.Name.Name)
+	a.asts[importPath] = append(a.asts[importPath], file)
+	a.files[importPath] = append(a.files[importPath], filename)
 
-	// Add to package
-	pkgName := file.Name.Name
+	return nil
+}
+
+// packageOf returns the import path of the package a parsed file belongs
+// to. External test packages live next to the package they test.
+func (a *Analyzer) packageOf(filename, pkgName string) string {
+	importPath := a.importPathFor(filepath.Dir(filename))
+	if strings.HasSuffix(pkgName, "_test") {
+		importPath += "_test"
+	}
+	return importPath
+}
+
+// importPathFor derives the import path of the package in dir from the
+// nearest enclosing go.mod. Directories outside any module use their path
+// relative to the repository's parent, GOPATH-style.
+func (a *Analyzer) importPathFor(dir string) string {
+	if moduleDir, modulePath := a.moduleFor(dir); modulePath != "" {
+		rel, err := filepath.Rel(moduleDir, dir)
+		if err != nil || rel == "." {
+			return modulePath
+		}
+		return modulePath + "/" + filepath.ToSlash(rel)
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(a.repoPath), dir)
+	if err != nil {
+		return filepath.Base(dir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// moduleFor returns the directory and path of the module containing dir,
+// declared by the nearest enclosing go.mod. Both are empty outside any module.
+func (a *Analyzer) moduleFor(dir string) (string, string) {
+	for current := dir; ; {
+		if modulePath := a.modulePath(current); modulePath != "" {
+			return current, modulePath
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", ""
+		}
+		current = parent
+	}
+}
+
+// modulePath returns the module path declared by dir/go.mod, caching results
+func (a *Analyzer) modulePath(dir string) string {
+	if modulePath, ok := a.modules[dir]; ok {
+		return modulePath
+	}
+
+	modulePath := ""
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "module" {
+				modulePath = strings.Trim(fields[1], `"`)
+				break
+			}
+		}
+	}
+	a.modules[dir] = modulePath
+	return modulePath
+}
+
+// typeCheckPackages performs type checking on all parsed packages
+func (a *Analyzer) typeCheckPackages(ctx context.Context) error {
+	importer := &repoImporter{
+		analyzer: a,
+		fallback: importer.Default(),
+		checking: make(map[string]bool),
+	}
+	if a.deps != nil {
+		imports := a.externalImports()
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		if err := a.deps.Prefetch(ctx, paths); err != nil {
+			a.logWarn("Failed to list dependencies", "error", err)
+		}
+		importer.fallback = a.deps
+	}
+
+	for _, importPath := range a.sortedImportPaths() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := importer.Import(importPath); err != nil {
+			a.logWarn("Type checking failed", "package", importPath, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// checkPackage type checks a single parsed package
+func (a *Analyzer) checkPackage(importPath string, imp types.Importer) (*types.Package, error) {
+	files := a.asts[importPath]
+	if len(files) == 0 {
+		return nil, fmt.Errorf("package %s has no files", importPath)
+	}
+
+	delete(a.versionErrors, importPath)
+	delete(a.typeErrors, importPath)
 	conf := types.Config{
-		Importer: importer.Default(),
+		Importer:  imp,
+		GoVersion: languageVersion(a.config.GoVersion),
 		Error: func(err error) {
-			a.logWarn("Type checking error: %v", err)
+			a.logWarn("Type checking error", "error", err)
+			a.recordTypeError(importPath, err)
+			a.recordUnresolved(importPath, err)
 		},
 	}
 
@@ -368,187 +811,276 @@ func (a *Analyzer) parseFile(filename string) error {
 		Scopes:     make(map[ast.Node]*types.Scope),
 	}
 
-	// Type check the file
-	pkg, err := conf.Check(pkgName, a.fset, []*ast.File{file}, info)
-	if err != nil {
-		a.logWarn("Type checking failed for file %s: %v", filename, err)
-		return err
+	// Type check the package. Errors are reported through conf.Error and the
+	// partially checked package is kept so that lookups still work.
+	pkg, _ := conf.Check(importPath, a.fset, files, info)
+	if pkg == nil {
+		return nil, fmt.Errorf("type checking produced no package for %s", importPath)
 	}
 
-	a.pkgs[pkgName] = pkg
-	a.files[pkgName] = append(a.files[pkgName], filename)
+	a.pkgs[importPath] = pkg
+	a.infos[importPath] = info
+	return pkg, nil
+}
 
-	// Merge info if this is the first package or extend as needed
-	if len(a.info.Types) == 0 {
-		a.info = info
-	}
+// repoImporter resolves imports of packages inside the repository from the
+// analyzer's own parsed packages, type checking them on demand, and defers
+// everything else to the fallback importer
+type repoImporter struct {
+	analyzer *Analyzer
+	fallback types.Importer
+	checking map[string]bool
+}
 
-	return nil
+// Import implements types.Importer
+func (r *repoImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := r.analyzer.pkgs[path]; ok {
+		return pkg, nil
+	}
+	if _, ok := r.analyzer.asts[path]; ok {
+		if r.checking[path] {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		r.checking[path] = true
+		defer delete(r.checking, path)
+		return r.analyzer.checkPackage(path, r)
+	}
+	return r.fallback.Import(path)
 }
 
-// typeCheckPackages performs type checking on all parsed packages
-func (a *Analyzer) typeCheckPackages() error {
-	conf := types.Config{
-		Importer: importer.Default(),
-		Error: func(err error) {
-			a.logWarn("Type checking error: %v", err)
-		},
+// analyzePackages runs the analysis following type checking for the given
+// packages: plugins, the symbol index, documentation and taggers
+func (a *Analyzer) analyzePackages(ctx context.Context, importPaths []string) error {
+	if err := a.runPlugins(ctx, "after type check", importPaths, afterTypeCheck(ctx)); err != nil {
+		return err
 	}
 
-	for pkgName, files := range a.files {
-		// Convert ast.Files to slice
-		var astFiles []*ast.File
-		for _, file := range files {
-			astFile, err := parser.ParseFile(a.fset, file, nil, parser.ParseComments)
-			if err != nil {
-				a.logWarn("Failed to parse file %s: %v", file, err)
-				continue
-			}
-			astFiles = append(astFiles, astFile)
-		}
+	a.buildIndex()
 
-		// Create type info
-		info := &types.Info{
-			Types:      make(map[ast.Expr]types.TypeAndValue),
-			Defs:       make(map[*ast.Ident]types.Object),
-			Uses:       make(map[*ast.Ident]types.Object),
-			Implicits:  make(map[ast.Node]types.Object),
-			Selections: make(map[*ast.SelectorExpr]*types.Selection),
-			Scopes:     make(map[ast.Node]*types.Scope),
-		}
+	// Generate documentation
+	_, docSpan := tracing.Start(ctx, "doc", tracing.KindInternal, tracing.Int("scope.packages", len(importPaths)))
+	if err := a.generateDocumentation(); err != nil {
+		a.logWarn("Failed to generate documentation", "error", err)
+		docSpan.SetError(err)
+	}
+	docSpan.End()
+	if err := a.runTaggers(ctx); err != nil {
+		return err
+	}
+	return a.runPlugins(ctx, "before result", importPaths, beforeResult(ctx))
+}
+
+// sortedImportPaths returns the import paths of all parsed packages in sorted order
+func (a *Analyzer) sortedImportPaths() []string {
+	paths := make([]string, 0, len(a.asts))
+	for importPath := range a.asts {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
 
-		// Type check the package
-		pkg, err := conf.Check(pkgName, a.fset, astFiles, info)
+// generateDocumentation extracts the documentation of all packages from
+// their comments. Unexported declarations are kept, and the ASTs are left
+// untouched since they are shared with type checking.
+func (a *Analyzer) generateDocumentation() error {
+	for importPath := range a.pkgs {
+		if _, ok := a.docPkgs[importPath]; ok {
+			continue
+		}
+		docPkg, err := doc.NewFromFiles(a.fset, a.asts[importPath], importPath, doc.AllDecls|doc.PreserveAST)
 		if err != nil {
-			a.logWarn("Type checking failed for package %s: %v", pkgName, err)
+			a.logWarn("Failed to extract documentation", "package", importPath, "error", err)
 			continue
 		}
+		a.docPkgs[importPath] = docPkg
+	}
+	return nil
+}
 
-		a.pkgs[pkgName] = pkg
-		// Merge info if this is the first package or extend as needed
-		if len(a.info.Types) == 0 {
-			a.info = info
-		}
+// AmbiguousError is returned when a name matches symbols in more than one
+// package. Candidates holds qualified names that resolve unambiguously.
+type AmbiguousError struct {
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%s is ambiguous; use one of: %s", e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// splitQualifiedName splits "pkg.Name" or "import/path.Name" into its
+// package qualifier and identifier. Bare identifiers have an empty qualifier.
+func splitQualifiedName(name string) (qualifier, ident string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
 	}
+	return "", name
+}
 
-	return nil
+// matchesQualifier reports whether the package with the given import path and
+// name is selected by a qualifier: the full import path, an import path
+// suffix such as "internal/analyzer", or the package name
+func matchesQualifier(qualifier, importPath, pkgName string) bool {
+	return qualifier == "" || qualifier == importPath || qualifier == pkgName ||
+		strings.HasSuffix(importPath, "/"+qualifier)
 }
 
-// generateDocumentation generates documentation for all packages
-func (a *Analyzer) generateDocumentation() error {
-	for pkgName, pkg := range a.pkgs {
-		// Create documentation using the type information
-		docPkg := &doc.Package{
-			Name:   pkgName,
-			Types:  make([]*doc.Type, 0),
-			Funcs:  make([]*doc.Func, 0),
-			Vars:   make([]*doc.Value, 0),
-			Consts: make([]*doc.Value, 0),
-		}
+// resolve finds the packages defining name, which may be qualified. It
+// returns an AmbiguousError when more than one package matches.
+func (a *Analyzer) resolve(name string) (string, types.Object, error) {
+	qualifier, ident := splitQualifiedName(name)
 
-		// Add types and functions from the package
-		scope := pkg.Scope()
-		for _, name := range scope.Names() {
-			obj := scope.Lookup(name)
-			if obj == nil {
-				continue
-			}
+	var matches []string
+	var objects []types.Object
+	for _, sym := range a.index.lookup(qualifier, ident) {
+		matches = append(matches, sym.importPath)
+		objects = append(objects, sym.obj)
+	}
 
-			switch obj := obj.(type) {
-			case *types.TypeName:
-				docPkg.Types = append(docPkg.Types, &doc.Type{
-					Name: obj.Name(),
-				})
-			case *types.Func:
-				docPkg.Funcs = append(docPkg.Funcs, &doc.Func{
-					Name: obj.Name(),
-				})
-			}
+	if len(matches) == 0 && a.deps != nil && qualifier != "" {
+		matches, objects = a.resolveDependency(qualifier, ident)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", nil, fmt.Errorf("type %s not found", name)
+	case 1:
+		return matches[0], objects[0], nil
+	}
+
+	// Exact import path qualifiers always win over name or suffix matches
+	for i, importPath := range matches {
+		if importPath == qualifier {
+			return importPath, objects[i], nil
 		}
+	}
 
-		a.docPkgs[pkgName] = docPkg
+	candidates := make([]string, len(matches))
+	for i, importPath := range matches {
+		candidates[i] = importPath + "." + ident
 	}
-	return nil
+	return "", nil, &AmbiguousError{Name: name, Candidates: candidates}
 }
 
-// LookupType finds and returns comprehensive information about a specific type
-func (a *Analyzer) LookupType(typeName string) (*TypeInfo, error) {
-	a.mu.RLock()
+// sortedPackagePaths returns the import paths of all type-checked packages in sorted order
+func (a *Analyzer) sortedPackagePaths() []string {
+	paths := make([]string, 0, len(a.pkgs))
+	for importPath := range a.pkgs {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// LookupType finds and returns comprehensive information about a specific
+// type. The name may be qualified with a package name or import path, e.g.
+// "analyzer.Config" or "github.com/x/y/pkg.Type".
+func (a *Analyzer) LookupType(ctx context.Context, typeName string) (*TypeInfo, error) {
+	if err := a.rlockNames(ctx, typeName); err != nil {
+		return nil, err
+	}
 	defer a.mu.RUnlock()
 
 	if !a.initialized {
+		if a.snapshot != nil {
+			return a.snapshot.lookupType(typeName)
+		}
 		return nil, fmt.Errorf("analyzer not initialized")
 	}
 
-	for pkgName, pkg := range a.pkgs {
-		obj := pkg.Scope().Lookup(typeName)
-		if obj == nil {
-			continue
-		}
+	importPath, obj, err := a.resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	return a.typeInfoFor(importPath, obj), nil
+}
 
-		typeInfo := &TypeInfo{
-			Name:       typeName,
-			Package:    pkgName,
-			ImportPath: pkg.Path(),
-			Exported:   obj.Exported(),
-		}
+// typeInfoFor builds the TypeInfo for an object declared in the package at importPath
+func (a *Analyzer) typeInfoFor(importPath string, obj types.Object) *TypeInfo {
+	pkg := obj.Pkg()
+	typeName := obj.Name()
+
+	typeInfo := &TypeInfo{
+		Name:       typeName,
+		Package:    pkg.Name(),
+		ImportPath: importPath,
+		Exported:   obj.Exported(),
+		Tags:       a.tags[importPath].get(typeName),
+	}
 
-		// Get position information
-		if pos := a.fset.Position(obj.Pos()); pos.IsValid() {
-			typeInfo.Position = Position{
-				Filename: pos.Filename,
-				Line:     pos.Line,
-				Column:   pos.Column,
-			}
+	// Get position information
+	if pos := a.fset.Position(obj.Pos()); pos.IsValid() {
+		typeInfo.Position = Position{
+			Filename:  pos.Filename,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			Generated: a.generated[pos.Filename],
 		}
+	}
 
-		// Get documentation
-		if docPkg := a.docPkgs[pkgName]; docPkg != nil {
-			for _, docType := range docPkg.Types {
-				if docType.Name == typeName {
-					typeInfo.Doc = docType.Doc
-					break
-				}
+	// Get documentation
+	if docPkg := a.docPackage(importPath); docPkg != nil {
+		for _, docType := range docPkg.Types {
+			if docType.Name == typeName {
+				typeInfo.Doc = docType.Doc
+				break
 			}
 		}
+	}
+	typeInfo.DeprecationNote, typeInfo.Deprecated = deprecation(typeInfo.Doc)
+
+	// Analyze the type
+	typeInfo.Kind = kindOf(obj.Type())
+	switch t := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		typeInfo.Fields = a.analyzeStructFields(t, obj.Type())
+	case *types.Interface:
+		typeInfo.Methods = a.analyzeInterfaceMethods(t)
+	}
 
-		// Analyze the type
-		switch t := obj.Type().Underlying().(type) {
-		case *types.Struct:
-			typeInfo.Kind = "struct"
-			typeInfo.Fields = a.analyzeStructFields(t, obj.Type())
-		case *types.Interface:
-			typeInfo.Kind = "interface"
-			typeInfo.Methods = a.analyzeInterfaceMethods(t)
-		case *types.Slice:
-			typeInfo.Kind = "slice"
-		case *types.Array:
-			typeInfo.Kind = "array"
-		case *types.Map:
-			typeInfo.Kind = "map"
-		case *types.Chan:
-			typeInfo.Kind = "channel"
-		case *types.Pointer:
-			typeInfo.Kind = "pointer"
-		case *types.Signature:
-			typeInfo.Kind = "function"
-		default:
-			typeInfo.Kind = "other"
-		}
-
-		// Get methods
-		typeInfo.Methods = a.getTypeMethods(obj.Type())
+	// Get methods
+	typeInfo.Methods = a.getTypeMethods(obj.Type())
 
-		// Get size and alignment information
-		if sizes := types.SizesFor("gc", "amd64"); sizes != nil {
-			typeInfo.Size = sizes.Sizeof(obj.Type())
-			typeInfo.Alignment = sizes.Alignof(obj.Type())
-		}
+	typeInfo.Unresolved = containsInvalid(obj.Type().Underlying())
+	for _, method := range typeInfo.Methods {
+		typeInfo.Unresolved = typeInfo.Unresolved || method.Unresolved
+	}
 
-		return typeInfo, nil
+	// Get size and alignment information, which is meaningless for
+	// unresolved types
+	if sizes := types.SizesFor("gc", "amd64"); sizes != nil && !typeInfo.Unresolved {
+		typeInfo.Size = sizes.Sizeof(obj.Type())
+		typeInfo.Alignment = sizes.Alignof(obj.Type())
 	}
 
-	return nil, fmt.Errorf("type %s not found", typeName)
+	return typeInfo
+}
+
+// kindOf describes the kind of a type's underlying type
+func kindOf(t types.Type) string {
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Map:
+		return "map"
+	case *types.Chan:
+		return "channel"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Signature:
+		return "function"
+	case *types.Basic:
+		return "basic"
+	default:
+		return "other"
+	}
 }
 
 // analyzeStructFields analyzes struct fields
@@ -561,18 +1093,19 @@ func (a *Analyzer) analyzeStructFields(structType *types.Struct, namedType types
 
 		fieldInfo := FieldInfo{
 			Name:     field.Name(),
-			Type:     field.Type().String(),
 			Tag:      tag,
 			Exported: field.Exported(),
 			Embedded: field.Embedded(),
 		}
+		fieldInfo.Type, fieldInfo.Unresolved = a.typeString(field)
 
 		// Get position if available
 		if pos := a.fset.Position(field.Pos()); pos.IsValid() {
 			fieldInfo.Position = Position{
-				Filename: pos.Filename,
-				Line:     pos.Line,
-				Column:   pos.Column,
+				Filename:  pos.Filename,
+				Line:      pos.Line,
+				Column:    pos.Column,
+				Generated: a.generated[pos.Filename],
 			}
 		}
 
@@ -591,10 +1124,12 @@ func (a *Analyzer) analyzeInterfaceMethods(interfaceType *types.Interface) []Met
 		sig := method.Type().(*types.Signature)
 
 		methodInfo := MethodInfo{
-			Name:      method.Name(),
-			Signature: sig.String(),
-			Exported:  method.Exported(),
+			Name:     method.Name(),
+			Exported: method.Exported(),
+			Doc:      a.funcDoc(method),
 		}
+		methodInfo.Signature, methodInfo.Unresolved = a.typeString(method)
+		methodInfo.DeprecationNote, methodInfo.Deprecated = deprecation(methodInfo.Doc)
 
 		// Get parameters and results
 		methodInfo.Parameters = a.analyzeSignatureParams(sig.Params())
@@ -603,9 +1138,10 @@ func (a *Analyzer) analyzeInterfaceMethods(interfaceType *types.Interface) []Met
 		// Get position if available
 		if pos := a.fset.Position(method.Pos()); pos.IsValid() {
 			methodInfo.Position = Position{
-				Filename: pos.Filename,
-				Line:     pos.Line,
-				Column:   pos.Column,
+				Filename:  pos.Filename,
+				Line:      pos.Line,
+				Column:    pos.Column,
+				Generated: a.generated[pos.Filename],
 			}
 		}
 
@@ -615,109 +1151,135 @@ func (a *Analyzer) analyzeInterfaceMethods(interfaceType *types.Interface) []Met
 	return methods
 }
 
-// getTypeMethods gets all methods for a type
+// getTypeMethods gets all methods for a type, with those only the pointer
+// type has, and where promoted methods come from
 func (a *Analyzer) getTypeMethods(t types.Type) []MethodInfo {
 	var methods []MethodInfo
+	seen := make(map[string]bool)
 
-	// Get methods for the type
-	mset := types.NewMethodSet(t)
-	for i := 0; i < mset.Len(); i++ {
-		selection := mset.At(i)
-		if selection.Kind() != types.MethodVal {
-			continue
+	// Get methods for the type, then those of its pointer type
+	methodSets := []*types.MethodSet{types.NewMethodSet(t)}
+	if _, ok := t.(*types.Pointer); !ok {
+		methodSets = append(methodSets, types.NewMethodSet(types.NewPointer(t)))
+	}
+	for _, mset := range methodSets {
+		for i := 0; i < mset.Len(); i++ {
+			selection := mset.At(i)
+			if selection.Kind() != types.MethodVal || seen[selection.Obj().Name()] {
+				continue
+			}
+			seen[selection.Obj().Name()] = true
+			methods = append(methods, a.selectedMethod(t, selection))
 		}
+	}
 
-		method := selection.Obj().(*types.Func)
-		sig := method.Type().(*types.Signature)
-
-		methodInfo := MethodInfo{
-			Name:      method.Name(),
-			Signature: sig.String(),
-			Exported:  method.Exported(),
-			IsPointer: selection.Indirect(),
-		}
+	// Sort methods by name
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
 
-		// Get receiver information
-		if recv := sig.Recv(); recv != nil {
-			methodInfo.Receiver = recv.Type().String()
-		}
+	return methods
+}
 
-		// Get parameters and results
-		methodInfo.Parameters = a.analyzeSignatureParams(sig.Params())
-		methodInfo.Results = a.analyzeSignatureParams(sig.Results())
+// selectedMethod describes a method of a method set of t
+func (a *Analyzer) selectedMethod(t types.Type, selection *types.Selection) MethodInfo {
+	method := selection.Obj().(*types.Func)
+	sig := method.Type().(*types.Signature)
 
-		// Get position if available
-		if pos := a.fset.Position(method.Pos()); pos.IsValid() {
-			methodInfo.Position = Position{
-				Filename: pos.Filename,
-				Line:     pos.Line,
-				Column:   pos.Column,
-			}
+	methodInfo := MethodInfo{
+		Name:      method.Name(),
+		Exported:  method.Exported(),
+		IsPointer: selection.Indirect(),
+		Doc:       a.funcDoc(method),
+	}
+	if _, ok := selection.Recv().(*types.Pointer); ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			methodInfo.IsPointer = true
 		}
+	}
+	methodInfo.Signature, methodInfo.Unresolved = a.typeString(method)
+	methodInfo.DeprecationNote, methodInfo.Deprecated = deprecation(methodInfo.Doc)
 
-		methods = append(methods, methodInfo)
+	// Get receiver information
+	if recv := sig.Recv(); recv != nil {
+		methodInfo.Receiver = recv.Type().String()
 	}
 
-	// Also check pointer type methods
-	if _, ok := t.(*types.Pointer); !ok {
-		ptrType := types.NewPointer(t)
-		ptrMset := types.NewMethodSet(ptrType)
-		for i := 0; i < ptrMset.Len(); i++ {
-			selection := ptrMset.At(i)
-			if selection.Kind() != types.MethodVal {
-				continue
-			}
+	// Get parameters and results
+	methodInfo.Parameters = a.analyzeSignatureParams(sig.Params())
+	methodInfo.Results = a.analyzeSignatureParams(sig.Results())
 
-			method := selection.Obj().(*types.Func)
-			sig := method.Type().(*types.Signature)
+	methodInfo.Position = a.position(method.Pos())
 
-			// Skip if we already have this method
-			found := false
-			for _, existing := range methods {
-				if existing.Name == method.Name() {
-					found = true
-					break
-				}
-			}
-			if found {
-				continue
-			}
+	if iface, ok := t.Underlying().(*types.Interface); ok {
+		methodInfo.Embedding = a.interfaceEmbedding(iface, method)
+	} else {
+		methodInfo.Embedding = a.fieldEmbedding(selection)
+	}
+	methodInfo.Promoted = len(methodInfo.Embedding) > 0
+	return methodInfo
+}
 
-			methodInfo := MethodInfo{
-				Name:      method.Name(),
-				Signature: sig.String(),
-				Exported:  method.Exported(),
-				IsPointer: true,
-			}
+// fieldEmbedding returns the embedded fields a method selection goes
+// through, outermost first
+func (a *Analyzer) fieldEmbedding(selection *types.Selection) []EmbeddedField {
+	var chain []EmbeddedField
+	t := selection.Recv()
+	index := selection.Index()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := t.Underlying().(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok || i >= st.NumFields() {
+			break
+		}
+		field := st.Field(i)
+		embedded := EmbeddedField{Name: field.Name(), Type: field.Type().String(), Position: a.positionOf(field.Pos())}
+		_, embedded.Interface = field.Type().Underlying().(*types.Interface)
+		chain = append(chain, embedded)
+		t = field.Type()
+	}
+	return chain
+}
 
-			// Get receiver information
-			if recv := sig.Recv(); recv != nil {
-				methodInfo.Receiver = recv.Type().String()
+// interfaceEmbedding returns the embedded interfaces an interface has a
+// method through, outermost first, or nil when it declares the method
+func (a *Analyzer) interfaceEmbedding(iface *types.Interface, method *types.Func) []EmbeddedField {
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i) == method {
+			return nil
+		}
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embeddedType := iface.EmbeddedType(i)
+		inner, ok := embeddedType.Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		for j := 0; j < inner.NumMethods(); j++ {
+			if inner.Method(j) != method {
+				continue
 			}
-
-			// Get parameters and results
-			methodInfo.Parameters = a.analyzeSignatureParams(sig.Params())
-			methodInfo.Results = a.analyzeSignatureParams(sig.Results())
-
-			// Get position if available
-			if pos := a.fset.Position(method.Pos()); pos.IsValid() {
-				methodInfo.Position = Position{
-					Filename: pos.Filename,
-					Line:     pos.Line,
-					Column:   pos.Column,
-				}
+			embedded := EmbeddedField{Type: embeddedType.String(), Interface: true}
+			if named, ok := embeddedType.(*types.Named); ok {
+				embedded.Name = named.Obj().Name()
+				embedded.Position = a.positionOf(named.Obj().Pos())
 			}
-
-			methods = append(methods, methodInfo)
+			return append([]EmbeddedField{embedded}, a.interfaceEmbedding(inner, method)...)
 		}
 	}
+	return nil
+}
 
-	// Sort methods by name
-	sort.Slice(methods, func(i, j int) bool {
-		return methods[i].Name < methods[j].Name
-	})
-
-	return methods
+// positionOf converts a token position into a Position, or nil when it has
+// none
+func (a *Analyzer) positionOf(pos token.Pos) *Position {
+	if !pos.IsValid() {
+		return nil
+	}
+	p := a.position(pos)
+	return &p
 }
 
 // analyzeSignatureParams analyzes function signature parameters
@@ -726,10 +1288,8 @@ func (a *Analyzer) analyzeSignatureParams(tuple *types.Tuple) []ParamInfo {
 
 	for i := 0; i < tuple.Len(); i++ {
 		param := tuple.At(i)
-		paramInfo := ParamInfo{
-			Name: param.Name(),
-			Type: param.Type().String(),
-		}
+		paramInfo := ParamInfo{Name: param.Name()}
+		paramInfo.Type, paramInfo.Unresolved = a.typeString(param)
 		params = append(params, paramInfo)
 	}
 
@@ -737,8 +1297,8 @@ func (a *Analyzer) analyzeSignatureParams(tuple *types.Tuple) []ParamInfo {
 }
 
 // ListMethods returns all methods for a given type with comprehensive information
-func (a *Analyzer) ListMethods(typeName string) ([]MethodInfo, error) {
-	typeInfo, err := a.LookupType(typeName)
+func (a *Analyzer) ListMethods(ctx context.Context, typeName string) ([]MethodInfo, error) {
+	typeInfo, err := a.LookupType(ctx, typeName)
 	if err != nil {
 		return nil, err
 	}
@@ -746,9 +1306,13 @@ func (a *Analyzer) ListMethods(typeName string) ([]MethodInfo, error) {
 	return typeInfo.Methods, nil
 }
 
-// GetExample returns examples for a given type or topic
-func (a *Analyzer) GetExample(topic string) (string, error) {
-	a.mu.RLock()
+// GetExample returns examples for a given type or topic: the godoc Example
+// functions matching it or, when there are none, usage examples mined from
+// the repository (see UsageExamples)
+func (a *Analyzer) GetExample(ctx context.Context, topic string) (string, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return "", err
+	}
 	defer a.mu.RUnlock()
 
 	var examples []string
@@ -790,6 +1354,18 @@ func (a *Analyzer) GetExample(topic string) (string, error) {
 		}
 	}
 
+	// Most repositories have no Example functions, so fall back to
+	// examples mined from real uses of the symbol
+	if len(examples) == 0 && a.initialized {
+		usages, err := a.usageExamples(ctx, topic, maxUsageExamples)
+		if err != nil {
+			return "", err
+		}
+		for _, usage := range usages {
+			examples = append(examples, a.formatUsageExample(usage))
+		}
+	}
+
 	if len(examples) == 0 {
 		return "", fmt.Errorf("no examples found for topic: %s", topic)
 	}
@@ -799,10 +1375,16 @@ func (a *Analyzer) GetExample(topic string) (string, error) {
 
 // AnalyzeRepository performs a comprehensive analysis of the entire repository
 func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, error) {
-	a.mu.RLock()
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
 	defer a.mu.RUnlock()
 
 	if !a.initialized {
+		if a.snapshot != nil {
+			result := *a.snapshot.Result
+			return &result, nil
+		}
 		return nil, fmt.Errorf("analyzer not initialized")
 	}
 
@@ -812,7 +1394,12 @@ func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, erro
 	}
 
 	// Analyze types
-	for pkgName, pkg := range a.pkgs {
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkg := a.pkgs[importPath]
+		pkgName := pkg.Name()
 		scope := pkg.Scope()
 		for _, name := range scope.Names() {
 			obj := scope.Lookup(name)
@@ -822,9 +1409,7 @@ func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, erro
 
 			switch obj := obj.(type) {
 			case *types.TypeName:
-				if typeInfo, err := a.LookupType(obj.Name()); err == nil {
-					result.Types = append(result.Types, *typeInfo)
-				}
+				result.Types = append(result.Types, *a.typeInfoFor(importPath, obj))
 			case *types.Func:
 				funcInfo := a.analyzeFunctionObject(obj, pkgName)
 				result.Functions = append(result.Functions, funcInfo)
@@ -838,23 +1423,10 @@ func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, erro
 		}
 	}
 
-	// Analyze packages
-	for pkgName, pkg := range a.pkgs {
-		pkgInfo := PackageInfo{
-			Name:       pkgName,
-			ImportPath: pkg.Path(),
-			IsMain:     pkgName == "main",
-		}
-
-		// Get documentation
-		if docPkg := a.docPkgs[pkgName]; docPkg != nil {
-			pkgInfo.Doc = docPkg.Doc
-		}
-
-		// Get files
-		pkgInfo.Files = a.files[pkgName]
-
-		result.Packages = append(result.Packages, pkgInfo)
+	// Analyze packages, reporting what failed to type check
+	for _, importPath := range a.sortedPackagePaths() {
+		result.Packages = append(result.Packages, *a.packageInfoFor(importPath))
+		result.Errors = append(result.Errors, a.typeErrors[importPath]...)
 	}
 
 	// Calculate metrics
@@ -862,6 +1434,7 @@ func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, erro
 		TotalTypes:     len(result.Types),
 		TotalFunctions: len(result.Functions),
 		TotalPackages:  len(result.Packages),
+		Packages:       a.packageMetrics(false),
 		AnalysisTime:   time.Since(start),
 	}
 
@@ -869,6 +1442,58 @@ func (a *Analyzer) AnalyzeRepository(ctx context.Context) (*AnalysisResult, erro
 	return result, nil
 }
 
+// funcDoc returns the documentation of a function or concrete method
+func (a *Analyzer) funcDoc(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return ""
+	}
+	docPkg := a.docPackage(fn.Pkg().Path())
+	if docPkg == nil {
+		return ""
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv != nil && types.IsInterface(recv.Type()) {
+		// Interface methods are documented by their field comments
+		return a.declarationDoc(fn)
+	}
+	if recv == nil {
+		for _, docFunc := range docPkg.Funcs {
+			if docFunc.Name == fn.Name() {
+				return docFunc.Doc
+			}
+		}
+	}
+	recvName := ""
+	if recv != nil {
+		recvType := recv.Type()
+		if ptr, ok := recvType.(*types.Pointer); ok {
+			recvType = ptr.Elem()
+		}
+		named, ok := recvType.(*types.Named)
+		if !ok {
+			return ""
+		}
+		recvName = named.Obj().Name()
+	}
+	for _, docType := range docPkg.Types {
+		// go/doc groups constructors with the type they return
+		funcs := docType.Funcs
+		if recv != nil {
+			if docType.Name != recvName {
+				continue
+			}
+			funcs = docType.Methods
+		}
+		for _, docFunc := range funcs {
+			if docFunc.Name == fn.Name() {
+				return docFunc.Doc
+			}
+		}
+	}
+	return ""
+}
+
 // analyzeFunctionObject analyzes a function object
 func (a *Analyzer) analyzeFunctionObject(fn *types.Func, pkgName string) FunctionInfo {
 	sig := fn.Type().(*types.Signature)
@@ -878,10 +1503,12 @@ func (a *Analyzer) analyzeFunctionObject(fn *types.Func, pkgName string) Functio
 		Package:  pkgName,
 		Exported: fn.Exported(),
 		IsMethod: sig.Recv() != nil,
+		Doc:      a.funcDoc(fn),
 	}
+	funcInfo.DeprecationNote, funcInfo.Deprecated = deprecation(funcInfo.Doc)
 
 	// Get signature
-	funcInfo.Signature = sig.String()
+	funcInfo.Signature, funcInfo.Unresolved = a.typeString(fn)
 
 	// Get parameters and results
 	funcInfo.Parameters = a.analyzeSignatureParams(sig.Params())
@@ -890,9 +1517,10 @@ func (a *Analyzer) analyzeFunctionObject(fn *types.Func, pkgName string) Functio
 	// Get position
 	if pos := a.fset.Position(fn.Pos()); pos.IsValid() {
 		funcInfo.Position = Position{
-			Filename: pos.Filename,
-			Line:     pos.Line,
-			Column:   pos.Column,
+			Filename:  pos.Filename,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			Generated: a.generated[pos.Filename],
 		}
 	}
 
@@ -903,17 +1531,18 @@ func (a *Analyzer) analyzeFunctionObject(fn *types.Func, pkgName string) Functio
 func (a *Analyzer) analyzeVariableObject(v *types.Var, pkgName string) VariableInfo {
 	varInfo := VariableInfo{
 		Name:     v.Name(),
-		Type:     v.Type().String(),
 		Package:  pkgName,
 		Exported: v.Exported(),
 	}
+	varInfo.Type, varInfo.Unresolved = a.typeString(v)
 
 	// Get position
 	if pos := a.fset.Position(v.Pos()); pos.IsValid() {
 		varInfo.Position = Position{
-			Filename: pos.Filename,
-			Line:     pos.Line,
-			Column:   pos.Column,
+			Filename:  pos.Filename,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			Generated: a.generated[pos.Filename],
 		}
 	}
 
@@ -933,88 +1562,286 @@ func (a *Analyzer) analyzeConstantObject(c *types.Const, pkgName string) Constan
 	// Get position
 	if pos := a.fset.Position(c.Pos()); pos.IsValid() {
 		constInfo.Position = Position{
-			Filename: pos.Filename,
-			Line:     pos.Line,
-			Column:   pos.Column,
+			Filename:  pos.Filename,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			Generated: a.generated[pos.Filename],
 		}
 	}
 
 	return constInfo
 }
 
-// SearchTypes searches for types matching a query
-func (a *Analyzer) SearchTypes(query string) ([]TypeInfo, error) {
-	a.mu.RLock()
+// SearchTypes searches for types matching a query, best matches first. The
+// query may be qualified with a package name or import path (e.g.
+// "analyzer.Conf") to restrict the search to matching packages.
+func (a *Analyzer) SearchTypes(ctx context.Context, query string) ([]TypeResult, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
 	defer a.mu.RUnlock()
 
-	var results []TypeInfo
-	query = strings.ToLower(query)
+	_, mainModule := a.moduleFor(a.repoPath)
+	if !a.initialized && a.snapshot != nil {
+		return a.snapshot.searchTypes(query, mainModule), nil
+	}
 
-	for _, pkg := range a.pkgs {
-		scope := pkg.Scope()
-		for _, name := range scope.Names() {
-			obj := scope.Lookup(name)
-			if obj == nil {
-				continue
-			}
+	var results []TypeResult
+	qualifier, ident := splitQualifiedName(query)
+	ident = strings.ToLower(ident)
 
-			if typeName, ok := obj.(*types.TypeName); ok {
-				// Check if name matches query
-				if strings.Contains(strings.ToLower(typeName.Name()), query) {
-					if typeInfo, err := a.LookupType(typeName.Name()); err == nil {
-						results = append(results, *typeInfo)
-					}
-				}
-			}
+	for _, sym := range a.index.types {
+		if !matchesQualifier(qualifier, sym.importPath, sym.obj.Pkg().Name()) {
+			continue
+		}
+		match, ok := matchName(ident, sym.obj.Name())
+		if !ok {
+			continue
 		}
+		inMain := false
+		if files := a.files[sym.importPath]; len(files) > 0 {
+			_, module := a.moduleFor(filepath.Dir(files[0]))
+			inMain = module == mainModule
+		}
+		results = append(results, TypeResult{
+			TypeInfo: *a.typeInfoFor(sym.importPath, sym.obj),
+			Match:    match,
+			Score:    matchScore(match, ident, sym.obj.Name(), sym.obj.Exported(), inMain),
+		})
 	}
+	SortTypeResults(results, SortRelevance)
 
 	return results, nil
 }
 
-// GetPackageInfo returns information about a specific package
-func (a *Analyzer) GetPackageInfo(packageName string) (*PackageInfo, error) {
-	a.mu.RLock()
+// GetPackageInfo returns information about a specific package, identified by
+// import path, import path suffix, or package name
+func (a *Analyzer) GetPackageInfo(ctx context.Context, packageName string) (*PackageInfo, error) {
+	if err := a.rlockPackages(ctx, packageName); err != nil {
+		return nil, err
+	}
 	defer a.mu.RUnlock()
 
-	pkg, exists := a.pkgs[packageName]
-	if !exists {
+	if !a.initialized && a.snapshot != nil {
+		return a.snapshot.packageInfo(packageName)
+	}
+
+	if _, exists := a.pkgs[packageName]; exists {
+		return a.packageInfoFor(packageName), nil
+	}
+
+	var matches []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if matchesQualifier(packageName, importPath, a.pkgs[importPath].Name()) {
+			matches = append(matches, importPath)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
 		return nil, fmt.Errorf("package %s not found", packageName)
+	case 1:
+		return a.packageInfoFor(matches[0]), nil
+	default:
+		return nil, &AmbiguousError{Name: packageName, Candidates: matches}
 	}
+}
 
+// packageInfoFor builds the PackageInfo for the package at importPath
+func (a *Analyzer) packageInfoFor(importPath string) *PackageInfo {
+	pkg := a.pkgs[importPath]
 	pkgInfo := &PackageInfo{
-		Name:       packageName,
-		ImportPath: pkg.Path(),
-		IsMain:     packageName == "main",
+		Name:       pkg.Name(),
+		ImportPath: importPath,
+		IsMain:     pkg.Name() == "main",
 	}
 
 	// Get documentation
-	if docPkg := a.docPkgs[packageName]; docPkg != nil {
+	if docPkg := a.docPkgs[importPath]; docPkg != nil {
 		pkgInfo.Doc = docPkg.Doc
 	}
 
-	// Get files
-	pkgInfo.Files = a.files[packageName]
+	// Get files, copied since callers such as Snapshot rewrite them in place
+	pkgInfo.Files = append([]string(nil), a.files[importPath]...)
+	if len(pkgInfo.Files) > 0 {
+		_, pkgInfo.Module = a.moduleFor(filepath.Dir(pkgInfo.Files[0]))
+	}
+	pkgInfo.Generated = len(pkgInfo.Files) > 0
+	for _, filename := range pkgInfo.Files {
+		pkgInfo.Generated = pkgInfo.Generated && a.generated[filename]
+	}
+	pkgInfo.Tags = a.tags[importPath].get("")
+	pkgInfo.TypeErrors = len(a.typeErrors[importPath])
+	pkgInfo.Embeds = a.packageEmbeds(importPath)
 
-	return pkgInfo, nil
+	return pkgInfo
 }
 
-// Refresh re-analyzes the repository
-func (a *Analyzer) Refresh() error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+// Config returns a copy of the analyzer's configuration
+func (a *Analyzer) Config() Config {
+	return *a.config
+}
+
+// RepoPath returns the absolute path of the analyzed repository
+func (a *Analyzer) RepoPath() string {
+	return a.repoPath
+}
+
+// LastChange returns when the analyzed source files last changed: the newest
+// modification time seen by the first analysis, and the time of any later
+// refresh that found added, removed or modified files. Results computed
+// before it may be stale.
+func (a *Analyzer) LastChange() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastChange
+}
+
+// Status describes the state of an analyzer
+type Status struct {
+	RepoPath    string `json:"repo_path"`
+	Initialized bool   `json:"initialized"`
+	// Snapshot is set while queries are answered from a snapshot
+	Snapshot bool `json:"snapshot,omitempty"`
+	// Packages counts the known packages and PackagesLoaded those type
+	// checked, which differ only with lazy loading
+	Packages       int `json:"packages"`
+	PackagesLoaded int `json:"packages_loaded"`
+	Files          int `json:"files"`
+	GeneratedFiles int `json:"generated_files"`
+	// LastAnalysis is when the current analysis completed, and
+	// AnalysisSeconds how long it took
+	LastAnalysis    time.Time  `json:"last_analysis,omitempty"`
+	AnalysisSeconds float64    `json:"analysis_seconds,omitempty"`
+	LastChange      time.Time  `json:"last_change,omitempty"`
+	Lazy            *LazyStats `json:"lazy,omitempty"`
+}
 
+// Status reports whether the analyzer is initialized, how much of the
+// repository it covers and when it last analyzed it
+func (a *Analyzer) Status() Status {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	status := Status{
+		RepoPath:       a.repoPath,
+		Initialized:    a.initialized,
+		Snapshot:       !a.initialized && a.snapshot != nil,
+		Packages:       len(a.files),
+		PackagesLoaded: len(a.pkgs),
+		LastChange:     a.lastChange,
+		Lazy:           a.lazyStats(),
+	}
+	for _, filenames := range a.files {
+		status.Files += len(filenames)
+		for _, filename := range filenames {
+			if a.generated[filename] {
+				status.GeneratedFiles++
+			}
+		}
+	}
+	if status.Snapshot {
+		status.Packages = len(a.snapshot.packagePaths())
+	}
+	if !a.analyzed.IsZero() {
+		status.LastAnalysis = a.analyzed
+		status.AnalysisSeconds = a.analysis.Seconds()
+	}
+	return status
+}
+
+// Packages returns the import paths of the analyzed packages in sorted
+// order. With lazy loading these include the packages not loaded yet.
+func (a *Analyzer) Packages() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if !a.initialized && a.snapshot != nil {
+		return a.snapshot.packagePaths()
+	}
+	if a.lazy != nil {
+		paths := a.allPackages()
+		sort.Strings(paths)
+		return paths
+	}
+	return a.sortedPackagePaths()
+}
+
+// Refresh re-analyzes the repository. The current results are served until
+// the new analysis completes, and kept if ctx is done first.
+func (a *Analyzer) Refresh(ctx context.Context) error {
 	a.logInfo("Refreshing repository analysis")
 
-	// Clear existing data
-	a.pkgs = make(map[string]*types.Package)
-	a.docPkgs = make(map[string]*doc.Package)
-	a.fset = token.NewFileSet()
-	a.initialized = false
-	a.files = make(map[string][]string)
+	fresh, err := newAnalyzer(a.repoPath, a.config)
+	if err != nil {
+		return err
+	}
+	fresh.overlay = a.overlay
+	a.mu.RLock()
+	fresh.sources = a.sources
+	fresh.lastChange = a.lastChange
+	a.mu.RUnlock()
+	if err := fresh.initialize(ctx); err != nil {
+		return err
+	}
 
-	// Re-initialize
-	return a.initialize()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.adopt(fresh)
+	return nil
+}
+
+// adopt replaces the analysis with that of fresh. The caller must hold a.mu.
+func (a *Analyzer) adopt(fresh *Analyzer) {
+	a.fset = fresh.fset
+	a.pkgs = fresh.pkgs
+	a.docPkgs = fresh.docPkgs
+	a.infos = fresh.infos
+	a.asts = fresh.asts
+	a.files = fresh.files
+	a.modules = fresh.modules
+	a.deps = fresh.deps
+	a.sources = fresh.sources
+	a.lastChange = fresh.lastChange
+	a.analyzed = fresh.analyzed
+	a.analysis = fresh.analysis
+	a.index = fresh.index
+	a.tags = fresh.tags
+	a.lazy = fresh.lazy
+	a.workspace = fresh.workspace
+	a.generated = fresh.generated
+	a.ignore = fresh.ignore
+	a.parseErrors = fresh.parseErrors
+	a.versionErrors = fresh.versionErrors
+	a.typeErrors = fresh.typeErrors
+	a.initialized = true
+	a.snapshot = nil
+}
+
+// rlock acquires the read lock, giving up when ctx is done first, e.g.
+// while a refresh holds the write lock
+func (a *Analyzer) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.mu.TryRLock() {
+		return nil
+	}
+	acquired := make(chan struct{})
+	go func() {
+		a.mu.RLock()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		// Release the lock once the goroutine gets it
+		go func() {
+			<-acquired
+			a.mu.RUnlock()
+		}()
+		return ctx.Err()
+	}
 }
 
 // Close cleans up resources
@@ -1024,18 +1851,19 @@ func (a *Analyzer) Close() error {
 
 	a.logInfo("Closing analyzer")
 	a.initialized = false
+	a.snapshot = nil
 	return nil
 }
 
 // Logging methods
-func (a *Analyzer) logWarn(format string, args ...interface{}) {
+func (a *Analyzer) logWarn(msg string, args ...any) {
 	if a.config.LogLevel >= LogLevelWarn {
-		a.logger.Printf("[WARN] "+format, args...)
+		a.logger.Warn(msg, args...)
 	}
 }
 
-func (a *Analyzer) logInfo(format string, args ...interface{}) {
+func (a *Analyzer) logInfo(msg string, args ...any) {
 	if a.config.LogLevel >= LogLevelInfo {
-		a.logger.Printf("[INFO] "+format, args...)
+		a.logger.Info(msg, args...)
 	}
 }
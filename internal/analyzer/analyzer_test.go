@@ -1,9 +1,16 @@
 package analyzer
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAnalyzer(t *testing.T) {
@@ -45,6 +52,11 @@ func (t *TestStruct) Method1() string {
 func (t *TestStruct) Method2() int {
 	return t.Field2
 }
+
+// NewTestStruct returns a TestStruct with both fields set
+func NewTestStruct(name string, n int) *TestStruct {
+	return &TestStruct{Field1: name, Field2: n}
+}
 `
 	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
 		t.Fatalf("Failed to write test file: %v", err)
@@ -58,7 +70,7 @@ func (t *TestStruct) Method2() int {
 
 	// Test LookupType
 	t.Run("LookupType", func(t *testing.T) {
-		info, err := analyzer.LookupType("TestStruct")
+		info, err := analyzer.LookupType(context.Background(), "TestStruct")
 		if err != nil {
 			t.Fatalf("LookupType failed: %v", err)
 		}
@@ -75,7 +87,7 @@ func (t *TestStruct) Method2() int {
 
 	// Test ListMethods
 	t.Run("ListMethods", func(t *testing.T) {
-		methods, err := analyzer.ListMethods("TestStruct")
+		methods, err := analyzer.ListMethods(context.Background(), "TestStruct")
 		if err != nil {
 			t.Fatalf("ListMethods failed: %v", err)
 		}
@@ -90,12 +102,36 @@ func (t *TestStruct) Method2() int {
 			if !expectedMethods[method.Name] {
 				t.Errorf("Unexpected method: %s", method.Name)
 			}
+			if want := method.Name + " implements TestInterface\n"; method.Doc != want {
+				t.Errorf("Expected doc %q for %s, got %q", want, method.Name, method.Doc)
+			}
+		}
+	})
+
+	// Test function documentation, including constructors go/doc groups
+	// with their type
+	t.Run("FunctionDocs", func(t *testing.T) {
+		result, err := analyzer.AnalyzeRepository(context.Background())
+		if err != nil {
+			t.Fatalf("AnalyzeRepository failed: %v", err)
+		}
+		found := false
+		for _, fn := range result.Functions {
+			if fn.Name == "NewTestStruct" {
+				found = true
+				if fn.Doc != "NewTestStruct returns a TestStruct with both fields set\n" {
+					t.Errorf("Unexpected NewTestStruct doc: %q", fn.Doc)
+				}
+			}
+		}
+		if !found {
+			t.Error("Expected NewTestStruct in the analysis")
 		}
 	})
 
 	// Test GetExample
 	t.Run("GetExample", func(t *testing.T) {
-		example, err := analyzer.GetExample("TestStruct")
+		example, err := analyzer.GetExample(context.Background(), "TestStruct")
 		if err != nil {
 			t.Fatalf("GetExample failed: %v", err)
 		}
@@ -104,3 +140,391 @@ func (t *TestStruct) Method2() int {
 		}
 	})
 }
+
+func TestQualifiedLookup(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "analyzer-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"go.mod": "module example.com/multi\n\ngo 1.21\n",
+		"alpha/alpha.go": `package alpha
+
+// Config configures alpha
+type Config struct {
+	Name string
+}
+
+// Validate checks the config
+func (c Config) Validate() error { return nil }
+`,
+		"beta/beta.go": `package beta
+
+import "example.com/multi/alpha"
+
+// Config configures beta and embeds alpha's config
+type Config struct {
+	alpha.Config
+	Retries int
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	t.Run("AmbiguousBareName", func(t *testing.T) {
+		_, err := analyzer.LookupType(context.Background(), "Config")
+		var ambiguous *AmbiguousError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("Expected AmbiguousError, got %v", err)
+		}
+		expected := []string{"example.com/multi/alpha.Config", "example.com/multi/beta.Config"}
+		if strings.Join(ambiguous.Candidates, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected candidates %v, got %v", expected, ambiguous.Candidates)
+		}
+	})
+
+	t.Run("PackageQualified", func(t *testing.T) {
+		info, err := analyzer.LookupType(context.Background(), "alpha.Config")
+		if err != nil {
+			t.Fatalf("LookupType failed: %v", err)
+		}
+		if info.ImportPath != "example.com/multi/alpha" || info.Package != "alpha" {
+			t.Errorf("Unexpected package: %s (%s)", info.ImportPath, info.Package)
+		}
+	})
+
+	t.Run("ImportPathQualified", func(t *testing.T) {
+		info, err := analyzer.LookupType(context.Background(), "example.com/multi/beta.Config")
+		if err != nil {
+			t.Fatalf("LookupType failed: %v", err)
+		}
+		if len(info.Fields) != 2 || !info.Fields[0].Embedded {
+			t.Errorf("Expected embedded alpha.Config field, got %+v", info.Fields)
+		}
+	})
+
+	t.Run("ListMethodsQualified", func(t *testing.T) {
+		methods, err := analyzer.ListMethods(context.Background(), "beta.Config")
+		if err != nil {
+			t.Fatalf("ListMethods failed: %v", err)
+		}
+		if len(methods) != 1 || methods[0].Name != "Validate" {
+			t.Errorf("Expected promoted Validate method, got %+v", methods)
+		}
+	})
+
+	t.Run("SearchTypesQualified", func(t *testing.T) {
+		results, err := analyzer.SearchTypes(context.Background(), "beta.conf")
+		if err != nil {
+			t.Fatalf("SearchTypes failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ImportPath != "example.com/multi/beta" {
+			t.Errorf("Expected only beta.Config, got %+v", results)
+		}
+
+		results, err = analyzer.SearchTypes(context.Background(), "Config")
+		if err != nil {
+			t.Fatalf("SearchTypes failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results for bare query, got %d", len(results))
+		}
+	})
+
+	t.Run("GetPackageInfo", func(t *testing.T) {
+		info, err := analyzer.GetPackageInfo(context.Background(), "alpha")
+		if err != nil {
+			t.Fatalf("GetPackageInfo failed: %v", err)
+		}
+		if info.ImportPath != "example.com/multi/alpha" || len(info.Files) != 1 {
+			t.Errorf("Unexpected package info: %+v", info)
+		}
+	})
+}
+
+func TestPromotedMethods(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"shop.go": `package shop
+
+import "io"
+
+// Logger logs
+type Logger struct{}
+
+// Log logs a message
+func (l *Logger) Log(msg string) {}
+
+// Base is embedded by services
+type Base struct {
+	*Logger
+	io.Closer
+}
+
+// Name names the base
+func (b Base) Name() string { return "base" }
+
+// Service embeds Base
+type Service struct {
+	Base
+}
+
+// Start starts the service
+func (s *Service) Start() {}
+
+// ReadCloser adds nothing to io.ReadCloser
+type ReadCloser interface {
+	io.ReadCloser
+	Reset()
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	methods, err := a.ListMethods(context.Background(), "Service")
+	if err != nil {
+		t.Fatalf("Failed to list methods: %v", err)
+	}
+	byName := make(map[string]MethodInfo)
+	for _, m := range methods {
+		byName[m.Name] = m
+	}
+	if len(methods) != 4 {
+		t.Fatalf("Expected Close, Log, Name and Start, got %+v", methods)
+	}
+	if start := byName["Start"]; start.Promoted || len(start.Embedding) != 0 {
+		t.Errorf("Expected Start to be declared by Service, got %+v", start)
+	}
+	if name := byName["Name"]; !name.Promoted || len(name.Embedding) != 1 || name.Embedding[0].Name != "Base" || name.Embedding[0].Position == nil {
+		t.Errorf("Expected Name promoted through Base, got %+v", name)
+	}
+	logMethod := byName["Log"]
+	if !logMethod.Promoted || len(logMethod.Embedding) != 2 || logMethod.Embedding[1].Name != "Logger" || logMethod.Embedding[1].Type != "*example.com/shop.Logger" ||
+		logMethod.Receiver != "*example.com/shop.Logger" || !strings.HasSuffix(logMethod.Position.Filename, "shop.go") {
+		t.Errorf("Expected Log promoted through Base and *Logger, got %+v", logMethod)
+	}
+	if closeMethod := byName["Close"]; len(closeMethod.Embedding) != 2 || !closeMethod.Embedding[1].Interface || closeMethod.Embedding[1].Type != "io.Closer" {
+		t.Errorf("Expected Close promoted from the io.Closer interface, got %+v", closeMethod)
+	}
+
+	methods, err = a.ListMethods(context.Background(), "ReadCloser")
+	if err != nil {
+		t.Fatalf("Failed to list methods: %v", err)
+	}
+	for _, m := range methods {
+		switch m.Name {
+		case "Reset":
+			if m.Promoted {
+				t.Errorf("Expected Reset to be declared by ReadCloser, got %+v", m)
+			}
+		case "Read":
+			if len(m.Embedding) != 2 || m.Embedding[0].Type != "io.ReadCloser" || m.Embedding[1].Type != "io.Reader" {
+				t.Errorf("Expected Read through io.ReadCloser and io.Reader, got %+v", m.Embedding)
+			}
+		}
+	}
+}
+
+func TestLastChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "a.go")
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.WriteFile(path, []byte("package a\n\ntype A struct{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	if !analyzer.LastChange().Equal(modified) {
+		t.Errorf("Expected last change %v, got %v", modified, analyzer.LastChange())
+	}
+
+	// Refreshing unchanged sources keeps the last change
+	if err := analyzer.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !analyzer.LastChange().Equal(modified) {
+		t.Errorf("Expected unchanged last change %v, got %v", modified, analyzer.LastChange())
+	}
+
+	// Adding a file with an old modification time is still a change
+	other := filepath.Join(tmpDir, "b.go")
+	if err := os.WriteFile(other, []byte("package a\n\ntype B struct{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chtimes(other, modified, modified); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+	before := time.Now()
+	if err := analyzer.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if analyzer.LastChange().Before(before) {
+		t.Errorf("Expected last change after %v, got %v", before, analyzer.LastChange())
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/shop\n\ngo 1.21\n",
+		"cart/cart.go":   "package cart\n\ntype Cart struct{}\n",
+		"cart/items.go":  "// Code generated by stringer. DO NOT EDIT.\n\npackage cart\n",
+		"store/store.go": "package store\n\ntype Store struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		before := time.Now()
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+
+		status := analyzer.Status()
+		if !status.Initialized || status.Snapshot || status.Packages != 2 || status.Files != 3 || status.GeneratedFiles != 1 {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+		if status.LastAnalysis.Before(before) || status.AnalysisSeconds <= 0 {
+			t.Errorf("Expected the analysis to be timed, got %v taking %vs", status.LastAnalysis, status.AnalysisSeconds)
+		}
+		if lazy != (status.Lazy != nil) {
+			t.Errorf("Expected lazy stats only with lazy loading, got %+v", status.Lazy)
+		}
+		if loaded := map[bool]int{false: 2, true: 0}[lazy]; status.PackagesLoaded != loaded {
+			t.Errorf("Expected %d packages loaded, got %d", loaded, status.PackagesLoaded)
+		}
+
+		// A refresh replaces the time of the analysis
+		if err := analyzer.Refresh(context.Background()); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		if refreshed := analyzer.Status(); !refreshed.LastAnalysis.After(status.LastAnalysis) {
+			t.Errorf("Expected the refresh to update the last analysis, got %v", refreshed.LastAnalysis)
+		}
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	repoDir := writeSyntheticRepo(t, 3, 3)
+	analyzer, err := NewAnalyzer(repoDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := analyzer.LookupType(cancelled, "p0.T0"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled lookup, got %v", err)
+	}
+	if err := analyzer.Refresh(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled refresh, got %v", err)
+	}
+	if _, err := analyzer.LookupType(context.Background(), "p0.T0"); err != nil {
+		t.Errorf("Expected a cancelled refresh to keep the previous analysis, got %v", err)
+	}
+
+	// A query waiting for a refresh to release the lock gives up at its deadline
+	analyzer.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = analyzer.SearchTypes(ctx, "T0")
+	analyzer.mu.Unlock()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the query to time out waiting for the lock, got %v", err)
+	}
+	if _, err := analyzer.SearchTypes(context.Background(), "T0"); err != nil {
+		t.Errorf("Expected the abandoned lock to be released, got %v", err)
+	}
+
+	config := DefaultConfig()
+	config.AnalysisTimeout = time.Nanosecond
+	if _, err := NewAnalyzerWithConfig(repoDir, config); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected analysis to stop at AnalysisTimeout, got %v", err)
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/logs\n\ngo 1.21\n",
+		"a.go":    "package logs\n\nfunc A() {}\n",
+		"go.work": "go 1.21\n\nuse ./missing\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(repoDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	var warned bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected JSON log records, got %q", line)
+		}
+		if record["component"] != "analyzer" {
+			t.Errorf("Expected the analyzer component on %v", record)
+		}
+		if record["level"] == "WARN" && record["dir"] == filepath.Join(repoDir, "missing") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expected a structured warning about the missing workspace module, got %s", buf.String())
+	}
+}
@@ -0,0 +1,377 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Clustering strategies for Architecture
+const (
+	ClusterByDirectory = "directory"
+	ClusterByCohesion  = "cohesion"
+	ClusterByConfig    = "config"
+)
+
+// ArchitectureOptions controls how packages are grouped into components
+type ArchitectureOptions struct {
+	// Strategy is ClusterByDirectory (the default), ClusterByCohesion or
+	// ClusterByConfig
+	Strategy string
+	// Depth is the number of leading directory elements that name a
+	// component in the directory strategy (default 2, e.g. internal/analyzer)
+	Depth int
+	// Threshold is the import density two sibling components need to be
+	// merged by the cohesion strategy (default 0.5)
+	Threshold float64
+	// Components maps component names to package patterns for the config
+	// strategy. Patterns are directories relative to the repository or
+	// import paths, and may end in /... to include subdirectories. Packages
+	// matching no pattern fall back to the directory strategy.
+	Components map[string][]string
+}
+
+// Component is a group of packages forming one architectural unit
+type Component struct {
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+	// Responsibilities are the synopses of the component's package docs
+	Responsibilities []string `json:"responsibilities,omitempty"`
+}
+
+// ComponentEdge is a dependency between components, weighted by the number
+// of import declarations from one to the other
+type ComponentEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Weight int    `json:"weight"`
+}
+
+// Architecture is a component-level view of the repository
+type Architecture struct {
+	Strategy   string          `json:"strategy"`
+	Components []Component     `json:"components"`
+	Edges      []ComponentEdge `json:"edges"`
+}
+
+// archPackage is an analyzed package as seen by the architecture analysis
+type archPackage struct {
+	importPath string
+	dir        string // Slash-separated directory relative to the repository
+	imports    map[string]int
+}
+
+// Architecture clusters the analyzed packages into components and computes
+// the import dependencies between them
+func (a *Analyzer) Architecture(ctx context.Context, opts ArchitectureOptions) (*Architecture, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = ClusterByDirectory
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 2
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = 0.5
+	}
+
+	pkgs := a.archPackages()
+	var clusters map[string]string // Maps import path to component name
+	switch opts.Strategy {
+	case ClusterByDirectory:
+		clusters = clusterByDirectory(pkgs, opts.Depth)
+	case ClusterByCohesion:
+		clusters = clusterByCohesion(pkgs, opts.Threshold)
+	case ClusterByConfig:
+		if len(opts.Components) == 0 {
+			return nil, fmt.Errorf("the config strategy needs component definitions")
+		}
+		clusters = clusterByConfig(pkgs, opts.Components, opts.Depth)
+	default:
+		return nil, fmt.Errorf("unknown clustering strategy %q", opts.Strategy)
+	}
+
+	arch := &Architecture{Strategy: opts.Strategy, Components: []Component{}, Edges: []ComponentEdge{}}
+	byName := make(map[string]*Component)
+	var names []string
+	for _, pkg := range pkgs {
+		name := clusters[pkg.importPath]
+		component, ok := byName[name]
+		if !ok {
+			component = &Component{Name: name}
+			byName[name] = component
+			names = append(names, name)
+		}
+		component.Packages = append(component.Packages, pkg.importPath)
+		if synopsis := a.packageSynopsis(pkg.importPath); synopsis != "" {
+			component.Responsibilities = append(component.Responsibilities, synopsis)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		arch.Components = append(arch.Components, *byName[name])
+	}
+
+	weights := make(map[[2]string]int)
+	for _, pkg := range pkgs {
+		from := clusters[pkg.importPath]
+		for imported, count := range pkg.imports {
+			if to, ok := clusters[imported]; ok && to != from {
+				weights[[2]string{from, to}] += count
+			}
+		}
+	}
+	for key, weight := range weights {
+		arch.Edges = append(arch.Edges, ComponentEdge{From: key[0], To: key[1], Weight: weight})
+	}
+	sort.Slice(arch.Edges, func(i, j int) bool {
+		if arch.Edges[i].From != arch.Edges[j].From {
+			return arch.Edges[i].From < arch.Edges[j].From
+		}
+		return arch.Edges[i].To < arch.Edges[j].To
+	})
+	return arch, nil
+}
+
+// archPackages returns the analyzed non-test packages with their
+// repository-relative directories and per-package import counts
+func (a *Analyzer) archPackages() []archPackage {
+	var pkgs []archPackage
+	for _, importPath := range a.sortedImportPaths() {
+		files := a.files[importPath]
+		if strings.HasSuffix(importPath, "_test") || len(files) == 0 {
+			continue
+		}
+		dir := "."
+		if rel, err := filepath.Rel(a.repoPath, filepath.Dir(files[0])); err == nil {
+			dir = filepath.ToSlash(rel)
+		}
+
+		pkgs = append(pkgs, archPackage{importPath: importPath, dir: dir, imports: countImports(importPath, a.asts[importPath])})
+	}
+	return pkgs
+}
+
+// countImports counts how many of a package's files import each other
+// package
+func countImports(importPath string, files []*ast.File) map[string]int {
+	imports := make(map[string]int)
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err == nil && imported != importPath {
+				imports[imported]++
+			}
+		}
+	}
+	return imports
+}
+
+// packageSynopsis returns the first sentence of a package's documentation
+func (a *Analyzer) packageSynopsis(importPath string) string {
+	docPkg := a.docPkgs[importPath]
+	if docPkg == nil || docPkg.Doc == "" {
+		return ""
+	}
+	return docPkg.Synopsis(docPkg.Doc)
+}
+
+// directoryComponent names the component of a directory: its first depth
+// elements
+func directoryComponent(dir string, depth int) string {
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// clusterByDirectory groups packages by their leading directories
+func clusterByDirectory(pkgs []archPackage, depth int) map[string]string {
+	clusters := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		clusters[pkg.importPath] = directoryComponent(pkg.dir, depth)
+	}
+	return clusters
+}
+
+// clusterByCohesion starts with one component per package and repeatedly
+// merges the pair of sibling components (sharing a top-level directory) with
+// the highest import density, the number of importing package pairs between
+// them divided by the product of their sizes, while it reaches threshold.
+// Components are named after the common directory of their packages.
+func clusterByCohesion(pkgs []archPackage, threshold float64) map[string]string {
+	index := make(map[string]int, len(pkgs))
+	for i, pkg := range pkgs {
+		index[pkg.importPath] = i
+	}
+	linked := make(map[[2]int]bool)
+	for i, pkg := range pkgs {
+		for imported := range pkg.imports {
+			if j, ok := index[imported]; ok {
+				linked[[2]int{i, j}] = true
+			}
+		}
+	}
+
+	groups := make([][]int, len(pkgs))
+	for i := range pkgs {
+		groups[i] = []int{i}
+	}
+	top := func(group []int) string {
+		return directoryComponent(pkgs[group[0]].dir, 1)
+	}
+	for {
+		best, bestI, bestJ := 0.0, -1, -1
+		for i := range groups {
+			for j := i + 1; j < len(groups); j++ {
+				if top(groups[i]) != top(groups[j]) {
+					continue
+				}
+				links := 0
+				for _, p := range groups[i] {
+					for _, q := range groups[j] {
+						if linked[[2]int{p, q}] {
+							links++
+						}
+						if linked[[2]int{q, p}] {
+							links++
+						}
+					}
+				}
+				density := float64(links) / float64(len(groups[i])*len(groups[j]))
+				if density > best {
+					best, bestI, bestJ = density, i, j
+				}
+			}
+		}
+		if bestI < 0 || best < threshold {
+			break
+		}
+		groups[bestI] = append(groups[bestI], groups[bestJ]...)
+		groups = append(groups[:bestJ], groups[bestJ+1:]...)
+	}
+
+	clusters := make(map[string]string, len(pkgs))
+	used := make(map[string]int)
+	for _, group := range groups {
+		dirs := make([]string, len(group))
+		for i, p := range group {
+			dirs[i] = pkgs[p].dir
+		}
+		name := commonDir(dirs)
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, used[name])
+		}
+		for _, p := range group {
+			clusters[pkgs[p].importPath] = name
+		}
+	}
+	return clusters
+}
+
+// commonDir returns the longest directory prefix shared by dirs
+func commonDir(dirs []string) string {
+	common := strings.Split(dirs[0], "/")
+	for _, dir := range dirs[1:] {
+		parts := strings.Split(dir, "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) == 0 {
+		return "."
+	}
+	return strings.Join(common, "/")
+}
+
+// clusterByConfig assigns packages to the configured components. The first
+// component (in name order) with a matching pattern wins.
+func clusterByConfig(pkgs []archPackage, components map[string][]string, depth int) map[string]string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clusters := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		clusters[pkg.importPath] = directoryComponent(pkg.dir, depth)
+	assign:
+		for _, name := range names {
+			for _, pattern := range components[name] {
+				if matchesPackagePattern(pattern, pkg) {
+					clusters[pkg.importPath] = name
+					break assign
+				}
+			}
+		}
+	}
+	return clusters
+}
+
+// matchesPackagePattern reports whether a component pattern selects pkg
+func matchesPackagePattern(pattern string, pkg archPackage) bool {
+	pattern = strings.TrimPrefix(path.Clean(pattern), "./")
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		for _, candidate := range []string{pkg.dir, pkg.importPath} {
+			if candidate == prefix || strings.HasPrefix(candidate, prefix+"/") {
+				return true
+			}
+		}
+		return prefix == "." || prefix == ""
+	}
+	return pattern == pkg.dir || pattern == pkg.importPath
+}
+
+// Mermaid renders the architecture as a Mermaid flowchart
+func (arch *Architecture) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(arch.Components))
+	for i, component := range arch.Components {
+		id := fmt.Sprintf("c%d", i)
+		ids[component.Name] = id
+		label := component.Name
+		if len(component.Responsibilities) > 0 {
+			label += "<br/>" + strings.Join(component.Responsibilities, "<br/>")
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", id, strings.ReplaceAll(label, `"`, "#quot;"))
+	}
+	for _, edge := range arch.Edges {
+		fmt.Fprintf(&b, "    %s -->|%d| %s\n", ids[edge.From], edge.Weight, ids[edge.To])
+	}
+	return b.String()
+}
+
+// DOT renders the architecture as a Graphviz digraph
+func (arch *Architecture) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph architecture {\n    rankdir=LR;\n    node [shape=box];\n")
+	for _, component := range arch.Components {
+		label := component.Name
+		if len(component.Responsibilities) > 0 {
+			label += "\n" + strings.Join(component.Responsibilities, "\n")
+		}
+		fmt.Fprintf(&b, "    %s [label=%s];\n", strconv.Quote(component.Name), strconv.Quote(label))
+	}
+	for _, edge := range arch.Edges {
+		fmt.Fprintf(&b, "    %s -> %s [label=\"%d\", weight=%d];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), edge.Weight, edge.Weight)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArchitecture(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"cmd/app/main.go": `package main
+
+import (
+	"example.com/app/internal/store"
+	"example.com/app/internal/store/sqlstore"
+	"example.com/app/internal/web"
+)
+
+func main() { _, _, _ = store.New, sqlstore.Open, web.Serve }
+`,
+		"internal/store/store.go": `// Package store persists application records.
+package store
+
+func New() {}
+`,
+		"internal/store/sqlstore/sql.go": `// Package sqlstore implements the store on SQL databases.
+package sqlstore
+
+import "example.com/app/internal/store"
+
+var _ = store.New
+
+func Open() {}
+`,
+		"internal/web/web.go": `// Package web serves the HTTP API.
+package web
+
+import "example.com/app/internal/store"
+
+var _ = store.New
+
+func Serve() {}
+`,
+		"internal/web/handlers.go": `package web
+
+import "example.com/app/internal/store"
+
+var _ = store.New
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	componentNames := func(arch *Architecture) []string {
+		var names []string
+		for _, component := range arch.Components {
+			names = append(names, component.Name)
+		}
+		return names
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		arch, err := analyzer.Architecture(context.Background(), ArchitectureOptions{})
+		if err != nil {
+			t.Fatalf("Architecture failed: %v", err)
+		}
+		if want := []string{"cmd/app", "internal/store", "internal/web"}; !reflect.DeepEqual(componentNames(arch), want) {
+			t.Errorf("Expected components %v, got %v", want, componentNames(arch))
+		}
+		// web imports store from two files
+		found := false
+		for _, edge := range arch.Edges {
+			if edge.From == "internal/web" && edge.To == "internal/store" {
+				found = true
+				if edge.Weight != 2 {
+					t.Errorf("Expected weight 2, got %d", edge.Weight)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Expected edge from internal/web to internal/store, got %+v", arch.Edges)
+		}
+		store := arch.Components[1]
+		if len(store.Packages) != 2 || len(store.Responsibilities) != 2 || store.Responsibilities[0] != "Package store persists application records." {
+			t.Errorf("Unexpected store component: %+v", store)
+		}
+
+		mermaid := arch.Mermaid()
+		if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, "-->|2|") {
+			t.Errorf("Unexpected Mermaid output:\n%s", mermaid)
+		}
+		dot := arch.DOT()
+		if !strings.Contains(dot, `"internal/web" -> "internal/store" [label="2", weight=2];`) {
+			t.Errorf("Unexpected DOT output:\n%s", dot)
+		}
+	})
+
+	t.Run("Cohesion", func(t *testing.T) {
+		// store and sqlstore are linked one to one; web links to one of them
+		arch, err := analyzer.Architecture(context.Background(), ArchitectureOptions{Strategy: ClusterByCohesion, Threshold: 1})
+		if err != nil {
+			t.Fatalf("Architecture failed: %v", err)
+		}
+		if want := []string{"cmd/app", "internal/store", "internal/web"}; !reflect.DeepEqual(componentNames(arch), want) {
+			t.Errorf("Expected components %v, got %v", want, componentNames(arch))
+		}
+		if len(arch.Components[1].Packages) != 2 {
+			t.Errorf("Expected store and sqlstore to merge, got %v", arch.Components[1].Packages)
+		}
+
+		// A lower threshold merges web too, but never across top-level directories
+		arch, err = analyzer.Architecture(context.Background(), ArchitectureOptions{Strategy: ClusterByCohesion, Threshold: 0.5})
+		if err != nil {
+			t.Fatalf("Architecture failed: %v", err)
+		}
+		if want := []string{"cmd/app", "internal"}; !reflect.DeepEqual(componentNames(arch), want) {
+			t.Errorf("Expected components %v, got %v", want, componentNames(arch))
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		arch, err := analyzer.Architecture(context.Background(), ArchitectureOptions{
+			Strategy:   ClusterByConfig,
+			Components: map[string][]string{"Persistence": {"internal/store/..."}},
+		})
+		if err != nil {
+			t.Fatalf("Architecture failed: %v", err)
+		}
+		if want := []string{"Persistence", "cmd/app", "internal/web"}; !reflect.DeepEqual(componentNames(arch), want) {
+			t.Errorf("Expected components %v, got %v", want, componentNames(arch))
+		}
+	})
+
+	t.Run("UnknownStrategy", func(t *testing.T) {
+		if _, err := analyzer.Architecture(context.Background(), ArchitectureOptions{Strategy: "random"}); err == nil {
+			t.Error("Expected error for unknown strategy")
+		}
+	})
+}
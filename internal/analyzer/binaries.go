@@ -0,0 +1,531 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flag libraries Binaries recognizes. Cobra commands define their flags
+// with pflag.
+const (
+	FlagLibraryStd    = "flag"
+	FlagLibraryPflag  = "pflag"
+	FlagLibraryUrfave = "urfave/cli"
+)
+
+const (
+	pflagPath  = "github.com/spf13/pflag"
+	cobraPath  = "github.com/spf13/cobra"
+	urfavePath = "github.com/urfave/cli"
+)
+
+// flagTypePattern matches the types in the names of the functions and
+// methods of flag and pflag defining flags, such as String, DurationVar or
+// StringSliceVarP once the Var and P suffixes are removed
+var flagTypePattern = regexp.MustCompile(`^(Bool|Int(8|16|32|64)?|Uint(8|16|32|64)?|Float(32|64)|String|Duration|Text|IP|IPMask|IPNet|BytesHex|BytesBase64|StringTo(String|Int|Int64)|Count)(Slice|Array)?$`)
+
+// BinaryFlag is a command-line flag a binary defines
+type BinaryFlag struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	// Type is the type in the defining call or literal, such as String,
+	// Duration or StringSlice; Var for a flag.Value and Func for a function
+	Type string `json:"type"`
+	// Default is the source of the default value
+	Default string `json:"default,omitempty"`
+	Usage   string `json:"usage,omitempty"`
+	// Library is flag, pflag or urfave/cli
+	Library string `json:"library"`
+	// Env lists the environment variables a urfave/cli flag is read from
+	Env []string `json:"env,omitempty"`
+	// Package is the package defining the flag, the binary's or one it
+	// imports
+	Package  string   `json:"package"`
+	Position Position `json:"position"`
+}
+
+// BinaryEnv is an environment variable a binary reads
+type BinaryEnv struct {
+	// Name is the variable, or the source of the expression naming it
+	Name string `json:"name"`
+	// Call is os.Getenv, os.LookupEnv or urfave/cli for a flag's variable
+	Call     string   `json:"call"`
+	Package  string   `json:"package"`
+	Position Position `json:"position"`
+}
+
+// Binary is a main package of the repository
+type Binary struct {
+	// Name is the name go build gives the binary
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Position is that of func main
+	Position Position     `json:"position"`
+	Flags    []BinaryFlag `json:"flags"`
+	Env      []BinaryEnv  `json:"env"`
+	// Packages are the repository packages the binary imports, directly or
+	// through one another
+	Packages []string `json:"packages"`
+	// Dependencies are the packages from outside the repository and the
+	// standard library that the binary and its packages import
+	Dependencies []string `json:"dependencies"`
+}
+
+// SharedPackage is a repository package several binaries import
+type SharedPackage struct {
+	ImportPath string   `json:"import_path"`
+	Binaries   []string `json:"binaries"`
+}
+
+// BinaryReport describes the binaries of the repository
+type BinaryReport struct {
+	Binaries []Binary `json:"binaries"`
+	// Shared are the repository packages more than one binary imports
+	Shared []SharedPackage `json:"shared"`
+}
+
+// binaryFacts are the flags, environment variables and imports of a
+// package, test files aside
+type binaryFacts struct {
+	flags   []BinaryFlag
+	env     []BinaryEnv
+	imports []string
+}
+
+// Binaries lists the main packages a qualifier selects, or all of them,
+// with the flags and environment variables they and the repository
+// packages they import define and read, and the packages they depend on.
+// Flags are found in calls of flag and pflag, through cobra commands too,
+// and in urfave/cli flag literals, which only need type information for
+// flag sets held in variables.
+func (a *Analyzer) Binaries(ctx context.Context, pkg string) (*BinaryReport, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	facts := make(map[string]*binaryFacts)
+	factsOf := func(importPath string) *binaryFacts {
+		if f, ok := facts[importPath]; ok {
+			return f
+		}
+		f := a.binaryFacts(importPath)
+		facts[importPath] = f
+		return f
+	}
+
+	report := &BinaryReport{Binaries: []Binary{}, Shared: []SharedPackage{}}
+	importers := make(map[string][]string)
+	found := false
+	for _, importPath := range a.sortedImportPaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		files := a.asts[importPath]
+		if strings.HasSuffix(importPath, "_test") || len(files) == 0 || files[0].Name.Name != "main" || !matchesQualifier(pkg, importPath, "main") {
+			continue
+		}
+		found = true
+		binary := Binary{
+			Name:         binaryName(importPath),
+			ImportPath:   importPath,
+			Flags:        []BinaryFlag{},
+			Env:          []BinaryEnv{},
+			Packages:     []string{},
+			Dependencies: []string{},
+		}
+		if p := a.pkgs[importPath]; p != nil {
+			if main, ok := p.Scope().Lookup("main").(*types.Func); ok {
+				binary.Position = a.position(main.Pos())
+			}
+		}
+
+		// Walk the repository packages the binary imports
+		seen := map[string]bool{importPath: true}
+		dependencies := make(map[string]bool)
+		queue := []string{importPath}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			f := factsOf(current)
+			binary.Flags = append(binary.Flags, f.flags...)
+			binary.Env = append(binary.Env, f.env...)
+			for _, imported := range f.imports {
+				if _, inRepo := a.asts[imported]; !inRepo {
+					if first, _, _ := strings.Cut(imported, "/"); strings.Contains(first, ".") {
+						dependencies[imported] = true
+					}
+					continue
+				}
+				if !seen[imported] {
+					seen[imported] = true
+					queue = append(queue, imported)
+					binary.Packages = append(binary.Packages, imported)
+					importers[imported] = append(importers[imported], importPath)
+				}
+			}
+		}
+		sort.Strings(binary.Packages)
+		for dependency := range dependencies {
+			binary.Dependencies = append(binary.Dependencies, dependency)
+		}
+		sort.Strings(binary.Dependencies)
+		report.Binaries = append(report.Binaries, binary)
+	}
+	if !found && pkg != "" {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	for importPath, binaries := range importers {
+		if len(binaries) > 1 {
+			report.Shared = append(report.Shared, SharedPackage{ImportPath: importPath, Binaries: binaries})
+		}
+	}
+	sort.Slice(report.Shared, func(i, j int) bool {
+		if len(report.Shared[i].Binaries) != len(report.Shared[j].Binaries) {
+			return len(report.Shared[i].Binaries) > len(report.Shared[j].Binaries)
+		}
+		return report.Shared[i].ImportPath < report.Shared[j].ImportPath
+	})
+	return report, nil
+}
+
+// binaryName returns the name go build gives the binary of a main package
+func binaryName(importPath string) string {
+	name := path.Base(importPath)
+	if isMajorVersion(name) && strings.Contains(importPath, "/") {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
+
+// isMajorVersion reports whether a path element is a major version suffix
+// such as v2
+func isMajorVersion(element string) bool {
+	if len(element) < 2 || element[0] != 'v' {
+		return false
+	}
+	n, err := strconv.Atoi(element[1:])
+	return err == nil && n >= 2
+}
+
+// binaryFacts finds the flags a package defines, the environment variables
+// it reads and the packages it imports
+func (a *Analyzer) binaryFacts(importPath string) *binaryFacts {
+	f := &binaryFacts{}
+	info := a.infos[importPath]
+	imported := make(map[string]bool)
+	for _, file := range a.asts[importPath] {
+		if strings.HasSuffix(a.fset.Position(file.Package).Filename, "_test.go") {
+			continue
+		}
+		for _, spec := range file.Imports {
+			if p, err := strconv.Unquote(spec.Path.Value); err == nil && !imported[p] {
+				imported[p] = true
+				f.imports = append(f.imports, p)
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if call := envCall(info, file, n); call != "" && len(n.Args) == 1 {
+					f.env = append(f.env, BinaryEnv{
+						Name:     stringValue(info, n.Args[0]),
+						Call:     call,
+						Package:  importPath,
+						Position: a.position(n.Pos()),
+					})
+				} else if flag, ok := flagCall(info, file, n); ok {
+					flag.Package, flag.Position = importPath, a.position(n.Pos())
+					f.flags = append(f.flags, flag)
+				}
+			case *ast.CompositeLit:
+				if flag, ok := urfaveFlag(info, file, n); ok {
+					flag.Package, flag.Position = importPath, a.position(n.Pos())
+					f.flags = append(f.flags, flag)
+					for _, name := range flag.Env {
+						f.env = append(f.env, BinaryEnv{Name: name, Call: FlagLibraryUrfave, Package: importPath, Position: flag.Position})
+					}
+				}
+			}
+			return true
+		})
+	}
+	sort.Strings(f.imports)
+	return f
+}
+
+// envCall returns os.Getenv or os.LookupEnv for a call of either
+func envCall(info *types.Info, file *ast.File, call *ast.CallExpr) string {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || importedPath(info, file, sel.X) != "os" {
+		return ""
+	}
+	if sel.Sel.Name == "Getenv" || sel.Sel.Name == "LookupEnv" {
+		return "os." + sel.Sel.Name
+	}
+	return ""
+}
+
+// flagCall returns the flag a call of flag or pflag defines, as a function
+// of the package or a method of a flag set
+func flagCall(info *types.Info, file *ast.File, call *ast.CallExpr) (BinaryFlag, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return BinaryFlag{}, false
+	}
+	var library string
+	switch p := importedPath(info, file, sel.X); {
+	case p == "flag":
+		library = FlagLibraryStd
+	case p == pflagPath:
+		library = FlagLibraryPflag
+	case p != "":
+		return BinaryFlag{}, false
+	}
+	if library == "" && info != nil {
+		if selection, ok := info.Selections[sel]; ok {
+			recv := selection.Recv()
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			if named, ok := types.Unalias(recv).(*types.Named); ok && named.Obj().Pkg() != nil {
+				switch named.Obj().Pkg().Path() {
+				case "flag":
+					library = FlagLibraryStd
+				case pflagPath:
+					library = FlagLibraryPflag
+				}
+			}
+		}
+	}
+	// Without type information, cmd.Flags().String(...) is taken for pflag
+	// in files importing cobra
+	if inner, ok := ast.Unparen(sel.X).(*ast.CallExpr); library == "" && ok {
+		if innerSel, ok := ast.Unparen(inner.Fun).(*ast.SelectorExpr); ok && strings.HasSuffix(innerSel.Sel.Name, "Flags") && importsPath(file, cobraPath) {
+			library = FlagLibraryPflag
+		}
+	}
+	if library == "" {
+		return BinaryFlag{}, false
+	}
+
+	args, ok := flagArgs(sel.Sel.Name, len(call.Args), library == FlagLibraryPflag)
+	if !ok {
+		return BinaryFlag{}, false
+	}
+	flag := BinaryFlag{Type: args.typ, Library: library, Name: stringValue(info, call.Args[args.name])}
+	if args.shorthand >= 0 {
+		flag.Shorthand = stringValue(info, call.Args[args.shorthand])
+	}
+	if args.value >= 0 {
+		flag.Default = types.ExprString(call.Args[args.value])
+	}
+	flag.Usage = stringValue(info, call.Args[args.usage])
+	return flag, true
+}
+
+// flagArguments are the indexes of the arguments of a call defining a flag,
+// -1 for those it does not have
+type flagArguments struct {
+	typ                           string
+	name, shorthand, value, usage int
+}
+
+// flagArgs returns where the arguments of a function or method of flag or
+// pflag defining a flag are, from its name and number of arguments: Var
+// suffixes take a pointer first and pflag's P suffixes a shorthand after
+// the name
+func flagArgs(method string, n int, pflag bool) (flagArguments, bool) {
+	base, ptr, short := method, false, false
+	switch {
+	case strings.HasSuffix(base, "VarP") && pflag:
+		base, ptr, short = strings.TrimSuffix(base, "VarP"), true, true
+	case strings.HasSuffix(base, "Var"):
+		base, ptr = strings.TrimSuffix(base, "Var"), true
+	case strings.HasSuffix(base, "P") && pflag:
+		if trimmed := strings.TrimSuffix(base, "P"); trimmed == "Func" || trimmed == "BoolFunc" || flagTypePattern.MatchString(trimmed) {
+			base, short = trimmed, true
+		}
+	}
+
+	args := flagArguments{typ: base, name: -1, shorthand: -1, value: -1, usage: -1}
+	i := 0
+	next := func() int {
+		i++
+		return i - 1
+	}
+	nameAndShorthand := func() {
+		args.name = next()
+		if short {
+			args.shorthand = next()
+		}
+	}
+	switch {
+	case base == "":
+		// Var(value, name, usage) takes a flag.Value
+		args.typ = "Var"
+		next()
+		nameAndShorthand()
+		args.usage = next()
+	case (base == "Func" || base == "BoolFunc") && !ptr:
+		nameAndShorthand()
+		args.usage = next()
+		next()
+	case flagTypePattern.MatchString(base) && (base != "Text" || ptr):
+		if ptr {
+			next()
+		}
+		nameAndShorthand()
+		if base != "Count" {
+			args.value = next()
+		}
+		args.usage = next()
+	default:
+		return flagArguments{}, false
+	}
+	return args, i == n
+}
+
+// urfaveFlag returns the flag a urfave/cli flag literal, such as
+// &cli.StringFlag{Name: "port"}, defines
+func urfaveFlag(info *types.Info, file *ast.File, lit *ast.CompositeLit) (BinaryFlag, bool) {
+	sel, ok := ast.Unparen(lit.Type).(*ast.SelectorExpr)
+	if !ok || !strings.HasSuffix(sel.Sel.Name, "Flag") || !hasPathPrefix(importedPath(info, file, sel.X), urfavePath) {
+		return BinaryFlag{}, false
+	}
+	flag := BinaryFlag{Type: strings.TrimSuffix(sel.Sel.Name, "Flag"), Library: FlagLibraryUrfave}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		switch key.Name {
+		case "Name":
+			// urfave/cli v1 lists aliases after the name: "port, p"
+			names := strings.Split(stringValue(info, kv.Value), ",")
+			flag.Name = strings.TrimSpace(names[0])
+			for _, alias := range names[1:] {
+				if alias = strings.TrimSpace(alias); len(alias) == 1 && flag.Shorthand == "" {
+					flag.Shorthand = alias
+				}
+			}
+		case "Aliases":
+			for _, alias := range stringLiterals(kv.Value) {
+				if len(alias) == 1 && flag.Shorthand == "" {
+					flag.Shorthand = alias
+				}
+			}
+		case "Value":
+			flag.Default = types.ExprString(kv.Value)
+		case "Usage":
+			flag.Usage = stringValue(info, kv.Value)
+		case "EnvVar":
+			for _, name := range strings.Split(stringValue(info, kv.Value), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					flag.Env = append(flag.Env, name)
+				}
+			}
+		case "EnvVars":
+			flag.Env = append(flag.Env, stringLiterals(kv.Value)...)
+		case "Sources":
+			// urfave/cli v3 reads variables through cli.EnvVars(...)
+			ast.Inspect(kv.Value, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					if fun, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && fun.Sel.Name == "EnvVars" {
+						for _, arg := range call.Args {
+							flag.Env = append(flag.Env, stringLiterals(arg)...)
+						}
+						return false
+					}
+				}
+				return true
+			})
+		}
+	}
+	return flag, flag.Name != ""
+}
+
+// importedPath returns the path of the package an expression names, such
+// as the os of os.Getenv, from type information or else from the imports of
+// the file; empty when it names no package
+func importedPath(info *types.Info, file *ast.File, expr ast.Expr) string {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if info != nil {
+		if pkgName, ok := info.Uses[ident].(*types.PkgName); ok {
+			return pkgName.Imported().Path()
+		}
+		if obj := info.Uses[ident]; obj != nil {
+			return ""
+		}
+	}
+	for _, spec := range file.Imports {
+		name, importPath := importName(nil, spec)
+		if spec.Name == nil && isMajorVersion(name) {
+			name = path.Base(path.Dir(importPath))
+		}
+		if name == ident.Name {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// importsPath reports whether a file imports a package
+func importsPath(file *ast.File, importPath string) bool {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// stringValue returns the value of a constant string expression, or else
+// its source
+func stringValue(info *types.Info, expr ast.Expr) string {
+	if info != nil {
+		if tv, ok := info.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return constant.StringVal(tv.Value)
+		}
+	}
+	if lit, ok := ast.Unparen(expr).(*ast.BasicLit); ok {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+	return types.ExprString(expr)
+}
+
+// stringLiterals returns the string literals in an expression
+func stringLiterals(expr ast.Expr) []string {
+	var values []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok {
+			if s, err := strconv.Unquote(lit.Value); err == nil {
+				values = append(values, s)
+			}
+		}
+		return true
+	})
+	return values
+}
@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBinaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"internal/config/config.go": `package config
+
+import (
+	"flag"
+	"os"
+)
+
+var timeout = flag.Duration("timeout", 0, "request timeout")
+
+// DatabaseURL returns the database to connect to
+func DatabaseURL() string {
+	url, _ := os.LookupEnv("DATABASE_URL")
+	return url
+}
+`,
+		"cmd/server/main.go": `package main
+
+import (
+	"flag"
+	"os"
+
+	"example.com/shop/internal/config"
+)
+
+const portFlag = "port"
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	var port int
+	flag.IntVar(&port, portFlag, 80, "port to serve")
+	fs := flag.NewFlagSet("admin", flag.ExitOnError)
+	fs.Bool("debug", false, "enable debugging")
+	_ = os.Getenv("LOG_LEVEL")
+	_, _ = addr, config.DatabaseURL()
+}
+`,
+		"cmd/worker/main.go": `package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/urfave/cli/v2"
+
+	"example.com/shop/internal/config"
+)
+
+func main() {
+	cmd := &cobra.Command{Use: "worker"}
+	cmd.Flags().StringP("queue", "q", "jobs", "queue to consume")
+	cmd.PersistentFlags().Count("verbose", "verbosity")
+	_ = &cli.StringFlag{Name: "region", Aliases: []string{"r"}, Value: "eu", Usage: "region to run in", EnvVars: []string{"REGION"}}
+	_ = config.DatabaseURL()
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	report, err := analyzer.Binaries(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Binaries failed: %v", err)
+	}
+	if len(report.Binaries) != 2 {
+		t.Fatalf("Expected 2 binaries, got %+v", report.Binaries)
+	}
+	server, worker := report.Binaries[0], report.Binaries[1]
+	if server.Name != "server" || server.Position.Line != 12 {
+		t.Errorf("Expected the server with the position of main, got %s at %+v", server.Name, server.Position)
+	}
+
+	flags := make(map[string]BinaryFlag)
+	for _, flag := range append(server.Flags, worker.Flags...) {
+		flags[flag.Name] = flag
+	}
+	if addr := flags["addr"]; addr.Type != "String" || addr.Default != `":8080"` || addr.Usage != "listen address" || addr.Library != FlagLibraryStd {
+		t.Errorf("Unexpected addr flag %+v", addr)
+	}
+	if port := flags["port"]; port.Type != "Int" || port.Default != "80" {
+		t.Errorf("Expected the constant name of the port flag, got %+v", port)
+	}
+	if debug := flags["debug"]; debug.Type != "Bool" || debug.Usage != "enable debugging" {
+		t.Errorf("Expected the flag of the flag set, got %+v", debug)
+	}
+	if timeout := flags["timeout"]; timeout.Package != "example.com/shop/internal/config" {
+		t.Errorf("Expected the flag of the imported package, got %+v", timeout)
+	}
+	if queue := flags["queue"]; queue.Library != FlagLibraryPflag || queue.Shorthand != "q" || queue.Default != `"jobs"` {
+		t.Errorf("Expected a cobra flag with a shorthand, got %+v", queue)
+	}
+	if verbose := flags["verbose"]; verbose.Type != "Count" || verbose.Usage != "verbosity" {
+		t.Errorf("Expected a count flag without a default, got %+v", verbose)
+	}
+	if region := flags["region"]; region.Library != FlagLibraryUrfave || region.Shorthand != "r" || !slices.Equal(region.Env, []string{"REGION"}) {
+		t.Errorf("Expected a urfave/cli flag read from REGION, got %+v", region)
+	}
+	if len(server.Flags) != 4 || len(worker.Flags) != 4 {
+		t.Errorf("Expected 4 flags per binary, got %+v and %+v", server.Flags, worker.Flags)
+	}
+
+	var env []string
+	for _, v := range server.Env {
+		env = append(env, v.Call+" "+v.Name)
+	}
+	slices.Sort(env)
+	if !slices.Equal(env, []string{"os.Getenv LOG_LEVEL", "os.LookupEnv DATABASE_URL"}) {
+		t.Errorf("Unexpected environment variables %v", env)
+	}
+
+	if !slices.Equal(worker.Packages, []string{"example.com/shop/internal/config"}) ||
+		!slices.Equal(worker.Dependencies, []string{"github.com/spf13/cobra", "github.com/urfave/cli/v2"}) || len(server.Dependencies) != 0 {
+		t.Errorf("Unexpected dependencies %+v and %+v", worker, server)
+	}
+	if len(report.Shared) != 1 || report.Shared[0].ImportPath != "example.com/shop/internal/config" || len(report.Shared[0].Binaries) != 2 {
+		t.Errorf("Expected the config package to be shared, got %+v", report.Shared)
+	}
+
+	if report, err := analyzer.Binaries(context.Background(), "example.com/shop/cmd/worker"); err != nil || len(report.Binaries) != 1 {
+		t.Errorf("Expected the worker only, got %+v (%v)", report, err)
+	}
+	if _, err := analyzer.Binaries(context.Background(), "missing"); err == nil {
+		t.Error("Expected an unknown package to fail")
+	}
+}
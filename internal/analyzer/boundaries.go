@@ -0,0 +1,304 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rules a boundary violation breaks
+const (
+	// BoundaryInternal is Go's rule that a package under an internal
+	// directory may only be imported from the tree rooted at its parent
+	BoundaryInternal = "internal"
+	// BoundaryPrivate is a private package rule of the repository
+	BoundaryPrivate = "private"
+)
+
+// Statuses of a boundary violation
+const (
+	// BoundaryBroken means go build rejects the import
+	BoundaryBroken = "broken"
+	// BoundaryCompiles means only a private rule forbids the import
+	BoundaryCompiles = "compiles"
+	// BoundaryBreaksOnMove means the import is allowed today but would not
+	// be after the moves
+	BoundaryBreaksOnMove = "breaks_on_move"
+)
+
+// PrivateRule makes packages private like internal ones without renaming
+// them. Patterns are directories relative to the repository or import
+// paths, with a /... suffix selecting a tree, as in the architecture
+// config.
+type PrivateRule struct {
+	// Packages selects the private packages, which only packages it also
+	// selects may import
+	Packages string `json:"packages"`
+	// Allow selects the other packages that may import them
+	Allow []string `json:"allow,omitempty"`
+	// Reason is reported with the violations
+	Reason string `json:"reason,omitempty"`
+}
+
+// BoundaryOptions configure Boundaries
+type BoundaryOptions struct {
+	// Package limits the importers checked to the packages a qualifier
+	// selects
+	Package string
+	Private []PrivateRule
+	// Moves maps directories or import paths to where they would move,
+	// subtrees included, to find the imports the moves would break
+	Moves map[string]string
+}
+
+// BoundaryViolation is an import crossing a package boundary
+type BoundaryViolation struct {
+	Importer string `json:"importer"`
+	Imported string `json:"imported"`
+	// Rule is internal or private
+	Rule string `json:"rule"`
+	// Status is broken, compiles or breaks_on_move
+	Status string `json:"status"`
+	// Allowed are the patterns of the packages that may import Imported
+	Allowed []string `json:"allowed"`
+	Reason  string   `json:"reason,omitempty"`
+	// MovedImporter and MovedImported are the import paths after the moves,
+	// when they change
+	MovedImporter string   `json:"moved_importer,omitempty"`
+	MovedImported string   `json:"moved_imported,omitempty"`
+	Position      Position `json:"position"`
+}
+
+// BoundaryReport lists the imports violating package boundaries
+type BoundaryReport struct {
+	Violations []BoundaryViolation `json:"violations"`
+	// Counts are the violations by status
+	Counts map[string]int `json:"counts"`
+	// Imports is the number of imports checked
+	Imports int `json:"imports"`
+}
+
+// boundaryPackage is a package as seen by the boundary checks, where only
+// repository packages have a directory
+type boundaryPackage struct {
+	archPackage
+	inRepo bool
+}
+
+// Boundaries checks the imports of the repository's packages against Go's
+// internal rule and the private rules. With moves, it also reports the
+// imports that are allowed today but would break once the packages move,
+// with both ends at their new paths.
+func (a *Analyzer) Boundaries(ctx context.Context, opts BoundaryOptions) (*BoundaryReport, error) {
+	for _, rule := range opts.Private {
+		if rule.Packages == "" {
+			return nil, fmt.Errorf("private rule without packages")
+		}
+	}
+	moves := make(map[string]string, len(opts.Moves))
+	for from, to := range opts.Moves {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("invalid move %q to %q", from, to)
+		}
+		moves[cleanMovePath(from)] = cleanMovePath(to)
+	}
+
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	byPath := make(map[string]boundaryPackage)
+	for _, pkg := range a.archPackages() {
+		byPath[pkg.importPath] = boundaryPackage{archPackage: pkg, inRepo: true}
+	}
+	lookup := func(importPath string) boundaryPackage {
+		if pkg, ok := byPath[importPath]; ok {
+			return pkg
+		}
+		return boundaryPackage{archPackage: archPackage{importPath: importPath}}
+	}
+
+	report := &BoundaryReport{Violations: []BoundaryViolation{}, Counts: make(map[string]int)}
+	found := false
+	for _, importPath := range a.sortedImportPaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		importer, ok := byPath[importPath]
+		if !ok || !matchesQualifier(opts.Package, importPath, a.asts[importPath][0].Name.Name) {
+			continue
+		}
+		found = true
+		movedImporter := movedPackage(importer, moves)
+		for _, file := range a.asts[importPath] {
+			for _, spec := range file.Imports {
+				imported, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || imported == importPath {
+					continue
+				}
+				report.Imports++
+				target := lookup(imported)
+				violations := checkBoundary(importer, target, opts.Private)
+				if len(moves) > 0 {
+					movedTarget := movedPackage(target, moves)
+					for _, v := range checkBoundary(movedImporter, movedTarget, opts.Private) {
+						if containsRule(violations, v.Rule) {
+							continue
+						}
+						v.Status = BoundaryBreaksOnMove
+						if movedImporter.importPath != importer.importPath {
+							v.MovedImporter = movedImporter.importPath
+						}
+						if movedTarget.importPath != target.importPath {
+							v.MovedImported = movedTarget.importPath
+						}
+						violations = append(violations, v)
+					}
+				}
+				for _, v := range violations {
+					v.Importer, v.Imported = importPath, imported
+					v.Position = a.position(spec.Path.Pos())
+					report.Violations = append(report.Violations, v)
+					report.Counts[v.Status]++
+				}
+			}
+		}
+	}
+	if !found && opts.Package != "" {
+		return nil, fmt.Errorf("package %s not found", opts.Package)
+	}
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		vi, vj := report.Violations[i], report.Violations[j]
+		if vi.Importer != vj.Importer {
+			return vi.Importer < vj.Importer
+		}
+		return vi.Imported < vj.Imported
+	})
+	return report, nil
+}
+
+// checkBoundary returns the rules an import from one package to another
+// breaks, without their importer, imported package and position
+func checkBoundary(from, to boundaryPackage, private []PrivateRule) []BoundaryViolation {
+	var violations []BoundaryViolation
+	if parent, ok := internalParent(to.importPath); ok && !hasPathPrefix(from.importPath, parent) {
+		violations = append(violations, BoundaryViolation{
+			Rule:    BoundaryInternal,
+			Status:  BoundaryBroken,
+			Allowed: []string{parent + "/..."},
+		})
+	}
+	if !to.inRepo {
+		return violations
+	}
+	for _, rule := range private {
+		if !matchesPackagePattern(rule.Packages, to.archPackage) || matchesPackagePattern(rule.Packages, from.archPackage) {
+			continue
+		}
+		allowed := false
+		for _, pattern := range rule.Allow {
+			if matchesPackagePattern(pattern, from.archPackage) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, BoundaryViolation{
+				Rule:    BoundaryPrivate,
+				Status:  BoundaryCompiles,
+				Allowed: append([]string{rule.Packages}, rule.Allow...),
+				Reason:  rule.Reason,
+			})
+			// One private rule is enough to explain a violation
+			break
+		}
+	}
+	return violations
+}
+
+// containsRule reports whether a violation of a rule is in the list
+func containsRule(violations []BoundaryViolation, rule string) bool {
+	for _, v := range violations {
+		if v.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// internalParent returns the import path of the tree that may import a
+// package under an internal directory: the parent of its last internal
+// element, as go build finds it. Packages of a top-level internal
+// directory, such as the standard library's, are left out.
+func internalParent(importPath string) (string, bool) {
+	switch {
+	case strings.HasSuffix(importPath, "/internal"):
+		return strings.TrimSuffix(importPath, "/internal"), true
+	case strings.Contains(importPath, "/internal/"):
+		return importPath[:strings.LastIndex(importPath, "/internal/")], true
+	}
+	return "", false
+}
+
+// hasPathPrefix reports whether an import path is prefix or in its tree
+func hasPathPrefix(importPath, prefix string) bool {
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
+
+// cleanMovePath returns a directory or import path of a move without a
+// leading ./ or a trailing /...
+func cleanMovePath(p string) string {
+	return strings.TrimPrefix(path.Clean(strings.TrimSuffix(p, "/...")), "./")
+}
+
+// movedPackage returns a repository package at its directory and import
+// path after the moves. The longest matching move wins, and a move of an
+// import path keeps the module's directory layout.
+func movedPackage(pkg boundaryPackage, moves map[string]string) boundaryPackage {
+	if !pkg.inRepo || len(moves) == 0 {
+		return pkg
+	}
+	// base is the import path of the repository directory
+	base, ok := pkg.importPath, true
+	if pkg.dir != "." {
+		base, ok = strings.CutSuffix(pkg.importPath, "/"+pkg.dir)
+	}
+
+	var best string
+	for from := range moves {
+		if len(from) > len(best) && (hasPathPrefix(pkg.dir, from) || hasPathPrefix(pkg.importPath, from)) {
+			best = from
+		}
+	}
+	if best == "" {
+		return pkg
+	}
+	to := moves[best]
+
+	moved := pkg
+	if hasPathPrefix(pkg.dir, best) {
+		moved.dir = to + strings.TrimPrefix(pkg.dir, best)
+		if ok {
+			moved.importPath = base + "/" + moved.dir
+			if moved.dir == "." {
+				moved.importPath = base
+			}
+		}
+		return moved
+	}
+	moved.importPath = to + strings.TrimPrefix(pkg.importPath, best)
+	if ok {
+		if dir, inModule := strings.CutPrefix(moved.importPath, base+"/"); inModule {
+			moved.dir = dir
+		}
+	}
+	return moved
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoundaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                       "module example.com/app\n\ngo 1.21\n",
+		"internal/store/store.go":      "package store\n",
+		"api/internal/auth/auth.go":    "package auth\n",
+		"storage/postgres/postgres.go": "package postgres\n",
+		"api/handlers/handlers.go": `package handlers
+
+import (
+	_ "example.com/app/api/internal/auth"
+	_ "example.com/app/internal/store"
+	_ "example.com/app/storage/postgres"
+)
+`,
+		"cli/cli.go": `package cli
+
+import (
+	_ "example.com/app/api/internal/auth"
+	_ "example.com/app/storage/postgres"
+	_ "fmt"
+)
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	private := []PrivateRule{{Packages: "storage/postgres", Allow: []string{"api/..."}, Reason: "use the storage interfaces"}}
+	report, err := analyzer.Boundaries(context.Background(), BoundaryOptions{Private: private})
+	if err != nil {
+		t.Fatalf("Boundaries failed: %v", err)
+	}
+	if report.Imports != 6 || len(report.Violations) != 2 {
+		t.Fatalf("Expected 2 violations in 6 imports, got %d in %d: %+v", len(report.Violations), report.Imports, report.Violations)
+	}
+	internal, private0 := report.Violations[0], report.Violations[1]
+	if internal.Importer != "example.com/app/cli" || internal.Imported != "example.com/app/api/internal/auth" ||
+		internal.Rule != BoundaryInternal || internal.Status != BoundaryBroken || internal.Allowed[0] != "example.com/app/api/..." {
+		t.Errorf("Expected cli to break the internal rule, got %+v", internal)
+	}
+	if internal.Position.Line != 4 || filepath.Base(internal.Position.Filename) != "cli.go" {
+		t.Errorf("Expected the position of the import, got %+v", internal.Position)
+	}
+	if private0.Imported != "example.com/app/storage/postgres" || private0.Rule != BoundaryPrivate ||
+		private0.Status != BoundaryCompiles || private0.Reason != "use the storage interfaces" {
+		t.Errorf("Expected cli to break the private rule, got %+v", private0)
+	}
+
+	// Moving the handlers out of api cuts them off from api/internal/auth
+	// and from the storage they are allowed to use
+	report, err = analyzer.Boundaries(context.Background(), BoundaryOptions{
+		Package: "handlers",
+		Private: private,
+		Moves:   map[string]string{"./api/handlers/...": "web/handlers"},
+	})
+	if err != nil {
+		t.Fatalf("Boundaries failed: %v", err)
+	}
+	if len(report.Violations) != 2 || report.Counts[BoundaryBreaksOnMove] != 2 {
+		t.Fatalf("Expected 2 imports breaking on the move, got %+v", report.Violations)
+	}
+	for _, v := range report.Violations {
+		if v.Status != BoundaryBreaksOnMove || v.MovedImporter != "example.com/app/web/handlers" || v.MovedImported != "" {
+			t.Errorf("Expected the import to break on the move, got %+v", v)
+		}
+	}
+
+	// Moving the internal package along keeps its importers allowed
+	report, err = analyzer.Boundaries(context.Background(), BoundaryOptions{
+		Package: "handlers",
+		Moves:   map[string]string{"api": "web"},
+	})
+	if err != nil || len(report.Violations) != 0 {
+		t.Errorf("Expected moving the tree to break nothing, got %+v (%v)", report, err)
+	}
+	// Moving an internal package by import path narrows its importers
+	report, err = analyzer.Boundaries(context.Background(), BoundaryOptions{
+		Package: "handlers",
+		Moves:   map[string]string{"example.com/app/internal/store": "example.com/app/cli/internal/store"},
+	})
+	if err != nil || len(report.Violations) != 1 || report.Violations[0].MovedImported != "example.com/app/cli/internal/store" ||
+		report.Violations[0].MovedImporter != "" || report.Violations[0].Allowed[0] != "example.com/app/cli/..." {
+		t.Errorf("Expected the moved store to be out of reach of the handlers, got %+v (%v)", report, err)
+	}
+
+	if _, err := analyzer.Boundaries(context.Background(), BoundaryOptions{Package: "missing"}); err == nil {
+		t.Error("Expected an unknown package to fail")
+	}
+}
@@ -0,0 +1,273 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultCodeSearchLimit is the number of matches SearchCode returns when
+// no limit is set
+const DefaultCodeSearchLimit = 100
+
+// maxMatchText bounds the text of a matching line in search results
+const maxMatchText = 300
+
+// CodeSearchOptions select what SearchCode looks for
+type CodeSearchOptions struct {
+	// Pattern is matched against each line; literally unless Regex is set
+	Pattern    string
+	Regex      bool
+	IgnoreCase bool
+	// Package restricts the search to matching packages (import path,
+	// suffix or package name)
+	Package string
+	// Include and Exclude are path.Match patterns. Patterns with a slash
+	// match the path relative to the repository or one of its parent
+	// directories; others match the file name.
+	Include []string
+	Exclude []string
+	// Limit bounds the matches returned; zero uses DefaultCodeSearchLimit
+	Limit int
+}
+
+// CodeMatch is a line matching a code search
+type CodeMatch struct {
+	Position   Position `json:"position"`
+	Text       string   `json:"text"`
+	ImportPath string   `json:"import_path"`
+	// Declaration is the package-level declaration enclosing the match;
+	// nil for matches outside any, such as the package clause
+	Declaration *EnclosingDecl `json:"declaration,omitempty"`
+}
+
+// EnclosingDecl is the declaration a code search match lies in
+type EnclosingDecl struct {
+	// Kind is function, method, type, var, const or import
+	Kind string `json:"kind"`
+	// Name is qualified with the receiver type for methods: Type.Method
+	Name      string `json:"name"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// CodeSearchResult is the result of SearchCode
+type CodeSearchResult struct {
+	Matches       []CodeMatch `json:"matches"`
+	FilesSearched int         `json:"files_searched"`
+	// Truncated is set when more matches were found than the limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SearchCode greps the Go files of the analysis line by line and annotates
+// each match with its package and the declaration enclosing it. Files are
+// read from disk, so the search sees edits the analysis has not caught up
+// with; it needs no package to be loaded.
+func (a *Analyzer) SearchCode(ctx context.Context, opts CodeSearchOptions) (*CodeSearchResult, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	expr := opts.Pattern
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultCodeSearchLimit
+	}
+
+	type searchFile struct{ filename, importPath string }
+	if err := a.rlock(ctx); err != nil {
+		return nil, err
+	}
+	if !a.initialized {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	var files []searchFile
+	for importPath, filenames := range a.files {
+		if !matchesQualifier(opts.Package, importPath, a.packageName(importPath)) {
+			continue
+		}
+		for _, filename := range filenames {
+			rel := filename
+			if r, err := filepath.Rel(a.repoPath, filename); err == nil {
+				rel = filepath.ToSlash(r)
+			}
+			if len(opts.Include) > 0 && !matchesAnyGlob(opts.Include, rel) || matchesAnyGlob(opts.Exclude, rel) {
+				continue
+			}
+			files = append(files, searchFile{filename, importPath})
+		}
+	}
+	generated := make(map[string]bool)
+	for _, f := range files {
+		generated[f.filename] = a.generated[f.filename]
+	}
+	a.mu.RUnlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].filename < files[j].filename })
+
+	result := &CodeSearchResult{Matches: []CodeMatch{}}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		src, err := a.readSource(f.filename)
+		if err != nil {
+			a.logWarn("Skipping file in code search", "file", f.filename, "error", err)
+			continue
+		}
+		result.FilesSearched++
+
+		var decls *declLines
+		for i, line := range bytes.Split(src, []byte("\n")) {
+			loc := re.FindIndex(line)
+			if loc == nil {
+				continue
+			}
+			if len(result.Matches) == limit {
+				result.Truncated = true
+				return result, nil
+			}
+			if decls == nil {
+				decls = parseDeclLines(f.filename, src)
+			}
+			text := strings.TrimRight(string(line), "\r")
+			if len(text) > maxMatchText {
+				text = text[:maxMatchText] + "..."
+			}
+			result.Matches = append(result.Matches, CodeMatch{
+				Position:    Position{Filename: f.filename, Line: i + 1, Column: loc[0] + 1, Generated: generated[f.filename]},
+				Text:        text,
+				ImportPath:  f.importPath,
+				Declaration: decls.at(i + 1),
+			})
+		}
+	}
+	return result, nil
+}
+
+// packageName returns the name of a known package, loaded or not
+func (a *Analyzer) packageName(importPath string) string {
+	if pkg := a.pkgs[importPath]; pkg != nil {
+		return pkg.Name()
+	}
+	if a.lazy != nil {
+		return a.lazy.pkgNames[importPath]
+	}
+	return ""
+}
+
+// matchesAnyGlob reports whether a slash-separated relative path matches
+// any of the patterns
+func matchesAnyGlob(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			if matchesPathPattern(pattern, rel) {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// declLines are the line ranges of a file's package-level declarations
+type declLines struct {
+	decls []EnclosingDecl
+}
+
+// parseDeclLines parses src for its declarations, whose ranges include
+// their doc comments. A file that fails to parse has none, so its matches
+// carry no declaration.
+func parseDeclLines(filename string, src []byte) *declLines {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return &declLines{}
+	}
+	lines := &declLines{}
+	add := func(kind, name string, doc *ast.CommentGroup, node ast.Node) {
+		from := node.Pos()
+		if doc != nil {
+			from = doc.Pos()
+		}
+		lines.decls = append(lines.decls, EnclosingDecl{
+			Kind:      kind,
+			Name:      name,
+			StartLine: fset.Position(from).Line,
+			EndLine:   fset.Position(node.End()).Line,
+		})
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			kind := "function"
+			if decl.Recv != nil {
+				kind = "method"
+			}
+			add(kind, funcDeclName(decl), decl.Doc, decl)
+		case *ast.GenDecl:
+			kind := decl.Tok.String()
+			if len(decl.Specs) == 1 {
+				add(kind, specName(decl.Specs[0]), decl.Doc, decl)
+				continue
+			}
+			// The specs of a group come before the group, so matches inside
+			// belong to their spec and those on the parentheses to the group
+			for _, spec := range decl.Specs {
+				add(kind, specName(spec), specDoc(decl, spec), spec)
+			}
+			add(kind, "", decl.Doc, decl)
+		}
+	}
+	return lines
+}
+
+// at returns the innermost declaration spanning line
+func (d *declLines) at(line int) *EnclosingDecl {
+	for _, decl := range d.decls {
+		if decl.StartLine <= line && line <= decl.EndLine {
+			return &decl
+		}
+	}
+	return nil
+}
+
+// specName names the declarations of a spec
+func specName(spec ast.Spec) string {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Name.Name
+	case *ast.ValueSpec:
+		names := make([]string, len(spec.Names))
+		for i, name := range spec.Names {
+			names[i] = name.Name
+		}
+		return strings.Join(names, ", ")
+	case *ast.ImportSpec:
+		return strings.Trim(spec.Path.Value, "\"`")
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchCode(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissing is returned for unknown items
+var ErrMissing = errors.New("missing item")
+
+type Store struct {
+	items map[string]int
+}
+
+func (s *Store) Get(name string) (int, error) {
+	count, ok := s.items[name]
+	if !ok {
+		return 0, fmt.Errorf("get %s: %w", name, ErrMissing)
+	}
+	return count, nil
+}
+
+func Describe(s *Store) string {
+	return fmt.Sprint(len(s.items))
+}
+`,
+		"store/internal/cache.go": "package internal\n\nfunc Lookup() error {\n\treturn nil // TODO: use ErrMissing\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		result, err := analyzer.SearchCode(ctx, CodeSearchOptions{Pattern: "ErrMissing"})
+		if err != nil {
+			t.Fatalf("SearchCode failed: %v", err)
+		}
+		if len(result.Matches) != 4 || result.FilesSearched != 2 || result.Truncated {
+			t.Fatalf("Expected 4 matches in 2 files, got %+v", result)
+		}
+		cache := result.Matches[0]
+		if cache.ImportPath != "example.com/shop/store/internal" || cache.Position.Line != 4 || cache.Position.Column != 26 ||
+			cache.Text != "\treturn nil // TODO: use ErrMissing" {
+			t.Errorf("Unexpected first match: %+v", cache)
+		}
+		if d := cache.Declaration; d == nil || d.Kind != "function" || d.Name != "Lookup" || d.StartLine != 3 || d.EndLine != 5 {
+			t.Errorf("Expected the match in Lookup, got %+v", d)
+		}
+		// Doc comments belong to their declaration
+		for _, match := range result.Matches[1:3] {
+			if d := match.Declaration; d == nil || d.Kind != "var" || d.Name != "ErrMissing" || d.StartLine != 8 || d.EndLine != 9 {
+				t.Errorf("Expected the match in the ErrMissing var, got %+v", d)
+			}
+		}
+		if d := result.Matches[3].Declaration; d == nil || d.Kind != "method" || d.Name != "Store.Get" || d.StartLine != 15 || d.EndLine != 21 {
+			t.Errorf("Expected the match in Store.Get, got %+v", d)
+		}
+
+		// Regular expressions, case folding, and grouped declarations
+		result, err = analyzer.SearchCode(ctx, CodeSearchOptions{Pattern: `^\s+"FMT"$`, Regex: true, IgnoreCase: true})
+		if err != nil {
+			t.Fatalf("SearchCode failed: %v", err)
+		}
+		if len(result.Matches) != 1 || result.Matches[0].Declaration == nil || result.Matches[0].Declaration.Kind != "import" || result.Matches[0].Declaration.Name != "fmt" {
+			t.Errorf("Expected the fmt import, got %+v", result.Matches)
+		}
+
+		// Package and glob filters
+		for _, opts := range []CodeSearchOptions{
+			{Pattern: "ErrMissing", Package: "store"},
+			{Pattern: "ErrMissing", Exclude: []string{"store/internal"}},
+			{Pattern: "ErrMissing", Include: []string{"store.go"}},
+		} {
+			result, err = analyzer.SearchCode(ctx, opts)
+			if err != nil {
+				t.Fatalf("SearchCode failed: %v", err)
+			}
+			if len(result.Matches) != 3 || !strings.HasSuffix(result.Matches[0].Position.Filename, "store.go") {
+				t.Errorf("Expected 3 matches in store.go for %+v, got %+v", opts, result.Matches)
+			}
+		}
+
+		result, err = analyzer.SearchCode(ctx, CodeSearchOptions{Pattern: "ErrMissing", Limit: 2})
+		if err != nil {
+			t.Fatalf("SearchCode failed: %v", err)
+		}
+		if len(result.Matches) != 2 || !result.Truncated {
+			t.Errorf("Expected 2 matches and truncation, got %+v", result)
+		}
+
+		if _, err := analyzer.SearchCode(ctx, CodeSearchOptions{Pattern: "(", Regex: true}); err == nil {
+			t.Error("Expected an error for an invalid regular expression")
+		}
+		if _, err := analyzer.SearchCode(ctx, CodeSearchOptions{Pattern: "x", Include: []string{"["}}); err == nil {
+			t.Error("Expected an error for an invalid glob")
+		}
+		if stats := analyzer.LazyStats(); lazy && (stats == nil || stats.Loaded != 0) {
+			t.Errorf("Expected code search to load no package, got %+v", stats)
+		}
+	}
+}
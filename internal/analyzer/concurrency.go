@@ -0,0 +1,683 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"go/version"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rules of the concurrency report
+const (
+	RuleLoopVariableCapture = "loop-variable-capture"
+	RuleUnbufferedSelect    = "unbuffered-select-default"
+	RuleMissingDone         = "missing-waitgroup-done"
+)
+
+// Channel operations
+const (
+	ChanMake    = "make"
+	ChanSend    = "send"
+	ChanReceive = "receive"
+	ChanRange   = "range"
+	ChanClose   = "close"
+)
+
+// atomicFunctions is the SyncUsage type under which calls of the sync/atomic
+// functions are counted
+const atomicFunctions = "atomic functions"
+
+// ConcurrencyReport describes how the analyzed packages use goroutines,
+// channels and sync primitives
+type ConcurrencyReport struct {
+	Packages []PackageConcurrency `json:"packages"`
+	// Issues counts the issues of all packages
+	Issues int `json:"issues"`
+}
+
+// PackageConcurrency is the concurrency of one package
+type PackageConcurrency struct {
+	ImportPath string `json:"import_path"`
+	// GoVersion is the language version of the package's module, which
+	// decides whether loop variables are created per iteration
+	GoVersion  string             `json:"go_version,omitempty"`
+	Goroutines []GoroutineLaunch  `json:"goroutines"`
+	Channels   []ChannelOp        `json:"channels"`
+	Sync       []SyncUsage        `json:"sync"`
+	Issues     []ConcurrencyIssue `json:"issues"`
+}
+
+// GoroutineLaunch is a go statement
+type GoroutineLaunch struct {
+	// Function is the declaration holding the statement, as pkg.Func or
+	// pkg.Type.Method
+	Function string `json:"function"`
+	// Target is the function started: its name, or "func literal"
+	Target   string   `json:"target"`
+	Position Position `json:"position"`
+}
+
+// ChannelOp is a channel made, sent to, received from, ranged over or closed
+type ChannelOp struct {
+	Op string `json:"op"`
+	// Channel is the channel expression; empty for make
+	Channel string `json:"channel,omitempty"`
+	Type    string `json:"type"`
+	// Buffer is the capacity given to make; empty when unbuffered
+	Buffer   string   `json:"buffer,omitempty"`
+	Function string   `json:"function"`
+	Position Position `json:"position"`
+}
+
+// SyncUsage counts the uses of a sync or sync/atomic type
+type SyncUsage struct {
+	// Type is the type, such as sync.Mutex or atomic.Int64, or "atomic
+	// functions" for the functions of sync/atomic
+	Type string `json:"type"`
+	// Declarations counts the variables and fields of the type or a
+	// pointer to it
+	Declarations int `json:"declarations"`
+	// Calls counts calls of its methods, or of the functions
+	Calls int `json:"calls"`
+}
+
+// ConcurrencyIssue is a suspicious concurrency pattern
+type ConcurrencyIssue struct {
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+	Function string   `json:"function"`
+	Position Position `json:"position"`
+}
+
+// ConcurrencyReport finds the go statements, channel operations and sync
+// primitive uses of the packages a qualifier selects, or of every package,
+// and flags three suspicious patterns:
+//
+//   - goroutine literals capturing a loop variable, in modules whose go
+//     version predates per-iteration loop variables (1.22)
+//   - a select with a default case on a channel made unbuffered, where a
+//     send is dropped and a receive succeeds only if a sender is waiting
+//   - a WaitGroup.Add in a function whose goroutines never call Done on the
+//     same WaitGroup, when every goroutine it starts can be inspected
+//
+// Packages with none of these are left out.
+func (a *Analyzer) ConcurrencyReport(ctx context.Context, pkg string) (*ConcurrencyReport, error) {
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	var importPaths []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	if len(importPaths) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	// Channels made unbuffered and function bodies are looked up across
+	// packages, since fields and goroutine targets cross them
+	unbuffered := make(map[types.Object]bool)
+	bodies := make(map[*types.Func]funcBody)
+	for _, importPath := range a.sortedPackagePaths() {
+		a.collectChannelsAndBodies(importPath, unbuffered, bodies)
+	}
+
+	report := &ConcurrencyReport{Packages: []PackageConcurrency{}}
+	for _, importPath := range importPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pc := a.packageConcurrency(importPath, unbuffered, bodies)
+		if len(pc.Goroutines) == 0 && len(pc.Channels) == 0 && len(pc.Sync) == 0 {
+			continue
+		}
+		report.Issues += len(pc.Issues)
+		report.Packages = append(report.Packages, pc)
+	}
+	return report, nil
+}
+
+// funcBody is a function declaration with the type information of its package
+type funcBody struct {
+	decl *ast.FuncDecl
+	info *types.Info
+}
+
+// collectChannelsAndBodies records the variables and fields of a package
+// assigned a channel made without a buffer, and its function declarations
+func (a *Analyzer) collectChannelsAndBodies(importPath string, unbuffered map[types.Object]bool, bodies map[*types.Func]funcBody) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	assign := func(lhs ast.Expr, rhs ast.Expr) {
+		if call, ok := ast.Unparen(rhs).(*ast.CallExpr); ok && isUnbufferedMake(info, call) {
+			if obj := exprObject(info, lhs); obj != nil {
+				unbuffered[obj] = true
+			}
+		}
+	}
+	for _, file := range a.asts[importPath] {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if fn, ok := info.Defs[n.Name].(*types.Func); ok && n.Body != nil {
+					bodies[fn] = funcBody{decl: n, info: info}
+				}
+			case *ast.AssignStmt:
+				if len(n.Lhs) == len(n.Rhs) {
+					for i := range n.Lhs {
+						assign(n.Lhs[i], n.Rhs[i])
+					}
+				}
+			case *ast.ValueSpec:
+				if len(n.Names) == len(n.Values) {
+					for i := range n.Names {
+						assign(n.Names[i], n.Values[i])
+					}
+				}
+			case *ast.KeyValueExpr:
+				// Fields set in composite literals
+				if key, ok := n.Key.(*ast.Ident); ok {
+					assign(key, n.Value)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// packageConcurrency reports the concurrency of one package
+func (a *Analyzer) packageConcurrency(importPath string, unbuffered map[types.Object]bool, bodies map[*types.Func]funcBody) PackageConcurrency {
+	info := a.infos[importPath]
+	pc := PackageConcurrency{
+		ImportPath: importPath,
+		Goroutines: []GoroutineLaunch{},
+		Channels:   []ChannelOp{},
+		Sync:       []SyncUsage{},
+		Issues:     []ConcurrencyIssue{},
+	}
+	files := a.asts[importPath]
+	if len(files) == 0 {
+		return pc
+	}
+	pc.GoVersion = a.goVersion(filepath.Dir(a.fset.Position(files[0].Package).Filename))
+	perIteration := version.IsValid("go"+pc.GoVersion) && version.Compare("go"+pc.GoVersion, "go1.22") >= 0
+
+	usage := make(map[string]*SyncUsage)
+	use := func(typeName string) *SyncUsage {
+		if usage[typeName] == nil {
+			usage[typeName] = &SyncUsage{Type: typeName}
+		}
+		return usage[typeName]
+	}
+	for ident, obj := range info.Defs {
+		if v, ok := obj.(*types.Var); ok && ident.Name != "_" {
+			if typeName := syncType(v.Type()); typeName != "" {
+				use(typeName).Declarations++
+			}
+		}
+	}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			function := fd.Name.Name
+			if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+				function = funcName(fn)
+			}
+			op := func(name string, ch ast.Expr, t types.Type, pos token.Pos) {
+				channel := ""
+				if ch != nil {
+					channel = types.ExprString(ch)
+				}
+				pc.Channels = append(pc.Channels, ChannelOp{
+					Op:       name,
+					Channel:  channel,
+					Type:     types.TypeString(t, types.RelativeTo(a.pkgs[importPath])),
+					Function: function,
+					Position: a.position(pos),
+				})
+			}
+
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.GoStmt:
+					target := "func literal"
+					if _, ok := n.Call.Fun.(*ast.FuncLit); !ok {
+						target = types.ExprString(n.Call.Fun)
+					}
+					pc.Goroutines = append(pc.Goroutines, GoroutineLaunch{
+						Function: function,
+						Target:   target,
+						Position: a.position(n.Pos()),
+					})
+				case *ast.SendStmt:
+					op(ChanSend, n.Chan, info.TypeOf(n.Chan), n.Pos())
+				case *ast.UnaryExpr:
+					if n.Op == token.ARROW {
+						op(ChanReceive, n.X, info.TypeOf(n.X), n.Pos())
+					}
+				case *ast.RangeStmt:
+					if t := info.TypeOf(n.X); t != nil {
+						if _, ok := t.Underlying().(*types.Chan); ok {
+							op(ChanRange, n.X, t, n.Pos())
+						}
+					}
+				case *ast.CallExpr:
+					switch builtinName(info, n.Fun) {
+					case "make":
+						if t := info.TypeOf(n); t != nil {
+							if _, ok := t.Underlying().(*types.Chan); ok {
+								op(ChanMake, nil, t, n.Pos())
+								if !isUnbufferedMake(info, n) {
+									pc.Channels[len(pc.Channels)-1].Buffer = types.ExprString(n.Args[1])
+								}
+							}
+						}
+					case "close":
+						if len(n.Args) == 1 {
+							op(ChanClose, n.Args[0], info.TypeOf(n.Args[0]), n.Pos())
+						}
+					}
+					if typeName := syncCall(info, n); typeName != "" {
+						use(typeName).Calls++
+					}
+				}
+				return true
+			})
+
+			if !perIteration {
+				pc.Issues = append(pc.Issues, a.loopCaptures(info, fd.Body, function)...)
+			}
+			pc.Issues = append(pc.Issues, a.unbufferedSelects(info, fd.Body, function, unbuffered)...)
+			pc.Issues = append(pc.Issues, a.missingDones(info, fd.Body, function, bodies)...)
+		}
+	}
+
+	for _, u := range usage {
+		pc.Sync = append(pc.Sync, *u)
+	}
+	sort.Slice(pc.Sync, func(i, j int) bool { return pc.Sync[i].Type < pc.Sync[j].Type })
+	sort.SliceStable(pc.Issues, func(i, j int) bool {
+		pi, pj := pc.Issues[i].Position, pc.Issues[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+	return pc
+}
+
+// loopCaptures flags goroutine literals in a function body that use a
+// variable declared by an enclosing for or range statement
+func (a *Analyzer) loopCaptures(info *types.Info, body *ast.BlockStmt, function string) []ConcurrencyIssue {
+	type loop struct {
+		body *ast.BlockStmt
+		vars map[types.Object]bool
+	}
+	var loops []loop
+	ast.Inspect(body, func(n ast.Node) bool {
+		vars := make(map[types.Object]bool)
+		define := func(exprs ...ast.Expr) {
+			for _, expr := range exprs {
+				if ident, ok := expr.(*ast.Ident); ok && info.Defs[ident] != nil {
+					vars[info.Defs[ident]] = true
+				}
+			}
+		}
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+				define(init.Lhs...)
+			}
+			if len(vars) > 0 {
+				loops = append(loops, loop{n.Body, vars})
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				define(n.Key, n.Value)
+			}
+			if len(vars) > 0 {
+				loops = append(loops, loop{n.Body, vars})
+			}
+		}
+		return true
+	})
+	if len(loops) == 0 {
+		return nil
+	}
+
+	var issues []ConcurrencyIssue
+	ast.Inspect(body, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := stmt.Call.Fun.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		captured := make(map[types.Object]bool)
+		for _, l := range loops {
+			if stmt.Pos() < l.body.Pos() || stmt.End() > l.body.End() {
+				continue
+			}
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj := info.Uses[ident]
+				if !l.vars[obj] || captured[obj] {
+					return true
+				}
+				captured[obj] = true
+				issues = append(issues, ConcurrencyIssue{
+					Rule:     RuleLoopVariableCapture,
+					Message:  fmt.Sprintf("goroutine captures loop variable %s, which is shared by all iterations before Go 1.22; pass it as an argument or copy it", ident.Name),
+					Function: function,
+					Position: a.position(ident.Pos()),
+				})
+				return true
+			})
+		}
+		return true
+	})
+	return issues
+}
+
+// unbufferedSelects flags the cases of selects with a default case on
+// channels made unbuffered
+func (a *Analyzer) unbufferedSelects(info *types.Info, body *ast.BlockStmt, function string, unbuffered map[types.Object]bool) []ConcurrencyIssue {
+	var issues []ConcurrencyIssue
+	ast.Inspect(body, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectStmt)
+		if !ok {
+			return true
+		}
+		hasDefault := false
+		for _, stmt := range sel.Body.List {
+			if clause, ok := stmt.(*ast.CommClause); ok && clause.Comm == nil {
+				hasDefault = true
+			}
+		}
+		if !hasDefault {
+			return true
+		}
+		for _, stmt := range sel.Body.List {
+			clause, ok := stmt.(*ast.CommClause)
+			if !ok || clause.Comm == nil {
+				continue
+			}
+			ch, send := commChannel(clause.Comm)
+			if ch == nil || !unbuffered[exprObject(info, ch)] {
+				continue
+			}
+			message := fmt.Sprintf("send on unbuffered channel %s in a select with default is dropped unless a receiver is already waiting", types.ExprString(ch))
+			if !send {
+				message = fmt.Sprintf("receive from unbuffered channel %s in a select with default only succeeds if a sender is already waiting", types.ExprString(ch))
+			}
+			issues = append(issues, ConcurrencyIssue{
+				Rule:     RuleUnbufferedSelect,
+				Message:  message,
+				Function: function,
+				Position: a.position(clause.Pos()),
+			})
+		}
+		return true
+	})
+	return issues
+}
+
+// commChannel returns the channel of a select case, and whether the case
+// sends to it
+func commChannel(comm ast.Stmt) (ast.Expr, bool) {
+	var expr ast.Expr
+	switch comm := comm.(type) {
+	case *ast.SendStmt:
+		return comm.Chan, true
+	case *ast.ExprStmt:
+		expr = comm.X
+	case *ast.AssignStmt:
+		if len(comm.Rhs) == 1 {
+			expr = comm.Rhs[0]
+		}
+	}
+	if recv, ok := ast.Unparen(expr).(*ast.UnaryExpr); ok && recv.Op == token.ARROW {
+		return recv.X, false
+	}
+	return nil, false
+}
+
+// missingDones flags WaitGroups a function adds to when none of the
+// goroutines it starts calls Done on them. Nothing is flagged when a
+// WaitGroup is handed to another function or the function starts no
+// goroutine or one that cannot be inspected, since Done may then be
+// called elsewhere.
+func (a *Analyzer) missingDones(info *types.Info, body *ast.BlockStmt, function string, bodies map[*types.Func]funcBody) []ConcurrencyIssue {
+	adds := make(map[types.Object]*ast.CallExpr)
+	var order []types.Object
+	handedOff := make(map[types.Object]bool)
+	var launches []*ast.GoStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			launches = append(launches, n)
+		case *ast.CallExpr:
+			if wg, method := waitGroupCall(info, n); wg != nil && method == "Add" && adds[wg] == nil {
+				adds[wg] = n
+				order = append(order, wg)
+			}
+			for _, arg := range n.Args {
+				if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+					arg = unary.X
+				}
+				if obj := exprObject(info, arg); obj != nil {
+					handedOff[obj] = true
+				}
+			}
+		}
+		return true
+	})
+	if len(adds) == 0 || len(launches) == 0 {
+		return nil
+	}
+
+	// The goroutines' code: literals, and the repository functions started
+	type code struct {
+		node ast.Node
+		info *types.Info
+	}
+	var started []code
+	for _, launch := range launches {
+		if lit, ok := launch.Call.Fun.(*ast.FuncLit); ok {
+			started = append(started, code{lit.Body, info})
+			continue
+		}
+		fn, _ := calledFunc(info, launch.Call).(*types.Func)
+		if fn == nil {
+			return nil
+		}
+		target, ok := bodies[fn.Origin()]
+		if !ok {
+			return nil
+		}
+		started = append(started, code{target.decl.Body, target.info})
+	}
+
+	var issues []ConcurrencyIssue
+	for _, wg := range order {
+		if handedOff[wg] {
+			continue
+		}
+		done := false
+		for _, c := range started {
+			ast.Inspect(c.node, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					if obj, method := waitGroupCall(c.info, call); obj == wg && method == "Done" {
+						done = true
+					}
+				}
+				return !done
+			})
+		}
+		if done {
+			continue
+		}
+		add := adds[wg]
+		name := types.ExprString(add.Fun.(*ast.SelectorExpr).X)
+		issues = append(issues, ConcurrencyIssue{
+			Rule:     RuleMissingDone,
+			Message:  fmt.Sprintf("%s.Add is not matched by %s.Done in any goroutine started here, so Wait blocks forever", name, name),
+			Function: function,
+			Position: a.position(add.Pos()),
+		})
+	}
+	return issues
+}
+
+// waitGroupCall returns the WaitGroup variable or field a call invokes a
+// method of, and the method
+func waitGroupCall(info *types.Info, call *ast.CallExpr) (types.Object, string) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, ""
+	}
+	selection := info.Selections[sel]
+	if selection == nil || selection.Kind() != types.MethodVal || syncType(selection.Recv()) != "sync.WaitGroup" {
+		return nil, ""
+	}
+	return exprObject(info, sel.X), sel.Sel.Name
+}
+
+// calledFunc returns the object of the function or method a call invokes
+func calledFunc(info *types.Info, call *ast.CallExpr) types.Object {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		return info.Uses[fun]
+	case *ast.SelectorExpr:
+		return info.Uses[fun.Sel]
+	case *ast.IndexExpr:
+		return calledFunc(info, &ast.CallExpr{Fun: fun.X})
+	}
+	return nil
+}
+
+// exprObject returns the variable or field an expression names
+func exprObject(info *types.Info, expr ast.Expr) types.Object {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		if obj := info.Uses[expr]; obj != nil {
+			return obj
+		}
+		return info.Defs[expr]
+	case *ast.SelectorExpr:
+		return info.Uses[expr.Sel]
+	}
+	return nil
+}
+
+// builtinName returns the name of the builtin function an expression
+// refers to, if any
+func builtinName(info *types.Info, fun ast.Expr) string {
+	if ident, ok := ast.Unparen(fun).(*ast.Ident); ok {
+		if builtin, ok := info.Uses[ident].(*types.Builtin); ok {
+			return builtin.Name()
+		}
+	}
+	return ""
+}
+
+// isUnbufferedMake reports whether a call makes a channel without a buffer
+// or with a constant zero one
+func isUnbufferedMake(info *types.Info, call *ast.CallExpr) bool {
+	if builtinName(info, call.Fun) != "make" {
+		return false
+	}
+	t := info.TypeOf(call)
+	if t == nil {
+		return false
+	}
+	if _, ok := t.Underlying().(*types.Chan); !ok {
+		return false
+	}
+	if len(call.Args) < 2 {
+		return true
+	}
+	tv := info.Types[call.Args[1]]
+	return tv.Value != nil && constant.Sign(tv.Value) == 0
+}
+
+// syncType names the sync or sync/atomic type of t, or of what t points to
+func syncType(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return ""
+	}
+	switch named.Obj().Pkg().Path() {
+	case "sync", "sync/atomic":
+		return named.Obj().Pkg().Name() + "." + named.Obj().Name()
+	}
+	return ""
+}
+
+// syncCall names the sync or sync/atomic type a call invokes a method of,
+// or atomicFunctions for the functions of sync/atomic
+func syncCall(info *types.Info, call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if selection := info.Selections[sel]; selection != nil {
+		if selection.Kind() == types.MethodVal {
+			return syncType(selection.Recv())
+		}
+		return ""
+	}
+	if fn, ok := info.Uses[sel.Sel].(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == "sync/atomic" {
+		return atomicFunctions
+	}
+	return ""
+}
+
+// goVersion returns the go directive of the go.mod of the module containing
+// dir; empty outside a module
+func (a *Analyzer) goVersion(dir string) string {
+	for current := dir; ; {
+		if data, err := os.ReadFile(filepath.Join(current, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "go" {
+					return fields[1]
+				}
+			}
+			return ""
+		}
+		parent := filepath.Dir(current)
+		if parent == current || !strings.HasPrefix(parent, a.repoPath) {
+			return ""
+		}
+		current = parent
+	}
+}
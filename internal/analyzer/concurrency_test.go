@@ -0,0 +1,194 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConcurrencyReport(t *testing.T) {
+	workers := `package workers
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type Pool struct {
+	mu    sync.Mutex
+	wg    sync.WaitGroup
+	done  chan struct{}
+	jobs  chan int
+	count int64
+}
+
+func NewPool() *Pool {
+	return &Pool{done: make(chan struct{}), jobs: make(chan int, 8)}
+}
+
+func (p *Pool) Start(items []int) {
+	for _, item := range items {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.jobs <- item
+		}()
+	}
+	p.wg.Add(1)
+	go p.drain()
+}
+
+func (p *Pool) drain() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		atomic.AddInt64(&p.count, int64(job))
+	}
+}
+
+func (p *Pool) Stop() {
+	select {
+	case p.done <- struct{}{}:
+	default:
+	}
+	select {
+	case p.jobs <- 0:
+	default:
+	}
+	close(p.jobs)
+}
+
+func Leak(n int) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			_ = i
+		}(i)
+	}
+	wg.Wait()
+}
+
+func Handoff(wg *sync.WaitGroup) {
+	wg.Add(1)
+	go work(wg)
+}
+
+func work(wg *sync.WaitGroup) { wg.Done() }
+`
+	files := map[string]string{
+		"old/go.mod":        "module example.com/old\n\ngo 1.21\n",
+		"old/workers.go":    workers,
+		"new/go.mod":        "module example.com/new\n\ngo 1.22\n",
+		"new/workers.go":    workers,
+		"old/plain/calc.go": "package plain\n\nfunc Add(a, b int) int { return a + b }\n",
+	}
+	tmpDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+
+		report, err := analyzer.ConcurrencyReport(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Failed to build concurrency report (lazy %v): %v", lazy, err)
+		}
+		if len(report.Packages) != 2 {
+			t.Fatalf("Expected the two worker packages, got %+v", report.Packages)
+		}
+		byPath := make(map[string]PackageConcurrency)
+		for _, pkg := range report.Packages {
+			byPath[pkg.ImportPath] = pkg
+		}
+		old, ok := byPath["example.com/old"]
+		if !ok {
+			t.Fatalf("Expected example.com/old in the report, got %+v", report.Packages)
+		}
+		if old.GoVersion != "1.21" {
+			t.Errorf("Expected go version 1.21, got %q", old.GoVersion)
+		}
+
+		var targets []string
+		for _, g := range old.Goroutines {
+			targets = append(targets, g.Function+" "+g.Target)
+		}
+		wantTargets := []string{
+			"workers.Pool.Start func literal",
+			"workers.Pool.Start p.drain",
+			"workers.Leak func literal",
+			"workers.Handoff work",
+		}
+		if !reflect.DeepEqual(targets, wantTargets) {
+			t.Errorf("Expected goroutines %v, got %v", wantTargets, targets)
+		}
+
+		ops := make(map[string]int)
+		for _, op := range old.Channels {
+			ops[op.Op]++
+			if op.Op == ChanMake && op.Type == "chan int" && op.Buffer != "8" {
+				t.Errorf("Expected jobs to be made with a buffer of 8, got %+v", op)
+			}
+		}
+		wantOps := map[string]int{ChanMake: 2, ChanSend: 3, ChanRange: 1, ChanClose: 1}
+		if !reflect.DeepEqual(ops, wantOps) {
+			t.Errorf("Expected channel operations %v, got %v", wantOps, ops)
+		}
+
+		wantSync := []SyncUsage{
+			{Type: atomicFunctions, Calls: 1},
+			{Type: "sync.Mutex", Declarations: 1},
+			{Type: "sync.WaitGroup", Declarations: 4, Calls: 8},
+		}
+		if !reflect.DeepEqual(old.Sync, wantSync) {
+			t.Errorf("Expected sync usage %+v, got %+v", wantSync, old.Sync)
+		}
+
+		var issues []string
+		for _, issue := range old.Issues {
+			issues = append(issues, issue.Rule+" "+issue.Function)
+		}
+		wantIssues := []string{
+			RuleLoopVariableCapture + " workers.Pool.Start",
+			RuleUnbufferedSelect + " workers.Pool.Stop",
+			RuleMissingDone + " workers.Leak",
+		}
+		if !reflect.DeepEqual(issues, wantIssues) {
+			t.Errorf("Expected issues %v, got %v", wantIssues, issues)
+		}
+
+		// Go 1.22 creates loop variables per iteration
+		newIssues := byPath["example.com/new"].Issues
+		if len(newIssues) != 2 || newIssues[0].Rule != RuleUnbufferedSelect {
+			t.Errorf("Expected no loop variable capture with Go 1.22, got %+v", newIssues)
+		}
+		if report.Issues != 5 {
+			t.Errorf("Expected 5 issues in all, got %d", report.Issues)
+		}
+
+		filtered, err := analyzer.ConcurrencyReport(context.Background(), "example.com/new")
+		if err != nil {
+			t.Fatalf("Failed to build concurrency report for example.com/new: %v", err)
+		}
+		if len(filtered.Packages) != 1 || filtered.Packages[0].ImportPath != "example.com/new" {
+			t.Errorf("Expected only example.com/new, got %+v", filtered.Packages)
+		}
+		if _, err := analyzer.ConcurrencyReport(context.Background(), "missing"); err == nil {
+			t.Error("Expected an error for an unknown package")
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Styles of generated constructors
+const (
+	// ConstructorWithParams takes every field as a parameter
+	ConstructorWithParams = "params"
+	// ConstructorWithOptions takes the required fields as parameters and sets
+	// the others with functional options
+	ConstructorWithOptions = "options"
+	// ConstructorWithConfig takes the required fields as parameters and the
+	// others in a config struct
+	ConstructorWithConfig = "config"
+)
+
+// ConstructorOptions configure GenerateConstructor
+type ConstructorOptions struct {
+	// Style is ConstructorWithParams (the default), ConstructorWithOptions or
+	// ConstructorWithConfig
+	Style string
+	// Required are the fields passed as parameters; empty passes every
+	// field in the params style and none in the others
+	Required []string
+	// Name is the name of the constructor; empty follows the package's
+	// conventions
+	Name string
+}
+
+// Constructor is a constructor function generated for a struct, with the
+// option or config declarations its style needs
+type Constructor struct {
+	// Type is the struct, qualified with its package name
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	Style      string `json:"style"`
+	// Pointer reports whether the constructor returns a pointer
+	Pointer  bool     `json:"pointer"`
+	Required []string `json:"required,omitempty"`
+	Optional []string `json:"optional,omitempty"`
+	// OptionType is the functional option type, which Code declares
+	// unless the package already does
+	OptionType string `json:"option_type,omitempty"`
+	// Options are the option functions setting the optional fields, by
+	// field. Code declares those the package does not.
+	Options map[string]string `json:"options,omitempty"`
+	// ConfigType is the config struct Code declares
+	ConfigType string `json:"config_type,omitempty"`
+	// Conventions explain how the names and result were chosen
+	Conventions []string `json:"conventions"`
+	// File is the file declaring the struct
+	File string `json:"file"`
+	// Imports are the import paths the declarations refer to, keyed by the
+	// package names they use
+	Imports map[string]string `json:"imports,omitempty"`
+	// Code is the formatted declarations
+	Code string `json:"code"`
+}
+
+// constructorField is a struct field and the names the generated code
+// uses for it
+type constructorField struct {
+	name   string // Field name; the type name for embedded fields
+	typ    string // Qualified type
+	param  string // Parameter name
+	export string // Exported name, for config fields and option functions
+}
+
+// packageConventions are the naming conventions found in a package
+type packageConventions struct {
+	pointers, values int               // Constructors returning pointers and values
+	optionTypes      map[string]string // Functional option types by the struct they configure
+	optionFuncs      map[string]bool   // Functions returning option types
+	withPrefix       int               // Option functions named With...
+	configs, options int               // Structs named ...Config and ...Options
+}
+
+// GenerateConstructor generates a constructor for a struct declared in the
+// repository. It follows the conventions of the package: constructors
+// return pointers unless the package's constructors return values, a
+// struct named after its package gets New, an existing functional option
+// type for the struct is reused, option functions are prefixed with With
+// unless the package's are not, and config structs are suffixed with
+// Options when the package names them so.
+func (a *Analyzer) GenerateConstructor(ctx context.Context, typeName string, opts ConstructorOptions) (*Constructor, error) {
+	style := opts.Style
+	if style == "" {
+		style = ConstructorWithParams
+	}
+	if style != ConstructorWithParams && style != ConstructorWithOptions && style != ConstructorWithConfig {
+		return nil, fmt.Errorf("unknown style %q (expected %s, %s or %s)", opts.Style, ConstructorWithParams, ConstructorWithOptions, ConstructorWithConfig)
+	}
+
+	if err := a.rlockLoaded(ctx, func() []string { return a.packagesNamed(typeName) }); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	importPath, obj, err := a.resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok || typeObj.IsAlias() {
+		return nil, fmt.Errorf("%s is not a defined type", typeName)
+	}
+	named, ok := typeObj.Type().(*types.Named)
+	if !ok || len(a.asts[importPath]) == 0 {
+		return nil, fmt.Errorf("%s is not declared in the repository", typeName)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", typeName)
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is generic; generating constructors for generic types is not supported", typeName)
+	}
+
+	pkg := typeObj.Pkg()
+	exported := typeObj.Exported()
+	result := &Constructor{
+		Type:       pkg.Name() + "." + typeObj.Name(),
+		ImportPath: importPath,
+		Style:      style,
+		File:       a.fset.Position(typeObj.Pos()).Filename,
+		Imports:    make(map[string]string),
+	}
+	qualifier := importQualifier(importPath, result.Imports)
+
+	var fields []constructorField
+	byName := make(map[string]int)
+	for field := range st.Fields() {
+		if field.Name() == "_" {
+			continue
+		}
+		byName[field.Name()] = len(fields)
+		fields = append(fields, constructorField{
+			name:   field.Name(),
+			typ:    types.TypeString(field.Type(), qualifier),
+			export: exportName(field.Name(), true),
+		})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s has no fields to initialize", typeName)
+	}
+
+	required := make(map[string]bool)
+	for _, name := range opts.Required {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("%s has no field %s", typeName, name)
+		}
+		required[name] = true
+	}
+	for _, field := range fields {
+		if style == ConstructorWithParams && len(opts.Required) == 0 {
+			required[field.name] = true
+		}
+		if required[field.name] {
+			result.Required = append(result.Required, field.name)
+		} else {
+			result.Optional = append(result.Optional, field.name)
+		}
+	}
+	if style != ConstructorWithParams && len(result.Optional) == 0 {
+		return nil, fmt.Errorf("every field of %s is required; use the %s style", typeName, ConstructorWithParams)
+	}
+
+	conv := a.packageConventions(pkg)
+	declared := func(name string) bool { return pkg.Scope().Lookup(name) != nil }
+
+	result.Name = opts.Name
+	switch {
+	case result.Name != "":
+		result.Conventions = append(result.Conventions, "constructor named as requested")
+	case strings.EqualFold(typeObj.Name(), pkg.Name()) && !declared(exportName("New", exported)):
+		result.Name = exportName("New", exported)
+		result.Conventions = append(result.Conventions, fmt.Sprintf("constructor named %s since the struct is named after package %s", result.Name, pkg.Name()))
+	default:
+		result.Name = exportName("New", exported) + exportName(typeObj.Name(), true)
+		result.Conventions = append(result.Conventions, fmt.Sprintf("constructor named %s after the struct", result.Name))
+	}
+	if !token.IsIdentifier(result.Name) {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", result.Name)
+	}
+	if declared(result.Name) {
+		return nil, fmt.Errorf("package %s already declares %s; choose another name", pkg.Name(), result.Name)
+	}
+	// Names after New follow the struct's name: New for store.Store has
+	// Option and Config rather than StoreOption and StoreConfig
+	prefix := exportName(typeObj.Name(), exported)
+	if result.Name == exportName("New", exported) {
+		prefix = ""
+	}
+
+	switch {
+	case conv.values > conv.pointers:
+		result.Conventions = append(result.Conventions, fmt.Sprintf("returns a value like %d of %d constructors in the package", conv.values, conv.values+conv.pointers))
+	case conv.pointers > 0:
+		result.Pointer = true
+		result.Conventions = append(result.Conventions, fmt.Sprintf("returns a pointer like %d of %d constructors in the package", conv.pointers, conv.values+conv.pointers))
+	default:
+		result.Pointer = true
+		result.Conventions = append(result.Conventions, "returns a pointer; the package has no other constructors")
+	}
+
+	// Parameters are named after their fields and must not collide with
+	// each other, the struct's variable or the packages they refer to
+	used := make(map[string]bool)
+	for name := range result.Imports {
+		used[name] = true
+	}
+	methods := a.existingMethods(importPath, typeObj.Name())
+	recv, _ := receiverName(typeObj.Name(), methods)
+	recv = uniqueName(recv, used)
+	for i := range fields {
+		fields[i].param = uniqueName(paramName(fields[i].name), used)
+	}
+
+	var code strings.Builder
+	switch style {
+	case ConstructorWithOptions:
+		if err := writeOptions(&code, result, fields, required, conv, typeObj, recv, prefix, declared); err != nil {
+			return nil, err
+		}
+	case ConstructorWithConfig:
+		suffix := "Config"
+		if conv.options > conv.configs {
+			suffix = "Options"
+		}
+		result.ConfigType = exportName(prefix+suffix, exported)
+		if declared(result.ConfigType) {
+			return nil, fmt.Errorf("package %s already declares %s", pkg.Name(), result.ConfigType)
+		}
+		result.Conventions = append(result.Conventions, fmt.Sprintf("config struct named %s", result.ConfigType))
+		fmt.Fprintf(&code, "// %s holds the optional settings of a %s\n", result.ConfigType, typeObj.Name())
+		fmt.Fprintf(&code, "type %s struct {\n", result.ConfigType)
+		for _, field := range fields {
+			if !required[field.name] {
+				fmt.Fprintf(&code, "\t%s %s\n", field.export, field.typ)
+			}
+		}
+		code.WriteString("}\n\n")
+	}
+
+	var params []string
+	for _, field := range fields {
+		if required[field.name] {
+			params = append(params, field.param+" "+field.typ)
+		}
+	}
+	var options, config string
+	switch style {
+	case ConstructorWithOptions:
+		options = uniqueName("opts", used)
+		params = append(params, options+" ..."+result.OptionType)
+	case ConstructorWithConfig:
+		config = uniqueName("config", used)
+		params = append(params, config+" "+result.ConfigType)
+	}
+	resultType := typeObj.Name()
+	if result.Pointer {
+		resultType = "*" + resultType
+	}
+	fmt.Fprintf(&code, "// %s creates a %s\n", result.Name, typeObj.Name())
+	fmt.Fprintf(&code, "func %s(%s) %s {\n", result.Name, strings.Join(params, ", "), resultType)
+	literal := func(indent string) {
+		for _, field := range fields {
+			switch {
+			case required[field.name]:
+				fmt.Fprintf(&code, "%s\t%s: %s,\n", indent, field.name, field.param)
+			case style == ConstructorWithConfig:
+				fmt.Fprintf(&code, "%s\t%s: %s.%s,\n", indent, field.name, config, field.export)
+			}
+		}
+	}
+	amp := ""
+	if result.Pointer {
+		amp = "&"
+	}
+	if style == ConstructorWithOptions {
+		fmt.Fprintf(&code, "\t%s := %s%s{\n", recv, amp, typeObj.Name())
+		literal("\t")
+		code.WriteString("\t}\n")
+		opt := uniqueName("opt", used)
+		target := recv
+		if !result.Pointer {
+			target = "&" + recv
+		}
+		fmt.Fprintf(&code, "\tfor _, %s := range %s {\n\t\t%s(%s)\n\t}\n", opt, options, opt, target)
+		fmt.Fprintf(&code, "\treturn %s\n", recv)
+	} else {
+		fmt.Fprintf(&code, "\treturn %s%s{\n", amp, typeObj.Name())
+		literal("")
+		code.WriteString("\t}\n")
+	}
+	code.WriteString("}\n")
+
+	formatted, err := format.Source([]byte("package p\n\n" + code.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated constructor is not valid Go: %w", err)
+	}
+	result.Code = strings.TrimPrefix(string(formatted), "package p\n\n")
+	if len(result.Imports) == 0 {
+		result.Imports = nil
+	}
+	return result, nil
+}
+
+// writeOptions writes the functional option type of a struct, unless the
+// package declares one, and an option function per optional field
+func writeOptions(code *strings.Builder, result *Constructor, fields []constructorField, required map[string]bool, conv packageConventions, typeObj *types.TypeName, recv, prefix string, declared func(string) bool) error {
+	exported := typeObj.Exported()
+	reused := false
+	if name, ok := conv.optionTypes[typeObj.Name()]; ok {
+		result.OptionType = name
+		reused = true
+		result.Conventions = append(result.Conventions, fmt.Sprintf("reuses the option type %s of the package", name))
+	} else {
+		result.OptionType = exportName(prefix+"Option", exported)
+		if prefix != "" && len(conv.optionTypes) == 0 && !declared(exportName("Option", exported)) {
+			result.OptionType = exportName("Option", exported)
+		}
+		if declared(result.OptionType) {
+			return fmt.Errorf("package %s already declares %s", typeObj.Pkg().Name(), result.OptionType)
+		}
+		result.Conventions = append(result.Conventions, fmt.Sprintf("option type named %s", result.OptionType))
+	}
+
+	with := "With"
+	if len(conv.optionFuncs) > 0 && conv.withPrefix == 0 {
+		with = ""
+		result.Conventions = append(result.Conventions, "option functions named after their fields like those of the package")
+	} else {
+		result.Conventions = append(result.Conventions, "option functions prefixed with With")
+	}
+
+	if !reused {
+		fmt.Fprintf(code, "// %s configures a %s\n", result.OptionType, typeObj.Name())
+		fmt.Fprintf(code, "type %s func(*%s)\n\n", result.OptionType, typeObj.Name())
+	}
+	result.Options = make(map[string]string)
+	for _, field := range fields {
+		if required[field.name] {
+			continue
+		}
+		name := exportName(with+field.export, exported)
+		if reused && conv.optionFuncs[name] {
+			// The package already sets the field
+			result.Options[field.name] = name
+			continue
+		}
+		if declared(name) {
+			return fmt.Errorf("package %s already declares %s", typeObj.Pkg().Name(), name)
+		}
+		result.Options[field.name] = name
+		fmt.Fprintf(code, "// %s sets the %s of a %s\n", name, field.name, typeObj.Name())
+		fmt.Fprintf(code, "func %s(%s %s) %s {\n", name, field.param, field.typ, result.OptionType)
+		fmt.Fprintf(code, "\treturn func(%s *%s) {\n\t\t%s.%s = %s\n\t}\n}\n\n", recv, typeObj.Name(), recv, field.name, field.param)
+	}
+	return nil
+}
+
+// packageConventions collects the constructors, functional options and
+// config structs a package declares
+func (a *Analyzer) packageConventions(pkg *types.Package) packageConventions {
+	conv := packageConventions{optionTypes: make(map[string]string), optionFuncs: make(map[string]bool)}
+	scope := pkg.Scope()
+	optionTypes := make(map[*types.Named]bool)
+	for _, name := range scope.Names() {
+		typeObj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeObj.IsAlias() {
+			continue
+		}
+		named, ok := typeObj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		switch underlying := named.Underlying().(type) {
+		case *types.Struct:
+			switch {
+			case strings.HasSuffix(name, "Config"):
+				conv.configs++
+			case strings.HasSuffix(name, "Options"):
+				conv.options++
+			}
+		case *types.Signature:
+			if underlying.Params().Len() != 1 || underlying.Results().Len() != 0 {
+				continue
+			}
+			if target := pointerToStruct(underlying.Params().At(0).Type(), pkg); target != nil {
+				if _, ok := conv.optionTypes[target.Obj().Name()]; !ok {
+					conv.optionTypes[target.Obj().Name()] = name
+				}
+				optionTypes[named] = true
+			}
+		}
+	}
+	for _, name := range scope.Names() {
+		fn, ok := scope.Lookup(name).(*types.Func)
+		if !ok {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		if sig.Results().Len() == 0 {
+			continue
+		}
+		first := sig.Results().At(0).Type()
+		if named, ok := first.(*types.Named); ok && optionTypes[named] {
+			conv.optionFuncs[name] = true
+			if strings.HasPrefix(name, "With") || strings.HasPrefix(name, "with") {
+				conv.withPrefix++
+			}
+			continue
+		}
+		if !strings.HasPrefix(name, "New") && !strings.HasPrefix(name, "new") {
+			continue
+		}
+		if pointerToStruct(first, pkg) != nil {
+			conv.pointers++
+		} else if named, ok := first.(*types.Named); ok && named.Obj().Pkg() == pkg {
+			if _, ok := named.Underlying().(*types.Struct); ok {
+				conv.values++
+			}
+		}
+	}
+	return conv
+}
+
+// pointerToStruct returns the struct of a package t points to, or nil
+func pointerToStruct(t types.Type, pkg *types.Package) *types.Named {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Pkg() != pkg {
+		return nil
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	return named
+}
+
+// exportName returns name with its first letter in upper case when
+// exported, or else in lower case
+func exportName(name string, exported bool) string {
+	r, size := utf8.DecodeRuneInString(name)
+	if exported {
+		return string(unicode.ToUpper(r)) + name[size:]
+	}
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// paramName turns a field name into a parameter name, lowering its leading
+// initialism (URLPath: urlPath, ID: id) and avoiding keywords
+func paramName(field string) string {
+	runes := []rune(field)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		// The last capital starts the next word
+		upper--
+	}
+	for i := range upper {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	name := string(runes)
+	if token.IsKeyword(name) {
+		switch name {
+		case "type":
+			return "typ"
+		case "func":
+			return "fn"
+		case "package":
+			return "pkg"
+		case "interface":
+			return "iface"
+		}
+		return name + "Value"
+	}
+	return name
+}
+
+// uniqueName returns name, numbered when already used, and marks it used
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateConstructor(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"server/server.go": `package server
+
+import (
+	"net/http"
+	"time"
+)
+
+type Server struct {
+	Addr    string
+	URLPath string
+	timeout time.Duration
+	*http.Client
+	_ int
+}
+
+func (srv *Server) Run() {}
+
+type Client struct {
+	Type string
+	srv  *Server
+}
+`,
+		"shop/shop.go": `package shop
+
+type Shop struct {
+	Name string
+	Open bool
+}
+
+type Cart struct {
+	items []string
+}
+
+type Option func(*Cart)
+
+func Items(items ...string) Option { return nil }
+
+type RetryOptions struct{}
+
+func NewCart() Cart { return Cart{} }
+
+type Color int
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	// The struct named after its package gets New; with one for it, option
+	// and config types drop the struct's name
+	ctor, err := analyzer.GenerateConstructor(ctx, "server.Server", ConstructorOptions{Style: ConstructorWithOptions, Required: []string{"Addr"}})
+	if err != nil {
+		t.Fatalf("GenerateConstructor failed: %v", err)
+	}
+	if ctor.Name != "New" || ctor.OptionType != "Option" || !ctor.Pointer || ctor.Options["URLPath"] != "WithURLPath" {
+		t.Errorf("Unexpected constructor: %+v", ctor)
+	}
+	if len(ctor.Imports) != 2 || ctor.Imports["http"] != "net/http" {
+		t.Errorf("Expected the field types' imports, got %v", ctor.Imports)
+	}
+	for _, snippet := range []string{
+		"// Option configures a Server\ntype Option func(*Server)\n",
+		"func WithURLPath(urlPath string) Option {\n\treturn func(srv *Server) {\n\t\tsrv.URLPath = urlPath\n\t}\n}\n",
+		"func WithTimeout(timeout time.Duration) Option {",
+		"func WithClient(client *http.Client) Option {",
+		"func New(addr string, opts ...Option) *Server {\n\tsrv := &Server{\n\t\tAddr: addr,\n\t}\n\tfor _, opt := range opts {\n\t\topt(srv)\n\t}\n\treturn srv\n}\n",
+	} {
+		if !strings.Contains(ctor.Code, snippet) {
+			t.Errorf("Expected the code to contain %q, got:\n%s", snippet, ctor.Code)
+		}
+	}
+
+	ctor, err = analyzer.GenerateConstructor(ctx, "server.Client", ConstructorOptions{})
+	if err != nil {
+		t.Fatalf("GenerateConstructor failed for Client: %v", err)
+	}
+	want := "// NewClient creates a Client\nfunc NewClient(typ string, srv *Server) *Client {\n\treturn &Client{\n\t\tType: typ,\n\t\tsrv:  srv,\n\t}\n}\n"
+	if ctor.Code != want || ctor.Imports != nil {
+		t.Errorf("Unexpected Client constructor:\n%s", ctor.Code)
+	}
+
+	// Shop follows the value constructors and Options configs of its package
+	ctor, err = analyzer.GenerateConstructor(ctx, "Shop", ConstructorOptions{Style: ConstructorWithConfig, Required: []string{"Name"}})
+	if err != nil {
+		t.Fatalf("GenerateConstructor failed for Shop: %v", err)
+	}
+	if ctor.Name != "New" || ctor.ConfigType != "Options" || ctor.Pointer {
+		t.Errorf("Unexpected Shop constructor: %+v", ctor)
+	}
+	for _, snippet := range []string{
+		"type Options struct {\n\tOpen bool\n}\n",
+		"func New(name string, config Options) Shop {\n\treturn Shop{\n\t\tName: name,\n\t\tOpen: config.Open,\n\t}\n}\n",
+	} {
+		if !strings.Contains(ctor.Code, snippet) {
+			t.Errorf("Expected the code to contain %q, got:\n%s", snippet, ctor.Code)
+		}
+	}
+
+	// The package's option type for Cart is reused, and its functions are
+	// named without With
+	ctor, err = analyzer.GenerateConstructor(ctx, "Cart", ConstructorOptions{Style: ConstructorWithOptions, Name: "MakeCart"})
+	if err != nil {
+		t.Fatalf("GenerateConstructor failed for Cart: %v", err)
+	}
+	if ctor.OptionType != "Option" || strings.Contains(ctor.Code, "type Option") || strings.Contains(ctor.Code, "func Items") || ctor.Options["items"] != "Items" {
+		t.Errorf("Unexpected Cart constructor: %+v", ctor)
+	}
+
+	for _, tc := range []struct {
+		typeName string
+		opts     ConstructorOptions
+	}{
+		{"Cart", ConstructorOptions{}},
+		{"Cart", ConstructorOptions{Style: ConstructorWithOptions}},
+		{"Color", ConstructorOptions{}},
+		{"Shop", ConstructorOptions{Required: []string{"Missing"}}},
+		{"Shop", ConstructorOptions{Style: "builder"}},
+		{"Shop", ConstructorOptions{Style: ConstructorWithOptions, Required: []string{"Name", "Open"}}},
+	} {
+		if _, err := analyzer.GenerateConstructor(ctx, tc.typeName, tc.opts); err == nil {
+			t.Errorf("Expected an error for %s with %+v", tc.typeName, tc.opts)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math"
+	"sort"
+)
+
+// Zones of packages far from the main sequence
+const (
+	// ZonePain holds stable, concrete packages: many packages depend on
+	// them, so their concrete types are hard to change
+	ZonePain = "pain"
+	// ZoneUselessness holds unstable, abstract packages: interfaces nothing
+	// depends on
+	ZoneUselessness = "uselessness"
+)
+
+// zoneDistance is the distance from the main sequence beyond which a
+// package is placed in a zone
+const zoneDistance = 0.5
+
+// PackageMetrics are the coupling and cohesion metrics of a package, after
+// Robert C. Martin's package metrics. Coupling counts the repository's own
+// packages only; the standard library and dependencies are stable and
+// would make every package look unstable.
+type PackageMetrics struct {
+	ImportPath string `json:"import_path"`
+	// Afferent is the number of packages importing this one
+	Afferent int `json:"afferent"`
+	// Efferent is the number of packages this one imports
+	Efferent int `json:"efferent"`
+	// Instability is Efferent / (Afferent + Efferent): 0 for packages only
+	// depended on, 1 for packages only depending on others
+	Instability        float64 `json:"instability"`
+	ExportedTypes      int     `json:"exported_types"`
+	ExportedInterfaces int     `json:"exported_interfaces"`
+	// Abstractness is the share of interfaces among the exported types
+	Abstractness float64 `json:"abstractness"`
+	// Distance is that from the main sequence, |Abstractness + Instability
+	// - 1|, where packages are as abstract as they are stable
+	Distance float64 `json:"distance"`
+	// Zone is ZonePain or ZoneUselessness for packages with a Distance of
+	// at least 0.5
+	Zone string `json:"zone,omitempty"`
+	// Declarations counts the package-level types, functions, variables
+	// and constants, of which Exported are exported
+	Declarations  int     `json:"declarations"`
+	Exported      int     `json:"exported"`
+	ExportedRatio float64 `json:"exported_ratio"`
+	// Cohesion is the relational cohesion (R + 1) / N of the N
+	// declarations with R references among them, methods counting as their
+	// type. Values below 1.5 suggest unrelated declarations sharing a
+	// package, values above 4 a tangle.
+	Cohesion     float64  `json:"cohesion"`
+	Dependents   []string `json:"dependents,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// PackageMetrics computes the coupling and cohesion metrics of the
+// analyzed packages, or of one package when pkg is not empty. Test
+// packages are left out. With excludeGenerated, so are generated files:
+// their declarations, imports and references are not counted, and
+// packages made only of generated files are not reported.
+func (a *Analyzer) PackageMetrics(ctx context.Context, pkg string, excludeGenerated bool) ([]PackageMetrics, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	metrics := a.packageMetrics(excludeGenerated)
+	if pkg == "" {
+		return metrics, nil
+	}
+	var matched []PackageMetrics
+	for _, m := range metrics {
+		if matchesQualifier(pkg, m.ImportPath, a.pkgs[m.ImportPath].Name()) {
+			matched = append(matched, m)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+	return matched, nil
+}
+
+// packageMetrics computes the metrics of every non-test package, leaving
+// generated files out with excludeGenerated. The caller holds a.mu.
+func (a *Analyzer) packageMetrics(excludeGenerated bool) []PackageMetrics {
+	pkgs := a.archPackages()
+	if excludeGenerated {
+		pkgs = a.handWrittenPackages(pkgs)
+	}
+	known := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		known[pkg.importPath] = true
+	}
+	dependents := make(map[string][]string)
+	for _, pkg := range pkgs {
+		for imported := range pkg.imports {
+			if known[imported] {
+				dependents[imported] = append(dependents[imported], pkg.importPath)
+			}
+		}
+	}
+
+	metrics := []PackageMetrics{}
+	for _, pkg := range pkgs {
+		typesPkg := a.pkgs[pkg.importPath]
+		if typesPkg == nil {
+			continue
+		}
+		m := PackageMetrics{ImportPath: pkg.importPath, Dependents: dependents[pkg.importPath]}
+		for imported := range pkg.imports {
+			if known[imported] {
+				m.Dependencies = append(m.Dependencies, imported)
+			}
+		}
+		sort.Strings(m.Dependents)
+		sort.Strings(m.Dependencies)
+		m.Afferent, m.Efferent = len(m.Dependents), len(m.Dependencies)
+		if m.Afferent+m.Efferent > 0 {
+			m.Instability = ratio(m.Efferent, m.Afferent+m.Efferent)
+		}
+
+		scope := typesPkg.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if excludeGenerated && a.generatedPos(obj.Pos()) {
+				continue
+			}
+			m.Declarations++
+			if !obj.Exported() {
+				continue
+			}
+			m.Exported++
+			if typeName, ok := obj.(*types.TypeName); ok {
+				m.ExportedTypes++
+				if types.IsInterface(typeName.Type()) {
+					m.ExportedInterfaces++
+				}
+			}
+		}
+		if m.Declarations > 0 {
+			m.ExportedRatio = ratio(m.Exported, m.Declarations)
+			m.Cohesion = ratio(a.internalReferences(pkg.importPath, excludeGenerated)+1, m.Declarations)
+		}
+		if m.ExportedTypes > 0 {
+			m.Abstractness = ratio(m.ExportedInterfaces, m.ExportedTypes)
+		}
+
+		m.Distance = math.Round(math.Abs(m.Abstractness+m.Instability-1)*100) / 100
+		switch {
+		case m.Distance < zoneDistance:
+		case m.Abstractness+m.Instability < 1:
+			m.Zone = ZonePain
+		default:
+			m.Zone = ZoneUselessness
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// handWrittenPackages returns the packages with their imports counted
+// from their hand-written files only, leaving out the packages made only
+// of generated files
+func (a *Analyzer) handWrittenPackages(pkgs []archPackage) []archPackage {
+	var kept []archPackage
+	for _, pkg := range pkgs {
+		files := a.metricFiles(pkg.importPath, true)
+		if len(files) == 0 {
+			continue
+		}
+		pkg.imports = countImports(pkg.importPath, files)
+		kept = append(kept, pkg)
+	}
+	return kept
+}
+
+// metricFiles returns the syntax trees of a package, without those of
+// generated files with excludeGenerated
+func (a *Analyzer) metricFiles(importPath string, excludeGenerated bool) []*ast.File {
+	if !excludeGenerated {
+		return a.asts[importPath]
+	}
+	var files []*ast.File
+	for _, file := range a.asts[importPath] {
+		if !a.generatedPos(file.Package) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// internalReferences counts the pairs of package-level declarations of a
+// package where one refers to the other, leaving out the declarations of
+// generated files with excludeGenerated. Methods belong to their
+// receiver's type.
+func (a *Analyzer) internalReferences(importPath string, excludeGenerated bool) int {
+	info := a.infos[importPath]
+	pkg := a.pkgs[importPath]
+	if info == nil || pkg == nil {
+		return 0
+	}
+	// owner maps objects to the package-level declaration they belong to
+	owner := func(obj types.Object) types.Object {
+		if obj == nil || obj.Pkg() != pkg || excludeGenerated && a.generatedPos(obj.Pos()) {
+			return nil
+		}
+		if fn, ok := obj.(*types.Func); ok {
+			if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+				if named := receiverNamed(recv.Type()); named != nil {
+					return named.Obj()
+				}
+				return nil
+			}
+		}
+		if obj.Parent() == pkg.Scope() {
+			return obj
+		}
+		return nil
+	}
+
+	pairs := make(map[[2]types.Object]bool)
+	refer := func(from types.Object, node ast.Node) {
+		if from == nil {
+			return
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if to := owner(info.Uses[ident]); to != nil && to != from {
+				pairs[[2]types.Object{from, to}] = true
+			}
+			return true
+		})
+	}
+	for _, file := range a.metricFiles(importPath, excludeGenerated) {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				refer(owner(info.Defs[decl.Name]), decl)
+			case *ast.GenDecl:
+				if decl.Tok == token.IMPORT {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						refer(owner(info.Defs[spec.Name]), spec)
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							refer(owner(info.Defs[name]), spec)
+						}
+					}
+				}
+			}
+		}
+	}
+	return len(pairs)
+}
+
+// ratio divides two counts, rounded to two decimals
+func ratio(n, d int) float64 {
+	return math.Round(float64(n)/float64(d)*100) / 100
+}
@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackageMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"model/model.go": `package model
+
+// Item is sold
+type Item struct{ Price int }
+
+// Total sums prices
+func Total(items []Item) int {
+	sum := 0
+	for _, item := range items {
+		sum += item.Price
+	}
+	return sum
+}
+
+const currency = "EUR"
+`,
+		"store/store.go": `package store
+
+import "example.com/shop/model"
+
+// Store persists items
+type Store interface {
+	Save(model.Item) error
+}
+
+// Finder finds items
+type Finder interface {
+	Find(name string) (model.Item, error)
+}
+`,
+		"cmd/shop/main.go": `package main
+
+import (
+	"example.com/shop/model"
+	"example.com/shop/store"
+)
+
+var s store.Store
+
+func main() {
+	_ = model.Total(nil)
+	run(s)
+}
+
+func run(s store.Store) {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	metrics, err := analyzer.PackageMetrics(ctx, "", false)
+	if err != nil {
+		t.Fatalf("Failed to compute package metrics: %v", err)
+	}
+	byPath := make(map[string]PackageMetrics)
+	for _, m := range metrics {
+		byPath[m.ImportPath] = m
+	}
+	if len(byPath) != 3 {
+		t.Fatalf("Expected 3 packages, got %+v", metrics)
+	}
+
+	model := byPath["example.com/shop/model"]
+	if model.Afferent != 2 || model.Efferent != 0 || model.Instability != 0 || model.Abstractness != 0 || model.Distance != 1 || model.Zone != ZonePain {
+		t.Errorf("Unexpected model metrics: %+v", model)
+	}
+	// Total refers to Item; currency is unrelated
+	if model.Declarations != 3 || model.Exported != 2 || model.ExportedRatio != 0.67 || model.Cohesion != 0.67 {
+		t.Errorf("Unexpected model declarations: %+v", model)
+	}
+	if strings.Join(model.Dependents, ",") != "example.com/shop/cmd/shop,example.com/shop/store" {
+		t.Errorf("Unexpected model dependents: %v", model.Dependents)
+	}
+
+	store := byPath["example.com/shop/store"]
+	if store.Afferent != 1 || store.Efferent != 1 || store.Instability != 0.5 || store.ExportedInterfaces != 2 || store.Abstractness != 1 || store.Distance != 0.5 || store.Zone != ZoneUselessness {
+		t.Errorf("Unexpected store metrics: %+v", store)
+	}
+
+	main := byPath["example.com/shop/cmd/shop"]
+	// main refers to s and run
+	if main.Instability != 1 || main.Distance != 0 || main.Zone != "" || main.Cohesion != 1 {
+		t.Errorf("Unexpected main metrics: %+v", main)
+	}
+
+	result, err := analyzer.AnalyzeRepository(ctx)
+	if err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if len(result.Metrics.Packages) != 3 {
+		t.Errorf("Expected package metrics in the analysis result, got %+v", result.Metrics.Packages)
+	}
+
+	if metrics, err := analyzer.PackageMetrics(ctx, "store", false); err != nil || len(metrics) != 1 {
+		t.Errorf("Expected the metrics of store, got %+v, %v", metrics, err)
+	}
+	if _, err := analyzer.PackageMetrics(ctx, "nosuchpkg", false); err == nil {
+		t.Error("Expected error for an unknown package")
+	}
+}
+
+func TestPackageMetricsExcludeGenerated(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"model/model.go": `package model
+
+// Item is sold
+type Item struct{ Price int }
+
+// Total sums prices
+func Total(items []Item) int { return len(items) }
+`,
+		"model/dto.go": `// Code generated by dtogen. DO NOT EDIT.
+
+package model
+
+type ItemDTO struct{ Price int }
+
+func ToDTO(item Item) ItemDTO { return ItemDTO{Price: item.Price} }
+`,
+		"pb/shop.pb.go": `package pb
+
+import "example.com/shop/model"
+
+type Order struct{ Items []model.Item }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	metrics, err := analyzer.PackageMetrics(ctx, "", false)
+	if err != nil {
+		t.Fatalf("Failed to compute package metrics: %v", err)
+	}
+	if len(metrics) != 2 || metrics[0].ImportPath != "example.com/shop/model" || metrics[0].Declarations != 4 || metrics[0].Afferent != 1 {
+		t.Errorf("Expected generated code to be counted, got %+v", metrics)
+	}
+
+	// Only the hand-written Item and Total remain, and nothing imports model
+	metrics, err = analyzer.PackageMetrics(ctx, "", true)
+	if err != nil {
+		t.Fatalf("Failed to compute package metrics: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("Expected the package of generated files to be left out, got %+v", metrics)
+	}
+	if model := metrics[0]; model.Declarations != 2 || model.Exported != 2 || model.Afferent != 0 || model.Cohesion != 1 {
+		t.Errorf("Expected the metrics of the hand-written files, got %+v", model)
+	}
+}
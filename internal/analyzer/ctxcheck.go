@@ -0,0 +1,402 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Rules of the context check
+const (
+	RuleContextInStruct   = "ctx-in-struct"
+	RuleBackgroundWithCtx = "background-with-ctx"
+	RuleLoopIgnoresCtx    = "loop-ignores-ctx"
+	RuleMissingCtxParam   = "missing-ctx-param"
+)
+
+// blockingCalls are the standard library calls that reach the network, a
+// database or a subprocess while their package offers a variant taking a
+// context, keyed by import path, receiver type and name
+var blockingCalls = map[string]bool{
+	"net.Dial":                   true,
+	"net.DialTimeout":            true,
+	"net.Dialer.Dial":            true,
+	"net.Listen":                 true,
+	"net.ListenPacket":           true,
+	"net.LookupAddr":             true,
+	"net.LookupCNAME":            true,
+	"net.LookupHost":             true,
+	"net.LookupIP":               true,
+	"net.LookupMX":               true,
+	"net.LookupSRV":              true,
+	"net.LookupTXT":              true,
+	"net/http.Get":               true,
+	"net/http.Head":              true,
+	"net/http.Post":              true,
+	"net/http.PostForm":          true,
+	"net/http.NewRequest":        true,
+	"net/http.Client.Get":        true,
+	"net/http.Client.Head":       true,
+	"net/http.Client.Post":       true,
+	"net/http.Client.PostForm":   true,
+	"database/sql.DB.Begin":      true,
+	"database/sql.DB.Exec":       true,
+	"database/sql.DB.Ping":       true,
+	"database/sql.DB.Prepare":    true,
+	"database/sql.DB.Query":      true,
+	"database/sql.DB.QueryRow":   true,
+	"database/sql.Tx.Exec":       true,
+	"database/sql.Tx.Prepare":    true,
+	"database/sql.Tx.Query":      true,
+	"database/sql.Tx.QueryRow":   true,
+	"database/sql.Stmt.Exec":     true,
+	"database/sql.Stmt.Query":    true,
+	"database/sql.Stmt.QueryRow": true,
+	"os/exec.Command":            true,
+}
+
+// ContextReport lists the misuses of context.Context in the analyzed
+// packages
+type ContextReport struct {
+	Issues []ContextIssue `json:"issues"`
+	// Rules counts the issues by rule
+	Rules map[string]int `json:"rules"`
+}
+
+// ContextIssue is a misuse of context.Context
+type ContextIssue struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Function is the function or, for ctx-in-struct, the type, as
+	// pkg.Name or pkg.Type.Method
+	Function string   `json:"function"`
+	Position Position `json:"position"`
+}
+
+// ContextCheck flags context anti-patterns in the packages a qualifier
+// selects, or in every package:
+//
+//   - struct types with a context.Context field
+//   - context.Background or context.TODO in functions receiving a context
+//   - loops in functions receiving a context that make blocking calls but
+//     never look at the context
+//   - exported functions making blocking calls, directly or through the
+//     repository's functions, without a context parameter
+//
+// Blocking calls are those of the standard library that reach the network,
+// a database or a subprocess and have a variant taking a context, such as
+// http.Get or sql.DB.Query. Test files and main packages' missing
+// parameters are left out.
+func (a *Analyzer) ContextCheck(ctx context.Context, pkg string) (*ContextReport, error) {
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	var importPaths []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	if len(importPaths) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	// Blocking functions are found across packages, since call chains cross
+	// them
+	blocking := a.blockingFuncs()
+	report := &ContextReport{Issues: []ContextIssue{}, Rules: make(map[string]int)}
+	for _, importPath := range importPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, a.contextIssues(importPath, blocking)...)
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		pi, pj := report.Issues[i].Position, report.Issues[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+	for _, issue := range report.Issues {
+		report.Rules[issue.Rule]++
+	}
+	return report, nil
+}
+
+// blockingFuncs maps the repository's functions that make a blocking call,
+// directly or through other functions of the repository, to the blocking
+// call they make, or the first function they call that does. The caller
+// holds a.mu.
+func (a *Analyzer) blockingFuncs() map[*types.Func]string {
+	blocking := make(map[*types.Func]string)
+	var funcs []*types.Func
+	callees := make(map[*types.Func][]*types.Func)
+	for _, importPath := range a.sortedPackagePaths() {
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				fn, ok := info.Defs[fd.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				funcs = append(funcs, fn)
+				ast.Inspect(fd.Body, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					callee, ok := calledFunc(info, call).(*types.Func)
+					if !ok {
+						return true
+					}
+					if key := blockingKey(callee); blockingCalls[key] {
+						if _, found := blocking[fn]; !found {
+							blocking[fn] = callee.Pkg().Name() + strings.TrimPrefix(key, callee.Pkg().Path())
+						}
+					} else {
+						callees[fn] = append(callees[fn], callee.Origin())
+					}
+					return true
+				})
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range funcs {
+			if _, ok := blocking[fn]; ok {
+				continue
+			}
+			for _, callee := range callees[fn] {
+				if _, ok := blocking[callee]; ok && callee != fn {
+					blocking[fn] = funcName(callee)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return blocking
+}
+
+// blockingKey names a function as blockingCalls does
+func blockingKey(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return ""
+	}
+	key := fn.Pkg().Path() + "."
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		named := receiverNamed(recv.Type())
+		if named == nil {
+			return ""
+		}
+		key += named.Obj().Name() + "."
+	}
+	return key + fn.Name()
+}
+
+// contextIssues checks the non-test files of one package
+func (a *Analyzer) contextIssues(importPath string, blocking map[*types.Func]string) []ContextIssue {
+	info := a.infos[importPath]
+	pkg := a.pkgs[importPath]
+	if info == nil || pkg == nil {
+		return nil
+	}
+	var issues []ContextIssue
+	issue := func(rule, function string, node ast.Node, format string, args ...any) {
+		issues = append(issues, ContextIssue{
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+			Function: function,
+			Position: a.position(node.Pos()),
+		})
+	}
+
+	for _, file := range a.asts[importPath] {
+		if strings.HasSuffix(a.fset.Position(file.Package).Filename, "_test.go") {
+			continue
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						if !isContextType(info.TypeOf(field.Type)) {
+							continue
+						}
+						name := "embedded"
+						if len(field.Names) > 0 {
+							name = field.Names[0].Name
+						}
+						issue(RuleContextInStruct, pkg.Name()+"."+ts.Name.Name, field,
+							"%s stores a context.Context in field %s; pass the context as the first parameter of the methods that need it", ts.Name.Name, name)
+					}
+				}
+			case *ast.FuncDecl:
+				fn, ok := info.Defs[decl.Name].(*types.Func)
+				if !ok || decl.Body == nil {
+					continue
+				}
+				function := funcName(fn)
+				sig := fn.Type().(*types.Signature)
+				if via, ok := blocking[fn]; ok && fn.Exported() && pkg.Name() != "main" && !hasContextParam(sig) && !ctxCarrier(fn) {
+					if recv := sig.Recv(); recv == nil || receiverNamed(recv.Type()) == nil || receiverNamed(recv.Type()).Obj().Exported() {
+						issue(RuleMissingCtxParam, function, decl.Name,
+							"%s makes blocking calls (%s) without a context.Context parameter, so callers cannot cancel them", decl.Name.Name, via)
+					}
+				}
+				a.checkContextUses(info, decl.Type, decl.Body, nil, false, function, blocking, issue)
+			}
+		}
+	}
+	return issues
+}
+
+// checkContextUses looks for context.Background calls and loops ignoring
+// the context in a function body. ctxVars are the context parameters of
+// the enclosing functions, and inLoop is set inside a flagged loop.
+func (a *Analyzer) checkContextUses(info *types.Info, ftype *ast.FuncType, body *ast.BlockStmt, ctxVars []types.Object, inLoop bool, function string, blocking map[*types.Func]string, issue func(rule, function string, node ast.Node, format string, args ...any)) {
+	for _, field := range ftype.Params.List {
+		for _, name := range field.Names {
+			if obj := info.Defs[name]; obj != nil && isContextType(obj.Type()) {
+				ctxVars = append(ctxVars, obj)
+			}
+		}
+	}
+
+	var inspect func(n ast.Node, inLoop bool) bool
+	inspect = func(n ast.Node, inLoop bool) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			a.checkContextUses(info, n.Type, n.Body, ctxVars, inLoop, function, blocking, issue)
+			return false
+		case *ast.CallExpr:
+			if len(ctxVars) == 0 {
+				break
+			}
+			if fn, ok := calledFunc(info, n).(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == "context" && (fn.Name() == "Background" || fn.Name() == "TODO") {
+				issue(RuleBackgroundWithCtx, function, n,
+					"context.%s() while %s is in scope; pass %s on so that cancellation and deadlines reach the call", fn.Name(), ctxVars[0].Name(), ctxVars[0].Name())
+			}
+		case *ast.ForStmt, *ast.RangeStmt:
+			if len(ctxVars) == 0 || inLoop {
+				break
+			}
+			var loopBody *ast.BlockStmt
+			if f, ok := n.(*ast.ForStmt); ok {
+				loopBody = f.Body
+			} else {
+				loopBody = n.(*ast.RangeStmt).Body
+			}
+			if call := a.blockingCall(info, loopBody, blocking); call != "" && !usesAny(info, loopBody, ctxVars) {
+				issue(RuleLoopIgnoresCtx, function, n,
+					"loop makes blocking calls (%s) without checking %s; check %s.Err() or pass %s on so that cancellation stops it", call, ctxVars[0].Name(), ctxVars[0].Name(), ctxVars[0].Name())
+				ast.Inspect(loopBody, func(child ast.Node) bool { return inspect(child, true) })
+				return false
+			}
+		}
+		return true
+	}
+	ast.Inspect(body, func(n ast.Node) bool { return inspect(n, inLoop) })
+}
+
+// blockingCall returns the first blocking call made in a node, directly or
+// through a function of the repository
+func (a *Analyzer) blockingCall(info *types.Info, node ast.Node, blocking map[*types.Func]string) string {
+	found := ""
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || found != "" {
+			return found == ""
+		}
+		fn, ok := calledFunc(info, call).(*types.Func)
+		if !ok {
+			return true
+		}
+		if blockingCalls[blockingKey(fn)] {
+			found = types.ExprString(call.Fun)
+		} else if _, ok := blocking[fn.Origin()]; ok {
+			found = funcName(fn)
+		}
+		return found == ""
+	})
+	return found
+}
+
+// usesAny reports whether a node refers to one of the objects
+func usesAny(info *types.Info, node ast.Node, objs []types.Object) bool {
+	used := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			for _, obj := range objs {
+				if info.Uses[ident] == obj {
+					used = true
+				}
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+// isContextType reports whether t is context.Context
+func isContextType(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
+
+// hasContextParam reports whether a signature takes a context.Context
+func hasContextParam(sig *types.Signature) bool {
+	for i := range sig.Params().Len() {
+		if isContextType(sig.Params().At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ctxCarrier reports whether a function gets its context another way: from
+// an *http.Request parameter, or as a method whose signature an io
+// interface fixes
+func ctxCarrier(fn *types.Func) bool {
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() != nil {
+		switch fn.Name() {
+		case "Close", "Read", "Write", "ServeHTTP":
+			return true
+		}
+	}
+	for i := range sig.Params().Len() {
+		if ptr, ok := sig.Params().At(i).Type().(*types.Pointer); ok {
+			if named, ok := ptr.Elem().(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http" && named.Obj().Name() == "Request" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContextCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+)
+
+// Store keeps a context it should not
+type Store struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+// Load queries without a context
+func (s *Store) Load(id int) error {
+	_, err := s.db.Query("SELECT 1 WHERE id = ?", id)
+	return err
+}
+
+// Fetch reaches the network through load
+func Fetch(url string) error {
+	return load(url)
+}
+
+func load(url string) error {
+	_, err := http.Get(url)
+	return err
+}
+
+// Sync takes a context but drops it
+func Sync(ctx context.Context, urls []string) error {
+	for _, url := range urls {
+		for range 2 {
+			if err := load(url); err != nil {
+				return err
+			}
+		}
+	}
+	go func() {
+		_ = context.Background()
+	}()
+	return nil
+}
+
+// SyncChecked checks its context in the loop
+func SyncChecked(ctx context.Context, urls []string) error {
+	for _, url := range urls {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := load(url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handle gets its context from the request
+func Handle(w http.ResponseWriter, r *http.Request) {
+	load(r.URL.String())
+}
+
+// Pure makes no blocking calls
+func Pure(ctx context.Context) int {
+	for i := range 3 {
+		_ = i
+	}
+	return 0
+}
+`,
+		"store/store_test.go": `package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	Sync(context.Background(), nil)
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	report, err := analyzer.ContextCheck(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to check contexts: %v", err)
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, fmt.Sprintf("%s:%s:%d", issue.Rule, issue.Function, issue.Position.Line))
+	}
+	want := []string{
+		"ctx-in-struct:store.Store:12",
+		"missing-ctx-param:store.Store.Load:16",
+		"missing-ctx-param:store.Fetch:22",
+		"loop-ignores-ctx:store.Sync:33",
+		"background-with-ctx:store.Sync:41",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Unexpected issues:\n got %v\nwant %v", got, want)
+	}
+	if report.Rules[RuleMissingCtxParam] != 2 || report.Rules[RuleLoopIgnoresCtx] != 1 {
+		t.Errorf("Unexpected rule counts: %v", report.Rules)
+	}
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleMissingCtxParam && issue.Function == "store.Fetch" && !strings.Contains(issue.Message, "store.load") {
+			t.Errorf("Expected Fetch to name the call it blocks through, got %q", issue.Message)
+		}
+		if issue.Rule == RuleMissingCtxParam && issue.Function == "store.Store.Load" && !strings.Contains(issue.Message, "sql.DB.Query") {
+			t.Errorf("Expected Load to name the blocking call, got %q", issue.Message)
+		}
+	}
+
+	if _, err := analyzer.ContextCheck(ctx, "nosuchpkg"); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
@@ -0,0 +1,523 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// FixedSetting is a constant, or a package variable that is never changed,
+// deciding at least one conditional branch
+type FixedSetting struct {
+	Name       string   `json:"name"`
+	ImportPath string   `json:"import_path"`
+	Kind       string   `json:"kind"` // "const" or "var"
+	Value      string   `json:"value"`
+	Position   Position `json:"position"`
+	// DeadBranches is the number of branches the setting makes unreachable
+	DeadBranches int `json:"dead_branches"`
+}
+
+// DeadBranch is a conditional branch that can never execute because its
+// condition always has the same value
+type DeadBranch struct {
+	ImportPath string `json:"import_path"`
+	Function   string `json:"function,omitempty"`
+	// Kind is "if" (the body of an if statement), "else", "case", "default"
+	// or "for"
+	Kind      string `json:"kind"`
+	Condition string `json:"condition"`
+	Value     string `json:"value"`
+	// Settings are the fixed constants and variables the condition depends on
+	Settings []string `json:"settings,omitempty"`
+	Position Position `json:"position"`
+}
+
+// DeadConfigReport lists the fixed settings of the analyzed packages and
+// the branches they make unreachable
+type DeadConfigReport struct {
+	Settings []FixedSetting `json:"settings"`
+	Branches []DeadBranch   `json:"branches"`
+}
+
+// DeadConfig finds conditions that always evaluate to the same value by
+// propagating constants within each package, and reports the branches that
+// can therefore never execute along with the settings responsible. Besides
+// constants, unexported package variables of basic type that are never
+// assigned, incremented or addressed after their declaration count as fixed.
+// String variables are not, since the linker can set them with -ldflags -X.
+// An empty packageName analyzes every package.
+func (a *Analyzer) DeadConfig(ctx context.Context, packageName string) (*DeadConfigReport, error) {
+	if err := a.rlockPackages(ctx, packageName); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	report := &DeadConfigReport{Settings: []FixedSetting{}, Branches: []DeadBranch{}}
+	matched := false
+	counts := make(map[types.Object]int)
+	values := make(map[types.Object]constant.Value)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkg := a.pkgs[importPath]
+		if !matchesQualifier(packageName, importPath, pkg.Name()) {
+			continue
+		}
+		matched = true
+
+		eval := &constEval{pkg: pkg, info: a.infos[importPath], fixed: make(map[*types.Var]constant.Value)}
+		a.findFixedVars(importPath, eval)
+		for v, value := range eval.fixed {
+			values[v] = value
+		}
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				finder := &deadBranchFinder{analyzer: a, eval: eval, importPath: importPath, counts: counts}
+				if fn, ok := decl.(*ast.FuncDecl); ok {
+					finder.function = funcDeclName(fn)
+				}
+				ast.Inspect(decl, finder.visit)
+				report.Branches = append(report.Branches, finder.branches...)
+			}
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("package %s not found", packageName)
+	}
+
+	for obj, count := range counts {
+		setting := FixedSetting{
+			Name:         obj.Name(),
+			ImportPath:   obj.Pkg().Path(),
+			Kind:         "const",
+			Position:     a.position(obj.Pos()),
+			DeadBranches: count,
+		}
+		switch obj := obj.(type) {
+		case *types.Const:
+			setting.Value = obj.Val().ExactString()
+		case *types.Var:
+			setting.Kind = "var"
+			setting.Value = values[obj].ExactString()
+		}
+		report.Settings = append(report.Settings, setting)
+	}
+	sort.Slice(report.Settings, func(i, j int) bool {
+		if report.Settings[i].ImportPath != report.Settings[j].ImportPath {
+			return report.Settings[i].ImportPath < report.Settings[j].ImportPath
+		}
+		return report.Settings[i].Name < report.Settings[j].Name
+	})
+	sort.SliceStable(report.Branches, func(i, j int) bool {
+		pi, pj := report.Branches[i].Position, report.Branches[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+	return report, nil
+}
+
+// position converts a token position into a Position
+func (a *Analyzer) position(pos token.Pos) Position {
+	p := a.fset.Position(pos)
+	if !p.IsValid() {
+		return Position{}
+	}
+	return Position{Filename: p.Filename, Line: p.Line, Column: p.Column, Generated: a.generated[p.Filename]}
+}
+
+// funcDeclName names a function declaration, prefixing methods with their
+// receiver type
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	for {
+		switch t := recv.(type) {
+		case *ast.StarExpr:
+			recv = t.X
+			continue
+		case *ast.IndexExpr:
+			recv = t.X
+			continue
+		case *ast.IndexListExpr:
+			recv = t.X
+			continue
+		}
+		break
+	}
+	return types.ExprString(recv) + "." + fn.Name.Name
+}
+
+// findFixedVars adds the package variables of a package that keep the value
+// of their declaration for the whole program to eval.fixed
+func (a *Analyzer) findFixedVars(importPath string, eval *constEval) {
+	info := a.infos[importPath]
+	changed := make(map[types.Object]bool)
+	mark := func(expr ast.Expr) {
+		if ident, ok := ast.Unparen(expr).(*ast.Ident); ok {
+			changed[info.Uses[ident]] = true
+		}
+	}
+	for _, file := range a.asts[importPath] {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range n.Lhs {
+					mark(lhs)
+				}
+			case *ast.IncDecStmt:
+				mark(n.X)
+			case *ast.UnaryExpr:
+				if n.Op == token.AND {
+					mark(n.X)
+				}
+			case *ast.RangeStmt:
+				if n.Tok == token.ASSIGN {
+					mark(n.Key)
+					mark(n.Value)
+				}
+			}
+			return true
+		})
+	}
+
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					v, ok := info.Defs[name].(*types.Var)
+					if !ok || v.Exported() || changed[v] {
+						continue
+					}
+					basic, ok := v.Type().Underlying().(*types.Basic)
+					if !ok || basic.Info()&types.IsString != 0 {
+						continue
+					}
+					var value constant.Value
+					switch {
+					case len(valueSpec.Values) == len(valueSpec.Names):
+						value = eval.eval(valueSpec.Values[i], map[types.Object]bool{})
+					case len(valueSpec.Values) == 0:
+						value = zeroValue(basic)
+					}
+					if value != nil {
+						eval.fixed[v] = value
+					}
+				}
+			}
+		}
+	}
+}
+
+// zeroValue returns the zero value of a boolean or numeric type
+func zeroValue(basic *types.Basic) constant.Value {
+	switch {
+	case basic.Info()&types.IsBoolean != 0:
+		return constant.MakeBool(false)
+	case basic.Info()&(types.IsInteger|types.IsFloat) != 0:
+		return constant.MakeInt64(0)
+	}
+	return nil
+}
+
+// constEval evaluates expressions over the constants and fixed variables of
+// a single package. Constants of other packages are treated as unknown, so
+// platform constants such as runtime.GOOS never decide a branch.
+type constEval struct {
+	pkg   *types.Package
+	info  *types.Info
+	fixed map[*types.Var]constant.Value
+}
+
+// eval returns the value of expr, or nil when it is not fixed. The named
+// constants and variables the value depends on are added to used.
+func (e *constEval) eval(expr ast.Expr, used map[types.Object]bool) constant.Value {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return e.eval(x.X, used)
+	case *ast.BasicLit:
+		return e.info.Types[x].Value
+	case *ast.Ident:
+		switch obj := e.info.Uses[x].(type) {
+		case *types.Const:
+			if obj.Pkg() == nil {
+				return obj.Val() // true and false
+			}
+			if obj.Pkg() == e.pkg {
+				used[obj] = true
+				return obj.Val()
+			}
+		case *types.Var:
+			if value, ok := e.fixed[obj]; ok {
+				used[obj] = true
+				return value
+			}
+		}
+		return nil
+	case *ast.CallExpr:
+		// Conversions such as Mode(1) keep their operand's value
+		if tv, ok := e.info.Types[x.Fun]; ok && tv.IsType() && len(x.Args) == 1 {
+			return e.eval(x.Args[0], used)
+		}
+		return nil
+	case *ast.UnaryExpr:
+		value := e.eval(x.X, used)
+		if value == nil {
+			return nil
+		}
+		switch {
+		case x.Op == token.NOT && value.Kind() == constant.Bool:
+			return constant.UnaryOp(x.Op, value, 0)
+		case (x.Op == token.SUB || x.Op == token.ADD) && isNumeric(value):
+			return constant.UnaryOp(x.Op, value, 0)
+		}
+		return nil
+	case *ast.BinaryExpr:
+		return e.evalBinary(x, used)
+	}
+	return nil
+}
+
+// evalBinary evaluates a binary expression. && and || are decided by either
+// operand alone when it is false or true respectively.
+func (e *constEval) evalBinary(x *ast.BinaryExpr, used map[types.Object]bool) constant.Value {
+	leftUsed, rightUsed := map[types.Object]bool{}, map[types.Object]bool{}
+	left, right := e.eval(x.X, leftUsed), e.eval(x.Y, rightUsed)
+	merge := func(sets ...map[types.Object]bool) {
+		for _, set := range sets {
+			for obj := range set {
+				used[obj] = true
+			}
+		}
+	}
+
+	if x.Op == token.LAND || x.Op == token.LOR {
+		decisive := x.Op == token.LOR // true decides ||, false decides &&
+		switch {
+		case isBool(left, decisive):
+			merge(leftUsed)
+			return left
+		case isBool(right, decisive):
+			merge(rightUsed)
+			return right
+		case left != nil && right != nil:
+			merge(leftUsed, rightUsed)
+			return right
+		}
+		return nil
+	}
+	if left == nil || right == nil {
+		return nil
+	}
+
+	var value constant.Value
+	switch x.Op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		if comparable(left, right) && (x.Op == token.EQL || x.Op == token.NEQ || left.Kind() != constant.Bool) {
+			value = constant.MakeBool(constant.Compare(left, x.Op, right))
+		}
+	case token.ADD:
+		if (isNumeric(left) && isNumeric(right)) || (left.Kind() == constant.String && right.Kind() == constant.String) {
+			value = constant.BinaryOp(left, x.Op, right)
+		}
+	case token.SUB, token.MUL:
+		if isNumeric(left) && isNumeric(right) {
+			value = constant.BinaryOp(left, x.Op, right)
+		}
+	case token.QUO:
+		if isNumeric(left) && isNumeric(right) && constant.Sign(right) != 0 {
+			op := x.Op
+			if left.Kind() == constant.Int && right.Kind() == constant.Int {
+				op = token.QUO_ASSIGN // Integer division
+			}
+			value = constant.BinaryOp(left, op, right)
+		}
+	case token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		if left.Kind() == constant.Int && right.Kind() == constant.Int && (x.Op != token.REM || constant.Sign(right) != 0) {
+			value = constant.BinaryOp(left, x.Op, right)
+		}
+	}
+	if value != nil {
+		merge(leftUsed, rightUsed)
+	}
+	return value
+}
+
+// isBool reports whether value is the boolean want
+func isBool(value constant.Value, want bool) bool {
+	return value != nil && value.Kind() == constant.Bool && constant.BoolVal(value) == want
+}
+
+// isNumeric reports whether value is an integer or floating-point constant
+func isNumeric(value constant.Value) bool {
+	return value.Kind() == constant.Int || value.Kind() == constant.Float
+}
+
+// comparable reports whether two constants can be compared with each other
+func comparable(x, y constant.Value) bool {
+	return x.Kind() == y.Kind() || (isNumeric(x) && isNumeric(y))
+}
+
+// deadBranchFinder walks a declaration collecting the branches whose
+// conditions are fixed. It does not descend into dead branches, so only the
+// outermost unreachable code is reported.
+type deadBranchFinder struct {
+	analyzer   *Analyzer
+	eval       *constEval
+	importPath string
+	function   string
+	counts     map[types.Object]int
+	branches   []DeadBranch
+}
+
+// visit is the ast.Inspect callback
+func (f *deadBranchFinder) visit(n ast.Node) bool {
+	switch s := n.(type) {
+	case *ast.IfStmt:
+		used := map[types.Object]bool{}
+		value := f.eval.eval(s.Cond, used)
+		if value == nil || value.Kind() != constant.Bool {
+			return true
+		}
+		if s.Init != nil {
+			ast.Inspect(s.Init, f.visit)
+		}
+		if constant.BoolVal(value) {
+			if s.Else != nil {
+				f.report("else", s.Else.Pos(), s.Cond, value, used)
+			}
+			ast.Inspect(s.Body, f.visit)
+		} else {
+			f.report("if", s.Body.Pos(), s.Cond, value, used)
+			if s.Else != nil {
+				ast.Inspect(s.Else, f.visit)
+			}
+		}
+		return false
+	case *ast.ForStmt:
+		if s.Cond == nil {
+			return true
+		}
+		used := map[types.Object]bool{}
+		value := f.eval.eval(s.Cond, used)
+		if !isBool(value, false) {
+			return true
+		}
+		if s.Init != nil {
+			ast.Inspect(s.Init, f.visit)
+		}
+		f.report("for", s.Body.Pos(), s.Cond, value, used)
+		return false
+	case *ast.SwitchStmt:
+		return f.visitSwitch(s)
+	}
+	return true
+}
+
+// visitSwitch reports the case clauses of a switch statement that cannot
+// match its fixed tag, and the default clause when a case always matches
+func (f *deadBranchFinder) visitSwitch(s *ast.SwitchStmt) bool {
+	tagUsed := map[types.Object]bool{}
+	tag := constant.MakeBool(true)
+	if s.Tag != nil {
+		if tag = f.eval.eval(s.Tag, tagUsed); tag == nil {
+			return true
+		}
+	}
+	if s.Init != nil {
+		ast.Inspect(s.Init, f.visit)
+	}
+
+	var defaultClause *ast.CaseClause
+	var matching ast.Expr
+	matchingUsed := map[types.Object]bool{}
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			defaultClause = clause
+			continue
+		}
+
+		dead := true
+		used := map[types.Object]bool{}
+		for obj := range tagUsed {
+			used[obj] = true
+		}
+		for _, expr := range clause.List {
+			value := f.eval.eval(expr, used)
+			if value == nil || !comparable(tag, value) {
+				dead = false
+				break
+			}
+			if constant.Compare(tag, token.EQL, value) {
+				dead = false
+				if matching == nil {
+					matching, matchingUsed = expr, used
+				}
+				break
+			}
+		}
+		if dead {
+			f.report("case", clause.Pos(), caseCondition(s.Tag, clause.List[0]), constant.MakeBool(false), used)
+			continue
+		}
+		for _, body := range clause.Body {
+			ast.Inspect(body, f.visit)
+		}
+	}
+
+	if defaultClause != nil {
+		if matching != nil {
+			f.report("default", defaultClause.Pos(), caseCondition(s.Tag, matching), constant.MakeBool(true), matchingUsed)
+		} else {
+			for _, body := range defaultClause.Body {
+				ast.Inspect(body, f.visit)
+			}
+		}
+	}
+	return false
+}
+
+// caseCondition builds the condition a case expression stands for
+func caseCondition(tag, expr ast.Expr) ast.Expr {
+	if tag == nil {
+		return expr
+	}
+	return &ast.BinaryExpr{X: tag, Op: token.EQL, Y: expr}
+}
+
+// report records a dead branch starting at pos
+func (f *deadBranchFinder) report(kind string, pos token.Pos, cond ast.Expr, value constant.Value, used map[types.Object]bool) {
+	branch := DeadBranch{
+		ImportPath: f.importPath,
+		Function:   f.function,
+		Kind:       kind,
+		Condition:  types.ExprString(cond),
+		Value:      value.ExactString(),
+		Position:   f.analyzer.position(pos),
+	}
+	for obj := range used {
+		branch.Settings = append(branch.Settings, obj.Name())
+		f.counts[obj]++
+	}
+	sort.Strings(branch.Settings)
+	f.branches = append(f.branches, branch)
+}
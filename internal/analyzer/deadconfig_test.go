@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestDeadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"config/config.go": `package config
+
+import "runtime"
+
+const enableCache = false
+
+type Mode int
+
+const (
+	ModeFast Mode = iota
+	ModeSafe
+)
+
+const mode = ModeSafe
+
+var verbose bool
+var retries = 3
+var toggled = false
+var flagged = false
+var version = "dev"
+
+func setup(enabled *bool) {}
+
+func Load() int {
+	toggled = true
+	setup(&flagged)
+
+	if enableCache {
+		return 1
+	}
+	if verbose && retries > 0 {
+		return 2
+	}
+	if retries > 2 {
+		return 3
+	} else {
+		return 4
+	}
+}
+
+func Other() int {
+	switch mode {
+	case ModeFast:
+		return 1
+	case ModeSafe:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func Live() bool {
+	if toggled || flagged {
+		return true
+	}
+	if version == "dev" {
+		return true
+	}
+	if runtime.GOOS == "plan9" {
+		return true
+	}
+	for enableCache {
+		if verbose {
+			return false
+		}
+	}
+	return false
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	report, err := analyzer.DeadConfig(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Failed to find dead configuration: %v", err)
+	}
+
+	type branch struct {
+		function, kind, condition string
+		line                      int
+	}
+	var got []branch
+	for _, b := range report.Branches {
+		got = append(got, branch{b.Function, b.Kind, b.Condition, b.Position.Line})
+	}
+	want := []branch{
+		{"Load", "if", "enableCache", 28},
+		{"Load", "if", "verbose && retries > 0", 31},
+		{"Load", "else", "retries > 2", 36},
+		{"Other", "case", "mode == ModeFast", 43},
+		{"Other", "default", "mode == ModeSafe", 47},
+		{"Live", "for", "enableCache", 62},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected dead branches %v, got %v", want, got)
+	}
+	if settings := report.Branches[1].Settings; !reflect.DeepEqual(settings, []string{"verbose"}) {
+		t.Errorf("Expected the && branch to depend only on verbose, got %v", settings)
+	}
+
+	values := make(map[string]FixedSetting)
+	for _, setting := range report.Settings {
+		values[setting.Name] = setting
+	}
+	if len(values) != 6 {
+		t.Errorf("Expected 6 settings, got %v", report.Settings)
+	}
+	if s := values["enableCache"]; s.Kind != "const" || s.Value != "false" || s.DeadBranches != 2 {
+		t.Errorf("Unexpected enableCache setting: %+v", s)
+	}
+	if s := values["retries"]; s.Kind != "var" || s.Value != "3" || s.DeadBranches != 1 {
+		t.Errorf("Unexpected retries setting: %+v", s)
+	}
+	for _, name := range []string{"toggled", "flagged", "version"} {
+		if _, ok := values[name]; ok {
+			t.Errorf("Expected %s not to be reported as fixed", name)
+		}
+	}
+	if runtime.GOOS != "plan9" {
+		for _, b := range report.Branches {
+			if b.Function == "Live" && b.Kind != "for" {
+				t.Errorf("Expected no dead branch from foreign constants, got %+v", b)
+			}
+		}
+	}
+
+	if _, err := analyzer.DeadConfig(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
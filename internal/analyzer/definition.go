@@ -0,0 +1,310 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// Definition is the declaration an identifier refers to
+type Definition struct {
+	Name string `json:"name"`
+	// Kind is "type", "func", "method", "field", "var", "const", "package",
+	// "label" or "builtin"
+	Kind string `json:"kind"`
+	// ImportPath is the package declaring the object, or the imported
+	// package for package names; empty for builtins
+	ImportPath string `json:"import_path,omitempty"`
+	// Declaration renders the object as in go/types, e.g.
+	// "func (*pkg.Client).Send(msg string) error"
+	Declaration string `json:"declaration"`
+	// Position is nil for builtins and package names
+	Position *Position `json:"position,omitempty"`
+	Doc      string    `json:"doc,omitempty"`
+}
+
+// Definition resolves the identifier at line and column of file, both
+// 1-based with the column counted in bytes like go/token positions, and
+// returns its declaration. The file is relative to the repository or
+// absolute. Dependency declarations resolve when dependency loading is
+// enabled.
+func (a *Analyzer) Definition(ctx context.Context, file string, line, column int) (*Definition, error) {
+	if err := a.rlockFile(ctx, file); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	importPath, astFile := a.fileAt(file)
+	if astFile == nil {
+		return nil, fmt.Errorf("file %s is not part of an analyzed package", file)
+	}
+	tokFile := a.fset.File(astFile.Pos())
+	if line < 1 || line > tokFile.LineCount() {
+		return nil, fmt.Errorf("line %d is outside %s, which has %d lines", line, file, tokFile.LineCount())
+	}
+	if column < 1 {
+		return nil, fmt.Errorf("column must be at least 1")
+	}
+	lineEnd := token.Pos(tokFile.Base() + tokFile.Size())
+	if line < tokFile.LineCount() {
+		lineEnd = tokFile.LineStart(line + 1)
+	}
+	pos := tokFile.LineStart(line) + token.Pos(column-1)
+	if pos >= lineEnd {
+		return nil, fmt.Errorf("column %d is past the end of line %d", column, line)
+	}
+
+	ident := identAt(astFile, pos)
+	if ident == nil {
+		return nil, fmt.Errorf("no identifier at %s:%d:%d", file, line, column)
+	}
+	info := a.infos[importPath]
+	obj := info.Uses[ident]
+	if obj == nil {
+		obj = info.Defs[ident]
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("%s at %s:%d:%d does not refer to a declaration", ident.Name, file, line, column)
+	}
+	return a.definitionOf(obj, a.pkgs[importPath]), nil
+}
+
+// fileAt finds the parsed file with the given path, relative to the
+// repository or absolute, and the import path of its package
+func (a *Analyzer) fileAt(file string) (string, *ast.File) {
+	file = a.absPath(file)
+	for importPath, files := range a.asts {
+		for _, astFile := range files {
+			if a.fset.Position(astFile.Package).Filename == file {
+				return importPath, astFile
+			}
+		}
+	}
+	return "", nil
+}
+
+// absPath resolves a path relative to the repository
+func (a *Analyzer) absPath(file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(a.repoPath, file)
+	}
+	return filepath.Clean(file)
+}
+
+// identAt returns the identifier spanning pos, if any
+func identAt(file *ast.File, pos token.Pos) *ast.Ident {
+	var found *ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || found != nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok {
+			found = ident
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// definitionOf describes obj, qualifying names relative to the package the
+// identifier appeared in
+func (a *Analyzer) definitionOf(obj types.Object, from *types.Package) *Definition {
+	def := &Definition{
+		Name:        obj.Name(),
+		Declaration: types.ObjectString(obj, types.RelativeTo(from)),
+	}
+	if obj.Pkg() != nil {
+		def.ImportPath = obj.Pkg().Path()
+	}
+
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		def.Kind = "type"
+	case *types.Func:
+		def.Kind = "func"
+		if obj.Type().(*types.Signature).Recv() != nil {
+			def.Kind = "method"
+		}
+	case *types.Var:
+		def.Kind = "var"
+		if obj.IsField() {
+			def.Kind = "field"
+		}
+	case *types.Const:
+		def.Kind = "const"
+	case *types.PkgName:
+		def.Kind = "package"
+		def.ImportPath = obj.Imported().Path()
+		if docPkg := a.docPackage(def.ImportPath); docPkg != nil {
+			def.Doc = docPkg.Doc
+		}
+		return def
+	case *types.Label:
+		def.Kind = "label"
+	default:
+		def.Kind = "builtin"
+		def.ImportPath = ""
+		return def
+	}
+
+	// Without dependency loading, imported objects carry positions from
+	// the default importer's file set, which mean nothing in ours
+	if _, local := a.pkgs[def.ImportPath]; local || a.deps != nil {
+		if pos := a.position(obj.Pos()); pos.Filename != "" {
+			def.Position = &pos
+		}
+	}
+	def.Doc = a.declarationDoc(obj)
+	return def
+}
+
+// declarationDoc returns the doc comment of the declaration of obj: from the
+// syntax tree for repository packages, or from the package documentation for
+// package-level dependency declarations and their methods
+func (a *Analyzer) declarationDoc(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	for _, file := range a.asts[obj.Pkg().Path()] {
+		if obj.Pos() < file.Pos() || obj.Pos() >= file.End() {
+			continue
+		}
+		if comment := docAt(file, obj.Pos()); comment != nil {
+			return comment.Text()
+		}
+		return ""
+	}
+
+	docPkg := a.docPackage(obj.Pkg().Path())
+	if docPkg == nil {
+		return ""
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			if named := receiverNamed(recv.Type()); named != nil {
+				return docMethod(docPkg, named.Obj().Name(), fn.Name())
+			}
+			return ""
+		}
+	}
+	return docDecl(docPkg, obj.Name())
+}
+
+// docAt returns the doc comment of the declaration whose name is at pos: a
+// function, a type or value spec, or a field
+func docAt(file *ast.File, pos token.Pos) *ast.CommentGroup {
+	var comment *ast.CommentGroup
+	var gen *ast.GenDecl
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || comment != nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			gen = n
+		case *ast.FuncDecl:
+			if n.Name.Pos() == pos {
+				comment = n.Doc
+				return false
+			}
+		case *ast.TypeSpec:
+			if n.Name.Pos() == pos {
+				comment = specDoc(gen, n)
+				return false
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				if name.Pos() == pos {
+					comment = specDoc(gen, n)
+					return false
+				}
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				if name.Pos() == pos {
+					comment = n.Doc
+					if comment == nil {
+						comment = n.Comment
+					}
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return comment
+}
+
+// receiverNamed returns the named type of a method receiver
+func receiverNamed(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// docDecl finds the documentation of a package-level declaration
+func docDecl(docPkg *doc.Package, name string) string {
+	for _, t := range docPkg.Types {
+		if t.Name == name {
+			return t.Doc
+		}
+		for _, fn := range t.Funcs {
+			if fn.Name == name {
+				return fn.Doc
+			}
+		}
+		if found := docValue(t.Consts, name); found != "" {
+			return found
+		}
+		if found := docValue(t.Vars, name); found != "" {
+			return found
+		}
+	}
+	for _, fn := range docPkg.Funcs {
+		if fn.Name == name {
+			return fn.Doc
+		}
+	}
+	if found := docValue(docPkg.Consts, name); found != "" {
+		return found
+	}
+	return docValue(docPkg.Vars, name)
+}
+
+// docValue finds the documentation of a constant or variable
+func docValue(values []*doc.Value, name string) string {
+	for _, value := range values {
+		for _, valueName := range value.Names {
+			if valueName == name {
+				return value.Doc
+			}
+		}
+	}
+	return ""
+}
+
+// docMethod finds the documentation of a method
+func docMethod(docPkg *doc.Package, typeName, name string) string {
+	for _, t := range docPkg.Types {
+		if t.Name != typeName {
+			continue
+		}
+		for _, method := range t.Methods {
+			if method.Name == name {
+				return method.Doc
+			}
+		}
+	}
+	return ""
+}
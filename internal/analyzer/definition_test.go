@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefinition(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"client/client.go": `package client
+
+// Client talks to a server
+type Client struct {
+	// Addr is the server address
+	Addr string
+}
+
+// Send delivers msg
+func (c *Client) Send(msg string) error { return nil }
+`,
+		"app/app.go": `package app
+
+import "example.com/app/client"
+
+func Run() error {
+	c := &client.Client{Addr: "localhost"}
+	n := len(c.Addr)
+	_ = n
+	return c.Send("hi")
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		line, column int
+		name, kind   string
+		declaration  string
+		doc          string
+		line0        int // Line of the definition; 0 when it has no position
+	}{
+		{6, 15, "Client", "type", "type example.com/app/client.Client struct{Addr string}", "Client talks to a server\n", 4},
+		{6, 23, "Addr", "field", "field Addr string", "Addr is the server address\n", 6},
+		{9, 11, "Send", "method", "func (*example.com/app/client.Client).Send(msg string) error", "Send delivers msg\n", 10},
+		{7, 11, "c", "var", "var c *example.com/app/client.Client", "", 6},
+		{7, 7, "len", "builtin", "builtin len", "", 0},
+		{6, 8, "client", "package", "package client (\"example.com/app/client\")", "", 0},
+	}
+	for _, tt := range tests {
+		def, err := analyzer.Definition(ctx, "app/app.go", tt.line, tt.column)
+		if err != nil {
+			t.Errorf("Definition at %d:%d failed: %v", tt.line, tt.column, err)
+			continue
+		}
+		if def.Name != tt.name || def.Kind != tt.kind || def.Declaration != tt.declaration || def.Doc != tt.doc {
+			t.Errorf("Expected %s %s (%q, doc %q) at %d:%d, got %+v", tt.kind, tt.name, tt.declaration, tt.doc, tt.line, tt.column, def)
+		}
+		switch {
+		case tt.line0 == 0 && def.Position != nil:
+			t.Errorf("Expected no position for %s, got %+v", tt.name, def.Position)
+		case tt.line0 != 0 && (def.Position == nil || def.Position.Line != tt.line0):
+			t.Errorf("Expected %s to be defined on line %d, got %+v", tt.name, tt.line0, def.Position)
+		}
+	}
+
+	// Positions on the definition itself resolve too, with absolute paths
+	def, err := analyzer.Definition(ctx, filepath.Join(tmpDir, "client", "client.go"), 4, 6)
+	if err != nil || def.Name != "Client" || def.Kind != "type" {
+		t.Errorf("Expected the Client definition, got %+v (%v)", def, err)
+	}
+
+	errorCases := []struct {
+		file         string
+		line, column int
+		want         string
+	}{
+		{"app/missing.go", 1, 1, "not part of an analyzed package"},
+		{"app/app.go", 99, 1, "outside"},
+		{"app/app.go", 5, 80, "past the end"},
+		{"app/app.go", 5, 17, "no identifier"},
+	}
+	for _, tt := range errorCases {
+		if _, err := analyzer.Definition(ctx, tt.file, tt.line, tt.column); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %s:%d:%d, got %v", tt.want, tt.file, tt.line, tt.column, err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SnapshotDelta is the difference between two snapshots of a repository.
+// Entries of the result lists are identified by a hash of their content, so
+// a changed type is sent as the removal of its old entry and the addition of
+// the new one. Metrics, errors and warnings are small and sent whole.
+type SnapshotDelta struct {
+	Created   time.Time               `json:"created"`
+	Types     ListDelta[TypeInfo]     `json:"types"`
+	Functions ListDelta[FunctionInfo] `json:"functions"`
+	Variables ListDelta[VariableInfo] `json:"variables"`
+	Constants ListDelta[ConstantInfo] `json:"constants"`
+	Imports   ListDelta[ImportInfo]   `json:"imports"`
+	Packages  ListDelta[PackageInfo]  `json:"packages"`
+	Metrics   AnalysisMetrics         `json:"metrics"`
+	Errors    []AnalysisError         `json:"errors,omitempty"`
+	Warnings  []AnalysisWarning       `json:"warnings,omitempty"`
+	Timestamp time.Time               `json:"timestamp"`
+	Duration  time.Duration           `json:"duration"`
+}
+
+// ListDelta lists the entries removed from and added to one result list
+type ListDelta[T any] struct {
+	Removed []string `json:"removed,omitempty"`
+	Added   []T      `json:"added,omitempty"`
+}
+
+// Empty reports whether the delta changes no entries
+func (d *SnapshotDelta) Empty() bool {
+	return len(d.Types.Removed)+len(d.Types.Added)+
+		len(d.Functions.Removed)+len(d.Functions.Added)+
+		len(d.Variables.Removed)+len(d.Variables.Added)+
+		len(d.Constants.Removed)+len(d.Constants.Added)+
+		len(d.Imports.Removed)+len(d.Imports.Added)+
+		len(d.Packages.Removed)+len(d.Packages.Added) == 0
+}
+
+// DiffSnapshots returns the delta that turns old into new. Both snapshots
+// must use the same path form, e.g. both as written by Analyzer.Snapshot.
+func DiffSnapshots(old, new *Snapshot) (*SnapshotDelta, error) {
+	delta := &SnapshotDelta{
+		Created:   new.Created,
+		Metrics:   new.Result.Metrics,
+		Errors:    new.Result.Errors,
+		Warnings:  new.Result.Warnings,
+		Timestamp: new.Result.Timestamp,
+		Duration:  new.Result.Duration,
+	}
+	var err error
+	if delta.Types, err = diffList(old.Result.Types, new.Result.Types); err != nil {
+		return nil, err
+	}
+	if delta.Functions, err = diffList(old.Result.Functions, new.Result.Functions); err != nil {
+		return nil, err
+	}
+	if delta.Variables, err = diffList(old.Result.Variables, new.Result.Variables); err != nil {
+		return nil, err
+	}
+	if delta.Constants, err = diffList(old.Result.Constants, new.Result.Constants); err != nil {
+		return nil, err
+	}
+	if delta.Imports, err = diffList(old.Result.Imports, new.Result.Imports); err != nil {
+		return nil, err
+	}
+	if delta.Packages, err = diffList(old.Result.Packages, new.Result.Packages); err != nil {
+		return nil, err
+	}
+	return delta, nil
+}
+
+// Apply updates the snapshot in place with a delta produced by
+// DiffSnapshots against it, and rebuilds its type index. The snapshot is
+// left unchanged when the delta does not apply. Added entries are
+// appended, so list order can differ from the snapshot the delta came from.
+func (s *Snapshot) Apply(delta *SnapshotDelta) error {
+	types, err := applyList(s.Result.Types, delta.Types)
+	if err != nil {
+		return fmt.Errorf("failed to apply type changes: %w", err)
+	}
+	functions, err := applyList(s.Result.Functions, delta.Functions)
+	if err != nil {
+		return fmt.Errorf("failed to apply function changes: %w", err)
+	}
+	variables, err := applyList(s.Result.Variables, delta.Variables)
+	if err != nil {
+		return fmt.Errorf("failed to apply variable changes: %w", err)
+	}
+	constants, err := applyList(s.Result.Constants, delta.Constants)
+	if err != nil {
+		return fmt.Errorf("failed to apply constant changes: %w", err)
+	}
+	imports, err := applyList(s.Result.Imports, delta.Imports)
+	if err != nil {
+		return fmt.Errorf("failed to apply import changes: %w", err)
+	}
+	packages, err := applyList(s.Result.Packages, delta.Packages)
+	if err != nil {
+		return fmt.Errorf("failed to apply package changes: %w", err)
+	}
+
+	result := s.Result
+	result.Types, result.Functions, result.Variables = types, functions, variables
+	result.Constants, result.Imports, result.Packages = constants, imports, packages
+	result.Metrics = delta.Metrics
+	result.Errors = delta.Errors
+	result.Warnings = delta.Warnings
+	result.Timestamp = delta.Timestamp
+	result.Duration = delta.Duration
+	s.Created = delta.Created
+
+	s.Index = make(map[string][]int)
+	for i, typeInfo := range result.Types {
+		s.Index[typeInfo.Name] = append(s.Index[typeInfo.Name], i)
+	}
+	return nil
+}
+
+// entryHash identifies a result list entry by its JSON encoding
+func entryHash(entry interface{}) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12]), nil
+}
+
+// diffList compares two lists as multisets of entries
+func diffList[T any](old, new []T) (ListDelta[T], error) {
+	var delta ListDelta[T]
+	remaining := make(map[string]int, len(old))
+	for _, entry := range old {
+		hash, err := entryHash(entry)
+		if err != nil {
+			return delta, err
+		}
+		remaining[hash]++
+	}
+	for _, entry := range new {
+		hash, err := entryHash(entry)
+		if err != nil {
+			return delta, err
+		}
+		if remaining[hash] > 0 {
+			remaining[hash]--
+			continue
+		}
+		delta.Added = append(delta.Added, entry)
+	}
+	// Walk old again so removals come out in a stable order
+	for _, entry := range old {
+		hash, _ := entryHash(entry)
+		if remaining[hash] > 0 {
+			remaining[hash]--
+			delta.Removed = append(delta.Removed, hash)
+		}
+	}
+	return delta, nil
+}
+
+// applyList removes and appends the entries of a delta
+func applyList[T any](list []T, delta ListDelta[T]) ([]T, error) {
+	removed := make(map[string]int, len(delta.Removed))
+	for _, hash := range delta.Removed {
+		removed[hash]++
+	}
+	kept := make([]T, 0, len(list)+len(delta.Added))
+	for _, entry := range list {
+		if len(removed) > 0 {
+			hash, err := entryHash(entry)
+			if err != nil {
+				return nil, err
+			}
+			if removed[hash] > 0 {
+				removed[hash]--
+				if removed[hash] == 0 {
+					delete(removed, hash)
+				}
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	if len(removed) > 0 {
+		return nil, fmt.Errorf("%d removed entries are not in the snapshot", len(removed))
+	}
+	return append(kept, delta.Added...), nil
+}
@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDelta(t *testing.T) {
+	newSnapshot := func(types ...TypeInfo) *Snapshot {
+		return &Snapshot{
+			Version: SchemaVersion,
+			Created: time.Now(),
+			Result:  &AnalysisResult{Types: types},
+		}
+	}
+	record := TypeInfo{Name: "Record", Kind: "struct", Package: "store"}
+	renamed := TypeInfo{Name: "Entry", Kind: "struct", Package: "store"}
+	store := TypeInfo{Name: "Store", Kind: "interface", Package: "store"}
+	changed := store
+	changed.Doc = "Store persists records"
+
+	old := newSnapshot(record, store, store)
+	updated := newSnapshot(renamed, changed, store)
+	updated.Result.Metrics.TotalTypes = 3
+
+	delta, err := DiffSnapshots(old, updated)
+	if err != nil {
+		t.Fatalf("Failed to diff snapshots: %v", err)
+	}
+	if len(delta.Types.Removed) != 2 || len(delta.Types.Added) != 2 {
+		t.Errorf("Expected two removed and two added types, got %+v", delta.Types)
+	}
+	if delta.Empty() {
+		t.Error("Expected a non-empty delta")
+	}
+
+	if err := old.Apply(delta); err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	names := func(types []TypeInfo) []string {
+		var out []string
+		for _, typeInfo := range types {
+			out = append(out, typeInfo.Name+":"+typeInfo.Doc)
+		}
+		sort.Strings(out)
+		return out
+	}
+	if !reflect.DeepEqual(names(old.Result.Types), names(updated.Result.Types)) {
+		t.Errorf("Expected %v after applying, got %v", names(updated.Result.Types), names(old.Result.Types))
+	}
+	if old.Result.Metrics.TotalTypes != 3 {
+		t.Errorf("Expected metrics to be replaced, got %+v", old.Result.Metrics)
+	}
+	if len(old.Index["Entry"]) != 1 || len(old.Index["Record"]) != 0 {
+		t.Errorf("Expected the index to be rebuilt, got %v", old.Index)
+	}
+
+	if again, err := DiffSnapshots(old, updated); err != nil || !again.Empty() {
+		t.Errorf("Expected no changes after applying, got %+v (%v)", again, err)
+	}
+	// The same delta cannot be applied twice
+	if err := old.Apply(delta); err == nil {
+		t.Error("Expected error applying a delta to the wrong snapshot")
+	}
+	if len(old.Result.Types) != 3 {
+		t.Errorf("Expected a failed apply to leave the snapshot unchanged, got %d types", len(old.Result.Types))
+	}
+}
@@ -0,0 +1,401 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+)
+
+// DeprecatedSymbol is a declaration whose doc comment has a "Deprecated:"
+// paragraph, with the places that still use it
+type DeprecatedSymbol struct {
+	// Name is qualified with the package name: pkg.Name, pkg.Type.Method
+	// or pkg.Type.Field
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	Kind       string `json:"kind"` // "type", "func", "method", "field", "var" or "const"
+	Note       string `json:"note"`
+	// Replacement is the symbol the note suggests using instead, if any
+	Replacement string     `json:"replacement,omitempty"`
+	Position    Position   `json:"position"`
+	Callers     []CallSite `json:"callers"`
+}
+
+// CallSite is a use of a deprecated symbol
+type CallSite struct {
+	ImportPath string `json:"import_path"`
+	// Function is the function or method containing the use, as pkg.Func
+	// or pkg.Type.Method; empty at package level
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+}
+
+// MigrationPlan groups the remaining uses of deprecated symbols by the
+// replacement their notes suggest
+type MigrationPlan struct {
+	Groups []MigrationGroup `json:"groups"`
+	// Unused lists deprecated symbols nothing uses any more, which can be deleted
+	Unused []string `json:"unused"`
+	Sites  int      `json:"sites"`
+}
+
+// MigrationGroup is the work of moving to one replacement. Its stages split
+// the call sites by package, so each stage can land on its own.
+type MigrationGroup struct {
+	// Replacement is empty for deprecated symbols whose note names none
+	Replacement string           `json:"replacement,omitempty"`
+	Deprecated  []string         `json:"deprecated"`
+	Sites       int              `json:"sites"`
+	Stages      []MigrationStage `json:"stages"`
+}
+
+// MigrationStage is the call sites of a group within one package
+type MigrationStage struct {
+	ImportPath string     `json:"import_path"`
+	Sites      []CallSite `json:"sites"`
+}
+
+// replacementPattern finds the suggested replacement in a deprecation note,
+// such as "Use NewClient instead" or "replaced by io.ReadAll"
+var replacementPattern = regexp.MustCompile(`(?i)\b(?:use|prefer|replaced by|superseded by)\s+(?:the\s+)?` + "`?" + `((?:\(\*?[A-Za-z_]\w*\)\.)?[A-Za-z_][\w/.-]*\w)`)
+
+// Replacement extracts the replacement suggested by a deprecation note, or
+// returns an empty string
+func Replacement(note string) string {
+	match := replacementPattern.FindStringSubmatch(note)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// deprecation returns the "Deprecated:" paragraph of a doc comment and
+// whether it has one
+func deprecation(doc string) (string, bool) {
+	return docParagraph(doc, "Deprecated:")
+}
+
+// Deprecation returns the deprecation note of the symbol a name resolves
+// to, which may be qualified or Type.Method, and whether it is deprecated
+func (a *Analyzer) Deprecation(ctx context.Context, name string) (string, bool, error) {
+	if err := a.rlockNames(ctx, name); err != nil {
+		return "", false, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return "", false, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(name)
+	if err != nil {
+		var ambiguous *AmbiguousError
+		if errors.As(err, &ambiguous) {
+			return "", false, err
+		}
+		fn, fnErr := a.resolveFunc(name)
+		if fnErr != nil {
+			return "", false, fmt.Errorf("symbol %s not found", name)
+		}
+		obj = fn
+	}
+	note, ok := deprecation(a.declarationDoc(obj))
+	return note, ok, nil
+}
+
+// deprecatedDecl is a deprecated declaration found while scanning packages
+type deprecatedDecl struct {
+	symbol   *DeprecatedSymbol
+	pos, end token.Pos // Extent of the declaration; uses inside it are ignored
+}
+
+// Deprecated lists the deprecated symbols declared in the analyzed packages
+// and their remaining uses. A non-empty pkg restricts the list to symbols
+// declared in matching packages (import path, suffix or package name).
+// Uses inside deprecated declarations are left out, since they go away
+// together with them.
+func (a *Analyzer) Deprecated(ctx context.Context, pkg string) ([]DeprecatedSymbol, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	decls := make(map[types.Object]*deprecatedDecl)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if pkg != "" && !matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			continue
+		}
+		a.collectDeprecated(importPath, decls)
+	}
+	if len(decls) == 0 {
+		return []DeprecatedSymbol{}, nil
+	}
+
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a.collectCallers(importPath, decls)
+	}
+
+	symbols := make([]DeprecatedSymbol, 0, len(decls))
+	for _, decl := range decls {
+		sortCallSites(decl.symbol.Callers)
+		symbols = append(symbols, *decl.symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].ImportPath != symbols[j].ImportPath {
+			return symbols[i].ImportPath < symbols[j].ImportPath
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols, nil
+}
+
+// collectDeprecated records the deprecated declarations of a package
+func (a *Analyzer) collectDeprecated(importPath string, decls map[types.Object]*deprecatedDecl) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	add := func(ident *ast.Ident, doc *ast.CommentGroup, kind, name string, node ast.Node) {
+		note, ok := deprecation(doc.Text())
+		if !ok {
+			return
+		}
+		obj := info.Defs[ident]
+		if obj == nil {
+			return
+		}
+		decls[obj] = &deprecatedDecl{
+			symbol: &DeprecatedSymbol{
+				Name:        name,
+				ImportPath:  importPath,
+				Kind:        kind,
+				Note:        note,
+				Replacement: Replacement(note),
+				Position:    a.position(ident.Pos()),
+				Callers:     []CallSite{},
+			},
+			pos: node.Pos(),
+			end: node.End(),
+		}
+	}
+
+	pkgName := a.pkgs[importPath].Name()
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				fn, ok := info.Defs[decl.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				kind := "func"
+				if decl.Recv != nil {
+					kind = "method"
+				}
+				add(decl.Name, decl.Doc, kind, funcName(fn), decl)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					doc := specDoc(decl, spec)
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						typeName := pkgName + "." + spec.Name.Name
+						add(spec.Name, doc, "type", typeName, spec)
+						collectDeprecatedMembers(spec, typeName, add)
+					case *ast.ValueSpec:
+						kind := "var"
+						if decl.Tok == token.CONST {
+							kind = "const"
+						}
+						for _, name := range spec.Names {
+							add(name, doc, kind, pkgName+"."+name.Name, spec)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// collectDeprecatedMembers records the deprecated fields of a struct type
+// and methods of an interface type
+func collectDeprecatedMembers(spec *ast.TypeSpec, typeName string, add func(*ast.Ident, *ast.CommentGroup, string, string, ast.Node)) {
+	var fields *ast.FieldList
+	kind := "field"
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, "method"
+	default:
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			add(name, field.Doc, kind, typeName+"."+name.Name, field)
+		}
+	}
+}
+
+// specDoc returns the doc comment of a spec, falling back to that of its
+// declaration when the declaration has a single spec
+func specDoc(decl *ast.GenDecl, spec ast.Spec) *ast.CommentGroup {
+	var doc *ast.CommentGroup
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		doc = spec.Doc
+	case *ast.ValueSpec:
+		doc = spec.Doc
+	}
+	if doc == nil && len(decl.Specs) == 1 {
+		doc = decl.Doc
+	}
+	return doc
+}
+
+// collectCallers records the uses of deprecated declarations in a package
+func (a *Analyzer) collectCallers(importPath string, decls map[types.Object]*deprecatedDecl) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			function := ""
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+					function = funcName(fn)
+				}
+			}
+			ast.Inspect(decl, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				target := decls[originOf(info.Uses[ident])]
+				if target == nil || insideDeprecated(ident.Pos(), decls) {
+					return true
+				}
+				target.symbol.Callers = append(target.symbol.Callers, CallSite{
+					ImportPath: importPath,
+					Function:   function,
+					Position:   a.position(ident.Pos()),
+				})
+				return true
+			})
+		}
+	}
+}
+
+// originOf maps an instantiated generic function or field to its declaration
+func originOf(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.Var:
+		return obj.Origin()
+	}
+	return obj
+}
+
+// insideDeprecated reports whether pos lies within a deprecated declaration
+func insideDeprecated(pos token.Pos, decls map[types.Object]*deprecatedDecl) bool {
+	for _, decl := range decls {
+		if decl.pos <= pos && pos < decl.end {
+			return true
+		}
+	}
+	return false
+}
+
+// sortCallSites orders call sites by file, line and column
+func sortCallSites(sites []CallSite) {
+	sort.Slice(sites, func(i, j int) bool {
+		pi, pj := sites[i].Position, sites[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Column < pj.Column
+	})
+}
+
+// PlanMigration groups the uses of the deprecated symbols selected like
+// Deprecated by the replacement their notes suggest, splitting each group
+// into per-package stages. Groups with the fewest call sites come first, as
+// the quickest to finish; symbols without a suggested replacement come last.
+func (a *Analyzer) PlanMigration(ctx context.Context, pkg string) (*MigrationPlan, error) {
+	symbols, err := a.Deprecated(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{Groups: []MigrationGroup{}, Unused: []string{}}
+	groups := make(map[string]*MigrationGroup)
+	var order []string
+	for _, symbol := range symbols {
+		if len(symbol.Callers) == 0 {
+			plan.Unused = append(plan.Unused, symbol.Name)
+			continue
+		}
+		group := groups[symbol.Replacement]
+		if group == nil {
+			group = &MigrationGroup{Replacement: symbol.Replacement}
+			groups[symbol.Replacement] = group
+			order = append(order, symbol.Replacement)
+		}
+		group.Deprecated = append(group.Deprecated, symbol.Name)
+		for _, site := range symbol.Callers {
+			group.addSite(site)
+		}
+		plan.Sites += len(symbol.Callers)
+	}
+
+	for _, replacement := range order {
+		group := groups[replacement]
+		sort.Slice(group.Stages, func(i, j int) bool { return group.Stages[i].ImportPath < group.Stages[j].ImportPath })
+		for _, stage := range group.Stages {
+			sortCallSites(stage.Sites)
+		}
+		plan.Groups = append(plan.Groups, *group)
+	}
+	sort.SliceStable(plan.Groups, func(i, j int) bool {
+		gi, gj := plan.Groups[i], plan.Groups[j]
+		if (gi.Replacement == "") != (gj.Replacement == "") {
+			return gj.Replacement == ""
+		}
+		if gi.Sites != gj.Sites {
+			return gi.Sites < gj.Sites
+		}
+		return gi.Replacement < gj.Replacement
+	})
+	return plan, nil
+}
+
+// addSite adds a call site to the stage of its package
+func (g *MigrationGroup) addSite(site CallSite) {
+	g.Sites++
+	for i := range g.Stages {
+		if g.Stages[i].ImportPath == site.ImportPath {
+			g.Stages[i].Sites = append(g.Stages[i].Sites, site)
+			return
+		}
+	}
+	g.Stages = append(g.Stages, MigrationStage{ImportPath: site.ImportPath, Sites: []CallSite{site}})
+}
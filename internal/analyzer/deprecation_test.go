@@ -0,0 +1,254 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeprecated(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"client/client.go": `package client
+
+// Client talks to the server
+type Client struct {
+	// Timeout is in seconds.
+	//
+	// Deprecated: Use Deadline instead.
+	Timeout  int
+	Deadline int
+}
+
+// Dial connects.
+//
+// Deprecated: use NewClient instead.
+func Dial() *Client { return NewClient() }
+
+// NewClient connects
+func NewClient() *Client { return &Client{} }
+
+// Connect connects.
+//
+// Deprecated: replaced by NewClient.
+func Connect() *Client { return Dial() }
+
+// Legacy is kept for old callers.
+//
+// Deprecated: nothing replaces it.
+const Legacy = 1
+
+// Unused is not used anywhere.
+//
+// Deprecated: Use Legacy.
+var Unused = 2
+`,
+		"app/app.go": `package app
+
+import "example.com/app/client"
+
+var level = client.Legacy
+
+func Run() {
+	c := client.Dial()
+	c.Timeout = 5
+	_ = client.Connect()
+}
+
+func Stop() {
+	client.Dial()
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	symbols, err := a.Deprecated(context.Background(), "client")
+	if err != nil {
+		t.Fatalf("Failed to list deprecated symbols: %v", err)
+	}
+	type summary struct {
+		kind, replacement string
+		callers           []string
+	}
+	got := make(map[string]summary)
+	for _, symbol := range symbols {
+		var callers []string
+		for _, site := range symbol.Callers {
+			callers = append(callers, site.Function)
+		}
+		got[symbol.Name] = summary{symbol.Kind, symbol.Replacement, callers}
+	}
+	want := map[string]summary{
+		// Connect's call of Dial is left out since Connect is deprecated too
+		"client.Dial":           {"func", "NewClient", []string{"app.Run", "app.Stop"}},
+		"client.Connect":        {"func", "NewClient", []string{"app.Run"}},
+		"client.Client.Timeout": {"field", "Deadline", []string{"app.Run"}},
+		"client.Legacy":         {"const", "", []string{""}},
+		"client.Unused":         {"var", "Legacy", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected deprecated symbols\n%v\ngot\n%v", want, got)
+	}
+
+	if others, err := a.Deprecated(context.Background(), "app"); err != nil || len(others) != 0 {
+		t.Errorf("Expected no deprecated symbols in app, got %v, %v", others, err)
+	}
+
+	plan, err := a.PlanMigration(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if plan.Sites != 5 || !reflect.DeepEqual(plan.Unused, []string{"client.Unused"}) {
+		t.Errorf("Expected 5 sites and client.Unused unused, got %d and %v", plan.Sites, plan.Unused)
+	}
+	var order []string
+	for _, group := range plan.Groups {
+		order = append(order, group.Replacement)
+	}
+	if !reflect.DeepEqual(order, []string{"Deadline", "NewClient", ""}) {
+		t.Errorf("Expected groups ordered by size with unnamed replacements last, got %q", order)
+	}
+	newClient := plan.Groups[1]
+	if newClient.Sites != 3 || len(newClient.Stages) != 1 || newClient.Stages[0].ImportPath != "example.com/app/app" {
+		t.Errorf("Expected 3 NewClient sites in one app stage, got %+v", newClient)
+	}
+}
+
+func TestDeprecationMarkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+// Store keeps values.
+//
+// Deprecated: Use Cache instead.
+type Store struct{}
+
+// Get returns a value.
+//
+// Deprecated: use Cache.Get.
+func (s *Store) Get(key string) string { return "" }
+
+// Put stores a value
+func (s *Store) Put(key, value string) {}
+
+// Open opens a store.
+//
+// Deprecated: use NewCache.
+func Open() *Store { return &Store{} }
+
+// Getter gets values
+type Getter interface {
+	// Fetch gets a value.
+	//
+	// Deprecated: use Get.
+	Fetch(key string) string
+	Get(key string) string
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		a, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer a.Close()
+		ctx := context.Background()
+
+		store, err := a.LookupType(ctx, "Store")
+		if err != nil {
+			t.Fatalf("Failed to look up Store (lazy %v): %v", lazy, err)
+		}
+		if !store.Deprecated || store.DeprecationNote != "Use Cache instead." {
+			t.Errorf("Expected Store to be deprecated, got %v %q", store.Deprecated, store.DeprecationNote)
+		}
+		methods := make(map[string]string)
+		for _, method := range store.Methods {
+			if method.Deprecated {
+				methods[method.Name] = method.DeprecationNote
+			}
+		}
+		if want := map[string]string{"Get": "use Cache.Get."}; !reflect.DeepEqual(methods, want) {
+			t.Errorf("Expected deprecated methods %v, got %v", want, methods)
+		}
+
+		getter, err := a.LookupType(ctx, "Getter")
+		if err != nil {
+			t.Fatalf("Failed to look up Getter: %v", err)
+		}
+		if getter.Deprecated || len(getter.Methods) != 2 || !getter.Methods[0].Deprecated || getter.Methods[1].Deprecated {
+			t.Errorf("Expected only Getter.Fetch to be deprecated, got %+v", getter)
+		}
+
+		note, deprecated, err := a.Deprecation(ctx, "Open")
+		if err != nil || !deprecated || note != "use NewCache." {
+			t.Errorf("Expected Open to be deprecated, got %v %q %v", deprecated, note, err)
+		}
+		if _, deprecated, err := a.Deprecation(ctx, "Store.Put"); err != nil || deprecated {
+			t.Errorf("Expected Store.Put not to be deprecated, got %v %v", deprecated, err)
+		}
+		if _, _, err := a.Deprecation(ctx, "Missing"); err == nil {
+			t.Error("Expected an error for an unknown symbol")
+		}
+	}
+
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+	result, err := a.AnalyzeRepository(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	for _, fn := range result.Functions {
+		if fn.Name == "Open" && (!fn.Deprecated || fn.DeprecationNote != "use NewCache.") {
+			t.Errorf("Expected Open to be deprecated, got %+v", fn)
+		}
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	tests := map[string]string{
+		"Use io.ReadAll instead.":                        "io.ReadAll",
+		"use the `NewReader` function":                   "NewReader",
+		"Replaced by golang.org/x/text/cases.":           "golang.org/x/text/cases",
+		"Prefer (*Client).Do.":                           "(*Client).Do",
+		"As of Go 1.16, this function simply calls Foo.": "",
+	}
+	for note, want := range tests {
+		if got := Replacement(note); got != want {
+			t.Errorf("Expected replacement %q for %q, got %q", want, note, got)
+		}
+	}
+}
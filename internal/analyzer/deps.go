@@ -0,0 +1,339 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// depLoader loads packages from outside the repository, the standard library
+// and modules in GOMODCACHE, on demand. Types come from the compiler's export
+// data, which `go list -export` builds through the build cache, and
+// documentation is parsed from the package sources.
+type depLoader struct {
+	dir      string
+	fset     *token.FileSet
+	importer types.Importer
+
+	mu       sync.Mutex
+	listed   map[string]*listedPackage
+	pkgs     map[string]*types.Package
+	docPkgs  map[string]*doc.Package
+	failures map[string]error
+}
+
+// listedPackage is the subset of `go list -json` output the loader needs
+type listedPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	Error      *struct {
+		Err string
+	}
+}
+
+// newDepLoader creates a loader that resolves import paths relative to the
+// module containing dir, so the versions selected by its go.mod are used
+func newDepLoader(dir string, fset *token.FileSet) *depLoader {
+	d := &depLoader{
+		dir:      dir,
+		fset:     fset,
+		listed:   make(map[string]*listedPackage),
+		pkgs:     make(map[string]*types.Package),
+		docPkgs:  make(map[string]*doc.Package),
+		failures: make(map[string]error),
+	}
+	d.importer = importer.ForCompiler(fset, "gc", d.openExport)
+	return d
+}
+
+// Import implements types.Importer using export data
+func (d *depLoader) Import(path string) (*types.Package, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.importLocked(path)
+}
+
+func (d *depLoader) importLocked(path string) (*types.Package, error) {
+	if pkg, ok := d.pkgs[path]; ok {
+		return pkg, nil
+	}
+	if err, ok := d.failures[path]; ok {
+		return nil, err
+	}
+
+	pkg, err := d.importer.Import(path)
+	if err != nil {
+		d.failures[path] = err
+		return nil, err
+	}
+	d.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// Load imports a package and parses its documentation
+func (d *depLoader) Load(path string) (*types.Package, *doc.Package, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pkg, err := d.importLocked(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if docPkg, ok := d.docPkgs[path]; ok {
+		return pkg, docPkg, nil
+	}
+
+	// Documentation is best effort: types are still useful without it
+	var docPkg *doc.Package
+	if listed := d.listed[path]; listed != nil && listed.Dir != "" {
+		docPkg = parseDocs(listed)
+	}
+	d.docPkgs[path] = docPkg
+	return pkg, docPkg, nil
+}
+
+// Loaded returns a package that has already been imported
+func (d *depLoader) Loaded(path string) (*types.Package, *doc.Package) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pkgs[path], d.docPkgs[path]
+}
+
+// Name returns the package name go list reported for path, or "" when the
+// path has not been listed
+func (d *depLoader) Name(path string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if listed := d.listed[path]; listed != nil {
+		return listed.Name
+	}
+	return ""
+}
+
+// Prefetch lists the given packages and their dependencies with a single
+// `go list` run, so later imports do not each start a process
+func (d *depLoader) Prefetch(ctx context.Context, paths []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var missing []string
+	for _, path := range paths {
+		if _, ok := d.listed[path]; !ok && path != "unsafe" && path != "C" {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return d.list(ctx, append([]string{"-deps"}, missing...)...)
+}
+
+// openExport is the lookup function of the export data importer
+func (d *depLoader) openExport(path string) (io.ReadCloser, error) {
+	listed, ok := d.listed[path]
+	if !ok {
+		// The importer offers no way to pass the context of the query
+		if err := d.list(context.Background(), path); err != nil {
+			return nil, err
+		}
+		listed = d.listed[path]
+	}
+	if listed == nil {
+		return nil, fmt.Errorf("package %s not found", path)
+	}
+	if listed.Error != nil && listed.Export == "" {
+		return nil, fmt.Errorf("package %s: %s", path, listed.Error.Err)
+	}
+	if listed.Export == "" {
+		return nil, fmt.Errorf("no export data for package %s", path)
+	}
+	return os.Open(listed.Export)
+}
+
+// list runs `go list -e -export -json` with args and records the results.
+// The caller must hold d.mu.
+func (d *depLoader) list(ctx context.Context, args ...string) error {
+	cmdArgs := append([]string{"list", "-e", "-export", "-json=ImportPath,Name,Dir,GoFiles,Export,Error"}, args...)
+	cmd := exec.CommandContext(ctx, "go", cmdArgs...)
+	cmd.Dir = d.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pkg listedPackage
+		if err := decoder.Decode(&pkg); err != nil {
+			return fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		d.listed[pkg.ImportPath] = &pkg
+	}
+	return nil
+}
+
+// parseDocs parses the non-test sources of a listed package for documentation
+func parseDocs(listed *listedPackage) *doc.Package {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range listed.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(listed.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	docPkg, err := doc.NewFromFiles(fset, files, listed.ImportPath)
+	if err != nil {
+		return nil
+	}
+	return docPkg
+}
+
+// externalImports returns the import paths the repository imports from
+// outside itself, each with the local names (aliases) it is imported under
+func (a *Analyzer) externalImports() map[string][]string {
+	imports := make(map[string][]string)
+	for _, files := range a.asts {
+		for _, file := range files {
+			for _, spec := range file.Imports {
+				path, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || path == "C" || path == "unsafe" {
+					continue
+				}
+				if _, ok := a.files[path]; ok {
+					continue
+				}
+				aliases := imports[path]
+				if spec.Name != nil && spec.Name.Name != "_" && spec.Name.Name != "." {
+					aliases = append(aliases, spec.Name.Name)
+				}
+				imports[path] = aliases
+			}
+		}
+	}
+	return imports
+}
+
+// ExternalPackages returns the import paths outside the repository a
+// qualifier may name: the qualifier itself when it looks like a module
+// import path, and the packages the repository imports under it as an
+// alias, by their guessed package name or by an import path suffix
+func (a *Analyzer) ExternalPackages(ctx context.Context, qualifier string) ([]string, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	candidates := make(map[string]bool)
+	if first, _, _ := strings.Cut(qualifier, "/"); strings.Contains(first, ".") {
+		candidates[qualifier] = true
+	}
+	for path, aliases := range a.externalImports() {
+		if matchesQualifier(qualifier, path, guessPackageName(path)) {
+			candidates[path] = true
+		}
+		for _, alias := range aliases {
+			if alias == qualifier {
+				candidates[path] = true
+			}
+		}
+	}
+	paths := make([]string, 0, len(candidates))
+	for path := range candidates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// guessPackageName guesses the name of a package from its import path, as
+// goimports does: the last element other than a major version suffix,
+// without a go- prefix and cut at the first character an identifier cannot
+// hold, so that gopkg.in/yaml.v3 gives yaml
+func guessPackageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// resolveDependency looks ident up in the dependency packages selected by
+// qualifier: the qualifier as an import path ("net/http",
+// "github.com/user/mod/pkg"), or a package the repository imports whose
+// name, alias, or import path suffix matches it
+func (a *Analyzer) resolveDependency(qualifier, ident string) ([]string, []types.Object) {
+	candidates := map[string]bool{qualifier: true}
+	for path, aliases := range a.externalImports() {
+		if matchesQualifier(qualifier, path, a.deps.Name(path)) {
+			candidates[path] = true
+		}
+		for _, alias := range aliases {
+			if alias == qualifier {
+				candidates[path] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(candidates))
+	for path := range candidates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var matches []string
+	var objects []types.Object
+	for _, path := range paths {
+		pkg, _, err := a.deps.Load(path)
+		if err != nil {
+			continue
+		}
+		if obj := pkg.Scope().Lookup(ident); obj != nil {
+			matches = append(matches, path)
+			objects = append(objects, obj)
+		}
+	}
+	return matches, objects
+}
+
+// docPackage returns the documentation for a repository or dependency package
+func (a *Analyzer) docPackage(importPath string) *doc.Package {
+	if docPkg := a.docPkgs[importPath]; docPkg != nil {
+		return docPkg
+	}
+	if a.deps != nil {
+		_, docPkg := a.deps.Loaded(importPath)
+		return docPkg
+	}
+	return nil
+}
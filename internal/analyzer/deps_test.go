@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDependencies(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/deps\n\ngo 1.21\n",
+		"server/server.go": `package server
+
+import (
+	"context"
+	stdhttp "net/http"
+)
+
+type Server struct {
+	Handler stdhttp.Handler
+}
+
+func (s *Server) Run(ctx context.Context) error { return ctx.Err() }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.LoadDependencies = true
+	a, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	t.Run("ImportPath", func(t *testing.T) {
+		info, err := a.LookupType(context.Background(), "context.Context")
+		if err != nil {
+			t.Fatalf("Failed to look up context.Context: %v", err)
+		}
+		if info.ImportPath != "context" || info.Kind != "interface" {
+			t.Errorf("Expected interface in context, got %s in %s", info.Kind, info.ImportPath)
+		}
+		if info.Doc == "" {
+			t.Error("Expected documentation for context.Context")
+		}
+		if len(info.Methods) != 4 {
+			t.Errorf("Expected 4 methods, got %d", len(info.Methods))
+		}
+	})
+
+	t.Run("Alias", func(t *testing.T) {
+		info, err := a.LookupType(context.Background(), "stdhttp.Request")
+		if err != nil {
+			t.Fatalf("Failed to look up stdhttp.Request: %v", err)
+		}
+		if info.ImportPath != "net/http" {
+			t.Errorf("Expected net/http, got %s", info.ImportPath)
+		}
+	})
+
+	t.Run("PackageName", func(t *testing.T) {
+		info, err := a.LookupType(context.Background(), "http.Handler")
+		if err != nil {
+			t.Fatalf("Failed to look up http.Handler: %v", err)
+		}
+		if info.ImportPath != "net/http" {
+			t.Errorf("Expected net/http, got %s", info.ImportPath)
+		}
+	})
+
+	t.Run("RepositoryFirst", func(t *testing.T) {
+		info, err := a.LookupType(context.Background(), "Server")
+		if err != nil {
+			t.Fatalf("Failed to look up Server: %v", err)
+		}
+		if len(info.Fields) != 1 || info.Fields[0].Type != "net/http.Handler" {
+			t.Errorf("Expected Handler field of type net/http.Handler, got %+v", info.Fields)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := a.LookupType(context.Background(), "context.Missing")
+		var ambiguous *AmbiguousError
+		if err == nil || errors.As(err, &ambiguous) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestDependenciesDisabled(t *testing.T) {
+	a, err := NewAnalyzer(".")
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	if _, err := a.LookupType(context.Background(), "context.Context"); err == nil {
+		t.Error("Expected context.Context to be unresolved without LoadDependencies")
+	}
+}
+
+func TestExternalPackages(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"app.go": `package app
+
+import (
+	yaml "gopkg.in/yaml.v3"
+	cmp "github.com/google/go-cmp/cmp"
+	"github.com/org/client/v2"
+)
+
+var _ = yaml.Marshal
+var _ = cmp.Equal
+var _ = client.New
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"yaml":                     "gopkg.in/yaml.v3",
+		"client":                   "github.com/org/client/v2",
+		"cmp":                      "github.com/google/go-cmp/cmp",
+		"example.com/other/pkg":    "example.com/other/pkg",
+		"github.com/org/client/v2": "github.com/org/client/v2",
+		"unknown":                  "",
+	}
+	for qualifier, want := range tests {
+		paths, err := a.ExternalPackages(ctx, qualifier)
+		if err != nil {
+			t.Fatalf("ExternalPackages(%s) failed: %v", qualifier, err)
+		}
+		if got := strings.Join(paths, " "); got != want {
+			t.Errorf("ExternalPackages(%s) = %q, want %q", qualifier, got, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DependencyUsage is everything the repository uses from an imported
+// module or package, for planning a migration away from it
+type DependencyUsage struct {
+	// Dependency is the module or package path asked for
+	Dependency string `json:"dependency"`
+	// Packages are the imported packages of the dependency
+	Packages []string           `json:"packages"`
+	Imports  []DependencyImport `json:"imports"`
+	Symbols  []DependencySymbol `json:"symbols"`
+	Uses     int                `json:"uses"`
+	Files    int                `json:"files"`
+}
+
+// DependencyImport is an import of a package of the dependency by a file
+// of the repository
+type DependencyImport struct {
+	// ImportPath is the importing package
+	ImportPath string `json:"import_path"`
+	// Path is the imported package
+	Path string `json:"path"`
+	// Name is the import's explicit name: an alias, "_" or "."
+	Name     string   `json:"name,omitempty"`
+	Position Position `json:"position"`
+}
+
+// DependencySymbol is a symbol of the dependency and the places using it
+type DependencySymbol struct {
+	// Name is qualified with the package name: pkg.Name, pkg.Type.Method
+	// or pkg.Type.Field
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Kind is "type", "func", "method", "field", "var" or "const"; empty
+	// when the dependency's types are not loaded, so uses are only found
+	// by their syntax
+	Kind string     `json:"kind,omitempty"`
+	Uses []CallSite `json:"uses"`
+}
+
+// DependencyUsage lists the symbols of a dependency the repository uses and
+// where. The dependency is an import path, covering the packages below it
+// as a module path does. Uses are found through type information, which
+// also finds the methods and fields of the dependency's types reached
+// through values; where the dependency's types cannot be loaded, qualified
+// identifiers such as errors.Wrap are matched by their syntax.
+func (a *Analyzer) DependencyUsage(ctx context.Context, dependency string) (*DependencyUsage, error) {
+	dependency = strings.TrimSuffix(strings.TrimSpace(dependency), "/")
+	if dependency == "" {
+		return nil, fmt.Errorf("dependency is required")
+	}
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	matches := func(importPath string) bool {
+		return importPath == dependency || strings.HasPrefix(importPath, dependency+"/")
+	}
+	usage := &DependencyUsage{
+		Dependency: dependency,
+		Imports:    []DependencyImport{},
+		Symbols:    []DependencySymbol{},
+	}
+	symbols := make(map[string]*DependencySymbol)
+	packages := make(map[string]bool)
+	files := make(map[string]bool)
+	owners := make(map[*types.Package]map[*types.Var]string)
+	record := func(sym DependencySymbol, site CallSite) {
+		key := sym.ImportPath + " " + sym.Name
+		existing := symbols[key]
+		if existing == nil {
+			sym.Uses = []CallSite{}
+			existing = &sym
+			symbols[key] = existing
+		}
+		existing.Uses = append(existing.Uses, site)
+		files[site.Position.Filename] = true
+	}
+
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if matches(importPath) {
+			// The dependency is part of the repository
+			continue
+		}
+		info := a.infos[importPath]
+		for _, file := range a.asts[importPath] {
+			imported := false
+			for _, spec := range file.Imports {
+				specPath, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || !matches(specPath) {
+					continue
+				}
+				imported = true
+				packages[specPath] = true
+				imp := DependencyImport{ImportPath: importPath, Path: specPath, Position: a.position(spec.Pos())}
+				if spec.Name != nil {
+					imp.Name = spec.Name.Name
+				}
+				usage.Imports = append(usage.Imports, imp)
+				files[imp.Position.Filename] = true
+			}
+			// Files that do not import the dependency may still use it
+			// through values of its types
+			if info == nil && !imported {
+				continue
+			}
+			for _, decl := range file.Decls {
+				function := ""
+				if fd, ok := decl.(*ast.FuncDecl); ok && info != nil {
+					if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+						function = funcName(fn)
+					}
+				}
+				ast.Inspect(decl, func(n ast.Node) bool {
+					if info == nil {
+						if sel, ok := n.(*ast.SelectorExpr); ok {
+							if sym, ok := syntacticUse(file, sel, matches); ok {
+								record(sym, CallSite{ImportPath: importPath, Function: function, Position: a.position(sel.Sel.Pos())})
+							}
+						}
+						return true
+					}
+					switch n := n.(type) {
+					case *ast.SelectorExpr:
+						// A selector on a package whose types failed to load
+						x, ok := n.X.(*ast.Ident)
+						if !ok || info.Uses[n.Sel] != nil {
+							return true
+						}
+						pkgName, ok := info.Uses[x].(*types.PkgName)
+						if !ok || !matches(pkgName.Imported().Path()) {
+							return true
+						}
+						record(DependencySymbol{
+							Name:       pkgName.Imported().Name() + "." + n.Sel.Name,
+							ImportPath: pkgName.Imported().Path(),
+						}, CallSite{ImportPath: importPath, Function: function, Position: a.position(n.Sel.Pos())})
+					case *ast.Ident:
+						obj := originOf(info.Uses[n])
+						if obj == nil || obj.Pkg() == nil || !matches(obj.Pkg().Path()) {
+							return true
+						}
+						if _, ok := obj.(*types.PkgName); ok {
+							return true
+						}
+						if _, ok := obj.(*types.Label); ok {
+							return true
+						}
+						record(dependencySymbol(obj, owners), CallSite{ImportPath: importPath, Function: function, Position: a.position(n.Pos())})
+					}
+					return true
+				})
+			}
+		}
+	}
+	if len(usage.Imports) == 0 && len(symbols) == 0 {
+		return nil, fmt.Errorf("no package of the repository imports %s", dependency)
+	}
+
+	for importPath := range packages {
+		usage.Packages = append(usage.Packages, importPath)
+	}
+	sort.Strings(usage.Packages)
+	sort.Slice(usage.Imports, func(i, j int) bool {
+		pi, pj := usage.Imports[i].Position, usage.Imports[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+	for _, sym := range symbols {
+		sortCallSites(sym.Uses)
+		usage.Uses += len(sym.Uses)
+		usage.Symbols = append(usage.Symbols, *sym)
+	}
+	sort.Slice(usage.Symbols, func(i, j int) bool {
+		si, sj := usage.Symbols[i], usage.Symbols[j]
+		if len(si.Uses) != len(sj.Uses) {
+			return len(si.Uses) > len(sj.Uses)
+		}
+		if si.ImportPath != sj.ImportPath {
+			return si.ImportPath < sj.ImportPath
+		}
+		return si.Name < sj.Name
+	})
+	usage.Files = len(files)
+	return usage, nil
+}
+
+// dependencySymbol names a type-checked object of a dependency. Fields are
+// named after the struct declaring them, found once per package in owners.
+func dependencySymbol(obj types.Object, owners map[*types.Package]map[*types.Var]string) DependencySymbol {
+	pkg := obj.Pkg()
+	sym := DependencySymbol{
+		Name:       pkg.Name() + "." + obj.Name(),
+		ImportPath: pkg.Path(),
+		Kind:       objectKind(obj),
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			sym.Name, sym.Kind = funcName(obj), "method"
+		}
+	case *types.Var:
+		if !obj.IsField() {
+			break
+		}
+		sym.Kind = "field"
+		fields, ok := owners[pkg]
+		if !ok {
+			fields = structFields(pkg)
+			owners[pkg] = fields
+		}
+		if owner := fields[obj]; owner != "" {
+			sym.Name = pkg.Name() + "." + owner + "." + obj.Name()
+		}
+	}
+	return sym
+}
+
+// structFields maps the fields of a package's named struct types to the
+// names of the types
+func structFields(pkg *types.Package) map[*types.Var]string {
+	fields := make(map[*types.Var]string)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		typeObj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if st, ok := typeObj.Type().Underlying().(*types.Struct); ok {
+			for field := range st.Fields() {
+				fields[field] = name
+			}
+		}
+	}
+	return fields
+}
+
+// syntacticUse matches a selector qualified with the name a file imports a
+// package of the dependency under, for files without type information
+func syntacticUse(file *ast.File, sel *ast.SelectorExpr, matches func(string) bool) (DependencySymbol, bool) {
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return DependencySymbol{}, false
+	}
+	for _, spec := range file.Imports {
+		specPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || !matches(specPath) {
+			continue
+		}
+		name := path.Base(specPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == x.Name {
+			return DependencySymbol{Name: path.Base(specPath) + "." + sel.Sel.Name, ImportPath: specPath}, true
+		}
+	}
+	return DependencySymbol{}, false
+}
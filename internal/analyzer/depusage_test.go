@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDependencyUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	_ "github.com/pkg/errors/register"
+)
+
+func Load(name string) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if strings.TrimSpace(b.String()) == "" {
+		return pkgerrors.New("empty name")
+	}
+	return pkgerrors.Wrap(nil, strings.ToUpper(name))
+}
+`,
+		"store/text.go": `package store
+
+import "text/tabwriter"
+
+func format() int {
+	w := tabwriter.Writer{}
+	_ = w
+	return tabwriter.AlignRight
+}
+`,
+		"service/service.go": "package service\n\nimport \"example.com/shop/store\"\n\nvar _ = store.Load\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	usage, err := analyzer.DependencyUsage(ctx, "strings")
+	if err != nil {
+		t.Fatalf("DependencyUsage failed: %v", err)
+	}
+	byName := make(map[string]DependencySymbol)
+	for _, sym := range usage.Symbols {
+		byName[sym.Name] = sym
+	}
+	if sym := byName["strings.Builder.WriteString"]; sym.Kind != "method" || len(sym.Uses) != 1 || sym.Uses[0].Function != "store.Load" || sym.Uses[0].Position.Line != 12 {
+		t.Errorf("Expected the method used through a value, got %+v", sym)
+	}
+	if sym := byName["strings.Builder"]; sym.Kind != "type" || len(sym.Uses) != 1 {
+		t.Errorf("Expected strings.Builder, got %+v", sym)
+	}
+	if len(usage.Packages) != 1 || len(usage.Imports) != 1 || usage.Files != 1 || usage.Uses != 5 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	// Packages that fail to load are matched by their syntax, and the
+	// module path covers its packages
+	usage, err = analyzer.DependencyUsage(ctx, "github.com/pkg/errors")
+	if err != nil {
+		t.Fatalf("DependencyUsage failed: %v", err)
+	}
+	if len(usage.Packages) != 2 || len(usage.Imports) != 2 || usage.Imports[0].Name != "pkgerrors" || usage.Imports[1].Name != "_" {
+		t.Errorf("Unexpected imports: %+v", usage.Imports)
+	}
+	if len(usage.Symbols) != 2 || usage.Symbols[0].Name != "errors.New" || usage.Symbols[0].Kind != "" || usage.Symbols[1].Name != "errors.Wrap" {
+		t.Errorf("Unexpected symbols: %+v", usage.Symbols)
+	}
+
+	usage, err = analyzer.DependencyUsage(ctx, "text/tabwriter")
+	if err != nil {
+		t.Fatalf("DependencyUsage failed: %v", err)
+	}
+	if len(usage.Symbols) != 2 || usage.Symbols[0].Name != "tabwriter.AlignRight" || usage.Symbols[0].Kind != "const" {
+		t.Errorf("Unexpected symbols: %+v", usage.Symbols)
+	}
+
+	// Packages of the repository are dependencies of their importers too
+	usage, err = analyzer.DependencyUsage(ctx, "example.com/shop/store")
+	if err != nil {
+		t.Fatalf("DependencyUsage failed: %v", err)
+	}
+	if len(usage.Symbols) != 1 || usage.Symbols[0].Name != "store.Load" || usage.Symbols[0].Uses[0].ImportPath != "example.com/shop/service" {
+		t.Errorf("Unexpected symbols: %+v", usage.Symbols)
+	}
+
+	for _, dependency := range []string{"", "github.com/pkg/err", "net/http"} {
+		if _, err := analyzer.DependencyUsage(ctx, dependency); err == nil {
+			t.Errorf("Expected an error for %q", dependency)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// PackageDocumentation is the exported API of a package with its doc
+// comments, as godoc shows it
+type PackageDocumentation struct {
+	Name       string    `json:"name"`
+	ImportPath string    `json:"import_path"`
+	Doc        string    `json:"doc"`
+	Files      []string  `json:"files"`
+	Constants  []DeclDoc `json:"constants,omitempty"`
+	Variables  []DeclDoc `json:"variables,omitempty"`
+	Functions  []DeclDoc `json:"functions,omitempty"`
+	Types      []TypeDoc `json:"types,omitempty"`
+}
+
+// DeclDoc is a documented declaration: a function, or a const or var
+// declaration, which may declare several names
+type DeclDoc struct {
+	Name     string   `json:"name"`
+	Decl     string   `json:"decl"`
+	Doc      string   `json:"doc,omitempty"`
+	Position Position `json:"position"`
+}
+
+// TypeDoc is a documented type with the declarations godoc groups under it:
+// constants and variables of the type, constructors and methods, which are
+// named Type.Method
+type TypeDoc struct {
+	DeclDoc
+	Constants []DeclDoc `json:"constants,omitempty"`
+	Variables []DeclDoc `json:"variables,omitempty"`
+	Functions []DeclDoc `json:"functions,omitempty"`
+	Methods   []DeclDoc `json:"methods,omitempty"`
+}
+
+// PackageDocumentation returns the documentation of the package with the
+// given import path, loading it first with lazy loading
+func (a *Analyzer) PackageDocumentation(ctx context.Context, importPath string) (*PackageDocumentation, error) {
+	if err := a.rlockPackages(ctx, importPath); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	docPkg := a.docPkgs[importPath]
+	if docPkg == nil {
+		return nil, fmt.Errorf("package %s not found", importPath)
+	}
+
+	result := &PackageDocumentation{
+		Name:       docPkg.Name,
+		ImportPath: importPath,
+		Doc:        docPkg.Doc,
+		Files:      append([]string(nil), docPkg.Filenames...),
+		Constants:  a.valueDocs(docPkg.Consts),
+		Variables:  a.valueDocs(docPkg.Vars),
+		Functions:  a.funcDocs(docPkg.Funcs),
+	}
+	for _, typ := range docPkg.Types {
+		if !token.IsExported(typ.Name) {
+			continue
+		}
+		methods := a.funcDocs(typ.Methods)
+		for i := range methods {
+			methods[i].Name = typ.Name + "." + methods[i].Name
+		}
+		result.Types = append(result.Types, TypeDoc{
+			DeclDoc: DeclDoc{
+				Name:     typ.Name,
+				Decl:     a.declText(typ.Decl),
+				Doc:      typ.Doc,
+				Position: a.position(typeSpecPos(typ.Decl, typ.Name)),
+			},
+			Constants: a.valueDocs(typ.Consts),
+			Variables: a.valueDocs(typ.Vars),
+			Functions: a.funcDocs(typ.Funcs),
+			Methods:   methods,
+		})
+	}
+	return result, nil
+}
+
+// valueDocs documents the const or var declarations declaring at least one
+// exported name
+func (a *Analyzer) valueDocs(values []*doc.Value) []DeclDoc {
+	var docs []DeclDoc
+	for _, value := range values {
+		exported := false
+		for _, name := range value.Names {
+			exported = exported || token.IsExported(name)
+		}
+		if !exported {
+			continue
+		}
+		docs = append(docs, DeclDoc{
+			Name:     strings.Join(value.Names, ", "),
+			Decl:     a.declText(value.Decl),
+			Doc:      value.Doc,
+			Position: a.position(value.Decl.Pos()),
+		})
+	}
+	return docs
+}
+
+// funcDocs documents the exported functions or methods, without bodies
+func (a *Analyzer) funcDocs(funcs []*doc.Func) []DeclDoc {
+	var docs []DeclDoc
+	for _, fn := range funcs {
+		if !token.IsExported(fn.Name) || fn.Decl == nil {
+			continue
+		}
+		decl := *fn.Decl
+		decl.Body = nil
+		docs = append(docs, DeclDoc{
+			Name:     fn.Name,
+			Decl:     a.declText(&decl),
+			Doc:      fn.Doc,
+			Position: a.position(fn.Decl.Name.Pos()),
+		})
+	}
+	return docs
+}
+
+// declText prints a declaration without its doc comment
+func (a *Analyzer) declText(node ast.Node) string {
+	switch decl := node.(type) {
+	case *ast.GenDecl:
+		stripped := *decl
+		stripped.Doc = nil
+		node = &stripped
+	case *ast.FuncDecl:
+		stripped := *decl
+		stripped.Doc = nil
+		node = &stripped
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, a.fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// typeSpecPos returns the position of a type's name in its declaration
+func typeSpecPos(decl *ast.GenDecl, name string) token.Pos {
+	for _, spec := range decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+			return ts.Name.Pos()
+		}
+	}
+	return decl.Pos()
+}
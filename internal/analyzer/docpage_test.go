@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageDocumentation(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `// Package store keeps values
+package store
+
+// Version of the format
+const Version = 2
+
+const internal = 1
+
+// Cache is a store in memory
+type Cache struct {
+	values map[string]string
+}
+
+// New returns an empty Cache
+func New() *Cache {
+	return &Cache{values: map[string]string{}}
+}
+
+// Get returns the value of a key
+func (c *Cache) Get(key string) string {
+	return c.values[key]
+}
+
+func (c *Cache) size() int { return len(c.values) }
+
+// Open opens a store
+func Open(path string) error { return nil }
+
+type hidden struct{}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+
+		pkg, err := analyzer.PackageDocumentation(context.Background(), "example.com/app/store")
+		if err != nil {
+			t.Fatalf("Failed to get documentation (lazy %v): %v", lazy, err)
+		}
+		if pkg.Name != "store" || pkg.Doc != "Package store keeps values\n" || len(pkg.Files) != 1 {
+			t.Errorf("Expected package store with its doc and one file, got %+v", pkg)
+		}
+		if len(pkg.Constants) != 1 || pkg.Constants[0].Decl != "const Version = 2" {
+			t.Errorf("Expected only the exported constant, got %+v", pkg.Constants)
+		}
+		if len(pkg.Functions) != 1 || pkg.Functions[0].Decl != "func Open(path string) error" || pkg.Functions[0].Position.Line != 27 {
+			t.Errorf("Expected Open at line 27, got %+v", pkg.Functions)
+		}
+		if len(pkg.Types) != 1 {
+			t.Fatalf("Expected only the exported type, got %+v", pkg.Types)
+		}
+		cache := pkg.Types[0]
+		if cache.Name != "Cache" || cache.Doc != "Cache is a store in memory\n" || cache.Position.Line != 10 {
+			t.Errorf("Expected Cache at line 10 with its doc, got %+v", cache.DeclDoc)
+		}
+		if len(cache.Functions) != 1 || cache.Functions[0].Name != "New" {
+			t.Errorf("Expected New under Cache, got %+v", cache.Functions)
+		}
+		if len(cache.Methods) != 1 || cache.Methods[0].Name != "Cache.Get" || cache.Methods[0].Decl != "func (c *Cache) Get(key string) string" {
+			t.Errorf("Expected only the exported method Cache.Get, got %+v", cache.Methods)
+		}
+
+		if _, err := analyzer.PackageDocumentation(context.Background(), "example.com/app/missing"); err == nil {
+			t.Error("Expected an error for an unknown package")
+		}
+	}
+}
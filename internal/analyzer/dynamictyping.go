@@ -0,0 +1,228 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+)
+
+// DynamicTypingReport lists where the analyzed packages leave static typing:
+// type assertions, type switches and uses of package reflect
+type DynamicTypingReport struct {
+	Packages []PackageDynamicTyping `json:"packages"`
+	// Assertions, Unchecked, TypeSwitches and ReflectCalls count the
+	// findings of all packages
+	Assertions   int `json:"assertions"`
+	Unchecked    int `json:"unchecked"`
+	TypeSwitches int `json:"type_switches"`
+	ReflectCalls int `json:"reflect_calls"`
+}
+
+// PackageDynamicTyping is the dynamic typing of one package
+type PackageDynamicTyping struct {
+	ImportPath   string          `json:"import_path"`
+	Assertions   []TypeAssertion `json:"assertions"`
+	TypeSwitches []TypeSwitch    `json:"type_switches"`
+	Reflection   []ReflectCall   `json:"reflection"`
+}
+
+// TypeAssertion is a type assertion outside a type switch
+type TypeAssertion struct {
+	// Expr is the asserted expression and From its static type
+	Expr string `json:"expr"`
+	From string `json:"from"`
+	// Type is the type asserted
+	Type string `json:"type"`
+	// Checked is set for the comma-ok form. An unchecked assertion panics
+	// when the dynamic type does not match.
+	Checked bool `json:"checked"`
+	// Function is the declaration holding the assertion, as pkg.Func or
+	// pkg.Type.Method; empty at package level
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+}
+
+// TypeSwitch is a type switch and the types of its cases
+type TypeSwitch struct {
+	Expr string `json:"expr"`
+	From string `json:"from"`
+	// Cases are the types listed by the cases, including nil, in order
+	Cases []string `json:"cases"`
+	// Default is set when the switch has a default case
+	Default  bool     `json:"default"`
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+}
+
+// ReflectCall is a call of a function or method of package reflect
+type ReflectCall struct {
+	// Call is the function or method called, as reflect.ValueOf or
+	// reflect.Value.Interface
+	Call     string   `json:"call"`
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+}
+
+// DynamicTypingReport lists the type assertions, type switches and calls
+// into package reflect of the packages a qualifier selects, or of every
+// package. Assertions without the comma-ok form are reported as unchecked,
+// since they panic on a mismatch. Packages with none of these are left out.
+func (a *Analyzer) DynamicTypingReport(ctx context.Context, pkg string) (*DynamicTypingReport, error) {
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	var importPaths []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	if len(importPaths) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	report := &DynamicTypingReport{Packages: []PackageDynamicTyping{}}
+	for _, importPath := range importPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pd := a.packageDynamicTyping(importPath)
+		if len(pd.Assertions) == 0 && len(pd.TypeSwitches) == 0 && len(pd.Reflection) == 0 {
+			continue
+		}
+		report.Assertions += len(pd.Assertions)
+		for _, assertion := range pd.Assertions {
+			if !assertion.Checked {
+				report.Unchecked++
+			}
+		}
+		report.TypeSwitches += len(pd.TypeSwitches)
+		report.ReflectCalls += len(pd.Reflection)
+		report.Packages = append(report.Packages, pd)
+	}
+	return report, nil
+}
+
+// packageDynamicTyping reports the dynamic typing of one package
+func (a *Analyzer) packageDynamicTyping(importPath string) PackageDynamicTyping {
+	info := a.infos[importPath]
+	pd := PackageDynamicTyping{
+		ImportPath:   importPath,
+		Assertions:   []TypeAssertion{},
+		TypeSwitches: []TypeSwitch{},
+		Reflection:   []ReflectCall{},
+	}
+	if info == nil {
+		return pd
+	}
+	qualifier := types.RelativeTo(a.pkgs[importPath])
+	typeOf := func(expr ast.Expr) string {
+		if t := info.TypeOf(expr); t != nil {
+			return types.TypeString(t, qualifier)
+		}
+		return types.ExprString(expr)
+	}
+
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			function := ""
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				function = fd.Name.Name
+				if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+					function = funcName(fn)
+				}
+			}
+
+			// Assertions whose second result is taken
+			checked := make(map[*ast.TypeAssertExpr]bool)
+			ast.Inspect(decl, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+						if ta, ok := ast.Unparen(n.Rhs[0]).(*ast.TypeAssertExpr); ok {
+							checked[ta] = true
+						}
+					}
+				case *ast.ValueSpec:
+					if len(n.Names) == 2 && len(n.Values) == 1 {
+						if ta, ok := ast.Unparen(n.Values[0]).(*ast.TypeAssertExpr); ok {
+							checked[ta] = true
+						}
+					}
+				case *ast.TypeSwitchStmt:
+					pd.TypeSwitches = append(pd.TypeSwitches, a.typeSwitch(n, typeOf, function))
+				case *ast.TypeAssertExpr:
+					if n.Type == nil {
+						// The guard of a type switch
+						return true
+					}
+					pd.Assertions = append(pd.Assertions, TypeAssertion{
+						Expr:     types.ExprString(n.X),
+						From:     typeOf(n.X),
+						Type:     typeOf(n.Type),
+						Checked:  checked[n],
+						Function: function,
+						Position: a.position(n.Lparen),
+					})
+				case *ast.CallExpr:
+					if call := reflectCall(info, n); call != "" {
+						pd.Reflection = append(pd.Reflection, ReflectCall{
+							Call:     call,
+							Function: function,
+							Position: a.position(n.Pos()),
+						})
+					}
+				}
+				return true
+			})
+		}
+	}
+	return pd
+}
+
+// typeSwitch describes a type switch statement
+func (a *Analyzer) typeSwitch(stmt *ast.TypeSwitchStmt, typeOf func(ast.Expr) string, function string) TypeSwitch {
+	var guard ast.Expr
+	switch assign := stmt.Assign.(type) {
+	case *ast.AssignStmt:
+		guard = assign.Rhs[0]
+	case *ast.ExprStmt:
+		guard = assign.X
+	}
+	ts := TypeSwitch{Cases: []string{}, Function: function, Position: a.position(stmt.Pos())}
+	if ta, ok := ast.Unparen(guard).(*ast.TypeAssertExpr); ok {
+		ts.Expr = types.ExprString(ta.X)
+		ts.From = typeOf(ta.X)
+	}
+	for _, clause := range stmt.Body.List {
+		clause := clause.(*ast.CaseClause)
+		if clause.List == nil {
+			ts.Default = true
+		}
+		for _, expr := range clause.List {
+			if ident, ok := expr.(*ast.Ident); ok && ident.Name == "nil" {
+				ts.Cases = append(ts.Cases, "nil")
+				continue
+			}
+			ts.Cases = append(ts.Cases, typeOf(expr))
+		}
+	}
+	return ts
+}
+
+// reflectCall names the function or method of package reflect a call
+// calls, or returns "" for other calls
+func reflectCall(info *types.Info, call *ast.CallExpr) string {
+	fn, ok := calledFunc(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "reflect" {
+		return ""
+	}
+	return funcName(fn)
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDynamicTypingReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"codec/codec.go": `package codec
+
+import "reflect"
+
+type Message interface{ Kind() string }
+
+type Ping struct{}
+
+func (Ping) Kind() string { return "ping" }
+
+var defaultPing, _ = any(Ping{}).(Ping)
+
+func Decode(v any) Ping {
+	return v.(Ping)
+}
+
+func TryDecode(v any) (Ping, bool) {
+	p, ok := v.(Ping)
+	return p, ok
+}
+
+func Describe(v any) string {
+	switch m := v.(type) {
+	case nil:
+		return "nil"
+	case Ping, *Ping:
+		return "ping"
+	case Message:
+		return m.Kind()
+	default:
+		return reflect.TypeOf(v).String()
+	}
+}
+
+func Fields(v any) int {
+	return reflect.ValueOf(v).Elem().NumField()
+}
+`,
+		"plain/plain.go": "package plain\n\nfunc Add(a, b int) int { return a + b }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	report, err := a.DynamicTypingReport(context.Background(), "")
+	if err != nil {
+		t.Fatalf("DynamicTypingReport failed: %v", err)
+	}
+	if len(report.Packages) != 1 || report.Packages[0].ImportPath != "example.com/app/codec" {
+		t.Fatalf("Expected only the codec package, got %+v", report.Packages)
+	}
+	if report.Assertions != 3 || report.Unchecked != 1 || report.TypeSwitches != 1 {
+		t.Errorf("Expected 3 assertions with 1 unchecked and 1 type switch, got %+v", report)
+	}
+	pkg := report.Packages[0]
+
+	unchecked := pkg.Assertions[1]
+	if unchecked.Checked || unchecked.Expr != "v" || unchecked.From != "any" || unchecked.Type != "Ping" || unchecked.Function != "codec.Decode" {
+		t.Errorf("Expected the unchecked assertion in Decode, got %+v", unchecked)
+	}
+	if !pkg.Assertions[0].Checked || pkg.Assertions[0].Function != "" {
+		t.Errorf("Expected the checked package-level assertion, got %+v", pkg.Assertions[0])
+	}
+	if !pkg.Assertions[2].Checked || pkg.Assertions[2].Function != "codec.TryDecode" {
+		t.Errorf("Expected the comma-ok assertion in TryDecode, got %+v", pkg.Assertions[2])
+	}
+
+	ts := pkg.TypeSwitches[0]
+	if ts.Expr != "v" || !ts.Default || len(ts.Cases) != 4 || ts.Cases[0] != "nil" || ts.Cases[2] != "*Ping" || ts.Function != "codec.Describe" {
+		t.Errorf("Expected the type switch in Describe, got %+v", ts)
+	}
+
+	var calls []string
+	for _, call := range pkg.Reflection {
+		calls = append(calls, call.Call)
+	}
+	want := "reflect.Type.String reflect.TypeOf reflect.Value.NumField reflect.Value.Elem reflect.ValueOf"
+	if strings.Join(calls, " ") != want {
+		t.Errorf("Expected the calls into reflect, got %v", calls)
+	}
+
+	if _, err := a.DynamicTypingReport(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for an unknown package")
+	}
+}
@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// embedDirective starts the comments embedding files into a variable
+const embedDirective = "//go:embed"
+
+// Embed is a variable initialized with //go:embed directives
+type Embed struct {
+	// Package is the import path of the package declaring the variable
+	Package  string `json:"package"`
+	Variable string `json:"variable"`
+	// Type is string, []byte or embed.FS
+	Type     string   `json:"type"`
+	Patterns []string `json:"patterns"`
+	// Files are the embedded files, relative to the repository
+	Files []string `json:"files"`
+	// Problems are what go build would reject: patterns matching nothing,
+	// invalid patterns, and more than one file for a string or []byte
+	Problems []string `json:"problems,omitempty"`
+	Position Position `json:"position"`
+}
+
+// EmbedReport lists the embedded files of the analyzed packages
+type EmbedReport struct {
+	Embeds []Embed `json:"embeds"`
+	// Problems counts the problems of all embeds
+	Problems int `json:"problems"`
+}
+
+// Embeds finds the //go:embed directives of the packages a qualifier
+// selects, or of every package, resolves their patterns against the files
+// on disk and checks them as go build would
+func (a *Analyzer) Embeds(ctx context.Context, pkg string) (*EmbedReport, error) {
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	report := &EmbedReport{Embeds: []Embed{}}
+	found := false
+	for _, importPath := range a.sortedPackagePaths() {
+		if !matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			continue
+		}
+		found = true
+		for _, embed := range a.packageEmbeds(importPath) {
+			report.Problems += len(embed.Problems)
+			report.Embeds = append(report.Embeds, embed)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+	return report, nil
+}
+
+// packageEmbeds returns the embeds of a package in declaration order. The
+// caller holds a.mu.
+func (a *Analyzer) packageEmbeds(importPath string) []Embed {
+	info := a.infos[importPath]
+	pkg := a.pkgs[importPath]
+	var embeds []Embed
+	for _, file := range a.asts[importPath] {
+		dir := filepath.Dir(a.fset.Position(file.Package).Filename)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				doc := vs.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				patterns, err := embedPatterns(doc)
+				if len(patterns) == 0 && err == nil {
+					continue
+				}
+				embed := Embed{
+					Package:  importPath,
+					Variable: pkg.Name() + "." + vs.Names[0].Name,
+					Patterns: patterns,
+					Files:    []string{},
+					Position: a.position(vs.Names[0].Pos()),
+				}
+				if err != nil {
+					embed.Problems = append(embed.Problems, err.Error())
+				}
+				if obj := info.Defs[vs.Names[0]]; obj != nil {
+					embed.Type = embedType(obj.Type())
+				}
+				switch {
+				case len(vs.Names) > 1:
+					embed.Problems = append(embed.Problems, "go:embed cannot apply to multiple vars")
+				case len(vs.Values) > 0:
+					embed.Problems = append(embed.Problems, "go:embed cannot apply to var with initializer")
+				case embed.Type == "":
+					embed.Problems = append(embed.Problems, "go:embed cannot apply to var of type "+types.TypeString(info.TypeOf(vs.Type), types.RelativeTo(pkg)))
+				}
+				a.resolveEmbed(&embed, dir)
+				embeds = append(embeds, embed)
+			}
+		}
+	}
+	return embeds
+}
+
+// resolveEmbed matches the patterns of an embed against the files of the
+// package directory
+func (a *Analyzer) resolveEmbed(embed *Embed, dir string) {
+	seen := make(map[string]bool)
+	for _, pattern := range embed.Patterns {
+		all := strings.HasPrefix(pattern, "all:")
+		glob := strings.TrimPrefix(pattern, "all:")
+		if !validEmbedPattern(glob) {
+			embed.Problems = append(embed.Problems, fmt.Sprintf("pattern %s: invalid pattern syntax", pattern))
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(glob)))
+		if err != nil {
+			embed.Problems = append(embed.Problems, fmt.Sprintf("pattern %s: %v", pattern, err))
+			continue
+		}
+		var files []string
+		for _, match := range matches {
+			info, err := os.Lstat(match)
+			if err != nil {
+				continue
+			}
+			switch {
+			case info.Mode().IsRegular():
+				files = append(files, match)
+			case info.IsDir():
+				dirFiles := embedDir(match, all)
+				if len(dirFiles) == 0 {
+					embed.Problems = append(embed.Problems, fmt.Sprintf("pattern %s: cannot embed directory %s: contains no embeddable files", pattern, a.relPath(match)))
+				}
+				files = append(files, dirFiles...)
+			default:
+				embed.Problems = append(embed.Problems, fmt.Sprintf("pattern %s: cannot embed irregular file %s", pattern, a.relPath(match)))
+			}
+		}
+		if len(matches) == 0 {
+			embed.Problems = append(embed.Problems, fmt.Sprintf("pattern %s: no matching files found", pattern))
+		}
+		for _, file := range files {
+			if rel := a.relPath(file); !seen[rel] {
+				seen[rel] = true
+				embed.Files = append(embed.Files, rel)
+			}
+		}
+	}
+	sort.Strings(embed.Files)
+	if embed.Type != "embed.FS" && embed.Type != "" && len(embed.Files) > 1 {
+		embed.Problems = append(embed.Problems, fmt.Sprintf("invalid go:embed: multiple files for type %s", embed.Type))
+	}
+}
+
+// embedDir returns the files a pattern naming a directory embeds: those of
+// its tree, leaving out names starting with . or _ unless all is set, and
+// directories of other modules
+func embedDir(root string, all bool) []string {
+	var files []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != root {
+			name := d.Name()
+			if !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil || name == ".git" {
+					return filepath.SkipDir
+				}
+			}
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// validEmbedPattern reports whether a pattern is valid for go:embed: a
+// slash-separated, unrooted glob without . or .. elements
+func validEmbedPattern(pattern string) bool {
+	if pattern == "" || strings.HasPrefix(pattern, "/") || strings.HasSuffix(pattern, "/") || strings.Contains(pattern, "\\") {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// embedPatterns returns the patterns of the //go:embed lines of a comment
+// group. Patterns are separated by spaces and may be quoted.
+func embedPatterns(doc *ast.CommentGroup) ([]string, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	var patterns []string
+	for _, comment := range doc.List {
+		rest, ok := strings.CutPrefix(comment.Text, embedDirective)
+		if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+			var pattern string
+			switch rest[0] {
+			case '"', '`':
+				end := strings.IndexByte(rest[1:], rest[0])
+				if end < 0 {
+					return patterns, fmt.Errorf("invalid quoted string in //go:embed: %s", rest)
+				}
+				unquoted, err := strconv.Unquote(rest[:end+2])
+				if err != nil {
+					return patterns, fmt.Errorf("invalid quoted string in //go:embed: %s", rest[:end+2])
+				}
+				pattern, rest = unquoted, rest[end+2:]
+			default:
+				end := strings.IndexAny(rest, " \t")
+				if end < 0 {
+					end = len(rest)
+				}
+				pattern, rest = rest[:end], rest[end:]
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// embedType returns the name of a type a variable embedding files may
+// have, or an empty string
+func embedType(t types.Type) string {
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "embed" && obj.Name() == "FS" {
+			return "embed.FS"
+		}
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		if t.Kind() == types.String {
+			return "string"
+		}
+	case *types.Slice:
+		if basic, ok := t.Elem().Underlying().(*types.Basic); ok && basic.Kind() == types.Byte {
+			return "[]byte"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeds(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/site\n\ngo 1.21\n",
+		"web/web.go": `package web
+
+import "embed"
+
+// Index is the home page
+//
+//go:embed static/index.html
+var Index string
+
+var (
+	// Assets are served as is
+	//go:embed static
+	Assets embed.FS
+
+	//go:embed all:static "templates/*.tmpl"
+	All embed.FS
+
+	//go:embed missing.txt
+	Missing []byte
+
+	//go:embed static/*
+	Many string
+)
+`,
+		"web/static/index.html":      "<html></html>",
+		"web/static/app.js":          "",
+		"web/static/.hidden":         "",
+		"web/static/_draft/page.txt": "",
+		"web/templates/base.tmpl":    "",
+		"web/plain.go": `package web
+
+// Version is not embedded
+var Version = "1"
+`,
+		"other/other.go": "package other\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	report, err := analyzer.Embeds(ctx, "web")
+	if err != nil {
+		t.Fatalf("Failed to list embeds: %v", err)
+	}
+	byVar := make(map[string]Embed)
+	for _, embed := range report.Embeds {
+		byVar[embed.Variable] = embed
+	}
+	if len(byVar) != 5 {
+		t.Fatalf("Expected 5 embeds, got %+v", report.Embeds)
+	}
+
+	index := byVar["web.Index"]
+	if index.Type != "string" || strings.Join(index.Files, " ") != "web/static/index.html" || len(index.Problems) != 0 {
+		t.Errorf("Unexpected Index embed: %+v", index)
+	}
+	if index.Position.Line != 8 {
+		t.Errorf("Expected Index at line 8, got %d", index.Position.Line)
+	}
+	assets := byVar["web.Assets"]
+	if want := "web/static/app.js web/static/index.html"; assets.Type != "embed.FS" || strings.Join(assets.Files, " ") != want {
+		t.Errorf("Expected Assets to leave out hidden files, got %+v", assets)
+	}
+	all := byVar["web.All"]
+	if want := "web/static/.hidden web/static/_draft/page.txt web/static/app.js web/static/index.html web/templates/base.tmpl"; strings.Join(all.Files, " ") != want {
+		t.Errorf("Unexpected All files: %v", all.Files)
+	}
+	if strings.Join(all.Patterns, " ") != "all:static templates/*.tmpl" {
+		t.Errorf("Unexpected All patterns: %v", all.Patterns)
+	}
+	missing := byVar["web.Missing"]
+	if missing.Type != "[]byte" || len(missing.Problems) != 1 || !strings.Contains(missing.Problems[0], "no matching files") {
+		t.Errorf("Expected missing pattern problem, got %+v", missing)
+	}
+	many := byVar["web.Many"]
+	if len(many.Problems) != 1 || !strings.Contains(many.Problems[0], "multiple files") {
+		t.Errorf("Expected multiple files problem, got %+v", many)
+	}
+	if report.Problems != 2 {
+		t.Errorf("Expected 2 problems, got %d", report.Problems)
+	}
+
+	info, err := analyzer.GetPackageInfo(ctx, "web")
+	if err != nil {
+		t.Fatalf("Failed to get package info: %v", err)
+	}
+	if len(info.Embeds) != 5 {
+		t.Errorf("Expected package info to include 5 embeds, got %d", len(info.Embeds))
+	}
+	if report, err := analyzer.Embeds(ctx, "other"); err != nil || len(report.Embeds) != 0 {
+		t.Errorf("Expected no embeds in other, got %v, %v", report, err)
+	}
+	if _, err := analyzer.Embeds(ctx, "nosuchpkg"); err == nil {
+		t.Error("Expected error for unknown package")
+	}
+}
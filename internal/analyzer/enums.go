@@ -0,0 +1,175 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// EnumInfo is a named type used as an enumeration: a group of constants
+// of the type declared in the analyzed packages
+type EnumInfo struct {
+	// Name is qualified with the package name: pkg.Type
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Underlying is the basic type the enum is defined over, such as int
+	// or string
+	Underlying string      `json:"underlying"`
+	Exported   bool        `json:"exported"`
+	Position   Position    `json:"position"`
+	Values     []EnumValue `json:"values"`
+	// Iota reports whether any of the values is declared with iota
+	Iota bool `json:"iota"`
+	// HasString reports whether values of the type have a String() string
+	// method, so fmt prints their names. A String method on the pointer
+	// type does not count, since fmt is handed values.
+	HasString bool `json:"has_string"`
+}
+
+// EnumValue is one constant of an enum, in declaration order
+type EnumValue struct {
+	Name string `json:"name"`
+	// Value is the constant's exact value as Go source
+	Value    string   `json:"value"`
+	Position Position `json:"position"`
+}
+
+// ListEnums detects enums: named types with an integer, float or string
+// underlying type and either an iota block or at least two constants of
+// the type. A non-empty pkg restricts the list to types declared in
+// matching packages (import path, suffix or package name).
+func (a *Analyzer) ListEnums(ctx context.Context, pkg string) ([]EnumInfo, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	consts := make(map[*types.TypeName][]*types.Const)
+	fromIota := make(map[*types.Const]bool)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		scope := a.pkgs[importPath].Scope()
+		for _, name := range scope.Names() {
+			c, ok := scope.Lookup(name).(*types.Const)
+			if !ok {
+				continue
+			}
+			if typeName := a.enumType(c); typeName != nil {
+				consts[typeName] = append(consts[typeName], c)
+			}
+		}
+		a.markIotaConsts(importPath, fromIota)
+	}
+
+	enums := []EnumInfo{}
+	for typeName, values := range consts {
+		importPath := typeName.Pkg().Path()
+		if pkg != "" && !matchesQualifier(pkg, importPath, typeName.Pkg().Name()) {
+			continue
+		}
+		enum := EnumInfo{
+			Name:       typeName.Pkg().Name() + "." + typeName.Name(),
+			ImportPath: importPath,
+			Underlying: typeName.Type().Underlying().String(),
+			Exported:   typeName.Exported(),
+			Position:   a.position(typeName.Pos()),
+			HasString:  hasStringMethod(typeName.Type()),
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i].Pos() < values[j].Pos() })
+		for _, c := range values {
+			enum.Iota = enum.Iota || fromIota[c]
+			enum.Values = append(enum.Values, EnumValue{
+				Name:     c.Name(),
+				Value:    c.Val().ExactString(),
+				Position: a.position(c.Pos()),
+			})
+		}
+		if !enum.Iota && len(enum.Values) < 2 {
+			continue
+		}
+		enums = append(enums, enum)
+	}
+
+	sort.Slice(enums, func(i, j int) bool {
+		if enums[i].ImportPath != enums[j].ImportPath {
+			return enums[i].ImportPath < enums[j].ImportPath
+		}
+		return enums[i].Name < enums[j].Name
+	})
+	return enums, nil
+}
+
+// enumType returns the named type of a constant when it can be an enum:
+// declared in the analyzed packages and defined over a numeric or string type
+func (a *Analyzer) enumType(c *types.Const) *types.TypeName {
+	named, ok := c.Type().(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || a.pkgs[named.Obj().Pkg().Path()] == nil {
+		return nil
+	}
+	basic, ok := named.Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsInteger|types.IsFloat|types.IsString) == 0 {
+		return nil
+	}
+	return named.Obj()
+}
+
+// markIotaConsts records the constants of a package declared in a const
+// block using iota. Specs without values repeat the previous expression,
+// so every constant of such a block counts.
+func (a *Analyzer) markIotaConsts(importPath string, fromIota map[*types.Const]bool) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST || !usesIota(info, gen) {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					if c, ok := info.Defs[name].(*types.Const); ok {
+						fromIota[c] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// usesIota reports whether a const declaration refers to the predeclared iota
+func usesIota(info *types.Info, decl *ast.GenDecl) bool {
+	found := false
+	ast.Inspect(decl, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && info.Uses[ident] == types.Universe.Lookup("iota") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// hasStringMethod reports whether the value method set of t has a
+// String() string method
+func hasStringMethod(t types.Type) bool {
+	sel := types.NewMethodSet(t).Lookup(nil, "String")
+	if sel == nil {
+		return false
+	}
+	sig := sel.Obj().Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	result, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && result.Kind() == types.String
+}
@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListEnums(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"color/color.go": `package color
+
+import "time"
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+func (c Color) String() string { return "" }
+
+type Mode string
+
+const (
+	Fast Mode = "fast"
+	Slow Mode = "slow"
+)
+
+type level int
+
+const Debug level = iota
+
+type ptrString int
+
+const (
+	A ptrString = 1
+	B ptrString = 2
+)
+
+func (p *ptrString) String() string { return "" }
+
+type Single int
+
+const Only Single = 1
+
+const Timeout time.Duration = 5
+
+const Untyped = 3
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	enums, err := analyzer.ListEnums(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListEnums failed: %v", err)
+	}
+	byName := make(map[string]EnumInfo)
+	for _, enum := range enums {
+		byName[enum.Name] = enum
+	}
+	// Single has one constant and no iota; time.Duration is not declared
+	// in the repository
+	if len(enums) != 4 {
+		t.Fatalf("Expected 4 enums, got %+v", enums)
+	}
+
+	color := byName["color.Color"]
+	if !color.Iota || !color.HasString || color.Underlying != "int" || !color.Exported {
+		t.Errorf("Expected an exported int iota enum with String, got %+v", color)
+	}
+	var names []string
+	for _, value := range color.Values {
+		names = append(names, value.Name+"="+value.Value)
+	}
+	if len(names) != 3 || names[0] != "Red=0" || names[1] != "Green=1" || names[2] != "Blue=2" {
+		t.Errorf("Expected Red, Green and Blue in declaration order, got %v", names)
+	}
+
+	if mode := byName["color.Mode"]; mode.Iota || mode.HasString || mode.Underlying != "string" || mode.Values[0].Value != `"fast"` {
+		t.Errorf("Expected a string enum without iota or String, got %+v", mode)
+	}
+	if level := byName["color.level"]; !level.Iota || level.Exported || len(level.Values) != 1 {
+		t.Errorf("Expected a single-value unexported iota enum, got %+v", level)
+	}
+	if ptr := byName["color.ptrString"]; ptr.HasString {
+		t.Errorf("Expected a pointer String method not to count, got %+v", ptr)
+	}
+
+	if filtered, err := analyzer.ListEnums(context.Background(), "other"); err != nil || len(filtered) != 0 {
+		t.Errorf("Expected no enums for another package, got %+v (%v)", filtered, err)
+	}
+}
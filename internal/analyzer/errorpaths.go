@@ -0,0 +1,478 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+)
+
+// Kinds of error returns
+const (
+	ErrorCreated    = "created"    // errors.New, fmt.Errorf without %w, or a new error value
+	ErrorWrapped    = "wrapped"    // fmt.Errorf with %w or errors.Join
+	ErrorFlattened  = "flattened"  // fmt.Errorf formatting an error without %w, losing errors.Is/As
+	ErrorPropagated = "propagated" // an error variable or call result returned as is
+)
+
+// Kinds of discarded errors
+const (
+	ErrorBlank    = "blank"    // assigned to _
+	ErrorIgnored  = "ignored"  // result of a call statement
+	ErrorDeferred = "deferred" // result of a deferred call
+)
+
+// ErrorPaths describes how errors and panics leave a function
+type ErrorPaths struct {
+	Function  string           `json:"function"`
+	Position  Position         `json:"position"`
+	Returns   []ErrorReturn    `json:"returns"`
+	Swallowed []SwallowedError `json:"swallowed"`
+	Panics    []PanicSite      `json:"panics"`
+	// DynamicCalls counts calls through interfaces and function values, which
+	// the call graph traversal cannot follow
+	DynamicCalls int `json:"dynamic_calls"`
+}
+
+// ErrorReturn is a return statement that can return a non-nil error
+type ErrorReturn struct {
+	Kind       string   `json:"kind"`
+	Expression string   `json:"expression"`
+	Position   Position `json:"position"`
+}
+
+// SwallowedError is an error value that is discarded
+type SwallowedError struct {
+	Kind       string   `json:"kind"`
+	Expression string   `json:"expression"`
+	Position   Position `json:"position"`
+}
+
+// PanicSite is a panic call reachable from the analyzed function
+type PanicSite struct {
+	Function string `json:"function"`
+	Argument string `json:"argument"`
+	// Chain is the call path from the analyzed function to Function
+	Chain    []string `json:"chain"`
+	Position Position `json:"position"`
+}
+
+// ErrorPaths reports where a function returns errors and how they are built,
+// where it discards errors, and the panic calls reachable from it through
+// statically resolvable calls up to depth levels deep (default 5). The name
+// may be a function or a Type.Method, optionally package-qualified.
+func (a *Analyzer) ErrorPaths(ctx context.Context, name string, depth int) (*ErrorPaths, error) {
+	if err := a.rlockNames(ctx, name); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	if depth <= 0 {
+		depth = 5
+	}
+
+	fn, err := a.resolveFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	decls := a.funcDecls()
+	root, ok := decls[fn]
+	if !ok || root.decl.Body == nil {
+		return nil, fmt.Errorf("%s has no body in the analyzed packages", name)
+	}
+
+	paths := &ErrorPaths{
+		Function:  funcName(fn),
+		Position:  a.position(fn.Pos()),
+		Returns:   []ErrorReturn{},
+		Swallowed: []SwallowedError{},
+		Panics:    []PanicSite{},
+	}
+	info := a.infos[root.importPath]
+	a.collectErrorReturns(paths, info, fn, root.decl)
+	a.collectSwallowedErrors(paths, info, root.decl.Body)
+
+	// Breadth-first traversal of the static call graph, so each panic is
+	// reported with a shortest chain
+	type visit struct {
+		fn    *types.Func
+		chain []string
+	}
+	queue := []visit{{fn, []string{paths.Function}}}
+	seen := map[*types.Func]bool{fn: true}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		current := queue[0]
+		queue = queue[1:]
+		decl := decls[current.fn]
+		info := a.infos[decl.importPath]
+
+		inspectBody(decl.decl.Body, func(call *ast.CallExpr) {
+			if isBuiltin(info, call.Fun, "panic") {
+				site := PanicSite{
+					Function: current.chain[len(current.chain)-1],
+					Chain:    current.chain,
+					Position: a.position(call.Pos()),
+				}
+				if len(call.Args) == 1 {
+					site.Argument = types.ExprString(call.Args[0])
+				}
+				paths.Panics = append(paths.Panics, site)
+				return
+			}
+
+			callee, dynamic := staticCallee(info, call)
+			if dynamic && current.fn == fn {
+				paths.DynamicCalls++
+			}
+			if callee == nil || seen[callee] || len(current.chain) > depth {
+				return
+			}
+			if calleeDecl, ok := decls[callee]; ok && calleeDecl.decl.Body != nil {
+				seen[callee] = true
+				chain := append(append([]string{}, current.chain...), funcName(callee))
+				queue = append(queue, visit{callee, chain})
+			}
+		})
+	}
+	return paths, nil
+}
+
+// resolveFunc finds a function, or a method given as Type.Method
+func (a *Analyzer) resolveFunc(name string) (*types.Func, error) {
+	_, obj, err := a.resolve(name)
+	if err == nil {
+		if fn, ok := obj.(*types.Func); ok {
+			return fn, nil
+		}
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+	var ambiguous *AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return nil, err
+	}
+
+	typeName, method := splitQualifiedName(name)
+	if typeName == "" {
+		return nil, fmt.Errorf("function %s not found", name)
+	}
+	_, obj, typeErr := a.resolve(typeName)
+	if typeErr != nil {
+		if errors.As(typeErr, &ambiguous) {
+			return nil, typeErr
+		}
+		return nil, fmt.Errorf("function %s not found", name)
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("function %s not found", name)
+	}
+	recv := typeObj.Type()
+	if !types.IsInterface(recv) {
+		recv = types.NewPointer(recv)
+	}
+	if fn, ok := lookupMethod(recv, typeObj.Pkg(), method); ok {
+		return fn, nil
+	}
+	return nil, fmt.Errorf("%s has no method %s", typeName, method)
+}
+
+// lookupMethod finds a method in the method set of recv
+func lookupMethod(recv types.Type, pkg *types.Package, name string) (*types.Func, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(recv, true, pkg, name)
+	fn, ok := obj.(*types.Func)
+	return fn, ok
+}
+
+// funcDecl is the declaration of a function in an analyzed package
+type funcDecl struct {
+	importPath string
+	decl       *ast.FuncDecl
+}
+
+// funcDecls maps the functions and methods of all analyzed packages to
+// their declarations
+func (a *Analyzer) funcDecls() map[*types.Func]funcDecl {
+	decls := make(map[*types.Func]funcDecl)
+	for importPath, files := range a.asts {
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		for _, file := range files {
+			for _, decl := range file.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok {
+					if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+						decls[fn] = funcDecl{importPath: importPath, decl: fd}
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// funcName names a function as pkg.Func or pkg.Type.Method
+func funcName(fn *types.Func) string {
+	name := fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			name = named.Obj().Name() + "." + name
+		}
+	}
+	if fn.Pkg() != nil {
+		name = fn.Pkg().Name() + "." + name
+	}
+	return name
+}
+
+// inspectBody calls fn for every call in a function body. Function literals
+// are included since they usually run as part of the function.
+func inspectBody(body *ast.BlockStmt, fn func(*ast.CallExpr)) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			fn(call)
+		}
+		return true
+	})
+}
+
+// isBuiltin reports whether expr refers to the named builtin function
+func isBuiltin(info *types.Info, expr ast.Expr, name string) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	builtin, ok := info.Uses[ident].(*types.Builtin)
+	return ok && builtin.Name() == name
+}
+
+// staticCallee returns the function a call statically invokes. dynamic
+// reports calls through interface methods or function values.
+func staticCallee(info *types.Info, call *ast.CallExpr) (callee *types.Func, dynamic bool) {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		switch obj := info.Uses[fun].(type) {
+		case *types.Func:
+			return obj.Origin(), false
+		case *types.Var:
+			return nil, true
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[fun]; ok {
+			switch sel.Kind() {
+			case types.MethodVal:
+				if types.IsInterface(sel.Recv()) {
+					return nil, true
+				}
+				return sel.Obj().(*types.Func).Origin(), false
+			case types.FieldVal:
+				return nil, true
+			}
+			return nil, false
+		}
+		if fn, ok := info.Uses[fun.Sel].(*types.Func); ok {
+			return fn.Origin(), false // Package-qualified function
+		}
+		if _, ok := info.Uses[fun.Sel].(*types.Var); ok {
+			return nil, true
+		}
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		var x ast.Expr
+		if index, ok := fun.(*ast.IndexExpr); ok {
+			x = index.X
+		} else {
+			x = fun.(*ast.IndexListExpr).X
+		}
+		return staticCallee(info, &ast.CallExpr{Fun: x})
+	case *ast.FuncLit:
+		return nil, false
+	}
+	return nil, false
+}
+
+// errorType is the predeclared error interface
+var errorType = types.Universe.Lookup("error").Type()
+
+// collectErrorReturns records the return statements of decl that can return
+// a non-nil error, skipping those of nested function literals
+func (a *Analyzer) collectErrorReturns(paths *ErrorPaths, info *types.Info, fn *types.Func, decl *ast.FuncDecl) {
+	results := fn.Type().(*types.Signature).Results()
+	index := -1
+	for i := 0; i < results.Len(); i++ {
+		if types.Identical(results.At(i).Type(), errorType) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			ret := ErrorReturn{Position: a.position(n.Pos())}
+			switch {
+			case len(n.Results) == 0:
+				// A bare return yields the named error result
+				ret.Kind = ErrorPropagated
+				ret.Expression = results.At(index).Name()
+			case len(n.Results) == results.Len():
+				expr := n.Results[index]
+				if isNil(info, expr) {
+					return true
+				}
+				ret.Kind = classifyError(info, expr)
+				ret.Expression = types.ExprString(expr)
+			default:
+				// return f() with f returning the same results
+				ret.Kind = ErrorPropagated
+				ret.Expression = types.ExprString(n.Results[0])
+			}
+			paths.Returns = append(paths.Returns, ret)
+		}
+		return true
+	})
+}
+
+// isNil reports whether expr is the untyped nil
+func isNil(info *types.Info, expr ast.Expr) bool {
+	return info.Types[ast.Unparen(expr)].IsNil()
+}
+
+// classifyError describes how a returned error expression is built
+func classifyError(info *types.Info, expr ast.Expr) string {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		switch ast.Unparen(expr).(type) {
+		case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr:
+			return ErrorPropagated
+		}
+		return ErrorCreated
+	}
+
+	switch qualifiedFuncName(info, call.Fun) {
+	case "errors.New":
+		return ErrorCreated
+	case "errors.Join":
+		return ErrorWrapped
+	case "fmt.Errorf":
+		if len(call.Args) > 0 {
+			if format := info.Types[call.Args[0]].Value; format != nil && format.Kind() == constant.String &&
+				strings.Contains(constant.StringVal(format), "%w") {
+				return ErrorWrapped
+			}
+		}
+		for _, arg := range call.Args[1:] {
+			if t := info.TypeOf(arg); t != nil && types.Implements(t, errorType.Underlying().(*types.Interface)) {
+				return ErrorFlattened
+			}
+		}
+		return ErrorCreated
+	}
+	if tv, ok := info.Types[call.Fun]; ok && tv.IsType() {
+		return ErrorCreated // Conversion to an error type
+	}
+	return ErrorPropagated
+}
+
+// qualifiedFuncName returns "path.Name" for package-level functions
+func qualifiedFuncName(info *types.Info, fun ast.Expr) string {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	return fn.Pkg().Path() + "." + fn.Name()
+}
+
+// collectSwallowedErrors records errors assigned to _ and calls whose error
+// result is dropped, skipping nested function literals
+func (a *Analyzer) collectSwallowedErrors(paths *ErrorPaths, info *types.Info, body *ast.BlockStmt) {
+	isError := func(t types.Type) bool {
+		return t != nil && types.Identical(t, errorType)
+	}
+	returnsError := func(call *ast.CallExpr) bool {
+		switch t := info.TypeOf(call).(type) {
+		case *types.Tuple:
+			for i := 0; i < t.Len(); i++ {
+				if isError(t.At(i).Type()) {
+					return true
+				}
+			}
+			return false
+		default:
+			return isError(t)
+		}
+	}
+	record := func(kind string, node ast.Node, expr ast.Expr) {
+		paths.Swallowed = append(paths.Swallowed, SwallowedError{
+			Kind:       kind,
+			Expression: types.ExprString(expr),
+			Position:   a.position(node.Pos()),
+		})
+	}
+	blanks := func(node ast.Node, lhs []ast.Expr, rhs []ast.Expr) {
+		for i, target := range lhs {
+			if ident, ok := target.(*ast.Ident); !ok || ident.Name != "_" {
+				continue
+			}
+			switch {
+			case len(rhs) == len(lhs):
+				if isError(info.TypeOf(rhs[i])) {
+					record(ErrorBlank, node, rhs[i])
+				}
+			case len(rhs) == 1:
+				if tuple, ok := info.TypeOf(rhs[0]).(*types.Tuple); ok && i < tuple.Len() && isError(tuple.At(i).Type()) {
+					record(ErrorBlank, node, rhs[0])
+				}
+			}
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			blanks(n, n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			blanks(n, lhs, n.Values)
+		case *ast.ExprStmt:
+			if call, ok := n.X.(*ast.CallExpr); ok && returnsError(call) {
+				record(ErrorIgnored, n, call)
+			}
+		case *ast.DeferStmt:
+			if returnsError(n.Call) {
+				record(ErrorDeferred, n, n.Call)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestErrorPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrMissing = errors.New("missing")
+
+type Store struct {
+	w io.Writer
+}
+
+func (s *Store) Save(name string) error {
+	if name == "" {
+		return ErrMissing
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", name, err)
+	}
+	defer f.Close()
+	_ = f.Sync()
+	f.Chmod(0644)
+	_, _ = s.w.Write(nil)
+	if err := validate(name); err != nil {
+		return fmt.Errorf("invalid: %v", err)
+	}
+	if len(name) > 100 {
+		return errors.New("name too long")
+	}
+	func() error {
+		return fmt.Errorf("ignored closure")
+	}()
+	return check(name)
+}
+
+func validate(name string) error {
+	mustBeASCII(name)
+	return nil
+}
+
+func mustBeASCII(name string) {
+	for _, r := range name {
+		if r > 127 {
+			panic("non-ASCII name")
+		}
+	}
+}
+
+func check(name string) error {
+	return nil
+}
+
+func Plain() int { return 1 }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	paths, err := analyzer.ErrorPaths(context.Background(), "Store.Save", 0)
+	if err != nil {
+		t.Fatalf("Failed to analyze error paths: %v", err)
+	}
+	if paths.Function != "store.Store.Save" {
+		t.Errorf("Expected function store.Store.Save, got %s", paths.Function)
+	}
+
+	var returns []string
+	for _, ret := range paths.Returns {
+		returns = append(returns, ret.Kind+" "+ret.Expression)
+	}
+	wantReturns := []string{
+		"propagated ErrMissing",
+		`wrapped fmt.Errorf("open %s: %w", name, err)`,
+		`flattened fmt.Errorf("invalid: %v", err)`,
+		`created errors.New("name too long")`,
+		"propagated check(name)",
+	}
+	if !reflect.DeepEqual(returns, wantReturns) {
+		t.Errorf("Expected returns %v, got %v", wantReturns, returns)
+	}
+
+	var swallowed []string
+	for _, s := range paths.Swallowed {
+		swallowed = append(swallowed, s.Kind+" "+s.Expression)
+	}
+	wantSwallowed := []string{
+		"deferred f.Close()",
+		"blank f.Sync()",
+		"ignored f.Chmod(0644)",
+		"blank s.w.Write(nil)",
+		"ignored (func() error literal)()",
+	}
+	if !reflect.DeepEqual(swallowed, wantSwallowed) {
+		t.Errorf("Expected swallowed errors %v, got %v", wantSwallowed, swallowed)
+	}
+	if paths.DynamicCalls != 1 {
+		t.Errorf("Expected one dynamic call (s.w.Write), got %d", paths.DynamicCalls)
+	}
+
+	if len(paths.Panics) != 1 {
+		t.Fatalf("Expected one reachable panic, got %v", paths.Panics)
+	}
+	panicSite := paths.Panics[0]
+	wantChain := []string{"store.Store.Save", "store.validate", "store.mustBeASCII"}
+	if !reflect.DeepEqual(panicSite.Chain, wantChain) || panicSite.Argument != `"non-ASCII name"` {
+		t.Errorf("Unexpected panic site %+v", panicSite)
+	}
+
+	if paths, err := analyzer.ErrorPaths(context.Background(), "store.Store.Save", 1); err != nil || len(paths.Panics) != 0 {
+		t.Errorf("Expected no panics within depth 1, got %v (%v)", paths, err)
+	}
+	if paths, err := analyzer.ErrorPaths(context.Background(), "Plain", 0); err != nil || len(paths.Returns) != 0 {
+		t.Errorf("Expected no error returns for Plain, got %v (%v)", paths, err)
+	}
+	if _, err := analyzer.ErrorPaths(context.Background(), "ErrMissing", 0); err == nil {
+		t.Error("Expected error for a variable")
+	}
+	if _, err := analyzer.ErrorPaths(context.Background(), "Store.Missing", 0); err == nil {
+		t.Error("Expected error for an unknown method")
+	}
+}
@@ -0,0 +1,437 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Kinds of wrap sites
+const (
+	WrapErrorf  = "wrap"    // fmt.Errorf with %w
+	WrapJoin    = "join"    // errors.Join
+	WrapFlatten = "flatten" // fmt.Errorf formatting an error without %w
+)
+
+// ErrorTaxonomy inventories the errors of the analyzed packages: sentinel
+// values, error types, the places errors are wrapped, which errors can wrap
+// which, and comparisons that errors.Is should make
+type ErrorTaxonomy struct {
+	Sentinels []SentinelError `json:"sentinels"`
+	Types     []ErrorTypeInfo `json:"types"`
+	WrapSites []WrapSite      `json:"wrap_sites"`
+	// Edges are the graph of which errors can wrap which
+	Edges       []ErrorEdge       `json:"edges"`
+	Comparisons []ErrorComparison `json:"comparisons"`
+}
+
+// SentinelError is a package-level error variable
+type SentinelError struct {
+	// Name is pkg.Name
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Message is the text of an errors.New or fmt.Errorf initializer
+	Message  string   `json:"message,omitempty"`
+	Exported bool     `json:"exported"`
+	Position Position `json:"position"`
+}
+
+// ErrorTypeInfo is a named type implementing error
+type ErrorTypeInfo struct {
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Pointer is set when only the pointer implements error
+	Pointer bool `json:"pointer,omitempty"`
+	// Unwrap is the result type of the type's Unwrap method, error or
+	// []error; empty without one
+	Unwrap string `json:"unwrap,omitempty"`
+	// Is and As report methods customizing errors.Is and errors.As
+	Is       bool     `json:"is,omitempty"`
+	As       bool     `json:"as,omitempty"`
+	Position Position `json:"position"`
+}
+
+// WrapSite is a call wrapping errors, or formatting one into a new error
+// without %w so that errors.Is and errors.As no longer see it
+type WrapSite struct {
+	Kind     string `json:"kind"`
+	Function string `json:"function,omitempty"`
+	// Format is the format string of fmt.Errorf
+	Format string `json:"format,omitempty"`
+	// Wraps are the wrapped expressions, or the formatted ones for flatten
+	Wraps    []string `json:"wraps"`
+	Position Position `json:"position"`
+}
+
+// ErrorEdge records that the error From can wrap the error To. From is an
+// error type whose fields hold To, or the function whose wrap site wraps
+// To; To is a sentinel or error type.
+type ErrorEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Via is fmt.Errorf, errors.Join or field
+	Via      string   `json:"via"`
+	Position Position `json:"position"`
+}
+
+// ErrorComparison compares errors with == or != or switches on an error,
+// which misses wrapped errors
+type ErrorComparison struct {
+	Expr     string   `json:"expr"`
+	Function string   `json:"function,omitempty"`
+	Fix      string   `json:"fix"`
+	Position Position `json:"position"`
+}
+
+// ErrorTaxonomy inventories the sentinel errors, error types, wrap sites
+// and error comparisons of the packages a qualifier selects, or of every
+// package. Edges connect wrappers to the named errors they wrap, including
+// sentinels of other packages such as io.EOF. Comparisons inside Is methods
+// are left out, since implementing errors.Is is what they are for.
+func (a *Analyzer) ErrorTaxonomy(ctx context.Context, pkg string) (*ErrorTaxonomy, error) {
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	var importPaths []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	if len(importPaths) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	taxonomy := &ErrorTaxonomy{
+		Sentinels:   []SentinelError{},
+		Types:       []ErrorTypeInfo{},
+		WrapSites:   []WrapSite{},
+		Edges:       []ErrorEdge{},
+		Comparisons: []ErrorComparison{},
+	}
+	for _, importPath := range importPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a.collectErrorDecls(taxonomy, importPath)
+		a.collectErrorUses(taxonomy, importPath)
+	}
+	return taxonomy, nil
+}
+
+// collectErrorDecls records the sentinel errors and error types a package
+// declares
+func (a *Analyzer) collectErrorDecls(taxonomy *ErrorTaxonomy, importPath string) {
+	pkg := a.pkgs[importPath]
+	info := a.infos[importPath]
+	if pkg == nil || info == nil {
+		return
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Var:
+			if !isErrorType(obj.Type()) {
+				continue
+			}
+			taxonomy.Sentinels = append(taxonomy.Sentinels, SentinelError{
+				Name:       pkg.Name() + "." + name,
+				ImportPath: importPath,
+				Message:    a.sentinelMessage(info, obj),
+				Exported:   obj.Exported(),
+				Position:   a.position(obj.Pos()),
+			})
+		case *types.TypeName:
+			if obj.IsAlias() || types.IsInterface(obj.Type()) {
+				continue
+			}
+			pointer := false
+			if !isErrorType(obj.Type()) {
+				if !isErrorType(types.NewPointer(obj.Type())) {
+					continue
+				}
+				pointer = true
+			}
+			methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+			errType := ErrorTypeInfo{
+				Name:       pkg.Name() + "." + name,
+				ImportPath: importPath,
+				Pointer:    pointer,
+				Is:         methods.Lookup(pkg, "Is") != nil,
+				As:         methods.Lookup(pkg, "As") != nil,
+				Position:   a.position(obj.Pos()),
+			}
+			if sel := methods.Lookup(pkg, "Unwrap"); sel != nil {
+				if results := sel.Type().(*types.Signature).Results(); results.Len() == 1 {
+					errType.Unwrap = types.TypeString(results.At(0).Type(), nil)
+				}
+			}
+			taxonomy.Types = append(taxonomy.Types, errType)
+		}
+	}
+}
+
+// sentinelMessage returns the constant message a sentinel is created with
+func (a *Analyzer) sentinelMessage(info *types.Info, v *types.Var) string {
+	for _, init := range info.InitOrder {
+		if len(init.Lhs) != 1 || init.Lhs[0] != v {
+			continue
+		}
+		call, ok := ast.Unparen(init.Rhs).(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return ""
+		}
+		switch qualifiedFuncName(info, call.Fun) {
+		case "errors.New", "fmt.Errorf":
+			if value := info.Types[call.Args[0]].Value; value != nil && value.Kind() == constant.String {
+				return constant.StringVal(value)
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// collectErrorUses records the wrap sites, wrapping edges and error
+// comparisons of a package
+func (a *Analyzer) collectErrorUses(taxonomy *ErrorTaxonomy, importPath string) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			function := ""
+			inIs := false
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				function = fd.Name.Name
+				if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+					function = funcName(fn)
+				}
+				inIs = fd.Recv != nil && fd.Name.Name == "Is"
+			}
+			// Wrap sites at package level wrap for the package
+			wrapper := function
+			if wrapper == "" {
+				wrapper = importPath
+			}
+
+			ast.Inspect(decl, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.CallExpr:
+					if site, ok := a.wrapSite(info, n, function); ok {
+						taxonomy.WrapSites = append(taxonomy.WrapSites, site)
+						via := "fmt.Errorf"
+						if site.Kind == WrapJoin {
+							via = "errors.Join"
+						}
+						for _, arg := range wrappedArgs(info, n) {
+							if to := errorName(info, arg); to != "" {
+								taxonomy.Edges = append(taxonomy.Edges, ErrorEdge{From: wrapper, To: to, Via: via, Position: a.position(arg.Pos())})
+							}
+						}
+					}
+				case *ast.CompositeLit:
+					from := errorName(info, n)
+					if from == "" {
+						return true
+					}
+					for _, elt := range n.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok {
+							elt = kv.Value
+						}
+						if to := errorName(info, elt); to != "" && to != from {
+							taxonomy.Edges = append(taxonomy.Edges, ErrorEdge{From: from, To: to, Via: "field", Position: a.position(elt.Pos())})
+						}
+					}
+				case *ast.BinaryExpr:
+					if inIs || (n.Op != token.EQL && n.Op != token.NEQ) || !comparesErrors(info, n.X, n.Y) {
+						return true
+					}
+					fix := fmt.Sprintf("errors.Is(%s, %s)", types.ExprString(n.X), types.ExprString(n.Y))
+					if n.Op == token.NEQ {
+						fix = "!" + fix
+					}
+					taxonomy.Comparisons = append(taxonomy.Comparisons, ErrorComparison{
+						Expr:     types.ExprString(n),
+						Function: function,
+						Fix:      fix,
+						Position: a.position(n.OpPos),
+					})
+				case *ast.SwitchStmt:
+					if inIs || n.Tag == nil || !isErrorValue(info, n.Tag) {
+						return true
+					}
+					for _, stmt := range n.Body.List {
+						for _, expr := range stmt.(*ast.CaseClause).List {
+							if isNil(info, expr) {
+								continue
+							}
+							taxonomy.Comparisons = append(taxonomy.Comparisons, ErrorComparison{
+								Expr:     fmt.Sprintf("switch %s { case %s }", types.ExprString(n.Tag), types.ExprString(expr)),
+								Function: function,
+								Fix:      fmt.Sprintf("case errors.Is(%s, %s): in a switch without a tag", types.ExprString(n.Tag), types.ExprString(expr)),
+								Position: a.position(expr.Pos()),
+							})
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// wrapSite describes a call of fmt.Errorf or errors.Join that wraps errors
+// or formats one away
+func (a *Analyzer) wrapSite(info *types.Info, call *ast.CallExpr, function string) (WrapSite, bool) {
+	site := WrapSite{Function: function, Wraps: []string{}, Position: a.position(call.Pos())}
+	switch qualifiedFuncName(info, call.Fun) {
+	case "errors.Join":
+		site.Kind = WrapJoin
+		for _, arg := range call.Args {
+			site.Wraps = append(site.Wraps, types.ExprString(arg))
+		}
+		return site, len(call.Args) > 0
+	case "fmt.Errorf":
+		if len(call.Args) == 0 {
+			return site, false
+		}
+		if value := info.Types[call.Args[0]].Value; value != nil && value.Kind() == constant.String {
+			site.Format = constant.StringVal(value)
+		}
+		if wrapped := wrappedArgs(info, call); len(wrapped) > 0 {
+			site.Kind = WrapErrorf
+			for _, arg := range wrapped {
+				site.Wraps = append(site.Wraps, types.ExprString(arg))
+			}
+			return site, true
+		}
+		for _, arg := range call.Args[1:] {
+			if isErrorValue(info, arg) {
+				site.Kind = WrapFlatten
+				site.Wraps = append(site.Wraps, types.ExprString(arg))
+			}
+		}
+		return site, site.Kind != ""
+	}
+	return site, false
+}
+
+// wrappedArgs returns the arguments of errors.Join, or those fmt.Errorf
+// formats with %w
+func wrappedArgs(info *types.Info, call *ast.CallExpr) []ast.Expr {
+	switch qualifiedFuncName(info, call.Fun) {
+	case "errors.Join":
+		return call.Args
+	case "fmt.Errorf":
+		if len(call.Args) == 0 {
+			return nil
+		}
+		value := info.Types[call.Args[0]].Value
+		if value == nil || value.Kind() != constant.String {
+			return nil
+		}
+		var wrapped []ast.Expr
+		for i, verb := range formatVerbs(constant.StringVal(value)) {
+			if verb == 'w' && i+1 < len(call.Args) {
+				wrapped = append(wrapped, call.Args[i+1])
+			}
+		}
+		return wrapped
+	}
+	return nil
+}
+
+// formatVerbs returns the verbs of a format string in argument order
+func formatVerbs(format string) []byte {
+	var verbs []byte
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		// Skip flags, width and precision
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] != '%' {
+			verbs = append(verbs, format[i])
+		}
+	}
+	return verbs
+}
+
+// errorName names the sentinel or error type an expression refers to: a
+// package-level error variable, or a value, pointer or conversion of a
+// named error type. It returns "" for other expressions.
+func errorName(info *types.Info, expr ast.Expr) string {
+	expr = ast.Unparen(expr)
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = ast.Unparen(unary.X)
+	}
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	case *ast.CompositeLit:
+		return errorTypeName(info.TypeOf(e))
+	case *ast.CallExpr:
+		if tv, ok := info.Types[e.Fun]; ok && tv.IsType() {
+			return errorTypeName(tv.Type)
+		}
+		return ""
+	default:
+		return ""
+	}
+	v, ok := info.Uses[ident].(*types.Var)
+	if !ok || v.Pkg() == nil || v.Parent() != v.Pkg().Scope() || !isErrorType(v.Type()) {
+		return ""
+	}
+	return v.Pkg().Name() + "." + v.Name()
+}
+
+// errorTypeName returns pkg.Type for a named type whose value or pointer
+// implements error, or "" for other types
+func errorTypeName(t types.Type) string {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || types.IsInterface(named) || named.Obj().Pkg() == nil {
+		return ""
+	}
+	if !isErrorType(named) && !isErrorType(types.NewPointer(named)) {
+		return ""
+	}
+	return named.Obj().Pkg().Name() + "." + named.Obj().Name()
+}
+
+// isErrorType reports whether t implements error
+func isErrorType(t types.Type) bool {
+	return types.Implements(t, errorType.Underlying().(*types.Interface))
+}
+
+// isErrorValue reports whether expr is a non-nil value implementing error
+func isErrorValue(info *types.Info, expr ast.Expr) bool {
+	t := info.TypeOf(expr)
+	return t != nil && !isNil(info, expr) && isErrorType(t)
+}
+
+// comparesErrors reports whether a comparison is between two errors, where
+// one is an interface and neither is nil
+func comparesErrors(info *types.Info, x, y ast.Expr) bool {
+	if !isErrorValue(info, x) || !isErrorValue(info, y) {
+		return false
+	}
+	return types.IsInterface(info.TypeOf(x)) || types.IsInterface(info.TypeOf(y))
+}
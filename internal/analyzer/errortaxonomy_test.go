@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/store\n\ngo 1.21\n",
+		"store.go": `package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound is returned for missing keys
+var ErrNotFound = errors.New("not found")
+
+var errClosed = fmt.Errorf("closed")
+
+// KeyError reports a failed key
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string { return e.Key + ": " + e.Err.Error() }
+
+func (e *KeyError) Unwrap() error { return e.Err }
+
+func (e *KeyError) Is(target error) bool { return target == ErrNotFound }
+
+// Code is an error code
+type Code int
+
+func (c Code) Error() string { return fmt.Sprint(int(c)) }
+
+func Get(key string) error {
+	if key == "" {
+		return &KeyError{Key: key, Err: ErrNotFound}
+	}
+	return fmt.Errorf("get %q: %w", key, ErrNotFound)
+}
+
+func Read(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	if err != errClosed {
+		return errors.Join(err, errClosed)
+	}
+	return fmt.Errorf("read: %v", err)
+}
+
+func Kind(err error) string {
+	switch err {
+	case nil:
+		return "none"
+	case ErrNotFound:
+		return "missing"
+	}
+	return "other"
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	taxonomy, err := analyzer.ErrorTaxonomy(context.Background(), "store")
+	if err != nil {
+		t.Fatalf("Failed to build error taxonomy: %v", err)
+	}
+
+	if len(taxonomy.Sentinels) != 2 || taxonomy.Sentinels[0].Name != "store.ErrNotFound" || taxonomy.Sentinels[0].Message != "not found" || !taxonomy.Sentinels[0].Exported ||
+		taxonomy.Sentinels[1].Name != "store.errClosed" || taxonomy.Sentinels[1].Message != "closed" {
+		t.Errorf("Unexpected sentinels: %+v", taxonomy.Sentinels)
+	}
+
+	if len(taxonomy.Types) != 2 {
+		t.Fatalf("Expected 2 error types, got %+v", taxonomy.Types)
+	}
+	if code := taxonomy.Types[0]; code.Name != "store.Code" || code.Pointer || code.Unwrap != "" {
+		t.Errorf("Unexpected Code: %+v", code)
+	}
+	if keyErr := taxonomy.Types[1]; keyErr.Name != "store.KeyError" || !keyErr.Pointer || keyErr.Unwrap != "error" || !keyErr.Is || keyErr.As {
+		t.Errorf("Unexpected KeyError: %+v", keyErr)
+	}
+
+	var sites []string
+	for _, site := range taxonomy.WrapSites {
+		sites = append(sites, site.Kind+":"+site.Function+":"+strings.Join(site.Wraps, ","))
+	}
+	if got := strings.Join(sites, " "); got != "wrap:store.Get:ErrNotFound join:store.Read:err,errClosed flatten:store.Read:err" {
+		t.Errorf("Unexpected wrap sites: %s", got)
+	}
+
+	var edges []string
+	for _, edge := range taxonomy.Edges {
+		edges = append(edges, edge.From+"->"+edge.To+" via "+edge.Via)
+	}
+	if got := strings.Join(edges, "; "); got != "store.KeyError->store.ErrNotFound via field; store.Get->store.ErrNotFound via fmt.Errorf; store.Read->store.errClosed via errors.Join" {
+		t.Errorf("Unexpected edges: %s", got)
+	}
+
+	var fixes []string
+	for _, comparison := range taxonomy.Comparisons {
+		fixes = append(fixes, comparison.Function+": "+comparison.Fix)
+	}
+	if got := strings.Join(fixes, "; "); got != "store.Read: errors.Is(err, io.EOF); store.Read: !errors.Is(err, errClosed); store.Kind: case errors.Is(err, ErrNotFound): in a switch without a tag" {
+		t.Errorf("Unexpected comparisons: %s", got)
+	}
+
+	if _, err := analyzer.ErrorTaxonomy(context.Background(), "nosuchpkg"); err == nil {
+		t.Error("Expected error for an unknown package")
+	}
+}
@@ -0,0 +1,275 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/types"
+	"path/filepath"
+	"sort"
+)
+
+// maxUsageExamples is how many synthesized examples GetExample returns
+const maxUsageExamples = 3
+
+// maxExampleLines bounds the statement a synthesized example is built
+// around; longer statements are too noisy to serve as examples
+const maxExampleLines = 12
+
+// maxExampleContext bounds the preceding statements kept as context
+const maxExampleContext = 3
+
+// UsageExample is a use of a symbol mined from the repository. GetExample
+// falls back to these when a symbol has no godoc Example functions.
+type UsageExample struct {
+	Symbol string `json:"symbol"`
+	// Function is the function containing the use, as pkg.Func or pkg.Type.Method
+	Function string `json:"function"`
+	// Code is the statement using the symbol, preceded by the statements of
+	// the same block declaring the local variables it uses
+	Code     string   `json:"code"`
+	Position Position `json:"position"`
+	// Complexity is the number of syntax nodes in Code; simpler examples
+	// rank first
+	Complexity int `json:"complexity"`
+}
+
+// UsageExamples mines the uses of the symbol named topic (a type, function,
+// variable, constant or Type.Method, optionally qualified) inside function
+// bodies of the analyzed packages and returns up to limit of them, simplest
+// first. Uses inside the declaration of a function itself are skipped.
+func (a *Analyzer) UsageExamples(ctx context.Context, topic string, limit int) ([]UsageExample, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	return a.usageExamples(ctx, topic, limit)
+}
+
+// usageExamples implements UsageExamples; callers hold the read lock
+func (a *Analyzer) usageExamples(ctx context.Context, topic string, limit int) ([]UsageExample, error) {
+	return a.examplesOf(ctx, a.exampleTargets(topic), limit)
+}
+
+// examplesOf mines the uses of the targets, named by their values; callers
+// hold the read lock
+func (a *Analyzer) examplesOf(ctx context.Context, targets map[types.Object]string, limit int) ([]UsageExample, error) {
+	if len(targets) == 0 {
+		return []UsageExample{}, nil
+	}
+
+	var examples []UsageExample
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				fn, _ := info.Defs[fd.Name].(*types.Func)
+				examples = append(examples, a.examplesIn(info, fd, fn, targets)...)
+			}
+		}
+	}
+
+	sort.SliceStable(examples, func(i, j int) bool {
+		if examples[i].Complexity != examples[j].Complexity {
+			return examples[i].Complexity < examples[j].Complexity
+		}
+		pi, pj := examples[i].Position, examples[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+
+	// Identical snippets add nothing, and a statement using the symbol
+	// twice would otherwise appear twice
+	seen := make(map[string]bool)
+	unique := []UsageExample{}
+	for _, example := range examples {
+		if seen[example.Code] {
+			continue
+		}
+		seen[example.Code] = true
+		unique = append(unique, example)
+		if limit > 0 && len(unique) == limit {
+			break
+		}
+	}
+	return unique, nil
+}
+
+// exampleTargets resolves topic to the objects whose uses make examples,
+// with the name to show for each. Ambiguous names select every match.
+func (a *Analyzer) exampleTargets(topic string) map[types.Object]string {
+	targets := make(map[types.Object]string)
+	qualifier, ident := splitQualifiedName(topic)
+	for _, sym := range a.index.lookup(qualifier, ident) {
+		targets[sym.obj] = sym.obj.Pkg().Name() + "." + sym.obj.Name()
+	}
+	if len(targets) > 0 {
+		return targets
+	}
+	if fn, err := a.resolveFunc(topic); err == nil {
+		targets[fn] = funcName(fn)
+	}
+	return targets
+}
+
+// examplesIn returns an example for each use of a target in the body of a
+// function declaration
+func (a *Analyzer) examplesIn(info *types.Info, fd *ast.FuncDecl, fn *types.Func, targets map[types.Object]string) []UsageExample {
+	if fn != nil {
+		if _, ok := targets[fn]; ok {
+			return nil
+		}
+	}
+	function := fd.Name.Name
+	if fn != nil {
+		function = funcName(fn)
+	}
+
+	var examples []UsageExample
+	var stack []ast.Node
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		symbol, ok := targets[originOf(info.Uses[ident])]
+		if !ok {
+			return true
+		}
+		stmts := exampleStatements(info, stack)
+		if stmts == nil || a.lines(stmts[len(stmts)-1]) > maxExampleLines {
+			return true
+		}
+		code, complexity := a.renderExample(stmts)
+		examples = append(examples, UsageExample{
+			Symbol:     symbol,
+			Function:   function,
+			Code:       code,
+			Position:   a.position(ident.Pos()),
+			Complexity: complexity,
+		})
+		return true
+	})
+	return examples
+}
+
+// exampleStatements picks the innermost statement on the path to a use,
+// preceded by the statements of its block declaring local variables it
+// uses
+func exampleStatements(info *types.Info, path []ast.Node) []ast.Stmt {
+	for i := len(path) - 1; i > 0; i-- {
+		stmt, ok := path[i].(ast.Stmt)
+		if !ok {
+			continue
+		}
+		switch stmt.(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			continue
+		}
+
+		var block []ast.Stmt
+		switch parent := path[i-1].(type) {
+		case *ast.BlockStmt:
+			block = parent.List
+		case *ast.CaseClause:
+			block = parent.Body
+		case *ast.CommClause:
+			block = parent.Body
+		}
+		return append(exampleContext(info, block, stmt), stmt)
+	}
+	return nil
+}
+
+// exampleContext returns the statements of block before stmt that declare
+// the local variables stmt uses, nearest last, up to maxExampleContext
+func exampleContext(info *types.Info, block []ast.Stmt, stmt ast.Stmt) []ast.Stmt {
+	used := make(map[types.Object]bool)
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if v, ok := info.Uses[ident].(*types.Var); ok && !v.IsField() && v.Pkg() != nil && v.Parent() != v.Pkg().Scope() {
+				used[v] = true
+			}
+		}
+		return true
+	})
+
+	var preceding []ast.Stmt
+	for i := len(block) - 1; i >= 0 && len(used) > 0 && len(preceding) < maxExampleContext; i-- {
+		if block[i].Pos() >= stmt.Pos() {
+			continue
+		}
+		declares := false
+		ast.Inspect(block[i], func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && used[info.Defs[ident]] {
+				delete(used, info.Defs[ident])
+				declares = true
+			}
+			return true
+		})
+		if declares {
+			preceding = append([]ast.Stmt{block[i]}, preceding...)
+		}
+	}
+	return preceding
+}
+
+// lines counts the source lines spanned by a node
+func (a *Analyzer) lines(node ast.Node) int {
+	return a.fset.Position(node.End()).Line - a.fset.Position(node.Pos()).Line + 1
+}
+
+// renderExample formats statements as source and counts their syntax nodes
+func (a *Analyzer) renderExample(stmts []ast.Stmt) (string, int) {
+	var buf bytes.Buffer
+	complexity := 0
+	for i, stmt := range stmts {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if err := printer.Fprint(&buf, a.fset, stmt); err != nil {
+			continue
+		}
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if n != nil {
+				complexity++
+			}
+			return true
+		})
+	}
+	return buf.String(), complexity
+}
+
+// formatUsageExample renders a synthesized example for GetExample
+func (a *Analyzer) formatUsageExample(example UsageExample) string {
+	filename := example.Position.Filename
+	if rel, err := filepath.Rel(a.repoPath, filename); err == nil {
+		filename = filepath.ToSlash(rel)
+	}
+	return fmt.Sprintf("Usage of %s in %s (%s:%d)\n%s",
+		example.Symbol, example.Function, filename, example.Position.Line, example.Code)
+}
@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUsageExamples(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"client/client.go": `package client
+
+type Client struct {
+	Addr string
+}
+
+func Dial(addr string) (*Client, error) {
+	return Dial(addr)
+}
+
+func (c *Client) Send(msg string) error { return nil }
+`,
+		"app/app.go": `package app
+
+import (
+	"fmt"
+
+	"example.com/app/client"
+)
+
+func Run(addr string) error {
+	fmt.Println("starting")
+	c, err := client.Dial(addr)
+	if err != nil {
+		return err
+	}
+	msg := "hello"
+	return c.Send(msg)
+}
+
+func Quick() {
+	client.Dial("localhost:80")
+}
+
+func Retry(addrs []string) {
+	for _, addr := range addrs {
+		if _, err := client.Dial(addr); err == nil {
+			return
+		}
+	}
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	examples, err := analyzer.UsageExamples(ctx, "Dial", 0)
+	if err != nil {
+		t.Fatalf("UsageExamples failed: %v", err)
+	}
+	// The recursive call inside Dial itself is not an example, and the
+	// statement initializing the if in Retry stands on its own
+	if len(examples) != 3 {
+		t.Fatalf("Expected 3 examples, got %+v", examples)
+	}
+	if examples[0].Function != "app.Quick" || examples[0].Code != `client.Dial("localhost:80")` {
+		t.Errorf("Expected the simplest call first, got %+v", examples[0])
+	}
+	for _, example := range examples {
+		if example.Symbol != "client.Dial" {
+			t.Errorf("Expected symbol client.Dial, got %s", example.Symbol)
+		}
+	}
+	for i := 1; i < len(examples); i++ {
+		if examples[i].Complexity < examples[i-1].Complexity {
+			t.Errorf("Expected examples ordered by complexity, got %+v", examples)
+		}
+	}
+
+	// Statements declaring the variables a use needs are kept as context,
+	// unrelated ones are not
+	send, err := analyzer.UsageExamples(ctx, "Client.Send", 0)
+	if err != nil {
+		t.Fatalf("UsageExamples failed: %v", err)
+	}
+	if len(send) != 1 {
+		t.Fatalf("Expected 1 example of Client.Send, got %+v", send)
+	}
+	want := "c, err := client.Dial(addr)\nmsg := \"hello\"\nreturn c.Send(msg)"
+	if send[0].Code != want {
+		t.Errorf("Expected code %q, got %q", want, send[0].Code)
+	}
+
+	if limited, err := analyzer.UsageExamples(ctx, "client.Dial", 1); err != nil || len(limited) != 1 {
+		t.Errorf("Expected 1 example with a limit, got %d (%v)", len(limited), err)
+	}
+	if none, err := analyzer.UsageExamples(ctx, "Missing", 0); err != nil || len(none) != 0 {
+		t.Errorf("Expected no examples for an unknown symbol, got %+v (%v)", none, err)
+	}
+
+	example, err := analyzer.GetExample(ctx, "Dial")
+	if err != nil {
+		t.Fatalf("GetExample failed: %v", err)
+	}
+	if !strings.HasPrefix(example, "Usage of client.Dial in app.Quick (app/app.go:") {
+		t.Errorf("Expected synthesized examples from GetExample, got %q", example)
+	}
+}
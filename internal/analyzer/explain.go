@@ -0,0 +1,557 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultExplainTokens is the budget of an explanation unless told otherwise
+const DefaultExplainTokens = 1500
+
+// DefaultExplainUsages is how many referencing declarations an explanation
+// lists unless told otherwise
+const DefaultExplainUsages = 5
+
+// bytesPerToken approximates how many bytes of Go and Markdown make a token
+const bytesPerToken = 4
+
+// Sections of an explanation, as named in Explanation.Omitted
+const (
+	SectionDoc        = "doc"
+	SectionMethods    = "methods"
+	SectionInterfaces = "interfaces"
+	SectionUsages     = "usages"
+	SectionExample    = "example"
+)
+
+// ExplainOptions configure ExplainSymbol
+type ExplainOptions struct {
+	// Tokens is the budget of the Markdown, estimated at four bytes a
+	// token; defaults to DefaultExplainTokens
+	Tokens int
+	// Usages is the number of referencing declarations listed; defaults to
+	// DefaultExplainUsages
+	Usages int
+}
+
+// Explanation is a Markdown answer to "what is this symbol": its
+// declaration and doc, methods, interfaces, most frequent users and an
+// example
+type Explanation struct {
+	Symbol     string   `json:"symbol"`
+	Kind       string   `json:"kind"`
+	ImportPath string   `json:"import_path"`
+	Position   Position `json:"position"`
+	// References counts the uses of the symbol outside its own declaration
+	References int    `json:"references"`
+	Markdown   string `json:"markdown"`
+	// Tokens estimates the size of Markdown
+	Tokens int `json:"tokens"`
+	// Omitted names the sections shortened or left out to fit the budget
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// Referrer is a declaration using a symbol, with how often it does
+type Referrer struct {
+	// Name is the declaration as pkg.Func, pkg.Type.Method or pkg.Name
+	Name       string   `json:"name"`
+	Position   Position `json:"position"`
+	References int      `json:"references"`
+}
+
+// ExplainSymbol gathers what is usually asked about a symbol one call at a
+// time into a single Markdown answer: its declaration and doc comment, the
+// methods of a type, the interfaces it implements or that implement it,
+// the declarations using it most, and an example. The name is a
+// package-level symbol, optionally qualified, or Type.Method. Sections are
+// added in that order of importance while they fit the token budget; lists
+// are shortened and the doc comment cut before a section is left out. The
+// declaration is always included.
+func (a *Analyzer) ExplainSymbol(ctx context.Context, name string, opts ExplainOptions) (*Explanation, error) {
+	tokens := opts.Tokens
+	if tokens <= 0 {
+		tokens = DefaultExplainTokens
+	}
+	usages := opts.Usages
+	if usages <= 0 {
+		usages = DefaultExplainUsages
+	}
+
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(name)
+	if err != nil {
+		var ambiguous *AmbiguousError
+		if errors.As(err, &ambiguous) {
+			return nil, err
+		}
+		fn, fnErr := a.resolveFunc(name)
+		if fnErr != nil {
+			return nil, fmt.Errorf("symbol %s not found", name)
+		}
+		obj = fn
+	}
+	if _, local := a.pkgs[obj.Pkg().Path()]; !local {
+		return nil, fmt.Errorf("%s is not declared in the repository", name)
+	}
+
+	def := a.definitionOf(obj, obj.Pkg())
+	explanation := &Explanation{
+		Symbol:     explainedName(obj),
+		Kind:       def.Kind,
+		ImportPath: def.ImportPath,
+	}
+	if def.Position != nil {
+		explanation.Position = *def.Position
+	}
+
+	referrers, err := a.referrers(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range referrers {
+		explanation.References += r.References
+	}
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "# %s\n\n", explanation.Symbol)
+	fmt.Fprintf(&header, "%s in `%s`", def.Kind, def.ImportPath)
+	if def.Position != nil {
+		fmt.Fprintf(&header, ", %s", a.shortPosition(*def.Position))
+	}
+	fmt.Fprintf(&header, "\n\n```go\n%s\n```\n", a.declarationSource(obj, def))
+
+	sections := map[string]*explainSection{
+		SectionDoc: {name: SectionDoc, body: strings.TrimSpace(def.Doc), cut: true},
+	}
+	if typeObj, ok := obj.(*types.TypeName); ok {
+		sections[SectionMethods] = &explainSection{name: SectionMethods, title: "Methods", items: methodItems(typeObj)}
+		sections[SectionInterfaces] = &explainSection{name: SectionInterfaces, title: "Interfaces", items: interfaceItems(a.hierarchyOf(typeObj))}
+	}
+	usageSection := &explainSection{name: SectionUsages, title: "Usages"}
+	if len(referrers) == 0 {
+		usageSection.intro = "No references in the repository."
+	} else {
+		usageSection.intro = fmt.Sprintf("%d references from %d declarations; the most frequent:", explanation.References, len(referrers))
+		for _, r := range referrers[:min(usages, len(referrers))] {
+			usageSection.items = append(usageSection.items,
+				fmt.Sprintf("`%s` (%s): %d", r.Name, a.shortPosition(r.Position), r.References))
+		}
+	}
+	sections[SectionUsages] = usageSection
+	example, err := a.explainExample(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	sections[SectionExample] = &explainSection{name: SectionExample, title: "Example", body: example}
+
+	// Fit the sections by importance, then write them in reading order
+	remaining := tokens*bytesPerToken - header.Len()
+	for _, name := range []string{SectionDoc, SectionUsages, SectionInterfaces, SectionExample, SectionMethods} {
+		section := sections[name]
+		if section == nil || section.empty() {
+			continue
+		}
+		text, shortened := section.fit(remaining)
+		if text == "" || shortened {
+			explanation.Omitted = append(explanation.Omitted, name)
+		}
+		section.text = text
+		remaining -= len(text)
+	}
+
+	var markdown strings.Builder
+	markdown.WriteString(header.String())
+	for _, name := range []string{SectionDoc, SectionMethods, SectionInterfaces, SectionUsages, SectionExample} {
+		if section := sections[name]; section != nil {
+			markdown.WriteString(section.text)
+		}
+	}
+	if len(explanation.Omitted) > 0 {
+		sort.Strings(explanation.Omitted)
+		fmt.Fprintf(&markdown, "\n_Shortened to fit the token budget: %s._\n", strings.Join(explanation.Omitted, ", "))
+	}
+	explanation.Markdown = markdown.String()
+	explanation.Tokens = (len(explanation.Markdown) + bytesPerToken - 1) / bytesPerToken
+	return explanation, nil
+}
+
+// explainSection is a part of an explanation after the declaration: a list
+// under a heading, or a text that may be cut at a paragraph
+type explainSection struct {
+	name  string
+	title string
+	intro string
+	items []string
+	body  string
+	// cut allows the body to be cut to fit; other bodies fit whole or not
+	// at all
+	cut bool
+	// text is the section as written
+	text string
+}
+
+// empty reports whether the section has nothing to say
+func (s *explainSection) empty() bool {
+	return s.intro == "" && len(s.items) == 0 && s.body == ""
+}
+
+// render writes the section with its first n items
+func (s *explainSection) render(n int, body string) string {
+	var b strings.Builder
+	b.WriteString("\n")
+	if s.title != "" {
+		fmt.Fprintf(&b, "## %s\n\n", s.title)
+	}
+	if s.intro != "" {
+		b.WriteString(s.intro + "\n")
+		if n > 0 {
+			b.WriteString("\n")
+		}
+	}
+	for _, item := range s.items[:n] {
+		b.WriteString("- " + item + "\n")
+	}
+	if n < len(s.items) {
+		fmt.Fprintf(&b, "- and %d more\n", len(s.items)-n)
+	}
+	if body != "" {
+		b.WriteString(body + "\n")
+	}
+	return b.String()
+}
+
+// fit writes the section within budget bytes, dropping list items from the
+// end or cutting the body. It returns an empty text when nothing useful
+// fits, and whether the section was shortened.
+func (s *explainSection) fit(budget int) (string, bool) {
+	if text := s.render(len(s.items), s.body); len(text) <= budget {
+		return text, false
+	}
+	if len(s.items) > 0 {
+		for n := len(s.items) - 1; n > 0; n-- {
+			if text := s.render(n, s.body); len(text) <= budget {
+				return text, true
+			}
+		}
+		return "", true
+	}
+	if s.cut {
+		overhead := len(s.render(0, "x")) - 1
+		// A cut body keeps its first paragraph at least
+		if limit := budget - overhead; limit > 0 {
+			body, _ := truncateDoc(s.body, limit)
+			if body = strings.TrimSpace(body); len(body) <= limit && !strings.HasPrefix(body, "[truncated") {
+				return s.render(0, body), true
+			}
+		}
+	}
+	return "", true
+}
+
+// explainedName names a symbol as pkg.Name or pkg.Type.Method
+func explainedName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		return funcName(fn)
+	}
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// declarationSource renders the declaration of a symbol: a type as written
+// in the source, without comments, and other symbols as go/types does
+func (a *Analyzer) declarationSource(obj types.Object, def *Definition) string {
+	if _, ok := obj.(*types.TypeName); !ok {
+		return def.Declaration
+	}
+	for _, file := range a.asts[obj.Pkg().Path()] {
+		if obj.Pos() < file.Pos() || obj.Pos() >= file.End() {
+			continue
+		}
+		var spec *ast.TypeSpec
+		ast.Inspect(file, func(n ast.Node) bool {
+			if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Pos() == obj.Pos() {
+				spec = ts
+			}
+			return spec == nil
+		})
+		if spec == nil {
+			break
+		}
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, a.fset, spec); err != nil {
+			break
+		}
+		return "type " + buf.String()
+	}
+	return def.Declaration
+}
+
+// methodItems lists the exported methods of a type and of a pointer to it
+func methodItems(typeObj *types.TypeName) []string {
+	t := typeObj.Type()
+	if !types.IsInterface(t) {
+		t = types.NewPointer(t)
+	}
+	qualifier := types.RelativeTo(typeObj.Pkg())
+	var items []string
+	methods := types.NewMethodSet(t)
+	for i := 0; i < methods.Len(); i++ {
+		fn, ok := methods.At(i).Obj().(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+		sig := types.TypeString(fn.Type(), qualifier)
+		items = append(items, fmt.Sprintf("`%s%s`", fn.Name(), strings.TrimPrefix(sig, "func")))
+	}
+	return items
+}
+
+// interfaceItems lists the interface relationships of a hierarchy
+func interfaceItems(info *HierarchyInfo) []string {
+	var items []string
+	for _, r := range info.Implements {
+		item := fmt.Sprintf("implements `%s.%s`", r.ImportPath, r.Name)
+		if r.Pointer {
+			item += " through a pointer"
+		}
+		items = append(items, item)
+	}
+	for _, r := range info.ImplementedBy {
+		name := r.ImportPath + "." + r.Name
+		if r.Pointer {
+			name = "*" + name
+		}
+		items = append(items, fmt.Sprintf("implemented by `%s`", name))
+	}
+	return items
+}
+
+// referrers counts the uses of obj by declaration in the analyzed packages,
+// most frequent first. Uses inside the symbol's own declaration, and the
+// receivers of a type's methods, are not counted.
+func (a *Analyzer) referrers(ctx context.Context, obj types.Object) ([]Referrer, error) {
+	var referrers []Referrer
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		pkgName := a.pkgs[importPath].Name()
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				for _, d := range a.declarations(info, pkgName, decl, obj) {
+					count := 0
+					var first *ast.Ident
+					for _, node := range d.nodes {
+						ast.Inspect(node, func(n ast.Node) bool {
+							if ident, ok := n.(*ast.Ident); ok && sameObject(info.Uses[ident], obj) {
+								if first == nil {
+									first = ident
+								}
+								count++
+							}
+							return true
+						})
+					}
+					if count > 0 {
+						referrers = append(referrers, Referrer{
+							Name:       d.name,
+							Position:   a.position(first.Pos()),
+							References: count,
+						})
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(referrers, func(i, j int) bool {
+		return referrers[i].References > referrers[j].References
+	})
+	return referrers, nil
+}
+
+// declaration is a named declaration and the syntax in which it may use
+// other symbols
+type declaration struct {
+	name  string
+	nodes []ast.Node
+}
+
+// declarations splits a top-level declaration into the declarations that
+// may use obj, leaving out obj's own. The receivers of obj's methods are
+// left out too, as they do not use the type so much as belong to it.
+func (a *Analyzer) declarations(info *types.Info, pkgName string, decl ast.Decl, obj types.Object) []declaration {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		fn, _ := info.Defs[decl.Name].(*types.Func)
+		if fn == nil || fn == obj {
+			return nil
+		}
+		d := declaration{name: funcName(fn), nodes: []ast.Node{decl.Type}}
+		if decl.Body != nil {
+			d.nodes = append(d.nodes, decl.Body)
+		}
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			if named := receiverNamed(recv.Type()); named == nil || named.Obj() != obj {
+				d.nodes = append(d.nodes, decl.Recv)
+			}
+		}
+		return []declaration{d}
+	case *ast.GenDecl:
+		var decls []declaration
+		for _, spec := range decl.Specs {
+			var ident *ast.Ident
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				ident = spec.Name
+			case *ast.ValueSpec:
+				ident = spec.Names[0]
+			default:
+				continue
+			}
+			if info.Defs[ident] != obj {
+				decls = append(decls, declaration{name: pkgName + "." + ident.Name, nodes: []ast.Node{spec}})
+			}
+		}
+		return decls
+	}
+	return nil
+}
+
+// sameObject reports whether used refers to obj, looking through the
+// instantiations of generic functions and methods
+func sameObject(used, obj types.Object) bool {
+	switch u := used.(type) {
+	case nil:
+		return false
+	case *types.Func:
+		return u.Origin() == obj
+	case *types.Var:
+		return u.Origin() == obj
+	}
+	return used == obj
+}
+
+// explainExample returns an example of a symbol as Markdown: its godoc
+// Example function or, without one, the simplest use in the repository
+func (a *Analyzer) explainExample(ctx context.Context, obj types.Object) (string, error) {
+	for _, example := range a.docExamples(obj) {
+		code := a.exampleCode(example)
+		if code == "" {
+			continue
+		}
+		title := "Example" + example.Name
+		if example.Name == "" {
+			title = "Example"
+		}
+		return fmt.Sprintf("`%s`:\n\n```go\n%s\n```", title, code), nil
+	}
+
+	examples, err := a.examplesOf(ctx, map[types.Object]string{obj: explainedName(obj)}, 1)
+	if err != nil || len(examples) == 0 {
+		return "", err
+	}
+	example := examples[0]
+	return fmt.Sprintf("Usage in `%s` (%s):\n\n```go\n%s\n```",
+		example.Function, a.shortPosition(example.Position), example.Code), nil
+}
+
+// docExamples returns the godoc Example functions go/doc attached to a
+// symbol, from its package and its external test package
+func (a *Analyzer) docExamples(obj types.Object) []*doc.Example {
+	var recvName string
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			named := receiverNamed(recv.Type())
+			if named == nil {
+				return nil
+			}
+			recvName = named.Obj().Name()
+		}
+	}
+	var examples []*doc.Example
+	for _, importPath := range []string{obj.Pkg().Path(), obj.Pkg().Path() + "_test"} {
+		docPkg := a.docPkgs[importPath]
+		if docPkg == nil {
+			continue
+		}
+		for _, docType := range docPkg.Types {
+			if recvName != "" {
+				if docType.Name != recvName {
+					continue
+				}
+				for _, method := range docType.Methods {
+					if method.Name == obj.Name() {
+						examples = append(examples, method.Examples...)
+					}
+				}
+				continue
+			}
+			if docType.Name == obj.Name() {
+				examples = append(examples, docType.Examples...)
+			}
+			for _, docFunc := range docType.Funcs {
+				if docFunc.Name == obj.Name() {
+					examples = append(examples, docFunc.Examples...)
+				}
+			}
+		}
+		if recvName == "" {
+			for _, docFunc := range docPkg.Funcs {
+				if docFunc.Name == obj.Name() {
+					examples = append(examples, docFunc.Examples...)
+				}
+			}
+		}
+	}
+	return examples
+}
+
+// exampleCode prints the body of an Example function without its braces
+func (a *Analyzer) exampleCode(example *doc.Example) string {
+	var buf bytes.Buffer
+	block, ok := example.Code.(*ast.BlockStmt)
+	if !ok {
+		if err := printer.Fprint(&buf, a.fset, example.Code); err != nil {
+			return ""
+		}
+		return strings.TrimSpace(buf.String())
+	}
+	for i, stmt := range block.List {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if err := printer.Fprint(&buf, a.fset, stmt); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// shortPosition writes a position as file:line relative to the repository
+func (a *Analyzer) shortPosition(pos Position) string {
+	filename := pos.Filename
+	if rel, err := filepath.Rel(a.repoPath, filename); err == nil {
+		filename = filepath.ToSlash(rel)
+	}
+	return fmt.Sprintf("%s:%d", filename, pos.Line)
+}
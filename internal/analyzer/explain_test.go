@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainSymbol(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+// Store keeps values by key.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Get(key string) (string, error)
+}
+
+// Cache is a Store in memory
+type Cache struct {
+	values map[string]string
+}
+
+// New returns an empty Cache
+func New() *Cache {
+	return &Cache{values: map[string]string{}}
+}
+
+// Get returns the value of a key
+func (c *Cache) Get(key string) (string, error) {
+	return c.values[key], nil
+}
+
+func (c *Cache) size() int { return len(c.values) }
+`,
+		"store/example_test.go": `package store
+
+func ExampleNew() {
+	cache := New()
+	cache.Get("key")
+}
+`,
+		"app/app.go": `package app
+
+import "example.com/app/store"
+
+type Server struct {
+	cache *store.Cache
+}
+
+func Run() (string, error) {
+	c := store.New()
+	first := store.New()
+	_ = first
+	return c.Get("key")
+}
+
+func Warm() {
+	store.New()
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		config.IncludeTests = true
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		explanation, err := analyzer.ExplainSymbol(ctx, "Cache", ExplainOptions{})
+		if err != nil {
+			t.Fatalf("Failed to explain Cache (lazy %v): %v", lazy, err)
+		}
+		if explanation.Symbol != "store.Cache" || explanation.Kind != "type" || explanation.Position.Line != 11 {
+			t.Errorf("Expected store.Cache at line 11, got %+v", explanation)
+		}
+		// The receivers of Cache's methods are not uses
+		if explanation.References != 3 {
+			t.Errorf("Expected 3 references to Cache, got %d", explanation.References)
+		}
+		for _, want := range []string{
+			"# store.Cache",
+			"type Cache struct {\n\tvalues map[string]string\n}",
+			"Cache is a Store in memory",
+			"- `Get(key string) (string, error)`",
+			"- implements `example.com/app/store.Store` through a pointer",
+			"3 references from 2 declarations",
+			"- `store.New` (store/store.go:16): 2",
+			"- `app.Server` (app/app.go:6): 1",
+		} {
+			if !strings.Contains(explanation.Markdown, want) {
+				t.Errorf("Expected explanation of Cache to contain %q, got:\n%s", want, explanation.Markdown)
+			}
+		}
+		if strings.Contains(explanation.Markdown, "size") {
+			t.Errorf("Expected unexported methods to be left out, got:\n%s", explanation.Markdown)
+		}
+
+		explanation, err = analyzer.ExplainSymbol(ctx, "store.New", ExplainOptions{Usages: 1})
+		if err != nil {
+			t.Fatalf("Failed to explain New: %v", err)
+		}
+		for _, want := range []string{
+			"func New() *Cache",
+			"4 references from 3 declarations",
+			"- `app.Run` (app/app.go:10): 2\n\n## Example",
+			"`ExampleNew`:\n\n```go\ncache := New()\ncache.Get(\"key\")\n```",
+		} {
+			if !strings.Contains(explanation.Markdown, want) {
+				t.Errorf("Expected explanation of New to contain %q, got:\n%s", want, explanation.Markdown)
+			}
+		}
+
+		explanation, err = analyzer.ExplainSymbol(ctx, "Cache.Get", ExplainOptions{})
+		if err != nil {
+			t.Fatalf("Failed to explain Cache.Get: %v", err)
+		}
+		if explanation.Symbol != "store.Cache.Get" || explanation.Kind != "method" {
+			t.Errorf("Expected method store.Cache.Get, got %+v", explanation)
+		}
+		if !strings.Contains(explanation.Markdown, "Usage in `store.ExampleNew` (store/example_test.go:5)") {
+			t.Errorf("Expected a usage example of Cache.Get, got:\n%s", explanation.Markdown)
+		}
+
+		// A small budget keeps the declaration and cuts the doc comment
+		explanation, err = analyzer.ExplainSymbol(ctx, "Store", ExplainOptions{Tokens: 45})
+		if err != nil {
+			t.Fatalf("Failed to explain Store: %v", err)
+		}
+		if !strings.Contains(explanation.Markdown, "type Store interface") {
+			t.Errorf("Expected the declaration of Store, got:\n%s", explanation.Markdown)
+		}
+		if strings.Contains(explanation.Markdown, "concurrent use") || strings.Contains(explanation.Markdown, "## Interfaces") {
+			t.Errorf("Expected the budget to leave out sections, got:\n%s", explanation.Markdown)
+		}
+		if !reflect.DeepEqual(explanation.Omitted, []string{SectionDoc, SectionInterfaces, SectionMethods, SectionUsages}) {
+			t.Errorf("Expected doc, interfaces, methods and usages to be shortened, got %v", explanation.Omitted)
+		}
+
+		if _, err := analyzer.ExplainSymbol(ctx, "Missing", ExplainOptions{}); err == nil {
+			t.Error("Expected an error for an unknown symbol")
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExtractOptions configure ExtractInterface
+type ExtractOptions struct {
+	// Methods selects the methods of the interface; empty selects every
+	// exported method of the type and its pointer
+	Methods []string
+	// Name is the name of the interface; empty suggests one
+	Name string
+	// Package is the package the interface is for (import path or package
+	// name); empty uses the type's package
+	Package string
+}
+
+// ExtractedInterface is an interface declaration generated from the
+// methods of a concrete type
+type ExtractedInterface struct {
+	Name string `json:"name"`
+	// Type is the concrete type, qualified with its package name
+	Type        string   `json:"type"`
+	TypePackage string   `json:"type_package"`
+	Methods     []string `json:"methods"`
+	// ImportPath, PackageName and Dir locate the package the declaration
+	// is written for
+	ImportPath  string `json:"import_path"`
+	PackageName string `json:"package_name"`
+	Dir         string `json:"dir"`
+	// Imports are the import paths the declaration refers to, keyed by
+	// the package names it uses
+	Imports map[string]string `json:"imports,omitempty"`
+	// Code is the interface declaration, with the methods' doc comments
+	Code string `json:"code"`
+	// Assertion is a declaration checking at compile time that the type
+	// implements the interface. Outside the type's package it also needs
+	// an import of TypePackage, which Imports leaves out.
+	Assertion string `json:"assertion"`
+}
+
+// ExtractInterface generates an interface declaration from the methods of
+// a named type, such as for test doubles. Signatures are qualified
+// relative to the target package, which may differ from the type's.
+func (a *Analyzer) ExtractInterface(ctx context.Context, typeName string, opts ExtractOptions) (*ExtractedInterface, error) {
+	if err := a.rlockLoaded(ctx, func() []string {
+		paths := a.packagesNamed(typeName)
+		if opts.Package != "" {
+			paths = append(paths, a.packagesMatching(opts.Package)...)
+		}
+		return paths
+	}); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", typeName)
+	}
+	named, ok := typeObj.Type().(*types.Named)
+	if !ok || types.IsInterface(named) {
+		return nil, fmt.Errorf("%s is not a concrete named type", typeName)
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is generic; extracting interfaces from generic types is not supported", typeName)
+	}
+
+	target := typeObj.Pkg().Path()
+	if opts.Package != "" {
+		if target, err = a.uniquePackage(opts.Package); err != nil {
+			return nil, err
+		}
+	}
+	samePackage := target == typeObj.Pkg().Path()
+	if len(a.files[target]) == 0 {
+		return nil, fmt.Errorf("package %s has no source files in the repository", target)
+	}
+
+	// The method set of the pointer holds the methods of both receivers
+	// and those promoted from embedded fields
+	methodSet := types.NewMethodSet(types.NewPointer(named))
+	var methods []*types.Func
+	if len(opts.Methods) == 0 {
+		for i := range methodSet.Len() {
+			if fn := methodSet.At(i).Obj().(*types.Func); fn.Exported() {
+				methods = append(methods, fn)
+			}
+		}
+		if len(methods) == 0 {
+			return nil, fmt.Errorf("%s has no exported methods", typeName)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, name := range opts.Methods {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			sel := methodSet.Lookup(typeObj.Pkg(), name)
+			if sel == nil {
+				return nil, fmt.Errorf("%s has no method %s", typeName, name)
+			}
+			fn := sel.Obj().(*types.Func)
+			if !fn.Exported() && !samePackage {
+				return nil, fmt.Errorf("method %s is unexported, so only an interface in package %s can declare it", name, typeObj.Pkg().Name())
+			}
+			methods = append(methods, fn)
+		}
+		sort.Slice(methods, func(i, j int) bool { return methods[i].Name() < methods[j].Name() })
+	}
+
+	result := &ExtractedInterface{
+		Type:        typeObj.Pkg().Name() + "." + typeObj.Name(),
+		TypePackage: typeObj.Pkg().Path(),
+		ImportPath:  target,
+		PackageName: a.packageName(target),
+		Dir:         filepath.Dir(a.files[target][0]),
+		Imports:     make(map[string]string),
+	}
+	qualifier := importQualifier(target, result.Imports)
+
+	result.Name = opts.Name
+	if result.Name == "" {
+		result.Name = suggestInterfaceName(typeObj.Name(), methods, samePackage)
+	}
+	if !token.IsIdentifier(result.Name) {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", result.Name)
+	}
+	if pkg := a.pkgs[target]; pkg != nil && pkg.Scope().Lookup(result.Name) != nil {
+		return nil, fmt.Errorf("package %s already declares %s; choose another name", result.PackageName, result.Name)
+	}
+
+	var code strings.Builder
+	fmt.Fprintf(&code, "// %s is implemented by %s\n", result.Name, result.Type)
+	fmt.Fprintf(&code, "type %s interface {\n", result.Name)
+	for i, fn := range methods {
+		if i > 0 {
+			code.WriteString("\n")
+		}
+		if doc := strings.TrimSpace(a.funcDoc(fn)); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				code.WriteString(strings.TrimRight("\t// "+line, " ") + "\n")
+			}
+		}
+		sig := types.TypeString(fn.Type(), qualifier)
+		fmt.Fprintf(&code, "\t%s%s\n", fn.Name(), strings.TrimPrefix(sig, "func"))
+		result.Methods = append(result.Methods, fn.Name())
+	}
+	code.WriteString("}\n")
+	result.Code = code.String()
+	result.Assertion = fmt.Sprintf("var _ %s = (*%s)(nil)\n", result.Name, types.TypeString(named, func(pkg *types.Package) string {
+		if pkg.Path() == target {
+			return ""
+		}
+		return pkg.Name()
+	}))
+	return result, nil
+}
+
+// uniquePackage returns the known package a qualifier selects, which must
+// be exactly one. External test packages are left out.
+func (a *Analyzer) uniquePackage(qualifier string) (string, error) {
+	var matches []string
+	for importPath := range a.files {
+		if !strings.HasSuffix(importPath, "_test") && matchesQualifier(qualifier, importPath, a.packageName(importPath)) {
+			matches = append(matches, importPath)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("package %s not found", qualifier)
+	case 1:
+		return matches[0], nil
+	}
+	return "", &AmbiguousError{Name: qualifier, Candidates: matches}
+}
+
+// suggestInterfaceName names an interface after its single method, the
+// Go way (Read: Reader), or else after the type it is extracted from
+func suggestInterfaceName(typeName string, methods []*types.Func, samePackage bool) string {
+	if len(methods) == 1 {
+		return erName(methods[0].Name())
+	}
+	if samePackage {
+		return typeName + "Interface"
+	}
+	return typeName
+}
+
+// erName turns a method name into an agent noun: Read is Reader, Close is
+// Closer and Get is Getter
+func erName(method string) string {
+	if strings.HasSuffix(method, "er") {
+		return method
+	}
+	if strings.HasSuffix(method, "e") {
+		return method + "r"
+	}
+	// One-syllable words ending in consonant, vowel, consonant double the
+	// last consonant
+	word := strings.ToLower(method)
+	if n := len(word); n >= 3 && strings.Count(word, "a")+strings.Count(word, "e")+strings.Count(word, "i")+strings.Count(word, "o")+strings.Count(word, "u") == 1 &&
+		isVowel(word[n-2]) && !isVowel(word[n-1]) && !isVowel(word[n-3]) && !strings.ContainsRune("wxy", rune(word[n-1])) {
+		return method + method[n-1:] + "er"
+	}
+	return method + "er"
+}
+
+// isVowel reports whether a lower-case letter is a vowel
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractInterface(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import "io"
+
+type Item struct{ Name string }
+
+type Store struct{}
+
+// Get returns the named item.
+// It fails for unknown items.
+func (s *Store) Get(name string) (*Item, error) { return nil, nil }
+
+// Put stores an item
+func (s Store) Put(item Item) error { return nil }
+
+func (s *Store) Export(w io.Writer) error { return nil }
+
+func (s *Store) reindex() {}
+
+type Pair[T any] struct{ A, B T }
+`,
+		"service/service.go": "package service\n\ntype Service struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		// All exported methods of both receivers, in the type's package
+		result, err := analyzer.ExtractInterface(ctx, "Store", ExtractOptions{})
+		if err != nil {
+			t.Fatalf("ExtractInterface failed: %v", err)
+		}
+		if result.Name != "StoreInterface" || result.ImportPath != "example.com/shop/store" || result.PackageName != "store" ||
+			result.Dir != filepath.Join(tmpDir, "store") || strings.Join(result.Methods, ",") != "Export,Get,Put" {
+			t.Errorf("Unexpected interface: %+v", result)
+		}
+		expected := `// StoreInterface is implemented by store.Store
+type StoreInterface interface {
+	Export(w io.Writer) error
+
+	// Get returns the named item.
+	// It fails for unknown items.
+	Get(name string) (*Item, error)
+
+	// Put stores an item
+	Put(item Item) error
+}
+`
+		if result.Code != expected {
+			t.Errorf("Expected code:\n%s\ngot:\n%s", expected, result.Code)
+		}
+		if len(result.Imports) != 1 || result.Imports["io"] != "io" {
+			t.Errorf("Expected an import of io, got %v", result.Imports)
+		}
+		if result.Assertion != "var _ StoreInterface = (*Store)(nil)\n" {
+			t.Errorf("Unexpected assertion: %q", result.Assertion)
+		}
+
+		// A subset for another package qualifies the type's own types
+		result, err = analyzer.ExtractInterface(ctx, "store.Store", ExtractOptions{Methods: []string{"Put", "Get"}, Package: "service"})
+		if err != nil {
+			t.Fatalf("ExtractInterface failed: %v", err)
+		}
+		if result.Name != "Store" || result.ImportPath != "example.com/shop/service" || result.TypePackage != "example.com/shop/store" ||
+			strings.Join(result.Methods, ",") != "Get,Put" {
+			t.Errorf("Unexpected interface: %+v", result)
+		}
+		if !strings.Contains(result.Code, "\tGet(name string) (*store.Item, error)\n") || !strings.Contains(result.Code, "\tPut(item store.Item) error\n") {
+			t.Errorf("Expected signatures qualified with store, got:\n%s", result.Code)
+		}
+		if len(result.Imports) != 1 || result.Imports["store"] != "example.com/shop/store" {
+			t.Errorf("Expected an import of the store package, got %v", result.Imports)
+		}
+		if result.Assertion != "var _ Store = (*store.Store)(nil)\n" {
+			t.Errorf("Unexpected assertion: %q", result.Assertion)
+		}
+
+		// Imports leave out the type's package when only the assertion uses it
+		result, err = analyzer.ExtractInterface(ctx, "Store", ExtractOptions{Methods: []string{"Export"}, Package: "service"})
+		if err != nil {
+			t.Fatalf("ExtractInterface failed: %v", err)
+		}
+		if len(result.Imports) != 1 || result.Imports["io"] != "io" || result.Assertion != "var _ Exporter = (*store.Store)(nil)\n" {
+			t.Errorf("Unexpected imports %v or assertion %q", result.Imports, result.Assertion)
+		}
+
+		// A single method names the interface after it
+		result, err = analyzer.ExtractInterface(ctx, "Store", ExtractOptions{Methods: []string{"Get"}})
+		if err != nil {
+			t.Fatalf("ExtractInterface failed: %v", err)
+		}
+		if result.Name != "Getter" {
+			t.Errorf("Expected Getter, got %s", result.Name)
+		}
+
+		// Unexported methods only fit interfaces of the type's package
+		if _, err := analyzer.ExtractInterface(ctx, "Store", ExtractOptions{Methods: []string{"reindex"}, Name: "Indexer"}); err != nil {
+			t.Errorf("Expected an unexported method in the same package, got %v", err)
+		}
+		for _, tc := range []struct {
+			typeName string
+			opts     ExtractOptions
+			errText  string
+		}{
+			{"Store", ExtractOptions{Methods: []string{"reindex"}, Package: "service"}, "unexported"},
+			{"Store", ExtractOptions{Methods: []string{"Delete"}}, "has no method Delete"},
+			{"Store", ExtractOptions{Name: "Item"}, "already declares Item"},
+			{"Store", ExtractOptions{Name: "not valid"}, "not a valid Go identifier"},
+			{"Store", ExtractOptions{Package: "missing"}, "package missing not found"},
+			{"Service", ExtractOptions{}, "no exported methods"},
+			{"Pair", ExtractOptions{}, "generic"},
+		} {
+			if _, err := analyzer.ExtractInterface(ctx, tc.typeName, tc.opts); err == nil || !strings.Contains(err.Error(), tc.errText) {
+				t.Errorf("Expected an error containing %q for %s %+v, got %v", tc.errText, tc.typeName, tc.opts, err)
+			}
+		}
+	}
+}
+
+func TestErName(t *testing.T) {
+	for method, expected := range map[string]string{
+		"Read":    "Reader",
+		"Close":   "Closer",
+		"Get":     "Getter",
+		"Run":     "Runner",
+		"Stop":    "Stopper",
+		"Fix":     "Fixer",
+		"Handler": "Handler",
+		"Export":  "Exporter",
+	} {
+		if got := erName(method); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, method, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileIndexVersion identifies the format of persisted file indexes; files
+// of other versions are ignored
+const fileIndexVersion = 1
+
+// mtimeSlack is how long before the index was written a file must have
+// last changed for its modification time to be trusted. Files changed
+// within the same timestamp granularity as the write are hashed instead.
+const mtimeSlack = 2 * time.Second
+
+// fileIndex persists what lazy discovery learns from each file, so a
+// restart only parses the files that changed. Entries are reused when the
+// size and modification time match, or otherwise when the content hash
+// does.
+type fileIndex struct {
+	Version  int                    `json:"version"`
+	RepoPath string                 `json:"repo_path"`
+	Written  int64                  `json:"written"` // UnixNano
+	Files    map[string]indexedFile `json:"files"`   // By absolute filename
+
+	path   string
+	seen   map[string]indexedFile // Entries of the files discovered this time
+	reused int
+	parsed int
+}
+
+// indexedFile is what discovery records about a file
+type indexedFile struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"` // UnixNano
+	Hash    string `json:"hash"`     // SHA-256 of the content
+	Package string `json:"package"`
+	// Names are the package-level identifiers the file declares, except
+	// methods
+	Names     []string `json:"names,omitempty"`
+	Generated bool     `json:"generated,omitempty"`
+	// SyntaxErrors reports whether the file failed to parse cleanly. Such
+	// entries are never reused, so discovery reports the errors again.
+	SyntaxErrors bool `json:"syntax_errors,omitempty"`
+}
+
+// readFileIndex loads the index at path. No path, or a missing, unreadable
+// or outdated index, yields an empty one, which discovery fills.
+func (a *Analyzer) readFileIndex(path string) *fileIndex {
+	index := &fileIndex{path: path, seen: make(map[string]indexedFile)}
+	if path == "" {
+		return index
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.logWarn("Ignoring file index", "error", err)
+		}
+		return index
+	}
+
+	var stored fileIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		a.logWarn("Ignoring file index", "path", path, "error", err)
+		return index
+	}
+	if stored.Version == fileIndexVersion && stored.RepoPath == a.repoPath {
+		index.Written = stored.Written
+		index.Files = stored.Files
+	}
+	return index
+}
+
+// write saves the entries of the files discovered this time, dropping
+// those of deleted files. The file is replaced atomically, so concurrent
+// servers never read a partial index.
+func (x *fileIndex) write(repoPath string) error {
+	x.Version = fileIndexVersion
+	x.RepoPath = repoPath
+	x.Written = time.Now().UnixNano()
+	x.Files = x.seen
+	data, err := json.Marshal(x)
+	if err != nil {
+		return fmt.Errorf("failed to encode file index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("failed to create file index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(x.path), filepath.Base(x.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write file index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), x.path); err != nil {
+		return fmt.Errorf("failed to write file index: %w", err)
+	}
+	return nil
+}
+
+// entry returns what is known about filename, reading it with read and
+// parsing it only when the index has no entry matching its modification
+// time or content. For a file with syntax errors it returns the errors
+// together with what parsed; the entry has no package when the package
+// clause did not parse.
+func (x *fileIndex) entry(filename string, info os.FileInfo, read func(string) ([]byte, error)) (indexedFile, error) {
+	if entry, ok := x.Files[filename]; ok && !entry.SyntaxErrors && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() &&
+		entry.ModTime < x.Written-int64(mtimeSlack) {
+		x.remember(filename, entry, true)
+		return entry, nil
+	}
+
+	src, err := read(filename)
+	if err != nil {
+		return indexedFile{}, err
+	}
+	sum := sha256.Sum256(src)
+	hash := hex.EncodeToString(sum[:])
+	if entry, ok := x.Files[filename]; ok && !entry.SyntaxErrors && entry.Hash == hash {
+		entry.Size, entry.ModTime = info.Size(), info.ModTime().UnixNano()
+		x.remember(filename, entry, true)
+		return entry, nil
+	}
+
+	file, parseErr := parser.ParseFile(token.NewFileSet(), filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil || file.Name == nil || file.Name.Name == "" {
+		return indexedFile{}, parseErr
+	}
+	entry := indexedFile{
+		Size:         info.Size(),
+		ModTime:      info.ModTime().UnixNano(),
+		Hash:         hash,
+		Package:      file.Name.Name,
+		Names:        declaredNames(file),
+		Generated:    generatedReason(filename, factsOf(file)) != "",
+		SyntaxErrors: parseErr != nil,
+	}
+	x.remember(filename, entry, false)
+	return entry, parseErr
+}
+
+// remember records the entry of a discovered file
+func (x *fileIndex) remember(filename string, entry indexedFile, reused bool) {
+	x.seen[filename] = entry
+	if reused {
+		x.reused++
+	} else {
+		x.parsed++
+	}
+}
+
+// declaredNames returns the package-level identifiers a file declares,
+// except methods, in declaration order
+func declaredNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				names = append(names, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := filepath.Join(tmpDir, "repo")
+	files := map[string]string{
+		"go.mod":         "module example.com/idx\n\ngo 1.21\n",
+		"alpha/alpha.go": "package alpha\n\ntype Alpha struct{}\n",
+		"beta/beta.go":   "package beta\n\nfunc Beta() {}\n",
+		"beta/extra.go":  "package beta\n\nconst Extra = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.LazyLoading = true
+	config.IndexPath = filepath.Join(tmpDir, "cache", "index.json")
+	open := func() (*Analyzer, *LazyStats) {
+		t.Helper()
+		analyzer, err := NewAnalyzerWithConfig(repo, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		t.Cleanup(func() { analyzer.Close() })
+		return analyzer, analyzer.LazyStats()
+	}
+
+	if _, stats := open(); stats.FilesParsed != 3 || stats.FilesIndexed != 0 {
+		t.Errorf("Expected every file parsed without an index, got %+v", stats)
+	}
+	if _, err := os.Stat(config.IndexPath); err != nil {
+		t.Fatalf("Expected the index to be written: %v", err)
+	}
+
+	if _, stats := open(); stats.FilesParsed != 0 || stats.FilesIndexed != 3 {
+		t.Errorf("Expected every file taken from the index, got %+v", stats)
+	}
+
+	// A touched file with the same content is matched by its hash
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(repo, "alpha/alpha.go"), later, later); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if _, stats := open(); stats.FilesParsed != 0 {
+		t.Errorf("Expected the touched file taken from the index, got %+v", stats)
+	}
+
+	// Changed files are parsed again and deleted ones dropped
+	if err := os.WriteFile(filepath.Join(repo, "alpha/alpha.go"), []byte("package alpha\n\ntype Gamma struct{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(repo, "beta/extra.go")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	analyzer, stats := open()
+	if stats.FilesParsed != 1 || stats.FilesIndexed != 1 {
+		t.Errorf("Expected only the changed file parsed, got %+v", stats)
+	}
+	if _, err := analyzer.LookupType(context.Background(), "Gamma"); err != nil {
+		t.Errorf("Expected the changed file's type to be found: %v", err)
+	}
+	data, err := os.ReadFile(config.IndexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	var index fileIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Failed to decode index: %v", err)
+	}
+	if len(index.Files) != 2 {
+		t.Errorf("Expected the deleted file dropped from the index, got %d entries", len(index.Files))
+	}
+
+	// A corrupt index is rebuilt
+	if err := os.WriteFile(config.IndexPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt index: %v", err)
+	}
+	if _, stats := open(); stats.FilesParsed != 2 {
+		t.Errorf("Expected every file parsed with a corrupt index, got %+v", stats)
+	}
+	if _, stats := open(); stats.FilesIndexed != 2 {
+		t.Errorf("Expected the index rebuilt, got %+v", stats)
+	}
+}
@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// generatedSuffixes are file name endings marking generated code, for
+// generators that do not write the standard header
+var generatedSuffixes = []string{".pb.go", "_gen.go"}
+
+// generatedReason returns why a file counts as generated: its
+// "Code generated ... DO NOT EDIT." header or its name. It is empty for
+// hand-written files.
+func generatedReason(filename string, facts fileFacts) string {
+	if facts.generated != "" {
+		return facts.generated
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(filename, suffix) {
+			return "file name ends in " + suffix
+		}
+	}
+	return ""
+}
+
+// skipGenerated records whether a file is generated and reports whether
+// Config.ExcludeGenerated leaves it out of the analysis
+func (a *Analyzer) skipGenerated(filename string, generated bool) bool {
+	if !generated {
+		return false
+	}
+	if a.config.ExcludeGenerated {
+		return true
+	}
+	a.generated[filename] = true
+	return false
+}
+
+// generatedPos reports whether a position lies in a generated file
+func (a *Analyzer) generatedPos(pos token.Pos) bool {
+	return a.generated[a.fset.Position(pos).Filename]
+}
+
+// WithoutGenerated returns a copy of the result without the declarations
+// of generated files and the packages made only of generated files, with
+// the metrics counted again
+func (r *AnalysisResult) WithoutGenerated() *AnalysisResult {
+	filtered := *r
+	filtered.Types = slices.DeleteFunc(slices.Clone(r.Types), func(t TypeInfo) bool { return t.Position.Generated })
+	filtered.Functions = slices.DeleteFunc(slices.Clone(r.Functions), func(f FunctionInfo) bool { return f.Position.Generated })
+	filtered.Variables = slices.DeleteFunc(slices.Clone(r.Variables), func(v VariableInfo) bool { return v.Position.Generated })
+	filtered.Constants = slices.DeleteFunc(slices.Clone(r.Constants), func(c ConstantInfo) bool { return c.Position.Generated })
+	filtered.Packages = slices.DeleteFunc(slices.Clone(r.Packages), func(p PackageInfo) bool { return p.Generated })
+	filtered.Metrics.TotalTypes = len(filtered.Types)
+	filtered.Metrics.TotalFunctions = len(filtered.Functions)
+	filtered.Metrics.TotalPackages = len(filtered.Packages)
+	return &filtered
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/gen\n\ngo 1.21\n",
+		"api/api.go": `package api
+
+// Server is written by hand
+type Server struct{}
+`,
+		"api/zz_deepcopy.go": `// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package api
+
+func (s *Server) DeepCopy() *Server { return s }
+`,
+		"api/api.pb.go": `package api
+
+type Request struct{}
+`,
+		"mocks/mock_gen.go": `package mocks
+
+type MockServer struct{}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	server, err := analyzer.LookupType(ctx, "Server")
+	if err != nil {
+		t.Fatalf("LookupType failed: %v", err)
+	}
+	if server.Position.Generated {
+		t.Error("Expected Server to be hand-written")
+	}
+	if len(server.Methods) != 1 || !server.Methods[0].Position.Generated {
+		t.Errorf("Expected the DeepCopy method to be generated, got %+v", server.Methods)
+	}
+	request, err := analyzer.LookupType(ctx, "Request")
+	if err != nil {
+		t.Fatalf("LookupType failed: %v", err)
+	}
+	if !request.Position.Generated || request.Tags["generated"] != "file name ends in .pb.go" {
+		t.Errorf("Expected Request to be generated, got %+v and tags %v", request.Position, request.Tags)
+	}
+
+	api, err := analyzer.GetPackageInfo(ctx, "api")
+	if err != nil {
+		t.Fatalf("GetPackageInfo failed: %v", err)
+	}
+	mocks, err := analyzer.GetPackageInfo(ctx, "mocks")
+	if err != nil {
+		t.Fatalf("GetPackageInfo failed: %v", err)
+	}
+	if api.Generated || !mocks.Generated {
+		t.Errorf("Expected only mocks to be a generated package, got api=%v mocks=%v", api.Generated, mocks.Generated)
+	}
+
+	result, err := analyzer.AnalyzeRepository(ctx)
+	if err != nil {
+		t.Fatalf("AnalyzeRepository failed: %v", err)
+	}
+	filtered := result.WithoutGenerated()
+	if filtered.Metrics.TotalTypes != 1 || filtered.Metrics.TotalPackages != 1 || filtered.Types[0].Name != "Server" {
+		t.Errorf("Expected only Server and the api package, got %+v", filtered.Metrics)
+	}
+	if result.Metrics.TotalTypes != 3 {
+		t.Errorf("Expected the original result untouched, got %d types", result.Metrics.TotalTypes)
+	}
+
+	config := DefaultConfig()
+	config.ExcludeGenerated = true
+	excluding, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer excluding.Close()
+	if packages := excluding.Packages(); len(packages) != 1 || packages[0] != "example.com/gen/api" {
+		t.Errorf("Expected only the api package, got %v", packages)
+	}
+	if _, err := excluding.LookupType(ctx, "Request"); err == nil {
+		t.Error("Expected generated types to be excluded")
+	}
+}
@@ -0,0 +1,412 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GlobalsReport lists the package-level variables and init functions of
+// the analyzed packages and the order the packages are initialized in
+type GlobalsReport struct {
+	Packages []PackageGlobals `json:"packages"`
+	// InitOrder is the order the repository packages are initialized in
+	InitOrder []string `json:"init_order"`
+	// Shared lists the variables written from more than one package
+	Shared []string `json:"shared"`
+}
+
+// PackageGlobals is the package-level state of one package
+type PackageGlobals struct {
+	ImportPath string      `json:"import_path"`
+	Variables  []GlobalVar `json:"variables"`
+	InitFuncs  []InitFunc  `json:"init_funcs"`
+	// Imports are the repository packages it imports, which are
+	// initialized before it
+	Imports []string `json:"imports,omitempty"`
+}
+
+// GlobalVar is a package-level variable
+type GlobalVar struct {
+	// Name is qualified with the package name: pkg.Name
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Position Position `json:"position"`
+	// InitDeps are the variables and functions of other repository
+	// packages its initializer refers to
+	InitDeps []string `json:"init_deps,omitempty"`
+	// Writes are the assignments to the variable, its fields or elements
+	// outside its declaration, and the places its address is taken
+	Writes []GlobalWrite `json:"writes"`
+	// WrittenFrom lists the packages holding the writes
+	WrittenFrom []string `json:"written_from,omitempty"`
+	// Shared is set when more than one package writes the variable
+	Shared bool `json:"shared,omitempty"`
+}
+
+// GlobalWrite is a write to a package-level variable
+type GlobalWrite struct {
+	ImportPath string `json:"import_path"`
+	// Function is the function or method holding the write, as pkg.Func or
+	// pkg.Type.Method; empty at package level
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+}
+
+// InitFunc is an init function and the package-level state it touches
+type InitFunc struct {
+	Position Position `json:"position"`
+	// Reads and Writes are the package-level variables it reads and
+	// writes, of any repository package
+	Reads  []string `json:"reads,omitempty"`
+	Writes []string `json:"writes,omitempty"`
+	// Calls are the functions of other repository packages it calls
+	Calls []string `json:"calls,omitempty"`
+}
+
+// GlobalsReport lists the package-level variables of the packages a
+// qualifier selects, or of every package, with the writes to them from
+// anywhere in the repository, and their init functions. Variables named _
+// are left out, as are packages without variables or init functions.
+func (a *Analyzer) GlobalsReport(ctx context.Context, pkg string) (*GlobalsReport, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	var importPaths []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if !strings.HasSuffix(importPath, "_test") && matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+	if len(importPaths) == 0 {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+
+	// Writes are collected from every package, since globals are written
+	// across packages
+	writes := make(map[*types.Var][]GlobalWrite)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a.collectGlobalWrites(importPath, writes)
+	}
+
+	report := &GlobalsReport{Packages: []PackageGlobals{}, InitOrder: a.initOrder(), Shared: []string{}}
+	for _, importPath := range importPaths {
+		pg := a.packageGlobals(importPath, writes)
+		if len(pg.Variables) == 0 && len(pg.InitFuncs) == 0 {
+			continue
+		}
+		for _, v := range pg.Variables {
+			if v.Shared {
+				report.Shared = append(report.Shared, v.Name)
+			}
+		}
+		report.Packages = append(report.Packages, pg)
+	}
+	return report, nil
+}
+
+// packageGlobals describes the variables and init functions of a package
+func (a *Analyzer) packageGlobals(importPath string, writes map[*types.Var][]GlobalWrite) PackageGlobals {
+	pkg := a.pkgs[importPath]
+	info := a.infos[importPath]
+	pg := PackageGlobals{ImportPath: importPath, Variables: []GlobalVar{}, InitFuncs: []InitFunc{}}
+	for _, imported := range pkg.Imports() {
+		if _, local := a.pkgs[imported.Path()]; local {
+			pg.Imports = append(pg.Imports, imported.Path())
+		}
+	}
+	sort.Strings(pg.Imports)
+	if info == nil {
+		return pg
+	}
+
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					for i, name := range spec.Names {
+						v, ok := info.Defs[name].(*types.Var)
+						if !ok || name.Name == "_" {
+							continue
+						}
+						values := spec.Values
+						if len(spec.Values) == len(spec.Names) {
+							values = spec.Values[i : i+1]
+						}
+						pg.Variables = append(pg.Variables, a.globalVar(importPath, v, values, writes[v]))
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Name.Name == "init" && decl.Recv == nil && decl.Body != nil {
+					pg.InitFuncs = append(pg.InitFuncs, a.initFunc(importPath, decl))
+				}
+			}
+		}
+	}
+	return pg
+}
+
+// globalVar describes a package-level variable initialized by values
+func (a *Analyzer) globalVar(importPath string, v *types.Var, values []ast.Expr, writes []GlobalWrite) GlobalVar {
+	info := a.infos[importPath]
+	gv := GlobalVar{
+		Name:     globalName(v),
+		Type:     types.TypeString(v.Type(), types.RelativeTo(a.pkgs[importPath])),
+		Position: a.position(v.Pos()),
+		Writes:   []GlobalWrite{},
+	}
+	for _, value := range values {
+		ast.Inspect(value, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := info.Uses[ident]
+			if obj == nil || obj.Pkg() == nil || obj.Pkg().Path() == importPath || !a.isRepoGlobal(obj) {
+				return true
+			}
+			if name := globalName(obj); !slices.Contains(gv.InitDeps, name) {
+				gv.InitDeps = append(gv.InitDeps, name)
+			}
+			return true
+		})
+	}
+
+	gv.Writes = append(gv.Writes, writes...)
+	sortGlobalWrites(gv.Writes)
+	for _, write := range gv.Writes {
+		if !slices.Contains(gv.WrittenFrom, write.ImportPath) {
+			gv.WrittenFrom = append(gv.WrittenFrom, write.ImportPath)
+		}
+	}
+	sort.Strings(gv.WrittenFrom)
+	gv.Shared = len(gv.WrittenFrom) > 1
+	return gv
+}
+
+// initFunc describes the package-level variables an init function reads
+// and writes and the functions of other packages it calls
+func (a *Analyzer) initFunc(importPath string, decl *ast.FuncDecl) InitFunc {
+	info := a.infos[importPath]
+	fn := InitFunc{Position: a.position(decl.Name.Pos())}
+	add := func(names *[]string, obj types.Object) {
+		if name := globalName(obj); !slices.Contains(*names, name) {
+			*names = append(*names, name)
+		}
+	}
+
+	written := make(map[*ast.Ident]bool)
+	forEachGlobalWrite(info, decl.Body, func(ident *ast.Ident, v *types.Var) {
+		if a.isRepoGlobal(v) {
+			written[ident] = true
+			add(&fn.Writes, v)
+		}
+	})
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if v, ok := info.Uses[n].(*types.Var); ok && !written[n] && a.isRepoGlobal(v) {
+				add(&fn.Reads, v)
+			}
+		case *ast.CallExpr:
+			if callee, ok := calledFunc(info, n).(*types.Func); ok && callee.Pkg() != nil && callee.Pkg().Path() != importPath && a.isRepoGlobal(callee) {
+				add(&fn.Calls, callee)
+			}
+		}
+		return true
+	})
+	return fn
+}
+
+// collectGlobalWrites records the writes of a package to package-level
+// variables of repository packages
+func (a *Analyzer) collectGlobalWrites(importPath string, writes map[*types.Var][]GlobalWrite) {
+	info := a.infos[importPath]
+	if info == nil {
+		return
+	}
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			function := ""
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+					function = funcName(fn)
+				}
+			}
+			forEachGlobalWrite(info, decl, func(ident *ast.Ident, v *types.Var) {
+				if !a.isRepoGlobal(v) {
+					return
+				}
+				writes[v] = append(writes[v], GlobalWrite{
+					ImportPath: importPath,
+					Function:   function,
+					Position:   a.position(ident.Pos()),
+				})
+			})
+		}
+	}
+}
+
+// forEachGlobalWrite calls write for every write to a package-level
+// variable below node: assignments, increments and range assignments to
+// the variable or to its fields or elements, and taking its address. The
+// identifier is the one naming the variable.
+func forEachGlobalWrite(info *types.Info, node ast.Node, write func(*ast.Ident, *types.Var)) {
+	target := func(expr ast.Expr) {
+		if ident, v := writtenGlobal(info, expr); v != nil {
+			write(ident, v)
+		}
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				for _, lhs := range n.Lhs {
+					target(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			target(n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				if n.Key != nil {
+					target(n.Key)
+				}
+				if n.Value != nil {
+					target(n.Value)
+				}
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				target(n.X)
+			}
+		}
+		return true
+	})
+}
+
+// writtenGlobal returns the package-level variable a write to expr
+// changes, following field selections and index expressions to the
+// variable they start from
+func writtenGlobal(info *types.Info, expr ast.Expr) (*ast.Ident, *types.Var) {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			if v, ok := info.Uses[e].(*types.Var); ok && isPackageLevel(v) {
+				return e, v
+			}
+			return nil, nil
+		case *ast.SelectorExpr:
+			if sel := info.Selections[e]; sel != nil {
+				if sel.Kind() != types.FieldVal {
+					return nil, nil
+				}
+				expr = e.X
+				continue
+			}
+			// A qualified identifier
+			if v, ok := info.Uses[e.Sel].(*types.Var); ok && isPackageLevel(v) {
+				return e.Sel, v
+			}
+			return nil, nil
+		case *ast.IndexExpr:
+			expr = e.X
+		default:
+			return nil, nil
+		}
+	}
+}
+
+// isPackageLevel reports whether a variable is declared at package level
+func isPackageLevel(v *types.Var) bool {
+	return v.Pkg() != nil && !v.IsField() && v.Parent() == v.Pkg().Scope()
+}
+
+// isRepoGlobal reports whether obj is declared at package level in a
+// repository package
+func (a *Analyzer) isRepoGlobal(obj types.Object) bool {
+	if obj.Pkg() == nil || a.pkgs[obj.Pkg().Path()] == nil {
+		return false
+	}
+	switch obj := obj.(type) {
+	case *types.Var:
+		return isPackageLevel(obj)
+	case *types.Func:
+		return obj.Type().(*types.Signature).Recv() == nil
+	}
+	return false
+}
+
+// globalName qualifies a package-level object with its package name
+func globalName(obj types.Object) string {
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// sortGlobalWrites orders writes by file, line and column
+func sortGlobalWrites(writes []GlobalWrite) {
+	sort.Slice(writes, func(i, j int) bool {
+		pi, pj := writes[i].Position, writes[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Column < pj.Column
+	})
+}
+
+// initOrder orders the repository packages so each comes after the
+// repository packages it imports. Of the packages ready at a time, the one
+// with the lowest import path comes first, as the go command orders them
+// since Go 1.21.
+func (a *Analyzer) initOrder() []string {
+	var pending []string
+	for _, importPath := range a.sortedPackagePaths() {
+		if !strings.HasSuffix(importPath, "_test") {
+			pending = append(pending, importPath)
+		}
+	}
+	done := make(map[string]bool)
+	order := make([]string, 0, len(pending))
+	for len(pending) > 0 {
+		next := 0
+		for i, importPath := range pending {
+			ready := true
+			for _, imported := range a.pkgs[importPath].Imports() {
+				if _, local := a.pkgs[imported.Path()]; local && !done[imported.Path()] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		// Import cycles do not type check; take the first package then
+		done[pending[next]] = true
+		order = append(order, pending[next])
+		pending = slices.Delete(pending, next, next+1)
+	}
+	return order
+}
@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlobalsReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"config/config.go": `package config
+
+import "example.com/app/registry"
+
+var Debug bool
+
+var Limits = map[string]int{}
+
+var Default = registry.Lookup("default")
+
+const Version = "1"
+
+var _ = Debug
+
+func init() {
+	Limits["max"] = registry.Size
+	registry.Register("config")
+}
+`,
+		"registry/registry.go": `package registry
+
+var names []string
+
+var Size = 10
+
+func Register(name string) {
+	names = append(names, name)
+	Size++
+}
+
+func Lookup(name string) string { return name }
+`,
+		"app/app.go": `package app
+
+import "example.com/app/config"
+
+func Enable() {
+	config.Debug = true
+	for _, limit := range []int{1} {
+		config.Limits["app"] = limit
+	}
+}
+
+func Local() {
+	debug := config.Debug
+	_ = debug
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		report, err := analyzer.GlobalsReport(ctx, "")
+		if err != nil {
+			t.Fatalf("Failed to get globals report: %v", err)
+		}
+		wantOrder := []string{"example.com/app/registry", "example.com/app/config", "example.com/app/app"}
+		if !reflect.DeepEqual(report.InitOrder, wantOrder) {
+			t.Errorf("Expected init order %v, got %v", wantOrder, report.InitOrder)
+		}
+		if !reflect.DeepEqual(report.Shared, []string{"config.Limits"}) {
+			t.Errorf("Expected config.Limits to be shared, got %v", report.Shared)
+		}
+		// app declares no globals and no init function
+		if len(report.Packages) != 2 {
+			t.Fatalf("Expected 2 packages, got %+v", report.Packages)
+		}
+
+		cfg := report.Packages[0]
+		if cfg.ImportPath != "example.com/app/config" || !reflect.DeepEqual(cfg.Imports, []string{"example.com/app/registry"}) {
+			t.Errorf("Expected config importing registry, got %+v", cfg)
+		}
+		vars := make(map[string]GlobalVar)
+		for _, v := range cfg.Variables {
+			vars[v.Name] = v
+		}
+		if len(vars) != 3 {
+			t.Errorf("Expected Debug, Limits and Default, got %+v", cfg.Variables)
+		}
+		debug := vars["config.Debug"]
+		if debug.Type != "bool" || len(debug.Writes) != 1 || debug.Writes[0].Function != "app.Enable" || debug.Shared {
+			t.Errorf("Expected Debug written once by app.Enable, got %+v", debug)
+		}
+		limits := vars["config.Limits"]
+		if !reflect.DeepEqual(limits.WrittenFrom, []string{"example.com/app/app", "example.com/app/config"}) || !limits.Shared {
+			t.Errorf("Expected Limits written from app and config, got %+v", limits)
+		}
+		if !reflect.DeepEqual(vars["config.Default"].InitDeps, []string{"registry.Lookup"}) {
+			t.Errorf("Expected Default to depend on registry.Lookup, got %+v", vars["config.Default"])
+		}
+
+		if len(cfg.InitFuncs) != 1 {
+			t.Fatalf("Expected one init function, got %+v", cfg.InitFuncs)
+		}
+		initFn := cfg.InitFuncs[0]
+		if !reflect.DeepEqual(initFn.Writes, []string{"config.Limits"}) || !reflect.DeepEqual(initFn.Reads, []string{"registry.Size"}) || !reflect.DeepEqual(initFn.Calls, []string{"registry.Register"}) {
+			t.Errorf("Expected init to write Limits, read Size and call Register, got %+v", initFn)
+		}
+
+		reg := report.Packages[1]
+		if reg.ImportPath != "example.com/app/registry" || len(reg.Variables) != 2 {
+			t.Fatalf("Expected names and Size in registry, got %+v", reg)
+		}
+		for _, v := range reg.Variables {
+			if len(v.Writes) != 1 || v.Writes[0].Function != "registry.Register" {
+				t.Errorf("Expected %s written by Register, got %+v", v.Name, v.Writes)
+			}
+		}
+
+		filtered, err := analyzer.GlobalsReport(ctx, "registry")
+		if err != nil {
+			t.Fatalf("Failed to get globals report for registry: %v", err)
+		}
+		if len(filtered.Packages) != 1 || len(filtered.InitOrder) != 3 {
+			t.Errorf("Expected only registry with the full init order, got %+v", filtered)
+		}
+		if _, err := analyzer.GlobalsReport(ctx, "missing"); err == nil {
+			t.Error("Expected an error for an unknown package")
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/scope/internal/proto"
+)
+
+// GRPCMap links the declarations of the repository's .proto files to the
+// Go code generated for them and to the servers implementing their
+// services
+type GRPCMap struct {
+	Files []ProtoFileMap `json:"files"`
+	// Errors are the .proto files that could not be parsed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ProtoFileMap is a .proto file with its declarations
+type ProtoFileMap struct {
+	File    string `json:"file"`
+	Package string `json:"package,omitempty"`
+	// GoPackage is the import path of the generated package; empty when it
+	// is not among the analyzed packages
+	GoPackage string         `json:"go_package,omitempty"`
+	Messages  []ProtoTypeMap `json:"messages,omitempty"`
+	Enums     []ProtoTypeMap `json:"enums,omitempty"`
+	Services  []GRPCService  `json:"services,omitempty"`
+}
+
+// ProtoTypeMap is a message or enum with its generated Go type
+type ProtoTypeMap struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+	// GoType is qualified with the package name; empty when the generated
+	// type was not found
+	GoType   string    `json:"go_type,omitempty"`
+	Position *Position `json:"position,omitempty"`
+}
+
+// GRPCService is a service with its generated server interface and the
+// types implementing it
+type GRPCService struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+	// ServerInterface is the generated NameServer interface; empty when it
+	// was not found
+	ServerInterface string    `json:"server_interface,omitempty"`
+	Position        *Position `json:"position,omitempty"`
+	// Implementations are the types implementing the server interface,
+	// prefixed with * when only the pointer does
+	Implementations []string     `json:"implementations"`
+	Methods         []GRPCMethod `json:"methods"`
+}
+
+// GRPCMethod is an RPC with its implementations
+type GRPCMethod struct {
+	Name            string               `json:"name"`
+	GoName          string               `json:"go_name"`
+	Line            int                  `json:"line"`
+	Request         string               `json:"request"`
+	Response        string               `json:"response"`
+	ClientStreaming bool                 `json:"client_streaming,omitempty"`
+	ServerStreaming bool                 `json:"server_streaming,omitempty"`
+	Implementations []GRPCImplementation `json:"implementations"`
+}
+
+// GRPCImplementation is the method of a server type implementing an RPC
+type GRPCImplementation struct {
+	Type     string    `json:"type"`
+	Position *Position `json:"position,omitempty"`
+	// Unimplemented is set when the type only has the method of the
+	// embedded generated UnimplementedNameServer, which fails every call
+	Unimplemented bool `json:"unimplemented,omitempty"`
+}
+
+// GRPCMap parses the .proto files of the repository and maps their
+// messages, enums and services to the generated Go types. Services are
+// linked to the server types that satisfy their generated interface.
+// service limits the result to the services of that name.
+func (a *Analyzer) GRPCMap(ctx context.Context, service string) (*GRPCMap, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	filenames, err := a.protoFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &GRPCMap{Files: []ProtoFileMap{}}
+	generated := a.generatedPackages()
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", a.relPath(filename), err)
+		}
+		file, err := proto.Parse(a.relPath(filename), src)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+
+		fileMap := ProtoFileMap{File: file.Path, Package: file.Package}
+		pkg := a.protoPackage(file, filename, generated)
+		if pkg != nil {
+			fileMap.GoPackage = pkg.Path()
+		}
+		for _, svc := range file.Services {
+			if service != "" && svc.Name != service {
+				continue
+			}
+			fileMap.Services = append(fileMap.Services, a.grpcService(pkg, svc))
+		}
+		if service != "" {
+			if len(fileMap.Services) > 0 {
+				result.Files = append(result.Files, fileMap)
+			}
+			continue
+		}
+		for _, m := range file.Messages {
+			fileMap.Messages = append(fileMap.Messages, a.protoType(pkg, m.Name, m.Line))
+		}
+		for _, e := range file.Enums {
+			fileMap.Enums = append(fileMap.Enums, a.protoType(pkg, e.Name, e.Line))
+		}
+		result.Files = append(result.Files, fileMap)
+	}
+	if service != "" && len(result.Files) == 0 {
+		return nil, fmt.Errorf("service %s not found", service)
+	}
+	return result, nil
+}
+
+// protoFiles returns the .proto files of the repository the analysis does
+// not ignore. The caller holds a.mu.
+func (a *Analyzer) protoFiles(ctx context.Context) ([]string, error) {
+	var filenames []string
+	err := filepath.WalkDir(a.repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := a.relPath(path)
+		if a.ignored(path, d.IsDir()) || rel != "." && excludedPath(rel, a.config.ExcludePatterns) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".proto") {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk repository: %w", err)
+	}
+	return filenames, nil
+}
+
+// generatedPackages maps the .pb.go and _grpc.pb.go files of the analyzed
+// packages to their package. The caller holds a.mu.
+func (a *Analyzer) generatedPackages() map[string]*types.Package {
+	generated := make(map[string]*types.Package)
+	for _, importPath := range a.sortedPackagePaths() {
+		for _, file := range a.asts[importPath] {
+			filename := a.fset.Position(file.Package).Filename
+			if strings.HasSuffix(filename, ".pb.go") {
+				generated[filename] = a.pkgs[importPath]
+			}
+		}
+	}
+	return generated
+}
+
+// protoPackage finds the package generated from a .proto file: the one
+// its go_package option names, or else the one holding its .pb.go file,
+// preferably next to it
+func (a *Analyzer) protoPackage(file *proto.File, filename string, generated map[string]*types.Package) *types.Package {
+	if pkg := a.pkgs[file.GoImportPath()]; pkg != nil {
+		return pkg
+	}
+	base := strings.TrimSuffix(filepath.Base(filename), ".proto")
+	if pkg := generated[filepath.Join(filepath.Dir(filename), base+".pb.go")]; pkg != nil {
+		return pkg
+	}
+	var found *types.Package
+	for generatedFile, pkg := range generated {
+		if filepath.Base(generatedFile) != base+".pb.go" {
+			continue
+		}
+		if name := file.GoPackageName(); name != "" && pkg.Name() != name {
+			continue
+		}
+		// Deterministic among several candidates
+		if found == nil || pkg.Path() < found.Path() {
+			found = pkg
+		}
+	}
+	return found
+}
+
+// protoType maps a message or enum to its generated type
+func (a *Analyzer) protoType(pkg *types.Package, name string, line int) ProtoTypeMap {
+	mapped := ProtoTypeMap{Name: name, Line: line}
+	if pkg == nil {
+		return mapped
+	}
+	if obj, ok := pkg.Scope().Lookup(proto.GoName(name)).(*types.TypeName); ok {
+		mapped.GoType = pkg.Name() + "." + obj.Name()
+		pos := a.position(obj.Pos())
+		mapped.Position = &pos
+	}
+	return mapped
+}
+
+// grpcService maps a service to its generated server interface and the
+// types implementing it
+func (a *Analyzer) grpcService(pkg *types.Package, svc proto.Service) GRPCService {
+	mapped := GRPCService{Name: svc.Name, Line: svc.Line, Implementations: []string{}, Methods: []GRPCMethod{}}
+	goName := proto.GoName(svc.Name)
+	var iface *types.Interface
+	var unimplemented *types.TypeName
+	if pkg != nil {
+		if obj, ok := pkg.Scope().Lookup(goName + "Server").(*types.TypeName); ok {
+			if i, ok := obj.Type().Underlying().(*types.Interface); ok {
+				iface = i
+				mapped.ServerInterface = pkg.Name() + "." + obj.Name()
+				pos := a.position(obj.Pos())
+				mapped.Position = &pos
+			}
+		}
+		unimplemented, _ = pkg.Scope().Lookup("Unimplemented" + goName + "Server").(*types.TypeName)
+	}
+
+	// The server types and the receiver each implements through
+	type server struct {
+		recv types.Type
+		name string
+	}
+	var servers []server
+	if iface != nil {
+		for _, named := range a.namedTypes() {
+			if named.Obj() == unimplemented || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+				continue
+			}
+			name := named.Obj().Pkg().Name() + "." + named.Obj().Name()
+			switch {
+			case types.Implements(named, iface):
+				servers = append(servers, server{named, name})
+			case types.Implements(types.NewPointer(named), iface):
+				servers = append(servers, server{types.NewPointer(named), "*" + name})
+			default:
+				continue
+			}
+			mapped.Implementations = append(mapped.Implementations, servers[len(servers)-1].name)
+		}
+	}
+
+	for _, method := range svc.Methods {
+		m := GRPCMethod{
+			Name:            method.Name,
+			GoName:          proto.GoName(method.Name),
+			Line:            method.Line,
+			Request:         method.Request,
+			Response:        method.Response,
+			ClientStreaming: method.ClientStreaming,
+			ServerStreaming: method.ServerStreaming,
+			Implementations: []GRPCImplementation{},
+		}
+		for _, s := range servers {
+			fn, ok := lookupMethod(s.recv, pkg, m.GoName)
+			if !ok {
+				continue
+			}
+			impl := GRPCImplementation{Type: s.name}
+			pos := a.position(fn.Pos())
+			impl.Position = &pos
+			if recv := receiverNamed(fn.Type().(*types.Signature).Recv().Type()); recv != nil && recv.Obj() == unimplemented {
+				impl.Unimplemented = true
+			}
+			m.Implementations = append(m.Implementations, impl)
+		}
+		mapped.Methods = append(mapped.Methods, m)
+	}
+	return mapped
+}
@@ -0,0 +1,162 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGRPCMap(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"proto/greeter.proto": `syntax = "proto3";
+package shop;
+option go_package = "example.com/shop/api;api";
+
+message HelloRequest {
+  message Inner { string x = 1; }
+  string name = 1;
+}
+message HelloReply { string message = 1; }
+enum Mood { MOOD_UNSPECIFIED = 0; }
+
+service Greeter {
+  rpc SayHello(HelloRequest) returns (HelloReply);
+  rpc lots_of_replies(HelloRequest) returns (stream HelloReply);
+}
+`,
+		"api/greeter.pb.go": `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+package api
+
+type HelloRequest struct{ Name string }
+
+type HelloRequest_Inner struct{ X string }
+
+type HelloReply struct{ Message string }
+
+type Mood int32
+`,
+		"api/greeter_grpc.pb.go": `// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package api
+
+import "context"
+
+type Greeter_LotsOfRepliesServer interface {
+	Send(*HelloReply) error
+}
+
+type GreeterServer interface {
+	SayHello(context.Context, *HelloRequest) (*HelloReply, error)
+	LotsOfReplies(*HelloRequest, Greeter_LotsOfRepliesServer) error
+	mustEmbedUnimplementedGreeterServer()
+}
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) SayHello(context.Context, *HelloRequest) (*HelloReply, error) {
+	return nil, nil
+}
+
+func (UnimplementedGreeterServer) LotsOfReplies(*HelloRequest, Greeter_LotsOfRepliesServer) error {
+	return nil
+}
+
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+`,
+		"server/server.go": `package server
+
+import (
+	"context"
+
+	"example.com/shop/api"
+)
+
+type greeter struct {
+	api.UnimplementedGreeterServer
+}
+
+func (g *greeter) SayHello(ctx context.Context, req *api.HelloRequest) (*api.HelloReply, error) {
+	return &api.HelloReply{Message: "hello " + req.Name}, nil
+}
+`,
+		// No go_package: the generated file next to it gives the package
+		"events/events.proto":   "syntax = \"proto3\";\nmessage Event { string id = 1; }\n",
+		"events/events.pb.go":   "package events\n\ntype Event struct{ Id string }\n",
+		"broken/broken.proto":   "message Broken {\n",
+		"vendor/x/skip.proto":   "message Skipped {}\n",
+		"events/unmapped.proto": "message Orphan {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	grpcMap, err := analyzer.GRPCMap(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to map gRPC services: %v", err)
+	}
+	if len(grpcMap.Files) != 3 || len(grpcMap.Errors) != 1 || !strings.HasPrefix(grpcMap.Errors[0], "broken/broken.proto:") {
+		t.Fatalf("Expected 3 files and the broken one as an error, got %+v", grpcMap)
+	}
+	byFile := make(map[string]ProtoFileMap)
+	for _, file := range grpcMap.Files {
+		byFile[file.File] = file
+	}
+
+	events := byFile["events/events.proto"]
+	if events.GoPackage != "example.com/shop/events" || len(events.Messages) != 1 || events.Messages[0].GoType != "events.Event" {
+		t.Errorf("Expected Event to map by its .pb.go file, got %+v", events)
+	}
+	if orphan := byFile["events/unmapped.proto"]; orphan.GoPackage != "" || orphan.Messages[0].GoType != "" {
+		t.Errorf("Expected no Go package for a file without generated code, got %+v", orphan)
+	}
+
+	greeter := byFile["proto/greeter.proto"]
+	if greeter.GoPackage != "example.com/shop/api" || len(greeter.Messages) != 3 || greeter.Messages[1].GoType != "api.HelloRequest_Inner" || greeter.Enums[0].GoType != "api.Mood" {
+		t.Errorf("Unexpected greeter types: %+v", greeter)
+	}
+	if pos := greeter.Messages[0].Position; pos == nil || !pos.Generated || pos.Line != 5 {
+		t.Errorf("Expected the generated position of HelloRequest, got %+v", pos)
+	}
+	if len(greeter.Services) != 1 {
+		t.Fatalf("Expected the Greeter service, got %+v", greeter.Services)
+	}
+	service := greeter.Services[0]
+	if service.ServerInterface != "api.GreeterServer" || len(service.Implementations) != 1 || service.Implementations[0] != "*server.greeter" {
+		t.Errorf("Expected *server.greeter to implement api.GreeterServer, got %+v", service)
+	}
+	sayHello, lots := service.Methods[0], service.Methods[1]
+	if len(sayHello.Implementations) != 1 || sayHello.Implementations[0].Unimplemented || sayHello.Implementations[0].Position.Line != 13 {
+		t.Errorf("Expected SayHello to be implemented by the server, got %+v", sayHello)
+	}
+	if lots.GoName != "LotsOfReplies" || !lots.ServerStreaming || len(lots.Implementations) != 1 || !lots.Implementations[0].Unimplemented {
+		t.Errorf("Expected LotsOfReplies to be left unimplemented, got %+v", lots)
+	}
+
+	grpcMap, err = analyzer.GRPCMap(ctx, "Greeter")
+	if err != nil {
+		t.Fatalf("Failed to map the Greeter service: %v", err)
+	}
+	if len(grpcMap.Files) != 1 || len(grpcMap.Files[0].Messages) != 0 {
+		t.Errorf("Expected only the Greeter service, got %+v", grpcMap.Files)
+	}
+	if _, err := analyzer.GRPCMap(ctx, "Nope"); err == nil {
+		t.Error("Expected error for an unknown service")
+	}
+}
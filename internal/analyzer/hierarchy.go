@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+)
+
+// TypeRef identifies a named type within a type hierarchy
+type TypeRef struct {
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	Kind       string `json:"kind"`
+	// Pointer reports that the relationship holds through a pointer: the type
+	// is embedded as *T, or only *T implements the interface
+	Pointer bool      `json:"pointer,omitempty"`
+	Embeds  []TypeRef `json:"embeds,omitempty"`
+}
+
+// HierarchyInfo describes the composition relationships of a type
+type HierarchyInfo struct {
+	Type          TypeRef   `json:"type"`
+	Implements    []TypeRef `json:"implements,omitempty"`
+	ImplementedBy []TypeRef `json:"implemented_by,omitempty"`
+	EmbeddedBy    []TypeRef `json:"embedded_by,omitempty"`
+}
+
+// TypeHierarchy returns the embedding tree of a type (recursively), the
+// analyzed interfaces it implements, the analyzed types implementing it when
+// it is an interface, and the analyzed types that embed it
+func (a *Analyzer) TypeHierarchy(ctx context.Context, typeName string) (*HierarchyInfo, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", typeName)
+	}
+	return a.hierarchyOf(typeObj), nil
+}
+
+// hierarchyOf implements TypeHierarchy; callers hold the read lock
+func (a *Analyzer) hierarchyOf(typeObj *types.TypeName) *HierarchyInfo {
+	named, _ := typeObj.Type().(*types.Named)
+	info := &HierarchyInfo{
+		Type: embeddingTree(typeObj, false, map[*types.TypeName]bool{}),
+	}
+	if named == nil {
+		return info
+	}
+
+	_, isInterface := named.Underlying().(*types.Interface)
+	generic := named.TypeParams().Len() > 0
+	for _, candidate := range a.namedTypes() {
+		if candidate.Obj() == typeObj {
+			continue
+		}
+
+		if ptr, ok := embeds(candidate, named); ok {
+			ref := typeRef(candidate.Obj(), false)
+			ref.Pointer = ptr
+			info.EmbeddedBy = append(info.EmbeddedBy, ref)
+		}
+
+		if generic || candidate.TypeParams().Len() > 0 {
+			continue
+		}
+		if iface, ok := candidate.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			if types.Implements(named, iface) {
+				info.Implements = append(info.Implements, typeRef(candidate.Obj(), false))
+			} else if !isInterface && types.Implements(types.NewPointer(named), iface) {
+				info.Implements = append(info.Implements, typeRef(candidate.Obj(), true))
+			}
+		}
+		if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			if _, candidateIsInterface := candidate.Underlying().(*types.Interface); candidateIsInterface {
+				continue
+			}
+			if types.Implements(candidate, iface) {
+				info.ImplementedBy = append(info.ImplementedBy, typeRef(candidate.Obj(), false))
+			} else if types.Implements(types.NewPointer(candidate), iface) {
+				info.ImplementedBy = append(info.ImplementedBy, typeRef(candidate.Obj(), true))
+			}
+		}
+	}
+
+	return info
+}
+
+// namedTypes returns every package-level named type in the analyzed
+// packages, in import path and then declaration name order
+func (a *Analyzer) namedTypes() []*types.Named {
+	var named []*types.Named
+	for _, sym := range a.index.types {
+		typeObj := sym.obj.(*types.TypeName)
+		if typeObj.IsAlias() {
+			continue
+		}
+		if t, ok := typeObj.Type().(*types.Named); ok {
+			named = append(named, t)
+		}
+	}
+	return named
+}
+
+// embeddingTree returns the reference for obj with its embedded types
+// resolved recursively. visiting guards against embedding cycles through
+// pointers.
+func embeddingTree(obj *types.TypeName, pointer bool, visiting map[*types.TypeName]bool) TypeRef {
+	ref := typeRef(obj, pointer)
+	if visiting[obj] {
+		return ref
+	}
+	visiting[obj] = true
+	defer delete(visiting, obj)
+
+	for _, e := range embeddedTypes(obj.Type()) {
+		ref.Embeds = append(ref.Embeds, embeddingTree(e.named.Origin().Obj(), e.pointer, visiting))
+	}
+	return ref
+}
+
+// embedding is a named type embedded in a struct or interface
+type embedding struct {
+	named   *types.Named
+	pointer bool
+}
+
+// embeddedTypes returns the named types directly embedded in t's underlying
+// struct fields or interface
+func embeddedTypes(t types.Type) []embedding {
+	var result []embedding
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			if !field.Embedded() {
+				continue
+			}
+			fieldType, pointer := field.Type(), false
+			if p, ok := fieldType.(*types.Pointer); ok {
+				fieldType, pointer = p.Elem(), true
+			}
+			if named, ok := fieldType.(*types.Named); ok {
+				result = append(result, embedding{named: named, pointer: pointer})
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			if named, ok := u.EmbeddedType(i).(*types.Named); ok {
+				result = append(result, embedding{named: named})
+			}
+		}
+	}
+	return result
+}
+
+// embeds reports whether candidate directly embeds target, and whether it
+// does so through a pointer
+func embeds(candidate, target *types.Named) (bool, bool) {
+	for _, e := range embeddedTypes(candidate) {
+		if e.named.Origin().Obj() == target.Obj() {
+			return e.pointer, true
+		}
+	}
+	return false, false
+}
+
+// typeRef builds the reference for a named type without its embeddings
+func typeRef(obj *types.TypeName, pointer bool) TypeRef {
+	ref := TypeRef{
+		Name:    obj.Name(),
+		Kind:    kindOf(obj.Type()),
+		Pointer: pointer,
+	}
+	if obj.Pkg() != nil {
+		ref.ImportPath = obj.Pkg().Path()
+	}
+	return ref
+}
@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeHierarchy(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shapes\n\ngo 1.21\n",
+		"io/io.go": `package io
+
+type Reader interface {
+	Read() string
+}
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	Reader
+	Closer
+}
+`,
+		"shapes/shapes.go": `package shapes
+
+import "example.com/shapes/io"
+
+type Base struct {
+	ID int
+}
+
+func (b Base) Read() string { return "" }
+
+type Named struct {
+	*Base
+	Name string
+}
+
+func (n *Named) Close() error { return nil }
+
+type File struct {
+	Named
+	io.Closer
+}
+
+type Loop struct {
+	*Loop
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	a, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	t.Run("Embeddings", func(t *testing.T) {
+		h, err := a.TypeHierarchy(context.Background(), "File")
+		if err != nil {
+			t.Fatalf("TypeHierarchy failed: %v", err)
+		}
+		if len(h.Type.Embeds) != 2 || h.Type.Embeds[0].Name != "Named" || h.Type.Embeds[1].Name != "Closer" {
+			t.Fatalf("Unexpected embeddings: %+v", h.Type.Embeds)
+		}
+		nested := h.Type.Embeds[0].Embeds
+		if len(nested) != 1 || nested[0].Name != "Base" || !nested[0].Pointer {
+			t.Errorf("Expected Named to embed *Base, got %+v", nested)
+		}
+		if h.Type.Embeds[1].ImportPath != "example.com/shapes/io" || h.Type.Embeds[1].Kind != "interface" {
+			t.Errorf("Unexpected Closer reference: %+v", h.Type.Embeds[1])
+		}
+	})
+
+	t.Run("Implements", func(t *testing.T) {
+		h, err := a.TypeHierarchy(context.Background(), "Named")
+		if err != nil {
+			t.Fatalf("TypeHierarchy failed: %v", err)
+		}
+		implements := map[string]bool{}
+		for _, ref := range h.Implements {
+			implements[ref.Name] = ref.Pointer
+		}
+		if pointer, ok := implements["Reader"]; !ok || pointer {
+			t.Errorf("Expected Named to implement Reader by value, got %+v", h.Implements)
+		}
+		if pointer, ok := implements["ReadCloser"]; !ok || !pointer {
+			t.Errorf("Expected *Named to implement ReadCloser, got %+v", h.Implements)
+		}
+		if len(h.EmbeddedBy) != 1 || h.EmbeddedBy[0].Name != "File" || h.EmbeddedBy[0].Pointer {
+			t.Errorf("Expected Named to be embedded by File, got %+v", h.EmbeddedBy)
+		}
+	})
+
+	t.Run("Interface", func(t *testing.T) {
+		h, err := a.TypeHierarchy(context.Background(), "io.Closer")
+		if err != nil {
+			t.Fatalf("TypeHierarchy failed: %v", err)
+		}
+		var embeddedBy []string
+		for _, ref := range h.EmbeddedBy {
+			embeddedBy = append(embeddedBy, ref.Name)
+		}
+		if len(embeddedBy) != 2 || embeddedBy[0] != "ReadCloser" || embeddedBy[1] != "File" {
+			t.Errorf("Expected Closer to be embedded by ReadCloser and File, got %v", embeddedBy)
+		}
+		implementedBy := map[string]bool{}
+		for _, ref := range h.ImplementedBy {
+			implementedBy[ref.Name] = ref.Pointer
+		}
+		if pointer, ok := implementedBy["Named"]; !ok || !pointer {
+			t.Errorf("Expected *Named to implement Closer, got %+v", h.ImplementedBy)
+		}
+		// File promotes Close from both Named and io.Closer at the same
+		// depth, so the selector is ambiguous and File has no Close method
+		if _, ok := implementedBy["File"]; ok {
+			t.Errorf("Expected File not to implement Closer, got %+v", h.ImplementedBy)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		h, err := a.TypeHierarchy(context.Background(), "Loop")
+		if err != nil {
+			t.Fatalf("TypeHierarchy failed: %v", err)
+		}
+		if len(h.Type.Embeds) != 1 || len(h.Type.Embeds[0].Embeds) != 0 {
+			t.Errorf("Expected cycle to stop after one level, got %+v", h.Type)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the file at the repository root listing paths, in
+// gitignore syntax, to leave out of the analysis
+const IgnoreFile = ".scopeignore"
+
+// ignoreRules are the patterns of an ignore file, in file order
+type ignoreRules []ignoreRule
+
+// ignoreRule is one pattern of an ignore file
+type ignoreRule struct {
+	segments []string // The pattern split at slashes; "**" matches any number of segments
+	negate   bool     // The pattern starts with "!" and re-includes what it matches
+	dirOnly  bool     // The pattern ends with "/" and only matches directories
+	anchored bool     // The pattern holds a slash and matches from the root only
+}
+
+// readIgnoreFile reads the ignore file of a repository. It returns nil
+// when there is none.
+func readIgnoreFile(repoPath string) (ignoreRules, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnore(data)
+}
+
+// parseIgnore parses gitignore-style patterns: blank lines and lines
+// starting with "#" are skipped, "!" negates a pattern, a trailing "/"
+// matches directories only, a pattern with another slash is relative to
+// the root, and "*", "?", "[...]" and "**" match as in gitignore
+func parseIgnore(data []byte) (ignoreRules, error) {
+	var rules ignoreRules
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		switch {
+		case strings.HasPrefix(line, "!"):
+			rule.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		for _, segment := range rule.segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", IgnoreFile, lineNum, scanner.Text(), err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// ignored reports whether a path relative to the root, with forward
+// slashes, is ignored. As in gitignore, the last matching pattern decides.
+// Paths inside an ignored directory are not matched here: the walk does
+// not enter the directory.
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	segments := strings.Split(rel, "/")
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(segments) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether a rule matches a path. Unanchored patterns
+// match the last segments of the path, anchored ones all of it.
+func (rule ignoreRule) matches(segments []string) bool {
+	if rule.anchored {
+		return matchSegments(rule.segments, segments)
+	}
+	return matchSegments(rule.segments, segments[len(segments)-1:])
+}
+
+// matchSegments matches path segments against pattern segments, where
+// "**" stands for any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
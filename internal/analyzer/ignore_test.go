@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules, err := parseIgnore([]byte(`# Generated trees
+gen/
+/build
+api/**/*.pb.go
+*_mock.go
+!keep_mock.go
+\#weird.go
+third_party/**
+!third_party/fork/
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse ignore patterns: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"gen", true, true},
+		{"internal/gen", true, true},
+		{"gen", false, false},
+		{"build", true, true},
+		{"cmd/build", true, false},
+		{"api/v1/user.pb.go", false, true},
+		{"api/user.pb.go", false, true},
+		{"internal/api/user.pb.go", false, false},
+		{"store/store_mock.go", false, true},
+		{"store/keep_mock.go", false, false},
+		{"#weird.go", false, true},
+		{"third_party/lib", true, true},
+		{"third_party/fork", true, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := rules.ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Expected ignored(%q, dir %v) to be %v, got %v", tt.path, tt.isDir, tt.want, got)
+		}
+	}
+
+	if _, err := parseIgnore([]byte("[a-\n")); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
+
+func TestScopeIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                "module example.com/app\n\ngo 1.21\n",
+		IgnoreFile:              "# Keep generated code out\ngen/\n*_mock.go\n",
+		"app/app.go":            "package app\n\ntype App struct{}\n",
+		"app/app_mock.go":       "package app\n\ntype MockApp struct{}\n",
+		"gen/api/api.go":        "package api\n\ntype Request struct{}\n",
+		"internal/gen/types.go": "package gen\n\ntype Type struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+
+		packages := analyzer.Packages()
+		sort.Strings(packages)
+		if want := []string{"example.com/app/app"}; !reflect.DeepEqual(packages, want) {
+			t.Errorf("Expected packages %v (lazy %v), got %v", want, lazy, packages)
+		}
+		if _, err := analyzer.LookupType(context.Background(), "MockApp"); err == nil {
+			t.Error("Expected MockApp to be ignored")
+		}
+		if _, err := analyzer.LookupType(context.Background(), "App"); err != nil {
+			t.Errorf("Failed to look up App: %v", err)
+		}
+	}
+
+	// Refresh reads the ignore file again
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFile), []byte("gen/\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite %s: %v", IgnoreFile, err)
+	}
+	if err := analyzer.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if _, err := analyzer.LookupType(context.Background(), "MockApp"); err != nil {
+		t.Errorf("Expected MockApp after removing its pattern: %v", err)
+	}
+}
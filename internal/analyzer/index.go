@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// Symbol is a package-level declaration as recorded in the symbol index
+type Symbol struct {
+	Name       string   `json:"name"`
+	Package    string   `json:"package"`
+	ImportPath string   `json:"import_path"`
+	Kind       string   `json:"kind"` // "type", "func", "var" or "const"
+	Position   Position `json:"position"`
+}
+
+// symbolIndex maps identifiers to the package-level objects declaring them,
+// so lookups do not walk every package scope
+type symbolIndex struct {
+	byName map[string][]indexedSymbol
+	types  []indexedSymbol // Every type name, ordered by import path and name
+}
+
+// indexedSymbol is an object in the symbol index
+type indexedSymbol struct {
+	importPath string
+	lowerName  string
+	obj        types.Object
+}
+
+// buildIndex indexes the package-level objects of all type-checked packages.
+// Entries are ordered by import path and then name, matching the order in
+// which packages used to be searched.
+func (a *Analyzer) buildIndex() {
+	index := symbolIndex{byName: make(map[string][]indexedSymbol)}
+	for _, importPath := range a.sortedPackagePaths() {
+		scope := a.pkgs[importPath].Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if obj == nil {
+				continue
+			}
+			sym := indexedSymbol{importPath: importPath, lowerName: strings.ToLower(name), obj: obj}
+			index.byName[name] = append(index.byName[name], sym)
+			if _, ok := obj.(*types.TypeName); ok {
+				index.types = append(index.types, sym)
+			}
+		}
+	}
+	a.index = index
+}
+
+// lookup returns the indexed objects named ident in packages selected by qualifier
+func (idx *symbolIndex) lookup(qualifier, ident string) []indexedSymbol {
+	var matches []indexedSymbol
+	for _, sym := range idx.byName[ident] {
+		if matchesQualifier(qualifier, sym.importPath, sym.obj.Pkg().Name()) {
+			matches = append(matches, sym)
+		}
+	}
+	return matches
+}
+
+// Symbols returns every package-level declaration with the given name, which
+// may be qualified with a package name or import path
+func (a *Analyzer) Symbols(ctx context.Context, name string) ([]Symbol, error) {
+	if err := a.rlockNames(ctx, name); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	qualifier, ident := splitQualifiedName(name)
+	symbols := []Symbol{}
+	for _, sym := range a.index.lookup(qualifier, ident) {
+		symbols = append(symbols, Symbol{
+			Name:       sym.obj.Name(),
+			Package:    sym.obj.Pkg().Name(),
+			ImportPath: sym.importPath,
+			Kind:       objectKind(sym.obj),
+			Position:   a.position(sym.obj.Pos()),
+		})
+	}
+	return symbols, nil
+}
+
+// objectKind names the kind of a package-level object
+func objectKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.TypeName:
+		return "type"
+	case *types.Func:
+		return "func"
+	case *types.Var:
+		return "var"
+	case *types.Const:
+		return "const"
+	}
+	return "other"
+}
@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSyntheticRepo writes a module with pkgs packages declaring types
+// types each, named T0..Tn and shared across packages to exercise ambiguity
+func writeSyntheticRepo(tb testing.TB, pkgs, types int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/big\n\ngo 1.21\n"), 0644); err != nil {
+		tb.Fatalf("Failed to write go.mod: %v", err)
+	}
+	for p := 0; p < pkgs; p++ {
+		var src strings.Builder
+		fmt.Fprintf(&src, "package p%d\n\n", p)
+		for i := 0; i < types; i++ {
+			fmt.Fprintf(&src, "// T%d is a type\ntype T%d struct{ Field int }\n\n", i, i)
+			fmt.Fprintf(&src, "// Method does nothing\nfunc (t *T%d) Method() int { return t.Field }\n\n", i)
+		}
+		fmt.Fprintf(&src, "// Unique%d is only declared here\nfunc Unique%d() {}\n", p, p)
+
+		pkgDir := filepath.Join(dir, fmt.Sprintf("p%d", p))
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			tb.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(pkgDir, "types.go"), []byte(src.String()), 0644); err != nil {
+			tb.Fatalf("Failed to write package: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestSymbols(t *testing.T) {
+	analyzer, err := NewAnalyzer(writeSyntheticRepo(t, 3, 2))
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	symbols, err := analyzer.Symbols(context.Background(), "T1")
+	if err != nil {
+		t.Fatalf("Failed to look up symbols: %v", err)
+	}
+	if len(symbols) != 3 {
+		t.Fatalf("Expected T1 in 3 packages, got %v", symbols)
+	}
+	for i, sym := range symbols {
+		if want := fmt.Sprintf("example.com/big/p%d", i); sym.ImportPath != want || sym.Kind != "type" {
+			t.Errorf("Unexpected symbol %+v, expected a type in %s", sym, want)
+		}
+		if sym.Position.Line == 0 || !strings.HasSuffix(sym.Position.Filename, "types.go") {
+			t.Errorf("Expected position of %s, got %+v", sym.Name, sym.Position)
+		}
+	}
+
+	symbols, err = analyzer.Symbols(context.Background(), "p2.Unique2")
+	if err != nil || len(symbols) != 1 || symbols[0].Kind != "func" {
+		t.Errorf("Expected the Unique2 function, got %v (%v)", symbols, err)
+	}
+	if symbols, _ := analyzer.Symbols(context.Background(), "p1.Unique2"); len(symbols) != 0 {
+		t.Errorf("Expected no symbols for a qualifier matching no package, got %v", symbols)
+	}
+
+	// The index is rebuilt on refresh
+	if err := os.WriteFile(filepath.Join(analyzer.RepoPath(), "p0", "extra.go"), []byte("package p0\n\nconst Extra = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := analyzer.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if symbols, _ := analyzer.Symbols(context.Background(), "Extra"); len(symbols) != 1 || symbols[0].Kind != "const" {
+		t.Errorf("Expected the Extra constant after refresh, got %v", symbols)
+	}
+}
+
+// newBenchmarkAnalyzer analyzes a repository of 200 packages with 50 types each
+func newBenchmarkAnalyzer(b *testing.B) *Analyzer {
+	config := DefaultConfig()
+	config.LogLevel = LogLevelError
+	analyzer, err := NewAnalyzerWithConfig(writeSyntheticRepo(b, 200, 50), config)
+	if err != nil {
+		b.Fatalf("Failed to create analyzer: %v", err)
+	}
+	return analyzer
+}
+
+func BenchmarkLookupType(b *testing.B) {
+	analyzer := newBenchmarkAnalyzer(b)
+	defer analyzer.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := analyzer.LookupType(context.Background(), "p199.T49"); err != nil {
+			b.Fatalf("Failed to look up type: %v", err)
+		}
+	}
+}
+
+func BenchmarkSearchTypes(b *testing.B) {
+	analyzer := newBenchmarkAnalyzer(b)
+	defer analyzer.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results, err := analyzer.SearchTypes(context.Background(), "p7.T4"); err != nil || len(results) != 11 {
+			b.Fatalf("Unexpected search results: %d (%v)", len(results), err)
+		}
+	}
+}
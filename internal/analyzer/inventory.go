@@ -0,0 +1,271 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// languages maps file extensions to the language of the files
+var languages = map[string]string{
+	".go":         "Go",
+	".s":          "Assembly",
+	".c":          "C",
+	".h":          "C",
+	".cc":         "C++",
+	".cpp":        "C++",
+	".hpp":        "C++",
+	".proto":      "Protocol Buffers",
+	".sql":        "SQL",
+	".yaml":       "YAML",
+	".yml":        "YAML",
+	".json":       "JSON",
+	".toml":       "TOML",
+	".xml":        "XML",
+	".sh":         "Shell",
+	".bash":       "Shell",
+	".zsh":        "Shell",
+	".py":         "Python",
+	".js":         "JavaScript",
+	".mjs":        "JavaScript",
+	".ts":         "TypeScript",
+	".tsx":        "TypeScript",
+	".jsx":        "JavaScript",
+	".html":       "HTML",
+	".tmpl":       "Go Template",
+	".gotmpl":     "Go Template",
+	".css":        "CSS",
+	".md":         "Markdown",
+	".txt":        "Text",
+	".mod":        "Go Module",
+	".sum":        "Go Module",
+	".work":       "Go Module",
+	".tf":         "Terraform",
+	".graphql":    "GraphQL",
+	".rs":         "Rust",
+	".java":       "Java",
+	".rb":         "Ruby",
+	".mk":         "Makefile",
+	".bzl":        "Starlark",
+	".bazel":      "Starlark",
+	".csv":        "CSV",
+	".svg":        "SVG",
+	".png":        "Image",
+	".jpg":        "Image",
+	".jpeg":       "Image",
+	".gif":        "Image",
+	".pprof":      "Profile",
+	".dockerfile": "Dockerfile",
+}
+
+// languageFiles maps file names without a telling extension to their
+// language
+var languageFiles = map[string]string{
+	"Makefile":      "Makefile",
+	"GNUmakefile":   "Makefile",
+	"Dockerfile":    "Dockerfile",
+	"Containerfile": "Dockerfile",
+	"BUILD":         "Starlark",
+	"WORKSPACE":     "Starlark",
+	"LICENSE":       "Text",
+	"CODEOWNERS":    "Text",
+}
+
+// languageOther is the language of files the tables do not know
+const languageOther = "Other"
+
+// InventoryOptions bound the file listings of an inventory
+type InventoryOptions struct {
+	// Largest is the number of largest files to list
+	Largest int
+	// Recent is the number of most recently modified files to list
+	Recent int
+}
+
+// Inventory summarizes the files of a repository, Go or not, by language
+// and top-level directory
+type Inventory struct {
+	Files     int                 `json:"files"`
+	Bytes     int64               `json:"bytes"`
+	Languages []LanguageInventory `json:"languages"`
+	// Directories are the top-level directories, with the files at the
+	// root as "."
+	Directories []DirectoryInventory `json:"directories"`
+	Largest     []InventoryFile      `json:"largest"`
+	// Recent are the files modified last, by modification time
+	Recent []InventoryFile `json:"recent"`
+}
+
+// LanguageInventory counts the files of a language
+type LanguageInventory struct {
+	Language   string   `json:"language"`
+	Extensions []string `json:"extensions,omitempty"`
+	Files      int      `json:"files"`
+	Bytes      int64    `json:"bytes"`
+}
+
+// DirectoryInventory counts the files under a top-level directory
+type DirectoryInventory struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+	// Languages counts the files by language
+	Languages map[string]int `json:"languages"`
+}
+
+// InventoryFile is a file of an inventory listing
+type InventoryFile struct {
+	Path     string    `json:"path"`
+	Language string    `json:"language"`
+	Bytes    int64     `json:"bytes"`
+	Modified time.Time `json:"modified"`
+}
+
+// Inventory walks the repository and summarizes its files. Paths the
+// ignore file or the exclude patterns list are left out, like in the
+// analysis.
+func (a *Analyzer) Inventory(ctx context.Context, opts InventoryOptions) (*Inventory, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	inventory := &Inventory{Languages: []LanguageInventory{}, Directories: []DirectoryInventory{}}
+	byLanguage := make(map[string]*LanguageInventory)
+	extensions := make(map[string]map[string]bool)
+	byDirectory := make(map[string]*DirectoryInventory)
+	var files []InventoryFile
+	err := filepath.WalkDir(a.repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := a.relPath(path)
+		if a.ignored(path, d.IsDir()) || rel != "." && excludedPath(rel, a.config.ExcludePatterns) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		language, ext := fileLanguage(d.Name())
+		file := InventoryFile{Path: rel, Language: language, Bytes: info.Size(), Modified: info.ModTime()}
+		files = append(files, file)
+		inventory.Files++
+		inventory.Bytes += file.Bytes
+
+		lang := byLanguage[language]
+		if lang == nil {
+			lang = &LanguageInventory{Language: language}
+			byLanguage[language] = lang
+			extensions[language] = make(map[string]bool)
+		}
+		lang.Files++
+		lang.Bytes += file.Bytes
+		if ext != "" {
+			extensions[language][ext] = true
+		}
+
+		top := "."
+		if i := strings.IndexByte(rel, '/'); i >= 0 {
+			top = rel[:i]
+		}
+		dir := byDirectory[top]
+		if dir == nil {
+			dir = &DirectoryInventory{Path: top, Languages: make(map[string]int)}
+			byDirectory[top] = dir
+		}
+		dir.Files++
+		dir.Bytes += file.Bytes
+		dir.Languages[language]++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk repository: %w", err)
+	}
+
+	for language, lang := range byLanguage {
+		for ext := range extensions[language] {
+			lang.Extensions = append(lang.Extensions, ext)
+		}
+		sort.Strings(lang.Extensions)
+		inventory.Languages = append(inventory.Languages, *lang)
+	}
+	sort.Slice(inventory.Languages, func(i, j int) bool {
+		li, lj := inventory.Languages[i], inventory.Languages[j]
+		if li.Files != lj.Files {
+			return li.Files > lj.Files
+		}
+		return li.Language < lj.Language
+	})
+	for _, dir := range byDirectory {
+		inventory.Directories = append(inventory.Directories, *dir)
+	}
+	sort.Slice(inventory.Directories, func(i, j int) bool {
+		return inventory.Directories[i].Path < inventory.Directories[j].Path
+	})
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Bytes != files[j].Bytes {
+			return files[i].Bytes > files[j].Bytes
+		}
+		return files[i].Path < files[j].Path
+	})
+	inventory.Largest = append([]InventoryFile{}, files[:min(max(opts.Largest, 0), len(files))]...)
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].Modified.Equal(files[j].Modified) {
+			return files[i].Modified.After(files[j].Modified)
+		}
+		return files[i].Path < files[j].Path
+	})
+	inventory.Recent = append([]InventoryFile{}, files[:min(max(opts.Recent, 0), len(files))]...)
+	return inventory, nil
+}
+
+// fileLanguage returns the language of a file by its name, and the
+// extension the language was found by
+func fileLanguage(name string) (language, ext string) {
+	if language, ok := languageFiles[name]; ok {
+		return language, ""
+	}
+	ext = strings.ToLower(filepath.Ext(name))
+	if language, ok := languages[ext]; ok {
+		return language, ext
+	}
+	if strings.HasPrefix(name, "Dockerfile.") {
+		return "Dockerfile", ""
+	}
+	return languageOther, ext
+}
+
+// excludedPath reports whether a path relative to the repository contains
+// one of the exclude patterns
+func excludedPath(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(rel, pattern) {
+			return true
+		}
+	}
+	return false
+}
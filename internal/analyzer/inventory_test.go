@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventory(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module example.com/shop\n\ngo 1.21\n",
+		"Makefile":                  "build:\n\tgo build ./...\n",
+		"cart/cart.go":              "package cart\n\n// Cart holds items\ntype Cart struct{}\n",
+		"db/schema.sql":             "CREATE TABLE carts (id INTEGER PRIMARY KEY, owner TEXT NOT NULL);\n",
+		"db/queries.sql":            "SELECT 1;\n",
+		"api/cart.proto":            "syntax = \"proto3\";\n",
+		"deploy/app.yaml":           "name: shop\n",
+		"scripts/release.sh":        "#!/bin/sh\n",
+		"vendor/x/x.go":             "package x\n",
+		".git/HEAD":                 "ref: refs/heads/main\n",
+		"node_modules/left/pad.js":  "module.exports = 1\n",
+		"assets/logo.unknownformat": "?",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// The release script changed last
+	now := time.Now()
+	for name := range files {
+		os.Chtimes(filepath.Join(tmpDir, name), now.Add(-time.Hour), now.Add(-time.Hour))
+	}
+	os.Chtimes(filepath.Join(tmpDir, "scripts/release.sh"), now, now)
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	inventory, err := analyzer.Inventory(context.Background(), InventoryOptions{Largest: 1, Recent: 2})
+	if err != nil {
+		t.Fatalf("Failed to build inventory: %v", err)
+	}
+	if inventory.Files != 9 {
+		t.Errorf("Expected 9 files without .git, vendor and node_modules, got %d", inventory.Files)
+	}
+
+	var languages []string
+	for _, lang := range inventory.Languages {
+		languages = append(languages, fmt.Sprintf("%s=%d", lang.Language, lang.Files))
+	}
+	want := "SQL=2 Go=1 Go Module=1 Makefile=1 Other=1 Protocol Buffers=1 Shell=1 YAML=1"
+	if strings.Join(languages, " ") != want {
+		t.Errorf("Unexpected languages:\n got %s\nwant %s", strings.Join(languages, " "), want)
+	}
+	if sql := inventory.Languages[0]; sql.Bytes != int64(len(files["db/schema.sql"])+len(files["db/queries.sql"])) || len(sql.Extensions) != 1 || sql.Extensions[0] != ".sql" {
+		t.Errorf("Unexpected SQL inventory: %+v", sql)
+	}
+
+	var directories []string
+	for _, dir := range inventory.Directories {
+		directories = append(directories, fmt.Sprintf("%s=%d", dir.Path, dir.Files))
+	}
+	if got := strings.Join(directories, " "); got != ".=2 api=1 assets=1 cart=1 db=2 deploy=1 scripts=1" {
+		t.Errorf("Unexpected directories: %s", got)
+	}
+
+	if len(inventory.Largest) != 1 || inventory.Largest[0].Path != "db/schema.sql" {
+		t.Errorf("Expected schema.sql to be the largest file, got %+v", inventory.Largest)
+	}
+	if len(inventory.Recent) != 2 || inventory.Recent[0].Path != "scripts/release.sh" || inventory.Recent[0].Language != "Shell" {
+		t.Errorf("Expected release.sh to be the most recent file, got %+v", inventory.Recent)
+	}
+}
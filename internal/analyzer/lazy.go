@@ -0,0 +1,459 @@
+package analyzer
+
+import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/types"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/TFMV/scope/internal/tracing"
+)
+
+// maxLoadAttempts bounds how often a query loads missing packages before
+// answering with what is loaded. Concurrent queries may evict each other's
+// packages between loading them and acquiring the read lock.
+const maxLoadAttempts = 3
+
+// lazyState tracks the packages of an analyzer with Config.LazyLoading.
+// Discovery records every package and the names it declares without
+// keeping syntax trees; a.asts, a.pkgs and a.infos only hold the packages
+// loaded so far.
+type lazyState struct {
+	names    map[string][]string // Package-level identifier to the import paths declaring it
+	pkgNames map[string]string   // Import path to package name
+	fallback types.Importer      // Shared by all loads so dependency types stay identical
+	index    *fileIndex          // What discovery learned from each file
+
+	mu        sync.Mutex // Guards the fields below, which queries update under the read lock
+	clock     uint64
+	lastUse   map[string]uint64 // Import path to the clock value of its last use
+	loads     int
+	evictions int
+}
+
+// LazyStats describes the packages of a lazily loading analyzer
+type LazyStats struct {
+	Known  int `json:"known"`
+	Loaded int `json:"loaded"`
+	// FilesParsed and FilesIndexed count the files discovery parsed and
+	// the unchanged files it took from the file index
+	FilesParsed  int    `json:"files_parsed"`
+	FilesIndexed int    `json:"files_indexed"`
+	Loads        int    `json:"loads"`
+	Evictions    int    `json:"evictions"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+}
+
+// newLazyState creates the state of a lazily loading analyzer
+func newLazyState() *lazyState {
+	return &lazyState{
+		names:    make(map[string][]string),
+		pkgNames: make(map[string]string),
+		fallback: importer.Default(),
+		index:    &fileIndex{seen: make(map[string]indexedFile)},
+		lastUse:  make(map[string]uint64),
+	}
+}
+
+// touch marks packages as used now
+func (l *lazyState) touch(importPaths []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock++
+	for _, importPath := range importPaths {
+		l.lastUse[importPath] = l.clock
+	}
+}
+
+// LazyStats reports how many packages are known and loaded, and how often
+// packages were loaded and evicted. It returns nil without lazy loading.
+func (a *Analyzer) LazyStats() *LazyStats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lazyStats()
+}
+
+// lazyStats builds the LazyStats; the caller holds a.mu
+func (a *Analyzer) lazyStats() *LazyStats {
+	if a.lazy == nil {
+		return nil
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	a.lazy.mu.Lock()
+	defer a.lazy.mu.Unlock()
+	return &LazyStats{
+		Known:        len(a.files),
+		Loaded:       len(a.pkgs),
+		FilesParsed:  a.lazy.index.parsed,
+		FilesIndexed: a.lazy.index.reused,
+		Loads:        a.lazy.loads,
+		Evictions:    a.lazy.evictions,
+		HeapAlloc:    mem.HeapAlloc,
+	}
+}
+
+// discoverFile records a Go file and the package-level names it declares,
+// from the file index when the file is unchanged. Its syntax tree is not
+// kept; the file is parsed again when its package is loaded.
+func (a *Analyzer) discoverFile(filename string, info os.FileInfo) error {
+	entry, err := a.lazy.index.entry(filename, info, a.readSource)
+	if err != nil {
+		// A file with syntax errors is still discovered when its package
+		// clause parsed
+		a.recordParseError(filename, entry.Package, err)
+		if entry.Package == "" {
+			return err
+		}
+	}
+	if a.skipGenerated(filename, entry.Generated) {
+		return nil
+	}
+
+	importPath := a.packageOf(filename, entry.Package)
+	a.files[importPath] = append(a.files[importPath], filename)
+	a.lazy.pkgNames[importPath] = entry.Package
+	for _, name := range entry.Names {
+		a.lazy.declare(name, importPath)
+	}
+	return nil
+}
+
+// declare records that a package declares name
+func (l *lazyState) declare(name, importPath string) {
+	paths := l.names[name]
+	if len(paths) > 0 && paths[len(paths)-1] == importPath {
+		return
+	}
+	l.names[name] = append(paths, importPath)
+}
+
+// packagesNamed returns the packages declaring a name, which may be
+// qualified. Names that match no declaration are retried as Type.Method.
+func (a *Analyzer) packagesNamed(name string) []string {
+	qualifier, ident := splitQualifiedName(name)
+	var paths []string
+	for _, importPath := range a.lazy.names[ident] {
+		if matchesQualifier(qualifier, importPath, a.lazy.pkgNames[importPath]) {
+			paths = append(paths, importPath)
+		}
+	}
+	if len(paths) == 0 && qualifier != "" {
+		return a.packagesNamed(qualifier)
+	}
+	return paths
+}
+
+// packagesMatching returns the known packages selected by a qualifier
+func (a *Analyzer) packagesMatching(qualifier string) []string {
+	var paths []string
+	for importPath := range a.files {
+		if matchesQualifier(qualifier, importPath, a.lazy.pkgNames[importPath]) {
+			paths = append(paths, importPath)
+		}
+	}
+	return paths
+}
+
+// packageOfFile returns the known package containing file, relative to the
+// repository or absolute
+func (a *Analyzer) packageOfFile(file string) []string {
+	file = a.absPath(file)
+	for importPath, files := range a.files {
+		for _, filename := range files {
+			if filename == file {
+				return []string{importPath}
+			}
+		}
+	}
+	return nil
+}
+
+// allPackages returns every known package
+func (a *Analyzer) allPackages() []string {
+	paths := make([]string, 0, len(a.files))
+	for importPath := range a.files {
+		paths = append(paths, importPath)
+	}
+	return paths
+}
+
+// rlockAll acquires the read lock with every package loaded
+func (a *Analyzer) rlockAll(ctx context.Context) error {
+	return a.rlockLoaded(ctx, a.allPackages)
+}
+
+// rlockNames acquires the read lock with the packages declaring the given
+// names loaded
+func (a *Analyzer) rlockNames(ctx context.Context, names ...string) error {
+	return a.rlockLoaded(ctx, func() []string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, a.packagesNamed(name)...)
+		}
+		return paths
+	})
+}
+
+// rlockPackages acquires the read lock with the packages selected by a
+// qualifier loaded; an empty qualifier selects every package
+func (a *Analyzer) rlockPackages(ctx context.Context, qualifier string) error {
+	return a.rlockLoaded(ctx, func() []string { return a.packagesMatching(qualifier) })
+}
+
+// rlockFile acquires the read lock with the package containing file loaded
+func (a *Analyzer) rlockFile(ctx context.Context, file string) error {
+	return a.rlockLoaded(ctx, func() []string { return a.packageOfFile(file) })
+}
+
+// rlockLoaded acquires the read lock once the packages returned by need are
+// loaded, or failed to load. need is called with the read lock held.
+// Without lazy loading it is the same as rlock.
+func (a *Analyzer) rlockLoaded(ctx context.Context, need func() []string) error {
+	for attempt := 1; ; attempt++ {
+		if err := a.rlock(ctx); err != nil {
+			return err
+		}
+		if a.lazy == nil || !a.initialized {
+			return nil
+		}
+		importPaths := need()
+		missing := false
+		for _, importPath := range importPaths {
+			if _, ok := a.asts[importPath]; !ok {
+				missing = true
+				break
+			}
+		}
+		if !missing || attempt == maxLoadAttempts {
+			a.lazy.touch(importPaths)
+			return nil
+		}
+		a.mu.RUnlock()
+
+		if err := a.lock(ctx); err != nil {
+			return err
+		}
+		err := a.loadPackages(ctx, importPaths)
+		a.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lock acquires the write lock, giving up when ctx is done first
+func (a *Analyzer) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if a.mu.TryLock() {
+		return nil
+	}
+	acquired := make(chan struct{})
+	go func() {
+		a.mu.Lock()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		// Release the lock once the goroutine gets it
+		go func() {
+			<-acquired
+			a.mu.Unlock()
+		}()
+		return ctx.Err()
+	}
+}
+
+// loadPackages parses and type checks the given packages and the repository
+// packages they import, then evicts other packages while the heap exceeds
+// the memory budget. The caller holds the write lock.
+func (a *Analyzer) loadPackages(ctx context.Context, importPaths []string) error {
+	var parsed []string
+	var parseSpan *tracing.Span // Started by the first package parsed
+	queue := append([]string(nil), importPaths...)
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		importPath := queue[0]
+		queue = queue[1:]
+		if seen[importPath] {
+			continue
+		}
+		seen[importPath] = true
+		if _, ok := a.asts[importPath]; !ok {
+			if parseSpan == nil {
+				_, parseSpan = tracing.Start(ctx, "parse", tracing.KindInternal)
+			}
+			a.parsePackage(importPath)
+			parsed = append(parsed, importPath)
+		}
+		for _, file := range a.asts[importPath] {
+			for _, spec := range file.Imports {
+				if path, err := strconv.Unquote(spec.Path.Value); err == nil && len(a.files[path]) > 0 {
+					queue = append(queue, path)
+				}
+			}
+		}
+	}
+	sort.Strings(parsed)
+	parseSpan.SetAttributes(tracing.Int("scope.packages", len(parsed)))
+	parseSpan.End()
+
+	if len(parsed) > 0 {
+		a.logInfo("Loading packages", "count", len(parsed))
+		if err := a.runPlugins(ctx, "after parse", parsed, afterParse(ctx)); err != nil {
+			return err
+		}
+		importer := &repoImporter{
+			analyzer: a,
+			fallback: a.lazy.fallback,
+			checking: make(map[string]bool),
+		}
+		if a.deps != nil {
+			importer.fallback = a.deps
+		}
+		_, checkSpan := tracing.Start(ctx, "typecheck", tracing.KindInternal, tracing.Int("scope.packages", len(parsed)))
+		for _, importPath := range parsed {
+			if err := ctx.Err(); err != nil {
+				checkSpan.SetError(err)
+				checkSpan.End()
+				return err
+			}
+			if _, err := importer.Import(importPath); err != nil {
+				a.logWarn("Type checking failed", "package", importPath, "error", err)
+			}
+		}
+		checkSpan.End()
+		if err := a.analyzePackages(ctx, parsed); err != nil {
+			return err
+		}
+		a.lazy.mu.Lock()
+		a.lazy.loads += len(parsed)
+		a.lazy.mu.Unlock()
+	}
+
+	keep := make([]string, 0, len(seen))
+	for importPath := range seen {
+		keep = append(keep, importPath)
+	}
+	a.lazy.touch(keep)
+	a.evict(seen)
+	return nil
+}
+
+// parsePackage parses the files of a known package into a.asts
+func (a *Analyzer) parsePackage(importPath string) {
+	files := []*ast.File{}
+	for _, filename := range a.files[importPath] {
+		src, err := a.readSource(filename)
+		if err != nil {
+			a.logWarn("Failed to parse file", "file", filename, "error", err)
+			continue
+		}
+		file, err := parser.ParseFile(a.fset, filename, src, parser.ParseComments)
+		if file, err = a.parsed(filename, file, err); err != nil {
+			a.logWarn("Failed to parse file", "file", filename, "error", err)
+			continue
+		}
+		files = append(files, file)
+	}
+	a.asts[importPath] = files
+}
+
+// evict unloads the least recently used packages, together with the loaded
+// packages importing them, until the heap fits the memory budget. Packages
+// in keep, and packages they import, stay loaded. The caller holds the
+// write lock.
+func (a *Analyzer) evict(keep map[string]bool) {
+	budget := a.config.MemoryBudget
+	if budget == 0 {
+		return
+	}
+	for {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		if mem.HeapAlloc <= budget {
+			return
+		}
+
+		importers := a.importers()
+		victim := ""
+		var victimUse uint64
+		a.lazy.mu.Lock()
+		for importPath := range a.asts {
+			if keep[importPath] {
+				continue
+			}
+			if use := a.lazy.lastUse[importPath]; victim == "" || use < victimUse || (use == victimUse && importPath < victim) {
+				victim, victimUse = importPath, use
+			}
+		}
+		a.lazy.mu.Unlock()
+		if victim == "" {
+			a.logWarn("Heap exceeds the memory budget with only needed packages loaded", "heap_bytes", mem.HeapAlloc, "budget_bytes", budget)
+			return
+		}
+
+		unload := []string{victim}
+		for i := 0; i < len(unload); i++ {
+			for _, importer := range importers[unload[i]] {
+				if !slices.Contains(unload, importer) {
+					unload = append(unload, importer)
+				}
+			}
+		}
+		for _, importPath := range unload {
+			a.unloadPackage(importPath)
+		}
+		a.logInfo("Evicted packages to fit the memory budget", "count", len(unload))
+		a.buildIndex()
+		runtime.GC()
+	}
+}
+
+// importers maps each loaded package to the loaded packages importing it
+func (a *Analyzer) importers() map[string][]string {
+	importers := make(map[string][]string)
+	for importPath, files := range a.asts {
+		for _, file := range files {
+			for _, spec := range file.Imports {
+				if path, err := strconv.Unquote(spec.Path.Value); err == nil && path != importPath {
+					if _, ok := a.asts[path]; ok && !slices.Contains(importers[path], importPath) {
+						importers[path] = append(importers[path], importPath)
+					}
+				}
+			}
+		}
+	}
+	return importers
+}
+
+// unloadPackage drops the syntax trees and type information of a package
+func (a *Analyzer) unloadPackage(importPath string) {
+	for _, file := range a.asts[importPath] {
+		if tokFile := a.fset.File(file.Pos()); tokFile != nil {
+			a.fset.RemoveFile(tokFile)
+		}
+	}
+	delete(a.asts, importPath)
+	delete(a.pkgs, importPath)
+	delete(a.infos, importPath)
+	delete(a.docPkgs, importPath)
+	delete(a.tags, importPath)
+
+	a.lazy.mu.Lock()
+	delete(a.lazy.lastUse, importPath)
+	a.lazy.evictions++
+	a.lazy.mu.Unlock()
+}
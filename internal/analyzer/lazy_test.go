@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLazyLoading(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/mono\n\ngo 1.21\n",
+		"alpha/alpha.go": `package alpha
+
+type Alpha struct{ Name string }
+`,
+		"beta/beta.go": `package beta
+
+type Beta int
+
+func (b Beta) Double() Beta { return b * 2 }
+`,
+		"gamma/gamma.go": `package gamma
+
+import "example.com/mono/alpha"
+
+// Gamma wraps an Alpha
+type Gamma struct{ A alpha.Alpha }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.LazyLoading = true
+	// Any heap exceeds one byte, so every load evicts what the query does
+	// not need
+	config.MemoryBudget = 1
+	analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	loaded := func() []string {
+		analyzer.mu.RLock()
+		defer analyzer.mu.RUnlock()
+		return analyzer.sortedPackagePaths()
+	}
+	if got := loaded(); len(got) != 0 {
+		t.Fatalf("Expected no packages loaded after discovery, got %v", got)
+	}
+	if got := analyzer.Packages(); len(got) != 3 {
+		t.Errorf("Expected 3 known packages, got %v", got)
+	}
+
+	if _, err := analyzer.LookupType(ctx, "Alpha"); err != nil {
+		t.Fatalf("LookupType failed: %v", err)
+	}
+	if got := loaded(); !slices.Equal(got, []string{"example.com/mono/alpha"}) {
+		t.Errorf("Expected only alpha loaded, got %v", got)
+	}
+
+	// Loading beta evicts alpha, which is no longer needed
+	if _, err := analyzer.ErrorPaths(ctx, "Beta.Double", 0); err != nil {
+		t.Fatalf("ErrorPaths failed: %v", err)
+	}
+	if got := loaded(); !slices.Equal(got, []string{"example.com/mono/beta"}) {
+		t.Errorf("Expected only beta loaded, got %v", got)
+	}
+
+	// Imports of the needed package are loaded and kept with it
+	info, err := analyzer.LookupType(ctx, "gamma.Gamma")
+	if err != nil {
+		t.Fatalf("LookupType failed: %v", err)
+	}
+	if len(info.Fields) != 1 || info.Fields[0].Type != "example.com/mono/alpha.Alpha" {
+		t.Errorf("Expected field of type alpha.Alpha, got %+v", info.Fields)
+	}
+	if got := loaded(); !slices.Equal(got, []string{"example.com/mono/alpha", "example.com/mono/gamma"}) {
+		t.Errorf("Expected gamma and alpha loaded, got %v", got)
+	}
+
+	// Evicting alpha, the least recently used package, also evicts gamma,
+	// which imports it
+	if _, err := analyzer.Definition(ctx, "beta/beta.go", 3, 6); err != nil {
+		t.Fatalf("Definition failed: %v", err)
+	}
+	if got := loaded(); !slices.Equal(got, []string{"example.com/mono/beta"}) {
+		t.Errorf("Expected only beta loaded, got %v", got)
+	}
+
+	if _, err := analyzer.LookupType(ctx, "Missing"); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+
+	// Repository-wide queries load every package at once
+	results, err := analyzer.SearchTypes(ctx, "a")
+	if err != nil {
+		t.Fatalf("SearchTypes failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 types, got %d", len(results))
+	}
+
+	stats := analyzer.LazyStats()
+	if stats == nil || stats.Known != 3 || stats.Loaded != 3 || stats.Evictions == 0 {
+		t.Errorf("Unexpected lazy stats: %+v", stats)
+	}
+}
+
+func TestLazyLoadingWithoutBudget(t *testing.T) {
+	tmpDir := writeSyntheticRepo(t, 4, 3)
+	config := DefaultConfig()
+	config.LazyLoading = true
+	analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	packages := analyzer.Packages()
+	if len(packages) != 4 {
+		t.Fatalf("Expected 4 known packages, got %v", packages)
+	}
+	for _, importPath := range packages {
+		if _, err := analyzer.GetPackageInfo(ctx, importPath); err != nil {
+			t.Fatalf("GetPackageInfo(%s) failed: %v", importPath, err)
+		}
+	}
+	stats := analyzer.LazyStats()
+	if stats.Loaded != 4 || stats.Evictions != 0 {
+		t.Errorf("Expected every package kept without a budget, got %+v", stats)
+	}
+
+	eager, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer eager.Close()
+	if eager.LazyStats() != nil {
+		t.Error("Expected no lazy stats without lazy loading")
+	}
+	lazyResult, err := analyzer.AnalyzeRepository(ctx)
+	if err != nil {
+		t.Fatalf("AnalyzeRepository failed: %v", err)
+	}
+	eagerResult, err := eager.AnalyzeRepository(ctx)
+	if err != nil {
+		t.Fatalf("AnalyzeRepository failed: %v", err)
+	}
+	if len(lazyResult.Types) != len(eagerResult.Types) || len(lazyResult.Functions) != len(eagerResult.Functions) {
+		t.Errorf("Expected the same analysis as eager loading, got %d types and %d functions instead of %d and %d",
+			len(lazyResult.Types), len(lazyResult.Functions), len(eagerResult.Types), len(eagerResult.Functions))
+	}
+}
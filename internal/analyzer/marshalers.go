@@ -0,0 +1,230 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// marshalerInterface is an interface CheckMarshalers looks for, matched by
+// method signature so that packages need not import it
+type marshalerInterface struct {
+	name   string
+	method string
+	// params and results are type strings; "any" is the empty interface
+	params, results []string
+	// decodes is set for methods filling in their receiver, which a value
+	// receiver cannot
+	decodes bool
+	// pointerOK is set when implementing the interface with pointers only
+	// is idiomatic, as for errors
+	pointerOK bool
+	// pair is the interface doing the opposite conversion
+	pair string
+}
+
+// marshalerInterfaces are the interfaces of fmt, encoding and database/sql
+// that change how values are printed, encoded, decoded or stored
+var marshalerInterfaces = []marshalerInterface{
+	{name: "fmt.Stringer", method: "String", results: []string{"string"}},
+	{name: "fmt.GoStringer", method: "GoString", results: []string{"string"}},
+	{name: "error", method: "Error", results: []string{"string"}, pointerOK: true},
+	{name: "json.Marshaler", method: "MarshalJSON", results: []string{"[]byte", "error"}, pair: "json.Unmarshaler"},
+	{name: "json.Unmarshaler", method: "UnmarshalJSON", params: []string{"[]byte"}, results: []string{"error"}, decodes: true, pair: "json.Marshaler"},
+	{name: "encoding.TextMarshaler", method: "MarshalText", results: []string{"[]byte", "error"}, pair: "encoding.TextUnmarshaler"},
+	{name: "encoding.TextUnmarshaler", method: "UnmarshalText", params: []string{"[]byte"}, results: []string{"error"}, decodes: true, pair: "encoding.TextMarshaler"},
+	{name: "encoding.BinaryMarshaler", method: "MarshalBinary", results: []string{"[]byte", "error"}, pair: "encoding.BinaryUnmarshaler"},
+	{name: "encoding.BinaryUnmarshaler", method: "UnmarshalBinary", params: []string{"[]byte"}, results: []string{"error"}, decodes: true, pair: "encoding.BinaryMarshaler"},
+	{name: "gob.GobEncoder", method: "GobEncode", results: []string{"[]byte", "error"}, pair: "gob.GobDecoder"},
+	{name: "gob.GobDecoder", method: "GobDecode", params: []string{"[]byte"}, results: []string{"error"}, decodes: true, pair: "gob.GobEncoder"},
+	{name: "sql.Scanner", method: "Scan", params: []string{"any"}, results: []string{"error"}, decodes: true, pair: "driver.Valuer"},
+	{name: "driver.Valuer", method: "Value", results: []string{"database/sql/driver.Value", "error"}, pair: "sql.Scanner"},
+}
+
+// MarshalerInterfaces returns the names of the interfaces CheckMarshalers
+// looks for, in the order of its matrix
+func MarshalerInterfaces() []string {
+	names := make([]string, len(marshalerInterfaces))
+	for i, iface := range marshalerInterfaces {
+		names[i] = iface.name
+	}
+	return names
+}
+
+// MarshalerType is a row of the marshaler matrix
+type MarshalerType struct {
+	// Name is qualified with the package name: pkg.Type
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	Kind       string `json:"kind"`
+	// Implements maps the interfaces the type implements to the receiver
+	// it implements them with: value or pointer
+	Implements map[string]string `json:"implements"`
+	// Issues are implementations that do not behave as they likely should
+	Issues   []string `json:"issues,omitempty"`
+	Position Position `json:"position"`
+}
+
+// MarshalerReport is the matrix of which exported types implement which of
+// the interfaces controlling printing and serialization
+type MarshalerReport struct {
+	// Interfaces are the columns of the matrix
+	Interfaces []string        `json:"interfaces"`
+	Types      []MarshalerType `json:"types"`
+	// Counts are the types implementing each interface
+	Counts map[string]int `json:"counts"`
+	Issues int            `json:"issues"`
+}
+
+// CheckMarshalers reports, for the exported types of the packages a
+// qualifier selects, or of every package, whether they implement
+// fmt.Stringer, json.Marshaler, sql.Scanner and the other interfaces of
+// MarshalerInterfaces, and flags implementations that are likely wrong: a
+// decoding method on a value receiver, an encoding method only pointers
+// have, a method whose signature misses its interface, and an encoder
+// without its decoder or the reverse. A non-empty interfaces restricts the
+// matrix to those interfaces.
+func (a *Analyzer) CheckMarshalers(ctx context.Context, pkg string, interfaces []string) (*MarshalerReport, error) {
+	checked := marshalerInterfaces
+	if len(interfaces) > 0 {
+		checked = nil
+		for _, name := range interfaces {
+			i := slices.IndexFunc(marshalerInterfaces, func(iface marshalerInterface) bool { return iface.name == name })
+			if i < 0 {
+				return nil, fmt.Errorf("unknown interface %q (expected one of %s)", name, strings.Join(MarshalerInterfaces(), ", "))
+			}
+			checked = append(checked, marshalerInterfaces[i])
+		}
+	}
+
+	if err := a.rlockPackages(ctx, pkg); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	report := &MarshalerReport{Types: []MarshalerType{}, Counts: make(map[string]int)}
+	for _, iface := range checked {
+		report.Interfaces = append(report.Interfaces, iface.name)
+		report.Counts[iface.name] = 0
+	}
+	found := false
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := a.pkgs[importPath]
+		if !matchesQualifier(pkg, importPath, p.Name()) {
+			continue
+		}
+		found = true
+		scope := p.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !typeName.Exported() || typeName.IsAlias() || types.IsInterface(typeName.Type()) {
+				continue
+			}
+			row := a.marshalerType(typeName, checked)
+			for iface := range row.Implements {
+				report.Counts[iface]++
+			}
+			report.Issues += len(row.Issues)
+			report.Types = append(report.Types, row)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+	return report, nil
+}
+
+// marshalerType checks a type against the interfaces
+func (a *Analyzer) marshalerType(typeName *types.TypeName, interfaces []marshalerInterface) MarshalerType {
+	t := typeName.Type()
+	row := MarshalerType{
+		Name:       typeName.Pkg().Name() + "." + typeName.Name(),
+		ImportPath: typeName.Pkg().Path(),
+		Kind:       kindOf(t),
+		Implements: make(map[string]string),
+		Position:   a.position(typeName.Pos()),
+	}
+	valueMethods := types.NewMethodSet(t)
+	pointerMethods := types.NewMethodSet(types.NewPointer(t))
+	for _, iface := range interfaces {
+		sel := pointerMethods.Lookup(nil, iface.method)
+		if sel == nil {
+			continue
+		}
+		fn, ok := sel.Obj().(*types.Func)
+		if !ok {
+			continue
+		}
+		if !iface.matches(fn.Type().(*types.Signature)) {
+			row.Issues = append(row.Issues, fmt.Sprintf("%s does not match the signature of %s", iface.method, iface.name))
+			continue
+		}
+		receiver := ReceiverPointer
+		if valueMethods.Lookup(nil, iface.method) != nil {
+			receiver = ReceiverValue
+		}
+		row.Implements[iface.name] = receiver
+
+		switch {
+		case iface.decodes && receiver == ReceiverValue && !isReferenceType(t):
+			row.Issues = append(row.Issues, fmt.Sprintf("%s has a value receiver, so what it decodes is lost", iface.method))
+		case !iface.decodes && !iface.pointerOK && receiver == ReceiverPointer:
+			row.Issues = append(row.Issues, fmt.Sprintf("%s has a pointer receiver, so %s values (not pointers) do not implement %s", iface.method, typeName.Name(), iface.name))
+		}
+	}
+	// Pairs are only flagged when both interfaces are checked
+	for _, iface := range interfaces {
+		_, has := row.Implements[iface.name]
+		pairChecked := slices.ContainsFunc(interfaces, func(other marshalerInterface) bool { return other.name == iface.pair })
+		if _, hasPair := row.Implements[iface.pair]; has && pairChecked && !hasPair {
+			row.Issues = append(row.Issues, fmt.Sprintf("implements %s but not %s", iface.name, iface.pair))
+		}
+	}
+	sort.Strings(row.Issues)
+	return row
+}
+
+// matches reports whether a method signature is that of the interface
+func (iface marshalerInterface) matches(sig *types.Signature) bool {
+	return !sig.Variadic() && tupleMatches(sig.Params(), iface.params) && tupleMatches(sig.Results(), iface.results)
+}
+
+// tupleMatches reports whether the types of a tuple are the given type
+// strings, where "any" matches any empty interface
+func tupleMatches(tuple *types.Tuple, want []string) bool {
+	if tuple.Len() != len(want) {
+		return false
+	}
+	for i, w := range want {
+		t := tuple.At(i).Type()
+		if w == "any" {
+			if iface, ok := t.Underlying().(*types.Interface); !ok || iface.NumMethods() > 0 || types.Unalias(t) != t.Underlying() {
+				return false
+			}
+			continue
+		}
+		if types.TypeString(t, nil) != w {
+			return false
+		}
+	}
+	return true
+}
+
+// isReferenceType reports whether a type's values share their contents, so
+// that a method with a value receiver can still fill them in
+func isReferenceType(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Map, *types.Pointer, *types.Chan:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckMarshalers(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/store\n\ngo 1.21\n",
+		"store.go": `package store
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Money round-trips through JSON
+type Money struct{ Cents int64 }
+
+func (m Money) MarshalJSON() ([]byte, error)   { return nil, nil }
+func (m *Money) UnmarshalJSON(b []byte) error { return nil }
+func (m Money) String() string                { return fmt.Sprint(m.Cents) }
+
+// Status decodes into a copy
+type Status int
+
+func (s Status) UnmarshalText(b []byte) error { return nil }
+func (s *Status) MarshalText() ([]byte, error) { return nil, nil }
+
+// ID is stored in a database
+type ID string
+
+func (id *ID) Scan(src any) error           { return nil }
+func (id ID) Value() (driver.Value, error) { return string(id), nil }
+
+// Broken has a String method of the wrong shape
+type Broken struct{}
+
+func (Broken) String(verbose bool) string { return "" }
+
+// Failure is an error on pointers
+type Failure struct{}
+
+func (*Failure) Error() string { return "" }
+
+type hidden struct{}
+
+func (hidden) String() string { return "" }
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	report, err := analyzer.CheckMarshalers(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("CheckMarshalers failed: %v", err)
+	}
+	if !slices.Equal(report.Interfaces, MarshalerInterfaces()) {
+		t.Errorf("Expected every interface as a column, got %v", report.Interfaces)
+	}
+	rows := make(map[string]MarshalerType)
+	for _, row := range report.Types {
+		rows[row.Name] = row
+	}
+	if len(rows) != 5 {
+		t.Fatalf("Expected the 5 exported types, got %+v", report.Types)
+	}
+
+	money := rows["store.Money"]
+	if money.Implements["json.Marshaler"] != ReceiverValue || money.Implements["json.Unmarshaler"] != ReceiverPointer ||
+		money.Implements["fmt.Stringer"] != ReceiverValue || len(money.Issues) != 0 {
+		t.Errorf("Expected Money to round-trip JSON without issues, got %+v", money)
+	}
+
+	status := rows["store.Status"]
+	if len(status.Issues) != 2 || !strings.Contains(status.Issues[0], "MarshalText has a pointer receiver") ||
+		!strings.Contains(status.Issues[1], "UnmarshalText has a value receiver") {
+		t.Errorf("Expected the receivers of Status to be flagged, got %+v", status.Issues)
+	}
+
+	id := rows["store.ID"]
+	if id.Implements["sql.Scanner"] != ReceiverPointer || id.Implements["driver.Valuer"] != ReceiverValue || len(id.Issues) != 0 {
+		t.Errorf("Expected ID to implement sql.Scanner and driver.Valuer, got %+v", id)
+	}
+
+	broken := rows["store.Broken"]
+	if _, ok := broken.Implements["fmt.Stringer"]; ok || len(broken.Issues) != 1 || !strings.Contains(broken.Issues[0], "does not match") {
+		t.Errorf("Expected a mismatched String method, got %+v", broken)
+	}
+
+	if failure := rows["store.Failure"]; failure.Implements["error"] != ReceiverPointer || len(failure.Issues) != 0 {
+		t.Errorf("Expected a pointer error without issues, got %+v", failure)
+	}
+
+	if report.Counts["fmt.Stringer"] != 1 || report.Issues != 3 {
+		t.Errorf("Unexpected counts %v and %d issues", report.Counts, report.Issues)
+	}
+
+	// Without its pair checked, a missing decoder is not flagged
+	report, err = analyzer.CheckMarshalers(context.Background(), "store", []string{"json.Marshaler"})
+	if err != nil {
+		t.Fatalf("CheckMarshalers failed: %v", err)
+	}
+	if len(report.Interfaces) != 1 || report.Counts["json.Marshaler"] != 1 || report.Issues != 0 {
+		t.Errorf("Expected json.Marshaler only, got %+v", report)
+	}
+
+	if _, err := analyzer.CheckMarshalers(context.Background(), "", []string{"yaml.Marshaler"}); err == nil {
+		t.Error("Expected an unknown interface to fail")
+	}
+	if _, err := analyzer.CheckMarshalers(context.Background(), "missing", nil); err == nil {
+		t.Error("Expected an unknown package to fail")
+	}
+}
@@ -0,0 +1,287 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMentionLimit is the number of mentions FindMentions returns
+// unless told otherwise
+const DefaultMentionLimit = 200
+
+// Kinds of mentions
+const (
+	MentionComment = "comment"
+	MentionTag     = "struct_tag"
+	MentionString  = "string"
+)
+
+// Mention is an occurrence of a name outside the identifiers a rename
+// changes, in a comment, struct tag or string literal, which needs review
+// by hand
+type Mention struct {
+	Position   Position `json:"position"`
+	ImportPath string   `json:"import_path"`
+	Kind       string   `json:"kind"`
+	// Match is the text found: the name itself or its lowerCamel or
+	// snake_case form, as wire names often spell it
+	Match string `json:"match"`
+	// Replacement is the new name in the form of the match
+	Replacement string `json:"replacement,omitempty"`
+	// Text is the line holding the mention
+	Text string `json:"text"`
+}
+
+// MentionResult is the result of FindMentions
+type MentionResult struct {
+	Mentions []Mention `json:"mentions"`
+	// Truncated is set when more mentions were found than the limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// FindMentions finds a name, and its lowerCamel and snake_case forms, as
+// whole words in the comments, struct tags and string literals of the Go
+// files of the analysis. A qualified name (pkg.Name or Type.Name) is
+// looked up by its last part. With newName set, each mention suggests the
+// replacement of the same form. Files are read from disk, so no package
+// needs to be loaded.
+func (a *Analyzer) FindMentions(ctx context.Context, name, newName string, limit int) (*MentionResult, error) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", name)
+	}
+	if limit <= 0 {
+		limit = DefaultMentionLimit
+	}
+	forms := nameForms(name, newName)
+
+	type mentionFile struct{ filename, importPath string }
+	if err := a.rlock(ctx); err != nil {
+		return nil, err
+	}
+	if !a.initialized {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	var files []mentionFile
+	generated := make(map[string]bool)
+	for importPath, filenames := range a.files {
+		for _, filename := range filenames {
+			files = append(files, mentionFile{filename, importPath})
+			generated[filename] = a.generated[filename]
+		}
+	}
+	a.mu.RUnlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].filename < files[j].filename })
+
+	result := &MentionResult{Mentions: []Mention{}}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		src, err := a.readSource(f.filename)
+		if err != nil {
+			a.logWarn("Skipping file in mention search", "file", f.filename, "error", err)
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, f.filename, src, parser.ParseComments)
+		if file == nil {
+			a.logWarn("Skipping file in mention search", "file", f.filename, "error", err)
+			continue
+		}
+		lines := splitSourceLines(src)
+		tokFile := fset.File(file.Pos())
+
+		scan := func(kind string, start, end token.Pos) bool {
+			from, to := tokFile.Offset(start), tokFile.Offset(end)
+			for _, m := range findForms(string(src[from:to]), forms) {
+				if len(result.Mentions) == limit {
+					result.Truncated = true
+					return false
+				}
+				pos := tokFile.Position(start + token.Pos(m.offset))
+				text := strings.TrimRight(lines[pos.Line-1], "\r\n")
+				if len(text) > maxMatchText {
+					text = text[:maxMatchText] + "..."
+				}
+				result.Mentions = append(result.Mentions, Mention{
+					Position: Position{
+						Filename:  f.filename,
+						Line:      pos.Line,
+						Column:    pos.Column,
+						Generated: generated[f.filename],
+					},
+					ImportPath:  f.importPath,
+					Kind:        kind,
+					Match:       m.form.name,
+					Replacement: m.form.replacement,
+					Text:        text,
+				})
+			}
+			return true
+		}
+
+		// Comments, tags and strings are visited in source order
+		type literal struct {
+			kind       string
+			start, end token.Pos
+		}
+		var literals []literal
+		for _, group := range file.Comments {
+			literals = append(literals, literal{MentionComment, group.Pos(), group.End()})
+		}
+		tags := make(map[*ast.BasicLit]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ImportSpec:
+				// Import paths name packages, not symbols
+				return false
+			case *ast.Field:
+				if n.Tag != nil {
+					tags[n.Tag] = true
+				}
+			case *ast.BasicLit:
+				if n.Kind != token.STRING {
+					break
+				}
+				kind := MentionString
+				if tags[n] {
+					kind = MentionTag
+				}
+				literals = append(literals, literal{kind, n.Pos(), n.End()})
+			}
+			return true
+		})
+		sort.Slice(literals, func(i, j int) bool { return literals[i].start < literals[j].start })
+		for _, lit := range literals {
+			if !scan(lit.kind, lit.start, lit.end) {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// nameForm is a spelling of a name and of its replacement
+type nameForm struct {
+	name, replacement string
+}
+
+// nameForms returns the distinct spellings of a name looked for in text:
+// itself, lowerCamel and snake_case
+func nameForms(name, newName string) []nameForm {
+	forms := []nameForm{{name, newName}}
+	seen := map[string]bool{name: true}
+	for _, convert := range []func(string) string{lowerCamel, snakeCase} {
+		form := nameForm{convert(name), ""}
+		if newName != "" {
+			form.replacement = convert(newName)
+		}
+		if !seen[form.name] {
+			seen[form.name] = true
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// lowerCamel lower-cases the first word of a name, keeping initialisms
+// whole: HTTPServer is httpServer and ID is id
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	switch {
+	case upper == 0:
+		return name
+	case upper == 1 || upper == len(runes):
+		// A single capital, or the whole name is an initialism
+	case !unicode.IsLetter(runes[upper]):
+		// An initialism followed by a digit or underscore
+	default:
+		// The last capital starts the next word
+		upper--
+	}
+	return strings.ToLower(string(runes[:upper])) + string(runes[upper:])
+}
+
+// snakeCase splits a name into lower-cased words joined by underscores:
+// HTTPServer is http_server and userID is user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && runes[i-1] != '_' {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || unicode.IsUpper(runes[i-1]) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// formMatch is a whole-word occurrence of a name form in a text
+type formMatch struct {
+	offset int
+	form   nameForm
+}
+
+// findForms finds the whole-word occurrences of the forms in text, in
+// order. Where forms overlap the longest wins.
+func findForms(text string, forms []nameForm) []formMatch {
+	var matches []formMatch
+	for i := 0; i < len(text); {
+		var best *nameForm
+		for j := range forms {
+			form := &forms[j]
+			if strings.HasPrefix(text[i:], form.name) && isWordBoundary(text, i, i+len(form.name)) &&
+				(best == nil || len(form.name) > len(best.name)) {
+				best = form
+			}
+		}
+		if best != nil {
+			matches = append(matches, formMatch{i, *best})
+			i += len(best.name)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return matches
+}
+
+// isWordBoundary reports whether text[start:end] is not part of a longer
+// identifier-like word
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune reports whether a rune can be part of an identifier
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindMentions(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module example.com/shop\n\ngo 1.21\n",
+		"UserName/names.go": "package names\n",
+		"user/user.go": `package user
+
+import _ "example.com/shop/UserName"
+
+// UserName is the login of a user; see also UserNames
+type User struct {
+	UserName string ` + "`json:\"user_name\" db:\"userName\"`" + `
+}
+
+func Lookup(fields map[string]string) string {
+	return fields["UserName"] + "OtherUserName"
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		result, err := analyzer.FindMentions(ctx, "User.UserName", "Login", 0)
+		if err != nil {
+			t.Fatalf("FindMentions failed: %v", err)
+		}
+		expected := []struct {
+			kind, match, replacement string
+			line, column             int
+		}{
+			{MentionComment, "UserName", "Login", 5, 4},
+			{MentionTag, "user_name", "login", 7, 25},
+			{MentionTag, "userName", "login", 7, 40},
+			{MentionString, "UserName", "Login", 11, 17},
+		}
+		if len(result.Mentions) != len(expected) || result.Truncated {
+			t.Fatalf("Expected %d mentions, got %+v", len(expected), result)
+		}
+		for i, e := range expected {
+			m := result.Mentions[i]
+			if m.Kind != e.kind || m.Match != e.match || m.Replacement != e.replacement || m.Position.Line != e.line || m.Position.Column != e.column {
+				t.Errorf("Expected mention %d to be %+v, got %+v", i, e, m)
+			}
+		}
+		if result.Mentions[0].Text != "// UserName is the login of a user; see also UserNames" || result.Mentions[0].ImportPath != "example.com/shop/user" {
+			t.Errorf("Unexpected first mention: %+v", result.Mentions[0])
+		}
+
+		result, err = analyzer.FindMentions(ctx, "UserName", "", 2)
+		if err != nil {
+			t.Fatalf("FindMentions failed: %v", err)
+		}
+		if len(result.Mentions) != 2 || !result.Truncated || result.Mentions[0].Replacement != "" {
+			t.Errorf("Expected 2 mentions without replacements, got %+v", result)
+		}
+
+		if _, err := analyzer.FindMentions(ctx, "not valid", "", 0); err == nil {
+			t.Error("Expected an error for an invalid name")
+		}
+	}
+}
+
+func TestNameForms(t *testing.T) {
+	for name, expected := range map[string][2]string{
+		"UserName":   {"userName", "user_name"},
+		"HTTPServer": {"httpServer", "http_server"},
+		"ID":         {"id", "id"},
+		"userID":     {"userID", "user_id"},
+		"parse2XML":  {"parse2XML", "parse2_xml"},
+	} {
+		if camel, snake := lowerCamel(name), snakeCase(name); camel != expected[0] || snake != expected[1] {
+			t.Errorf("Expected %v for %s, got %s and %s", expected, name, camel, snake)
+		}
+	}
+	if forms := nameForms("ID", "Key"); len(forms) != 2 || forms[1] != (nameForm{"id", "key"}) {
+		t.Errorf("Expected duplicate forms to be merged, got %v", forms)
+	}
+}
@@ -0,0 +1,434 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Receiver kinds of a method stub
+const (
+	ReceiverPointer = "pointer"
+	ReceiverValue   = "value"
+)
+
+// MethodStubOptions configure GenerateMethodStub
+type MethodStubOptions struct {
+	// Name is the name of the method
+	Name string
+	// Signature holds the parameters and results of the method, such as
+	// "(ctx context.Context, id string) (*User, error)". A leading func
+	// keyword or method name is allowed.
+	Signature string
+	// Doc is the text of the method's doc comment; empty writes none
+	Doc string
+	// Receiver is ReceiverPointer or ReceiverValue; empty follows the
+	// type's other methods
+	Receiver string
+}
+
+// MethodStub is a method declaration generated for a named type, whose
+// body panics until it is implemented
+type MethodStub struct {
+	// Type is the receiver type, qualified with its package name
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	// Receiver is the receiver as declared, such as "s *Server"
+	Receiver string `json:"receiver"`
+	Pointer  bool   `json:"pointer"`
+	// Convention explains how the receiver was chosen
+	Convention string `json:"convention"`
+	// File is the file the method belongs in: the one holding the type's
+	// methods, or else its declaration
+	File string `json:"file"`
+	// Imports are the import paths the signature refers to that File does
+	// not import yet, keyed by the package names it uses
+	Imports map[string]string `json:"imports,omitempty"`
+	// Code is the formatted method declaration
+	Code string `json:"code"`
+}
+
+// existingMethod is a method declared on a type in the repository
+type existingMethod struct {
+	receiver string // Receiver name; empty when unnamed
+	pointer  bool
+	file     string
+}
+
+// GenerateMethodStub generates a method for a named type declared in the
+// repository, following the conventions of the type's other methods: the
+// receiver is named as most of them name it and is a pointer when any of
+// them has a pointer receiver. Without other methods, the receiver is the
+// lower-cased first letter of the type, and a pointer for structs.
+func (a *Analyzer) GenerateMethodStub(ctx context.Context, typeName string, opts MethodStubOptions) (*MethodStub, error) {
+	if opts.Receiver != "" && opts.Receiver != ReceiverPointer && opts.Receiver != ReceiverValue {
+		return nil, fmt.Errorf("unknown receiver %q (expected %s or %s)", opts.Receiver, ReceiverPointer, ReceiverValue)
+	}
+	name, funcType, err := parseStubSignature(opts.Name, opts.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.rlockLoaded(ctx, func() []string { return a.packagesNamed(typeName) }); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	importPath, obj, err := a.resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok || typeObj.IsAlias() {
+		return nil, fmt.Errorf("%s is not a defined type", typeName)
+	}
+	named, ok := typeObj.Type().(*types.Named)
+	if !ok || len(a.asts[importPath]) == 0 {
+		return nil, fmt.Errorf("%s is not declared in the repository", typeName)
+	}
+	if types.IsInterface(named) {
+		return nil, fmt.Errorf("%s is an interface; methods are declared on concrete types", typeName)
+	}
+	if _, isPointer := named.Underlying().(*types.Pointer); isPointer {
+		return nil, fmt.Errorf("%s is a pointer type, which cannot have methods", typeName)
+	}
+	for i := range named.NumMethods() {
+		if named.Method(i).Name() == name {
+			return nil, fmt.Errorf("%s already has a method %s at %s", typeName, name, a.fset.Position(named.Method(i).Pos()))
+		}
+	}
+	if st, ok := named.Underlying().(*types.Struct); ok {
+		for field := range st.Fields() {
+			if field.Name() == name {
+				return nil, fmt.Errorf("%s has a field %s, so it cannot have a method of that name", typeName, name)
+			}
+		}
+	}
+
+	methods := a.existingMethods(importPath, typeObj.Name())
+	stub := &MethodStub{
+		Type:       typeObj.Pkg().Name() + "." + typeObj.Name(),
+		Name:       name,
+		ImportPath: importPath,
+		File:       a.stubFile(typeObj, methods),
+	}
+
+	recvName, conventions := receiverName(typeObj.Name(), methods)
+	var pointerConvention string
+	stub.Pointer, pointerConvention = receiverPointer(named, methods, opts.Receiver)
+	stub.Convention = strings.Join(append(conventions, pointerConvention), "; ")
+	for _, field := range append(fieldList(funcType.Params), fieldList(funcType.Results)...) {
+		for _, ident := range field.Names {
+			if ident.Name == recvName {
+				return nil, fmt.Errorf("parameter %s has the name of the receiver; rename it", ident.Name)
+			}
+		}
+	}
+
+	recvType := typeObj.Name()
+	if tparams := named.TypeParams(); tparams.Len() > 0 {
+		names := make([]string, tparams.Len())
+		for i := range tparams.Len() {
+			names[i] = tparams.At(i).Obj().Name()
+		}
+		recvType += "[" + strings.Join(names, ", ") + "]"
+	}
+	if stub.Pointer {
+		recvType = "*" + recvType
+	}
+	stub.Receiver = recvName + " " + recvType
+
+	if stub.Imports, err = a.stubImports(importPath, stub.File, funcType); err != nil {
+		return nil, err
+	}
+
+	var code strings.Builder
+	if doc := strings.TrimSpace(opts.Doc); doc != "" {
+		for _, line := range strings.Split(doc, "\n") {
+			code.WriteString(strings.TrimRight("// "+strings.TrimSpace(line), " ") + "\n")
+		}
+	}
+	sig := strings.TrimPrefix(types.ExprString(funcType), "func")
+	fmt.Fprintf(&code, "func (%s) %s%s {\n\tpanic(\"not implemented\")\n}\n", stub.Receiver, name, sig)
+	formatted, err := format.Source([]byte("package p\n\n" + code.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated method is not valid Go: %w", err)
+	}
+	stub.Code = strings.TrimPrefix(string(formatted), "package p\n\n")
+	return stub, nil
+}
+
+// parseStubSignature parses the signature of a method stub, which may
+// start with the func keyword and the method name, and returns the
+// method's name. A name in the signature must agree with name.
+func parseStubSignature(name, signature string) (string, *ast.FuncType, error) {
+	sig := strings.TrimSpace(signature)
+	sig = strings.TrimSpace(strings.TrimPrefix(sig, "func"))
+	if i := strings.IndexByte(sig, '('); i > 0 {
+		inSig := strings.TrimSpace(sig[:i])
+		if !token.IsIdentifier(inSig) {
+			return "", nil, fmt.Errorf("invalid signature %q", signature)
+		}
+		if name != "" && name != inSig {
+			return "", nil, fmt.Errorf("signature names method %s, not %s", inSig, name)
+		}
+		name, sig = inSig, sig[i:]
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if !token.IsIdentifier(name) || name == "_" {
+		return "", nil, fmt.Errorf("%q is not a valid method name", name)
+	}
+	if !strings.HasPrefix(sig, "(") {
+		return "", nil, fmt.Errorf("invalid signature %q: expected parameters in parentheses", signature)
+	}
+	expr, err := parser.ParseExpr("func" + sig)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid signature %q: %w", signature, err)
+	}
+	funcType, ok := expr.(*ast.FuncType)
+	if !ok {
+		return "", nil, fmt.Errorf("invalid signature %q", signature)
+	}
+	if funcType.TypeParams != nil {
+		return "", nil, fmt.Errorf("methods cannot have type parameters")
+	}
+	return name, funcType, nil
+}
+
+// fieldList returns the fields of a possibly nil field list
+func fieldList(list *ast.FieldList) []*ast.Field {
+	if list == nil {
+		return nil
+	}
+	return list.List
+}
+
+// existingMethods returns the methods declared on a type of a package, in
+// file and source order
+func (a *Analyzer) existingMethods(importPath, typeName string) []existingMethod {
+	var methods []existingMethod
+	for _, file := range a.asts[importPath] {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+			recv := fn.Recv.List[0]
+			expr := ast.Unparen(recv.Type)
+			star, pointer := expr.(*ast.StarExpr)
+			if pointer {
+				expr = ast.Unparen(star.X)
+			}
+			switch index := expr.(type) {
+			case *ast.IndexExpr:
+				expr = index.X
+			case *ast.IndexListExpr:
+				expr = index.X
+			}
+			if ident, ok := expr.(*ast.Ident); !ok || ident.Name != typeName {
+				continue
+			}
+			method := existingMethod{pointer: pointer, file: a.fset.Position(fn.Pos()).Filename}
+			if len(recv.Names) > 0 && recv.Names[0].Name != "_" {
+				method.receiver = recv.Names[0].Name
+			}
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// receiverName returns the receiver name most of a type's methods use,
+// preferring the earliest on ties, or else the lower-cased first letter of
+// the type, with the reason for the choice
+func receiverName(typeName string, methods []existingMethod) (string, []string) {
+	counts := make(map[string]int)
+	best := ""
+	for _, method := range methods {
+		if method.receiver == "" {
+			continue
+		}
+		counts[method.receiver]++
+		if counts[method.receiver] > counts[best] {
+			best = method.receiver
+		}
+	}
+	if best != "" {
+		return best, []string{fmt.Sprintf("receiver named %s like %d of %d methods", best, counts[best], len(methods))}
+	}
+	r, _ := utf8.DecodeRuneInString(typeName)
+	name := string(unicode.ToLower(r))
+	if name == "_" || token.IsKeyword(name) || !token.IsIdentifier(name) {
+		name = "x"
+	}
+	if len(methods) == 0 {
+		return name, []string{fmt.Sprintf("receiver named %s after the type, which has no methods", name)}
+	}
+	return name, []string{fmt.Sprintf("receiver named %s after the type, since its methods leave the receiver unnamed", name)}
+}
+
+// receiverPointer decides whether the receiver is a pointer: as requested,
+// when any method has a pointer receiver, or for a struct without methods
+func receiverPointer(named *types.Named, methods []existingMethod, requested string) (bool, string) {
+	switch requested {
+	case ReceiverPointer:
+		return true, "pointer receiver as requested"
+	case ReceiverValue:
+		return false, "value receiver as requested"
+	}
+	pointers := 0
+	for _, method := range methods {
+		if method.pointer {
+			pointers++
+		}
+	}
+	switch {
+	case pointers > 0:
+		return true, fmt.Sprintf("pointer receiver like %d of %d methods", pointers, len(methods))
+	case len(methods) > 0:
+		return false, fmt.Sprintf("value receiver like all %d methods", len(methods))
+	}
+	if _, ok := named.Underlying().(*types.Struct); ok {
+		return true, "pointer receiver for a struct without methods"
+	}
+	return false, "value receiver for a non-struct type without methods"
+}
+
+// stubFile returns the file a new method of a type belongs in: the file
+// declaring the type when it holds any of its methods, or else the file
+// holding most of them. Generated files are avoided.
+func (a *Analyzer) stubFile(typeObj *types.TypeName, methods []existingMethod) string {
+	declFile := a.fset.Position(typeObj.Pos()).Filename
+	counts := make(map[string]int)
+	for _, method := range methods {
+		if !a.generated[method.file] {
+			counts[method.file]++
+		}
+	}
+	if counts[declFile] > 0 || (len(counts) == 0 && !a.generated[declFile]) {
+		return declFile
+	}
+	files := make([]string, 0, len(counts))
+	for file := range counts {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if counts[files[i]] != counts[files[j]] {
+			return counts[files[i]] > counts[files[j]]
+		}
+		return files[i] < files[j]
+	})
+	if len(files) == 0 {
+		return declFile
+	}
+	return files[0]
+}
+
+// stubImports returns the imports the package qualifiers of a signature
+// need that filename does not have, resolving each name from the imports
+// of the package, then of the rest of the repository, then the standard
+// library
+func (a *Analyzer) stubImports(importPath, filename string, funcType *ast.FuncType) (map[string]string, error) {
+	needed := make(map[string]bool)
+	ast.Inspect(funcType, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				needed[ident.Name] = true
+			}
+		}
+		return true
+	})
+	if len(needed) == 0 {
+		return nil, nil
+	}
+	if pkgName := a.packageName(importPath); needed[pkgName] {
+		return nil, fmt.Errorf("the signature qualifies names with %s, its own package; leave them unqualified", pkgName)
+	}
+
+	imports := make(map[string]string)
+	found := make(map[string]string)
+	collect := func(pkgPath string, file *ast.File) {
+		for _, spec := range file.Imports {
+			name, specPath := importName(a.infos[pkgPath], spec)
+			if needed[name] && found[name] == "" {
+				found[name] = specPath
+			}
+		}
+	}
+	for _, file := range a.asts[importPath] {
+		if a.fset.Position(file.Pos()).Filename == filename {
+			collect(importPath, file)
+		}
+	}
+	inFile := make(map[string]bool)
+	for name := range found {
+		inFile[name] = true
+	}
+	for _, file := range a.asts[importPath] {
+		collect(importPath, file)
+	}
+	for _, pkgPath := range a.sortedImportPaths() {
+		for _, file := range a.asts[pkgPath] {
+			collect(pkgPath, file)
+		}
+	}
+
+	names := make([]string, 0, len(needed))
+	for name := range needed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if inFile[name] {
+			continue
+		}
+		specPath := found[name]
+		if specPath == "" {
+			if pkg, err := build.Default.Import(name, "", build.FindOnly); err == nil && pkg.Goroot {
+				specPath = name
+			}
+		}
+		if specPath == "" {
+			return nil, fmt.Errorf("package %s in the signature is not imported anywhere in the repository nor in the standard library; add its import first", name)
+		}
+		imports[name] = specPath
+	}
+	if len(imports) == 0 {
+		return nil, nil
+	}
+	return imports, nil
+}
+
+// importName returns the name an import declares in its file and the
+// imported path. Without an explicit name it is the imported package's
+// name when type checked, or else the last element of its path.
+func importName(info *types.Info, spec *ast.ImportSpec) (string, string) {
+	importPath, _ := strconv.Unquote(spec.Path.Value)
+	if spec.Name != nil {
+		return spec.Name.Name, importPath
+	}
+	if info != nil {
+		if pkgName, ok := info.Implicits[spec].(*types.PkgName); ok {
+			return pkgName.Imported().Name(), importPath
+		}
+	}
+	return path.Base(importPath), importPath
+}
@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMethodStub(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import "context"
+
+// Store keeps items
+type Store struct {
+	items map[string]string
+	Size  int
+}
+
+func (st *Store) Get(ctx context.Context, name string) string { return st.items[name] }
+
+type Color int
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+type Reader interface{ Read() }
+`,
+		"store/methods.go": `package store
+
+import "time"
+
+func (st Store) Len() int { return len(st.items) }
+
+func (s *Store) Touch(at time.Time) {}
+
+type Cache struct{}
+
+func (c Cache) A() {}
+func (c Cache) B() {}
+`,
+		"store/cache.go": "package store\n\nfunc (cc Cache) C() {}\n",
+		"other/other.go": "package other\n\nimport str \"strings\"\n\nvar _ = str.ToUpper\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		stub, err := analyzer.GenerateMethodStub(ctx, "Store", MethodStubOptions{
+			Name:      "Put",
+			Signature: "(ctx context.Context, name string, ttl time.Duration, r io.Reader) error",
+			Doc:       "Put stores an item",
+		})
+		if err != nil {
+			t.Fatalf("GenerateMethodStub failed: %v", err)
+		}
+		if stub.Type != "store.Store" || stub.Receiver != "st *Store" || !stub.Pointer || stub.ImportPath != "example.com/shop/store" {
+			t.Errorf("Unexpected stub: %+v", stub)
+		}
+		if filepath.Base(stub.File) != "store.go" {
+			t.Errorf("Expected the method in the type's file, got %s", stub.File)
+		}
+		if len(stub.Imports) != 2 || stub.Imports["time"] != "time" || stub.Imports["io"] != "io" {
+			t.Errorf("Expected time and io to be imported, got %v", stub.Imports)
+		}
+		want := "// Put stores an item\nfunc (st *Store) Put(ctx context.Context, name string, ttl time.Duration, r io.Reader) error {\n\tpanic(\"not implemented\")\n}\n"
+		if stub.Code != want {
+			t.Errorf("Unexpected code:\n%s", stub.Code)
+		}
+		if !strings.Contains(stub.Convention, "2 of 3 methods") {
+			t.Errorf("Unexpected convention: %s", stub.Convention)
+		}
+
+		stub, err = analyzer.GenerateMethodStub(ctx, "store.Cache", MethodStubOptions{Signature: "func D(b bytes.Buffer)"})
+		if err != nil {
+			t.Fatalf("GenerateMethodStub failed for Cache: %v", err)
+		}
+		if stub.Name != "D" || stub.Receiver != "c Cache" || filepath.Base(stub.File) != "methods.go" {
+			t.Errorf("Unexpected Cache stub: %+v", stub)
+		}
+		if stub.Imports["bytes"] != "bytes" {
+			t.Errorf("Expected bytes from the standard library, got %v", stub.Imports)
+		}
+		if !lazy {
+			// Packages loaded on demand only see the imports of loaded packages
+			stub, err = analyzer.GenerateMethodStub(ctx, "Cache", MethodStubOptions{Name: "E", Signature: "(b *str.Builder)"})
+			if err != nil || stub.Imports["str"] != "strings" {
+				t.Errorf("Expected str from the repository's imports, got %+v: %v", stub, err)
+			}
+		}
+
+		stub, err = analyzer.GenerateMethodStub(ctx, "Color", MethodStubOptions{Name: "String", Signature: "() string"})
+		if err != nil {
+			t.Fatalf("GenerateMethodStub failed for Color: %v", err)
+		}
+		if stub.Receiver != "c Color" || stub.Pointer || stub.Imports != nil {
+			t.Errorf("Unexpected Color stub: %+v", stub)
+		}
+
+		stub, err = analyzer.GenerateMethodStub(ctx, "Pair", MethodStubOptions{Name: "Swap", Signature: "()", Receiver: ReceiverValue})
+		if err != nil {
+			t.Fatalf("GenerateMethodStub failed for Pair: %v", err)
+		}
+		if stub.Receiver != "p Pair[K, V]" {
+			t.Errorf("Unexpected Pair receiver: %s", stub.Receiver)
+		}
+
+		for _, tc := range []struct {
+			typeName string
+			opts     MethodStubOptions
+		}{
+			{"Store", MethodStubOptions{Name: "Get", Signature: "()"}},
+			{"Store", MethodStubOptions{Name: "Size", Signature: "()"}},
+			{"Store", MethodStubOptions{Name: "Put", Signature: "(st string)"}},
+			{"Store", MethodStubOptions{Name: "Put", Signature: "(x nosuchpkg.Thing)"}},
+			{"Store", MethodStubOptions{Name: "Put", Signature: "func Other()"}},
+			{"Store", MethodStubOptions{Name: "Put", Signature: "(", Receiver: ""}},
+			{"Store", MethodStubOptions{Name: "Put", Signature: "()", Receiver: "both"}},
+			{"Reader", MethodStubOptions{Name: "Close", Signature: "()"}},
+			{"Missing", MethodStubOptions{Name: "Close", Signature: "()"}},
+		} {
+			if _, err := analyzer.GenerateMethodStub(ctx, tc.typeName, tc.opts); err == nil {
+				t.Errorf("Expected an error for %s.%s%s", tc.typeName, tc.opts.Name, tc.opts.Signature)
+			}
+		}
+	}
+}
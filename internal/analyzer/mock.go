@@ -0,0 +1,452 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Styles of generated mocks
+const (
+	// MockSimple mocks call a function field per method
+	MockSimple = "simple"
+	// MockGomock mocks record expectations with a gomock controller, like
+	// the mocks of mockgen
+	MockGomock = "gomock"
+)
+
+// Import paths of the gomock package: the maintained fork, and the
+// original used when the module still requires it
+const (
+	gomockPath       = "go.uber.org/mock/gomock"
+	legacyGomockPath = "github.com/golang/mock/gomock"
+)
+
+// MockOptions configure GenerateMock
+type MockOptions struct {
+	// Style is MockSimple (the default) or MockGomock
+	Style string
+	// Name is the name of the mock type; empty uses Mock and the name of
+	// the interface
+	Name string
+	// Package is the package the mock is for (import path or package
+	// name); empty uses the interface's package
+	Package string
+}
+
+// Mock is a generated implementation of an interface for tests
+type Mock struct {
+	Name string `json:"name"`
+	// Interface is the mocked interface, qualified with its package name
+	Interface string `json:"interface"`
+	Style     string `json:"style"`
+	// ImportPath and PackageName are the package the mock is written for
+	ImportPath  string `json:"import_path"`
+	PackageName string `json:"package_name"`
+	// Methods are the mocked methods, with their types qualified and their
+	// parameters named as in the generated code
+	Methods []MethodInfo `json:"methods"`
+	// Imports are the import paths the mock refers to, keyed by the
+	// package names it uses
+	Imports map[string]string `json:"imports,omitempty"`
+	// Code holds the declarations of the mock and Source a complete,
+	// formatted file with them
+	Code   string `json:"code"`
+	Source string `json:"source"`
+}
+
+// GenerateMock generates a mock implementation of a named interface in the
+// given style. Types are qualified relative to the target package, which
+// may differ from the interface's.
+func (a *Analyzer) GenerateMock(ctx context.Context, interfaceName string, opts MockOptions) (*Mock, error) {
+	if opts.Style == "" {
+		opts.Style = MockSimple
+	}
+	if opts.Style != MockSimple && opts.Style != MockGomock {
+		return nil, fmt.Errorf("unknown mock style %q (expected %s or %s)", opts.Style, MockSimple, MockGomock)
+	}
+	if err := a.rlockLoaded(ctx, func() []string {
+		paths := a.packagesNamed(interfaceName)
+		if opts.Package != "" {
+			paths = append(paths, a.packagesMatching(opts.Package)...)
+		}
+		return paths
+	}); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(interfaceName)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", interfaceName)
+	}
+	named, ok := typeObj.Type().(*types.Named)
+	if !ok || !types.IsInterface(named) {
+		return nil, fmt.Errorf("%s is not a named interface", interfaceName)
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is generic; mocking generic interfaces is not supported", interfaceName)
+	}
+	iface := named.Underlying().(*types.Interface)
+	if !iface.IsMethodSet() {
+		return nil, fmt.Errorf("%s is a type constraint and cannot be implemented", interfaceName)
+	}
+	if iface.NumMethods() == 0 {
+		return nil, fmt.Errorf("%s has no methods", interfaceName)
+	}
+
+	target := typeObj.Pkg().Path()
+	if opts.Package != "" {
+		if target, err = a.uniquePackage(opts.Package); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.files[target]) == 0 {
+		return nil, fmt.Errorf("package %s has no source files in the repository", target)
+	}
+
+	mock := &Mock{
+		Name:        opts.Name,
+		Interface:   typeObj.Pkg().Name() + "." + typeObj.Name(),
+		Style:       opts.Style,
+		ImportPath:  target,
+		PackageName: a.packageName(target),
+		Imports:     make(map[string]string),
+	}
+	if mock.Name == "" {
+		mock.Name = mockName(typeObj.Name())
+	}
+	if !token.IsIdentifier(mock.Name) {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", mock.Name)
+	}
+	if pkg := a.pkgs[target]; pkg != nil && pkg.Scope().Lookup(mock.Name) != nil {
+		return nil, fmt.Errorf("package %s already declares %s; choose another name", mock.PackageName, mock.Name)
+	}
+
+	qualifier := importQualifier(target, mock.Imports)
+	ifaceName := types.TypeString(named, qualifier)
+	for i := range iface.NumMethods() {
+		fn := iface.Method(i)
+		if !fn.Exported() && fn.Pkg().Path() != target {
+			return nil, fmt.Errorf("method %s is unexported, so only a mock in package %s can implement it", fn.Name(), fn.Pkg().Name())
+		}
+		sig := fn.Type().(*types.Signature)
+		mock.Methods = append(mock.Methods, MethodInfo{
+			Name:       fn.Name(),
+			Signature:  types.TypeString(sig, qualifier),
+			Parameters: qualifiedParams(sig.Params(), sig.Variadic(), qualifier),
+			Results:    qualifiedParams(sig.Results(), false, qualifier),
+			Exported:   fn.Exported(),
+			IsPointer:  true,
+		})
+	}
+	if opts.Style == MockGomock {
+		gomock := gomockPath
+		if a.requiresModule(filepath.Dir(a.files[target][0]), strings.TrimSuffix(legacyGomockPath, "/gomock")) {
+			gomock = legacyGomockPath
+		}
+		mock.Imports["gomock"] = gomock
+		mock.Imports["reflect"] = "reflect"
+	}
+	// Parameters are named once the imports are known, so that no name
+	// shadows a package the mock refers to
+	for i := range mock.Methods {
+		nameParams(mock.Methods[i].Parameters, mock.Imports)
+	}
+
+	var code strings.Builder
+	switch opts.Style {
+	case MockSimple:
+		err = writeSimpleMock(&code, mock, ifaceName)
+	case MockGomock:
+		writeGomock(&code, mock, ifaceName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&code, "\nvar _ %s = (*%s)(nil)\n", ifaceName, mock.Name)
+	mock.Code = code.String()
+
+	source, err := mockSource(mock)
+	if err != nil {
+		return nil, err
+	}
+	mock.Source = source
+	return mock, nil
+}
+
+// importQualifier qualifies types outside the target package with their
+// package names and records the imports that needs
+func importQualifier(target string, imports map[string]string) types.Qualifier {
+	return func(pkg *types.Package) string {
+		if pkg.Path() == target {
+			return ""
+		}
+		imports[pkg.Name()] = pkg.Path()
+		return pkg.Name()
+	}
+}
+
+// qualifiedParams describes parameters or results with qualified types,
+// writing the type of a variadic parameter as ...T
+func qualifiedParams(tuple *types.Tuple, variadic bool, qualifier types.Qualifier) []ParamInfo {
+	params := make([]ParamInfo, tuple.Len())
+	for i := range tuple.Len() {
+		params[i] = ParamInfo{Name: tuple.At(i).Name(), Type: types.TypeString(tuple.At(i).Type(), qualifier)}
+	}
+	if variadic {
+		last := tuple.At(tuple.Len() - 1).Type().(*types.Slice)
+		params[len(params)-1].Type = "..." + types.TypeString(last.Elem(), qualifier)
+	}
+	return params
+}
+
+// nameParams gives every parameter a distinct name that does not collide
+// with the names the generated methods use themselves
+func nameParams(params []ParamInfo, imports map[string]string) {
+	used := make(map[string]bool)
+	for i := range params {
+		name := params[i].Name
+		if name == "" || name == "_" || used[name] || imports[name] != "" || mockReserved(name) {
+			name = "arg" + strconv.Itoa(i)
+		}
+		used[name] = true
+		params[i].Name = name
+	}
+}
+
+// mockReserved reports whether generated methods use a name themselves
+func mockReserved(name string) bool {
+	switch name {
+	case "m", "mr", "ret", "varargs", "a":
+		return true
+	}
+	if rest, ok := strings.CutPrefix(name, "ret"); ok {
+		if _, err := strconv.Atoi(rest); err == nil {
+			return true
+		}
+	}
+	_, err := strconv.Atoi(strings.TrimPrefix(name, "arg"))
+	return strings.HasPrefix(name, "arg") && err == nil
+}
+
+// mockName names the mock of an interface, keeping unexported interfaces'
+// mocks unexported
+func mockName(ifaceName string) string {
+	if token.IsExported(ifaceName) {
+		return "Mock" + ifaceName
+	}
+	return "mock" + strings.ToUpper(ifaceName[:1]) + ifaceName[1:]
+}
+
+// variadic reports whether the last parameter of a method is variadic
+func variadic(method MethodInfo) bool {
+	params := method.Parameters
+	return len(params) > 0 && strings.HasPrefix(params[len(params)-1].Type, "...")
+}
+
+// paramList formats the parameters of a method as a declaration list
+func paramList(method MethodInfo, typed func(ParamInfo) string) string {
+	parts := make([]string, len(method.Parameters))
+	for i, param := range method.Parameters {
+		parts[i] = typed(param)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// callArgs formats the parameters as the arguments of a call passing them on
+func callArgs(method MethodInfo) string {
+	parts := make([]string, len(method.Parameters))
+	for i, param := range method.Parameters {
+		parts[i] = param.Name
+	}
+	if variadic(method) {
+		parts[len(parts)-1] += "..."
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resultList formats the result types of a method as they follow its
+// parameters
+func resultList(method MethodInfo) string {
+	switch len(method.Results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + method.Results[0].Type
+	}
+	parts := make([]string, len(method.Results))
+	for i, result := range method.Results {
+		parts[i] = result.Type
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// writeSimpleMock writes a mock with a function field per method
+func writeSimpleMock(code *strings.Builder, mock *Mock, ifaceName string) error {
+	methods := make(map[string]bool)
+	for _, method := range mock.Methods {
+		methods[method.Name] = true
+	}
+	typed := func(param ParamInfo) string { return param.Name + " " + param.Type }
+
+	fmt.Fprintf(code, "// %s is a mock of %s. Each method calls the function in the field\n// named after it.\n", mock.Name, ifaceName)
+	fmt.Fprintf(code, "type %s struct {\n", mock.Name)
+	for _, method := range mock.Methods {
+		field := method.Name + "Func"
+		if methods[field] {
+			return fmt.Errorf("the field %s of method %s would collide with the method %s; use the %s style", field, method.Name, field, MockGomock)
+		}
+		fmt.Fprintf(code, "\t%s func(%s)%s\n", field, paramList(method, typed), resultList(method))
+	}
+	code.WriteString("}\n")
+
+	for _, method := range mock.Methods {
+		field := method.Name + "Func"
+		fmt.Fprintf(code, "\n// %s calls %s\n", method.Name, field)
+		fmt.Fprintf(code, "func (m *%s) %s(%s)%s {\n", mock.Name, method.Name, paramList(method, typed), resultList(method))
+		fmt.Fprintf(code, "\tif m.%s == nil {\n\t\tpanic(%q)\n\t}\n", field, mock.Name+"."+method.Name+" called without "+field)
+		if len(method.Results) > 0 {
+			code.WriteString("\treturn ")
+		} else {
+			code.WriteString("\t")
+		}
+		fmt.Fprintf(code, "m.%s(%s)\n}\n", field, callArgs(method))
+	}
+	return nil
+}
+
+// writeGomock writes a mock and its recorder the way mockgen does
+func writeGomock(code *strings.Builder, mock *Mock, ifaceName string) {
+	recorder := mock.Name + "MockRecorder"
+	fmt.Fprintf(code, "// %s is a mock of %s\n", mock.Name, ifaceName)
+	fmt.Fprintf(code, "type %s struct {\n\tctrl     *gomock.Controller\n\trecorder *%s\n}\n\n", mock.Name, recorder)
+	fmt.Fprintf(code, "// %s is the mock recorder for %s\n", recorder, mock.Name)
+	fmt.Fprintf(code, "type %s struct {\n\tmock *%s\n}\n\n", recorder, mock.Name)
+	fmt.Fprintf(code, "// New%s creates a new mock instance\n", strings.ToUpper(mock.Name[:1])+mock.Name[1:])
+	fmt.Fprintf(code, "func New%s(ctrl *gomock.Controller) *%s {\n", strings.ToUpper(mock.Name[:1])+mock.Name[1:], mock.Name)
+	fmt.Fprintf(code, "\tmock := &%s{ctrl: ctrl}\n\tmock.recorder = &%s{mock}\n\treturn mock\n}\n\n", mock.Name, recorder)
+	code.WriteString("// EXPECT returns an object that allows the caller to indicate expected use\n")
+	fmt.Fprintf(code, "func (m *%s) EXPECT() *%s {\n\treturn m.recorder\n}\n", mock.Name, recorder)
+
+	typed := func(param ParamInfo) string { return param.Name + " " + param.Type }
+	untyped := func(param ParamInfo) string {
+		if strings.HasPrefix(param.Type, "...") {
+			return param.Name + " ...any"
+		}
+		return param.Name + " any"
+	}
+	for _, method := range mock.Methods {
+		fixed := method.Parameters
+		if variadic(method) {
+			fixed = fixed[:len(fixed)-1]
+		}
+		names := make([]string, len(fixed))
+		for i, param := range fixed {
+			names[i] = param.Name
+		}
+
+		fmt.Fprintf(code, "\n// %s mocks base method\n", method.Name)
+		fmt.Fprintf(code, "func (m *%s) %s(%s)%s {\n\tm.ctrl.T.Helper()\n", mock.Name, method.Name, paramList(method, typed), resultList(method))
+		args := strings.Join(append([]string{"m", strconv.Quote(method.Name)}, names...), ", ")
+		if variadic(method) {
+			last := method.Parameters[len(method.Parameters)-1].Name
+			fmt.Fprintf(code, "\tvarargs := []any{%s}\n", strings.Join(names, ", "))
+			fmt.Fprintf(code, "\tfor _, a := range %s {\n\t\tvarargs = append(varargs, a)\n\t}\n", last)
+			args = fmt.Sprintf("m, %q, varargs...", method.Name)
+		}
+		if len(method.Results) == 0 {
+			fmt.Fprintf(code, "\tm.ctrl.Call(%s)\n}\n", args)
+		} else {
+			fmt.Fprintf(code, "\tret := m.ctrl.Call(%s)\n", args)
+			rets := make([]string, len(method.Results))
+			for i, result := range method.Results {
+				rets[i] = "ret" + strconv.Itoa(i)
+				fmt.Fprintf(code, "\t%s, _ := ret[%d].(%s)\n", rets[i], i, result.Type)
+			}
+			fmt.Fprintf(code, "\treturn %s\n}\n", strings.Join(rets, ", "))
+		}
+
+		fmt.Fprintf(code, "\n// %s indicates an expected call of %s\n", method.Name, method.Name)
+		fmt.Fprintf(code, "func (mr *%s) %s(%s) *gomock.Call {\n\tmr.mock.ctrl.T.Helper()\n", recorder, method.Name, paramList(method, untyped))
+		record := fmt.Sprintf("mr.mock, %q, reflect.TypeOf((*%s)(nil).%s)", method.Name, mock.Name, method.Name)
+		if variadic(method) {
+			last := method.Parameters[len(method.Parameters)-1].Name
+			fmt.Fprintf(code, "\tvarargs := append([]any{%s}, %s...)\n", strings.Join(names, ", "), last)
+			fmt.Fprintf(code, "\treturn mr.mock.ctrl.RecordCallWithMethodType(%s, varargs...)\n}\n", record)
+		} else {
+			fmt.Fprintf(code, "\treturn mr.mock.ctrl.RecordCallWithMethodType(%s)\n}\n", strings.Join(append([]string{record}, names...), ", "))
+		}
+	}
+}
+
+// mockSource wraps the code of a mock into a formatted file of its package
+func mockSource(mock *Mock) (string, error) {
+	names := make([]string, 0, len(mock.Imports))
+	for name := range mock.Imports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return mock.Imports[names[i]] < mock.Imports[names[j]] })
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "package %s\n\n", mock.PackageName)
+	if len(names) > 0 {
+		src.WriteString("import (\n")
+		for _, name := range names {
+			importPath := mock.Imports[name]
+			if name == path.Base(importPath) {
+				fmt.Fprintf(&src, "\t%q\n", importPath)
+			} else {
+				fmt.Fprintf(&src, "\t%s %q\n", name, importPath)
+			}
+		}
+		src.WriteString(")\n\n")
+	}
+	src.WriteString(mock.Code)
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generated mock is not valid Go: %w", err)
+	}
+	return string(out), nil
+}
+
+// requiresModule reports whether the go.mod of the module containing dir
+// requires a module
+func (a *Analyzer) requiresModule(dir, module string) bool {
+	for current := dir; ; {
+		if data, err := os.ReadFile(filepath.Join(current, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "require" {
+					fields = fields[1:]
+				}
+				if len(fields) >= 2 && fields[0] == module {
+					return true
+				}
+			}
+			return false
+		}
+		parent := filepath.Dir(current)
+		if parent == current || !strings.HasPrefix(parent, a.repoPath) {
+			return false
+		}
+		current = parent
+	}
+}
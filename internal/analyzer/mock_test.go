@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMock(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import "context"
+
+type Item struct{ Name string }
+
+// Store keeps items
+type Store interface {
+	Get(ctx context.Context, name string) (*Item, error)
+	Put(Item) error
+	Logf(format string, args ...any)
+	Close()
+}
+
+type Constraint interface{ ~int }
+
+type Empty interface{}
+`,
+		"service/service.go": "package service\n\ntype Service struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		mock, err := analyzer.GenerateMock(ctx, "Store", MockOptions{})
+		if err != nil {
+			t.Fatalf("GenerateMock failed: %v", err)
+		}
+		if mock.Name != "MockStore" || mock.Style != MockSimple || mock.ImportPath != "example.com/shop/store" || len(mock.Methods) != 4 {
+			t.Errorf("Unexpected mock: %+v", mock)
+		}
+		put := mock.Methods[3]
+		if put.Name != "Put" || len(put.Parameters) != 1 || put.Parameters[0] != (ParamInfo{Name: "arg0", Type: "Item"}) || put.Results[0].Type != "error" {
+			t.Errorf("Unexpected Put method: %+v", put)
+		}
+		for _, snippet := range []string{
+			"\tGetFunc func(ctx context.Context, name string) (*Item, error)\n",
+			"func (m *MockStore) Logf(format string, args ...any) {\n",
+			"\tm.LogfFunc(format, args...)\n",
+			"\treturn m.PutFunc(arg0)\n",
+			`panic("MockStore.Close called without CloseFunc")`,
+			"var _ Store = (*MockStore)(nil)\n",
+		} {
+			if !strings.Contains(mock.Code, snippet) {
+				t.Errorf("Expected the mock to contain %q, got:\n%s", snippet, mock.Code)
+			}
+		}
+		if !strings.HasPrefix(mock.Source, "package store\n\nimport (\n\t\"context\"\n)\n") {
+			t.Errorf("Unexpected source header:\n%s", mock.Source)
+		}
+
+		// In another package the mock qualifies the interface's types
+		external, err := analyzer.GenerateMock(ctx, "store.Store", MockOptions{Package: "service", Name: "fakeStore"})
+		if err != nil {
+			t.Fatalf("GenerateMock failed: %v", err)
+		}
+		if external.Imports["store"] != "example.com/shop/store" || !strings.Contains(external.Code, "(*store.Item, error)") ||
+			!strings.Contains(external.Code, "var _ store.Store = (*fakeStore)(nil)") {
+			t.Errorf("Expected a mock qualified with store, got:\n%s", external.Code)
+		}
+
+		gomock, err := analyzer.GenerateMock(ctx, "Store", MockOptions{Style: MockGomock})
+		if err != nil {
+			t.Fatalf("GenerateMock failed: %v", err)
+		}
+		if gomock.Imports["gomock"] != "go.uber.org/mock/gomock" || gomock.Imports["reflect"] != "reflect" {
+			t.Errorf("Expected imports of gomock and reflect, got %v", gomock.Imports)
+		}
+		for _, snippet := range []string{
+			"func NewMockStore(ctrl *gomock.Controller) *MockStore {\n",
+			"\tret := m.ctrl.Call(m, \"Get\", ctx, name)\n\tret0, _ := ret[0].(*Item)\n\tret1, _ := ret[1].(error)\n\treturn ret0, ret1\n",
+			"func (mr *MockStoreMockRecorder) Get(ctx any, name any) *gomock.Call {\n",
+			"\tvarargs := []any{format}\n\tfor _, a := range args {\n\t\tvarargs = append(varargs, a)\n\t}\n\tm.ctrl.Call(m, \"Logf\", varargs...)\n",
+			"func (mr *MockStoreMockRecorder) Logf(format any, args ...any) *gomock.Call {\n",
+			"reflect.TypeOf((*MockStore)(nil).Close))\n",
+		} {
+			if !strings.Contains(gomock.Code, snippet) {
+				t.Errorf("Expected the gomock mock to contain %q, got:\n%s", snippet, gomock.Code)
+			}
+		}
+
+		for _, tc := range []struct {
+			name    string
+			opts    MockOptions
+			errText string
+		}{
+			{"Item", MockOptions{}, "not a named interface"},
+			{"Constraint", MockOptions{}, "type constraint"},
+			{"Empty", MockOptions{}, "has no methods"},
+			{"Store", MockOptions{Style: "mockery"}, "unknown mock style"},
+			{"Store", MockOptions{Name: "Item"}, "already declares Item"},
+		} {
+			if _, err := analyzer.GenerateMock(ctx, tc.name, tc.opts); err == nil || !strings.Contains(err.Error(), tc.errText) {
+				t.Errorf("Expected an error containing %q for %s %+v, got %v", tc.errText, tc.name, tc.opts, err)
+			}
+		}
+
+		// Written last, so the mocks do not change the earlier analysis
+		if !lazy {
+			continue
+		}
+		if _, err := exec.LookPath("go"); err != nil {
+			t.Skip("go command not available")
+		}
+		// The simple mocks compile in test files of their packages
+		for file, source := range map[string]string{"store/mock_test.go": mock.Source, "service/mock_test.go": external.Source} {
+			if err := os.WriteFile(filepath.Join(tmpDir, file), []byte(source), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file, err)
+			}
+		}
+		cmd := exec.Command("go", "vet", "./...")
+		cmd.Dir = tmpDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("Expected the mocks to compile: %v\n%s", err, output)
+		}
+	}
+}
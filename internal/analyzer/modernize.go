@@ -0,0 +1,422 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Modernization rules
+const (
+	// RuleIoutil replaces the functions of io/ioutil with those of io and os
+	RuleIoutil = "ioutil"
+	// RuleStringsTitle flags strings.Title, which mishandles Unicode
+	RuleStringsTitle = "strings_title"
+	// RuleRandSeed removes calls of math/rand.Seed, which Go 1.20 made
+	// unnecessary
+	RuleRandSeed = "rand_seed"
+	// RuleAny replaces interface{} with any
+	RuleAny = "any"
+	// RuleExpSlices replaces golang.org/x/exp/slices with slices
+	RuleExpSlices = "exp_slices"
+)
+
+// ModernizeRules lists the modernization rules
+var ModernizeRules = []string{RuleIoutil, RuleStringsTitle, RuleRandSeed, RuleAny, RuleExpSlices}
+
+// Modernization is a use of an outdated API or pattern with the rewrite
+// replacing it
+type Modernization struct {
+	Rule       string `json:"rule"`
+	Message    string `json:"message"`
+	ImportPath string `json:"import_path"`
+	// Function is the function or method containing the site, as pkg.Func
+	// or pkg.Type.Method; empty at package level
+	Function string   `json:"function,omitempty"`
+	Position Position `json:"position"`
+	// Old is the source text at Position that New replaces; an empty New
+	// removes it
+	Old string `json:"old"`
+	New string `json:"new"`
+	// Imports are the import paths New needs, keyed by the package names
+	// it uses
+	Imports map[string]string `json:"imports,omitempty"`
+	// Unused are the import paths Old uses, which the rewrite may leave
+	// unused
+	Unused []string `json:"unused,omitempty"`
+	// Manual is set when New is only a suggestion that needs review, such
+	// as a replacement with a different signature
+	Manual bool `json:"manual,omitempty"`
+}
+
+// ModernizeReport lists the modernizations of the analyzed packages
+type ModernizeReport struct {
+	Modernizations []Modernization `json:"modernizations"`
+	// Rules counts the modernizations by rule
+	Rules map[string]int `json:"rules"`
+	// Skipped explains rules left out of packages whose Go version is too
+	// old for the replacement
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// ioutilReplacements are the replacements of the functions and variables
+// of io/ioutil, by name. Those marked manual differ in their results.
+var ioutilReplacements = map[string]struct {
+	pkg, name string
+	manual    bool
+}{
+	"ReadAll":   {"io", "ReadAll", false},
+	"ReadFile":  {"os", "ReadFile", false},
+	"WriteFile": {"os", "WriteFile", false},
+	"ReadDir":   {"os", "ReadDir", true},
+	"TempFile":  {"os", "CreateTemp", false},
+	"TempDir":   {"os", "MkdirTemp", false},
+	"NopCloser": {"io", "NopCloser", false},
+	"Discard":   {"io", "Discard", false},
+}
+
+// ruleVersions are the Go versions the replacements of rules need
+var ruleVersions = map[string]string{
+	RuleIoutil:    "go1.16",
+	RuleRandSeed:  "go1.20",
+	RuleAny:       "go1.18",
+	RuleExpSlices: "go1.21",
+}
+
+// Modernize finds uses of outdated APIs and patterns with modern
+// replacements in the packages matching pkg (all when empty), limited to
+// the given rules (all when empty). A rule is skipped for packages whose
+// module's Go version predates its replacement. Generated files are left
+// out.
+func (a *Analyzer) Modernize(ctx context.Context, pkg string, rules []string) (*ModernizeReport, error) {
+	enabled := make(map[string]bool)
+	for _, rule := range rules {
+		known := false
+		for _, r := range ModernizeRules {
+			known = known || r == rule
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown rule %q (expected one of %s)", rule, strings.Join(ModernizeRules, ", "))
+		}
+		enabled[rule] = true
+	}
+	if len(enabled) == 0 {
+		for _, rule := range ModernizeRules {
+			enabled[rule] = true
+		}
+	}
+
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	report := &ModernizeReport{Modernizations: []Modernization{}, Rules: make(map[string]int)}
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if pkg != "" && !matchesQualifier(pkg, importPath, a.pkgs[importPath].Name()) {
+			continue
+		}
+		info := a.infos[importPath]
+		files := a.asts[importPath]
+		if info == nil || len(files) == 0 {
+			continue
+		}
+
+		goVersion := languageVersion(a.config.GoVersion)
+		if goVersion == "" {
+			goVersion = languageVersion(a.goVersion(filepath.Dir(a.fset.Position(files[0].Package).Filename)))
+		}
+		pkgRules := make(map[string]bool)
+		for _, rule := range ModernizeRules {
+			if !enabled[rule] {
+				continue
+			}
+			if need := ruleVersions[rule]; need != "" && version.IsValid(goVersion) && version.Compare(goVersion, need) < 0 {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s in %s: needs %s, the package is %s", rule, importPath, need, goVersion))
+				continue
+			}
+			pkgRules[rule] = true
+		}
+
+		for _, file := range files {
+			filename := a.fset.Position(file.Package).Filename
+			if a.generated[filename] {
+				continue
+			}
+			src, err := a.readSource(filename)
+			if err != nil {
+				continue
+			}
+			m := &modernizer{a: a, importPath: importPath, info: info, file: file, src: src, rules: pkgRules}
+			report.Modernizations = append(report.Modernizations, m.run()...)
+		}
+	}
+
+	sort.SliceStable(report.Modernizations, func(i, j int) bool {
+		pi, pj := report.Modernizations[i].Position, report.Modernizations[j].Position
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Column < pj.Column
+	})
+	for _, m := range report.Modernizations {
+		report.Rules[m.Rule]++
+	}
+	return report, nil
+}
+
+// modernizer finds the modernizations of one file
+type modernizer struct {
+	a          *Analyzer
+	importPath string
+	info       *types.Info
+	file       *ast.File
+	src        []byte
+	rules      map[string]bool
+	found      []Modernization
+}
+
+// run walks the file and returns its modernizations
+func (m *modernizer) run() []Modernization {
+	if m.rules[RuleExpSlices] {
+		m.expSlices()
+	}
+	for _, decl := range m.file.Decls {
+		function := ""
+		if fd, ok := decl.(*ast.FuncDecl); ok {
+			if fn, ok := m.info.Defs[fd.Name].(*types.Func); ok {
+				function = funcName(fn)
+			}
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ExprStmt:
+				if m.rules[RuleRandSeed] {
+					m.randSeed(n, function)
+				}
+			case *ast.SelectorExpr:
+				m.selector(n, function)
+			case *ast.InterfaceType:
+				if m.rules[RuleAny] {
+					m.emptyInterface(n, function)
+				}
+			}
+			return true
+		})
+	}
+	return m.found
+}
+
+// add records a modernization of the source text from node's start to end
+func (m *modernizer) add(node ast.Node, mod Modernization) {
+	start, end := m.offset(node.Pos()), m.offset(node.End())
+	if start < 0 || end > len(m.src) || start > end {
+		return
+	}
+	mod.ImportPath = m.importPath
+	mod.Position = m.a.position(node.Pos())
+	mod.Old = string(m.src[start:end])
+	m.found = append(m.found, mod)
+}
+
+// offset returns the offset of pos in the file
+func (m *modernizer) offset(pos token.Pos) int {
+	return m.a.fset.Position(pos).Offset
+}
+
+// imported returns the imported package an identifier refers to, or nil
+func (m *modernizer) imported(ident *ast.Ident) *types.Package {
+	if pkgName, ok := m.info.Uses[ident].(*types.PkgName); ok {
+		return pkgName.Imported()
+	}
+	return nil
+}
+
+// qualifier returns the name under which the file can refer to the
+// package at path at pos: the name of its import, or else its package name
+// when nothing in scope hides it. It reports false when the name is hidden.
+func (m *modernizer) qualifier(path string, pos ast.Node) (string, bool) {
+	name := path[strings.LastIndexByte(path, '/')+1:]
+	for _, spec := range m.file.Imports {
+		if specPath, _ := strconv.Unquote(spec.Path.Value); specPath == path && spec.Name != nil && spec.Name.Name != "_" && spec.Name.Name != "." {
+			name = spec.Name.Name
+		}
+	}
+	scope := m.a.pkgs[m.importPath].Scope().Innermost(pos.Pos())
+	if scope == nil {
+		return name, true
+	}
+	_, obj := scope.LookupParent(name, pos.Pos())
+	if obj == nil {
+		// The file scope holds the imports, which Innermost may not reach
+		// from package-level positions
+		if fileScope := m.info.Scopes[m.file]; fileScope != nil {
+			obj = fileScope.Lookup(name)
+		}
+	}
+	if obj == nil {
+		return name, true
+	}
+	pkgName, ok := obj.(*types.PkgName)
+	return name, ok && pkgName.Imported().Path() == path
+}
+
+// selector handles qualified uses of io/ioutil and strings.Title
+func (m *modernizer) selector(sel *ast.SelectorExpr, function string) {
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	pkg := m.imported(x)
+	if pkg == nil {
+		return
+	}
+	switch {
+	case pkg.Path() == "io/ioutil" && m.rules[RuleIoutil]:
+		repl, ok := ioutilReplacements[sel.Sel.Name]
+		if !ok {
+			return
+		}
+		name, visible := m.qualifier(repl.pkg, sel)
+		mod := Modernization{
+			Rule:     RuleIoutil,
+			Message:  fmt.Sprintf("ioutil.%s is deprecated; use %s.%s", sel.Sel.Name, repl.pkg, repl.name),
+			Function: function,
+			New:      name + "." + repl.name,
+			Imports:  map[string]string{name: repl.pkg},
+			Unused:   []string{"io/ioutil"},
+			Manual:   repl.manual || !visible,
+		}
+		if repl.manual {
+			mod.Message += fmt.Sprintf(", which returns %s rather than the results of ioutil.%s", "[]os.DirEntry", sel.Sel.Name)
+		}
+		if !visible {
+			mod.Message += fmt.Sprintf("; %s is hidden here", name)
+		}
+		m.add(sel, mod)
+	case pkg.Path() == "strings" && sel.Sel.Name == "Title" && m.rules[RuleStringsTitle]:
+		m.add(sel, Modernization{
+			Rule:     RuleStringsTitle,
+			Message:  "strings.Title is deprecated: it does not handle Unicode punctuation; use cases.Title from golang.org/x/text/cases",
+			Function: function,
+			New:      "cases.Title(language.Und).String",
+			Imports:  map[string]string{"cases": "golang.org/x/text/cases", "language": "golang.org/x/text/language"},
+			Unused:   []string{"strings"},
+			Manual:   true,
+		})
+	}
+}
+
+// randSeed removes statements calling math/rand.Seed
+func (m *modernizer) randSeed(stmt *ast.ExprStmt, function string) {
+	call, ok := stmt.X.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Seed" {
+		return
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	if pkg := m.imported(x); pkg == nil || pkg.Path() != "math/rand" {
+		return
+	}
+	m.add(stmt, Modernization{
+		Rule:     RuleRandSeed,
+		Message:  "rand.Seed is deprecated: since Go 1.20 the global generator is seeded randomly; use rand.New(rand.NewSource(seed)) for a reproducible sequence",
+		Function: function,
+		Unused:   m.packagesIn(stmt),
+	})
+}
+
+// packagesIn returns the import paths of the packages a node refers to
+func (m *modernizer) packagesIn(node ast.Node) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if pkg := m.imported(ident); pkg != nil && !seen[pkg.Path()] {
+				seen[pkg.Path()] = true
+				paths = append(paths, pkg.Path())
+			}
+		}
+		return true
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+// emptyInterface replaces interface{} with any
+func (m *modernizer) emptyInterface(it *ast.InterfaceType, function string) {
+	if it.Methods == nil || len(it.Methods.List) > 0 || it.Incomplete {
+		return
+	}
+	// Comments inside the braces would be lost
+	for _, group := range m.file.Comments {
+		if group.Pos() > it.Pos() && group.End() < it.End() {
+			return
+		}
+	}
+	visible := true
+	if scope := m.a.pkgs[m.importPath].Scope().Innermost(it.Pos()); scope != nil {
+		if _, obj := scope.LookupParent("any", it.Pos()); obj != nil && obj != types.Universe.Lookup("any") {
+			visible = false
+		}
+	}
+	mod := Modernization{
+		Rule:     RuleAny,
+		Message:  "interface{} can be written as any",
+		Function: function,
+		New:      "any",
+		Manual:   !visible,
+	}
+	if !visible {
+		mod.Message += "; any is redeclared here"
+	}
+	m.add(it, mod)
+}
+
+// expSlices replaces imports of golang.org/x/exp/slices with slices
+func (m *modernizer) expSlices() {
+	std := false
+	for _, spec := range m.file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "slices" {
+			std = true
+		}
+	}
+	for _, spec := range m.file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path != "golang.org/x/exp/slices" {
+			continue
+		}
+		mod := Modernization{
+			Rule:    RuleExpSlices,
+			Message: "golang.org/x/exp/slices is part of the standard library as slices since Go 1.21; comparison functions of SortFunc and similar functions return an int there",
+			New:     strconv.Quote("slices"),
+			Manual:  std,
+		}
+		if std {
+			mod.Message += "; the file imports slices already"
+		}
+		m.add(spec.Path, mod)
+	}
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModernize(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+func Load(name string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = ioutil.ReadDir(name)
+	return map[string]interface{}{strings.Title(name): data}, nil
+}
+
+func Write(os string, v interface{ String() string }) error {
+	return ioutil.WriteFile(os, []byte(v.String()), 0644)
+}
+`,
+		"legacy/go.mod": "module example.com/legacy\n\ngo 1.17\n",
+		"legacy/legacy.go": `package legacy
+
+func Any(v interface{}) interface {
+	// Keep
+} {
+	return v
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	report, err := analyzer.Modernize(ctx, "", nil)
+	if err != nil {
+		t.Fatalf("Modernize failed: %v", err)
+	}
+	var got []string
+	for _, m := range report.Modernizations {
+		line := m.Rule + " " + m.Old + " -> " + m.New
+		if m.Manual {
+			line += " (manual)"
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"rand_seed rand.Seed(time.Now().UnixNano()) -> ",
+		"any interface{} -> any",
+		"ioutil ioutil.ReadFile -> os.ReadFile",
+		"ioutil ioutil.ReadDir -> os.ReadDir (manual)",
+		"any interface{} -> any",
+		"strings_title strings.Title -> cases.Title(language.Und).String (manual)",
+		"ioutil ioutil.WriteFile -> os.WriteFile (manual)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected modernizations:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	seed := report.Modernizations[0]
+	if seed.Function != "store.init" || seed.Position.Line != 11 || strings.Join(seed.Unused, " ") != "math/rand time" {
+		t.Errorf("Unexpected rand.Seed modernization: %+v", seed)
+	}
+	if report.Rules[RuleIoutil] != 3 || report.Rules[RuleAny] != 2 {
+		t.Errorf("Unexpected rule counts: %v", report.Rules)
+	}
+	if len(report.Skipped) != 3 || !strings.Contains(report.Skipped[1], "any in example.com/legacy: needs go1.18") {
+		t.Errorf("Expected the legacy module to skip newer rules, got %v", report.Skipped)
+	}
+
+	report, err = analyzer.Modernize(ctx, "store", []string{RuleAny})
+	if err != nil {
+		t.Fatalf("Modernize failed: %v", err)
+	}
+	if len(report.Modernizations) != 2 || len(report.Skipped) != 0 {
+		t.Errorf("Expected only the any rule in store, got %+v", report)
+	}
+	if _, err := analyzer.Modernize(ctx, "", []string{"generics"}); err == nil {
+		t.Errorf("Expected an error for an unknown rule")
+	}
+}
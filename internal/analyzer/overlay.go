@@ -0,0 +1,200 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// overlay holds file contents supplied in place of what is on disk, such
+// as a client's unsaved edits, by absolute filename. Analyses replacing
+// each other on refresh share it.
+type overlay struct {
+	mu    sync.RWMutex
+	files map[string]overlayFile
+}
+
+// overlayFile is the content of an overlaid file and when it was set
+type overlayFile struct {
+	content []byte
+	modTime time.Time
+}
+
+func newOverlay() *overlay {
+	return &overlay{files: make(map[string]overlayFile)}
+}
+
+// read returns the overlaid content of filename, if any
+func (o *overlay) read(filename string) ([]byte, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	file, ok := o.files[filename]
+	return file.content, ok
+}
+
+// stat describes an overlaid file with the size of its content and the time
+// it was set, or returns info for files not in the overlay
+func (o *overlay) stat(filename string, info os.FileInfo) os.FileInfo {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	file, ok := o.files[filename]
+	if !ok {
+		return info
+	}
+	return overlayInfo{name: filepath.Base(filename), size: int64(len(file.content)), modTime: file.modTime}
+}
+
+// under returns the overlaid filenames below root in sorted order
+func (o *overlay) under(root string) []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var filenames []string
+	for filename := range o.files {
+		if strings.HasPrefix(filename, root+string(filepath.Separator)) {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// overlayInfo describes an overlaid file to the repository walk
+type overlayInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i overlayInfo) Name() string       { return i.name }
+func (i overlayInfo) Size() int64        { return i.size }
+func (i overlayInfo) Mode() os.FileMode  { return 0644 }
+func (i overlayInfo) ModTime() time.Time { return i.modTime }
+func (i overlayInfo) IsDir() bool        { return false }
+func (i overlayInfo) Sys() any           { return nil }
+
+// OverlayFile is a file whose content the analysis takes from the overlay
+type OverlayFile struct {
+	// File is relative to the repository
+	File string `json:"file"`
+	Size int    `json:"size"`
+	// Set is when the content was supplied
+	Set time.Time `json:"set"`
+	// OnDisk reports whether the file exists on disk; files that do not are
+	// added to the analysis by the overlay
+	OnDisk bool `json:"on_disk"`
+}
+
+// SetOverlay makes the analysis read files, Go files inside the repository
+// by name relative to it or absolute, with the given content instead of
+// what is on disk, or as new files when they do not exist. Either all files
+// are set or, on an error, none. Sources read afterwards come from the
+// overlay, and Refresh re-analyzes the repository with it. The overlay
+// lasts until ClearOverlay removes it, even when the files on disk change.
+func (a *Analyzer) SetOverlay(files map[string][]byte) error {
+	contents := make(map[string][]byte, len(files))
+	for file, content := range files {
+		filename, err := a.overlayPath(file)
+		if err != nil {
+			return err
+		}
+		contents[filename] = content
+	}
+
+	a.overlay.mu.Lock()
+	defer a.overlay.mu.Unlock()
+	for filename, content := range contents {
+		modTime := time.Now()
+		if previous, ok := a.overlay.files[filename]; ok && !modTime.After(previous.modTime) {
+			// Every change must be seen as one by the next analysis
+			modTime = previous.modTime.Add(time.Nanosecond)
+		}
+		a.overlay.files[filename] = overlayFile{content: content, modTime: modTime}
+	}
+	return nil
+}
+
+// ClearOverlay removes files from the overlay, or every file when none is
+// given, so that they are read from disk again; Refresh re-analyzes the
+// repository without them. It returns the files removed, relative to the repository.
+func (a *Analyzer) ClearOverlay(files ...string) ([]string, error) {
+	var filenames []string
+	for _, file := range files {
+		filename, err := a.overlayPath(file)
+		if err != nil {
+			return nil, err
+		}
+		filenames = append(filenames, filename)
+	}
+
+	a.overlay.mu.Lock()
+	defer a.overlay.mu.Unlock()
+	if len(files) == 0 {
+		for filename := range a.overlay.files {
+			filenames = append(filenames, filename)
+		}
+	}
+	removed := []string{}
+	for _, filename := range filenames {
+		if _, ok := a.overlay.files[filename]; ok {
+			delete(a.overlay.files, filename)
+			removed = append(removed, a.relPath(filename))
+		}
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// Overlays lists the files in the overlay in sorted order
+func (a *Analyzer) Overlays() []OverlayFile {
+	a.overlay.mu.RLock()
+	defer a.overlay.mu.RUnlock()
+	files := []OverlayFile{}
+	for filename, file := range a.overlay.files {
+		_, err := os.Stat(filename)
+		files = append(files, OverlayFile{
+			File:   a.relPath(filename),
+			Size:   len(file.content),
+			Set:    file.modTime,
+			OnDisk: err == nil,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	return files
+}
+
+// overlayPath resolves a file of the overlay, relative to the repository
+// unless absolute, refusing files outside it and files other than Go sources
+func (a *Analyzer) overlayPath(file string) (string, error) {
+	filename := file
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(a.repoPath, filename)
+	}
+	filename = filepath.Clean(filename)
+	if rel, err := filepath.Rel(a.repoPath, filename); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository", file)
+	}
+	if !strings.HasSuffix(filename, ".go") {
+		return "", fmt.Errorf("%s is not a Go file", file)
+	}
+	return filename, nil
+}
+
+// relPath returns filename relative to the repository, with forward slashes
+func (a *Analyzer) relPath(filename string) string {
+	if rel, err := filepath.Rel(a.repoPath, filename); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filename
+}
+
+// readSource reads a source file, from the overlay when it holds the file
+func (a *Analyzer) readSource(filename string) ([]byte, error) {
+	if content, ok := a.overlay.read(filename); ok {
+		return content, nil
+	}
+	return os.ReadFile(filename)
+}
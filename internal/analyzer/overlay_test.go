@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"shop.go": `package shop
+
+// Cart holds items
+type Cart struct {
+	Items []string
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		edited := "package shop\n\n// Cart holds items and a total\ntype Cart struct {\n\tItems []string\n\tTotal int\n}\n"
+		order := "package shop\n\n// Order is a placed cart\ntype Order struct {\n\tCart Cart\n}\n"
+		if err := analyzer.SetOverlay(map[string][]byte{"shop.go": []byte(edited), filepath.Join(tmpDir, "order.go"): []byte(order)}); err != nil {
+			t.Fatalf("Failed to set overlay: %v", err)
+		}
+		if err := analyzer.SetOverlay(map[string][]byte{"cart.go": nil, "../outside.go": []byte("package outside\n")}); err == nil {
+			t.Error("Expected error for a file outside the repository")
+		}
+		if err := analyzer.SetOverlay(map[string][]byte{"notes.txt": []byte("notes")}); err == nil {
+			t.Error("Expected error for a file other than Go")
+		}
+
+		before := analyzer.LastChange()
+		if err := analyzer.Refresh(ctx); err != nil {
+			t.Fatalf("Failed to refresh: %v", err)
+		}
+		if !analyzer.LastChange().After(before) {
+			t.Errorf("Expected the overlay to change the sources")
+		}
+		if info, err := analyzer.LookupType(ctx, "Cart"); err != nil || len(info.Fields) != 2 || info.Doc != "Cart holds items and a total\n" {
+			t.Errorf("Expected the overlaid Cart, got %+v, %v", info, err)
+		}
+		if _, err := analyzer.LookupType(ctx, "Order"); err != nil {
+			t.Errorf("Expected the file only the overlay holds to be analyzed: %v", err)
+		}
+		src, err := analyzer.ReadRange("shop.go", RangeOptions{StartLine: 6})
+		if err != nil || src.Text != "\tTotal int\n" {
+			t.Errorf("Expected to read the overlaid source, got %+v, %v", src, err)
+		}
+
+		overlays := analyzer.Overlays()
+		if len(overlays) != 2 || overlays[0].File != "order.go" || overlays[0].OnDisk || overlays[1].File != "shop.go" || !overlays[1].OnDisk || overlays[1].Size != len(edited) {
+			t.Errorf("Unexpected overlays: %+v", overlays)
+		}
+
+		// Clearing restores the disk's content
+		removed, err := analyzer.ClearOverlay("order.go", "cart.go")
+		if err != nil || strings.Join(removed, " ") != "order.go" {
+			t.Errorf("Expected order.go removed, got %v, %v", removed, err)
+		}
+		if removed, err := analyzer.ClearOverlay(); err != nil || strings.Join(removed, " ") != "shop.go" {
+			t.Errorf("Expected shop.go removed, got %v, %v", removed, err)
+		}
+		if err := analyzer.Refresh(ctx); err != nil {
+			t.Fatalf("Failed to refresh: %v", err)
+		}
+		if info, err := analyzer.LookupType(ctx, "Cart"); err != nil || len(info.Fields) != 1 {
+			t.Errorf("Expected the disk's Cart after clearing, got %+v, %v", info, err)
+		}
+		if _, err := analyzer.LookupType(ctx, "Order"); err == nil {
+			t.Error("Expected Order to be gone after clearing")
+		}
+	}
+}
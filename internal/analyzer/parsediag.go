@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/types"
+	"go/version"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ParseDiagnostics lists the files the parser could not fully read and the
+// language features type checking rejected for the configured version
+type ParseDiagnostics struct {
+	// Toolchain is the Go version scope was built with, which decides the
+	// syntax the parser understands
+	Toolchain string `json:"toolchain"`
+	// GoVersion is the configured language version, if any
+	GoVersion     string              `json:"go_version,omitempty"`
+	Files         []FileDiagnostic    `json:"files"`
+	VersionErrors []VersionDiagnostic `json:"version_errors"`
+}
+
+// FileDiagnostic describes a file with syntax errors
+type FileDiagnostic struct {
+	Filename   string        `json:"filename"`
+	ImportPath string        `json:"import_path,omitempty"`
+	Errors     []SyntaxError `json:"errors"`
+	// Partial reports whether the declarations the parser could read were
+	// kept; otherwise the whole file is missing from the analysis
+	Partial bool `json:"partial"`
+	// ModuleGoVersion is the go directive of the file's module
+	ModuleGoVersion string `json:"module_go_version,omitempty"`
+	// Hint explains a likely cause, such as a module requiring a newer Go
+	// than the toolchain
+	Hint string `json:"hint,omitempty"`
+}
+
+// SyntaxError is an error reported by the parser
+type SyntaxError struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// VersionDiagnostic is a use of a language feature newer than
+// Config.GoVersion
+type VersionDiagnostic struct {
+	ImportPath string   `json:"import_path"`
+	Message    string   `json:"message"`
+	Position   Position `json:"position"`
+}
+
+// languageVersion returns a Config.GoVersion such as "1.21" or "go1.21" in
+// the form go/types expects
+func languageVersion(v string) string {
+	if v == "" || strings.HasPrefix(v, "go") {
+		return v
+	}
+	return "go" + v
+}
+
+// parsed decides what to keep of a file parser.ParseFile returned with an
+// error. A file whose package clause parsed is kept, so the declarations
+// before and between syntax errors stay available; a file without one is
+// dropped. Either way the errors are recorded for ParseDiagnostics. The
+// caller holds the write lock.
+func (a *Analyzer) parsed(filename string, file *ast.File, err error) (*ast.File, error) {
+	if err == nil {
+		return file, nil
+	}
+	if file == nil || file.Name == nil || file.Name.Name == "" {
+		a.recordParseError(filename, "", err)
+		return nil, err
+	}
+	a.recordParseError(filename, file.Name.Name, err)
+	a.logWarn("Keeping partial syntax tree", "file", filename, "error", err)
+	return file, nil
+}
+
+// recordParseError records the syntax errors of a file. pkgName is the
+// package the file declares, empty when its package clause did not parse.
+func (a *Analyzer) recordParseError(filename, pkgName string, err error) {
+	diag := FileDiagnostic{Filename: filename, Partial: pkgName != ""}
+	if pkgName != "" {
+		diag.ImportPath = a.packageOf(filename, pkgName)
+	}
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			diag.Errors = append(diag.Errors, SyntaxError{Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg})
+		}
+	} else {
+		diag.Errors = []SyntaxError{{Message: err.Error()}}
+	}
+	a.parseErrors[filename] = diag
+}
+
+// recordTypeError records a type checking error of a package when it is
+// about a language feature newer than Config.GoVersion
+func (a *Analyzer) recordTypeError(importPath string, err error) {
+	var typeErr types.Error
+	if !errors.As(err, &typeErr) || !strings.Contains(typeErr.Msg, "requires go1") {
+		return
+	}
+	a.versionErrors[importPath] = append(a.versionErrors[importPath], VersionDiagnostic{
+		ImportPath: importPath,
+		Message:    typeErr.Msg,
+		Position:   a.position(typeErr.Pos),
+	})
+}
+
+// ParseDiagnostics returns the files with syntax errors and, when
+// Config.GoVersion is set, the uses of newer language features found in
+// the packages type checked so far. With lazy loading, syntax errors are
+// found by discovery, but version errors only for loaded packages.
+func (a *Analyzer) ParseDiagnostics(ctx context.Context) (*ParseDiagnostics, error) {
+	if err := a.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	result := &ParseDiagnostics{
+		Toolchain:     runtime.Version(),
+		GoVersion:     languageVersion(a.config.GoVersion),
+		Files:         []FileDiagnostic{},
+		VersionErrors: []VersionDiagnostic{},
+	}
+	for _, diag := range a.parseErrors {
+		diag.Errors = append([]SyntaxError(nil), diag.Errors...)
+		diag.ModuleGoVersion = a.goVersion(filepath.Dir(diag.Filename))
+		if module := languageVersion(diag.ModuleGoVersion); version.IsValid(result.Toolchain) && version.Compare(module, result.Toolchain) > 0 {
+			diag.Hint = fmt.Sprintf("the module requires %s but scope was built with %s, whose parser may not understand newer syntax; rebuild scope with a newer Go", module, result.Toolchain)
+		}
+		result.Files = append(result.Files, diag)
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Filename < result.Files[j].Filename
+	})
+
+	importPaths := make([]string, 0, len(a.versionErrors))
+	for importPath := range a.versionErrors {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+	for _, importPath := range importPaths {
+		result.VersionErrors = append(result.VersionErrors, a.versionErrors[importPath]...)
+	}
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.99\n",
+		"broken/broken.go": `package broken
+
+type Kept struct{}
+
+func Broken() {
+	x :=
+}
+`,
+		"broken/noclause.go": "func Lost() {}\n",
+		"loops/loops.go": `package loops
+
+func Count() int {
+	n := 0
+	for i := range 10 {
+		n += i
+	}
+	return n
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		config.GoVersion = "1.21"
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		// The declarations before a syntax error survive
+		if _, err := analyzer.LookupType(ctx, "Kept"); err != nil {
+			t.Errorf("Failed to look up Kept (lazy %v): %v", lazy, err)
+		}
+
+		diags, err := analyzer.ParseDiagnostics(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get parse diagnostics: %v", err)
+		}
+		if diags.GoVersion != "go1.21" || diags.Toolchain == "" {
+			t.Errorf("Expected go1.21 and the toolchain version, got %q and %q", diags.GoVersion, diags.Toolchain)
+		}
+		if len(diags.Files) != 2 {
+			t.Fatalf("Expected 2 files with syntax errors, got %+v", diags.Files)
+		}
+		partial, lost := diags.Files[0], diags.Files[1]
+		if filepath.Base(partial.Filename) != "broken.go" || !partial.Partial || partial.ImportPath != "example.com/app/broken" {
+			t.Errorf("Expected a partial broken.go in example.com/app/broken, got %+v", partial)
+		}
+		if len(partial.Errors) == 0 || partial.Errors[0].Line != 7 {
+			t.Errorf("Expected a syntax error on line 7, got %+v", partial.Errors)
+		}
+		if partial.ModuleGoVersion != "1.99" || !strings.Contains(partial.Hint, "go1.99") {
+			t.Errorf("Expected a hint about go 1.99, got %+v", partial)
+		}
+		if filepath.Base(lost.Filename) != "noclause.go" || lost.Partial || lost.ImportPath != "" {
+			t.Errorf("Expected noclause.go to be dropped, got %+v", lost)
+		}
+
+		// Version errors are found once the package is type checked
+		if lazy && len(diags.VersionErrors) != 0 {
+			t.Errorf("Expected no version errors before loading, got %+v", diags.VersionErrors)
+		}
+		if _, err := analyzer.GetPackageInfo(ctx, "loops"); err != nil {
+			t.Fatalf("Failed to get package info: %v", err)
+		}
+		diags, err = analyzer.ParseDiagnostics(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get parse diagnostics: %v", err)
+		}
+		if len(diags.VersionErrors) != 1 {
+			t.Fatalf("Expected one version error, got %+v", diags.VersionErrors)
+		}
+		if got := diags.VersionErrors[0]; got.ImportPath != "example.com/app/loops" || got.Position.Line != 5 || !strings.Contains(got.Message, "requires go1.22") {
+			t.Errorf("Expected range over int to require go1.22, got %+v", got)
+		}
+	}
+
+	config := DefaultConfig()
+	config.GoVersion = "latest"
+	if _, err := NewAnalyzerWithConfig(tmpDir, config); err == nil {
+		t.Error("Expected an error for an invalid Go version")
+	}
+}
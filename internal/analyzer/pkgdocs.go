@@ -0,0 +1,247 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultPackageDocsBytes is the text budget of a package's documentation
+const DefaultPackageDocsBytes = 4000
+
+// readmeNames are the file names recognized as package READMEs, lower-cased
+var readmeNames = []string{"readme.md", "readme.markdown", "readme.txt", "readme"}
+
+var (
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// badgeLine matches lines holding nothing but badge images, which may
+	// be links
+	badgeLine  = regexp.MustCompile(`^\s*((\[!\[[^\]]*\]\([^)]*\)\]\([^)]*\)|!\[[^\]]*\]\([^)]*\))\s*)+$`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// PackageDocsOptions configure PackageDocs
+type PackageDocsOptions struct {
+	// Package selects packages by import path or package name; empty
+	// selects every documented package
+	Package string
+	// MaxBytes is the text budget per package; defaults to
+	// DefaultPackageDocsBytes
+	MaxBytes int
+	// Limit is the maximum number of packages; zero means no limit
+	Limit int
+}
+
+// PackageDoc is the human-written documentation of a package: its package
+// comment and the README of its directory
+type PackageDoc struct {
+	ImportPath string `json:"import_path"`
+	Name       string `json:"name"`
+	Dir        string `json:"dir"`
+	// DocFiles are the files holding the package comment, doc.go first
+	DocFiles []string `json:"doc_files,omitempty"`
+	Readme   string   `json:"readme,omitempty"`
+	// Text is the package comment followed by the README, cut at a
+	// paragraph boundary when it exceeds the budget
+	Text      string `json:"text"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// PackageDocsResult is the result of PackageDocs
+type PackageDocsResult struct {
+	Packages []PackageDoc `json:"packages"`
+	// Truncated is set when more packages were documented than the limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PackageDocs returns the package comments and README files of the packages
+// a qualifier selects. Both are read from disk, so no package needs to be
+// loaded. Without a qualifier, packages with neither are left out.
+func (a *Analyzer) PackageDocs(ctx context.Context, opts PackageDocsOptions) (*PackageDocsResult, error) {
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultPackageDocsBytes
+	}
+
+	if err := a.rlock(ctx); err != nil {
+		return nil, err
+	}
+	if !a.initialized {
+		a.mu.RUnlock()
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+	var pkgs []PackageDoc
+	files := make(map[string][]string)
+	for importPath, filenames := range a.files {
+		if len(filenames) == 0 || strings.HasSuffix(importPath, "_test") {
+			continue
+		}
+		name := a.packageName(importPath)
+		if !matchesQualifier(opts.Package, importPath, name) {
+			continue
+		}
+		pkgs = append(pkgs, PackageDoc{ImportPath: importPath, Name: name, Dir: filepath.Dir(filenames[0])})
+		files[importPath] = append([]string(nil), filenames...)
+	}
+	a.mu.RUnlock()
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("package %s not found", opts.Package)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+
+	result := &PackageDocsResult{Packages: []PackageDoc{}}
+	for _, pkg := range pkgs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		comment := a.packageComment(files[pkg.ImportPath], &pkg)
+		readme := a.packageReadme(&pkg)
+		if comment == "" && readme == "" && opts.Package == "" {
+			continue
+		}
+		if opts.Limit > 0 && len(result.Packages) == opts.Limit {
+			result.Truncated = true
+			break
+		}
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "# Package %s (%s)\n", pkg.Name, pkg.ImportPath)
+		if comment != "" {
+			text.WriteString("\n" + comment + "\n")
+		}
+		if readme != "" {
+			fmt.Fprintf(&text, "\n## %s\n\n%s\n", filepath.Base(pkg.Readme), readme)
+		}
+		pkg.Bytes = text.Len()
+		pkg.Text, pkg.Truncated = truncateDoc(text.String(), maxBytes)
+		result.Packages = append(result.Packages, pkg)
+	}
+	return result, nil
+}
+
+// packageComment returns the package comment of the non-test files of a
+// package, recording the files it comes from. Comments of several files
+// are joined with doc.go's first, as godoc shows them.
+func (a *Analyzer) packageComment(filenames []string, pkg *PackageDoc) string {
+	sort.Slice(filenames, func(i, j int) bool {
+		iDoc, jDoc := filepath.Base(filenames[i]) == "doc.go", filepath.Base(filenames[j]) == "doc.go"
+		if iDoc != jDoc {
+			return iDoc
+		}
+		return filenames[i] < filenames[j]
+	})
+	fset := token.NewFileSet()
+	var comments []string
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		src, err := a.readSource(filename)
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil || file.Doc == nil {
+			continue
+		}
+		if comment := strings.TrimSpace(file.Doc.Text()); comment != "" {
+			comments = append(comments, comment)
+			pkg.DocFiles = append(pkg.DocFiles, filename)
+		}
+	}
+	return strings.Join(comments, "\n\n")
+}
+
+// packageReadme returns the README of a package's directory with HTML
+// comments and badge lines removed, recording its file
+func (a *Analyzer) packageReadme(pkg *PackageDoc) string {
+	entries, err := os.ReadDir(pkg.Dir)
+	if err != nil {
+		return ""
+	}
+	for _, name := range readmeNames {
+		for _, entry := range entries {
+			if entry.IsDir() || strings.ToLower(entry.Name()) != name {
+				continue
+			}
+			filename := filepath.Join(pkg.Dir, entry.Name())
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				a.logWarn("Skipping README", "file", filename, "error", err)
+				return ""
+			}
+			pkg.Readme = filename
+			return cleanReadme(string(data))
+		}
+	}
+	return ""
+}
+
+// cleanReadme drops what carries no meaning as text from a README: HTML
+// comments, lines of badges and runs of blank lines
+func cleanReadme(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = htmlComment.ReplaceAllString(text, "")
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !badgeLine.MatchString(line) {
+			kept = append(kept, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(kept, "\n"), "\n\n"))
+}
+
+// truncateDoc cuts text to at most maxBytes, at the last paragraph boundary
+// outside code blocks that fits, and notes how much was left out. Without
+// such a boundary it cuts at the last line break, or mid-line as a last
+// resort.
+func truncateDoc(text string, maxBytes int) (string, bool) {
+	if len(text) <= maxBytes {
+		return text, false
+	}
+	// The note shows no more digits than it would for maxBytes
+	note := len(truncationNote(maxBytes, len(text)))
+	budget := max(maxBytes-note, 0)
+
+	cut := -1
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if offset+len(line) > budget {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		offset += len(line)
+		if trimmed == "" && !inFence {
+			cut = offset
+		}
+	}
+	if cut < 0 {
+		cut = strings.LastIndexByte(text[:budget], '\n') + 1
+	}
+	if cut <= 0 {
+		cut = budget
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	shown := strings.TrimRight(text[:cut], "\n") + "\n"
+	return shown + truncationNote(len(shown), len(text)), true
+}
+
+// truncationNote tells how much of a text truncateDoc kept
+func truncationNote(shown, total int) string {
+	return fmt.Sprintf("\n[truncated: %d of %d bytes shown]\n", shown, total)
+}
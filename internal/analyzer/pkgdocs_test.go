@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackageDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go":     "// Keeps the stock in sync.\npackage store\n\ntype Store struct{}\n",
+		"store/doc.go":       "// Package store keeps items.\npackage store\n",
+		"store/README.md":    "[![CI](https://ci/badge.svg)](https://ci)\n<!-- generated -->\n# Store\n\n\n\nUsage notes.\n",
+		"cart/cart.go":       "package cart\n\ntype Cart struct{}\n",
+		"cart/store_test.go": "// Package cart tests.\npackage cart\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		// Undocumented packages are left out of the overview
+		result, err := analyzer.PackageDocs(ctx, PackageDocsOptions{})
+		if err != nil {
+			t.Fatalf("PackageDocs failed: %v", err)
+		}
+		if len(result.Packages) != 1 || result.Truncated {
+			t.Fatalf("Expected only the store package, got %+v", result)
+		}
+		store := result.Packages[0]
+		expected := "# Package store (example.com/shop/store)\n\nPackage store keeps items.\n\nKeeps the stock in sync.\n\n## README.md\n\n# Store\n\nUsage notes.\n"
+		if store.Text != expected || store.Truncated || store.Bytes != len(expected) {
+			t.Errorf("Expected text:\n%s\ngot:\n%s", expected, store.Text)
+		}
+		if len(store.DocFiles) != 2 || filepath.Base(store.DocFiles[0]) != "doc.go" || store.Readme != filepath.Join(tmpDir, "store", "README.md") {
+			t.Errorf("Unexpected sources: %v, %s", store.DocFiles, store.Readme)
+		}
+
+		// Selected packages are returned even without documentation
+		result, err = analyzer.PackageDocs(ctx, PackageDocsOptions{Package: "cart"})
+		if err != nil {
+			t.Fatalf("PackageDocs failed: %v", err)
+		}
+		if len(result.Packages) != 1 || result.Packages[0].Text != "# Package cart (example.com/shop/cart)\n" {
+			t.Errorf("Expected the undocumented cart package, got %+v", result)
+		}
+
+		result, err = analyzer.PackageDocs(ctx, PackageDocsOptions{Package: "store", MaxBytes: 120})
+		if err != nil {
+			t.Fatalf("PackageDocs failed: %v", err)
+		}
+		if store := result.Packages[0]; !store.Truncated || len(store.Text) > 120 ||
+			!strings.HasPrefix(store.Text, "# Package store (example.com/shop/store)\n\nPackage store keeps items.\n\n[truncated: ") {
+			t.Errorf("Expected the text cut after the first paragraph, got %q", store.Text)
+		}
+
+		if _, err := analyzer.PackageDocs(ctx, PackageDocsOptions{Package: "missing"}); err == nil {
+			t.Error("Expected an error for an unknown package")
+		}
+	}
+}
+
+func TestTruncateDoc(t *testing.T) {
+	text := "Intro.\n\n```go\nfunc main() {\n\n\tfmt.Println()\n}\n```\n\nOutro paragraph that is long enough to need cutting.\n"
+	// The blank line inside the code block is no place to cut
+	shown, truncated := truncateDoc(text, 76)
+	if !truncated || shown != "Intro.\n\n[truncated: 7 of 104 bytes shown]\n" {
+		t.Errorf("Expected a cut before the code block, got %q", shown)
+	}
+	if shown, truncated := truncateDoc(text, 1000); truncated || shown != text {
+		t.Errorf("Expected short text unchanged, got %q", shown)
+	}
+	// Without a paragraph boundary the cut falls on a line break
+	shown, _ = truncateDoc(strings.Repeat("line\n", 20), 60)
+	if !strings.HasPrefix(shown, "line\nline\n") || strings.Contains(shown, "lin\n") || len(shown) > 60 {
+		t.Errorf("Expected a cut at a line break, got %q", shown)
+	}
+}
@@ -0,0 +1,238 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"sort"
+	"sync"
+)
+
+// Plugin adds organization-specific analysis to every package without
+// changing the analyzer. A plugin implements one or more of ParseHook,
+// TypeCheckHook and ResultHook, and attaches its findings to packages and
+// types as tags, which appear in PackageInfo.Tags and TypeInfo.Tags under
+// "<plugin name>.<key>".
+type Plugin interface {
+	Name() string
+}
+
+// ParseHook runs for each package once the repository is parsed, before
+// type checking. Only the syntax of the package is available.
+type ParseHook interface {
+	AfterParse(ctx context.Context, pkg *PluginPackage) error
+}
+
+// TypeCheckHook runs for each package once all packages are type checked
+type TypeCheckHook interface {
+	AfterTypeCheck(ctx context.Context, pkg *PluginPackage) error
+}
+
+// ResultHook runs for each package once documentation is extracted, as the
+// last step before the analysis starts answering queries. It sees the tags
+// attached by earlier hooks.
+type ResultHook interface {
+	BeforeResult(ctx context.Context, pkg *PluginPackage) error
+}
+
+// PluginPackage is the view of a package handed to plugin hooks. Fields that
+// are not available yet at a hook are nil.
+type PluginPackage struct {
+	ImportPath string
+	Name       string
+	Fset       *token.FileSet
+	Files      []*ast.File
+	// Types and Info are set from TypeCheckHook on
+	Types *types.Package
+	Info  *types.Info
+	// Doc is set for ResultHook
+	Doc *doc.Package
+
+	plugin string
+	tags   *packageTags
+}
+
+// Tag attaches a key and value to the package
+func (p *PluginPackage) Tag(key, value string) {
+	p.tags.set("", p.plugin+"."+key, value)
+}
+
+// TagType attaches a key and value to a type declared in the package
+func (p *PluginPackage) TagType(typeName, key, value string) {
+	p.tags.set(typeName, p.plugin+"."+key, value)
+}
+
+// Tags returns the tags attached to the package so far, by every plugin
+func (p *PluginPackage) Tags() map[string]string {
+	return p.tags.get("")
+}
+
+// TypeTags returns the tags attached to a type so far, by every plugin
+func (p *PluginPackage) TypeTags(typeName string) map[string]string {
+	return p.tags.get(typeName)
+}
+
+// packageTags holds the tags of a package ("" key) and of its types
+type packageTags struct {
+	mu     sync.Mutex
+	values map[string]map[string]string
+}
+
+func (t *packageTags) set(typeName, key, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.values == nil {
+		t.values = make(map[string]map[string]string)
+	}
+	if t.values[typeName] == nil {
+		t.values[typeName] = make(map[string]string)
+	}
+	t.values[typeName][key] = value
+}
+
+// get returns a copy of the tags of the package or one of its types, or nil
+func (t *packageTags) get(typeName string) map[string]string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.values[typeName]) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(t.values[typeName]))
+	for key, value := range t.values[typeName] {
+		tags[key] = value
+	}
+	return tags
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]Plugin)
+)
+
+// RegisterPlugin makes a plugin run for every analyzer created afterwards.
+// It is meant to be called from an init function, like database/sql drivers,
+// and panics if the name is empty or already registered.
+func RegisterPlugin(plugin Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	name := plugin.Name()
+	if name == "" {
+		panic("analyzer: plugin has no name")
+	}
+	if _, exists := plugins[name]; exists {
+		panic("analyzer: plugin " + name + " registered twice")
+	}
+	plugins[name] = plugin
+}
+
+// Plugins returns the names of the registered plugins in sorted order
+func Plugins() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activePlugins returns the registered plugins followed by those of the
+// analyzer's configuration
+func (a *Analyzer) activePlugins() []Plugin {
+	pluginsMu.RLock()
+	active := make([]Plugin, 0, len(plugins)+len(a.config.Plugins))
+	for _, plugin := range plugins {
+		active = append(active, plugin)
+	}
+	pluginsMu.RUnlock()
+	sort.Slice(active, func(i, j int) bool { return active[i].Name() < active[j].Name() })
+	return append(active, a.config.Plugins...)
+}
+
+// runPlugins calls a hook of every plugin implementing it for each of the
+// given packages. Plugin failures and panics are logged and do not fail the
+// analysis.
+func (a *Analyzer) runPlugins(ctx context.Context, stage string, importPaths []string, call func(Plugin, *PluginPackage) error) error {
+	active := a.activePlugins()
+	if len(active) == 0 {
+		return nil
+	}
+	for _, importPath := range importPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tags := a.tags[importPath]
+		if tags == nil {
+			tags = &packageTags{}
+			a.tags[importPath] = tags
+		}
+		pkg := &PluginPackage{
+			ImportPath: importPath,
+			Fset:       a.fset,
+			Files:      a.asts[importPath],
+			Types:      a.pkgs[importPath],
+			Info:       a.infos[importPath],
+			Doc:        a.docPkgs[importPath],
+			tags:       tags,
+		}
+		if len(pkg.Files) > 0 {
+			pkg.Name = pkg.Files[0].Name.Name
+		}
+		for _, plugin := range active {
+			pkg.plugin = plugin.Name()
+			if err := a.callPlugin(plugin, pkg, call); err != nil {
+				a.logWarn("Plugin failed", "plugin", plugin.Name(), "stage", stage, "package", importPath, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// callPlugin runs one hook, turning a panic into an error
+func (a *Analyzer) callPlugin(plugin Plugin, pkg *PluginPackage, call func(Plugin, *PluginPackage) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return call(plugin, pkg)
+}
+
+// Hook adapters for runPlugins; plugins without the hook are skipped
+
+func afterParse(ctx context.Context) func(Plugin, *PluginPackage) error {
+	return func(plugin Plugin, pkg *PluginPackage) error {
+		hook, ok := plugin.(ParseHook)
+		if !ok {
+			return nil
+		}
+		return hook.AfterParse(ctx, pkg)
+	}
+}
+
+func afterTypeCheck(ctx context.Context) func(Plugin, *PluginPackage) error {
+	return func(plugin Plugin, pkg *PluginPackage) error {
+		hook, ok := plugin.(TypeCheckHook)
+		if !ok {
+			return nil
+		}
+		return hook.AfterTypeCheck(ctx, pkg)
+	}
+}
+
+func beforeResult(ctx context.Context) func(Plugin, *PluginPackage) error {
+	return func(plugin Plugin, pkg *PluginPackage) error {
+		hook, ok := plugin.(ResultHook)
+		if !ok {
+			return nil
+		}
+		return hook.BeforeResult(ctx, pkg)
+	}
+}
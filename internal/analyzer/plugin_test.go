@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// stringerPlugin tags packages with their file count and types with a
+// String method, and panics for a package named boom
+type stringerPlugin struct {
+	stages []string
+}
+
+func (p *stringerPlugin) Name() string { return "stringer" }
+
+func (p *stringerPlugin) AfterParse(ctx context.Context, pkg *PluginPackage) error {
+	p.stages = append(p.stages, "parse:"+pkg.Name)
+	if pkg.Types != nil {
+		return fmt.Errorf("types available before type checking")
+	}
+	pkg.Tag("files", fmt.Sprint(len(pkg.Files)))
+	return nil
+}
+
+func (p *stringerPlugin) AfterTypeCheck(ctx context.Context, pkg *PluginPackage) error {
+	p.stages = append(p.stages, "typecheck:"+pkg.Name)
+	if pkg.Name == "boom" {
+		panic("boom")
+	}
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if method, _, _ := types.LookupFieldOrMethod(obj.Type(), true, pkg.Types, "String"); method != nil {
+			pkg.TagType(name, "stringer", "true")
+		}
+	}
+	return nil
+}
+
+func (p *stringerPlugin) BeforeResult(ctx context.Context, pkg *PluginPackage) error {
+	p.stages = append(p.stages, "result:"+pkg.Name)
+	if pkg.Doc == nil {
+		return fmt.Errorf("no documentation before result assembly")
+	}
+	if pkg.Tags()["stringer.files"] != "" {
+		pkg.Tag("documented", fmt.Sprint(pkg.Doc.Doc != ""))
+	}
+	return nil
+}
+
+func TestPlugins(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"color/color.go": `// Package color names colors.
+package color
+
+// Color is a named color
+type Color int
+
+func (c Color) String() string { return "red" }
+
+// Plain has no String method
+type Plain struct{}
+`,
+		"boom/boom.go": "package boom\n\ntype Boom struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	plugin := &stringerPlugin{}
+	config := DefaultConfig()
+	config.Plugins = []Plugin{plugin}
+	analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	want := []string{"parse:boom", "parse:color", "typecheck:boom", "typecheck:color", "result:boom", "result:color"}
+	if !slices.Equal(plugin.stages, want) {
+		t.Errorf("Expected hooks %v, got %v", want, plugin.stages)
+	}
+
+	ctx := context.Background()
+	pkg, err := analyzer.GetPackageInfo(ctx, "color")
+	if err != nil {
+		t.Fatalf("Failed to get package info: %v", err)
+	}
+	if pkg.Tags["stringer.files"] != "1" || pkg.Tags["stringer.documented"] != "true" {
+		t.Errorf("Unexpected package tags %v", pkg.Tags)
+	}
+	color, err := analyzer.LookupType(ctx, "color.Color")
+	if err != nil {
+		t.Fatalf("Failed to look up type: %v", err)
+	}
+	if color.Tags["stringer.stringer"] != "true" {
+		t.Errorf("Expected Color to be tagged, got %v", color.Tags)
+	}
+	if plain, err := analyzer.LookupType(ctx, "color.Plain"); err != nil || plain.Tags != nil {
+		t.Errorf("Expected Plain to be untagged, got %+v (%v)", plain, err)
+	}
+
+	// A panicking plugin does not fail the analysis
+	if _, err := analyzer.LookupType(ctx, "boom.Boom"); err != nil {
+		t.Errorf("Expected analysis to survive a plugin panic, got %v", err)
+	}
+
+	// Tags survive a refresh, which runs the plugins again
+	if err := analyzer.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if color, err := analyzer.LookupType(ctx, "color.Color"); err != nil || color.Tags["stringer.stringer"] != "true" {
+		t.Errorf("Expected tags after refresh, got %+v (%v)", color, err)
+	}
+}
+
+type namedPlugin string
+
+func (p namedPlugin) Name() string { return string(p) }
+
+func TestRegisterPlugin(t *testing.T) {
+	RegisterPlugin(namedPlugin("register-test"))
+	if !slices.Contains(Plugins(), "register-test") {
+		t.Errorf("Expected registered plugin in %v", Plugins())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering a plugin twice")
+		}
+	}()
+	RegisterPlugin(namedPlugin("register-test"))
+}
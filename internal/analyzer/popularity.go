@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"sort"
+)
+
+// TypeUsage is an exported type of the repository with the number of
+// references to it
+type TypeUsage struct {
+	// Name is the type qualified with its package name, as pkg.Type
+	Name       string `json:"name"`
+	ImportPath string `json:"import_path"`
+	References int    `json:"references"`
+	// Ambiguous is set when other packages declare a type of the same
+	// name, so that only the qualified name finds it
+	Ambiguous bool `json:"ambiguous,omitempty"`
+}
+
+// ExportedTypes returns the exported types of the analyzed packages, most
+// referenced first. References are counted across all packages, tests
+// included, and a type's own declaration does not count.
+func (a *Analyzer) ExportedTypes(ctx context.Context) ([]TypeUsage, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	references := make(map[*types.TypeName]int)
+	for _, importPath := range a.sortedPackagePaths() {
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		for _, obj := range info.Uses {
+			if typeName, ok := obj.(*types.TypeName); ok {
+				references[typeName]++
+			}
+		}
+	}
+
+	named := make(map[string]int)
+	for _, sym := range a.index.types {
+		if sym.obj.Exported() {
+			named[sym.obj.Name()]++
+		}
+	}
+	usages := []TypeUsage{}
+	for _, sym := range a.index.types {
+		typeName := sym.obj.(*types.TypeName)
+		if !typeName.Exported() {
+			continue
+		}
+		usages = append(usages, TypeUsage{
+			Name:       typeName.Pkg().Name() + "." + typeName.Name(),
+			ImportPath: sym.importPath,
+			References: references[typeName],
+			Ambiguous:  named[typeName.Name()] > 1,
+		})
+	}
+	// The index orders types by import path and name, which breaks ties
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].References > usages[j].References })
+	return usages, nil
+}
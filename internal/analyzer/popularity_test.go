@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportedTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"cart/cart.go": `package cart
+
+// Item is bought
+type Item struct{ Price int }
+
+// Cart holds items
+type Cart struct{ Items []Item }
+
+// Total sums the items
+func (c *Cart) Total(extra Item) int { return 0 }
+
+type hidden struct{}
+`,
+		"order/order.go": `package order
+
+import "example.com/shop/cart"
+
+// Item is ordered
+type Item struct{ Cart *cart.Cart }
+
+func items() []cart.Item { return []cart.Item{{Price: 1}} }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	usages, err := analyzer.ExportedTypes(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list exported types: %v", err)
+	}
+	var got []string
+	for _, usage := range usages {
+		got = append(got, fmt.Sprintf("%s=%d/%t", usage.Name, usage.References, usage.Ambiguous))
+	}
+	if want := "cart.Item=4/true cart.Cart=2/false order.Item=0/true"; strings.Join(got, " ") != want {
+		t.Errorf("Unexpected exported types:\n got %s\nwant %s", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,202 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/profile"
+)
+
+// Orders of a profile report
+const (
+	ProfileSortFlat = "flat"
+	ProfileSortCum  = "cum"
+)
+
+// ProfileOptions selects the functions of a profile report
+type ProfileOptions struct {
+	// SampleType is the sample type to report, such as cpu or alloc_space;
+	// empty for the profile's default
+	SampleType string
+	// SortBy is ProfileSortFlat or ProfileSortCum
+	SortBy string
+	// Limit caps the functions returned; 0 means no limit
+	Limit int
+	// RepoOnly leaves out functions outside the repository
+	RepoOnly bool
+}
+
+// ProfileReport shows the functions a pprof profile spent the most in, with
+// the repository's functions mapped to their declarations
+type ProfileReport struct {
+	SampleType  string   `json:"sample_type"`
+	Unit        string   `json:"unit"`
+	SampleTypes []string `json:"sample_types"`
+	Total       int64    `json:"total"`
+	Duration    string   `json:"duration,omitempty"`
+	// RepositoryFlat is the part of Total spent running the repository's
+	// own functions rather than the runtime, standard library or
+	// dependencies
+	RepositoryFlat int64         `json:"repository_flat"`
+	Functions      []HotFunction `json:"functions"`
+	// Truncated reports whether Limit left out functions
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HotFunction is a function of the profile with its share of the samples
+type HotFunction struct {
+	// Name is the function's name in the profile
+	Name string `json:"name"`
+	// Function is pkg.Func or pkg.Type.Method for the repository's
+	// functions, empty for others
+	Function   string `json:"function,omitempty"`
+	ImportPath string `json:"import_path,omitempty"`
+	// Closure reports whether the samples are those of a function literal
+	// inside Function
+	Closure     bool      `json:"closure,omitempty"`
+	Flat        int64     `json:"flat"`
+	FlatPercent float64   `json:"flat_percent"`
+	Cum         int64     `json:"cum"`
+	CumPercent  float64   `json:"cum_percent"`
+	Signature   string    `json:"signature,omitempty"`
+	Doc         string    `json:"doc,omitempty"`
+	Position    *Position `json:"position,omitempty"`
+}
+
+// ProfileReport sums the samples of a profile by function and maps the
+// functions to the repository's by their import path and name, or by their
+// file for main packages and binaries built from another checkout. Samples
+// of function literals count towards the enclosing function's declaration.
+func (a *Analyzer) ProfileReport(ctx context.Context, prof *profile.Profile, opts ProfileOptions) (*ProfileReport, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	index, err := prof.SampleIndex(opts.SampleType)
+	if err != nil {
+		return nil, err
+	}
+	stats, total := prof.Functions(index)
+	switch opts.SortBy {
+	case "", ProfileSortFlat:
+	case ProfileSortCum:
+		sort.SliceStable(stats, func(i, j int) bool { return stats[i].Cum > stats[j].Cum })
+	default:
+		return nil, fmt.Errorf("unknown sort order %s; expected %s or %s", opts.SortBy, ProfileSortFlat, ProfileSortCum)
+	}
+
+	report := &ProfileReport{
+		SampleType:  prof.SampleTypes[index].Type,
+		Unit:        prof.SampleTypes[index].Unit,
+		SampleTypes: []string{},
+		Total:       total,
+		Functions:   []HotFunction{},
+	}
+	for _, sampleType := range prof.SampleTypes {
+		report.SampleTypes = append(report.SampleTypes, sampleType.Type)
+	}
+	if prof.DurationNanos > 0 {
+		report.Duration = time.Duration(prof.DurationNanos).String()
+	}
+
+	percent := func(v int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return math.Round(float64(v)/float64(total)*10000) / 100
+	}
+	for _, stat := range stats {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hot := HotFunction{
+			Name:        stat.Name,
+			Flat:        stat.Flat,
+			FlatPercent: percent(stat.Flat),
+			Cum:         stat.Cum,
+			CumPercent:  percent(stat.Cum),
+		}
+		if fn, closure := a.profileFunc(stat); fn != nil {
+			report.RepositoryFlat += stat.Flat
+			hot.Function = funcName(fn)
+			hot.ImportPath = fn.Pkg().Path()
+			hot.Closure = closure
+			hot.Signature = types.ObjectString(fn, types.RelativeTo(fn.Pkg()))
+			hot.Doc = a.funcDoc(fn)
+			position := a.position(fn.Pos())
+			hot.Position = &position
+		} else if opts.RepoOnly {
+			continue
+		}
+		if opts.Limit > 0 && len(report.Functions) == opts.Limit {
+			report.Truncated = true
+			continue
+		}
+		report.Functions = append(report.Functions, hot)
+	}
+	return report, nil
+}
+
+// profileFunc finds the repository's function of a profile's function
+func (a *Analyzer) profileFunc(stat profile.FunctionStat) (*types.Func, bool) {
+	importPath, name, closure := profile.SplitName(stat.Name)
+	pkg := a.pkgs[importPath]
+	if pkg == nil {
+		pkg = a.profilePackage(importPath, stat.Filename)
+	}
+	if pkg == nil {
+		return nil, false
+	}
+
+	typeName, method, isMethod := strings.Cut(name, ".")
+	if !isMethod {
+		fn, _ := pkg.Scope().Lookup(name).(*types.Func)
+		return fn, closure
+	}
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, false
+	}
+	fn, _ := lookupMethod(obj.Type(), pkg, method)
+	return fn, closure
+}
+
+// profilePackage finds the package holding filename, whose name is the last
+// element of importPath. The profile names a main package main, and gives
+// the paths of the checkout the binary was built from, which may be
+// another one than the repository's.
+func (a *Analyzer) profilePackage(importPath, filename string) *types.Package {
+	if filename == "" {
+		return nil
+	}
+	name := importPath[strings.LastIndex(importPath, "/")+1:]
+	filename = filepath.ToSlash(filename)
+	var match *types.Package
+	for _, path := range a.sortedPackagePaths() {
+		pkg := a.pkgs[path]
+		if pkg == nil || pkg.Name() != name {
+			continue
+		}
+		for _, file := range a.asts[path] {
+			source := a.fset.Position(file.Pos()).Filename
+			if filepath.ToSlash(source) == filename {
+				return pkg
+			}
+			if match == nil && strings.HasSuffix(filename, "/"+a.relPath(source)) {
+				match = pkg
+			}
+		}
+	}
+	return match
+}
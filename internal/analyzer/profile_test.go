@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/profile"
+)
+
+func TestProfileReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"cart/cart.go": `package cart
+
+// Cart holds items
+type Cart struct{ items []int }
+
+// Total sums the items
+func (c *Cart) Total() int {
+	sum := 0
+	for _, item := range c.items {
+		sum += item
+	}
+	return sum
+}
+
+func parse(s string) []int {
+	var items []int
+	each := func(r rune) { items = append(items, int(r)) }
+	for _, r := range s {
+		each(r)
+	}
+	return items
+}
+`,
+		"cmd/shop/main.go": `package main
+
+func main() {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	total := &profile.Function{Name: "example.com/shop/cart.(*Cart).Total"}
+	closure := &profile.Function{Name: "example.com/shop/cart.parse.func1"}
+	mallocgc := &profile.Function{Name: "runtime.mallocgc", Filename: "/usr/local/go/src/runtime/malloc.go"}
+	// The binary was built from another checkout
+	mainFunc := &profile.Function{Name: "main.main", Filename: "/build/shop/cmd/shop/main.go"}
+	stack := func(fns ...*profile.Function) []*profile.Location {
+		var locations []*profile.Location
+		for _, fn := range fns {
+			locations = append(locations, &profile.Location{Lines: []profile.Line{{Function: fn}}})
+		}
+		return locations
+	}
+	prof := &profile.Profile{
+		SampleTypes:   []profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		DurationNanos: 2e9,
+		Samples: []profile.Sample{
+			{Locations: stack(total, mainFunc), Values: []int64{6, 60}},
+			{Locations: stack(mallocgc, closure, mainFunc), Values: []int64{3, 30}},
+			{Locations: stack(closure, mainFunc), Values: []int64{1, 10}},
+		},
+	}
+
+	report, err := analyzer.ProfileReport(ctx, prof, ProfileOptions{})
+	if err != nil {
+		t.Fatalf("Failed to build profile report: %v", err)
+	}
+	if report.SampleType != "cpu" || report.Unit != "nanoseconds" || report.Total != 100 || report.RepositoryFlat != 70 || report.Duration != "2s" {
+		t.Errorf("Unexpected report header: %+v", report)
+	}
+	var got []string
+	for _, hot := range report.Functions {
+		got = append(got, fmt.Sprintf("%s=%s:%d/%d", hot.Name, hot.Function, hot.Flat, hot.Cum))
+	}
+	want := "example.com/shop/cart.(*Cart).Total=cart.Cart.Total:60/60 runtime.mallocgc=:30/30 example.com/shop/cart.parse.func1=cart.parse:10/40 main.main=main.main:0/100"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Unexpected functions:\n got %s\nwant %s", strings.Join(got, " "), want)
+	}
+	hot := report.Functions[0]
+	if hot.Doc != "Total sums the items\n" || hot.Position == nil || hot.Position.Line != 7 || hot.FlatPercent != 60 || hot.Signature != "func (*Cart).Total() int" {
+		t.Errorf("Unexpected hot function: %+v", hot)
+	}
+	if !report.Functions[2].Closure || report.Functions[2].Position.Line != 15 {
+		t.Errorf("Expected the closure to map to parse, got %+v", report.Functions[2])
+	}
+	if main := report.Functions[3]; main.ImportPath != "example.com/shop/cmd/shop" {
+		t.Errorf("Expected main.main to map by its file, got %+v", main)
+	}
+
+	report, err = analyzer.ProfileReport(ctx, prof, ProfileOptions{SampleType: "samples", SortBy: ProfileSortCum, Limit: 2, RepoOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to build profile report: %v", err)
+	}
+	if len(report.Functions) != 2 || report.Functions[0].Function != "main.main" || report.Functions[1].Function != "cart.Cart.Total" || report.Functions[1].Cum != 6 || !report.Truncated {
+		t.Errorf("Unexpected repository functions by cumulative samples: %+v", report.Functions)
+	}
+
+	if _, err := analyzer.ProfileReport(ctx, prof, ProfileOptions{SampleType: "alloc_space"}); err == nil {
+		t.Error("Expected error for an unknown sample type")
+	}
+}
@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// How a search query matches a name, best first
+const (
+	MatchExact     = "exact"
+	MatchPrefix    = "prefix"
+	MatchSubstring = "substring"
+	MatchFuzzy     = "fuzzy"
+)
+
+// Orders search results can be sorted in
+const (
+	SortRelevance = "relevance"
+	SortName      = "name"
+	SortPackage   = "package"
+)
+
+// matchScores weighs the match kinds so that a better kind always outranks
+// the other criteria
+var matchScores = map[string]int{
+	MatchExact:     4000,
+	MatchPrefix:    3000,
+	MatchSubstring: 2000,
+	MatchFuzzy:     1000,
+}
+
+// TypeResult is a type found by SearchTypes with how well it matches the
+// query
+type TypeResult struct {
+	TypeInfo
+	Match string `json:"match"`
+	// Score ranks results, higher first: the match kind weighs most, then
+	// exported before unexported, then the main module before other
+	// modules, then names closer in length to the query
+	Score int `json:"score"`
+}
+
+// matchName reports how a lowercase query matches a name: exactly, as a
+// prefix or substring ignoring case, or fuzzily, with the query made of
+// prefixes of the name's words in order, as "hserv" matches HTTPServer
+func matchName(query, name string) (string, bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == query:
+		return MatchExact, true
+	case strings.HasPrefix(lower, query):
+		return MatchPrefix, true
+	case strings.Contains(lower, query):
+		return MatchSubstring, true
+	case matchWords(query, nameWords(name)):
+		return MatchFuzzy, true
+	}
+	return "", false
+}
+
+// matchScore scores a match of a query against a name
+func matchScore(match, query, name string, exported, mainModule bool) int {
+	score := matchScores[match]
+	if exported {
+		score += 200
+	}
+	if mainModule {
+		score += 100
+	}
+	// Listing every type ranks names alike whatever their length
+	if query != "" {
+		score -= min(len(name)-len(query), 99)
+	}
+	return score
+}
+
+// nameWords splits an identifier into its lowercase words at case changes,
+// digits and underscores, keeping acronyms whole: HTTPServer2 gives http,
+// server and 2
+func nameWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !wordBoundary(runes, i) {
+			continue
+		}
+		if word := strings.Trim(string(runes[start:i]), "_"); word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+		start = i
+	}
+	return words
+}
+
+// wordBoundary reports whether a new word starts at runes[i]
+func wordBoundary(runes []rune, i int) bool {
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case cur == '_' || prev == '_':
+		return true
+	case unicode.IsDigit(prev) != unicode.IsDigit(cur):
+		return true
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur):
+		// The last capital of an acronym starts the next word
+		return i+1 < len(runes) && unicode.IsLower(runes[i+1])
+	}
+	return false
+}
+
+// matchWords reports whether a query is made of non-empty prefixes of
+// words, taken in order and possibly skipping some
+func matchWords(query string, words []string) bool {
+	if query == "" {
+		return true
+	}
+	for i, word := range words {
+		n := 0
+		for n < len(query) && n < len(word) && query[n] == word[n] {
+			n++
+		}
+		for ; n > 0; n-- {
+			if matchWords(query[n:], words[i+1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SortTypeResults sorts search results by relevance, name or package.
+// Ties are broken by import path and name, so the order is deterministic.
+func SortTypeResults(results []TypeResult, order string) error {
+	byPackage := func(a, b TypeResult) bool {
+		if a.ImportPath != b.ImportPath {
+			return a.ImportPath < b.ImportPath
+		}
+		return a.Name < b.Name
+	}
+	var less func(a, b TypeResult) bool
+	switch order {
+	case SortRelevance, "":
+		less = func(a, b TypeResult) bool {
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return byPackage(a, b)
+		}
+	case SortName:
+		less = func(a, b TypeResult) bool {
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ImportPath < b.ImportPath
+		}
+	case SortPackage:
+		less = byPackage
+	default:
+		return fmt.Errorf("unknown sort order %q (expected %s, %s or %s)", order, SortRelevance, SortName, SortPackage)
+	}
+	sort.Slice(results, func(i, j int) bool { return less(results[i], results[j]) })
+	return nil
+}
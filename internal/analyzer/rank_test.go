@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchTypesRanking(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"go.work": "go 1.21\n\nuse (\n\t.\n\t./lib\n)\n",
+		"server/server.go": `package server
+
+type HTTPServer struct{}
+
+type Server struct{}
+
+type server struct{}
+
+type ServerConfig struct{}
+
+type FileServer struct{}
+`,
+		"lib/go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib/lib.go": `package lib
+
+type Server struct{}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	results, err := analyzer.SearchTypes(ctx, "server")
+	if err != nil {
+		t.Fatalf("SearchTypes failed: %v", err)
+	}
+	var got []string
+	for _, result := range results {
+		got = append(got, fmt.Sprintf("%s.%s:%s", result.Package, result.Name, result.Match))
+	}
+	want := []string{
+		"server.Server:exact",
+		"lib.Server:exact",
+		"server.server:exact",
+		"server.ServerConfig:prefix",
+		"server.FileServer:substring",
+		"server.HTTPServer:substring",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Unexpected ranking:\n got %v\nwant %v", got, want)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("Expected scores in decreasing order, got %d after %d", results[i].Score, results[i-1].Score)
+		}
+	}
+
+	results, err = analyzer.SearchTypes(ctx, "hserv")
+	if err != nil {
+		t.Fatalf("SearchTypes failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "HTTPServer" || results[0].Match != MatchFuzzy {
+		t.Errorf("Expected a fuzzy match of HTTPServer, got %+v", results)
+	}
+
+	results, _ = analyzer.SearchTypes(ctx, "server")
+	if err := SortTypeResults(results, SortPackage); err != nil {
+		t.Fatalf("Failed to sort by package: %v", err)
+	}
+	if results[0].ImportPath != "example.com/app/server" || results[0].Name != "FileServer" {
+		t.Errorf("Expected FileServer first by package, got %s.%s", results[0].ImportPath, results[0].Name)
+	}
+	if err := SortTypeResults(results, "size"); err == nil {
+		t.Error("Expected error for unknown sort order")
+	}
+}
+
+func TestNameWords(t *testing.T) {
+	tests := map[string]string{
+		"HTTPServer2":  "http server 2",
+		"fileServer":   "file server",
+		"snake_case":   "snake case",
+		"URL":          "url",
+		"parseJSONDoc": "parse json doc",
+	}
+	for name, want := range tests {
+		if got := strings.Join(nameWords(name), " "); got != want {
+			t.Errorf("nameWords(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RangeOptions select the lines ReadRange returns
+type RangeOptions struct {
+	// StartLine and EndLine are the 1-based inclusive range to read; an
+	// unset EndLine reads only StartLine
+	StartLine int
+	EndLine   int
+	// Before and After are the lines of context added on each side
+	Before int
+	After  int
+	// Snap widens the range of a Go file to the package-level
+	// declarations it overlaps, doc comments included, before context is
+	// added
+	Snap bool
+}
+
+// SourceRange is a range of lines of a file
+type SourceRange struct {
+	Filename   string `json:"filename"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	TotalLines int    `json:"total_lines"`
+	Text       string `json:"text"`
+	// Declarations are those the range was snapped to
+	Declarations []EnclosingDecl `json:"declarations,omitempty"`
+}
+
+// ReadRange returns lines of a file of the repository, relative to it or
+// absolute. Ranges past the end of the file are cut short.
+func (a *Analyzer) ReadRange(file string, opts RangeOptions) (*SourceRange, error) {
+	filename := a.absPath(file)
+	if rel, err := filepath.Rel(a.repoPath, filename); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is outside the repository", file)
+	}
+	if opts.StartLine < 1 {
+		return nil, fmt.Errorf("start line must be at least 1")
+	}
+	if opts.EndLine == 0 {
+		opts.EndLine = opts.StartLine
+	}
+	if opts.EndLine < opts.StartLine {
+		return nil, fmt.Errorf("end line %d is before start line %d", opts.EndLine, opts.StartLine)
+	}
+	if opts.Before < 0 || opts.After < 0 {
+		return nil, fmt.Errorf("context lines must not be negative")
+	}
+
+	src, err := a.readSource(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	lines := splitSourceLines(src)
+	if opts.StartLine > len(lines) {
+		return nil, fmt.Errorf("start line %d is past the end of %s (%d lines)", opts.StartLine, file, len(lines))
+	}
+
+	result := &SourceRange{Filename: filename, TotalLines: len(lines)}
+	start, end := opts.StartLine, min(opts.EndLine, len(lines))
+	if opts.Snap && strings.HasSuffix(filename, ".go") {
+		for _, decl := range parseDeclLines(filename, src).outermost() {
+			if decl.EndLine >= start && decl.StartLine <= end {
+				start, end = min(start, decl.StartLine), max(end, decl.EndLine)
+				result.Declarations = append(result.Declarations, decl)
+			}
+		}
+	}
+	result.StartLine = max(start-opts.Before, 1)
+	result.EndLine = min(end+opts.After, len(lines))
+	result.Text = strings.Join(lines[result.StartLine-1:result.EndLine], "")
+	return result, nil
+}
+
+// splitSourceLines splits src into lines, keeping their line endings. A
+// final line without one still counts.
+func splitSourceLines(src []byte) []string {
+	var lines []string
+	for len(src) > 0 {
+		i := bytes.IndexByte(src, '\n')
+		if i < 0 {
+			i = len(src) - 1
+		}
+		lines = append(lines, string(src[:i+1]))
+		src = src[i+1:]
+	}
+	return lines
+}
+
+// outermost returns the declarations no other declaration encloses, which
+// for a group is the whole group
+func (d *declLines) outermost() []EnclosingDecl {
+	var decls []EnclosingDecl
+	for i, decl := range d.decls {
+		enclosed := false
+		for j, other := range d.decls {
+			if i != j && other.StartLine <= decl.StartLine && decl.EndLine <= other.EndLine &&
+				(other.StartLine != decl.StartLine || other.EndLine != decl.EndLine || j > i) {
+				enclosed = true
+				break
+			}
+		}
+		if !enclosed {
+			decls = append(decls, decl)
+		}
+	}
+	return decls
+}
@@ -0,0 +1,94 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"shop.go": `package shop
+
+const (
+	// Small is a small size
+	Small = 1
+	Large = 2
+)
+
+// Total sums the sizes
+func Total(sizes []int) int {
+	sum := 0
+	for _, size := range sizes {
+		sum += size
+	}
+	return sum
+}
+`,
+		"notes.txt": "one\ntwo\nthree",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	tests := []struct {
+		name       string
+		file       string
+		opts       RangeOptions
+		start, end int
+		decls      []string
+	}{
+		{"single line", "shop.go", RangeOptions{StartLine: 12}, 12, 12, nil},
+		{"context", "shop.go", RangeOptions{StartLine: 12, EndLine: 13, Before: 1, After: 2}, 11, 15, nil},
+		{"clamped", "shop.go", RangeOptions{StartLine: 15, EndLine: 40, Before: 20}, 1, 16, nil},
+		{"snap to function with doc", "shop.go", RangeOptions{StartLine: 12, Snap: true}, 9, 16, []string{"Total"}},
+		{"snap to whole group", "shop.go", RangeOptions{StartLine: 5, Snap: true, After: 1}, 3, 8, []string{""}},
+		{"snap across declarations", "shop.go", RangeOptions{StartLine: 6, EndLine: 10, Snap: true}, 3, 16, []string{"", "Total"}},
+		{"snap outside declarations", "shop.go", RangeOptions{StartLine: 1, Snap: true}, 1, 1, nil},
+		{"no snapping outside Go", "notes.txt", RangeOptions{StartLine: 2, EndLine: 3, Snap: true}, 2, 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := analyzer.ReadRange(tt.file, tt.opts)
+			if err != nil {
+				t.Fatalf("ReadRange failed: %v", err)
+			}
+			if result.StartLine != tt.start || result.EndLine != tt.end {
+				t.Errorf("Expected lines %d-%d, got %d-%d", tt.start, tt.end, result.StartLine, result.EndLine)
+			}
+			lines := strings.SplitAfter(files[tt.file], "\n")
+			if want := strings.Join(lines[tt.start-1:tt.end], ""); result.Text != want {
+				t.Errorf("Expected text %q, got %q", want, result.Text)
+			}
+			var decls []string
+			for _, decl := range result.Declarations {
+				decls = append(decls, decl.Name)
+			}
+			if strings.Join(decls, ",") != strings.Join(tt.decls, ",") {
+				t.Errorf("Expected declarations %v, got %v", tt.decls, decls)
+			}
+		})
+	}
+
+	if result, _ := analyzer.ReadRange("notes.txt", RangeOptions{StartLine: 1}); result == nil || result.TotalLines != 3 {
+		t.Errorf("Expected 3 lines in notes.txt, got %+v", result)
+	}
+	for _, opts := range []RangeOptions{{StartLine: 0}, {StartLine: 5, EndLine: 4}, {StartLine: 17}, {StartLine: 1, Before: -1}} {
+		if _, err := analyzer.ReadRange("shop.go", opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+	if _, err := analyzer.ReadRange("../outside.go", RangeOptions{StartLine: 1}); err == nil || !strings.Contains(err.Error(), "outside the repository") {
+		t.Errorf("Expected an error for a file outside the repository, got %v", err)
+	}
+}
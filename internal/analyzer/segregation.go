@@ -0,0 +1,363 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InterfaceAnalysis shows which methods of an interface its consumers call
+// and suggests smaller interfaces along the lines they use it
+type InterfaceAnalysis struct {
+	Interface TypeRef              `json:"interface"`
+	Methods   []InterfaceMethodUse `json:"methods"`
+	Consumers []InterfaceConsumer  `json:"consumers"`
+	// Unused are the methods no consumer calls
+	Unused []string `json:"unused"`
+	// Splits are the suggested interfaces, empty when every consumer uses
+	// every method
+	Splits []InterfaceSplit `json:"splits"`
+	// Code declares the suggested interfaces in the interface's package
+	Code    string `json:"code,omitempty"`
+	Summary string `json:"summary"`
+}
+
+// InterfaceMethodUse counts the calls of one method of the interface
+type InterfaceMethodUse struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Consumers int    `json:"consumers"`
+	Calls     int    `json:"calls"`
+}
+
+// InterfaceConsumer is a function calling methods on values of the
+// interface type, or on type parameters it constrains
+type InterfaceConsumer struct {
+	// Function is pkg.Func or pkg.Type.Method; empty at package level
+	Function   string   `json:"function,omitempty"`
+	ImportPath string   `json:"import_path"`
+	Methods    []string `json:"methods"`
+	Calls      int      `json:"calls"`
+	// Position is that of the first call
+	Position Position `json:"position"`
+}
+
+// InterfaceSplit is a suggested interface: a group of methods always called
+// together, or the combination of groups some consumers need
+type InterfaceSplit struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods"`
+	// Embeds are the suggested interfaces a combination embeds
+	Embeds []string `json:"embeds,omitempty"`
+	// Consumers could take the suggested interface instead
+	Consumers []string `json:"consumers"`
+}
+
+// AnalyzeInterface finds the calls of an interface's methods through values
+// of the interface type in every package and groups the methods by the
+// consumers calling them. Methods called by the same consumers form one
+// suggested interface, and consumers needing several groups get an
+// interface embedding them, so that each consumer can ask for no more than
+// it uses. Implementations found through type assertions, such as
+// fmt.Stringer, are not consumers, so unused methods may still be needed.
+func (a *Analyzer) AnalyzeInterface(ctx context.Context, name string) (*InterfaceAnalysis, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok || !types.IsInterface(typeObj.Type()) {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	iface := typeObj.Type()
+	if named, ok := iface.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is generic; analyzing generic interfaces is not supported", name)
+	}
+	underlying := iface.Underlying().(*types.Interface)
+	if !underlying.IsMethodSet() {
+		return nil, fmt.Errorf("%s is a type constraint, not a method set", name)
+	}
+	if underlying.NumMethods() == 0 {
+		return nil, fmt.Errorf("%s has no methods", name)
+	}
+
+	qualifier := types.RelativeTo(typeObj.Pkg())
+	analysis := &InterfaceAnalysis{
+		Interface: typeRef(typeObj, false),
+		Methods:   []InterfaceMethodUse{},
+		Consumers: []InterfaceConsumer{},
+		Unused:    []string{},
+		Splits:    []InterfaceSplit{},
+	}
+	methods := make(map[string]*types.Func)
+	for i := range underlying.NumMethods() {
+		fn := underlying.Method(i)
+		methods[fn.Name()] = fn
+		analysis.Methods = append(analysis.Methods, InterfaceMethodUse{
+			Name:      fn.Name(),
+			Signature: fn.Name() + strings.TrimPrefix(types.TypeString(fn.Type(), qualifier), "func"),
+		})
+	}
+
+	consumers, calls, err := a.interfaceConsumers(ctx, iface, methods)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Consumers = consumers
+
+	// Group the methods by the consumers calling them
+	callers := make(map[string][]string)
+	for i, consumer := range consumers {
+		for _, method := range consumer.Methods {
+			callers[method] = append(callers[method], strconv.Itoa(i))
+		}
+	}
+	groups := make(map[string][]string)
+	var keys []string
+	for i := range analysis.Methods {
+		use := &analysis.Methods[i]
+		use.Consumers = len(callers[use.Name])
+		use.Calls = calls[use.Name]
+		if use.Consumers == 0 {
+			analysis.Unused = append(analysis.Unused, use.Name)
+			continue
+		}
+		key := strings.Join(callers[use.Name], "\x00")
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], use.Name)
+	}
+
+	switch {
+	case len(consumers) == 0:
+		analysis.Summary = fmt.Sprintf("No consumer calls the methods of %s.", typeObj.Name())
+		return analysis, nil
+	case len(groups) == 1 && len(analysis.Unused) == 0:
+		analysis.Summary = fmt.Sprintf("Every consumer of %s uses all %d of its methods; it is as small as its consumers allow.", typeObj.Name(), len(analysis.Methods))
+		return analysis, nil
+	}
+
+	a.suggestSplits(analysis, typeObj, groups, keys)
+	analysis.Code = a.splitCode(analysis, typeObj, methods, qualifier)
+	analysis.Summary = fmt.Sprintf("%d consumers use %d of the %d methods of %s in %d groups", len(consumers), len(analysis.Methods)-len(analysis.Unused), len(analysis.Methods), typeObj.Name(), len(groups))
+	if len(analysis.Unused) > 0 {
+		analysis.Summary += fmt.Sprintf("; no consumer calls %s", strings.Join(analysis.Unused, ", "))
+	}
+	analysis.Summary += "."
+	return analysis, nil
+}
+
+// interfaceConsumers finds the functions calling methods of iface on values
+// of its type or on type parameters it constrains, and counts the calls of
+// each method
+func (a *Analyzer) interfaceConsumers(ctx context.Context, iface types.Type, methods map[string]*types.Func) ([]InterfaceConsumer, map[string]int, error) {
+	consumers := []InterfaceConsumer{}
+	calls := make(map[string]int)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		info := a.infos[importPath]
+		if info == nil {
+			continue
+		}
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				consumer := InterfaceConsumer{ImportPath: importPath}
+				if fd, ok := decl.(*ast.FuncDecl); ok {
+					consumer.Function = fd.Name.Name
+					if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+						consumer.Function = funcName(fn)
+					}
+				}
+				called := make(map[string]bool)
+				ast.Inspect(decl, func(n ast.Node) bool {
+					sel, ok := n.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					selection := info.Selections[sel]
+					if selection == nil || selection.Kind() == types.FieldVal || !receives(selection.Recv(), iface) {
+						return true
+					}
+					if _, ok := methods[sel.Sel.Name]; !ok {
+						return true
+					}
+					if consumer.Calls == 0 {
+						consumer.Position = a.position(sel.Sel.Pos())
+					}
+					consumer.Calls++
+					calls[sel.Sel.Name]++
+					called[sel.Sel.Name] = true
+					return true
+				})
+				if consumer.Calls == 0 {
+					continue
+				}
+				for method := range called {
+					consumer.Methods = append(consumer.Methods, method)
+				}
+				sort.Strings(consumer.Methods)
+				consumers = append(consumers, consumer)
+			}
+		}
+	}
+	sort.SliceStable(consumers, func(i, j int) bool { return consumers[i].Function < consumers[j].Function })
+	return consumers, calls, nil
+}
+
+// name is the consumer's function, or its package for package-level code
+func (c InterfaceConsumer) name() string {
+	if c.Function == "" {
+		return c.ImportPath
+	}
+	return c.Function
+}
+
+// receives reports whether methods selected on recv are those of iface:
+// recv is iface, a pointer to it, or a type parameter it constrains
+func receives(recv, iface types.Type) bool {
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	if tp, ok := recv.(*types.TypeParam); ok {
+		return types.Identical(tp.Constraint(), iface)
+	}
+	return types.Identical(recv, iface)
+}
+
+// suggestSplits turns the method groups into suggested interfaces, with an
+// interface embedding the groups of each combination consumers need
+func (a *Analyzer) suggestSplits(analysis *InterfaceAnalysis, typeObj *types.TypeName, groups map[string][]string, keys []string) {
+	sort.Slice(keys, func(i, j int) bool { return groups[keys[i]][0] < groups[keys[j]][0] })
+	taken := make(map[string]bool)
+	name := func(methods []string) string {
+		return uniqueSplitName(splitName(typeObj.Name(), methods), typeObj.Pkg().Scope(), taken)
+	}
+
+	groupOf := make(map[string]int)
+	for i, key := range keys {
+		split := InterfaceSplit{Name: name(groups[key]), Methods: groups[key], Consumers: []string{}}
+		for _, method := range split.Methods {
+			groupOf[method] = i
+		}
+		analysis.Splits = append(analysis.Splits, split)
+	}
+
+	// Consumers take the group of their methods, or a combination
+	combinations := make(map[string]int)
+	for _, consumer := range analysis.Consumers {
+		var used []int
+		for _, method := range consumer.Methods {
+			if g := groupOf[method]; !slices.Contains(used, g) {
+				used = append(used, g)
+			}
+		}
+		sort.Ints(used)
+		if len(used) == 1 {
+			analysis.Splits[used[0]].Consumers = append(analysis.Splits[used[0]].Consumers, consumer.name())
+			continue
+		}
+		key := fmt.Sprint(used)
+		i, ok := combinations[key]
+		if !ok {
+			split := InterfaceSplit{Consumers: []string{}}
+			for _, g := range used {
+				split.Embeds = append(split.Embeds, analysis.Splits[g].Name)
+				split.Methods = append(split.Methods, analysis.Splits[g].Methods...)
+			}
+			sort.Strings(split.Methods)
+			if len(split.Methods) == len(analysis.Methods) {
+				// The interface itself, declared by embedding its groups
+				split.Name = typeObj.Name()
+			} else {
+				split.Name = name(split.Methods)
+			}
+			i = len(analysis.Splits)
+			combinations[key] = i
+			analysis.Splits = append(analysis.Splits, split)
+		}
+		analysis.Splits[i].Consumers = append(analysis.Splits[i].Consumers, consumer.name())
+	}
+}
+
+// splitName names a suggested interface the Go way after up to three of its
+// methods (Read and Close: ReadCloser), or else after the interface
+func splitName(iface string, methods []string) string {
+	if len(methods) > 3 {
+		return iface + "Core"
+	}
+	var b strings.Builder
+	for _, method := range methods[:len(methods)-1] {
+		b.WriteString(method)
+	}
+	b.WriteString(erName(methods[len(methods)-1]))
+	return b.String()
+}
+
+// uniqueSplitName numbers a name when the package already declares it or
+// another suggestion took it
+func uniqueSplitName(name string, scope *types.Scope, taken map[string]bool) string {
+	unique := name
+	for n := 2; taken[unique] || scope.Lookup(unique) != nil; n++ {
+		unique = name + strconv.Itoa(n)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// splitCode declares the suggested interfaces with the methods' doc comments
+func (a *Analyzer) splitCode(analysis *InterfaceAnalysis, typeObj *types.TypeName, methods map[string]*types.Func, qualifier types.Qualifier) string {
+	var code strings.Builder
+	for i, split := range analysis.Splits {
+		if i > 0 {
+			code.WriteString("\n")
+		}
+		users := "no consumer on its own"
+		if len(split.Consumers) > 0 {
+			users = strings.Join(split.Consumers, ", ")
+		}
+		if split.Name == typeObj.Name() {
+			fmt.Fprintf(&code, "// %s combines the interfaces above for %s\n", split.Name, users)
+		} else {
+			fmt.Fprintf(&code, "// %s is the part of %s used by %s\n", split.Name, typeObj.Name(), users)
+		}
+		fmt.Fprintf(&code, "type %s interface {\n", split.Name)
+		if split.Embeds != nil {
+			for _, embed := range split.Embeds {
+				fmt.Fprintf(&code, "\t%s\n", embed)
+			}
+			code.WriteString("}\n")
+			continue
+		}
+		for j, method := range split.Methods {
+			if j > 0 {
+				code.WriteString("\n")
+			}
+			fn := methods[method]
+			if doc := strings.TrimSpace(a.funcDoc(fn)); doc != "" {
+				for _, line := range strings.Split(doc, "\n") {
+					code.WriteString(strings.TrimRight("\t// "+line, " ") + "\n")
+				}
+			}
+			fmt.Fprintf(&code, "\t%s%s\n", fn.Name(), strings.TrimPrefix(types.TypeString(fn.Type(), qualifier), "func"))
+		}
+		code.WriteString("}\n")
+	}
+	return code.String()
+}
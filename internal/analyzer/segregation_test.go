@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeInterface(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/store\n\ngo 1.21\n",
+		"store.go": `package store
+
+// Store persists values
+type Store interface {
+	// Get returns a value
+	Get(key string) (string, error)
+	Put(key, value string) error
+	Delete(key string) error
+	Close() error
+}
+
+// Cache is used whole
+type Cache interface {
+	Get(key string) string
+	Len() int
+}
+
+func Lookup(s Store, key string) string {
+	v, _ := s.Get(key)
+	return v
+}
+
+func Show(s Store) {
+	s.Get("a")
+	s.Get("b")
+}
+
+func Save(s Store) error {
+	if err := s.Put("a", "b"); err != nil {
+		return err
+	}
+	return s.Delete("c")
+}
+
+func Sync[T Store](s T) {
+	s.Get("x")
+	s.Put("x", "y")
+	s.Delete("x")
+}
+
+func Size(c Cache) int {
+	c.Get("a")
+	return c.Len()
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	analysis, err := analyzer.AnalyzeInterface(ctx, "Store")
+	if err != nil {
+		t.Fatalf("Failed to analyze interface: %v", err)
+	}
+	var consumers []string
+	for _, consumer := range analysis.Consumers {
+		consumers = append(consumers, consumer.Function+":"+strings.Join(consumer.Methods, ","))
+	}
+	if got := strings.Join(consumers, " "); got != "store.Lookup:Get store.Save:Delete,Put store.Show:Get store.Sync:Delete,Get,Put" {
+		t.Errorf("Unexpected consumers: %s", got)
+	}
+	calls := make(map[string]int)
+	for _, method := range analysis.Methods {
+		calls[method.Name] = method.Calls
+	}
+	if calls["Get"] != 4 || calls["Put"] != 2 || calls["Delete"] != 2 || calls["Close"] != 0 {
+		t.Errorf("Unexpected call counts: %v", calls)
+	}
+	if strings.Join(analysis.Unused, ",") != "Close" {
+		t.Errorf("Expected Close to be unused, got %v", analysis.Unused)
+	}
+
+	var splits []string
+	for _, split := range analysis.Splits {
+		splits = append(splits, split.Name+"("+strings.Join(split.Methods, ",")+")="+strings.Join(split.Consumers, ","))
+	}
+	if got := strings.Join(splits, " "); got != "DeletePutter(Delete,Put)=store.Save Getter(Get)=store.Lookup,store.Show DeleteGetPutter(Delete,Get,Put)=store.Sync" {
+		t.Errorf("Unexpected splits: %s", got)
+	}
+	if embeds := analysis.Splits[2].Embeds; strings.Join(embeds, ",") != "DeletePutter,Getter" {
+		t.Errorf("Expected the combination to embed both groups, got %v", embeds)
+	}
+	for _, want := range []string{
+		"// Getter is the part of Store used by store.Lookup, store.Show\ntype Getter interface {\n\t// Get returns a value\n\tGet(key string) (string, error)\n}\n",
+		"type DeleteGetPutter interface {\n\tDeletePutter\n\tGetter\n}\n",
+	} {
+		if !strings.Contains(analysis.Code, want) {
+			t.Errorf("Expected code to contain %q, got:\n%s", want, analysis.Code)
+		}
+	}
+
+	// An interface every consumer uses whole needs no split
+	analysis, err = analyzer.AnalyzeInterface(ctx, "Cache")
+	if err != nil {
+		t.Fatalf("Failed to analyze interface: %v", err)
+	}
+	if len(analysis.Splits) != 0 || analysis.Code != "" || !strings.Contains(analysis.Summary, "uses all 2") {
+		t.Errorf("Expected no split of Cache, got %+v", analysis)
+	}
+
+	if _, err := analyzer.AnalyzeInterface(ctx, "Lookup"); err == nil {
+		t.Error("Expected error for a function")
+	}
+}
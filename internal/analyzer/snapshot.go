@@ -0,0 +1,375 @@
+package analyzer
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is a precomputed analysis of a repository that can be written to
+// disk, e.g. by CI, and loaded by a server so it answers queries before its
+// own analysis has finished. File paths are stored relative to the
+// repository so a snapshot can be loaded from a different checkout.
+type Snapshot struct {
+	// Version is the SchemaVersion of the analyzer that wrote the snapshot
+	Version  int             `json:"version"`
+	RepoPath string          `json:"repo_path"`
+	Created  time.Time       `json:"created"`
+	Result   *AnalysisResult `json:"result"`
+	// Index maps type names to their positions in Result.Types
+	Index map[string][]int `json:"index"`
+}
+
+// Snapshot captures the current analysis of the repository
+func (a *Analyzer) Snapshot(ctx context.Context) (*Snapshot, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	loading := !a.initialized && a.snapshot != nil
+	a.mu.RUnlock()
+	if loading {
+		return nil, fmt.Errorf("analysis is still loading from a snapshot")
+	}
+
+	result, err := a.AnalyzeRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Version:  SchemaVersion,
+		RepoPath: a.RepoPath(),
+		Created:  time.Now().UTC(),
+		Result:   result,
+		Index:    make(map[string][]int),
+	}
+	for i, typeInfo := range result.Types {
+		snap.Index[typeInfo.Name] = append(snap.Index[typeInfo.Name], i)
+	}
+	snap.mapPaths(func(path string) string {
+		if rel, err := filepath.Rel(snap.RepoPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	})
+	return snap, nil
+}
+
+// WriteFile writes the snapshot to path as gzip-compressed JSON
+func (s *Snapshot) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	if err := s.Encode(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Encode writes the snapshot to w as gzip-compressed JSON
+func (s *Snapshot) Encode(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot loads a snapshot written by WriteFile. Snapshots written by
+// an analyzer with a different SchemaVersion are rejected.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer file.Close()
+	return DecodeSnapshot(file)
+}
+
+// DecodeSnapshot reads a snapshot written by Encode
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	defer zr.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snap.Version != SchemaVersion {
+		return nil, fmt.Errorf("snapshot has schema version %d, expected %d", snap.Version, SchemaVersion)
+	}
+	if snap.Result == nil {
+		return nil, fmt.Errorf("snapshot has no analysis result")
+	}
+	return &snap, nil
+}
+
+// Clone returns a deep copy of the snapshot
+func (s *Snapshot) Clone() (*Snapshot, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	var clone Snapshot
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	return &clone, nil
+}
+
+// NewAnalyzerFromSnapshot creates an Analyzer that answers type, method,
+// search and package queries from snap right away while the repository is
+// analyzed in the background. Other queries fail as not initialized until
+// that analysis completes, after which the snapshot is dropped.
+func NewAnalyzerFromSnapshot(repoPath string, config *Config, snap *Snapshot) (*Analyzer, error) {
+	analyzer, err := NewStandbyAnalyzer(repoPath, config, snap)
+	if err != nil {
+		return nil, err
+	}
+	analyzer.Promote()
+	return analyzer, nil
+}
+
+// NewStandbyAnalyzer creates an Analyzer that only serves snap, e.g. one
+// replicated from another server, until Promote starts the analysis of the
+// repository. The snapshot can be replaced with UpdateSnapshot meanwhile.
+func NewStandbyAnalyzer(repoPath string, config *Config, snap *Snapshot) (*Analyzer, error) {
+	analyzer, err := newAnalyzer(repoPath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer.snapshot = analyzer.localSnapshot(snap)
+	analyzer.lastChange = snap.Created
+	analyzer.logInfo("Serving snapshot", "created", snap.Created.Format(time.RFC3339), "repo", analyzer.repoPath)
+	return analyzer, nil
+}
+
+// UpdateSnapshot replaces the snapshot a standby analyzer serves. It returns
+// false once the analyzer has its own analysis, which is never replaced.
+func (a *Analyzer) UpdateSnapshot(snap *Snapshot) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.initialized || a.snapshot == nil {
+		return false
+	}
+	a.snapshot = a.localSnapshot(snap)
+	a.lastChange = time.Now()
+	return true
+}
+
+// Promote starts analyzing the repository in the background; until that
+// completes, queries are still answered from the snapshot. Calling it more
+// than once has no effect.
+func (a *Analyzer) Promote() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.initialized || a.promoted {
+		return
+	}
+	a.promoted = true
+	a.logInfo("Analyzing in the background", "repo", a.repoPath)
+	go a.analyzeInBackground()
+}
+
+// localSnapshot resolves the repository-relative paths of snap against the
+// analyzer's repository
+func (a *Analyzer) localSnapshot(snap *Snapshot) *Snapshot {
+	snap.mapPaths(func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(a.repoPath, filepath.FromSlash(path))
+	})
+	return snap
+}
+
+// analyzeInBackground analyzes the repository without holding the lock and
+// then swaps the results in, unless a refresh got there first
+func (a *Analyzer) analyzeInBackground() {
+	fresh, err := newAnalyzer(a.repoPath, a.config)
+	if err == nil {
+		fresh.overlay = a.overlay
+		err = fresh.initialize(context.Background())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err != nil {
+		a.logWarn("Background analysis failed, still serving the snapshot", "error", err)
+		return
+	}
+	if a.initialized || a.snapshot == nil {
+		return
+	}
+
+	a.adopt(fresh)
+	// Results computed from the snapshot may differ from the live analysis
+	a.lastChange = time.Now()
+}
+
+// mapPaths rewrites every file path in the snapshot
+func (s *Snapshot) mapPaths(fn func(string) string) {
+	position := func(pos *Position) {
+		if pos.Filename != "" {
+			pos.Filename = fn(pos.Filename)
+		}
+	}
+
+	result := s.Result
+	for i := range result.Types {
+		typeInfo := &result.Types[i]
+		position(&typeInfo.Position)
+		for j := range typeInfo.Methods {
+			position(&typeInfo.Methods[j].Position)
+			for _, embedded := range typeInfo.Methods[j].Embedding {
+				if embedded.Position != nil {
+					position(embedded.Position)
+				}
+			}
+		}
+		for j := range typeInfo.Fields {
+			position(&typeInfo.Fields[j].Position)
+		}
+	}
+	for i := range result.Functions {
+		position(&result.Functions[i].Position)
+	}
+	for i := range result.Variables {
+		position(&result.Variables[i].Position)
+	}
+	for i := range result.Constants {
+		position(&result.Constants[i].Position)
+	}
+	for i := range result.Imports {
+		position(&result.Imports[i].Position)
+	}
+	for i := range result.Packages {
+		position(&result.Packages[i].Position)
+		for j, file := range result.Packages[i].Files {
+			result.Packages[i].Files[j] = fn(file)
+		}
+		for j := range result.Packages[i].Embeds {
+			position(&result.Packages[i].Embeds[j].Position)
+		}
+	}
+	for i := range result.Errors {
+		position(&result.Errors[i].Position)
+	}
+	for i := range result.Warnings {
+		position(&result.Warnings[i].Position)
+	}
+}
+
+// lookupType resolves a possibly qualified type name like Analyzer.LookupType
+func (s *Snapshot) lookupType(name string) (*TypeInfo, error) {
+	qualifier, ident := splitQualifiedName(name)
+
+	var matches []int
+	for _, i := range s.Index[ident] {
+		typeInfo := s.Result.Types[i]
+		if matchesQualifier(qualifier, typeInfo.ImportPath, typeInfo.Package) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("type %s not found", name)
+	case 1:
+		typeInfo := s.Result.Types[matches[0]]
+		return &typeInfo, nil
+	}
+
+	// Exact import path qualifiers always win over name or suffix matches
+	candidates := make([]string, len(matches))
+	for j, i := range matches {
+		typeInfo := s.Result.Types[i]
+		if typeInfo.ImportPath == qualifier {
+			return &typeInfo, nil
+		}
+		candidates[j] = typeInfo.ImportPath + "." + ident
+	}
+	sort.Strings(candidates)
+	return nil, &AmbiguousError{Name: name, Candidates: candidates}
+}
+
+// searchTypes matches the snapshot's types like Analyzer.SearchTypes
+func (s *Snapshot) searchTypes(query, mainModule string) []TypeResult {
+	qualifier, ident := splitQualifiedName(query)
+	ident = strings.ToLower(ident)
+
+	modules := make(map[string]string, len(s.Result.Packages))
+	for _, pkg := range s.Result.Packages {
+		modules[pkg.ImportPath] = pkg.Module
+	}
+	var results []TypeResult
+	for _, typeInfo := range s.Result.Types {
+		if !matchesQualifier(qualifier, typeInfo.ImportPath, typeInfo.Package) {
+			continue
+		}
+		if match, ok := matchName(ident, typeInfo.Name); ok {
+			results = append(results, TypeResult{
+				TypeInfo: typeInfo,
+				Match:    match,
+				Score:    matchScore(match, ident, typeInfo.Name, typeInfo.Exported, modules[typeInfo.ImportPath] == mainModule),
+			})
+		}
+	}
+	SortTypeResults(results, SortRelevance)
+	return results
+}
+
+// packageInfo resolves a package like Analyzer.GetPackageInfo
+func (s *Snapshot) packageInfo(packageName string) (*PackageInfo, error) {
+	var matches []PackageInfo
+	for _, pkg := range s.Result.Packages {
+		if pkg.ImportPath == packageName {
+			return &pkg, nil
+		}
+		if matchesQualifier(packageName, pkg.ImportPath, pkg.Name) {
+			matches = append(matches, pkg)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("package %s not found", packageName)
+	case 1:
+		return &matches[0], nil
+	}
+	candidates := make([]string, len(matches))
+	for i, pkg := range matches {
+		candidates[i] = pkg.ImportPath
+	}
+	return nil, &AmbiguousError{Name: packageName, Candidates: candidates}
+}
+
+// packagePaths returns the import paths of the snapshot's packages
+func (s *Snapshot) packagePaths() []string {
+	paths := make([]string, len(s.Result.Packages))
+	for i, pkg := range s.Result.Packages {
+		paths[i] = pkg.ImportPath
+	}
+	sort.Strings(paths)
+	return paths
+}
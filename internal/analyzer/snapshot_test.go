@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `// Package store persists records.
+package store
+
+// Record is a stored record
+type Record struct {
+	ID int
+}
+
+// Key returns the record key
+func (r Record) Key() int { return r.ID }
+`,
+		"web/web.go": `package web
+
+// Record is a rendered record
+type Record struct{}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	snap, err := analyzer.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	if len(snap.Index["Record"]) != 2 {
+		t.Errorf("Expected two indexed Record types, got %v", snap.Index["Record"])
+	}
+	for _, typeInfo := range snap.Result.Types {
+		if filepath.IsAbs(typeInfo.Position.Filename) {
+			t.Errorf("Expected repository-relative paths, got %s", typeInfo.Position.Filename)
+		}
+	}
+
+	snapPath := filepath.Join(t.TempDir(), "snapshot.json.gz")
+	if err := snap.WriteFile(snapPath); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+	loaded, err := ReadSnapshot(snapPath)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	t.Run("ServeFromSnapshot", func(t *testing.T) {
+		// Load the snapshot without starting the background analysis, as if
+		// it were still running
+		otherDir := t.TempDir()
+		served, err := newAnalyzer(otherDir, nil)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		loaded.mapPaths(func(path string) string { return filepath.Join(otherDir, path) })
+		served.snapshot = loaded
+
+		info, err := served.LookupType(context.Background(), "store.Record")
+		if err != nil {
+			t.Fatalf("Failed to look up type from snapshot: %v", err)
+		}
+		if len(info.Methods) != 1 || info.Doc == "" {
+			t.Errorf("Expected documented type with one method, got %+v", info)
+		}
+		if want := filepath.Join(otherDir, "store", "store.go"); info.Position.Filename != want {
+			t.Errorf("Expected position in %s, got %s", want, info.Position.Filename)
+		}
+
+		var ambiguous *AmbiguousError
+		if _, err := served.LookupType(context.Background(), "Record"); !errors.As(err, &ambiguous) {
+			t.Errorf("Expected AmbiguousError, got %v", err)
+		}
+		if methods, err := served.ListMethods(context.Background(), "example.com/app/store.Record"); err != nil || len(methods) != 1 {
+			t.Errorf("Expected one method, got %v (%v)", methods, err)
+		}
+		if results, _ := served.SearchTypes(context.Background(), "web.rec"); len(results) != 1 {
+			t.Errorf("Expected one search result, got %v", results)
+		}
+		if pkg, err := served.GetPackageInfo(context.Background(), "store"); err != nil || pkg.ImportPath != "example.com/app/store" {
+			t.Errorf("Unexpected package info %+v (%v)", pkg, err)
+		}
+		if paths := served.Packages(); len(paths) != 2 {
+			t.Errorf("Expected two packages, got %v", paths)
+		}
+		if _, err := served.TypeHierarchy(context.Background(), "store.Record"); err == nil {
+			t.Error("Expected error for queries the snapshot cannot answer")
+		}
+		if _, err := served.Snapshot(context.Background()); err == nil {
+			t.Error("Expected error snapshotting a snapshot")
+		}
+	})
+
+	t.Run("BackgroundAnalysis", func(t *testing.T) {
+		reloaded, err := ReadSnapshot(snapPath)
+		if err != nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		served, err := NewAnalyzerFromSnapshot(tmpDir, nil, reloaded)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer from snapshot: %v", err)
+		}
+		defer served.Close()
+
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			if _, err := served.TypeHierarchy(context.Background(), "store.Record"); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Background analysis did not complete")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("SchemaVersion", func(t *testing.T) {
+		stale := *snap
+		stale.Version = SchemaVersion - 1
+		path := filepath.Join(t.TempDir(), "stale.json.gz")
+		if err := stale.WriteFile(path); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		if _, err := ReadSnapshot(path); err == nil {
+			t.Error("Expected error for snapshot with another schema version")
+		}
+	})
+}
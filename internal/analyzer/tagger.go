@@ -0,0 +1,356 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Tagger labels types so clients can filter results by tag. Taggers run on
+// every type once per analysis; the tags they return appear in
+// TypeInfo.Tags keyed by tag name, with a short reason as the value.
+type Tagger interface {
+	Tags(decl *TypeDecl) map[string]string
+}
+
+// TaggerFunc adapts a function to the Tagger interface
+type TaggerFunc func(decl *TypeDecl) map[string]string
+
+// Tags calls f
+func (f TaggerFunc) Tags(decl *TypeDecl) map[string]string {
+	return f(decl)
+}
+
+// TypeDecl describes a type declaration to taggers
+type TypeDecl struct {
+	Name       string
+	Package    string
+	ImportPath string
+	// File is the declaring file relative to the repository, with forward slashes
+	File string
+	// Doc is the text of the type's doc comment
+	Doc string
+	// BuildTags are the tags named by the file's //go:build constraint
+	BuildTags []string
+	// Generated is the file's "Code generated ... DO NOT EDIT." header, or
+	// why its name marks it as generated; empty for hand-written files
+	Generated string
+}
+
+// BuiltinTaggers tag types as "generated", "deprecated", "test-only" and
+// "experimental". They run for every analyzer.
+var BuiltinTaggers = []Tagger{
+	TaggerFunc(tagGenerated),
+	TaggerFunc(tagDeprecated),
+	TaggerFunc(tagTestOnly),
+	TaggerFunc(tagExperimental),
+}
+
+// tagGenerated tags types declared in generated files
+func tagGenerated(decl *TypeDecl) map[string]string {
+	if decl.Generated == "" {
+		return nil
+	}
+	return map[string]string{"generated": decl.Generated}
+}
+
+// tagDeprecated tags types whose doc comment has a "Deprecated:" paragraph
+func tagDeprecated(decl *TypeDecl) map[string]string {
+	if reason, ok := docParagraph(decl.Doc, "Deprecated:"); ok {
+		return map[string]string{"deprecated": reason}
+	}
+	return nil
+}
+
+// tagTestOnly tags types declared in test files or under testdata
+func tagTestOnly(decl *TypeDecl) map[string]string {
+	switch {
+	case strings.HasSuffix(decl.File, "_test.go"):
+		return map[string]string{"test-only": "declared in a test file"}
+	case strings.HasPrefix(decl.File, "testdata/") || strings.Contains(decl.File, "/testdata/"):
+		return map[string]string{"test-only": "declared under testdata"}
+	}
+	return nil
+}
+
+// tagExperimental tags types behind an experimental build tag or whose doc
+// comment has an "Experimental:" paragraph
+func tagExperimental(decl *TypeDecl) map[string]string {
+	for _, tag := range decl.BuildTags {
+		if strings.Contains(strings.ToLower(tag), "experimental") {
+			return map[string]string{"experimental": "build tag " + tag}
+		}
+	}
+	if reason, ok := docParagraph(decl.Doc, "Experimental:"); ok {
+		return map[string]string{"experimental": reason}
+	}
+	return nil
+}
+
+// docParagraph finds the paragraph of a doc comment starting with marker,
+// ignoring case, and returns the rest of it on one line
+func docParagraph(doc, marker string) (string, bool) {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if len(paragraph) < len(marker) || !strings.EqualFold(paragraph[:len(marker)], marker) {
+			continue
+		}
+		rest := strings.Join(strings.Fields(paragraph[len(marker):]), " ")
+		if rest == "" {
+			rest = strings.ToLower(strings.TrimSuffix(marker, ":"))
+		}
+		return rest, true
+	}
+	return "", false
+}
+
+// TagRule is a tagger defined in a repository's tag config. It tags the
+// types whose file matches Path and whose name matches Pattern; a rule
+// needs at least one of them.
+type TagRule struct {
+	Tag string `json:"tag"`
+	// Path is a path.Match pattern matched against the declaring file and
+	// each of its parent directories, relative to the repository
+	Path string `json:"path,omitempty"`
+	// Pattern is a path.Match pattern matched against the type name, bare
+	// or qualified with its package name
+	Pattern string `json:"pattern,omitempty"`
+	// Reason is the tag's value; it defaults to the rule's patterns
+	Reason string `json:"reason,omitempty"`
+}
+
+// TagConfig is the content of a repository's tag config file
+type TagConfig struct {
+	Taggers []TagRule `json:"taggers"`
+}
+
+// TagConfigPath returns the location of a repository's tag config
+func TagConfigPath(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "tags.json")
+}
+
+// LoadTagRules reads the rules of a tag config. It returns nil without an
+// error when the file does not exist.
+func LoadTagRules(configPath string) ([]TagRule, error) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag config: %w", err)
+	}
+
+	var config TagConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse tag config: %w", err)
+	}
+	for i, rule := range config.Taggers {
+		if rule.Tag == "" {
+			return nil, fmt.Errorf("tag config rule %d has no tag", i+1)
+		}
+		if rule.Path == "" && rule.Pattern == "" {
+			return nil, fmt.Errorf("tag config rule %q needs a path or a pattern", rule.Tag)
+		}
+		for _, pattern := range []string{rule.Path, rule.Pattern} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("tag config rule %q has an invalid pattern %q", rule.Tag, pattern)
+			}
+		}
+	}
+	return config.Taggers, nil
+}
+
+// Tags tags decl if it matches the rule
+func (r TagRule) Tags(decl *TypeDecl) map[string]string {
+	if r.Path != "" && !matchesPathPattern(r.Path, decl.File) {
+		return nil
+	}
+	if r.Pattern != "" {
+		bare, _ := path.Match(r.Pattern, decl.Name)
+		qualified, _ := path.Match(r.Pattern, decl.Package+"."+decl.Name)
+		if !bare && !qualified {
+			return nil
+		}
+	}
+
+	reason := r.Reason
+	if reason == "" {
+		var matched []string
+		if r.Path != "" {
+			matched = append(matched, "path "+r.Path)
+		}
+		if r.Pattern != "" {
+			matched = append(matched, "pattern "+r.Pattern)
+		}
+		reason = "matches " + strings.Join(matched, " and ")
+	}
+	return map[string]string{r.Tag: reason}
+}
+
+// matchesPathPattern reports whether pattern matches file or one of its
+// parent directories
+func matchesPathPattern(pattern, file string) bool {
+	for current := file; current != "." && current != "/"; current = path.Dir(current) {
+		if ok, _ := path.Match(pattern, current); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTags reports whether tags has every tag of include and none of
+// exclude. Tag names match keys exactly, so plugin tags are selected by
+// their "<plugin>.<key>" name.
+func HasTags(tags map[string]string, include, exclude []string) bool {
+	for _, tag := range include {
+		if _, ok := tags[tag]; !ok {
+			return false
+		}
+	}
+	for _, tag := range exclude {
+		if _, ok := tags[tag]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// activeTaggers returns the built-in taggers, those of the repository's tag
+// config and those of the analyzer's configuration. A broken tag config is
+// logged and skipped.
+func (a *Analyzer) activeTaggers() []Tagger {
+	taggers := append([]Tagger{}, BuiltinTaggers...)
+	rules, err := LoadTagRules(TagConfigPath(a.repoPath))
+	if err != nil {
+		a.logWarn("Ignoring tag config", "error", err)
+	}
+	for _, rule := range rules {
+		taggers = append(taggers, rule)
+	}
+	return append(taggers, a.config.Taggers...)
+}
+
+// generatedHeader matches the comment marking a generated file, see
+// https://go.dev/s/generatedcode
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// fileFacts is what taggers need to know about a file
+type fileFacts struct {
+	buildTags []string
+	generated string
+}
+
+// factsOf extracts the build tags and generated header of a file
+func factsOf(file *ast.File) fileFacts {
+	var facts fileFacts
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if facts.generated == "" && generatedHeader.MatchString(comment.Text) {
+				facts.generated = strings.TrimPrefix(comment.Text, "// ")
+			}
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+			expr.Eval(func(tag string) bool {
+				facts.buildTags = append(facts.buildTags, tag)
+				return false
+			})
+		}
+	}
+	return facts
+}
+
+// typeDocs returns the doc comments of the types declared in file by name
+func typeDocs(file *ast.File) map[string]string {
+	docs := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+				docs[typeSpec.Name.Name] = specDoc(gen, spec).Text()
+			}
+		}
+	}
+	return docs
+}
+
+// runTaggers tags every indexed type with the active taggers. Tagger panics
+// are logged and do not fail the analysis.
+func (a *Analyzer) runTaggers(ctx context.Context) error {
+	taggers := a.activeTaggers()
+
+	type parsedFile struct {
+		facts fileFacts
+		docs  map[string]string
+	}
+	files := make(map[string]*parsedFile)
+	for importPath, asts := range a.asts {
+		for _, file := range asts {
+			name := a.fset.Position(file.Package).Filename
+			facts := factsOf(file)
+			facts.generated = generatedReason(name, facts)
+			files[name] = &parsedFile{facts: facts, docs: typeDocs(file)}
+		}
+		if a.tags[importPath] == nil {
+			a.tags[importPath] = &packageTags{}
+		}
+	}
+
+	for _, sym := range a.index.types {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		filename := a.fset.Position(sym.obj.Pos()).Filename
+		file := files[filename]
+		if file == nil {
+			continue
+		}
+		rel, err := filepath.Rel(a.repoPath, filename)
+		if err != nil {
+			rel = filename
+		}
+		decl := &TypeDecl{
+			Name:       sym.obj.Name(),
+			Package:    sym.obj.Pkg().Name(),
+			ImportPath: sym.importPath,
+			File:       filepath.ToSlash(rel),
+			Doc:        file.docs[sym.obj.Name()],
+			BuildTags:  file.facts.buildTags,
+			Generated:  file.facts.generated,
+		}
+		for _, tagger := range taggers {
+			for tag, reason := range a.callTagger(tagger, decl) {
+				a.tags[sym.importPath].set(decl.Name, tag, reason)
+			}
+		}
+	}
+	return nil
+}
+
+// callTagger runs one tagger, logging and dropping its result if it panics
+func (a *Analyzer) callTagger(tagger Tagger, decl *TypeDecl) (tags map[string]string) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.logWarn("Tagger failed", "package", decl.ImportPath, "symbol", decl.Name, "panic", r)
+			tags = nil
+		}
+	}()
+	return tagger.Tags(decl)
+}
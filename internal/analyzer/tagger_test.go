@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTaggers(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"api/api.go": `package api
+
+// Client talks to the server.
+//
+// Deprecated: use NewClient
+// instead.
+type Client struct{}
+
+// Request is sent by Client
+type Request struct{}
+
+// Handle is a plain type
+type Handle int
+`,
+		"api/api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n\ntype Message struct{}\n",
+		"api/beta.go": `//go:build linux && scope_experimental
+
+package api
+
+type Beta struct{}
+
+// Preview is new.
+//
+// Experimental: may change without notice.
+type Preview struct{}
+`,
+		"api/api_test.go":              "package api\n\ntype fakeClient struct{}\n",
+		"legacy/old/old.go":            "package old\n\ntype Old struct{}\n",
+		".scope/tags.json":             `{"taggers": [{"tag": "legacy", "path": "legacy/*"}, {"tag": "dto", "pattern": "api.*Request", "reason": "wire type"}]}`,
+		"internal/testdata/fixture.go": "package testdata\n\ntype Fixture struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.IncludeTests = true
+	config.Taggers = []Tagger{TaggerFunc(func(decl *TypeDecl) map[string]string {
+		if decl.Name == "Handle" {
+			return map[string]string{"custom": decl.File}
+		}
+		return nil
+	})}
+	a, err := NewAnalyzerWithConfig(tmpDir, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	tests := map[string]map[string]string{
+		"api.Client":       {"deprecated": "use NewClient instead."},
+		"api.Request":      {"dto": "wire type"},
+		"api.Handle":       {"custom": "api/api.go"},
+		"api.Message":      {"generated": "Code generated by protoc-gen-go. DO NOT EDIT."},
+		"api.Beta":         {"experimental": "build tag scope_experimental"},
+		"api.Preview":      {"experimental": "build tag scope_experimental"},
+		"api.fakeClient":   {"test-only": "declared in a test file"},
+		"old.Old":          {"legacy": "matches path legacy/*"},
+		"testdata.Fixture": {"test-only": "declared under testdata"},
+	}
+	for name, want := range tests {
+		info, err := a.LookupType(context.Background(), name)
+		if err != nil {
+			t.Errorf("Failed to look up %s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(info.Tags, want) {
+			t.Errorf("Expected tags %v for %s, got %v", want, name, info.Tags)
+		}
+	}
+
+	found, err := a.SearchTypes(context.Background(), "api.")
+	if err != nil {
+		t.Fatalf("Failed to search types: %v", err)
+	}
+	var experimental []string
+	for _, info := range found {
+		if HasTags(info.Tags, []string{"experimental"}, []string{"deprecated"}) {
+			experimental = append(experimental, info.Name)
+		}
+	}
+	if !reflect.DeepEqual(experimental, []string{"Beta", "Preview"}) {
+		t.Errorf("Expected Beta and Preview to be experimental, got %v", experimental)
+	}
+}
+
+func TestLoadTagRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	if rules, err := LoadTagRules(filepath.Join(tmpDir, "missing.json")); err != nil || rules != nil {
+		t.Errorf("Expected no rules for a missing config, got %v, %v", rules, err)
+	}
+
+	for name, content := range map[string]string{
+		"no-tag":   `{"taggers": [{"path": "x"}]}`,
+		"no-match": `{"taggers": [{"tag": "x"}]}`,
+		"bad-glob": `{"taggers": [{"tag": "x", "pattern": "[a"}]}`,
+		"not-json": `{`,
+	} {
+		path := filepath.Join(tmpDir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadTagRules(path); err == nil {
+			t.Errorf("Expected error for %s config", name)
+		}
+	}
+}
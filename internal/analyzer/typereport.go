@@ -0,0 +1,260 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// TypeStats are the size and coupling of a named type
+type TypeStats struct {
+	// Name is qualified with the package name: pkg.Type
+	Name       string   `json:"name"`
+	ImportPath string   `json:"import_path"`
+	Kind       string   `json:"kind"`
+	Position   Position `json:"position"`
+	// Methods counts the methods declared on the type and its pointer, or
+	// the methods of an interface
+	Methods int `json:"methods"`
+	Fields  int `json:"fields"`
+	// MethodLines is the total number of lines of the method declarations
+	MethodLines int `json:"method_lines"`
+	// FanIn counts the other package-level types and functions of the
+	// repository referring to the type
+	FanIn int `json:"fan_in"`
+	// FanOut counts the other types of the repository the type's fields
+	// and methods refer to
+	FanOut int `json:"fan_out"`
+	// GodObject is set when the type exceeds any threshold; Reasons says
+	// which
+	GodObject bool     `json:"god_object"`
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+// TypeThresholds are the limits above which a type is flagged as a god
+// object. Zero uses the default limit.
+type TypeThresholds struct {
+	MaxMethods     int `json:"max_methods"`
+	MaxMethodLines int `json:"max_method_lines"`
+	MaxFields      int `json:"max_fields"`
+	MaxFanOut      int `json:"max_fan_out"`
+}
+
+// DefaultTypeThresholds are the god object limits used when none are set
+var DefaultTypeThresholds = TypeThresholds{
+	MaxMethods:     20,
+	MaxMethodLines: 600,
+	MaxFields:      20,
+	MaxFanOut:      15,
+}
+
+// withDefaults fills unset limits from DefaultTypeThresholds
+func (t TypeThresholds) withDefaults() TypeThresholds {
+	if t.MaxMethods <= 0 {
+		t.MaxMethods = DefaultTypeThresholds.MaxMethods
+	}
+	if t.MaxMethodLines <= 0 {
+		t.MaxMethodLines = DefaultTypeThresholds.MaxMethodLines
+	}
+	if t.MaxFields <= 0 {
+		t.MaxFields = DefaultTypeThresholds.MaxFields
+	}
+	if t.MaxFanOut <= 0 {
+		t.MaxFanOut = DefaultTypeThresholds.MaxFanOut
+	}
+	return t
+}
+
+// TypeReport is the result of TypeStatistics
+type TypeReport struct {
+	Types      []TypeStats    `json:"types"`
+	GodObjects int            `json:"god_objects"`
+	Thresholds TypeThresholds `json:"thresholds"`
+}
+
+// TypeStatistics measures every named package-level type: its methods and
+// their lines, its fields, and its fan-in and fan-out among the
+// repository's declarations. Types exceeding the thresholds are flagged as
+// god objects. A non-empty pkg restricts the report to types declared in
+// matching packages (import path, suffix or package name); references from
+// other packages still count. Types are ordered by method lines, largest
+// first.
+func (a *Analyzer) TypeStatistics(ctx context.Context, pkg string, thresholds TypeThresholds) (*TypeReport, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	stats := make(map[*types.TypeName]*TypeStats)
+	for _, importPath := range a.sortedPackagePaths() {
+		scope := a.pkgs[importPath].Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			s := &TypeStats{
+				Name:       typeName.Pkg().Name() + "." + typeName.Name(),
+				ImportPath: importPath,
+				Kind:       kindOf(typeName.Type()),
+				Position:   a.position(typeName.Pos()),
+			}
+			switch u := typeName.Type().Underlying().(type) {
+			case *types.Struct:
+				s.Fields = u.NumFields()
+			case *types.Interface:
+				s.Methods = u.NumMethods()
+			}
+			stats[typeName] = s
+		}
+	}
+
+	// Package-level declarations and the repository types they refer to
+	refs := make(map[types.Object]map[*types.TypeName]bool)
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info := a.infos[importPath]
+		for _, file := range a.asts[importPath] {
+			for _, decl := range file.Decls {
+				a.declRefs(info, decl, stats, refs)
+			}
+		}
+	}
+
+	for owner, targets := range refs {
+		for target := range targets {
+			if target == owner {
+				continue
+			}
+			stats[target].FanIn++
+			if typeName, ok := owner.(*types.TypeName); ok && stats[typeName] != nil {
+				stats[typeName].FanOut++
+			}
+		}
+	}
+
+	thresholds = thresholds.withDefaults()
+	report := &TypeReport{Types: []TypeStats{}, Thresholds: thresholds}
+	for typeName, s := range stats {
+		if pkg != "" && !matchesQualifier(pkg, s.ImportPath, typeName.Pkg().Name()) {
+			continue
+		}
+		s.flag(thresholds)
+		if s.GodObject {
+			report.GodObjects++
+		}
+		report.Types = append(report.Types, *s)
+	}
+	sort.Slice(report.Types, func(i, j int) bool {
+		if report.Types[i].MethodLines != report.Types[j].MethodLines {
+			return report.Types[i].MethodLines > report.Types[j].MethodLines
+		}
+		if report.Types[i].Methods != report.Types[j].Methods {
+			return report.Types[i].Methods > report.Types[j].Methods
+		}
+		return report.Types[i].ImportPath+"."+report.Types[i].Name < report.Types[j].ImportPath+"."+report.Types[j].Name
+	})
+	return report, nil
+}
+
+// declRefs counts the methods a declaration adds to a type and records the
+// repository types it refers to under the type or function declaring it
+func (a *Analyzer) declRefs(info *types.Info, decl ast.Decl, stats map[*types.TypeName]*TypeStats, refs map[types.Object]map[*types.TypeName]bool) {
+	record := func(owner types.Object, node ast.Node) {
+		if owner == nil || node == nil {
+			return
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			var target *types.TypeName
+			switch n := n.(type) {
+			case *ast.Ident:
+				target, _ = info.Uses[n].(*types.TypeName)
+			case *ast.SelectorExpr:
+				// Field and method selections use the receiver's type
+				if sel := info.Selections[n]; sel != nil {
+					target = namedTypeName(sel.Recv())
+				}
+			}
+			if target != nil && stats[target] != nil {
+				if refs[owner] == nil {
+					refs[owner] = make(map[*types.TypeName]bool)
+				}
+				refs[owner][target] = true
+			}
+			return true
+		})
+	}
+
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		fn, _ := info.Defs[decl.Name].(*types.Func)
+		if fn == nil {
+			return
+		}
+		var owner types.Object = fn
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			typeName := namedTypeName(recv.Type())
+			if s := stats[typeName]; s != nil {
+				s.Methods++
+				s.MethodLines += a.fset.Position(decl.End()).Line - a.fset.Position(decl.Pos()).Line + 1
+				owner = typeName
+			}
+		}
+		record(owner, decl.Type)
+		if decl.Body != nil {
+			record(owner, decl.Body)
+		}
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				record(info.Defs[spec.Name], spec.Type)
+			case *ast.ValueSpec:
+				for i, name := range spec.Names {
+					owner := info.Defs[name]
+					record(owner, spec.Type)
+					if i < len(spec.Values) {
+						record(owner, spec.Values[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+// namedTypeName returns the declaration of a named type or a pointer to
+// one, using the generic type for instantiations
+func namedTypeName(t types.Type) *types.TypeName {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Origin().Obj()
+	}
+	return nil
+}
+
+// flag marks the type as a god object when it exceeds a threshold
+func (s *TypeStats) flag(t TypeThresholds) {
+	if s.Methods > t.MaxMethods {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d methods (max %d)", s.Methods, t.MaxMethods))
+	}
+	if s.MethodLines > t.MaxMethodLines {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d lines of methods (max %d)", s.MethodLines, t.MaxMethodLines))
+	}
+	if s.Fields > t.MaxFields {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d fields (max %d)", s.Fields, t.MaxFields))
+	}
+	if s.FanOut > t.MaxFanOut {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("depends on %d types (max %d)", s.FanOut, t.MaxFanOut))
+	}
+	s.GodObject = len(s.Reasons) > 0
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeStatistics(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+import "example.com/shop/model"
+
+// Store does everything
+type Store struct {
+	items []model.Item
+	users map[string]model.User
+}
+
+func (s *Store) Add(item model.Item) {
+	s.items = append(s.items, item)
+}
+
+func (s *Store) Find(name string) (model.Item, bool) {
+	for _, item := range s.items {
+		if item.Name == name {
+			return item, true
+		}
+	}
+	return model.Item{}, false
+}
+
+func (s Store) Count() int { return len(s.items) }
+
+func (s *Store) Owner(name string) model.User {
+	return s.users[name]
+}
+`,
+		"model/model.go": `package model
+
+type Item struct{ Name string }
+
+type User struct{ Name string }
+
+// Named is implemented by models
+type Named interface {
+	GetName() string
+}
+
+func (i Item) GetName() string { return i.Name }
+
+func Describe(n Named) string { return n.GetName() }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	report, err := analyzer.TypeStatistics(ctx, "", TypeThresholds{MaxMethods: 3})
+	if err != nil {
+		t.Fatalf("TypeStatistics failed: %v", err)
+	}
+	if len(report.Types) != 4 || report.Types[0].Name != "store.Store" {
+		t.Fatalf("Expected 4 types with Store first, got %+v", report.Types)
+	}
+	stats := make(map[string]TypeStats)
+	for _, s := range report.Types {
+		stats[s.Name] = s
+	}
+
+	store := stats["store.Store"]
+	if store.Methods != 4 || store.Fields != 2 || store.MethodLines != 15 || store.FanOut != 2 || store.FanIn != 0 {
+		t.Errorf("Unexpected Store stats: %+v", store)
+	}
+	if !store.GodObject || len(store.Reasons) != 1 || report.GodObjects != 1 {
+		t.Errorf("Expected Store flagged for its methods only, got %+v", store)
+	}
+	if report.Thresholds.MaxMethods != 3 || report.Thresholds.MaxFanOut != DefaultTypeThresholds.MaxFanOut {
+		t.Errorf("Expected explicit and default thresholds, got %+v", report.Thresholds)
+	}
+
+	// References from a type's own methods do not count
+	if item := stats["model.Item"]; item.Methods != 1 || item.FanIn != 1 {
+		t.Errorf("Unexpected Item stats: %+v", item)
+	}
+	if named := stats["model.Named"]; named.Kind != "interface" || named.Methods != 1 || named.FanIn != 1 {
+		t.Errorf("Unexpected Named stats: %+v", named)
+	}
+
+	report, err = analyzer.TypeStatistics(ctx, "model", TypeThresholds{})
+	if err != nil {
+		t.Fatalf("TypeStatistics failed: %v", err)
+	}
+	if len(report.Types) != 3 || report.GodObjects != 0 {
+		t.Errorf("Expected the 3 model types and no god objects, got %+v", report)
+	}
+}
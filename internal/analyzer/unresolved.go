@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Packages that fail to type check, because of a missing dependency or code
+// that does not compile yet, keep what go/types could resolve. Types it
+// could not resolve print as "invalid type", so declarations referring to
+// them are described by their type expressions as written in the source
+// instead, and marked unresolved.
+
+// typeString returns the type of obj as a string. When the type refers to a
+// type that failed to resolve, it is the type expression obj was declared
+// with, and unresolved is true.
+func (a *Analyzer) typeString(obj types.Object) (s string, unresolved bool) {
+	if !containsInvalid(obj.Type()) {
+		return obj.Type().String(), false
+	}
+	if expr := a.declaredType(obj); expr != nil {
+		return types.ExprString(expr), true
+	}
+	return obj.Type().String(), true
+}
+
+// containsInvalid reports whether a type is, or is built from, a type that
+// failed to resolve. Named types are not expanded, as their own
+// declarations are reported separately, but their type arguments are.
+func containsInvalid(t types.Type) bool {
+	return invalidIn(t, make(map[types.Type]bool))
+}
+
+// invalidIn implements containsInvalid, with seen guarding against
+// recursive types
+func invalidIn(t types.Type, seen map[types.Type]bool) bool {
+	if t == nil || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Kind() == types.Invalid
+	case *types.Alias:
+		return invalidIn(types.Unalias(t), seen)
+	case *types.Pointer:
+		return invalidIn(t.Elem(), seen)
+	case *types.Slice:
+		return invalidIn(t.Elem(), seen)
+	case *types.Array:
+		return invalidIn(t.Elem(), seen)
+	case *types.Chan:
+		return invalidIn(t.Elem(), seen)
+	case *types.Map:
+		return invalidIn(t.Key(), seen) || invalidIn(t.Elem(), seen)
+	case *types.Signature:
+		return invalidIn(t.Params(), seen) || invalidIn(t.Results(), seen)
+	case *types.Tuple:
+		for v := range t.Variables() {
+			if invalidIn(v.Type(), seen) {
+				return true
+			}
+		}
+	case *types.Struct:
+		for field := range t.Fields() {
+			if invalidIn(field.Type(), seen) {
+				return true
+			}
+		}
+	case *types.Interface:
+		for method := range t.ExplicitMethods() {
+			if invalidIn(method.Type(), seen) {
+				return true
+			}
+		}
+		for embedded := range t.EmbeddedTypes() {
+			if invalidIn(embedded, seen) {
+				return true
+			}
+		}
+	case *types.Union:
+		for term := range t.Terms() {
+			if invalidIn(term.Type(), seen) {
+				return true
+			}
+		}
+	case *types.Named:
+		for arg := range t.TypeArgs().Types() {
+			if invalidIn(arg, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// declaredType returns the type expression of the declaration of obj: the
+// type of a field, parameter or variable, or the signature of a function.
+// It returns nil when the declaration is not in the analyzed sources or
+// has no explicit type.
+func (a *Analyzer) declaredType(obj types.Object) ast.Expr {
+	if obj.Pkg() == nil || !obj.Pos().IsValid() {
+		return nil
+	}
+	pos := obj.Pos()
+	for _, file := range a.asts[obj.Pkg().Path()] {
+		if pos < file.Pos() || pos >= file.End() {
+			continue
+		}
+		var expr ast.Expr
+		ast.Inspect(file, func(n ast.Node) bool {
+			if expr != nil || n == nil || pos < n.Pos() || pos >= n.End() {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if n.Name.Pos() == pos {
+					expr = n.Type
+				}
+			case *ast.Field:
+				expr = fieldType(n, pos)
+			case *ast.ValueSpec:
+				if declares(n.Names, pos) {
+					expr = n.Type
+					return false
+				}
+			}
+			return expr == nil
+		})
+		return expr
+	}
+	return nil
+}
+
+// fieldType returns the type of a field, parameter or interface method
+// declared at pos. Embedded fields and unnamed parameters are declared
+// within their type.
+func fieldType(field *ast.Field, pos token.Pos) ast.Expr {
+	if len(field.Names) == 0 {
+		if field.Type.Pos() <= pos && pos < field.Type.End() {
+			return field.Type
+		}
+		return nil
+	}
+	if declares(field.Names, pos) {
+		return field.Type
+	}
+	return nil
+}
+
+// declares reports whether one of names is declared at pos
+func declares(names []*ast.Ident, pos token.Pos) bool {
+	for _, name := range names {
+		if name.Pos() == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// recordUnresolved records a type checking error of a package, so that
+// AnalyzeRepository and GetPackageInfo can tell which packages were only
+// partly resolved. Soft errors, such as unused variables, do not keep types
+// from resolving and are recorded as warnings.
+func (a *Analyzer) recordUnresolved(importPath string, err error) {
+	analysisErr := AnalysisError{Message: err.Error(), Type: "type_check", Severity: "error"}
+	var typeErr types.Error
+	if errors.As(err, &typeErr) {
+		analysisErr.Message = typeErr.Msg
+		analysisErr.Position = a.position(typeErr.Pos)
+		if typeErr.Soft {
+			analysisErr.Severity = "warning"
+		}
+	}
+	a.typeErrors[importPath] = append(a.typeErrors[importPath], analysisErr)
+}
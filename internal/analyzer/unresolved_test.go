@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnresolvedTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"wip/wip.go": `package wip
+
+import "github.com/missing/dep"
+
+type Service struct {
+	Name   string
+	Client *dep.Client
+	Cache  map[string]Entry
+}
+
+type Handler interface {
+	Handle(req dep.Request) (dep.Response, error)
+}
+
+func (s *Service) Call(req dep.Request) error {
+	return s.Client.Do(req)
+}
+
+func (s *Service) Count() int {
+	return "not a number"
+}
+
+func New(name string) *Service {
+	return &Service{Name: name}
+}
+
+var Default dep.Options
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, lazy := range []bool{false, true} {
+		config := DefaultConfig()
+		config.LazyLoading = lazy
+		analyzer, err := NewAnalyzerWithConfig(tmpDir, config)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		defer analyzer.Close()
+		ctx := context.Background()
+
+		service, err := analyzer.LookupType(ctx, "Service")
+		if err != nil {
+			t.Fatalf("Failed to look up Service (lazy %v): %v", lazy, err)
+		}
+		if !service.Unresolved {
+			t.Errorf("Expected Service to be unresolved (lazy %v)", lazy)
+		}
+		fields := make(map[string]FieldInfo)
+		for _, field := range service.Fields {
+			fields[field.Name] = field
+		}
+		if field := fields["Client"]; field.Type != "*dep.Client" || !field.Unresolved {
+			t.Errorf("Expected Client to be an unresolved *dep.Client (lazy %v), got %+v", lazy, field)
+		}
+		if field := fields["Name"]; field.Type != "string" || field.Unresolved {
+			t.Errorf("Expected Name to be a resolved string (lazy %v), got %+v", lazy, field)
+		}
+
+		methods := make(map[string]MethodInfo)
+		for _, method := range service.Methods {
+			methods[method.Name] = method
+		}
+		call := methods["Call"]
+		if call.Signature != "func(req dep.Request) error" || !call.Unresolved {
+			t.Errorf("Expected Call to have an unresolved signature as written (lazy %v), got %+v", lazy, call)
+		}
+		if len(call.Parameters) != 1 || call.Parameters[0].Type != "dep.Request" || !call.Parameters[0].Unresolved {
+			t.Errorf("Expected an unresolved dep.Request parameter (lazy %v), got %+v", lazy, call.Parameters)
+		}
+		// Errors in function bodies leave signatures resolved
+		if count := methods["Count"]; count.Signature != "func() int" || count.Unresolved {
+			t.Errorf("Expected Count to be resolved (lazy %v), got %+v", lazy, count)
+		}
+
+		handler, err := analyzer.LookupType(ctx, "Handler")
+		if err != nil {
+			t.Fatalf("Failed to look up Handler (lazy %v): %v", lazy, err)
+		}
+		if len(handler.Methods) != 1 || handler.Methods[0].Signature != "func(req dep.Request) (dep.Response, error)" {
+			t.Errorf("Expected Handle as written (lazy %v), got %+v", lazy, handler.Methods)
+		}
+
+		result, err := analyzer.AnalyzeRepository(ctx)
+		if err != nil {
+			t.Fatalf("Failed to analyze repository (lazy %v): %v", lazy, err)
+		}
+		if len(result.Errors) == 0 {
+			t.Errorf("Expected type errors in the result (lazy %v)", lazy)
+		}
+		for _, fn := range result.Functions {
+			if fn.Name == "New" && (fn.Signature != "func(name string) *example.com/app/wip.Service" || fn.Unresolved) {
+				t.Errorf("Expected New to be resolved (lazy %v), got %+v", lazy, fn)
+			}
+		}
+		for _, v := range result.Variables {
+			if v.Name == "Default" && (v.Type != "dep.Options" || !v.Unresolved) {
+				t.Errorf("Expected Default to be an unresolved dep.Options (lazy %v), got %+v", lazy, v)
+			}
+		}
+
+		pkg, err := analyzer.GetPackageInfo(ctx, "wip")
+		if err != nil {
+			t.Fatalf("Failed to get package info (lazy %v): %v", lazy, err)
+		}
+		if pkg.TypeErrors == 0 {
+			t.Errorf("Expected wip to report type errors (lazy %v)", lazy)
+		}
+	}
+}
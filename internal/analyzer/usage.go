@@ -0,0 +1,214 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// InterfaceUse is a parameter, result, field, or variable typed with an
+// interface, directly or through a pointer, slice, array, map, or channel
+type InterfaceUse struct {
+	Name string `json:"name,omitempty"`
+	// Owner is the function, method, or struct type the use belongs to;
+	// empty for package-level variables
+	Owner      string   `json:"owner,omitempty"`
+	ImportPath string   `json:"import_path"`
+	Type       string   `json:"type"`
+	Position   Position `json:"position"`
+}
+
+// InterfaceUsage lists where an interface appears in the analyzed packages
+type InterfaceUsage struct {
+	Interface  TypeRef        `json:"interface"`
+	Parameters []InterfaceUse `json:"parameters"`
+	Results    []InterfaceUse `json:"results"`
+	Fields     []InterfaceUse `json:"fields"`
+	Variables  []InterfaceUse `json:"variables"`
+}
+
+// InterfaceUsage finds every function and method parameter and result,
+// struct field, and variable whose type is the named interface, to show
+// the blast radius of changing it. Interface methods count as methods.
+func (a *Analyzer) InterfaceUsage(ctx context.Context, name string) (*InterfaceUsage, error) {
+	if err := a.rlockAll(ctx); err != nil {
+		return nil, err
+	}
+	defer a.mu.RUnlock()
+
+	if !a.initialized {
+		return nil, fmt.Errorf("analyzer not initialized")
+	}
+
+	_, obj, err := a.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	typeObj, ok := obj.(*types.TypeName)
+	if !ok || !types.IsInterface(typeObj.Type()) {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	iface := typeObj.Type()
+
+	usage := &InterfaceUsage{
+		Interface:  typeRef(typeObj, false),
+		Parameters: []InterfaceUse{},
+		Results:    []InterfaceUse{},
+		Fields:     []InterfaceUse{},
+		Variables:  []InterfaceUse{},
+	}
+	use := func(importPath, owner string, v *types.Var) InterfaceUse {
+		return InterfaceUse{
+			Name:       v.Name(),
+			Owner:      owner,
+			ImportPath: importPath,
+			Type:       types.TypeString(v.Type(), types.RelativeTo(v.Pkg())),
+			Position:   a.position(v.Pos()),
+		}
+	}
+
+	// Parameters and results are also recorded in Defs; remember them so
+	// they are not reported again as variables
+	signatureVars := make(map[*types.Var]bool)
+	addSignature := func(importPath, owner string, sig *types.Signature) {
+		for i := 0; i < sig.Params().Len(); i++ {
+			param := sig.Params().At(i)
+			signatureVars[param] = true
+			if mentionsType(param.Type(), iface) {
+				usage.Parameters = append(usage.Parameters, use(importPath, owner, param))
+			}
+		}
+		for i := 0; i < sig.Results().Len(); i++ {
+			result := sig.Results().At(i)
+			signatureVars[result] = true
+			if mentionsType(result.Type(), iface) {
+				usage.Results = append(usage.Results, use(importPath, owner, result))
+			}
+		}
+	}
+
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info := a.infos[importPath]
+		for _, file := range a.asts[importPath] {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncDecl:
+					if fn, ok := info.Defs[n.Name].(*types.Func); ok {
+						addSignature(importPath, funcName(fn), fn.Type().(*types.Signature))
+					}
+				case *ast.FuncType:
+					// Parameters of function literals and function types
+					for _, list := range []*ast.FieldList{n.Params, n.Results} {
+						if list == nil {
+							continue
+						}
+						for _, field := range list.List {
+							for _, name := range field.Names {
+								if v, ok := info.Defs[name].(*types.Var); ok {
+									signatureVars[v] = true
+								}
+							}
+						}
+					}
+				case *ast.TypeSpec:
+					typeName, ok := info.Defs[n.Name].(*types.TypeName)
+					if !ok {
+						return true
+					}
+					owner := typeName.Pkg().Name() + "." + typeName.Name()
+					switch t := typeName.Type().Underlying().(type) {
+					case *types.Struct:
+						for i := 0; i < t.NumFields(); i++ {
+							if mentionsType(t.Field(i).Type(), iface) {
+								usage.Fields = append(usage.Fields, use(importPath, owner, t.Field(i)))
+							}
+						}
+					case *types.Interface:
+						for i := 0; i < t.NumExplicitMethods(); i++ {
+							method := t.ExplicitMethod(i)
+							addSignature(importPath, owner+"."+method.Name(), method.Type().(*types.Signature))
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	for _, importPath := range a.sortedPackagePaths() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info := a.infos[importPath]
+		pkg := a.pkgs[importPath]
+		for ident, obj := range info.Defs {
+			v, ok := obj.(*types.Var)
+			if !ok || v.IsField() || signatureVars[v] || ident.Name == "_" || !mentionsType(v.Type(), iface) {
+				continue
+			}
+			owner := ""
+			if v.Parent() != pkg.Scope() {
+				owner = a.enclosingFunc(importPath, v)
+			}
+			usage.Variables = append(usage.Variables, use(importPath, owner, v))
+		}
+	}
+	sortUses(usage.Variables)
+	return usage, nil
+}
+
+// mentionsType reports whether t is target, or a pointer, slice, array,
+// map, or channel of it
+func mentionsType(t, target types.Type) bool {
+	switch u := t.(type) {
+	case *types.Pointer:
+		return mentionsType(u.Elem(), target)
+	case *types.Slice:
+		return mentionsType(u.Elem(), target)
+	case *types.Array:
+		return mentionsType(u.Elem(), target)
+	case *types.Chan:
+		return mentionsType(u.Elem(), target)
+	case *types.Map:
+		return mentionsType(u.Key(), target) || mentionsType(u.Elem(), target)
+	}
+	return types.Identical(t, target)
+}
+
+// enclosingFunc names the function declaring a local variable
+func (a *Analyzer) enclosingFunc(importPath string, v *types.Var) string {
+	info := a.infos[importPath]
+	for _, file := range a.asts[importPath] {
+		if v.Pos() < file.Pos() || v.Pos() > file.End() {
+			continue
+		}
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || v.Pos() < fd.Pos() || v.Pos() > fd.End() {
+				continue
+			}
+			if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+				return funcName(fn)
+			}
+		}
+	}
+	return ""
+}
+
+// sortUses orders uses by file and line
+func sortUses(uses []InterfaceUse) {
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].Position.Filename != uses[j].Position.Filename {
+			return uses[i].Position.Filename < uses[j].Position.Filename
+		}
+		if uses[i].Position.Line != uses[j].Position.Line {
+			return uses[i].Position.Line < uses[j].Position.Line
+		}
+		return uses[i].Position.Column < uses[j].Position.Column
+	})
+}
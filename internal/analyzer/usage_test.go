@@ -0,0 +1,118 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInterfaceUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+type Store interface {
+	Get(key string) (string, error)
+	Wrap(next Store) Store
+}
+
+type Cache struct {
+	backends []Store
+	primary  Store
+	size     int
+}
+
+var Default Store
+
+func New(s Store) *Cache {
+	var fallback Store = s
+	handler := func(inner Store) {}
+	handler(fallback)
+	return &Cache{primary: fallback}
+}
+
+func (c *Cache) Primary() (s Store) {
+	return c.primary
+}
+
+func Plain(n int) int { return n }
+`,
+		"app/app.go": `package app
+
+import "example.com/app/store"
+
+func Run(stores map[string]store.Store) {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	usage, err := analyzer.InterfaceUsage(context.Background(), "Store")
+	if err != nil {
+		t.Fatalf("Failed to get interface usage: %v", err)
+	}
+	if usage.Interface.Name != "Store" {
+		t.Errorf("Expected interface Store, got %+v", usage.Interface)
+	}
+
+	describe := func(uses []InterfaceUse) []string {
+		var out []string
+		for _, use := range uses {
+			out = append(out, use.Owner+" "+use.Name+" "+use.Type)
+		}
+		return out
+	}
+
+	wantParameters := []string{
+		"app.Run stores map[string]example.com/app/store.Store",
+		"store.Store.Wrap next Store",
+		"store.New s Store",
+	}
+	if got := describe(usage.Parameters); !reflect.DeepEqual(got, wantParameters) {
+		t.Errorf("Expected parameters %v, got %v", wantParameters, got)
+	}
+	wantResults := []string{
+		"store.Store.Wrap  Store",
+		"store.Cache.Primary s Store",
+	}
+	if got := describe(usage.Results); !reflect.DeepEqual(got, wantResults) {
+		t.Errorf("Expected results %v, got %v", wantResults, got)
+	}
+	wantFields := []string{
+		"store.Cache backends []Store",
+		"store.Cache primary Store",
+	}
+	if got := describe(usage.Fields); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Expected fields %v, got %v", wantFields, got)
+	}
+	wantVariables := []string{
+		" Default Store",
+		"store.New fallback Store",
+	}
+	if got := describe(usage.Variables); !reflect.DeepEqual(got, wantVariables) {
+		t.Errorf("Expected variables %v, got %v", wantVariables, got)
+	}
+
+	if _, err := analyzer.InterfaceUsage(context.Background(), "Cache"); err == nil {
+		t.Error("Expected error for a struct type")
+	}
+	if _, err := analyzer.InterfaceUsage(context.Background(), "DoesNotExist"); err == nil {
+		t.Error("Expected error for an unknown type")
+	}
+}
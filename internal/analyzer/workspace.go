@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Module is a Go module whose packages are analyzed
+type Module struct {
+	Path string `json:"path"`
+	// Dir is the module's directory, relative to the repository when it is
+	// inside it
+	Dir      string `json:"dir"`
+	Packages int    `json:"packages"`
+}
+
+// workspace is the set of modules a go.work file uses
+type workspace struct {
+	modules []workspaceModule // Ordered by directory
+}
+
+// workspaceModule is a module listed by a use directive
+type workspaceModule struct {
+	Dir  string // Absolute
+	Path string
+}
+
+// readWorkspace reads the go.work file at the root of the repository, or
+// the one named by GOWORK. It returns nil when there is none or GOWORK=off.
+func (a *Analyzer) readWorkspace() (*workspace, error) {
+	file := os.Getenv("GOWORK")
+	switch file {
+	case "off":
+		return nil, nil
+	case "":
+		file = filepath.Join(a.repoPath, "go.work")
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := parseWorkUses(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	ws := &workspace{}
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(file), dir)
+		}
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		modulePath := a.modulePath(dir)
+		if modulePath == "" {
+			a.logWarn("Skipping workspace module without a module path in go.mod", "dir", dir)
+			continue
+		}
+		ws.modules = append(ws.modules, workspaceModule{Dir: dir, Path: modulePath})
+	}
+	sort.Slice(ws.modules, func(i, j int) bool { return ws.modules[i].Dir < ws.modules[j].Dir })
+	return ws, nil
+}
+
+// parseWorkUses returns the directories of the use directives of a go.work
+// file, in both the single-line and the block form
+func parseWorkUses(data string) ([]string, error) {
+	var dirs []string
+	inUse := false
+	for i, line := range strings.Split(data, "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var dir string
+		switch {
+		case inUse && fields[0] == ")":
+			inUse = false
+			continue
+		case inUse:
+			dir = strings.TrimSpace(line)
+		case fields[0] != "use":
+			continue
+		case len(fields) >= 2 && fields[1] == "(":
+			inUse = true
+			continue
+		case len(fields) >= 2:
+			dir = strings.TrimSpace(strings.TrimSpace(line)[len("use"):])
+		default:
+			return nil, fmt.Errorf("line %d: use directive without a directory", i+1)
+		}
+
+		if strings.HasPrefix(dir, `"`) || strings.HasPrefix(dir, "`") {
+			unquoted, err := strconv.Unquote(dir)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid directory %s", i+1, dir)
+			}
+			dir = unquoted
+		}
+		dirs = append(dirs, filepath.FromSlash(dir))
+	}
+	if inUse {
+		return nil, fmt.Errorf("unterminated use block")
+	}
+	return dirs, nil
+}
+
+// Modules returns the modules whose packages are analyzed: the modules of
+// the go.work workspace when there is one, otherwise every module
+// containing an analyzed package, ordered by path
+func (a *Analyzer) Modules() []Module {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, files := range a.files {
+		if len(files) == 0 {
+			continue
+		}
+		if moduleDir, _ := a.moduleFor(filepath.Dir(files[0])); moduleDir != "" {
+			counts[moduleDir]++
+		}
+	}
+
+	var modules []Module
+	if a.workspace != nil {
+		for _, module := range a.workspace.modules {
+			modules = append(modules, Module{Path: module.Path, Dir: a.relDir(module.Dir), Packages: counts[module.Dir]})
+		}
+	} else {
+		for moduleDir, count := range counts {
+			modules = append(modules, Module{Path: a.modulePath(moduleDir), Dir: a.relDir(moduleDir), Packages: count})
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules
+}
+
+// relDir returns dir relative to the repository when it is inside it
+func (a *Analyzer) relDir(dir string) string {
+	rel, err := filepath.Rel(a.repoPath, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return dir
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	files := map[string]string{
+		"repo/go.work": `go 1.21
+
+use (
+	./api
+	./svc // the service
+)
+use ../shared
+`,
+		"repo/api/go.mod": "module example.com/api\n\ngo 1.21\n",
+		"repo/api/util/util.go": `package util
+
+type Helper struct{ API bool }
+`,
+		"repo/svc/go.mod": "module example.com/svc\n\ngo 1.21\n",
+		"repo/svc/util/util.go": `package util
+
+import "example.com/shared/types"
+
+type Helper struct{ ID types.ID }
+`,
+		"repo/svc/tools/go.mod": "module example.com/svc/tools\n\ngo 1.21\n",
+		"repo/svc/tools/gen.go": `package tools
+
+type Generator struct{}
+`,
+		"shared/go.mod": "module example.com/shared\n\ngo 1.21\n",
+		"shared/types/types.go": `package types
+
+type ID string
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer, err := NewAnalyzer(repo)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer analyzer.Close()
+	ctx := context.Background()
+
+	// Modules the workspace does not use are left out, modules outside the
+	// repository are included
+	want := []string{"example.com/api/util", "example.com/shared/types", "example.com/svc/util"}
+	if got := analyzer.Packages(); !slices.Equal(got, want) {
+		t.Errorf("Expected packages %v, got %v", want, got)
+	}
+
+	modules := analyzer.Modules()
+	if len(modules) != 3 {
+		t.Fatalf("Expected 3 modules, got %+v", modules)
+	}
+	if modules[0].Path != "example.com/api" || modules[0].Dir != "api" || modules[0].Packages != 1 {
+		t.Errorf("Unexpected api module: %+v", modules[0])
+	}
+	if modules[1].Path != "example.com/shared" || modules[1].Dir != filepath.Join(root, "shared") {
+		t.Errorf("Unexpected shared module: %+v", modules[1])
+	}
+
+	_, err = analyzer.LookupType(ctx, "util.Helper")
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("Expected util.Helper to be ambiguous across modules, got %v", err)
+	}
+	info, err := analyzer.LookupType(ctx, "example.com/svc/util.Helper")
+	if err != nil {
+		t.Fatalf("LookupType failed: %v", err)
+	}
+	if len(info.Fields) != 1 || info.Fields[0].Type != "example.com/shared/types.ID" {
+		t.Errorf("Expected a field of the shared module's ID type, got %+v", info.Fields)
+	}
+
+	pkg, err := analyzer.GetPackageInfo(ctx, "example.com/svc/util")
+	if err != nil {
+		t.Fatalf("GetPackageInfo failed: %v", err)
+	}
+	if pkg.Module != "example.com/svc" {
+		t.Errorf("Expected module example.com/svc, got %q", pkg.Module)
+	}
+
+	t.Setenv("GOWORK", "off")
+	flat, err := NewAnalyzer(repo)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer flat.Close()
+	if got := flat.Packages(); len(got) != 3 || !slices.Contains(got, "example.com/svc/tools") {
+		t.Errorf("Expected every module in the repository with GOWORK=off, got %v", got)
+	}
+}
+
+func TestParseWorkUses(t *testing.T) {
+	dirs, err := parseWorkUses("go 1.22\n\nuse \"./a b\"\nuse (\n\t./c\n)\nreplace x => ./y\n")
+	if err != nil {
+		t.Fatalf("parseWorkUses failed: %v", err)
+	}
+	if !slices.Equal(dirs, []string{"./a b", "./c"}) {
+		t.Errorf("Expected [./a b ./c], got %q", dirs)
+	}
+	if _, err := parseWorkUses("use (\n./a\n"); err == nil {
+		t.Error("Expected an error for an unterminated use block")
+	}
+}
@@ -0,0 +1,285 @@
+// Package apidiff reports changes to the exported API of a repository's
+// packages between two analyses, classifying each change as breaking or
+// compatible for importers, in the spirit of golang.org/x/exp/apidiff
+package apidiff
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// Change kinds
+const (
+	// Removed symbols break every importer using them
+	Removed = "removed"
+	// Changed symbols have a different type, signature or value
+	Changed = "changed"
+	// Narrowed interfaces gained methods, so existing implementations no
+	// longer satisfy them
+	Narrowed = "narrowed"
+	// Added symbols are compatible
+	Added = "added"
+)
+
+// Change is one difference between two versions of an API
+type Change struct {
+	Package string `json:"package"`
+	// Symbol is Name, Type.Method or Type.Field; empty when the change
+	// concerns the whole package
+	Symbol string `json:"symbol,omitempty"`
+	// SymbolKind is the Kind of the symbol after the change, or before it
+	// when it was removed
+	SymbolKind string             `json:"symbol_kind,omitempty"`
+	Kind       string             `json:"kind"`
+	Message    string             `json:"message"`
+	Old        string             `json:"old,omitempty"`
+	New        string             `json:"new,omitempty"`
+	Position   *analyzer.Position `json:"position,omitempty"`
+}
+
+// Report lists the changes between two versions of an API
+type Report struct {
+	Breaking   []Change `json:"breaking"`
+	Compatible []Change `json:"compatible"`
+}
+
+// Symbol is an exported declaration of a package
+type Symbol struct {
+	Kind string // "type", "func", "method", "field", "var" or "const"
+	// Type is what must stay the same for importers: the kind of a type,
+	// the signature of a function or method, the type of a field or
+	// variable, and the type and value of a constant
+	Type     string
+	Position analyzer.Position
+	// Interface and Sealed describe types; a sealed interface has
+	// unexported methods, so only its own package can implement it
+	Interface bool
+	Sealed    bool
+}
+
+// API is the exported API of a set of packages: symbols by name, by
+// import path
+type API map[string]map[string]Symbol
+
+// FromResult extracts the exported API of the non-main packages of an
+// analysis result
+func FromResult(result *analyzer.AnalysisResult) API {
+	api := make(API)
+	pkgByFile := make(map[string]string)
+	for _, pkg := range result.Packages {
+		if pkg.IsMain || strings.HasSuffix(pkg.ImportPath, "_test") {
+			continue
+		}
+		api[pkg.ImportPath] = make(map[string]Symbol)
+		for _, file := range pkg.Files {
+			pkgByFile[file] = pkg.ImportPath
+		}
+	}
+	add := func(importPath, name string, symbol Symbol) {
+		if symbols, ok := api[importPath]; ok {
+			symbols[name] = symbol
+		}
+	}
+
+	for _, typeInfo := range result.Types {
+		if !typeInfo.Exported {
+			continue
+		}
+		symbol := Symbol{Kind: "type", Type: typeInfo.Kind, Position: typeInfo.Position, Interface: typeInfo.Kind == "interface"}
+		for _, method := range typeInfo.Methods {
+			if !method.Exported {
+				symbol.Sealed = symbol.Interface
+				continue
+			}
+			add(typeInfo.ImportPath, typeInfo.Name+"."+method.Name, Symbol{
+				Kind:     "method",
+				Type:     signature(method.Signature, method.Parameters, method.Results),
+				Position: method.Position,
+			})
+		}
+		for _, field := range typeInfo.Fields {
+			if field.Exported {
+				add(typeInfo.ImportPath, typeInfo.Name+"."+field.Name, Symbol{Kind: "field", Type: field.Type, Position: field.Position})
+			}
+		}
+		add(typeInfo.ImportPath, typeInfo.Name, symbol)
+	}
+	for _, fn := range result.Functions {
+		if fn.Exported && !fn.IsMethod {
+			add(pkgByFile[fn.Position.Filename], fn.Name, Symbol{
+				Kind:     "func",
+				Type:     signature(fn.Signature, fn.Parameters, fn.Results),
+				Position: fn.Position,
+			})
+		}
+	}
+	for _, v := range result.Variables {
+		if v.Exported {
+			add(pkgByFile[v.Position.Filename], v.Name, Symbol{Kind: "var", Type: v.Type, Position: v.Position})
+		}
+	}
+	for _, c := range result.Constants {
+		if c.Exported {
+			add(pkgByFile[c.Position.Filename], c.Name, Symbol{Kind: "const", Type: c.Type + " = " + c.Value, Position: c.Position})
+		}
+	}
+	return api
+}
+
+// signature renders a function signature without parameter names, which
+// importers do not depend on
+func signature(full string, params, results []analyzer.ParamInfo) string {
+	types := make([]string, len(params))
+	for i, param := range params {
+		types[i] = param.Type
+	}
+	// ParamInfo reports a variadic parameter as a slice
+	if n := len(types); n > 0 && strings.Contains(full, "...") && strings.HasPrefix(types[n-1], "[]") {
+		types[n-1] = "..." + strings.TrimPrefix(types[n-1], "[]")
+	}
+	rendered := "func(" + strings.Join(types, ", ") + ")"
+
+	switch len(results) {
+	case 0:
+	case 1:
+		rendered += " " + results[0].Type
+	default:
+		types := make([]string, len(results))
+		for i, result := range results {
+			types[i] = result.Type
+		}
+		rendered += " (" + strings.Join(types, ", ") + ")"
+	}
+	return rendered
+}
+
+// Filter keeps the packages matching pkg, an import path, import path
+// suffix or package name, like the analyzer's qualifiers
+func (api API) Filter(pkg string) API {
+	if pkg == "" {
+		return api
+	}
+	filtered := make(API)
+	for importPath, symbols := range api {
+		if importPath == pkg || strings.HasSuffix(importPath, "/"+pkg) || path.Base(importPath) == pkg {
+			filtered[importPath] = symbols
+		}
+	}
+	return filtered
+}
+
+// Compare reports how the API changed from old to current
+func Compare(old, current API) *Report {
+	report := &Report{Breaking: []Change{}, Compatible: []Change{}}
+
+	for _, importPath := range sortedKeys(old, current) {
+		oldSymbols, existed := old[importPath]
+		symbols, exists := current[importPath]
+		switch {
+		case !exists:
+			report.Breaking = append(report.Breaking, Change{
+				Package: importPath,
+				Kind:    Removed,
+				Message: fmt.Sprintf("package %s was removed", importPath),
+			})
+			continue
+		case !existed:
+			report.Compatible = append(report.Compatible, Change{
+				Package: importPath,
+				Kind:    Added,
+				Message: fmt.Sprintf("package %s was added", importPath),
+			})
+			continue
+		}
+		comparePackage(report, importPath, oldSymbols, symbols)
+	}
+	return report
+}
+
+// comparePackage adds the changes between two versions of a package to report
+func comparePackage(report *Report, importPath string, old, current map[string]Symbol) {
+	for _, name := range sortedKeys(old, current) {
+		before, existed := old[name]
+		after, exists := current[name]
+		change := Change{Package: importPath, Symbol: name, SymbolKind: after.Kind}
+		if !exists {
+			change.SymbolKind = before.Kind
+		}
+
+		switch {
+		case !exists:
+			change.Kind = Removed
+			change.Message = fmt.Sprintf("%s %s was removed", before.Kind, name)
+			change.Old = before.Type
+			change.Position = &before.Position
+			report.Breaking = append(report.Breaking, change)
+		case !existed:
+			change.Kind = Added
+			change.Message = fmt.Sprintf("%s %s was added", after.Kind, name)
+			change.New = after.Type
+			change.Position = &after.Position
+			if owner, ok := ownerInterface(old, current, name); ok && after.Kind == "method" {
+				change.Kind = Narrowed
+				change.Message = fmt.Sprintf("interface %s gained method %s, so existing implementations no longer satisfy it", owner, strings.TrimPrefix(name, owner+"."))
+				report.Breaking = append(report.Breaking, change)
+				continue
+			}
+			report.Compatible = append(report.Compatible, change)
+		case before.Kind != after.Kind || before.Type != after.Type:
+			change.Kind = Changed
+			change.Message = fmt.Sprintf("%s %s changed from %s to %s", before.Kind, name, describe(before), describe(after))
+			change.Old = before.Type
+			change.New = after.Type
+			change.Position = &after.Position
+			report.Breaking = append(report.Breaking, change)
+		case before.Interface && before.Sealed && !after.Sealed:
+			change.Kind = Changed
+			change.Message = fmt.Sprintf("interface %s can now be implemented outside its package", name)
+			change.Position = &after.Position
+			report.Compatible = append(report.Compatible, change)
+		}
+	}
+}
+
+// ownerInterface returns the interface declaring a method name of the form
+// Type.Method when the interface existed before and other packages could
+// implement it
+func ownerInterface(old, current map[string]Symbol, name string) (string, bool) {
+	owner, _, ok := strings.Cut(name, ".")
+	if !ok {
+		return "", false
+	}
+	before, existed := old[owner]
+	after := current[owner]
+	if !existed || !before.Interface || !after.Interface || before.Sealed {
+		return "", false
+	}
+	return owner, true
+}
+
+// describe renders a symbol's kind and type for messages
+func describe(symbol Symbol) string {
+	if symbol.Kind == "type" {
+		return symbol.Type + " type"
+	}
+	return symbol.Type
+}
+
+// sortedKeys returns the union of the keys of two maps in sorted order
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,229 @@
+package apidiff
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+const originalLib = `package lib
+
+type Client struct {
+	Name string
+	Port int
+}
+
+func New(name string) *Client { return &Client{Name: name} }
+
+func (c *Client) Close() error { return nil }
+
+func Removed() {}
+
+func Join(sep string, parts ...string) string { return "" }
+
+type Reader interface {
+	Read() string
+}
+
+type sealed interface {
+	seal()
+}
+
+type Sealed interface {
+	Get() int
+	seal()
+}
+
+const Version = "1"
+`
+
+const changedLib = `package lib
+
+type Client struct {
+	Name string
+	Port string
+}
+
+func New(renamed string) *Client { return &Client{Name: renamed} }
+
+func (c *Client) Close() error { return nil }
+
+func (c *Client) Open() error { return nil }
+
+func Join(sep string, parts []string) string { return "" }
+
+type Reader interface {
+	Read() string
+	Close() error
+}
+
+type Sealed interface {
+	Get() int
+	Set(int)
+	seal()
+}
+
+const Version = "2"
+
+func Added() {}
+`
+
+func TestAtRef(t *testing.T) {
+	repo := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n",
+		"lib/lib.go":      originalLib,
+		"old/old.go":      "package old\n\nfunc Gone() {}\n",
+		"cmd/app/main.go": "package main\n\nfunc Exported() {}\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "lib", "lib.go"), []byte(changedLib), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(repo, "old")); err != nil {
+		t.Fatalf("Failed to remove package: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "cmd", "app", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	ctx := context.Background()
+	old, err := AtRef(ctx, repo, "HEAD", analyzer.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to extract API at HEAD: %v", err)
+	}
+	a, err := analyzer.NewAnalyzer(repo)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+	current, err := Current(ctx, a)
+	if err != nil {
+		t.Fatalf("Failed to extract current API: %v", err)
+	}
+
+	report := Compare(old, current)
+	breaking := summarize(report.Breaking)
+	want := []string{
+		"changed example.com/app/lib Client.Port",
+		"changed example.com/app/lib Join",
+		"changed example.com/app/lib Version",
+		"narrowed example.com/app/lib Reader.Close",
+		"removed example.com/app/lib Removed",
+		"removed example.com/app/old ",
+	}
+	if !slices.Equal(breaking, want) {
+		t.Errorf("Expected breaking changes %v, got %v", want, breaking)
+	}
+
+	compatible := summarize(report.Compatible)
+	want = []string{
+		"added example.com/app/lib Added",
+		"added example.com/app/lib Client.Open",
+		"added example.com/app/lib Sealed.Set",
+	}
+	if !slices.Equal(compatible, want) {
+		t.Errorf("Expected compatible changes %v, got %v", want, compatible)
+	}
+
+	if filtered := Compare(old.Filter("old"), current.Filter("old")); len(filtered.Breaking) != 1 || len(filtered.Compatible) != 0 {
+		t.Errorf("Expected only the removed package when filtering, got %+v", filtered)
+	}
+
+	if _, err := AtRef(ctx, repo, "no-such-ref", analyzer.DefaultConfig()); err == nil {
+		t.Error("Expected error for an unknown revision")
+	}
+
+	// The file index of the repository is left alone
+	indexPath := filepath.Join(t.TempDir(), "files.json")
+	if err := os.WriteFile(indexPath, []byte("index of the repository"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	lazy := analyzer.DefaultConfig()
+	lazy.LazyLoading = true
+	lazy.IndexPath = indexPath
+	if _, err := AtRef(ctx, repo, "HEAD", lazy); err != nil {
+		t.Fatalf("Failed to extract API at HEAD lazily: %v", err)
+	}
+	if data, err := os.ReadFile(indexPath); err != nil || string(data) != "index of the repository" {
+		t.Errorf("Expected the repository's index to be kept, got %q (%v)", data, err)
+	}
+	if lazy.IndexPath != indexPath {
+		t.Error("Expected the caller's config to be left unchanged")
+	}
+}
+
+func TestFromSnapshot(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "go.mod"), []byte("module example.com/lib\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "lib.go"), []byte(originalLib), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	a, err := analyzer.NewAnalyzer(repo)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+
+	snap, err := a.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	snapshotPath := filepath.Join(t.TempDir(), "scope.snapshot")
+	if err := snap.WriteFile(snapshotPath); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	old, err := FromSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot API: %v", err)
+	}
+	current, err := Current(context.Background(), a)
+	if err != nil {
+		t.Fatalf("Failed to extract current API: %v", err)
+	}
+	if report := Compare(old, current); len(report.Breaking) != 0 || len(report.Compatible) != 0 {
+		t.Errorf("Expected no changes against a snapshot of the same tree, got %+v", report)
+	}
+	if symbol := current["example.com/lib"]["Join"]; symbol.Type != "func(string, ...string) string" {
+		t.Errorf("Expected variadic signature without parameter names, got %q", symbol.Type)
+	}
+}
+
+// summarize renders changes as "kind package symbol" in sorted order
+func summarize(changes []Change) []string {
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.Kind+" "+change.Package+" "+change.Symbol)
+	}
+	sort.Strings(lines)
+	return lines
+}
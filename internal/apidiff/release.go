@@ -0,0 +1,147 @@
+package apidiff
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Version bumps suggested by a release's changes, as in semantic
+// versioning
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+// Release summarizes the API changes between two releases for a changelog
+type Release struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Bump is the version bump the changes call for: major when any is
+	// breaking, minor when the API only grew and patch when it is unchanged
+	Bump     string           `json:"bump"`
+	Breaking int              `json:"breaking"`
+	Sections []ReleaseSection `json:"sections"`
+	Markdown string           `json:"markdown"`
+}
+
+// ReleaseSection groups the changes of a release by what they mean for
+// importers
+type ReleaseSection struct {
+	Title   string         `json:"title"`
+	Entries []ReleaseEntry `json:"entries"`
+}
+
+// ReleaseEntry is one change listed in a release section
+type ReleaseEntry struct {
+	Change
+	Breaking bool `json:"breaking"`
+}
+
+// releaseSections are the section titles in changelog order
+var releaseSections = []string{
+	"Removed",
+	"Signature changes",
+	"Struct field changes",
+	"Interface changes",
+	"Other changes",
+	"Added",
+}
+
+// NewRelease groups the changes of a report into release sections and
+// renders them as markdown. Sections without changes are left out.
+func NewRelease(from, to string, report *Report) *Release {
+	release := &Release{From: from, To: to, Bump: BumpPatch, Breaking: len(report.Breaking), Sections: []ReleaseSection{}}
+	grouped := make(map[string][]ReleaseEntry)
+	for _, change := range report.Breaking {
+		title := releaseSection(change)
+		grouped[title] = append(grouped[title], ReleaseEntry{Change: change, Breaking: true})
+	}
+	for _, change := range report.Compatible {
+		title := releaseSection(change)
+		grouped[title] = append(grouped[title], ReleaseEntry{Change: change})
+	}
+	for _, title := range releaseSections {
+		if entries := grouped[title]; len(entries) > 0 {
+			release.Sections = append(release.Sections, ReleaseSection{Title: title, Entries: entries})
+		}
+	}
+
+	switch {
+	case len(report.Breaking) > 0:
+		release.Bump = BumpMajor
+	case len(report.Compatible) > 0:
+		release.Bump = BumpMinor
+	}
+	release.Markdown = release.markdown()
+	return release
+}
+
+// releaseSection picks the section a change is listed in
+func releaseSection(change Change) string {
+	switch {
+	case change.SymbolKind == "field":
+		return "Struct field changes"
+	case change.Kind == Removed:
+		return "Removed"
+	case change.Kind == Narrowed, change.Kind == Changed && change.Old == "" && change.New == "":
+		// Interfaces that gained methods or can now be implemented elsewhere
+		return "Interface changes"
+	case change.Kind == Changed && (change.SymbolKind == "func" || change.SymbolKind == "method"):
+		return "Signature changes"
+	case change.Kind == Changed:
+		return "Other changes"
+	default:
+		return "Added"
+	}
+}
+
+// markdown renders the release as a changelog entry
+func (r *Release) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## API changes from %s to %s\n\n", r.From, r.To)
+	if len(r.Sections) == 0 {
+		b.WriteString("No changes to the exported API.\n")
+		return b.String()
+	}
+	total := 0
+	for _, section := range r.Sections {
+		total += len(section.Entries)
+	}
+	fmt.Fprintf(&b, "%d changes, %d breaking; suggested version bump: %s.\n", total, r.Breaking, r.Bump)
+
+	for _, section := range r.Sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		for _, entry := range section.Entries {
+			b.WriteString("- ")
+			if entry.Breaking && section.Title != "Removed" && section.Title != "Signature changes" {
+				b.WriteString("**Breaking:** ")
+			}
+			b.WriteString(entryMarkdown(entry.Change))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// entryMarkdown renders a change as a changelog line naming the symbol
+// qualified with its package name
+func entryMarkdown(change Change) string {
+	if change.Symbol == "" {
+		return fmt.Sprintf("package `%s` was %s", change.Package, change.Kind)
+	}
+	name := fmt.Sprintf("`%s.%s`", path.Base(change.Package), change.Symbol)
+	switch {
+	case change.Kind == Removed:
+		return fmt.Sprintf("%s %s was removed", change.SymbolKind, name)
+	case change.Kind == Added && change.SymbolKind == "type":
+		return fmt.Sprintf("%s type %s was added", change.New, name)
+	case change.Kind == Added:
+		return fmt.Sprintf("%s %s was added: `%s`", change.SymbolKind, name, change.New)
+	case change.Kind == Changed && (change.Old != "" || change.New != ""):
+		return fmt.Sprintf("%s %s changed from `%s` to `%s`", change.SymbolKind, name, change.Old, change.New)
+	default:
+		return fmt.Sprintf("%s: %s", name, change.Message)
+	}
+}
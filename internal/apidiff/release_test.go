@@ -0,0 +1,77 @@
+package apidiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRelease(t *testing.T) {
+	old := API{
+		"example.com/lib": {
+			"Client":      {Kind: "type", Type: "struct"},
+			"Client.Port": {Kind: "field", Type: "int"},
+			"Client.Name": {Kind: "field", Type: "string"},
+			"New":         {Kind: "func", Type: "func(string) *Client"},
+			"Removed":     {Kind: "func", Type: "func()"},
+			"Reader":      {Kind: "type", Type: "interface", Interface: true},
+			"Reader.Read": {Kind: "method", Type: "func() string"},
+			"Version":     {Kind: "const", Type: "string = \"1\""},
+		},
+	}
+	current := API{
+		"example.com/lib": {
+			"Client":       {Kind: "type", Type: "struct"},
+			"Client.Port":  {Kind: "field", Type: "string"},
+			"Client.Name":  {Kind: "field", Type: "string"},
+			"Client.Debug": {Kind: "field", Type: "bool"},
+			"New":          {Kind: "func", Type: "func(string, int) *Client"},
+			"Reader":       {Kind: "type", Type: "interface", Interface: true},
+			"Reader.Read":  {Kind: "method", Type: "func() string"},
+			"Reader.Close": {Kind: "method", Type: "func() error"},
+			"Version":      {Kind: "const", Type: "string = \"2\""},
+			"Options":      {Kind: "type", Type: "struct"},
+		},
+	}
+
+	release := NewRelease("v1.0.0", "v2.0.0", Compare(old, current))
+	if release.Bump != BumpMajor || release.Breaking != 5 {
+		t.Errorf("Expected a major bump for 5 breaking changes, got %s for %d", release.Bump, release.Breaking)
+	}
+	sections := make(map[string][]string)
+	var titles []string
+	for _, section := range release.Sections {
+		titles = append(titles, section.Title)
+		for _, entry := range section.Entries {
+			sections[section.Title] = append(sections[section.Title], entry.Symbol)
+		}
+	}
+	want := "Removed,Signature changes,Struct field changes,Interface changes,Other changes,Added"
+	if got := strings.Join(titles, ","); got != want {
+		t.Errorf("Expected sections %s, got %s", want, got)
+	}
+	if got := strings.Join(sections["Struct field changes"], ","); got != "Client.Port,Client.Debug" {
+		t.Errorf("Expected the changed and added fields, got %s", got)
+	}
+
+	for _, line := range []string{
+		"## API changes from v1.0.0 to v2.0.0",
+		"7 changes, 5 breaking; suggested version bump: major.",
+		"- func `lib.Removed` was removed",
+		"- func `lib.New` changed from `func(string) *Client` to `func(string, int) *Client`",
+		"- **Breaking:** field `lib.Client.Port` changed from `int` to `string`",
+		"- field `lib.Client.Debug` was added: `bool`",
+		"- **Breaking:** `lib.Reader.Close`: interface Reader gained method Close",
+		"- struct type `lib.Options` was added",
+	} {
+		if !strings.Contains(release.Markdown, line) {
+			t.Errorf("Expected %q in the markdown:\n%s", line, release.Markdown)
+		}
+	}
+
+	if release := NewRelease("v1.0.0", "v1.0.1", Compare(old, old)); release.Bump != BumpPatch || !strings.Contains(release.Markdown, "No changes") {
+		t.Errorf("Expected a patch release without changes, got %+v", release)
+	}
+	if release := NewRelease("v1.0.0", "v1.1.0", Compare(old, API{"example.com/lib": old["example.com/lib"], "example.com/new": {}})); release.Bump != BumpMinor {
+		t.Errorf("Expected a minor bump for an added package, got %s", release.Bump)
+	}
+}
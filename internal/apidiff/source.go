@@ -0,0 +1,139 @@
+package apidiff
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// Current extracts the API analyzed by a
+func Current(ctx context.Context, a *analyzer.Analyzer) (API, error) {
+	snap, err := a.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FromResult(snap.Result), nil
+}
+
+// FromSnapshot extracts the API recorded in a snapshot written by scope export
+func FromSnapshot(snapshotPath string) (API, error) {
+	snap, err := analyzer.ReadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	return FromResult(snap.Result), nil
+}
+
+// AtRef extracts the API of the repository at repoPath as of the git
+// revision ref. The revision is checked out into a temporary directory and
+// analyzed with config, without its IndexPath.
+func AtRef(ctx context.Context, repoPath, ref string, config *analyzer.Config) (API, error) {
+	dir, err := os.MkdirTemp("", "scope-apidiff-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := checkout(ctx, repoPath, ref, dir); err != nil {
+		return nil, err
+	}
+
+	// The checkout must not overwrite what the config persists for the
+	// repository itself, such as its file index
+	checkoutConfig := analyzer.DefaultConfig()
+	if config != nil {
+		*checkoutConfig = *config
+	}
+	checkoutConfig.IndexPath = ""
+	a, err := analyzer.NewAnalyzerWithConfig(dir, checkoutConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze %s: %w", ref, err)
+	}
+	defer a.Close()
+	return Current(ctx, a)
+}
+
+// checkout extracts the tree of repoPath at ref into dir with git archive,
+// leaving the working tree and index untouched
+func checkout(ctx context.Context, repoPath, ref, dir string) error {
+	root, err := git(ctx, repoPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	root = strings.TrimSpace(root)
+	prefix, err := git(ctx, repoPath, "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	if _, err := git(ctx, root, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return fmt.Errorf("unknown git revision %q", ref)
+	}
+
+	archive, err := git(ctx, root, "archive", "--format=tar", ref+":"+strings.TrimSpace(prefix))
+	if err != nil {
+		return err
+	}
+	return untar(strings.NewReader(archive), dir)
+}
+
+// untar writes the regular files and directories of a tar archive into dir
+func untar(r io.Reader, dir string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s escapes the checkout directory", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+	}
+}
+
+// git runs a git command in dir and returns its standard output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket holds every cache entry, keyed by cache key
+var boltBucket = []byte("entries")
+
+// BoltStore keeps entries in a bbolt database, one record per entry, so a
+// write costs the size of the entry rather than of the whole cache
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path. bbolt locks the file,
+// so opening fails if another process has it open.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("cache database %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create cache bucket: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Get returns the entry at key
+func (s *BoltStore) Get(key string) (Entry, bool, error) {
+	var entry Entry
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	return entry, found, nil
+}
+
+// Put writes the entry at key
+func (s *BoltStore) Put(key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), data)
+	})
+}
+
+// Delete removes the entry at key
+func (s *BoltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+// DeletePrefix removes every entry whose key starts with prefix. Keys are
+// sorted, so only the matching range is visited.
+func (s *BoltStore) DeletePrefix(prefix string) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		p := []byte(prefix)
+		for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Seek(p) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	return removed, nil
+}
+
+// DeleteFunc removes every entry whose key matches, visiting all of them
+func (s *BoltStore) DeleteFunc(match func(key string) bool) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, _ := c.First(); k != nil; {
+			if !match(string(k)) {
+				k, _ = c.Next()
+				continue
+			}
+			// Next skips a key after a delete, so seek past the deleted one
+			deleted := bytes.Clone(k)
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			removed++
+			k, _ = c.Seek(deleted)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cache entries: %w", err)
+	}
+	return removed, nil
+}
+
+// Usage reports the number of entries and the size of the database
+func (s *BoltStore) Usage() (Usage, error) {
+	usage := Usage{Backend: BackendBolt}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		usage.Entries = tx.Bucket(boltBucket).Stats().KeyN
+		usage.DiskBytes = tx.Size()
+		return nil
+	})
+	if err != nil {
+		return usage, fmt.Errorf("failed to read cache database: %w", err)
+	}
+	return usage, nil
+}
+
+// Clear removes every entry
+func (s *BoltStore) Clear() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(boltBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(boltBucket)
+		return err
+	})
+}
+
+// Close closes the database, releasing its file lock
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
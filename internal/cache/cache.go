@@ -1,109 +1,236 @@
 package cache
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Cache represents an in-memory cache with file persistence
+// Cache is a cache of JSON-encodable values with expiry, kept in a
+// pluggable Store
 type Cache struct {
-	data     map[string]cacheEntry
-	filePath string
-	mu       sync.RWMutex
+	store  Store
+	mu     sync.Mutex
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
-type cacheEntry struct {
-	Value      interface{} `json:"value"`
-	Expiration int64       `json:"expiration"`
+// Stats reports cache usage counters
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
 }
 
-// New creates a new Cache instance
-func New(cacheDir string) (*Cache, error) {
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create cache directory: %w", err)
-	}
+// repoPrefix starts every repository namespace
+const repoPrefix = "repo-"
 
-	filePath := filepath.Join(cacheDir, "featherhead.cache")
-	cache := &Cache{
-		data:     make(map[string]cacheEntry),
-		filePath: filePath,
+// RepoNamespace returns a key prefix unique to a repository, so that entries
+// for different repositories sharing a cache directory do not collide
+func RepoNamespace(repoPath string) string {
+	if abs, err := filepath.Abs(repoPath); err == nil {
+		repoPath = abs
 	}
+	sum := sha256.Sum256([]byte(repoPath))
+	return fmt.Sprintf("%s%x/", repoPrefix, sum[:6])
+}
+
+// InRepoNamespace reports whether key is in the namespace of a repository,
+// rather than one of the keys written before entries were namespaced
+func InRepoNamespace(key string) bool {
+	return strings.HasPrefix(key, repoPrefix)
+}
+
+// New creates a new Cache instance persisted to a JSON file in cacheDir
+func New(cacheDir string) (*Cache, error) {
+	return Open(BackendJSON, cacheDir)
+}
 
-	// Load existing cache if it exists
-	if err := cache.load(); err != nil {
+// Open creates a Cache on the named backend, keeping its files in cacheDir
+func Open(backend, cacheDir string) (*Cache, error) {
+	store, err := OpenStore(backend, cacheDir)
+	if err != nil {
 		return nil, err
 	}
-
-	return cache, nil
+	return NewWithStore(store), nil
 }
 
-// Get retrieves a value from the cache
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// NewWithStore creates a Cache on an already opened store
+func NewWithStore(store Store) *Cache {
+	return &Cache{store: store}
+}
 
-	entry, found := c.data[key]
-	if !found {
+// Get retrieves a value from the cache. Values come back as generic JSON
+// (maps, slices, strings, float64s); use Typed to get them back as the type
+// they were stored as. Nothing is returned once ctx is done, so a cancelled
+// request does not go on to use the value.
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, bool) {
+	var value interface{}
+	if !c.get(ctx, key, time.Time{}, &value) {
 		return nil, false
 	}
+	return value, true
+}
 
-	if entry.Expiration > 0 && entry.Expiration < time.Now().UnixNano() {
+// GetFresh retrieves a value written at or after since. Older entries are
+// stale: they count as misses and are evicted.
+func (c *Cache) GetFresh(ctx context.Context, key string, since time.Time) (interface{}, bool) {
+	var value interface{}
+	if !c.get(ctx, key, since, &value) {
 		return nil, false
 	}
-
-	return entry.Value, true
+	return value, true
 }
 
-// Set adds a value to the cache
-func (c *Cache) Set(key string, value interface{}, duration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// get decodes the entry at key into dst, counting a hit or a miss. Entries
+// written before a non-zero since are evicted. An entry that cannot be read
+// or decoded into dst is a miss.
+func (c *Cache) get(ctx context.Context, key string, since time.Time, dst interface{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	entry, found, err := c.store.Get(key)
+	if err != nil || !found {
+		c.misses.Add(1)
+		return false
+	}
 
-	var exp int64
-	if duration > 0 {
-		exp = time.Now().Add(duration).UnixNano()
+	if !since.IsZero() && entry.Written < since.UnixNano() {
+		c.mu.Lock()
+		if current, ok, err := c.store.Get(key); err == nil && ok && current.Written == entry.Written {
+			c.store.Delete(key)
+		}
+		c.mu.Unlock()
+		c.misses.Add(1)
+		return false
 	}
 
-	c.data[key] = cacheEntry{
-		Value:      value,
-		Expiration: exp,
+	if entry.Expiration > 0 && entry.Expiration < time.Now().UnixNano() {
+		c.misses.Add(1)
+		return false
+	}
+
+	if err := json.Unmarshal(entry.Value, dst); err != nil {
+		c.misses.Add(1)
+		return false
 	}
+	c.hits.Add(1)
+	return true
+}
+
+// Stats returns the hit and miss counters accumulated since the cache was created
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
 
-	return c.save()
+// Usage reports the entries of the cache and the space they take, expired
+// entries included until they are next read
+func (c *Cache) Usage() (Usage, error) {
+	return c.store.Usage()
 }
 
-// load reads the cache from disk
-func (c *Cache) load() error {
-	data, err := os.ReadFile(c.filePath)
-	if os.IsNotExist(err) {
-		return nil
+// Set adds a value to the cache unless ctx is done. The value is stored
+// JSON-encoded.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	data, err := json.Marshal(value)
 	if err != nil {
-		return fmt.Errorf("failed to read cache file: %w", err)
+		return fmt.Errorf("failed to marshal cache value: %w", err)
 	}
 
-	return json.Unmarshal(data, &c.data)
+	var exp int64
+	if duration > 0 {
+		exp = time.Now().Add(duration).UnixNano()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store.Put(key, Entry{
+		Value:      data,
+		Expiration: exp,
+		Written:    time.Now().UnixNano(),
+	})
 }
 
-// save writes the cache to disk
-func (c *Cache) save() error {
-	data, err := json.Marshal(c.data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal cache data: %w", err)
+// InvalidatePrefix removes every entry whose key starts with prefix and
+// returns the number removed
+func (c *Cache) InvalidatePrefix(ctx context.Context, prefix string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store.DeletePrefix(prefix)
+}
 
-	return os.WriteFile(c.filePath, data, 0644)
+// InvalidateFunc removes every entry whose key match reports true for and
+// returns the number removed
+func (c *Cache) InvalidateFunc(ctx context.Context, match func(key string) bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store.DeleteFunc(match)
 }
 
 // Clear removes all entries from the cache
-func (c *Cache) Clear() error {
+func (c *Cache) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.store.Clear()
+}
+
+// Close closes the underlying store
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
+
+// Typed is a view of a Cache for values of one type, which are decoded back
+// into T instead of generic JSON. Entries that do not decode into T are misses.
+type Typed[T any] struct {
+	cache *Cache
+}
+
+// NewTyped returns a view of c for values of type T
+func NewTyped[T any](c *Cache) Typed[T] {
+	return Typed[T]{cache: c}
+}
+
+// Get retrieves a value from the cache
+func (t Typed[T]) Get(ctx context.Context, key string) (T, bool) {
+	var value T
+	if !t.cache.get(ctx, key, time.Time{}, &value) {
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
+
+// GetFresh retrieves a value written at or after since, evicting older entries
+func (t Typed[T]) GetFresh(ctx context.Context, key string, since time.Time) (T, bool) {
+	var value T
+	if !t.cache.get(ctx, key, since, &value) {
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
 
-	c.data = make(map[string]cacheEntry)
-	return c.save()
+// Set adds a value to the cache unless ctx is done
+func (t Typed[T]) Set(ctx context.Context, key string, value T, duration time.Duration) error {
+	return t.cache.Set(ctx, key, value, duration)
 }
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -24,12 +25,12 @@ func TestCache(t *testing.T) {
 	testKey := "test-key"
 	testValue := "test-value"
 
-	err = cache.Set(testKey, testValue, time.Hour)
+	err = cache.Set(context.Background(), testKey, testValue, time.Hour)
 	if err != nil {
 		t.Errorf("Failed to set cache value: %v", err)
 	}
 
-	value, found := cache.Get(testKey)
+	value, found := cache.Get(context.Background(), testKey)
 	if !found {
 		t.Error("Failed to get cached value")
 	}
@@ -40,25 +41,114 @@ func TestCache(t *testing.T) {
 
 	// Test expiration
 	expiredKey := "expired-key"
-	err = cache.Set(expiredKey, "expired-value", time.Millisecond)
+	err = cache.Set(context.Background(), expiredKey, "expired-value", time.Millisecond)
 	if err != nil {
 		t.Errorf("Failed to set expired value: %v", err)
 	}
 
 	time.Sleep(time.Millisecond * 2)
-	_, found = cache.Get(expiredKey)
+	_, found = cache.Get(context.Background(), expiredKey)
 	if found {
 		t.Error("Expired value should not be found")
 	}
 
+	// Test hit and miss accounting
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+
 	// Test clearing cache
-	err = cache.Clear()
+	err = cache.Clear(context.Background())
 	if err != nil {
 		t.Errorf("Failed to clear cache: %v", err)
 	}
 
-	_, found = cache.Get(testKey)
+	_, found = cache.Get(context.Background(), testKey)
 	if found {
 		t.Error("Value should not be found after clearing cache")
 	}
 }
+
+func TestInvalidatePrefix(t *testing.T) {
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	for _, key := range []string{"repo-a/v1/type:Foo", "repo-a/v1/methods:Foo", "repo-b/v1/type:Foo"} {
+		if err := cache.Set(context.Background(), key, "value", time.Hour); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	removed, err := cache.InvalidatePrefix(context.Background(), "repo-a/")
+	if err != nil {
+		t.Fatalf("Failed to invalidate prefix: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 entries removed, got %d", removed)
+	}
+	if _, found := cache.Get(context.Background(), "repo-a/v1/type:Foo"); found {
+		t.Error("Expected repo-a entry to be removed")
+	}
+	if _, found := cache.Get(context.Background(), "repo-b/v1/type:Foo"); !found {
+		t.Error("Expected repo-b entry to be kept")
+	}
+}
+
+func TestGetFresh(t *testing.T) {
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	before := time.Now()
+	if err := cache.Set(context.Background(), "key", "value", time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if _, found := cache.GetFresh(context.Background(), "key", before); !found {
+		t.Error("Expected entry written after since to be fresh")
+	}
+	if _, found := cache.GetFresh(context.Background(), "key", time.Now().Add(time.Second)); found {
+		t.Error("Expected entry written before since to be stale")
+	}
+	if _, found := cache.Get(context.Background(), "key"); found {
+		t.Error("Expected stale entry to be evicted")
+	}
+}
+
+func TestRepoNamespace(t *testing.T) {
+	a, b := RepoNamespace("/src/a"), RepoNamespace("/src/b")
+	if a == b {
+		t.Errorf("Expected distinct namespaces, got %s for both", a)
+	}
+	if a != RepoNamespace("/src/a") {
+		t.Error("Expected namespace to be stable")
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	cache, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if err := cache.Set(context.Background(), "key", "value", time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, found := cache.Get(ctx, "key"); found {
+		t.Error("Expected no value for a cancelled request")
+	}
+	if err := cache.Set(ctx, "other", "value", time.Hour); err == nil {
+		t.Error("Expected error setting a value for a cancelled request")
+	}
+	if _, found := cache.Get(context.Background(), "other"); found {
+		t.Error("Expected cancelled write to be dropped")
+	}
+	if stats := cache.Stats(); stats.Misses != 1 {
+		t.Errorf("Expected only the dropped write's lookup as a miss, got %+v", stats)
+	}
+}
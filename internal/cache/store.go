@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cache backends accepted by Open
+const (
+	// BackendJSON keeps every entry in memory and rewrites a single JSON
+	// file on each write. It suits small caches and is the default.
+	BackendJSON = "json"
+	// BackendBolt keeps entries in a bbolt database and writes only the
+	// entries that change, for large values such as embeddings and snapshots
+	BackendBolt = "bolt"
+	// BackendMemory keeps entries in memory only; they are lost on exit
+	BackendMemory = "memory"
+)
+
+// Entry is a cache entry as held by a Store. Values are stored encoded, so
+// every backend returns them the same way.
+type Entry struct {
+	Value      json.RawMessage `json:"value"`
+	Expiration int64           `json:"expiration"`
+	Written    int64           `json:"written"`
+}
+
+// Store is a cache backend. Implementations must be safe for concurrent
+// use; expiry and hit accounting are left to Cache.
+type Store interface {
+	// Get returns the entry at key and whether it exists
+	Get(key string) (Entry, bool, error)
+	// Put writes the entry at key, replacing any previous one
+	Put(key string, entry Entry) error
+	// Delete removes the entry at key if it exists
+	Delete(key string) error
+	// DeletePrefix removes every entry whose key starts with prefix and
+	// returns the number removed
+	DeletePrefix(prefix string) (int, error)
+	// DeleteFunc removes every entry whose key match reports true for and
+	// returns the number removed
+	DeleteFunc(match func(key string) bool) (int, error)
+	// Clear removes every entry
+	Clear() error
+	// Usage reports the number of entries and the space they take on disk
+	Usage() (Usage, error)
+	// Close releases the backend's resources
+	Close() error
+}
+
+// Usage describes how much a Store holds
+type Usage struct {
+	Backend string `json:"backend"`
+	Entries int    `json:"entries"`
+	// DiskBytes is the size of the backend's file; zero in memory
+	DiskBytes int64 `json:"disk_bytes"`
+}
+
+// Backends returns the names of the available backends in sorted order
+func Backends() []string {
+	return []string{BackendBolt, BackendJSON, BackendMemory}
+}
+
+// OpenStore opens a backend keeping its files in cacheDir. An empty
+// backend name selects BackendJSON.
+func OpenStore(backend, cacheDir string) (Store, error) {
+	if backend == "" {
+		backend = BackendJSON
+	}
+	if backend == BackendMemory {
+		return NewMemoryStore(), nil
+	}
+	if backend != BackendJSON && backend != BackendBolt {
+		return nil, fmt.Errorf("unknown cache backend %q (available: %s)", backend, strings.Join(Backends(), ", "))
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if backend == BackendBolt {
+		return OpenBoltStore(filepath.Join(cacheDir, "scope.bolt"))
+	}
+	return OpenJSONStore(filepath.Join(cacheDir, "featherhead.cache"))
+}
+
+// MemoryStore keeps entries in a map
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+// Get returns the entry at key
+func (s *MemoryStore) Get(key string) (Entry, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, found := s.entries[key]
+	return entry, found, nil
+}
+
+// Put writes the entry at key
+func (s *MemoryStore) Put(key string, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return nil
+}
+
+// Delete removes the entry at key
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// DeletePrefix removes every entry whose key starts with prefix
+func (s *MemoryStore) DeletePrefix(prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return deletePrefix(s.entries, prefix), nil
+}
+
+// DeleteFunc removes every entry whose key matches
+func (s *MemoryStore) DeleteFunc(match func(key string) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return deleteFunc(s.entries, match), nil
+}
+
+// Clear removes every entry
+func (s *MemoryStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]Entry)
+	return nil
+}
+
+// Usage reports the number of entries
+func (s *MemoryStore) Usage() (Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Usage{Backend: BackendMemory, Entries: len(s.entries)}, nil
+}
+
+// Close does nothing; the entries are dropped with the store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// JSONStore keeps entries in memory and persists all of them to a single
+// JSON file after every write
+type JSONStore struct {
+	MemoryStore
+	filePath string
+}
+
+// OpenJSONStore opens the store persisted at filePath, which may not exist yet
+func OpenJSONStore(filePath string) (*JSONStore, error) {
+	s := &JSONStore{MemoryStore: MemoryStore{entries: make(map[string]Entry)}, filePath: filePath}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse cache file: %w", err)
+	}
+	return s, nil
+}
+
+// Put writes the entry at key and saves the file
+func (s *JSONStore) Put(key string, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return s.save()
+}
+
+// Delete removes the entry at key and saves the file
+func (s *JSONStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.entries[key]; !found {
+		return nil
+	}
+	delete(s.entries, key)
+	return s.save()
+}
+
+// DeletePrefix removes every entry whose key starts with prefix and saves
+// the file if any was removed
+func (s *JSONStore) DeletePrefix(prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := deletePrefix(s.entries, prefix)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.save()
+}
+
+// DeleteFunc removes every entry whose key matches and saves the file if
+// any was removed
+func (s *JSONStore) DeleteFunc(match func(key string) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := deleteFunc(s.entries, match)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.save()
+}
+
+// Clear removes every entry and saves the empty file
+func (s *JSONStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]Entry)
+	return s.save()
+}
+
+// Usage reports the number of entries and the size of the file
+func (s *JSONStore) Usage() (Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	usage := Usage{Backend: BackendJSON, Entries: len(s.entries)}
+	info, err := os.Stat(s.filePath)
+	switch {
+	case err == nil:
+		usage.DiskBytes = info.Size()
+	case !os.IsNotExist(err):
+		return usage, fmt.Errorf("failed to stat cache file: %w", err)
+	}
+	return usage, nil
+}
+
+// save writes the entries to disk; the caller holds s.mu
+func (s *JSONStore) save() error {
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache data: %w", err)
+	}
+	return os.WriteFile(s.filePath, data, 0644)
+}
+
+// deletePrefix removes the entries whose key starts with prefix from entries
+func deletePrefix(entries map[string]Entry, prefix string) int {
+	return deleteFunc(entries, func(key string) bool { return strings.HasPrefix(key, prefix) })
+}
+
+// deleteFunc removes the entries whose key matches from entries
+func deleteFunc(entries map[string]Entry, match func(key string) bool) int {
+	removed := 0
+	for key := range entries {
+		if match(key) {
+			delete(entries, key)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackends(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			c, err := Open(backend, dir)
+			if err != nil {
+				t.Fatalf("Failed to open %s cache: %v", backend, err)
+			}
+
+			ctx := context.Background()
+			for _, key := range []string{"repo-a/type:Foo", "repo-a/type:Bar", "repo-b/type:Foo"} {
+				if err := c.Set(ctx, key, map[string]int{"size": len(key)}, time.Hour); err != nil {
+					t.Fatalf("Failed to set %s: %v", key, err)
+				}
+			}
+
+			value, found := c.Get(ctx, "repo-b/type:Foo")
+			if !found {
+				t.Fatal("Failed to get cached value")
+			}
+			if m, ok := value.(map[string]interface{}); !ok || m["size"] != float64(15) {
+				t.Errorf("Expected generic JSON value, got %#v", value)
+			}
+
+			usage, err := c.Usage()
+			if err != nil {
+				t.Fatalf("Failed to get usage: %v", err)
+			}
+			if onDisk := backend != BackendMemory; usage.Backend != backend || usage.Entries != 3 || (usage.DiskBytes > 0) != onDisk {
+				t.Errorf("Expected 3 entries of %s on disk %v, got %+v", backend, onDisk, usage)
+			}
+
+			removed, err := c.InvalidatePrefix(ctx, "repo-a/")
+			if err != nil {
+				t.Fatalf("Failed to invalidate prefix: %v", err)
+			}
+			if removed != 2 {
+				t.Errorf("Expected 2 entries removed, got %d", removed)
+			}
+			if _, found := c.Get(ctx, "repo-a/type:Foo"); found {
+				t.Error("Expected repo-a entry to be removed")
+			}
+			if err := c.Close(); err != nil {
+				t.Fatalf("Failed to close cache: %v", err)
+			}
+
+			reopened, err := Open(backend, dir)
+			if err != nil {
+				t.Fatalf("Failed to reopen %s cache: %v", backend, err)
+			}
+			defer reopened.Close()
+			_, found = reopened.Get(ctx, "repo-b/type:Foo")
+			if persisted := backend != BackendMemory; found != persisted {
+				t.Errorf("Expected entry found after reopening to be %v, got %v", persisted, found)
+			}
+
+			if err := reopened.Clear(ctx); err != nil {
+				t.Fatalf("Failed to clear cache: %v", err)
+			}
+			if _, found := reopened.Get(ctx, "repo-b/type:Foo"); found {
+				t.Error("Expected no entries after clearing")
+			}
+		})
+	}
+}
+
+func TestInvalidateFunc(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			c, err := Open(backend, dir)
+			if err != nil {
+				t.Fatalf("Failed to open %s cache: %v", backend, err)
+			}
+
+			// Legacy keys sort next to each other and around a namespaced one
+			ctx := context.Background()
+			keys := []string{"hierarchy:Foo", "methods:Foo", "repo-a/v2/type:Foo", "type:Bar", "type:Foo"}
+			for _, key := range keys {
+				if err := c.Set(ctx, key, "value", time.Hour); err != nil {
+					t.Fatalf("Failed to set %s: %v", key, err)
+				}
+			}
+			removed, err := c.InvalidateFunc(ctx, func(key string) bool { return !InRepoNamespace(key) })
+			if err != nil {
+				t.Fatalf("Failed to invalidate: %v", err)
+			}
+			if removed != 4 {
+				t.Errorf("Expected 4 entries removed, got %d", removed)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatalf("Failed to close cache: %v", err)
+			}
+
+			if backend == BackendMemory {
+				return
+			}
+			reopened, err := Open(backend, dir)
+			if err != nil {
+				t.Fatalf("Failed to reopen %s cache: %v", backend, err)
+			}
+			defer reopened.Close()
+			for _, key := range keys {
+				_, found := reopened.Get(ctx, key)
+				if want := InRepoNamespace(key); found != want {
+					t.Errorf("Expected %s found after reopening to be %v, got %v", key, want, found)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open("redis", t.TempDir()); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
+
+func TestBoltStoreLocked(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(BackendBolt, dir)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := OpenBoltStore(filepath.Join(dir, "scope.bolt")); err == nil {
+		t.Error("Expected error opening a database that is already open")
+	}
+}
+
+func TestTyped(t *testing.T) {
+	type info struct {
+		Name    string   `json:"name"`
+		Methods []string `json:"methods"`
+	}
+
+	c := NewWithStore(NewMemoryStore())
+	ctx := context.Background()
+	infos := NewTyped[*info](c)
+	if err := infos.Set(ctx, "type:Foo", &info{Name: "Foo", Methods: []string{"Bar"}}, time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	got, found := infos.Get(ctx, "type:Foo")
+	if !found {
+		t.Fatal("Failed to get typed value")
+	}
+	if got.Name != "Foo" || len(got.Methods) != 1 || got.Methods[0] != "Bar" {
+		t.Errorf("Got wrong value: %+v", got)
+	}
+
+	if _, found := NewTyped[int](c).Get(ctx, "type:Foo"); found {
+		t.Error("Expected value of another type to be a miss")
+	}
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %+v", stats)
+	}
+
+	if _, found := infos.GetFresh(ctx, "type:Foo", time.Now().Add(time.Second)); found {
+		t.Error("Expected entry written before since to be stale")
+	}
+	if _, found := infos.Get(ctx, "type:Foo"); found {
+		t.Error("Expected stale entry to be evicted")
+	}
+}
@@ -0,0 +1,288 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// apiDecl is a single exported declaration and its rendered signature
+type apiDecl struct {
+	kind      string
+	signature string
+	file      string
+	line      int
+}
+
+// APICompat compares the exported API of the packages containing files
+// against the same packages at the git revision ref, reporting removed
+// exported symbols and changed signatures. When no files are given, every
+// package tracked at ref is compared.
+func APICompat(ctx context.Context, dir, ref string, files ...string) ([]Diagnostic, error) {
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	pkgDirs := make(map[string]bool)
+	if len(files) == 0 {
+		tracked, err := gitOutput(ctx, root, "ls-tree", "-r", "--name-only", ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range strings.Split(tracked, "\n") {
+			if isAPIFile(file) {
+				pkgDirs[filepath.Dir(filepath.Join(root, file))] = true
+			}
+		}
+	}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if isAPIFile(file) {
+			pkgDirs[filepath.Dir(file)] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(pkgDirs))
+	for d := range pkgDirs {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	var diagnostics []Diagnostic
+	for _, pkgDir := range dirs {
+		rel, err := filepath.Rel(root, pkgDir)
+		if err != nil {
+			return nil, err
+		}
+
+		oldAPI, err := apiAtRef(ctx, root, ref, filepath.ToSlash(rel))
+		if err != nil {
+			return nil, err
+		}
+		newAPI, err := apiInDir(pkgDir)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, compareAPI(pkgDir, oldAPI, newAPI)...)
+	}
+	return diagnostics, nil
+}
+
+// compareAPI reports declarations from old that were removed or changed in new
+func compareAPI(pkgDir string, oldAPI, newAPI map[string]apiDecl) []Diagnostic {
+	names := make([]string, 0, len(oldAPI))
+	for name := range oldAPI {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diagnostics []Diagnostic
+	for _, name := range names {
+		old := oldAPI[name]
+		current, ok := newAPI[name]
+		if !ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     pkgDir,
+				Message:  fmt.Sprintf("exported %s %s was removed", old.kind, name),
+				Check:    "api-compat",
+				Severity: SeverityError,
+			})
+			continue
+		}
+		if current.signature != old.signature {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     current.file,
+				Line:     current.line,
+				Message:  fmt.Sprintf("exported %s %s changed from %q to %q", old.kind, name, old.signature, current.signature),
+				Check:    "api-compat",
+				Severity: SeverityError,
+			})
+		}
+	}
+	return diagnostics
+}
+
+// apiAtRef extracts the exported API of the package in relDir at git revision ref
+func apiAtRef(ctx context.Context, root, ref, relDir string) (map[string]apiDecl, error) {
+	listing, err := gitOutput(ctx, root, "ls-tree", "--name-only", ref, relDir+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string][]byte)
+	for _, file := range strings.Split(strings.TrimSpace(listing), "\n") {
+		if !isAPIFile(file) || filepath.ToSlash(filepath.Dir(file)) != relDir {
+			continue
+		}
+		content, err := gitOutput(ctx, root, "show", ref+":"+file)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Join(root, file)] = []byte(content)
+	}
+	return extractAPI(sources), nil
+}
+
+// apiInDir extracts the exported API of the package files currently in dir
+func apiInDir(dir string) (map[string]apiDecl, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return map[string]apiDecl{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string][]byte)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !isAPIFile(path) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[path] = content
+	}
+	return extractAPI(sources), nil
+}
+
+// extractAPI parses sources and collects exported declarations keyed by
+// qualified name (e.g. "Type", "Type.Method", "Type.Field")
+func extractAPI(sources map[string][]byte) map[string]apiDecl {
+	fset := token.NewFileSet()
+	api := make(map[string]apiDecl)
+
+	add := func(name, kind string, node ast.Node, pos token.Pos) {
+		position := fset.Position(pos)
+		api[name] = apiDecl{kind: kind, signature: render(fset, node), file: position.Filename, line: position.Line}
+	}
+
+	for filename, src := range sources {
+		file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				name := d.Name.Name
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					recv := receiverName(d.Recv.List[0].Type)
+					if !ast.IsExported(recv) {
+						continue
+					}
+					name = recv + "." + name
+				}
+				add(name, "func", d.Type, d.Pos())
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if !s.Name.IsExported() {
+							continue
+						}
+						switch t := s.Type.(type) {
+						case *ast.StructType:
+							api[s.Name.Name] = apiDecl{kind: "type", signature: "struct", file: filename, line: fset.Position(s.Pos()).Line}
+							for _, field := range t.Fields.List {
+								for _, fieldName := range field.Names {
+									if fieldName.IsExported() {
+										add(s.Name.Name+"."+fieldName.Name, "field", field.Type, fieldName.Pos())
+									}
+								}
+							}
+						case *ast.InterfaceType:
+							api[s.Name.Name] = apiDecl{kind: "type", signature: "interface", file: filename, line: fset.Position(s.Pos()).Line}
+							for _, method := range t.Methods.List {
+								for _, methodName := range method.Names {
+									add(s.Name.Name+"."+methodName.Name, "method", method.Type, methodName.Pos())
+								}
+							}
+						default:
+							add(s.Name.Name, "type", s.Type, s.Pos())
+						}
+					case *ast.ValueSpec:
+						kind := "var"
+						if d.Tok == token.CONST {
+							kind = "const"
+						}
+						for _, valueName := range s.Names {
+							if !valueName.IsExported() {
+								continue
+							}
+							if s.Type != nil {
+								add(valueName.Name, kind, s.Type, valueName.Pos())
+							} else {
+								api[valueName.Name] = apiDecl{kind: kind, file: filename, line: fset.Position(valueName.Pos()).Line}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return api
+}
+
+// receiverName returns the base type name of a method receiver expression
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func render(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// isAPIFile reports whether a file contributes to a package's exported API
+func isAPIFile(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") &&
+		!strings.Contains(filepath.ToSlash(path), "/vendor/") && !strings.HasPrefix(filepath.ToSlash(path), "vendor/")
+}
+
+// gitOutput runs a git command in dir and returns its standard output
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,141 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// auditFunc inspects one type-checked file and returns its findings
+type auditFunc func(fset *token.FileSet, info *types.Info, file *ast.File) []Diagnostic
+
+// runAudit runs audit over the given files, or over every non-test Go file
+// in the directory tree when none are given. Each package is type checked
+// as a whole against the standard library so that files can be audited
+// with type information.
+func runAudit(ctx context.Context, dir string, files []string, audit auditFunc) ([]Diagnostic, error) {
+	pkgFiles := make(map[string][]string) // Maps package directory to the files to report on
+	if len(files) == 0 {
+		err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				name := entry.Name()
+				if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isAuditFile(path) {
+				pkgFiles[filepath.Dir(path)] = append(pkgFiles[filepath.Dir(path)], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+	}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if isAuditFile(file) {
+			pkgFiles[filepath.Dir(file)] = append(pkgFiles[filepath.Dir(file)], file)
+		}
+	}
+
+	dirs := make([]string, 0, len(pkgFiles))
+	for d := range pkgFiles {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	imp := stdImporter{importer.Default()}
+	var diagnostics []Diagnostic
+	for _, pkgDir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := auditPackage(pkgDir, pkgFiles[pkgDir], imp, audit)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+	return diagnostics, nil
+}
+
+// isAuditFile reports whether path is a Go source file covered by the audit
+func isAuditFile(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
+
+// stdImporter imports standard library packages only. Packages of the
+// repository and its dependencies are left unresolved, which is enough to
+// type the standard library and builtin expressions the audits look at.
+type stdImporter struct {
+	imp types.Importer
+}
+
+// Import implements types.Importer
+func (s stdImporter) Import(path string) (*types.Package, error) {
+	if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+		return nil, fmt.Errorf("%s is not a standard library package", path)
+	}
+	return s.imp.Import(path)
+}
+
+// auditPackage type checks the package in pkgDir and audits the given files
+func auditPackage(pkgDir string, report []string, imp types.Importer, audit auditFunc) ([]Diagnostic, error) {
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, entry := range entries {
+		path := filepath.Join(pkgDir, entry.Name())
+		if entry.IsDir() || !isAuditFile(path) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, file)
+	}
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	_, _ = conf.Check(parsed[0].Name.Name, fset, parsed, info) // Errors from unresolved imports are expected
+
+	wanted := make(map[string]bool, len(report))
+	for _, file := range report {
+		wanted[file] = true
+	}
+	var diagnostics []Diagnostic
+	for _, file := range parsed {
+		filename := fset.Position(file.Pos()).Filename
+		if wanted[filename] {
+			diagnostics = append(diagnostics, audit(fset, info, file)...)
+		}
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,433 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic represents a single problem reported by a check
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	// Analyzer names the go vet analyzer behind a vet finding, or compiler
+	// and typecheck for build and type errors
+	Analyzer string `json:"analyzer,omitempty"`
+}
+
+// Severity levels for diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// String formats the diagnostic in the conventional file:line:col form
+func (d Diagnostic) String() string {
+	pos := d.File
+	if d.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, d.Line)
+		if d.Column > 0 {
+			pos = fmt.Sprintf("%s:%d", pos, d.Column)
+		}
+	}
+	if pos == "" {
+		return fmt.Sprintf("[%s] %s", d.Check, d.Message)
+	}
+	return fmt.Sprintf("%s: [%s] %s", pos, d.Check, d.Message)
+}
+
+// key identifies a diagnostic for diffing between runs
+func (d Diagnostic) key() string {
+	return fmt.Sprintf("%s\x00%d\x00%d\x00%s\x00%s", d.File, d.Line, d.Column, d.Check, d.Message)
+}
+
+// Build runs `go build` for the given package patterns in dir
+func Build(ctx context.Context, dir string, pkgs ...string) ([]Diagnostic, error) {
+	output, err := runGo(ctx, dir, append([]string{"build", "-o", os.DevNull}, defaultPatterns(pkgs)...)...)
+	if err != nil && output == "" {
+		return nil, err
+	}
+	return ParseCompilerOutput(output, dir, "build"), nil
+}
+
+// Vet runs `go vet` for the given package patterns in dir
+func Vet(ctx context.Context, dir string, pkgs ...string) ([]Diagnostic, error) {
+	output, err := runGo(ctx, dir, append([]string{"vet", "-json"}, defaultPatterns(pkgs)...)...)
+	if err != nil && output == "" {
+		return nil, err
+	}
+	return ParseVetOutput(output, dir), nil
+}
+
+// Test runs `go test` for the given package patterns in dir and reports failing tests
+func Test(ctx context.Context, dir string, pkgs ...string) ([]Diagnostic, error) {
+	packages, err := ListPackages(ctx, dir, pkgs...)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		dirs[pkg.ImportPath] = pkg.Dir
+	}
+
+	output, err := runGo(ctx, dir, append([]string{"test"}, defaultPatterns(pkgs)...)...)
+	if err != nil && output == "" {
+		return nil, err
+	}
+	return ParseTestOutput(output, dirs), nil
+}
+
+var (
+	// positionPattern matches "file.go:line:col: message" and "file.go:line: message"
+	positionPattern = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+	// posnPattern matches the "file.go:line:col" position of a vet finding
+	posnPattern = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?$`)
+
+	// failPattern matches a failing test header emitted by `go test`
+	failPattern = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+
+	// packageFailPattern matches the per-package failure summary
+	packageFailPattern = regexp.MustCompile(`^FAIL\s+(\S+)\s`)
+)
+
+// ParseCompilerOutput converts go build/vet output into diagnostics. Relative
+// file paths are resolved against dir.
+func ParseCompilerOutput(output, dir, check string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// go vet prefixes its findings with "vet: "
+		line = strings.TrimPrefix(line, "vet: ")
+
+		match := positionPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		diagnostic := Diagnostic{
+			File:     resolvePath(dir, match[1]),
+			Message:  match[4],
+			Check:    check,
+			Severity: SeverityError,
+			Analyzer: "compiler",
+		}
+		diagnostic.Line, _ = strconv.Atoi(match[2])
+		if match[3] != "" {
+			diagnostic.Column, _ = strconv.Atoi(match[3])
+		}
+		if check == "vet" {
+			diagnostic.Severity = SeverityWarning
+			diagnostic.Analyzer = "typecheck"
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// vetFinding is a single finding in go vet -json output
+type vetFinding struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// ParseVetOutput converts go vet -json output into diagnostics named after
+// the analyzer that reported them. go vet prints one JSON object per package,
+// mapping analyzer names to findings; type errors that stop a package from
+// being analyzed are printed as plain text and parsed like compiler output.
+func ParseVetOutput(output, dir string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var text, object strings.Builder
+	inObject := false
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case !inObject && line == "{":
+			inObject = true
+			object.Reset()
+			object.WriteString(line)
+		case inObject:
+			object.WriteString(line)
+			if line == "}" {
+				inObject = false
+				diagnostics = append(diagnostics, parseVetObject(object.String(), dir)...)
+			}
+		default:
+			text.WriteString(line)
+			text.WriteString("\n")
+		}
+	}
+	return append(diagnostics, ParseCompilerOutput(text.String(), dir, "vet")...)
+}
+
+// parseVetObject converts one package's go vet -json object into diagnostics
+func parseVetObject(data, dir string) []Diagnostic {
+	var packages map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &packages); err != nil {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	for _, analyzers := range packages {
+		for analyzer, raw := range analyzers {
+			var findings []vetFinding
+			if err := json.Unmarshal(raw, &findings); err != nil {
+				// Analyzer failures are reported as {"error": "..."}
+				continue
+			}
+			for _, finding := range findings {
+				diagnostic := Diagnostic{
+					Message:  finding.Message,
+					Check:    "vet",
+					Severity: SeverityWarning,
+					Analyzer: analyzer,
+				}
+				if match := posnPattern.FindStringSubmatch(finding.Posn); match != nil {
+					diagnostic.File = resolvePath(dir, match[1])
+					diagnostic.Line, _ = strconv.Atoi(match[2])
+					diagnostic.Column, _ = strconv.Atoi(match[3])
+				} else {
+					diagnostic.File = resolvePath(dir, finding.Posn)
+				}
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+	}
+	Sort(diagnostics)
+	return diagnostics
+}
+
+// ParseTestOutput converts `go test` output into one diagnostic per failing
+// test, attaching the first reported file position and the failure messages.
+// dirs maps import paths to package directories so that test file names can
+// be resolved to full paths.
+func ParseTestOutput(output string, dirs map[string]string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var pending []Diagnostic
+	var current *Diagnostic
+	var messages []string
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		if len(messages) > 0 {
+			current.Message = fmt.Sprintf("%s: %s", current.Message, strings.Join(messages, "; "))
+		}
+		pending = append(pending, *current)
+		current = nil
+		messages = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := failPattern.FindStringSubmatch(line); match != nil {
+			finish()
+			current = &Diagnostic{
+				Message:  fmt.Sprintf("%s failed", match[1]),
+				Check:    "test",
+				Severity: SeverityError,
+			}
+			continue
+		}
+
+		// The package summary tells us where the pending test files live
+		if match := packageFailPattern.FindStringSubmatch(line); match != nil {
+			finish()
+			for _, d := range pending {
+				if d.File != "" && !filepath.IsAbs(d.File) {
+					if pkgDir, ok := dirs[match[1]]; ok {
+						d.File = filepath.Join(pkgDir, d.File)
+					}
+				}
+				diagnostics = append(diagnostics, d)
+			}
+			pending = nil
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if match := positionPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			if current.File == "" {
+				current.File = match[1]
+				current.Line, _ = strconv.Atoi(match[2])
+			}
+			messages = append(messages, match[4])
+		}
+	}
+
+	// Output that ended without a package summary (e.g. a panic)
+	finish()
+	return append(diagnostics, pending...)
+}
+
+// Package describes a package as reported by `go list`
+type Package struct {
+	ImportPath   string   `json:"ImportPath"`
+	Dir          string   `json:"Dir"`
+	GoFiles      []string `json:"GoFiles"`
+	TestGoFiles  []string `json:"TestGoFiles"`
+	XTestGoFiles []string `json:"XTestGoFiles"`
+	Imports      []string `json:"Imports"`
+	TestImports  []string `json:"TestImports"`
+	XTestImports []string `json:"XTestImports"`
+}
+
+// ListPackages runs `go list` for the given package patterns in dir
+func ListPackages(ctx context.Context, dir string, pkgs ...string) ([]Package, error) {
+	args := append([]string{"list", "-e", "-json"}, defaultPatterns(pkgs)...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var packages []Package
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pkg Package
+		if err := decoder.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// ImpactedPackages returns the import paths of packages that contain one of
+// the changed files, plus every package that transitively imports them
+// (including through tests), sorted by import path
+func ImpactedPackages(packages []Package, changedFiles []string) []string {
+	byDir := make(map[string]string, len(packages))
+	importers := make(map[string][]string)
+	for _, pkg := range packages {
+		byDir[filepath.Clean(pkg.Dir)] = pkg.ImportPath
+		seen := make(map[string]bool)
+		for _, imports := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+			for _, imp := range imports {
+				if !seen[imp] && imp != pkg.ImportPath {
+					seen[imp] = true
+					importers[imp] = append(importers[imp], pkg.ImportPath)
+				}
+			}
+		}
+	}
+
+	impacted := make(map[string]bool)
+	var queue []string
+	for _, file := range changedFiles {
+		if pkg, ok := byDir[filepath.Clean(filepath.Dir(file))]; ok && !impacted[pkg] {
+			impacted[pkg] = true
+			queue = append(queue, pkg)
+		}
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[pkg] {
+			if !impacted[importer] {
+				impacted[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(impacted))
+	for pkg := range impacted {
+		result = append(result, pkg)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Diff compares two diagnostic runs and returns the diagnostics that are new
+// in current and the ones from previous that no longer appear
+func Diff(previous, current []Diagnostic) (added, resolved []Diagnostic) {
+	prevKeys := make(map[string]bool, len(previous))
+	for _, d := range previous {
+		prevKeys[d.key()] = true
+	}
+	curKeys := make(map[string]bool, len(current))
+	for _, d := range current {
+		curKeys[d.key()] = true
+		if !prevKeys[d.key()] {
+			added = append(added, d)
+		}
+	}
+	for _, d := range previous {
+		if !curKeys[d.key()] {
+			resolved = append(resolved, d)
+		}
+	}
+	return added, resolved
+}
+
+// Sort orders diagnostics by file, line, column, and check
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Check < b.Check
+	})
+}
+
+// runGo executes the go command in dir and returns its combined output
+func runGo(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+func defaultPatterns(pkgs []string) []string {
+	if len(pkgs) == 0 {
+		return []string{"./..."}
+	}
+	return pkgs
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
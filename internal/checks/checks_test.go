@@ -0,0 +1,403 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCompilerOutput(t *testing.T) {
+	output := `# example.com/m/pkg
+pkg/foo.go:12:3: undefined: bar
+pkg/foo.go:20: missing return
+vet: pkg/bar.go:7:2: unreachable code
+some unrelated line
+`
+	diagnostics := ParseCompilerOutput(output, "/repo", "build")
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	first := diagnostics[0]
+	if first.File != filepath.Join("/repo", "pkg/foo.go") || first.Line != 12 || first.Column != 3 {
+		t.Errorf("Unexpected position: %+v", first)
+	}
+	if first.Message != "undefined: bar" || first.Check != "build" || first.Severity != SeverityError {
+		t.Errorf("Unexpected diagnostic: %+v", first)
+	}
+	if diagnostics[1].Column != 0 || diagnostics[1].Line != 20 {
+		t.Errorf("Expected line-only position, got %+v", diagnostics[1])
+	}
+	if diagnostics[2].File != filepath.Join("/repo", "pkg/bar.go") {
+		t.Errorf("Expected vet prefix to be stripped, got %+v", diagnostics[2])
+	}
+
+	if got := ParseCompilerOutput("pkg/a.go:1:1: x", "", "vet"); got[0].Severity != SeverityWarning {
+		t.Errorf("Expected vet diagnostics to be warnings, got %s", got[0].Severity)
+	}
+}
+
+func TestParseVetOutput(t *testing.T) {
+	output := `# example.com/m/a
+{
+	"example.com/m/a": {
+		"printf": [
+			{
+				"posn": "/repo/a/a.go:6:14",
+				"end": "/repo/a/a.go:6:16",
+				"message": "fmt.Printf format %d has arg \"x\" of wrong type string"
+			}
+		],
+		"assign": [
+			{
+				"posn": "a/a.go:8:2",
+				"message": "self-assignment of x"
+			}
+		]
+	}
+}
+# example.com/m/b
+vet: b/b.go:3:23: cannot use "s" (untyped string constant) as int value in return statement
+`
+	diagnostics := ParseVetOutput(output, "/repo")
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	printf := diagnostics[0]
+	if printf.Analyzer != "printf" || printf.File != "/repo/a/a.go" || printf.Line != 6 || printf.Column != 14 || printf.Severity != SeverityWarning {
+		t.Errorf("Unexpected printf finding: %+v", printf)
+	}
+	if diagnostics[1].Analyzer != "assign" || diagnostics[1].File != filepath.Join("/repo", "a/a.go") {
+		t.Errorf("Unexpected assign finding: %+v", diagnostics[1])
+	}
+	if diagnostics[2].Analyzer != "typecheck" || diagnostics[2].Line != 3 {
+		t.Errorf("Unexpected type error: %+v", diagnostics[2])
+	}
+}
+
+func TestParseTestOutput(t *testing.T) {
+	output := `--- FAIL: TestAdd (0.00s)
+    add_test.go:10: expected 3, got 4
+    add_test.go:11: second failure
+--- FAIL: TestSub (0.00s)
+FAIL
+FAIL	example.com/m/math	0.002s
+ok  	example.com/m/other	0.001s
+`
+	dirs := map[string]string{"example.com/m/math": "/repo/math"}
+	diagnostics := ParseTestOutput(output, dirs)
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	add := diagnostics[0]
+	if add.File != "/repo/math/add_test.go" || add.Line != 10 {
+		t.Errorf("Unexpected position: %+v", add)
+	}
+	if add.Message != "TestAdd failed: expected 3, got 4; second failure" {
+		t.Errorf("Unexpected message: %s", add.Message)
+	}
+	if diagnostics[1].Message != "TestSub failed" || diagnostics[1].File != "" {
+		t.Errorf("Unexpected diagnostic: %+v", diagnostics[1])
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := Diagnostic{File: "a.go", Line: 1, Message: "a", Check: "build"}
+	b := Diagnostic{File: "b.go", Line: 2, Message: "b", Check: "vet"}
+	c := Diagnostic{File: "c.go", Line: 3, Message: "c", Check: "test"}
+
+	added, resolved := Diff([]Diagnostic{a, b}, []Diagnostic{b, c})
+	if len(added) != 1 || added[0] != c {
+		t.Errorf("Expected %v to be added, got %v", c, added)
+	}
+	if len(resolved) != 1 || resolved[0] != a {
+		t.Errorf("Expected %v to be resolved, got %v", a, resolved)
+	}
+}
+
+func TestImpactedPackages(t *testing.T) {
+	packages := []Package{
+		{ImportPath: "m/core", Dir: "/repo/core"},
+		{ImportPath: "m/api", Dir: "/repo/api", Imports: []string{"m/core"}},
+		{ImportPath: "m/cmd", Dir: "/repo/cmd", Imports: []string{"m/api"}},
+		{ImportPath: "m/tools", Dir: "/repo/tools", TestImports: []string{"m/core"}},
+		{ImportPath: "m/other", Dir: "/repo/other"},
+	}
+
+	impacted := ImpactedPackages(packages, []string{"/repo/core/core.go"})
+	expected := []string{"m/api", "m/cmd", "m/core", "m/tools"}
+	if strings.Join(impacted, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, impacted)
+	}
+
+	if impacted := ImpactedPackages(packages, []string{"/elsewhere/x.go"}); len(impacted) != 0 {
+		t.Errorf("Expected no impacted packages, got %v", impacted)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scope-checks-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"go.mod":  "module example.com/broken\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {\n\tundefinedFunc()\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	diagnostics, err := Build(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	if diagnostics[0].Line != 4 || !strings.Contains(diagnostics[0].Message, "undefinedFunc") {
+		t.Errorf("Unexpected diagnostic: %+v", diagnostics[0])
+	}
+
+	packages, err := ListPackages(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("ListPackages failed: %v", err)
+	}
+	if len(packages) != 1 || packages[0].ImportPath != "example.com/broken" {
+		t.Errorf("Unexpected packages: %+v", packages)
+	}
+}
+
+func TestVet(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/vetted\n\ngo 1.21\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Printf(\"%d\\n\", \"x\")\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	diagnostics, err := Vet(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("Vet failed: %v", err)
+	}
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.Analyzer != "printf" || d.Line != 6 || d.File != filepath.Join(tempDir, "main.go") {
+		t.Errorf("Unexpected diagnostic: %+v", d)
+	}
+}
+
+func TestAPICompat(t *testing.T) {
+	repo := t.TempDir()
+	libFile := filepath.Join(repo, "lib", "lib.go")
+	if err := os.MkdirAll(filepath.Dir(libFile), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	original := `package lib
+
+type Client struct {
+	Name string
+	Port int
+}
+
+func New(name string) *Client { return &Client{Name: name} }
+
+func (c *Client) Close() error { return nil }
+
+func Removed() {}
+`
+	if err := os.WriteFile(libFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+
+	changed := `package lib
+
+type Client struct {
+	Name string
+	Port string
+	unexported bool
+}
+
+func New(name string, port int) *Client { return &Client{Name: name} }
+
+func (c *Client) Close() error { return nil }
+
+func Added() {}
+`
+	if err := os.WriteFile(libFile, []byte(changed), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	diagnostics, err := APICompat(context.Background(), repo, "HEAD", libFile)
+	if err != nil {
+		t.Fatalf("APICompat failed: %v", err)
+	}
+
+	var messages []string
+	for _, d := range diagnostics {
+		messages = append(messages, d.Message)
+	}
+	joined := strings.Join(messages, "\n")
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got:\n%s", joined)
+	}
+	for _, want := range []string{"Client.Port changed", "func New changed", "func Removed was removed"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in:\n%s", want, joined)
+		}
+	}
+}
+
+func TestTimeAudit(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/clock\n\ngo 1.21\n",
+		"clock.go": `package clock
+
+import (
+	"time"
+
+	"example.com/clock/other"
+)
+
+func Stamp() (time.Time, bool) {
+	start := time.Now()
+	stored := time.Now().UTC()
+	day, _ := time.Parse("2006-01-02", "2024-01-01")
+	zoned, _ := time.Parse(time.RFC3339, "2024-01-01T00:00:00Z")
+	var ptr *time.Time
+	_ = ptr == nil
+	_ = time.Since(start)
+	_ = other.Value
+	return stored, day == zoned || start != stored
+}
+`,
+		"other/other.go": "package other\n\nvar Value = 1\n",
+		"clock_test.go":  "package clock\n\nimport \"time\"\n\nvar now = time.Now()\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	diagnostics, err := TimeAudit(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("TimeAudit failed: %v", err)
+	}
+	var got []string
+	for _, d := range diagnostics {
+		if d.Check != "time-audit" || d.File != filepath.Join(tempDir, "clock.go") {
+			t.Errorf("Unexpected diagnostic: %+v", d)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", d.Line, strings.SplitN(d.Message, " ", 2)[0]))
+	}
+	want := []string{"10:time.Now()", "12:time.Parse", "18:comparing", "18:comparing"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected findings %v, got %v", want, got)
+	}
+
+	diagnostics, err = Run(context.Background(), Target{Dir: tempDir, Files: []string{"other/other.go"}}, "time-audit")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no findings in other.go, got %v", diagnostics)
+	}
+}
+
+func TestNumericAudit(t *testing.T) {
+	tempDir := t.TempDir()
+	src := `package shop
+
+type Item struct {
+	UnitPrice float64
+	Weight    float64
+	Cents     int64
+}
+
+func Compute(items []Item, n int, ratio float64) (int32, uint) {
+	last := items[len(items)-1]
+	_ = last
+	small := int32(n)
+	unsigned := uint(n)
+	_ = int64(n)
+	_ = int(ratio)
+	_ = ratio == 0.1
+	_ = ratio == 0
+	_ = ratio != ratio
+	for i := len(items) - 1; i >= 0; i-- {
+	}
+	return small, unsigned
+}
+
+func Guarded(items []Item) Item {
+	if len(items) == 0 {
+		return Item{}
+	}
+	return items[len(items)-1]
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "shop.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	diagnostics, err := NumericAudit(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("NumericAudit failed: %v", err)
+	}
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, fmt.Sprintf("%d:%s", d.Line, d.Severity))
+	}
+	want := []string{"4:error", "10:warning", "12:warning", "13:warning", "15:warning", "16:warning"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected findings %v, got %v", want, diagnostics)
+	}
+
+	tests := map[string][]string{
+		"unitPrice":  {"unit", "price"},
+		"total_cost": {"total", "cost"},
+		"HTTPFees":   {"http", "fees"},
+	}
+	for name, want := range tests {
+		if got := splitWords(name); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("splitWords(%q) = %v, expected %v", name, got, want)
+		}
+		if !isCurrencyName(name) {
+			t.Errorf("Expected %s to name a currency amount", name)
+		}
+	}
+	if isCurrencyName("feedback") || isCurrencyName("Weight") {
+		t.Error("Expected feedback and Weight not to name currency amounts")
+	}
+}
@@ -0,0 +1,234 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+)
+
+// currencyWords are the name components suggesting a variable holds money
+var currencyWords = map[string]bool{
+	"amount": true, "balance": true, "cost": true, "currency": true,
+	"fee": true, "invoice": true, "money": true, "payment": true,
+	"payout": true, "price": true, "refund": true, "revenue": true,
+	"salary": true, "tax": true,
+}
+
+// NumericAudit flags arithmetic that can silently overflow or lose
+// precision: integer conversions that narrow or change signedness,
+// float-to-integer conversions, len() or cap() subtractions not guarded by a
+// length comparison in the same function, floating-point equality
+// comparisons, and currency amounts stored in floats. Currency findings are
+// errors, the rest warnings. When no files are given the whole directory
+// tree is audited. Test files are skipped.
+func NumericAudit(ctx context.Context, dir string, files ...string) ([]Diagnostic, error) {
+	return runAudit(ctx, dir, files, auditNumeric)
+}
+
+// auditNumeric reports the numeric findings in a single file
+func auditNumeric(fset *token.FileSet, info *types.Info, file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	add := func(pos token.Pos, severity, message string) {
+		position := fset.Position(pos)
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     position.Filename,
+			Line:     position.Line,
+			Column:   position.Column,
+			Message:  message,
+			Check:    "numeric-audit",
+			Severity: severity,
+		})
+	}
+	sizes := types.SizesFor("gc", "amd64")
+
+	for ident, obj := range info.Defs {
+		v, ok := obj.(*types.Var)
+		if !ok || ident.Pos() < file.Pos() || ident.Pos() > file.End() {
+			continue
+		}
+		if isFloat(v.Type()) && isCurrencyName(v.Name()) {
+			add(ident.Pos(), SeverityError, fmt.Sprintf("%s is a currency amount stored as %s; floats cannot represent most decimal fractions exactly, use integer minor units (e.g. cents) or a decimal type", v.Name(), v.Type()))
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				auditLenArithmetic(n.Body, add)
+			}
+		case *ast.FuncLit:
+			auditLenArithmetic(n.Body, add)
+		case *ast.CallExpr:
+			if message := conversionRisk(info, sizes, n); message != "" {
+				add(n.Pos(), SeverityWarning, message)
+			}
+		case *ast.BinaryExpr:
+			if (n.Op == token.EQL || n.Op == token.NEQ) && isFloat(info.TypeOf(n.X)) && isFloat(info.TypeOf(n.Y)) &&
+				!isConstantZero(info, n.X) && !isConstantZero(info, n.Y) &&
+				types.ExprString(n.X) != types.ExprString(n.Y) { // x != x is the NaN test
+				add(n.OpPos, SeverityWarning, fmt.Sprintf("floating-point comparison with %s is unreliable after rounding; compare the difference against a tolerance", n.Op))
+			}
+		}
+		return true
+	})
+	Sort(diagnostics)
+	return diagnostics
+}
+
+// conversionRisk describes the overflow risk of a conversion, or returns ""
+func conversionRisk(info *types.Info, sizes types.Sizes, call *ast.CallExpr) string {
+	if len(call.Args) != 1 {
+		return ""
+	}
+	fun, ok := info.Types[call.Fun]
+	if !ok || !fun.IsType() {
+		return ""
+	}
+	operand, ok := info.Types[call.Args[0]]
+	if !ok || operand.Value != nil || operand.Type == nil {
+		return "" // The compiler rejects constants that do not fit
+	}
+	to, ok := fun.Type.Underlying().(*types.Basic)
+	if !ok || to.Info()&types.IsInteger == 0 {
+		return ""
+	}
+	from, ok := operand.Type.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case from.Info()&types.IsFloat != 0:
+		return fmt.Sprintf("conversion from %s to %s truncates, and the result is implementation-defined when the value is out of range", operand.Type, fun.Type)
+	case from.Info()&types.IsInteger == 0:
+		return ""
+	case sizes.Sizeof(to) < sizes.Sizeof(from):
+		return fmt.Sprintf("conversion from %s to %s can overflow; check the range before converting", operand.Type, fun.Type)
+	case sizes.Sizeof(to) == sizes.Sizeof(from) && from.Info()&types.IsUnsigned == 0 && to.Info()&types.IsUnsigned != 0:
+		return fmt.Sprintf("conversion from %s to %s wraps negative values; check the sign before converting", operand.Type, fun.Type)
+	case sizes.Sizeof(to) == sizes.Sizeof(from) && from.Info()&types.IsUnsigned != 0 && to.Info()&types.IsUnsigned == 0:
+		return fmt.Sprintf("conversion from %s to %s turns large values negative; check the range before converting", operand.Type, fun.Type)
+	}
+	return ""
+}
+
+// auditLenArithmetic flags len(x) - n and cap(x) - n in a function body
+// unless the function compares the length of x somewhere, e.g. in an
+// if len(x) == 0 guard. Nested function literals are audited separately.
+// Loop initializers such as i := len(x) - 1 are fine since the loop
+// condition rejects the negative result.
+func auditLenArithmetic(body *ast.BlockStmt, add func(token.Pos, string, string)) {
+	guarded := make(map[string]bool)
+	var subtractions []*ast.BinaryExpr
+	loopInits := make(map[ast.Node]bool)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt:
+			if n.Init != nil {
+				loopInits[n.Init] = true
+			}
+		case *ast.BinaryExpr:
+			switch n.Op {
+			case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+				for _, operand := range []ast.Expr{n.X, n.Y} {
+					if arg := lengthOf(operand); arg != "" {
+						guarded[arg] = true
+					}
+				}
+			case token.SUB:
+				if lengthOf(n.X) != "" {
+					subtractions = append(subtractions, n)
+				}
+			}
+		}
+		return true
+	})
+
+	inLoopInit := func(expr ast.Expr) bool {
+		for init := range loopInits {
+			if expr.Pos() >= init.Pos() && expr.End() <= init.End() {
+				return true
+			}
+		}
+		return false
+	}
+	for _, sub := range subtractions {
+		arg := lengthOf(sub.X)
+		if guarded[arg] || inLoopInit(sub) {
+			continue
+		}
+		add(sub.OpPos, SeverityWarning, fmt.Sprintf("%s can be negative when %s is shorter than %s; guard it with a length check", types.ExprString(sub), arg, types.ExprString(sub.Y)))
+	}
+}
+
+// lengthOf returns the argument of a len or cap call, rendered as source
+func lengthOf(expr ast.Expr) string {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return ""
+	}
+	if ident, ok := call.Fun.(*ast.Ident); !ok || (ident.Name != "len" && ident.Name != "cap") {
+		return ""
+	}
+	return types.ExprString(call.Args[0])
+}
+
+// isFloat reports whether t is a floating-point or complex type
+func isFloat(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsFloat|types.IsComplex) != 0
+}
+
+// isConstantZero reports whether expr is the constant 0
+func isConstantZero(info *types.Info, expr ast.Expr) bool {
+	value := info.Types[expr].Value
+	return value != nil && constant.Sign(value) == 0
+}
+
+// isCurrencyName reports whether an identifier names a money amount, e.g.
+// unitPrice, total_cost or Fees
+func isCurrencyName(name string) bool {
+	for _, word := range splitWords(name) {
+		if currencyWords[strings.TrimSuffix(word, "s")] || currencyWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitWords splits a camelCase or snake_case identifier into lowercase words
+func splitWords(name string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
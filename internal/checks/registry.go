@@ -0,0 +1,141 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Target describes what a check should analyze: the module directory, the
+// changed files (empty means everything), the package patterns impacted by
+// those files, and the git revision to compare against (HEAD when empty)
+type Target struct {
+	Dir      string
+	Files    []string
+	Packages []string
+	Base     string
+}
+
+// Check runs a single named check against a target
+type Check func(ctx context.Context, target Target) ([]Diagnostic, error)
+
+var (
+	registry   = make(map[string]Check)
+	registryMu sync.RWMutex
+)
+
+func init() {
+	Register("format", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return Format(ctx, target.Dir, target.Files...)
+	})
+	Register("build_check", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return Build(ctx, target.Dir, target.Packages...)
+	})
+	Register("lint", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return Vet(ctx, target.Dir, target.Packages...)
+	})
+	Register("test", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return Test(ctx, target.Dir, target.Packages...)
+	})
+	Register("api-compat", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		base := target.Base
+		if base == "" {
+			base = "HEAD"
+		}
+		return APICompat(ctx, target.Dir, base, target.Files...)
+	})
+	Register("time-audit", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return TimeAudit(ctx, target.Dir, target.Files...)
+	})
+	Register("numeric-audit", func(ctx context.Context, target Target) ([]Diagnostic, error) {
+		return NumericAudit(ctx, target.Dir, target.Files...)
+	})
+}
+
+// Register adds or replaces a named check
+func Register(name string, check Check) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = check
+}
+
+// Lookup returns the check registered under name
+func Lookup(name string) (Check, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	check, ok := registry[name]
+	return check, ok
+}
+
+// Names returns the names of all registered checks in sorted order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes the named checks against target and returns their combined,
+// sorted diagnostics. Unknown check names are reported as errors.
+func Run(ctx context.Context, target Target, names ...string) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for _, name := range names {
+		check, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown check %q (available: %s)", name, strings.Join(Names(), ", "))
+		}
+		found, err := check(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("check %s failed: %w", name, err)
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+	Sort(diagnostics)
+	return diagnostics, nil
+}
+
+// Format reports Go files that are not gofmt-formatted. When no files are
+// given the whole directory tree is checked.
+func Format(ctx context.Context, dir string, files ...string) ([]Diagnostic, error) {
+	var goFiles []string
+	for _, file := range files {
+		if strings.HasSuffix(file, ".go") {
+			goFiles = append(goFiles, file)
+		}
+	}
+	if len(files) > 0 && len(goFiles) == 0 {
+		return nil, nil
+	}
+	if len(goFiles) == 0 {
+		goFiles = []string{"."}
+	}
+
+	cmd := exec.CommandContext(ctx, "gofmt", append([]string{"-l"}, goFiles...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("gofmt failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" || strings.Contains(line, string(filepath.Separator)+"vendor"+string(filepath.Separator)) {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     resolvePath(dir, line),
+			Message:  "file is not gofmt-formatted",
+			Check:    "format",
+			Severity: SeverityError,
+		})
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,144 @@
+package checks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Output formats supported by WriteReport
+const (
+	FormatText   = "text"
+	FormatGitHub = "github"
+	FormatGitLab = "gitlab"
+)
+
+// WriteReport renders diagnostics in the given format. File paths are made
+// relative to root, which is what CI systems expect for annotations.
+func WriteReport(w io.Writer, format, root string, diagnostics []Diagnostic) error {
+	switch format {
+	case FormatText, "":
+		return writeText(w, diagnostics)
+	case FormatGitHub:
+		return writeGitHub(w, root, diagnostics)
+	case FormatGitLab:
+		return writeGitLab(w, root, diagnostics)
+	default:
+		return fmt.Errorf("unknown report format %q (supported: %s, %s, %s)", format, FormatText, FormatGitHub, FormatGitLab)
+	}
+}
+
+func writeText(w io.Writer, diagnostics []Diagnostic) error {
+	for _, d := range diagnostics {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGitHub emits GitHub Actions workflow commands, which the runner turns
+// into inline annotations on the pull request
+func writeGitHub(w io.Writer, root string, diagnostics []Diagnostic) error {
+	for _, d := range diagnostics {
+		level := "error"
+		if d.Severity == SeverityWarning {
+			level = "warning"
+		}
+
+		var props []string
+		if d.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(relativePath(root, d.File)))
+		}
+		if d.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", d.Line))
+		}
+		if d.Column > 0 {
+			props = append(props, fmt.Sprintf("col=%d", d.Column))
+		}
+		props = append(props, "title="+escapeGitHubProperty("scope "+d.Check))
+
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", level, strings.Join(props, ","), escapeGitHubData(d.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitLabIssue is a single entry of a GitLab code quality report
+type gitLabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitLabLocation `json:"location"`
+}
+
+type gitLabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitLabLines `json:"lines"`
+}
+
+type gitLabLines struct {
+	Begin int `json:"begin"`
+}
+
+// writeGitLab emits a GitLab code quality (Code Climate subset) JSON report
+func writeGitLab(w io.Writer, root string, diagnostics []Diagnostic) error {
+	issues := make([]gitLabIssue, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		severity := "major"
+		if d.Severity == SeverityWarning {
+			severity = "minor"
+		}
+		line := d.Line
+		if line == 0 {
+			line = 1
+		}
+		path := relativePath(root, d.File)
+
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s:%s", path, d.Line, d.Check, d.Message)))
+		issues = append(issues, gitLabIssue{
+			Description: d.Message,
+			CheckName:   d.Check,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    severity,
+			Location: gitLabLocation{
+				Path:  path,
+				Lines: gitLabLines{Begin: line},
+			},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+func relativePath(root, path string) string {
+	if root == "" || path == "" || !filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// escapeGitHubData escapes the message part of a workflow command
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
@@ -0,0 +1,68 @@
+package checks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteReportGitHub(t *testing.T) {
+	diagnostics := []Diagnostic{
+		{File: "/repo/pkg/a.go", Line: 3, Column: 7, Message: "undefined: x\nsecond line", Check: "build", Severity: SeverityError},
+		{File: "/repo/pkg/b.go", Line: 9, Message: "unreachable code", Check: "lint", Severity: SeverityWarning},
+	}
+
+	var out strings.Builder
+	if err := WriteReport(&out, FormatGitHub, "/repo", diagnostics); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 annotations, got:\n%s", out.String())
+	}
+	if lines[0] != "::error file=pkg/a.go,line=3,col=7,title=scope build::undefined: x%0Asecond line" {
+		t.Errorf("Unexpected annotation: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "::warning file=pkg/b.go,line=9,") {
+		t.Errorf("Unexpected annotation: %s", lines[1])
+	}
+}
+
+func TestWriteReportGitLab(t *testing.T) {
+	diagnostics := []Diagnostic{
+		{File: "/repo/pkg/a.go", Line: 3, Message: "undefined: x", Check: "build", Severity: SeverityError},
+		{File: "/repo/pkg", Message: "exported func F was removed", Check: "api-compat", Severity: SeverityError},
+	}
+
+	var out strings.Builder
+	if err := WriteReport(&out, FormatGitLab, "/repo", diagnostics); err != nil {
+		t.Fatalf("WriteReport failed: %v", err)
+	}
+
+	var issues []map[string]interface{}
+	if err := json.Unmarshal([]byte(out.String()), &issues); err != nil {
+		t.Fatalf("Report is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+	location := issues[0]["location"].(map[string]interface{})
+	if location["path"] != "pkg/a.go" || issues[0]["severity"] != "major" || issues[0]["check_name"] != "build" {
+		t.Errorf("Unexpected issue: %v", issues[0])
+	}
+	if issues[0]["fingerprint"] == issues[1]["fingerprint"] {
+		t.Error("Expected distinct fingerprints")
+	}
+	lines := issues[1]["location"].(map[string]interface{})["lines"].(map[string]interface{})
+	if lines["begin"].(float64) != 1 {
+		t.Errorf("Expected file-level issue to begin at line 1, got %v", lines["begin"])
+	}
+}
+
+func TestWriteReportUnknownFormat(t *testing.T) {
+	var out strings.Builder
+	if err := WriteReport(&out, "xml", "", nil); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
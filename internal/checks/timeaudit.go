@@ -0,0 +1,101 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// TimeAudit catalogs time handling that commonly causes time zone bugs:
+// calls to time.Now() not converted with .UTC(), time.Parse with a layout
+// that has no zone (the result is silently UTC), and time.Time values
+// compared with == or !=, which also compares locations and monotonic clock
+// readings. When no files are given the whole directory tree is audited.
+// Test files are skipped.
+func TimeAudit(ctx context.Context, dir string, files ...string) ([]Diagnostic, error) {
+	return runAudit(ctx, dir, files, auditTime)
+}
+
+// auditTime reports the time handling findings in a single file
+func auditTime(fset *token.FileSet, info *types.Info, file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	add := func(pos token.Pos, message string) {
+		position := fset.Position(pos)
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     position.Filename,
+			Line:     position.Line,
+			Column:   position.Column,
+			Message:  message,
+			Check:    "time-audit",
+			Severity: SeverityWarning,
+		})
+	}
+
+	// Calls converted with time.Now().UTC() are fine
+	utc := make(map[*ast.CallExpr]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if call, ok := n.X.(*ast.CallExpr); ok && n.Sel.Name == "UTC" {
+				utc[call] = true
+			}
+		case *ast.CallExpr:
+			switch timeFunc(info, n.Fun) {
+			case "Now":
+				if !utc[n] {
+					add(n.Pos(), "time.Now() returns local time; use time.Now().UTC() for values that are stored, serialized or compared across hosts, and time.Since/Sub on the unconverted value to measure durations with the monotonic clock")
+				}
+			case "Parse":
+				if len(n.Args) == 2 && !layoutHasZone(info, n.Args[0]) {
+					add(n.Pos(), "time.Parse with a layout without a time zone returns UTC; use time.ParseInLocation with an explicit *time.Location")
+				}
+			}
+		case *ast.BinaryExpr:
+			if (n.Op == token.EQL || n.Op == token.NEQ) && (isTimeValue(info, n.X) || isTimeValue(info, n.Y)) {
+				add(n.OpPos, fmt.Sprintf("comparing time.Time with %s also compares the location and monotonic clock reading; use t.Equal(u)", n.Op))
+			}
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// timeFunc returns the name of the time package function expr refers to
+func timeFunc(info *types.Info, expr ast.Expr) string {
+	sel, ok := ast.Unparen(expr).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "time" || fn.Type().(*types.Signature).Recv() != nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+// isTimeValue reports whether expr is a time.Time (not a pointer to one)
+func isTimeValue(info *types.Info, expr ast.Expr) bool {
+	named, ok := info.TypeOf(expr).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time"
+}
+
+// layoutHasZone reports whether a time.Parse layout carries zone
+// information. Layouts that are not constant are given the benefit of the
+// doubt.
+func layoutHasZone(info *types.Info, layout ast.Expr) bool {
+	value := info.Types[layout].Value
+	if value == nil || value.Kind() != constant.String {
+		return true
+	}
+	s := constant.StringVal(value)
+	for _, zone := range []string{"MST", "Z07", "-07"} {
+		if strings.Contains(s, zone) {
+			return true
+		}
+	}
+	return false
+}
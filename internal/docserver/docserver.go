@@ -0,0 +1,162 @@
+// Package docserver serves a minimal godoc-style HTML view of the analyzed
+// repository: its packages, their exported API with doc comments, and the
+// source files they link to. It lets people supervising an agent browse the
+// same information the agent queries.
+package docserver
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"go/doc/comment"
+	"html/template"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// Paths served by a Server
+const (
+	PackagePath = "/pkg/"
+	SourcePath  = "/src/"
+)
+
+// Source is what a Server reads from; *analyzer.Analyzer satisfies it
+type Source interface {
+	Packages() []string
+	PackageDocumentation(ctx context.Context, importPath string) (*analyzer.PackageDocumentation, error)
+	ReadRange(file string, opts analyzer.RangeOptions) (*analyzer.SourceRange, error)
+	RepoPath() string
+}
+
+// Server renders the documentation of a Source as HTML
+type Server struct {
+	source    Source
+	templates *template.Template
+}
+
+// New creates a Server for source
+func New(source Source) (*Server, error) {
+	s := &Server{source: source}
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"doc":     renderDoc,
+		"srcLink": s.sourceLink,
+		"relPath": s.relPath,
+		"fileLink": func(filename string) string {
+			return SourcePath + s.relPath(filename)
+		},
+		"pkgLink": func(importPath string) string { return PackagePath + importPath },
+	}).ParseFS(templateFiles, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse documentation templates: %w", err)
+	}
+	s.templates = tmpl
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the package index at /, package
+// pages under /pkg/ and source files under /src/
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.serveIndex)
+	mux.HandleFunc("GET "+PackagePath+"{path...}", s.servePackage)
+	mux.HandleFunc("GET "+SourcePath+"{path...}", s.serveSource)
+	return mux
+}
+
+// serveIndex lists the packages of the repository
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "index.html", struct {
+		Root     string
+		Packages []string
+	}{s.source.RepoPath(), s.source.Packages()})
+}
+
+// servePackage shows the documentation of one package
+func (s *Server) servePackage(w http.ResponseWriter, r *http.Request) {
+	pkg, err := s.source.PackageDocumentation(r.Context(), r.PathValue("path"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.render(w, "package.html", pkg)
+}
+
+// sourceLine is a numbered line of a source file
+type sourceLine struct {
+	Number int
+	Text   string
+}
+
+// serveSource shows a Go file of the repository with numbered lines, which
+// positions link to as #L<line>
+func (s *Server) serveSource(w http.ResponseWriter, r *http.Request) {
+	rel := r.PathValue("path")
+	if !strings.HasSuffix(rel, ".go") {
+		http.Error(w, "only Go files are served", http.StatusNotFound)
+		return
+	}
+	src, err := s.source.ReadRange(filepath.FromSlash(rel), analyzer.RangeOptions{StartLine: 1, EndLine: math.MaxInt32})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(src.Text, "\n"), "\n")
+	numbered := make([]sourceLine, len(lines))
+	for i, line := range lines {
+		numbered[i] = sourceLine{Number: i + 1, Text: strings.TrimSuffix(line, "\r")}
+	}
+	s.render(w, "source.html", struct {
+		Path  string
+		Lines []sourceLine
+	}{rel, numbered})
+}
+
+// render executes a template into a buffer first, so a failing template
+// yields an error response rather than half a page
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to render %s: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// sourceLink returns the URL of a position's line in the source view, or
+// an empty string for positions outside the repository
+func (s *Server) sourceLink(pos analyzer.Position) string {
+	if pos.Filename == "" {
+		return ""
+	}
+	rel := s.relPath(pos.Filename)
+	if rel == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s#L%d", SourcePath, rel, pos.Line)
+}
+
+// relPath returns a filename relative to the repository with forward
+// slashes, or an empty string for files outside it
+func (s *Server) relPath(filename string) string {
+	rel, err := filepath.Rel(s.source.RepoPath(), filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// renderDoc renders a doc comment as HTML
+func renderDoc(text string) template.HTML {
+	var parser comment.Parser
+	var printer comment.Printer
+	return template.HTML(printer.HTML(parser.Parse(text)))
+}
@@ -0,0 +1,99 @@
+package docserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestServer(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `// Package store keeps values
+package store
+
+// Cache is a store in memory.
+//
+// It is safe for <concurrent> use.
+type Cache struct{}
+
+// Get returns the value of a key
+func (c *Cache) Get(key string) string { return "" }
+`,
+		".env": "TOKEN=secret\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	a, err := analyzer.NewAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	defer a.Close()
+	docs, err := New(a)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	server := httptest.NewServer(docs.Handler())
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/")
+	if status != http.StatusOK || !strings.Contains(body, `<a href="/pkg/example.com/app/store">example.com/app/store</a>`) {
+		t.Errorf("Expected the index to link the store package, got %d:\n%s", status, body)
+	}
+
+	status, body = get("/pkg/example.com/app/store")
+	if status != http.StatusOK {
+		t.Fatalf("Expected the package page, got %d:\n%s", status, body)
+	}
+	for _, want := range []string{
+		"<h1>Package store</h1>",
+		"<p>Package store keeps values\n",
+		"<pre>type Cache struct{}</pre>",
+		"It is safe for &lt;concurrent&gt; use.",
+		`<h3 id="Cache.Get">Cache.Get <a href="/src/store/store.go#L10">source</a></h3>`,
+		`<a href="/src/store/store.go">store/store.go</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the package page to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	status, body = get("/src/store/store.go")
+	if status != http.StatusOK || !strings.Contains(body, `<span id="L7"><a href="#L7">7</a>type Cache struct{}</span>`) {
+		t.Errorf("Expected numbered source lines, got %d:\n%s", status, body)
+	}
+
+	for _, path := range []string{"/pkg/example.com/app/missing", "/src/.env", "/src/missing.go"} {
+		if status, _ := get(path); status != http.StatusNotFound {
+			t.Errorf("Expected %s to be not found, got %d", path, status)
+		}
+	}
+}
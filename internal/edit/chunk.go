@@ -0,0 +1,191 @@
+package edit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// maxParagraphLines caps the chunks of files that are not Go, which are
+// otherwise split at blank lines
+const maxParagraphLines = 100
+
+// Chunk is a part of a file identified by a hash of its content. A Go file
+// is chunked at its package-level declarations, so that a chunk keeps its
+// ID while the lines before it shift and is found again by replace_chunk.
+// Chunks cover the file: the blank lines after a chunk belong to it, but
+// not to its ID.
+type Chunk struct {
+	// ID is the first 12 hex digits of the SHA-256 of the chunk's text
+	// without trailing blank lines
+	ID        string `json:"id"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	// Kind is header (the package clause and imports), func, method, type,
+	// var or const in Go files, and empty in other files
+	Kind string `json:"kind,omitempty"`
+	// Name is the declared name, Type.Method for methods; empty for groups
+	// and the header
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+
+	// start and end are the offsets of the text the ID hashes
+	start, end int
+}
+
+// Chunks splits a file into chunks: Go files at their package-level
+// declarations, doc comments included, and other files, or Go files that
+// do not parse, at blank lines
+func Chunks(filename string, src []byte) []Chunk {
+	lineStarts := []int{0}
+	for i, b := range src {
+		if b == '\n' && i+1 < len(src) {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	if len(src) == 0 {
+		return nil
+	}
+
+	var starts []chunkStart
+	if strings.HasSuffix(filename, ".go") {
+		starts = declStarts(filename, src)
+	}
+	if starts == nil {
+		starts = paragraphStarts(src, lineStarts)
+	}
+
+	chunks := make([]Chunk, len(starts))
+	for i, s := range starts {
+		endLine := len(lineStarts)
+		if i+1 < len(starts) {
+			endLine = starts[i+1].line - 1
+		}
+		from := lineStarts[s.line-1]
+		to := len(src)
+		if endLine < len(lineStarts) {
+			to = lineStarts[endLine]
+		}
+		text := src[from:to]
+		trimmed := bytes.TrimRight(text, " \t\r\n")
+		sum := sha256.Sum256(trimmed)
+		chunks[i] = Chunk{
+			ID:        hex.EncodeToString(sum[:6]),
+			StartLine: s.line,
+			EndLine:   endLine,
+			Kind:      s.kind,
+			Name:      s.name,
+			Text:      string(text),
+			start:     from,
+			end:       from + len(trimmed),
+		}
+	}
+	return chunks
+}
+
+// chunkStart is the first line of a chunk
+type chunkStart struct {
+	line       int
+	kind, name string
+}
+
+// declStarts returns the first lines of the header and of each
+// package-level declaration after the imports, or nil when the file does
+// not parse
+func declStarts(filename string, src []byte) []chunkStart {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	starts := []chunkStart{{line: 1, kind: "header"}}
+	for _, decl := range file.Decls {
+		from := decl.Pos()
+		var kind, name string
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			kind, name = "func", decl.Name.Name
+			if decl.Recv != nil {
+				kind, name = "method", receiverType(decl)+"."+decl.Name.Name
+			}
+			if decl.Doc != nil {
+				from = decl.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				continue
+			}
+			kind = decl.Tok.String()
+			if len(decl.Specs) == 1 {
+				name = chunkSpecName(decl.Specs[0])
+			}
+			if decl.Doc != nil {
+				from = decl.Doc.Pos()
+			}
+		}
+		line := fset.Position(from).Line
+		if line > starts[len(starts)-1].line {
+			starts = append(starts, chunkStart{line: line, kind: kind, name: name})
+		}
+	}
+	return starts
+}
+
+// chunkSpecName returns the first name a spec declares
+func chunkSpecName(spec ast.Spec) string {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Name.Name
+	case *ast.ValueSpec:
+		return spec.Names[0].Name
+	}
+	return ""
+}
+
+// paragraphStarts returns the first lines of the paragraphs of a file,
+// splitting those longer than maxParagraphLines
+func paragraphStarts(src []byte, lineStarts []int) []chunkStart {
+	starts := []chunkStart{{line: 1}}
+	blank := false
+	for i, from := range lineStarts {
+		line := i + 1
+		to := len(src)
+		if line < len(lineStarts) {
+			to = lineStarts[line]
+		}
+		isBlank := len(bytes.TrimSpace(src[from:to])) == 0
+		if line > 1 && !isBlank && (blank || line-starts[len(starts)-1].line >= maxParagraphLines) {
+			starts = append(starts, chunkStart{line: line})
+		}
+		blank = isBlank
+	}
+	return starts
+}
+
+// replaceChunk replaces the text of the chunk with ID Chunk with Code.
+// The chunk must occur once; a chunk that changed since it was read is not
+// found.
+func replaceChunk(filename string, src []byte, e Edit) (splice, error) {
+	if e.Chunk == "" {
+		return splice{}, fmt.Errorf("chunk is required")
+	}
+	var found []Chunk
+	for _, c := range Chunks(filename, src) {
+		if c.ID == e.Chunk {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return splice{}, fmt.Errorf("chunk %s not found; the file changed since it was read", e.Chunk)
+	case 1:
+		return splice{found[0].start, found[0].end, e.Code}, nil
+	default:
+		return splice{}, fmt.Errorf("chunk %s occurs %d times (lines %d and %d); use replace", e.Chunk, len(found), found[0].StartLine, found[1].StartLine)
+	}
+}
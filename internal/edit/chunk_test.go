@@ -0,0 +1,76 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	chunks := Chunks("cart.go", []byte(testSource))
+	want := []struct {
+		kind, name         string
+		startLine, endLine int
+	}{
+		{"header", "", 1, 4},
+		{"type", "Cart", 5, 9},
+		{"method", "Cart.Add", 10, 14},
+		{"func", "Total", 15, 19},
+		{"func", "describe", 20, 22},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected %d chunks, got %+v", len(want), chunks)
+	}
+	var text strings.Builder
+	for i, c := range chunks {
+		if c.Kind != want[i].kind || c.Name != want[i].name || c.StartLine != want[i].startLine || c.EndLine != want[i].endLine || len(c.ID) != 12 {
+			t.Errorf("Chunk %d: expected %+v, got %+v", i, want[i], c)
+		}
+		text.WriteString(c.Text)
+	}
+	if text.String() != testSource {
+		t.Error("Expected the chunks to cover the file")
+	}
+
+	// Chunks keep their IDs when the lines before them shift
+	shifted := strings.Replace(testSource, "type Cart struct {\n", "type Cart struct {\n\tOwner string\n\n", 1)
+	moved := Chunks("cart.go", []byte(shifted))
+	if moved[1].ID == chunks[1].ID || moved[3].ID != chunks[3].ID || moved[3].StartLine != 17 {
+		t.Errorf("Expected only the changed chunk to get a new ID, got %+v", moved)
+	}
+
+	paragraphs := Chunks("notes.md", []byte("# Notes\n\nFirst\nparagraph\n\n\nSecond\n"))
+	if len(paragraphs) != 3 || paragraphs[1].StartLine != 3 || paragraphs[1].EndLine != 6 || paragraphs[2].Text != "Second\n" || paragraphs[0].Kind != "" {
+		t.Errorf("Expected chunks at blank lines, got %+v", paragraphs)
+	}
+}
+
+func TestReplaceChunk(t *testing.T) {
+	chunks := Chunks("cart.go", []byte(testSource))
+	total := chunks[3].ID
+	shifted := strings.Replace(testSource, "import \"fmt\"\n", "import (\n\t\"fmt\"\n\t\"strings\"\n)\n\nvar _ = strings.TrimSpace\n", 1)
+
+	out, err := Source("cart.go", []byte(shifted), []Edit{{Op: ReplaceChunk, Chunk: total, Code: "// Total counts the items\nfunc Total(c *Cart) int { return len(c.Items) }"}})
+	if err != nil {
+		t.Fatalf("Failed to replace chunk: %v", err)
+	}
+	if !strings.Contains(string(out), "// Total counts the items\nfunc Total(c *Cart) int { return len(c.Items) }\n\nfunc describe") || strings.Contains(string(out), "returns the number") {
+		t.Errorf("Expected the chunk replaced, got\n%s", out)
+	}
+
+	if _, err := Source("cart.go", out, []Edit{{Op: ReplaceChunk, Chunk: total, Code: "func Total() {}"}}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a replaced chunk not to be found again, got %v", err)
+	}
+
+	removed, err := Source("cart.go", []byte(testSource), []Edit{{Op: ReplaceChunk, Chunk: chunks[4].ID}})
+	if err != nil {
+		t.Fatalf("Failed to remove chunk: %v", err)
+	}
+	if strings.Contains(string(removed), "describe") {
+		t.Errorf("Expected describe removed, got\n%s", removed)
+	}
+
+	twice := testSource + "\n// Total returns the number of items\nfunc Total(c *Cart) int {\n\treturn len(c.Items)\n}\n"
+	if _, err := Source("cart.go", []byte(twice), []Edit{{Op: ReplaceChunk, Chunk: total, Code: "func Total() {}"}}); err == nil || !strings.Contains(err.Error(), "occurs 2 times") {
+		t.Errorf("Expected an ambiguous chunk to fail, got %v", err)
+	}
+}
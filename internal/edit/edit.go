@@ -0,0 +1,799 @@
+// Package edit applies structural edits to Go source files: adding struct
+// fields, methods, declarations and imports, removing imports, replacing
+// function bodies, replacing source text at a line and replacing chunks
+// read by their ID. Each edit
+// locates its target in the syntax tree, splices the new code in at the
+// positions the tree gives, and the result is reparsed and formatted with
+// go/format. A file is only written when every edit succeeds.
+package edit
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Edit operations
+const (
+	AddField     = "add_field"
+	AddMethod    = "add_method"
+	ReplaceBody  = "replace_body"
+	AddImport    = "add_import"
+	AddDecl      = "add_decl"
+	Replace      = "replace"
+	RemoveImport = "remove_import"
+	ReplaceChunk = "replace_chunk"
+)
+
+// Edit is a single change to a file
+type Edit struct {
+	Op string `json:"op"`
+	// Type is the struct of add_field and the receiver type of add_method
+	Type string `json:"type,omitempty"`
+	// Func names the function (Name) or method (Type.Name) whose body
+	// replace_body replaces
+	Func string `json:"func,omitempty"`
+	// Code is the field declarations of add_field, the method declaration
+	// of add_method, the statements of the new body of replace_body
+	// without the enclosing braces, the package-level declarations of
+	// add_decl, or the text replacing Old or Chunk; empty removes it
+	Code string `json:"code,omitempty"`
+	// Path and the optional Name are the import of add_import, and Path
+	// the import of remove_import
+	Path string `json:"path,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Old is the text replace replaces, which starts on Line (1-based).
+	// The occurrence closest to Column, when given, is replaced.
+	Old    string `json:"old,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Chunk is the ID of the chunk replace_chunk replaces
+	Chunk string `json:"chunk,omitempty"`
+}
+
+// Result describes the effect of edits on a file
+type Result struct {
+	File string `json:"file"`
+	// Diff is the unified diff of the change; empty when nothing changed
+	Diff string `json:"diff"`
+	// Applied is set when the file was written, which dry runs and edits
+	// changing nothing never do
+	Applied bool `json:"applied"`
+}
+
+// Apply applies the edits to a file in order and, unless dryRun is set,
+// replaces it atomically with the result. Added methods and declarations
+// are also checked against the other files of the package.
+func Apply(filename string, edits []Edit, dryRun bool) (*Result, error) {
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no edits given")
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if err := checkPackage(filename, src, edits); err != nil {
+		return nil, err
+	}
+	out, err := Source(filename, src, edits)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{File: filename, Diff: Unified(filepath.Base(filename), src, out)}
+	if dryRun || bytes.Equal(src, out) {
+		return result, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", filename, err)
+	}
+	if err := writeFile(filename, out, info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// Create applies the edits to a new file holding only the clause of
+// package pkgName and, unless dryRun is set, writes the result. The file
+// must not exist yet.
+func Create(filename, pkgName string, edits []Edit, dryRun bool) (*Result, error) {
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no edits given")
+	}
+	if !token.IsIdentifier(pkgName) {
+		return nil, fmt.Errorf("invalid package name %q", pkgName)
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return nil, fmt.Errorf("%s already exists", filename)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat %s: %w", filename, err)
+	}
+	src := []byte("package " + pkgName + "\n")
+	if err := checkPackage(filename, src, edits); err != nil {
+		return nil, err
+	}
+	out, err := Source(filename, src, edits)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{File: filename, Diff: Unified(filepath.Base(filename), nil, out)}
+	if dryRun {
+		return result, nil
+	}
+	if err := writeFile(filename, out, 0644); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// Source applies the edits to the content of a file in order and returns
+// the formatted result
+func Source(filename string, src []byte, edits []Edit) ([]byte, error) {
+	for i, e := range edits {
+		out, err := applyEdit(filename, src, e)
+		if err != nil {
+			return nil, fmt.Errorf("edit %d (%s): %w", i+1, e.Op, err)
+		}
+		src = out
+	}
+	return src, nil
+}
+
+// applyEdit applies one edit and formats the result
+func applyEdit(filename string, src []byte, e Edit) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	tf := fset.File(file.Pos())
+
+	var s splice
+	switch e.Op {
+	case AddField:
+		s, err = addField(file, tf, src, e)
+	case AddMethod:
+		s, err = addMethod(file, tf, src, e)
+	case ReplaceBody:
+		s, err = replaceBody(file, tf, e)
+	case AddImport:
+		s, err = addImport(file, tf, src, e)
+	case AddDecl:
+		s, err = addDecl(file, src, e)
+	case Replace:
+		s, err = replace(tf, src, e)
+	case RemoveImport:
+		s, err = removeImport(file, tf, src, e)
+	case ReplaceChunk:
+		s, err = replaceChunk(filename, src, e)
+	default:
+		return nil, fmt.Errorf("unknown operation %q (expected %s, %s, %s, %s, %s, %s, %s or %s)", e.Op, AddField, AddMethod, ReplaceBody, AddImport, AddDecl, Replace, RemoveImport, ReplaceChunk)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.start == s.end && s.text == "" {
+		return src, nil
+	}
+
+	out := make([]byte, 0, len(src)+len(s.text))
+	out = append(out, src[:s.start]...)
+	out = append(out, s.text...)
+	out = append(out, src[s.end:]...)
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, fmt.Errorf("edited source is not valid Go: %w", err)
+	}
+	return formatted, nil
+}
+
+// splice replaces the bytes from start to end with text
+type splice struct {
+	start, end int
+	text       string
+}
+
+// addField appends field declarations to a struct
+func addField(file *ast.File, tf *token.File, src []byte, e Edit) (splice, error) {
+	if e.Type == "" || strings.TrimSpace(e.Code) == "" {
+		return splice{}, fmt.Errorf("type and code are required")
+	}
+	spec := findType(file, e.Type)
+	if spec == nil {
+		return splice{}, fmt.Errorf("type %s not found", e.Type)
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return splice{}, fmt.Errorf("%s is not a struct", e.Type)
+	}
+
+	snippet, err := parser.ParseFile(token.NewFileSet(), "", "package p\ntype _ struct {\n"+e.Code+"\n}", parser.SkipObjectResolution)
+	if err != nil {
+		return splice{}, fmt.Errorf("invalid field declaration: %w", err)
+	}
+	fields := snippet.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List
+	if len(fields) == 0 {
+		return splice{}, fmt.Errorf("code declares no field")
+	}
+	existing := make(map[string]bool)
+	for _, field := range st.Fields.List {
+		for _, name := range fieldNames(field) {
+			existing[name] = true
+		}
+	}
+	for _, field := range fields {
+		for _, name := range fieldNames(field) {
+			if existing[name] {
+				return splice{}, fmt.Errorf("struct %s already has a field %s", e.Type, name)
+			}
+			existing[name] = true
+		}
+	}
+
+	// Fields go on their own lines before the closing brace, which may
+	// share a line with the last field or the opening brace
+	closing := tf.Offset(st.Fields.Closing)
+	text := strings.TrimSpace(e.Code) + "\n"
+	if line := bytes.TrimRight(src[:closing], " \t"); !bytes.HasSuffix(line, []byte("\n")) {
+		text = "\n" + text
+	}
+	return splice{closing, closing, text}, nil
+}
+
+// addMethod adds a method after the last method of its receiver type in
+// the file, or else after the type's declaration or at the end of the file
+func addMethod(file *ast.File, tf *token.File, src []byte, e Edit) (splice, error) {
+	method, err := parseMethod(e)
+	if err != nil {
+		return splice{}, err
+	}
+	typeName := receiverType(method)
+
+	insert := -1
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && receiverType(decl) == typeName {
+				if decl.Name.Name == method.Name.Name {
+					return splice{}, fmt.Errorf("%s already has a method %s", typeName, method.Name.Name)
+				}
+				insert = tf.Offset(decl.End())
+			}
+		case *ast.GenDecl:
+			if insert < 0 && declaresType(decl, typeName) {
+				insert = tf.Offset(decl.End())
+			}
+		}
+	}
+	if insert < 0 {
+		insert = len(src)
+	}
+	return splice{insert, insert, "\n\n" + strings.TrimSpace(e.Code) + "\n"}, nil
+}
+
+// parseMethod parses the method declaration of an add_method edit and
+// checks its receiver against the edit's type
+func parseMethod(e Edit) (*ast.FuncDecl, error) {
+	if strings.TrimSpace(e.Code) == "" {
+		return nil, fmt.Errorf("code is required")
+	}
+	snippet, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+e.Code, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("invalid method declaration: %w", err)
+	}
+	if len(snippet.Decls) != 1 {
+		return nil, fmt.Errorf("code must declare exactly one method")
+	}
+	method, ok := snippet.Decls[0].(*ast.FuncDecl)
+	if !ok || method.Recv == nil {
+		return nil, fmt.Errorf("code must declare a method")
+	}
+	if e.Type != "" && receiverType(method) != e.Type {
+		return nil, fmt.Errorf("method receiver is %s, not %s", receiverType(method), e.Type)
+	}
+	return method, nil
+}
+
+// replaceBody replaces the body of a function or method
+func replaceBody(file *ast.File, tf *token.File, e Edit) (splice, error) {
+	if e.Func == "" {
+		return splice{}, fmt.Errorf("func is required")
+	}
+	typeName, name, isMethod := strings.Cut(e.Func, ".")
+	if !isMethod {
+		typeName, name = "", e.Func
+	}
+
+	var fn *ast.FuncDecl
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || decl.Name.Name != name || (decl.Recv != nil) != isMethod {
+			continue
+		}
+		if !isMethod || receiverType(decl) == typeName {
+			fn = decl
+			break
+		}
+	}
+	if fn == nil {
+		return splice{}, fmt.Errorf("function %s not found", e.Func)
+	}
+	if fn.Body == nil {
+		return splice{}, fmt.Errorf("function %s has no body", e.Func)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+e.Code+"\n}", parser.SkipObjectResolution); err != nil {
+		return splice{}, fmt.Errorf("invalid function body: %w", err)
+	}
+
+	body := "{\n" + strings.TrimSpace(e.Code) + "\n}"
+	if strings.TrimSpace(e.Code) == "" {
+		body = "{\n}"
+	}
+	return splice{tf.Offset(fn.Body.Lbrace), tf.Offset(fn.Body.Rbrace) + 1, body}, nil
+}
+
+// addImport adds an import to the first import declaration, or after the
+// package clause when there is none. Importing an already imported path
+// under the same name changes nothing.
+func addImport(file *ast.File, tf *token.File, src []byte, e Edit) (splice, error) {
+	if e.Path == "" {
+		return splice{}, fmt.Errorf("path is required")
+	}
+	if e.Name != "" && e.Name != "_" && e.Name != "." && !token.IsIdentifier(e.Name) {
+		return splice{}, fmt.Errorf("invalid import name %q", e.Name)
+	}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != e.Path {
+			continue
+		}
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == e.Name {
+			return splice{}, nil
+		}
+		if name == "" {
+			return splice{}, fmt.Errorf("%s is already imported without a name", e.Path)
+		}
+		return splice{}, fmt.Errorf("%s is already imported as %s", e.Path, name)
+	}
+
+	spec := strconv.Quote(e.Path)
+	if e.Name != "" {
+		spec = e.Name + " " + spec
+	}
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		if decl.Lparen.IsValid() {
+			rparen := tf.Offset(decl.Rparen)
+			return splice{rparen, rparen, "\t" + spec + "\n"}, nil
+		}
+		// Turn a single import into a block
+		start, end := tf.Offset(decl.Pos()), tf.Offset(decl.End())
+		existing := string(src[tf.Offset(decl.Specs[0].Pos()):end])
+		return splice{start, end, "import (\n\t" + existing + "\n\t" + spec + "\n)"}, nil
+	}
+	end := tf.Offset(file.Name.End())
+	return splice{end, end, "\n\nimport " + spec}, nil
+}
+
+// removeImport removes an import the file no longer uses. Removing an
+// import the file does not have changes nothing.
+func removeImport(file *ast.File, tf *token.File, src []byte, e Edit) (splice, error) {
+	if e.Path == "" {
+		return splice{}, fmt.Errorf("path is required")
+	}
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			imp := spec.(*ast.ImportSpec)
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || path != e.Path {
+				continue
+			}
+			name := path[strings.LastIndexByte(path, '/')+1:]
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			if name != "_" && name != "." && usesName(file, name) {
+				return splice{}, fmt.Errorf("%s is still used as %s", e.Path, name)
+			}
+			if len(decl.Specs) == 1 {
+				return lineSplice(tf, src, decl.Pos(), decl.End()), nil
+			}
+			start := imp.Pos()
+			if imp.Doc != nil {
+				start = imp.Doc.Pos()
+			}
+			end := imp.End()
+			if imp.Comment != nil {
+				end = imp.Comment.End()
+			}
+			return lineSplice(tf, src, start, end), nil
+		}
+	}
+	return splice{}, nil
+}
+
+// usesName reports whether a file qualifies an identifier with name, as
+// it refers to an imported package
+func usesName(file *ast.File, name string) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == name {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+// lineSplice removes the text from pos to end, with the rest of its lines
+// when nothing else is on them
+func lineSplice(tf *token.File, src []byte, pos, end token.Pos) splice {
+	return removeLines(src, tf.Offset(pos), tf.Offset(end))
+}
+
+// removeLines removes the bytes from start to stop, with the rest of their
+// lines when nothing else is on them
+func removeLines(src []byte, start, stop int) splice {
+	lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[stop:], '\n'); i >= 0 {
+		lineEnd = stop + i + 1
+	}
+	if len(bytes.TrimSpace(src[lineStart:start])) == 0 && len(bytes.TrimSpace(src[stop:lineEnd])) == 0 {
+		start, stop = lineStart, lineEnd
+	}
+	return splice{start, stop, ""}
+}
+
+// replace replaces the text Old starting on Line with Code. Removing all
+// there is on a line removes the line.
+func replace(tf *token.File, src []byte, e Edit) (splice, error) {
+	if e.Old == "" {
+		return splice{}, fmt.Errorf("old is required")
+	}
+	if e.Line < 1 || e.Line > tf.LineCount() {
+		return splice{}, fmt.Errorf("line %d is out of range (1-%d)", e.Line, tf.LineCount())
+	}
+	lineStart := tf.Offset(tf.LineStart(e.Line))
+	lineEnd := len(src)
+	if e.Line < tf.LineCount() {
+		lineEnd = tf.Offset(tf.LineStart(e.Line+1)) - 1
+	}
+	best := -1
+	for offset := lineStart; offset <= lineEnd; {
+		i := bytes.Index(src[offset:], []byte(e.Old))
+		if i < 0 || offset+i > lineEnd {
+			break
+		}
+		found := offset + i
+		if best < 0 || e.Column > 0 && abs(found-lineStart+1-e.Column) < abs(best-lineStart+1-e.Column) {
+			best = found
+		}
+		offset = found + 1
+	}
+	if best < 0 {
+		return splice{}, fmt.Errorf("%q not found on line %d", e.Old, e.Line)
+	}
+	if e.Code == "" {
+		return removeLines(src, best, best+len(e.Old)), nil
+	}
+	return splice{best, best + len(e.Old), e.Code}, nil
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// addDecl appends package-level declarations to the end of the file
+func addDecl(file *ast.File, src []byte, e Edit) (splice, error) {
+	names, err := parseDecls(e)
+	if err != nil {
+		return splice{}, err
+	}
+	declared := make(map[string]bool)
+	for _, name := range topLevelNames(file) {
+		declared[name] = true
+	}
+	for _, name := range names {
+		if declared[name] && name != "_" {
+			return splice{}, fmt.Errorf("%s is already declared", name)
+		}
+	}
+	return splice{len(src), len(src), "\n\n" + strings.TrimSpace(e.Code) + "\n"}, nil
+}
+
+// parseDecls parses the declarations of an add_decl edit and returns the
+// names they declare. Imports and methods have their own operations.
+func parseDecls(e Edit) ([]string, error) {
+	if strings.TrimSpace(e.Code) == "" {
+		return nil, fmt.Errorf("code is required")
+	}
+	snippet, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+e.Code, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("invalid declaration: %w", err)
+	}
+	if len(snippet.Decls) == 0 {
+		return nil, fmt.Errorf("code declares nothing")
+	}
+	for _, decl := range snippet.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil {
+				return nil, fmt.Errorf("use %s to add method %s", AddMethod, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			if decl.Tok == token.IMPORT {
+				return nil, fmt.Errorf("use %s to add imports", AddImport)
+			}
+		}
+	}
+	return topLevelNames(snippet), nil
+}
+
+// topLevelNames returns the package-level names a file declares, except
+// methods
+func topLevelNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name != "init" {
+				names = append(names, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// checkPackage checks add_method and add_decl edits against the other
+// files of the package: the receiver type of a method must be declared in
+// the package and must not already have the method, and declarations must
+// not redeclare a name. Edits see what earlier edits add.
+func checkPackage(filename string, src []byte, edits []Edit) error {
+	checked := false
+	for i, e := range edits {
+		var err error
+		switch e.Op {
+		case AddMethod:
+			_, err = parseMethod(e)
+		case AddDecl:
+			_, err = parseDecls(e)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("edit %d (%s): %w", i+1, e.Op, err)
+		}
+		checked = true
+	}
+	if !checked {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	// The file itself parses from src; other files of the directory that
+	// fail to parse or belong to another package are skipped
+	names, err := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.go"))
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(names, func(name string) bool { return filepath.Clean(name) == filepath.Clean(filename) }) {
+		names = append(names, filename)
+	}
+	declared := make(map[string]string) // Package-level name to file
+	typeNames := make(map[string]bool)  // Declared types
+	methods := make(map[string]string)  // Type.Method to file
+	for _, name := range names {
+		var content any
+		if filepath.Clean(name) == filepath.Clean(filename) {
+			content = src
+		}
+		f, err := parser.ParseFile(fset, name, content, parser.SkipObjectResolution)
+		if err != nil || f.Name.Name != file.Name.Name {
+			continue
+		}
+		base := filepath.Base(name)
+		for _, ident := range topLevelNames(f) {
+			declared[ident] = base
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv != nil {
+					methods[receiverType(decl)+"."+decl.Name.Name] = base
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if spec, ok := spec.(*ast.TypeSpec); ok {
+						typeNames[spec.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+
+	base := filepath.Base(filename)
+	for i, e := range edits {
+		switch e.Op {
+		case AddMethod:
+			method, _ := parseMethod(e)
+			typeName := receiverType(method)
+			if !typeNames[typeName] {
+				return fmt.Errorf("edit %d (%s): type %s is not declared in package %s", i+1, e.Op, typeName, file.Name.Name)
+			}
+			key := typeName + "." + method.Name.Name
+			if in, ok := methods[key]; ok {
+				return fmt.Errorf("edit %d (%s): %s already has a method %s in %s", i+1, e.Op, typeName, method.Name.Name, in)
+			}
+			methods[key] = base
+		case AddDecl:
+			snippet, _ := parser.ParseFile(token.NewFileSet(), "", "package p\n"+e.Code, parser.SkipObjectResolution)
+			for _, ident := range topLevelNames(snippet) {
+				if in, ok := declared[ident]; ok && ident != "_" {
+					return fmt.Errorf("edit %d (%s): %s is already declared in %s", i+1, e.Op, ident, in)
+				}
+				declared[ident] = base
+			}
+			for _, decl := range snippet.Decls {
+				if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+					for _, spec := range decl.Specs {
+						typeNames[spec.(*ast.TypeSpec).Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findType returns the declaration of a package-level type
+func findType(file *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			if spec := spec.(*ast.TypeSpec); spec.Name.Name == name {
+				return spec
+			}
+		}
+	}
+	return nil
+}
+
+// declaresType reports whether a declaration declares the named type
+func declaresType(decl *ast.GenDecl, name string) bool {
+	if decl.Tok != token.TYPE {
+		return false
+	}
+	for _, spec := range decl.Specs {
+		if spec.(*ast.TypeSpec).Name.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// receiverType returns the name of a method's receiver type, without
+// pointer or type parameters
+func receiverType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// fieldNames returns the names a field declares; an embedded field is
+// named after its type
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) > 0 {
+		names := make([]string, len(field.Names))
+		for i, name := range field.Names {
+			names[i] = name.Name
+		}
+		return names
+	}
+	expr := field.Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.SelectorExpr:
+			return []string{t.Sel.Name}
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return []string{t.Name}
+		default:
+			return nil
+		}
+	}
+}
+
+// writeFile replaces or creates a file atomically with the given
+// permissions
+func writeFile(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
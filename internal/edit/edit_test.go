@@ -0,0 +1,323 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package shop
+
+import "fmt"
+
+// Cart holds items
+type Cart struct {
+	Items []string
+}
+
+// Add adds an item
+func (c *Cart) Add(item string) {
+	c.Items = append(c.Items, item)
+}
+
+// Total returns the number of items
+func Total(c *Cart) int {
+	return len(c.Items)
+}
+
+func describe(c *Cart) string {
+	return fmt.Sprint(c.Items)
+}
+`
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  []string
+	}{
+		{
+			name:  "add field",
+			edits: []Edit{{Op: AddField, Type: "Cart", Code: "Owner string `json:\"owner\"`"}},
+			want:  []string{"\tItems []string\n\tOwner string `json:\"owner\"`\n}"},
+		},
+		{
+			name:  "add method after last method",
+			edits: []Edit{{Op: AddMethod, Type: "Cart", Code: "// Len returns the number of items\nfunc (c *Cart) Len() int { return len(c.Items) }"}},
+			want:  []string{"}\n\n// Len returns the number of items\nfunc (c *Cart) Len() int { return len(c.Items) }\n\n// Total"},
+		},
+		{
+			name:  "replace function body",
+			edits: []Edit{{Op: ReplaceBody, Func: "Total", Code: "if c == nil {\nreturn 0\n}\nreturn len(c.Items)"}},
+			want:  []string{"func Total(c *Cart) int {\n\tif c == nil {\n\t\treturn 0\n\t}\n\treturn len(c.Items)\n}"},
+		},
+		{
+			name:  "replace method body",
+			edits: []Edit{{Op: ReplaceBody, Func: "Cart.Add", Code: "c.Items = append([]string{item}, c.Items...)"}},
+			want:  []string{"func (c *Cart) Add(item string) {\n\tc.Items = append([]string{item}, c.Items...)\n}"},
+		},
+		{
+			name:  "add import to single import",
+			edits: []Edit{{Op: AddImport, Path: "strings"}, {Op: AddImport, Path: "errors", Name: "stderrors"}},
+			want:  []string{"import (\n\tstderrors \"errors\"\n\t\"fmt\"\n\t\"strings\"\n)"},
+		},
+		{
+			name: "edits build on each other",
+			edits: []Edit{
+				{Op: AddImport, Path: "strings"},
+				{Op: ReplaceBody, Func: "describe", Code: "return strings.Join(c.Items, \", \")"},
+			},
+			want: []string{"\"strings\"", "return strings.Join(c.Items, \", \")"},
+		},
+		{
+			name: "replace text and remove the import it used",
+			edits: []Edit{
+				{Op: Replace, Line: 21, Old: "fmt.Sprint(c.Items)", Code: "\"items\""},
+				{Op: RemoveImport, Path: "fmt"},
+			},
+			want: []string{"package shop\n\n// Cart", "\treturn \"items\"\n"},
+		},
+		{
+			name:  "replace the occurrence closest to the column",
+			edits: []Edit{{Op: Replace, Line: 12, Old: "c.Items", Column: 21, Code: "nil"}},
+			want:  []string{"\tc.Items = append(nil, item)\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Source("cart.go", []byte(testSource), tt.edits)
+			if err != nil {
+				t.Fatalf("Failed to apply edits: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceNoImports(t *testing.T) {
+	out, err := Source("a.go", []byte("package a\n\nfunc A() {}\n"), []Edit{{Op: AddImport, Path: "os"}})
+	if err != nil {
+		t.Fatalf("Failed to add import: %v", err)
+	}
+	if want := "package a\n\nimport \"os\"\n\nfunc A() {}\n"; string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+}
+
+func TestSourceIdempotentImport(t *testing.T) {
+	out, err := Source("cart.go", []byte(testSource), []Edit{{Op: AddImport, Path: "fmt"}})
+	if err != nil {
+		t.Fatalf("Failed to add import: %v", err)
+	}
+	if string(out) != testSource {
+		t.Errorf("Expected an existing import to change nothing, got:\n%s", out)
+	}
+}
+
+func TestRemoveImport(t *testing.T) {
+	src := "package a\n\nimport (\n\t\"io\"\n\t// Legacy helpers\n\t\"io/ioutil\" // Deprecated\n\t\"os\"\n)\n\nvar _ = io.EOF\n\nvar _ = os.Args\n"
+	out, err := Source("a.go", []byte(src), []Edit{{Op: RemoveImport, Path: "io/ioutil"}, {Op: RemoveImport, Path: "strings"}})
+	if err != nil {
+		t.Fatalf("Failed to remove import: %v", err)
+	}
+	if want := "package a\n\nimport (\n\t\"io\"\n\t\"os\"\n)\n\nvar _ = io.EOF\n\nvar _ = os.Args\n"; string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit Edit
+		want string
+	}{
+		{"unknown op", Edit{Op: "delete"}, "unknown operation"},
+		{"missing type", Edit{Op: AddField, Type: "Order", Code: "ID int"}, "type Order not found"},
+		{"duplicate field", Edit{Op: AddField, Type: "Cart", Code: "Items []int"}, "already has a field Items"},
+		{"invalid field", Edit{Op: AddField, Type: "Cart", Code: "func"}, "invalid field declaration"},
+		{"duplicate method", Edit{Op: AddMethod, Code: "func (c Cart) Add(string) {}"}, "already has a method Add"},
+		{"receiver mismatch", Edit{Op: AddMethod, Type: "Order", Code: "func (c Cart) Len() int { return 0 }"}, "receiver is Cart, not Order"},
+		{"not a method", Edit{Op: AddMethod, Code: "func Len() int { return 0 }"}, "must declare a method"},
+		{"missing function", Edit{Op: ReplaceBody, Func: "Cart.Total", Code: "return"}, "function Cart.Total not found"},
+		{"invalid body", Edit{Op: ReplaceBody, Func: "Total", Code: "return len(c.Items"}, "invalid function body"},
+		{"import alias conflict", Edit{Op: AddImport, Path: "fmt", Name: "f"}, "already imported without a name"},
+		{"missing text", Edit{Op: Replace, Line: 12, Old: "c.Total", Code: "0"}, "not found on line 12"},
+		{"line out of range", Edit{Op: Replace, Line: 99, Old: "c", Code: "d"}, "out of range"},
+		{"invalid replacement", Edit{Op: Replace, Line: 12, Old: "c.Items,", Code: "("}, "not valid Go"},
+		{"import still used", Edit{Op: RemoveImport, Path: "fmt"}, "still used as fmt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Source("cart.go", []byte(testSource), []Edit{tt.edit})
+			if err == nil {
+				t.Fatalf("Expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "cart.go")
+	if err := os.WriteFile(filename, []byte(testSource), 0640); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "order.go"), []byte("package shop\n\ntype Order struct{}\n\nfunc (c *Cart) Clear() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	edits := []Edit{{Op: AddField, Type: "Cart", Code: "Owner string"}}
+
+	result, err := Apply(filename, edits, true)
+	if err != nil {
+		t.Fatalf("Failed to apply dry run: %v", err)
+	}
+	if result.Applied {
+		t.Error("Expected a dry run not to be applied")
+	}
+	if !strings.Contains(result.Diff, "+\tOwner string\n") || !strings.HasPrefix(result.Diff, "--- a/cart.go\n+++ b/cart.go\n@@ -5,6 +5,7 @@\n") {
+		t.Errorf("Unexpected diff:\n%s", result.Diff)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != testSource {
+		t.Error("Expected a dry run to leave the file unchanged")
+	}
+
+	result, err = Apply(filename, edits, false)
+	if err != nil {
+		t.Fatalf("Failed to apply edits: %v", err)
+	}
+	if !result.Applied {
+		t.Error("Expected the edits to be applied")
+	}
+	data, _ := os.ReadFile(filename)
+	if !strings.Contains(string(data), "Owner string") {
+		t.Errorf("Expected the file to contain the new field, got:\n%s", data)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 to be kept, got %v", info.Mode().Perm())
+	}
+
+	// Methods are checked against the rest of the package
+	if _, err := Apply(filename, []Edit{{Op: AddMethod, Code: "func (c *Cart) Clear() {}"}}, false); err == nil || !strings.Contains(err.Error(), "in order.go") {
+		t.Errorf("Expected a duplicate method error naming order.go, got %v", err)
+	}
+	if _, err := Apply(filename, []Edit{{Op: AddMethod, Code: "func (l List) Len() int { return 0 }"}}, false); err == nil || !strings.Contains(err.Error(), "not declared") {
+		t.Errorf("Expected an undeclared type error, got %v", err)
+	}
+	result, err = Apply(filename, []Edit{{Op: AddMethod, Code: "func (o Order) ID() int { return 0 }"}}, false)
+	if err != nil {
+		t.Fatalf("Failed to add a method to a type of another file: %v", err)
+	}
+	if data, _ := os.ReadFile(filename); !strings.HasSuffix(string(data), "}\n\nfunc (o Order) ID() int { return 0 }\n") {
+		t.Errorf("Expected the method at the end of the file, got:\n%s", data)
+	}
+
+	// A failing edit leaves the file untouched
+	before, _ := os.ReadFile(filename)
+	if _, err := Apply(filename, []Edit{{Op: AddImport, Path: "os"}, {Op: ReplaceBody, Func: "Missing"}}, false); err == nil {
+		t.Error("Expected an error for a missing function")
+	}
+	if after, _ := os.ReadFile(filename); string(after) != string(before) {
+		t.Error("Expected a failed edit to leave the file unchanged")
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	want := `--- a/x.go
++++ b/x.go
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := Unified("x.go", []byte(a), []byte(b)); got != want {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", want, got)
+	}
+	if got := Unified("x.go", []byte(a), []byte(a)); got != "" {
+		t.Errorf("Expected no diff for equal input, got:\n%s", got)
+	}
+}
+
+func TestAddDecl(t *testing.T) {
+	out, err := Source("cart.go", []byte(testSource), []Edit{{Op: AddDecl, Code: "// Empty is a cart without items\nvar Empty = &Cart{}"}})
+	if err != nil {
+		t.Fatalf("Failed to add declaration: %v", err)
+	}
+	if !strings.HasSuffix(string(out), "}\n\n// Empty is a cart without items\nvar Empty = &Cart{}\n") {
+		t.Errorf("Expected the declaration at the end of the file, got:\n%s", out)
+	}
+
+	for _, tt := range []struct {
+		code, want string
+	}{
+		{"func Total() {}", "Total is already declared"},
+		{"func (c *Cart) Len() int { return 0 }", "use add_method"},
+		{"import \"os\"", "use add_import"},
+		{"var = 1", "invalid declaration"},
+	} {
+		if _, err := Source("cart.go", []byte(testSource), []Edit{{Op: AddDecl, Code: tt.code}}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %q, got %v", tt.want, tt.code, err)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cart.go"), []byte(testSource), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	filename := filepath.Join(dir, "adder.go")
+	edits := []Edit{
+		{Op: AddImport, Path: "context"},
+		{Op: AddDecl, Code: "// Adder adds items\ntype Adder interface {\n\tAdd(ctx context.Context, item string)\n}"},
+		{Op: AddMethod, Code: "func (Adder2) Add(context.Context, string) {}"},
+	}
+	if _, err := Create(filename, "shop", edits, true); err == nil || !strings.Contains(err.Error(), "type Adder2 is not declared") {
+		t.Errorf("Expected an undeclared type error, got %v", err)
+	}
+	if _, err := Create(filename, "shop", []Edit{{Op: AddDecl, Code: "type Cart int"}}, true); err == nil || !strings.Contains(err.Error(), "already declared in cart.go") {
+		t.Errorf("Expected a redeclaration error, got %v", err)
+	}
+
+	edits = edits[:2]
+	result, err := Create(filename, "shop", edits, true)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if result.Applied || !strings.HasPrefix(result.Diff, "--- a/adder.go\n+++ b/adder.go\n@@ -0,0 +1,8 @@\n+package shop\n") {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Error("Expected a dry run not to create the file")
+	}
+
+	if _, err := Create(filename, "shop", edits, false); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	data, _ := os.ReadFile(filename)
+	if want := "package shop\n\nimport \"context\"\n\n// Adder adds items\ntype Adder interface {\n\tAdd(ctx context.Context, item string)\n}\n"; string(data) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, data)
+	}
+	if _, err := Create(filename, "shop", edits, false); err == nil {
+		t.Error("Expected an error creating an existing file")
+	}
+}
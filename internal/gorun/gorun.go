@@ -0,0 +1,307 @@
+// Package gorun runs `go test -json` and turns its event stream into
+// structured per-package and per-test results
+package gorun
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/tracing"
+)
+
+// Test and package outcomes
+const (
+	StatusPass        = "pass"
+	StatusFail        = "fail"
+	StatusSkip        = "skip"
+	StatusBuildFailed = "build-failed"
+	// StatusIncomplete marks a test that never reported an outcome, e.g.
+	// because the binary panicked or timed out while it ran
+	StatusIncomplete = "incomplete"
+)
+
+// Options selects the tests to run
+type Options struct {
+	// Packages are the package patterns to test; ./... when empty
+	Packages []string
+	// Run is a -run regular expression selecting test names
+	Run     string
+	Short   bool
+	Timeout time.Duration
+	// NoCache forces tests to run even when go test has cached results
+	NoCache bool
+}
+
+// TestResult is the outcome of a single test, subtest, or example
+type TestResult struct {
+	Package string  `json:"package"`
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Elapsed float64 `json:"elapsed_seconds"`
+	// Output is kept for failed and incomplete tests only
+	Output string `json:"output,omitempty"`
+	// File and Line locate the first failure reported in the output
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// PackageResult is the outcome of one package's test binary
+type PackageResult struct {
+	Package string  `json:"package"`
+	Status  string  `json:"status"`
+	Elapsed float64 `json:"elapsed_seconds"`
+	Cached  bool    `json:"cached,omitempty"`
+	// Coverage is the percentage of statements covered, when reported
+	Coverage *float64 `json:"coverage,omitempty"`
+	Passed   int      `json:"passed"`
+	Failed   int      `json:"failed"`
+	Skipped  int      `json:"skipped"`
+	// Output holds build errors and output printed outside any test of a
+	// failed package, such as a panic in TestMain
+	Output string `json:"output,omitempty"`
+}
+
+// Result summarizes a go test run
+type Result struct {
+	Passed   int             `json:"passed"`
+	Failed   int             `json:"failed"`
+	Skipped  int             `json:"skipped"`
+	Elapsed  float64         `json:"elapsed_seconds"`
+	Packages []PackageResult `json:"packages"`
+	// Failures lists every failed or incomplete test with its output
+	Failures []TestResult `json:"failures"`
+	// Coverage is the mean coverage of the packages that reported one
+	Coverage *float64 `json:"coverage,omitempty"`
+}
+
+// event is a test2json or build JSON event
+type event struct {
+	Action      string
+	Package     string
+	ImportPath  string
+	Test        string
+	Elapsed     float64
+	Output      string
+	FailedBuild string
+}
+
+var (
+	// coveragePattern matches the coverage summary line of a package
+	coveragePattern = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+
+	// locationPattern matches the file:line prefix of a t.Error message
+	locationPattern = regexp.MustCompile(`^\s+([\w./-]+\.go):(\d+): `)
+)
+
+// Run executes go test -json with coverage in dir and parses the results.
+// Failing tests are not an error; an error is returned only when go test
+// produced no results at all.
+func Run(ctx context.Context, dir string, opts Options) (*Result, error) {
+	args := []string{"test", "-json", "-cover"}
+	if opts.Run != "" {
+		args = append(args, "-run", opts.Run)
+	}
+	if opts.Short {
+		args = append(args, "-short")
+	}
+	if opts.Timeout > 0 {
+		args = append(args, "-timeout", opts.Timeout.String())
+	}
+	if opts.NoCache {
+		args = append(args, "-count=1")
+	}
+	if len(opts.Packages) == 0 {
+		args = append(args, "./...")
+	} else {
+		args = append(args, opts.Packages...)
+	}
+
+	ctx, span := tracing.Start(ctx, "exec go test", tracing.KindClient, tracing.String("process.command", "go"))
+	defer span.End()
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	runErr := cmd.Run()
+
+	result, err := Parse(&stdout)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	if len(result.Packages) == 0 && runErr != nil {
+		err := fmt.Errorf("go test failed: %v: %s", runErr, strings.TrimSpace(stderr.String()))
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Int("scope.packages", len(result.Packages)))
+	result.Elapsed = time.Since(start).Seconds()
+	return result, nil
+}
+
+// Parse reads a go test -json event stream. Lines that are not JSON events,
+// such as output of older go commands, are ignored.
+func Parse(r io.Reader) (*Result, error) {
+	type testKey struct{ pkg, name string }
+	packages := make(map[string]*PackageResult)
+	packageOutput := make(map[string]*strings.Builder)
+	buildOutput := make(map[string]*strings.Builder)
+	tests := make(map[testKey]*TestResult)
+	testOutput := make(map[testKey]*strings.Builder)
+	var order []testKey
+
+	pkgResult := func(name string) *PackageResult {
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &PackageResult{Package: name}
+			packages[name] = pkg
+			packageOutput[name] = &strings.Builder{}
+		}
+		return pkg
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+
+		switch e.Action {
+		case "build-output":
+			if buildOutput[e.ImportPath] == nil {
+				buildOutput[e.ImportPath] = &strings.Builder{}
+			}
+			buildOutput[e.ImportPath].WriteString(e.Output)
+			continue
+		case "build-fail":
+			continue
+		}
+		if e.Package == "" {
+			continue
+		}
+		pkg := pkgResult(e.Package)
+
+		if e.Test == "" {
+			switch e.Action {
+			case "output":
+				packageOutput[e.Package].WriteString(e.Output)
+				if match := coveragePattern.FindStringSubmatch(e.Output); match != nil {
+					if coverage, err := strconv.ParseFloat(match[1], 64); err == nil {
+						pkg.Coverage = &coverage
+					}
+				}
+				if strings.Contains(e.Output, "(cached)") {
+					pkg.Cached = true
+				}
+			case StatusPass, StatusSkip:
+				pkg.Status = e.Action
+				pkg.Elapsed = e.Elapsed
+			case StatusFail:
+				pkg.Status = StatusFail
+				pkg.Elapsed = e.Elapsed
+				if e.FailedBuild != "" {
+					pkg.Status = StatusBuildFailed
+					if output := buildOutput[e.FailedBuild]; output != nil {
+						packageOutput[e.Package].WriteString(output.String())
+					}
+				}
+			}
+			continue
+		}
+
+		key := testKey{e.Package, e.Test}
+		test, ok := tests[key]
+		if !ok {
+			test = &TestResult{Package: e.Package, Name: e.Test, Status: StatusIncomplete}
+			tests[key] = test
+			testOutput[key] = &strings.Builder{}
+			order = append(order, key)
+		}
+		switch e.Action {
+		case "output":
+			testOutput[key].WriteString(e.Output)
+		case StatusPass, StatusFail, StatusSkip:
+			test.Status = e.Action
+			test.Elapsed = e.Elapsed
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go test output: %w", err)
+	}
+
+	result := &Result{Packages: []PackageResult{}, Failures: []TestResult{}}
+	for _, key := range order {
+		test := tests[key]
+		pkg := packages[key.pkg]
+		switch test.Status {
+		case StatusPass:
+			pkg.Passed++
+		case StatusSkip:
+			pkg.Skipped++
+		default:
+			pkg.Failed++
+			test.Output = testOutput[key].String()
+			test.File, test.Line = failureLocation(test.Output)
+			result.Failures = append(result.Failures, *test)
+		}
+	}
+
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var coverageSum float64
+	var covered int
+	for _, name := range names {
+		pkg := packages[name]
+		if pkg.Status == "" {
+			pkg.Status = StatusIncomplete
+		}
+		if pkg.Status != StatusPass && pkg.Status != StatusSkip {
+			pkg.Output = packageOutput[name].String()
+		}
+		if pkg.Coverage != nil {
+			coverageSum += *pkg.Coverage
+			covered++
+		}
+		result.Passed += pkg.Passed
+		result.Failed += pkg.Failed
+		result.Skipped += pkg.Skipped
+		result.Packages = append(result.Packages, *pkg)
+	}
+	if covered > 0 {
+		mean := coverageSum / float64(covered)
+		result.Coverage = &mean
+	}
+	return result, nil
+}
+
+// failureLocation returns the file and line of the first error in a test's output
+func failureLocation(output string) (string, int) {
+	for _, line := range strings.Split(output, "\n") {
+		if match := locationPattern.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[2])
+			return match[1], n
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,138 @@
+package gorun
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"ok/ok.go": `package ok
+
+func Add(a, b int) int { return a + b }
+
+func Sub(a, b int) int { return a - b }
+`,
+		"ok/ok_test.go": `package ok
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("wrong sum")
+	}
+}
+
+func TestSkipped(t *testing.T) { t.Skip("not now") }
+`,
+		"bad/bad_test.go": `package bad
+
+import "testing"
+
+func TestFail(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		t.Errorf("expected %d, got %d", 1, 2)
+	})
+}
+
+func TestPass(t *testing.T) {}
+`,
+		"broken/broken.go": `package broken
+
+func X() int { return "s" }
+`,
+		"broken/broken_test.go": `package broken
+
+import "testing"
+
+func TestX(t *testing.T) {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	result, err := Run(context.Background(), tmpDir, Options{NoCache: true})
+	if err != nil {
+		t.Fatalf("Failed to run tests: %v", err)
+	}
+	if result.Passed != 2 || result.Failed != 2 || result.Skipped != 1 {
+		t.Errorf("Expected 2 passed, 2 failed and 1 skipped, got %d/%d/%d", result.Passed, result.Failed, result.Skipped)
+	}
+
+	statuses := make(map[string]PackageResult)
+	for _, pkg := range result.Packages {
+		statuses[pkg.Package] = pkg
+	}
+	if pkg := statuses["example.com/app/ok"]; pkg.Status != StatusPass || pkg.Coverage == nil || *pkg.Coverage != 50 {
+		t.Errorf("Unexpected result for ok: %+v", pkg)
+	}
+	if pkg := statuses["example.com/app/bad"]; pkg.Status != StatusFail || pkg.Failed != 2 {
+		t.Errorf("Unexpected result for bad: %+v", pkg)
+	}
+	if pkg := statuses["example.com/app/broken"]; pkg.Status != StatusBuildFailed || !strings.Contains(pkg.Output, "cannot use") {
+		t.Errorf("Unexpected result for broken: %+v", pkg)
+	}
+
+	var sub *TestResult
+	for i := range result.Failures {
+		if result.Failures[i].Name == "TestFail/sub" {
+			sub = &result.Failures[i]
+		}
+	}
+	if sub == nil {
+		t.Fatalf("Expected TestFail/sub among failures, got %+v", result.Failures)
+	}
+	if sub.File != "bad_test.go" || sub.Line != 7 || !strings.Contains(sub.Output, "expected 1, got 2") {
+		t.Errorf("Unexpected failure: %+v", sub)
+	}
+
+	result, err = Run(context.Background(), tmpDir, Options{Packages: []string{"./ok"}, Run: "TestAdd"})
+	if err != nil {
+		t.Fatalf("Failed to run tests: %v", err)
+	}
+	if result.Passed != 1 || result.Skipped != 0 || len(result.Packages) != 1 {
+		t.Errorf("Expected only TestAdd to run, got %+v", result)
+	}
+
+	// Setup failures are reported like build failures
+	result, err = Run(context.Background(), tmpDir, Options{Packages: []string{"./missing"}})
+	if err != nil {
+		t.Fatalf("Failed to run tests: %v", err)
+	}
+	if len(result.Packages) != 1 || result.Packages[0].Status != StatusBuildFailed || !strings.Contains(result.Packages[0].Output, "not found") {
+		t.Errorf("Expected a failed setup for a missing package, got %+v", result.Packages)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	// A test binary that panics never reports the running test's outcome
+	stream := `{"Action":"start","Package":"example.com/p"}
+{"Action":"run","Package":"example.com/p","Test":"TestPanic"}
+{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"panic: boom\n"}
+not json
+{"Action":"output","Package":"example.com/p","Output":"FAIL\texample.com/p\t0.01s\n"}
+{"Action":"fail","Package":"example.com/p","Elapsed":0.01}
+`
+	result, err := Parse(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Status != StatusIncomplete || !strings.Contains(result.Failures[0].Output, "panic: boom") {
+		t.Errorf("Expected an incomplete test with its output, got %+v", result.Failures)
+	}
+	if result.Packages[0].Status != StatusFail || result.Coverage != nil {
+		t.Errorf("Unexpected package result: %+v", result.Packages[0])
+	}
+}
@@ -0,0 +1,297 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TFMV/scope/internal/checks"
+)
+
+// Supported git hooks
+const (
+	PreCommit = "pre-commit"
+	PrePush   = "pre-push"
+)
+
+// marker identifies hook scripts written by scope so they can be safely replaced
+const marker = "# Installed by scope hooks install"
+
+// Config maps hook names to the checks they run
+type Config struct {
+	Hooks map[string][]string `json:"hooks"`
+}
+
+// DefaultConfig returns the checks run by each hook when no configuration exists
+func DefaultConfig() *Config {
+	return &Config{
+		Hooks: map[string][]string{
+			PreCommit: {"format", "build_check", "lint"},
+			PrePush:   {"build_check", "lint", "test", "api-compat"},
+		},
+	}
+}
+
+// ConfigPath returns the location of the hooks configuration for a repository
+func ConfigPath(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "hooks.json")
+}
+
+// LoadConfig reads the hooks configuration for a repository, falling back to
+// the defaults when the file does not exist
+func LoadConfig(repoPath string) (*Config, error) {
+	data, err := os.ReadFile(ConfigPath(repoPath))
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hooks config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse hooks config: %w", err)
+	}
+	if config.Hooks == nil {
+		config.Hooks = make(map[string][]string)
+	}
+	return &config, nil
+}
+
+// Install writes git hook scripts for the named hooks that invoke
+// `<binary> hooks run <hook>`. Existing hooks not written by scope are only
+// replaced when force is set, in which case they are backed up with a .bak
+// suffix. A default configuration file is created if none exists.
+func Install(repoPath, binary string, hookNames []string, force bool) ([]string, error) {
+	gitDir, err := gitHooksDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	var installed []string
+	for _, hook := range hookNames {
+		if hook != PreCommit && hook != PrePush {
+			return installed, fmt.Errorf("unsupported hook %q (supported: %s, %s)", hook, PreCommit, PrePush)
+		}
+
+		path := filepath.Join(gitDir, hook)
+		if existing, err := os.ReadFile(path); err == nil && !bytes.Contains(existing, []byte(marker)) {
+			if !force {
+				return installed, fmt.Errorf("%s already exists and was not installed by scope (use -force to replace it)", path)
+			}
+			if err := os.WriteFile(path+".bak", existing, 0755); err != nil {
+				return installed, fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+
+		script := fmt.Sprintf("#!/bin/sh\n%s; do not edit.\nexec %s hooks run -repo \"$(git rev-parse --show-toplevel)\" %s\n", marker, shellQuote(binary), hook)
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return installed, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		installed = append(installed, path)
+	}
+
+	configPath := ConfigPath(repoPath)
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+			return installed, fmt.Errorf("failed to create config directory: %w", err)
+		}
+		data, err := json.MarshalIndent(DefaultConfig(), "", "  ")
+		if err != nil {
+			return installed, err
+		}
+		if err := os.WriteFile(configPath, data, 0644); err != nil {
+			return installed, fmt.Errorf("failed to write hooks config: %w", err)
+		}
+	}
+
+	return installed, nil
+}
+
+// Uninstall removes the named hook scripts written by Install, restoring
+// the hooks it backed up. Hooks not written by scope are left alone.
+func Uninstall(repoPath string, hookNames []string) ([]string, error) {
+	gitDir, err := gitHooksDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, hook := range hookNames {
+		if hook != PreCommit && hook != PrePush {
+			return removed, fmt.Errorf("unsupported hook %q (supported: %s, %s)", hook, PreCommit, PrePush)
+		}
+
+		path := filepath.Join(gitDir, hook)
+		existing, err := os.ReadFile(path)
+		if os.IsNotExist(err) || err == nil && !bytes.Contains(existing, []byte(marker)) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if _, err := os.Stat(path + ".bak"); err == nil {
+			if err := os.Rename(path+".bak", path); err != nil {
+				return removed, fmt.Errorf("failed to restore %s: %w", path, err)
+			}
+		} else if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// shellQuote quotes s as a single word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Run executes the checks configured for hook against the files it affects:
+// staged files for pre-commit and files changed since the upstream branch for
+// pre-push. Package-level checks only analyze the impacted packages.
+func Run(ctx context.Context, repoPath, hook string) ([]checks.Diagnostic, error) {
+	config, err := LoadConfig(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	names, ok := config.Hooks[hook]
+	if !ok {
+		return nil, fmt.Errorf("no checks configured for hook %q", hook)
+	}
+
+	files, err := ChangedFiles(ctx, repoPath, hook)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	target, err := NewTarget(ctx, repoPath, files)
+	if err != nil {
+		return nil, err
+	}
+	if len(target.Packages) == 0 && len(target.Files) == 0 {
+		return nil, nil
+	}
+	return checks.Run(ctx, target, names...)
+}
+
+// NewTarget builds a check target for the changed files, resolving the
+// packages impacted by them
+func NewTarget(ctx context.Context, repoPath string, files []string) (checks.Target, error) {
+	target := checks.Target{Dir: repoPath}
+	for _, file := range files {
+		if strings.HasSuffix(file, ".go") || filepath.Base(file) == "go.mod" {
+			target.Files = append(target.Files, file)
+		}
+	}
+	if len(target.Files) == 0 {
+		return target, nil
+	}
+
+	packages, err := checks.ListPackages(ctx, repoPath)
+	if err != nil {
+		return target, err
+	}
+	target.Packages = checks.ImpactedPackages(packages, target.Files)
+	return target, nil
+}
+
+// ChangedFiles returns the absolute paths of the files a hook should check
+func ChangedFiles(ctx context.Context, repoPath, hook string) ([]string, error) {
+	switch hook {
+	case PreCommit:
+		output, err := git(ctx, repoPath, "diff", "--cached", "--name-only", "--diff-filter=ACMR")
+		if err != nil {
+			return nil, err
+		}
+		return absolutePaths(repoPath, output), nil
+	case PrePush:
+		files, err := FilesChangedSince(ctx, repoPath, "@{upstream}")
+		if err == nil {
+			return files, nil
+		}
+		// No upstream yet: check every tracked file
+		output, err := git(ctx, repoPath, "ls-files")
+		if err != nil {
+			return nil, err
+		}
+		return absolutePaths(repoPath, output), nil
+	default:
+		return nil, fmt.Errorf("unsupported hook %q", hook)
+	}
+}
+
+// FilesChangedSince returns the absolute paths of files added, copied,
+// modified, or renamed between the merge base of ref and HEAD
+func FilesChangedSince(ctx context.Context, repoPath, ref string) ([]string, error) {
+	output, err := git(ctx, repoPath, "diff", "--name-only", "--diff-filter=ACMR", ref+"...HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return absolutePaths(repoPath, output), nil
+}
+
+// FilesChangedFrom returns the absolute paths of files that differ between
+// ref and the working tree, including uncommitted changes
+func FilesChangedFrom(ctx context.Context, repoPath, ref string) ([]string, error) {
+	output, err := git(ctx, repoPath, "diff", "--name-only", "--diff-filter=ACMR", ref)
+	if err != nil {
+		return nil, err
+	}
+	return absolutePaths(repoPath, output), nil
+}
+
+// Diff returns the unified diff between ref and the working tree
+func Diff(ctx context.Context, repoPath, ref string) (string, error) {
+	return git(ctx, repoPath, "diff", ref)
+}
+
+// absolutePaths converts git's repository-relative file list into sorted absolute paths
+func absolutePaths(repoPath, output string) []string {
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line != "" {
+			files = append(files, filepath.Join(repoPath, line))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// gitHooksDir returns the hooks directory of the repository at repoPath
+func gitHooksDir(repoPath string) (string, error) {
+	output, err := git(context.Background(), repoPath, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(output)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return dir, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
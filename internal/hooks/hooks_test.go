@@ -0,0 +1,222 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository containing a small Go module
+func initRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":     "module example.com/hooked\n\ngo 1.21\n",
+		"lib/lib.go": "package lib\n\n// Hello greets\nfunc Hello() string { return \"hi\" }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	return repo
+}
+
+func TestInstall(t *testing.T) {
+	repo := initRepo(t)
+
+	installed, err := Install(repo, "/usr/local/bin/scope", []string{PreCommit, PrePush}, false)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if len(installed) != 2 {
+		t.Fatalf("Expected 2 hooks, got %v", installed)
+	}
+
+	script, err := os.ReadFile(filepath.Join(repo, ".git", "hooks", PreCommit))
+	if err != nil {
+		t.Fatalf("Failed to read hook: %v", err)
+	}
+	if !strings.Contains(string(script), `exec '/usr/local/bin/scope' hooks run`) {
+		t.Errorf("Unexpected hook script:\n%s", script)
+	}
+
+	if _, err := os.Stat(ConfigPath(repo)); err != nil {
+		t.Errorf("Expected default config to be written: %v", err)
+	}
+
+	// Reinstalling over our own hooks is allowed
+	if _, err := Install(repo, "/usr/local/bin/scope", []string{PreCommit}, false); err != nil {
+		t.Errorf("Reinstall failed: %v", err)
+	}
+
+	// Foreign hooks are only replaced with force
+	foreign := filepath.Join(repo, ".git", "hooks", PrePush)
+	if err := os.WriteFile(foreign, []byte("#!/bin/sh\necho custom\n"), 0755); err != nil {
+		t.Fatalf("Failed to write foreign hook: %v", err)
+	}
+	if _, err := Install(repo, "scope", []string{PrePush}, false); err == nil {
+		t.Error("Expected error when replacing a foreign hook without force")
+	}
+	if _, err := Install(repo, "scope", []string{PrePush}, true); err != nil {
+		t.Errorf("Forced install failed: %v", err)
+	}
+	if _, err := os.Stat(foreign + ".bak"); err != nil {
+		t.Errorf("Expected backup of foreign hook: %v", err)
+	}
+
+	if _, err := Install(repo, "scope", []string{"post-merge"}, false); err == nil {
+		t.Error("Expected error for unsupported hook")
+	}
+}
+
+func TestInstallQuotesBinary(t *testing.T) {
+	repo := initRepo(t)
+
+	// A binary whose path sh would otherwise expand or split, standing in
+	// for scope by recording its arguments
+	dir := filepath.Join(t.TempDir(), "it's $HOME `x`")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	binary := filepath.Join(dir, "scope")
+	record := filepath.Join(t.TempDir(), "args")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\necho \"$@\" > '"+record+"'\n"), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	if _, err := Install(repo, binary, []string{PreCommit}, false); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	cmd := exec.Command("sh", filepath.Join(repo, ".git", "hooks", PreCommit))
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Hook failed: %v: %s", err, output)
+	}
+	args, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("Expected the hook to run the binary: %v", err)
+	}
+	if !strings.HasPrefix(string(args), "hooks run -repo ") || !strings.HasSuffix(string(args), " pre-commit\n") {
+		t.Errorf("Unexpected arguments: %q", args)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	repo := initRepo(t)
+	hooksDir := filepath.Join(repo, ".git", "hooks")
+
+	// A foreign pre-push hook is backed up on install and restored on
+	// uninstall
+	foreign := "#!/bin/sh\necho custom\n"
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(hooksDir, PrePush), []byte(foreign), 0755); err != nil {
+		t.Fatalf("Failed to write foreign hook: %v", err)
+	}
+	if _, err := Install(repo, "scope", []string{PreCommit, PrePush}, true); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	removed, err := Uninstall(repo, []string{PreCommit, PrePush})
+	if err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 hooks removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(hooksDir, PreCommit)); !os.IsNotExist(err) {
+		t.Errorf("Expected the pre-commit hook to be removed, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(hooksDir, PrePush)); err != nil || string(data) != foreign {
+		t.Errorf("Expected the foreign pre-push hook to be restored, got %q, %v", data, err)
+	}
+
+	// Hooks scope did not install are kept
+	removed, err = Uninstall(repo, []string{PrePush})
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Expected the foreign hook to be kept, got %v, %v", removed, err)
+	}
+	if _, err := Uninstall(repo, []string{"post-merge"}); err == nil {
+		t.Error("Expected error for unsupported hook")
+	}
+}
+
+func TestRunPreCommit(t *testing.T) {
+	repo := initRepo(t)
+
+	// Nothing staged: nothing to check
+	diagnostics, err := Run(context.Background(), repo, PreCommit)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+
+	// Stage a badly formatted file
+	badFile := filepath.Join(repo, "lib", "bad.go")
+	if err := os.WriteFile(badFile, []byte("package lib\nfunc   Bad( ) {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cmd := exec.Command("git", "add", "-A")
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v: %s", err, output)
+	}
+
+	diagnostics, err = Run(context.Background(), repo, PreCommit)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Check != "format" || diagnostics[0].File != badFile {
+		t.Errorf("Expected one format diagnostic for %s, got %v", badFile, diagnostics)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	repo := t.TempDir()
+
+	config, err := LoadConfig(repo)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(config.Hooks[PreCommit]) == 0 {
+		t.Error("Expected default pre-commit checks")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ConfigPath(repo)), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(ConfigPath(repo), []byte(`{"hooks":{"pre-commit":["lint"]}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err = LoadConfig(repo)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if strings.Join(config.Hooks[PreCommit], ",") != "lint" {
+		t.Errorf("Expected custom pre-commit checks, got %v", config.Hooks[PreCommit])
+	}
+}
@@ -0,0 +1,312 @@
+// Package i18n localizes user-facing text: tool summaries, errors and report
+// templates. Messages are keyed by their English format string, so code
+// keeps writing English and an untranslated message falls back to itself.
+// Only text is localized; JSON field names and check identifiers never change.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var builtinCatalogs embed.FS
+
+// DefaultLocale is the locale messages are written in; it needs no catalog
+const DefaultLocale = "en"
+
+// Config selects the locale for a repository
+type Config struct {
+	Locale string `json:"locale"`
+}
+
+// ConfigPath returns the location of the localization configuration for a repository
+func ConfigPath(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "i18n.json")
+}
+
+// CatalogDir returns the directory holding a repository's own message
+// catalogs, named <locale>.json
+func CatalogDir(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "locales")
+}
+
+// LoadConfig reads the localization configuration for a repository, falling
+// back to the default locale when the file does not exist
+func LoadConfig(repoPath string) (*Config, error) {
+	data, err := os.ReadFile(ConfigPath(repoPath))
+	if os.IsNotExist(err) {
+		return &Config{Locale: DefaultLocale}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read i18n config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse i18n config: %w", err)
+	}
+	if config.Locale == "" {
+		config.Locale = DefaultLocale
+	}
+	return &config, nil
+}
+
+// Localizer translates messages into one locale. A nil Localizer returns
+// messages unchanged.
+type Localizer struct {
+	locale   string
+	messages map[string]string
+	patterns []pattern
+}
+
+// pattern matches a formatted message so it can be translated after the fact
+type pattern struct {
+	re          *regexp.Regexp
+	verbs       []byte
+	translation string
+}
+
+// verbPattern matches a fmt verb with optional argument index, flags, width
+// and precision
+var verbPattern = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// New returns a Localizer for locale, e.g. "de", "es-MX" or "de_DE.UTF-8".
+// The built-in catalog is loaded first, followed by <locale>.json from each of
+// dirs; later catalogs replace earlier translations. A regional locale also
+// uses the catalogs of its language. Missing directories are skipped, but a
+// locale other than English without any catalog is an error.
+func New(locale string, dirs ...string) (*Localizer, error) {
+	locale = Normalize(locale)
+	l := &Localizer{locale: locale, messages: make(map[string]string)}
+	if locale == DefaultLocale {
+		return l, nil
+	}
+
+	names := []string{locale}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		names = []string{language, locale}
+	}
+	loaded := false
+	for _, name := range names {
+		data, err := builtinCatalogs.ReadFile("locales/" + name + ".json")
+		if err == nil {
+			if err := l.merge(data); err != nil {
+				return nil, fmt.Errorf("failed to parse built-in catalog %s: %w", name, err)
+			}
+			loaded = true
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		for _, name := range names {
+			path := filepath.Join(dir, name+".json")
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read catalog %s: %w", path, err)
+			}
+			if err := l.merge(data); err != nil {
+				return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+			}
+			loaded = true
+		}
+	}
+	if !loaded {
+		return nil, fmt.Errorf("no message catalog for locale %s", locale)
+	}
+	l.compile()
+	return l, nil
+}
+
+// Normalize converts POSIX and BCP 47 locale names to the lowercase language
+// and region form used for catalog names: de_DE.UTF-8 becomes de-de, and C
+// and POSIX become en
+func Normalize(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	switch locale {
+	case "", "c", "posix":
+		return DefaultLocale
+	}
+	if strings.HasPrefix(locale, DefaultLocale+"-") {
+		return DefaultLocale
+	}
+	return locale
+}
+
+// Supported returns the locales with a built-in catalog, plus English
+func Supported() []string {
+	locales := []string{DefaultLocale}
+	entries, _ := builtinCatalogs.ReadDir("locales")
+	for _, entry := range entries {
+		locales = append(locales, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// merge adds the translations of a JSON catalog mapping English format
+// strings to their translations
+func (l *Localizer) merge(data []byte) error {
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return err
+	}
+	for message, translation := range messages {
+		if translation != "" {
+			l.messages[message] = translation
+		}
+	}
+	return nil
+}
+
+// compile builds the patterns used to translate already formatted messages.
+// Longer messages are tried first so the most specific one wins.
+func (l *Localizer) compile() {
+	formats := make([]string, 0, len(l.messages))
+	for format := range l.messages {
+		if verbPattern.MatchString(strings.ReplaceAll(format, "%%", "")) {
+			formats = append(formats, format)
+		}
+	}
+	sort.Slice(formats, func(i, j int) bool {
+		if len(formats[i]) != len(formats[j]) {
+			return len(formats[i]) > len(formats[j])
+		}
+		return formats[i] < formats[j]
+	})
+
+	for _, format := range formats {
+		var expr strings.Builder
+		var verbs []byte
+		expr.WriteString("^")
+		last := 0
+		for _, loc := range verbPattern.FindAllStringIndex(format, -1) {
+			expr.WriteString(regexp.QuoteMeta(format[last:loc[0]]))
+			last = loc[1]
+			verb := format[loc[1]-1]
+			switch verb {
+			case '%':
+				expr.WriteString("%")
+				continue
+			case 'd':
+				expr.WriteString(`(-?\d+)`)
+			case 'q':
+				expr.WriteString(`("(?:[^"\\]|\\.)*")`)
+			default:
+				expr.WriteString(`(.*?)`)
+			}
+			verbs = append(verbs, verb)
+		}
+		expr.WriteString(regexp.QuoteMeta(format[last:]))
+		expr.WriteString("$")
+		re, err := regexp.Compile(expr.String())
+		if err != nil {
+			continue
+		}
+		l.patterns = append(l.patterns, pattern{re: re, verbs: verbs, translation: l.messages[format]})
+	}
+}
+
+// Locale returns the normalized locale, or en for a nil Localizer
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return DefaultLocale
+	}
+	return l.locale
+}
+
+// Message returns the translation of an English format string, or the
+// format itself when the catalog has none
+func (l *Localizer) Message(format string) string {
+	if l == nil {
+		return format
+	}
+	if translation, ok := l.messages[format]; ok {
+		return translation
+	}
+	return format
+}
+
+// Sprintf formats the translation of format
+func (l *Localizer) Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(l.Message(format), args...)
+}
+
+// Errorf is fmt.Errorf with the translation of format; %w still wraps
+func (l *Localizer) Errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(l.Message(format), args...)
+}
+
+// Translate localizes text that was already formatted in English, such as
+// an error message produced deep inside the analyzer, by matching it
+// against the catalog's format strings. Unmatched text is returned as is.
+func (l *Localizer) Translate(text string) string {
+	if l == nil {
+		return text
+	}
+	if translation, ok := l.messages[text]; ok && !verbPattern.MatchString(text) {
+		return translation
+	}
+	for _, p := range l.patterns {
+		match := p.re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		args := make([]interface{}, len(p.verbs))
+		for i, verb := range p.verbs {
+			args[i] = match[i+1]
+			switch verb {
+			case 'd':
+				if n, err := strconv.ParseInt(match[i+1], 10, 64); err == nil {
+					args[i] = n
+				}
+			case 'q':
+				if s, err := strconv.Unquote(match[i+1]); err == nil {
+					args[i] = s
+				}
+			case 'v', 'w':
+				// Wrapped errors are translated too
+				args[i] = l.Translate(match[i+1])
+			}
+		}
+		return fmt.Sprintf(strings.ReplaceAll(p.translation, "%w", "%v"), args...)
+	}
+	return text
+}
+
+// Error translates the message of err. The result still unwraps to err, so
+// errors.Is and errors.As keep working.
+func (l *Localizer) Error(err error) error {
+	if err == nil || l == nil {
+		return err
+	}
+	message := l.Translate(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	return &localizedError{message: message, err: err}
+}
+
+// localizedError carries a translated message for an English error
+type localizedError struct {
+	message string
+	err     error
+}
+
+func (e *localizedError) Error() string { return e.message }
+
+func (e *localizedError) Unwrap() error { return e.err }
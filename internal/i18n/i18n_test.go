@@ -0,0 +1,155 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"de":          "de",
+		"de_DE.UTF-8": "de-de",
+		"es-MX":       "es-mx",
+		"en_US":       "en",
+		"C":           "en",
+		"":            "en",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
+
+func TestLocalizer(t *testing.T) {
+	l, err := New("de_DE.UTF-8")
+	if err != nil {
+		t.Fatalf("Failed to create localizer: %v", err)
+	}
+	if l.Locale() != "de-de" {
+		t.Errorf("Expected locale de-de, got %s", l.Locale())
+	}
+	if got := l.Sprintf("%d finding(s):", 3); got != "3 Befund(e):" {
+		t.Errorf("Unexpected translation: %q", got)
+	}
+	if got := l.Sprintf("untranslated %s", "text"); got != "untranslated text" {
+		t.Errorf("Expected untranslated message to fall back to English, got %q", got)
+	}
+
+	// Messages formatted elsewhere are matched against the catalog
+	tests := map[string]string{
+		"type Foo not found":                         "Typ Foo nicht gefunden",
+		"analyzer not initialized":                   "der Analysator ist nicht initialisiert",
+		`unknown format "svg"`:                       `unbekanntes Format "svg"`,
+		"Foo is ambiguous; use one of: a.Foo, b.Foo": "Foo ist mehrdeutig; verwenden Sie einen der folgenden Namen: a.Foo, b.Foo",
+		"something else entirely":                    "something else entirely",
+	}
+	for input, want := range tests {
+		if got := l.Translate(input); got != want {
+			t.Errorf("Translate(%q) = %q, expected %q", input, got, want)
+		}
+	}
+
+	var nilLocalizer *Localizer
+	if got := nilLocalizer.Sprintf("%d finding(s):", 1); got != "1 finding(s):" {
+		t.Errorf("Expected nil localizer to format English, got %q", got)
+	}
+}
+
+func TestLocalizedError(t *testing.T) {
+	l, err := New("es")
+	if err != nil {
+		t.Fatalf("Failed to create localizer: %v", err)
+	}
+	cause := errors.New("type Foo not found")
+	localized := l.Error(cause)
+	if localized.Error() != "no se encontró el tipo Foo" {
+		t.Errorf("Unexpected localized error: %v", localized)
+	}
+	if !errors.Is(localized, cause) {
+		t.Error("Expected localized error to unwrap to the original")
+	}
+	if l.Error(nil) != nil {
+		t.Error("Expected nil error to stay nil")
+	}
+
+	wrapped := l.Errorf("failed to render: %w", cause)
+	if !errors.Is(wrapped, cause) {
+		t.Error("Expected Errorf to wrap with %w")
+	}
+}
+
+func TestUserCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	catalog := `{"No findings.": "Alles in Ordnung.", "Hello %s": "Hallo %s"}`
+	if err := os.WriteFile(filepath.Join(dir, "de.json"), []byte(catalog), 0644); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nl.json"), []byte(`{"No findings.": "Geen bevindingen."}`), 0644); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+
+	l, err := New("de-AT", dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("Failed to create localizer: %v", err)
+	}
+	if got := l.Message("No findings."); got != "Alles in Ordnung." {
+		t.Errorf("Expected user catalog to override built-in, got %q", got)
+	}
+	if got := l.Message("Packages"); got != "Pakete" {
+		t.Errorf("Expected built-in translation to remain, got %q", got)
+	}
+	if got := l.Translate("Hello Welt"); got != "Hallo Welt" {
+		t.Errorf("Unexpected translation: %q", got)
+	}
+
+	if l, err := New("nl", dir); err != nil || l.Message("No findings.") != "Geen bevindingen." {
+		t.Errorf("Expected user-only locale to load, got %v", err)
+	}
+	if _, err := New("xx"); err == nil {
+		t.Error("Expected error for a locale without a catalog")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	repo := t.TempDir()
+	config, err := LoadConfig(repo)
+	if err != nil || config.Locale != DefaultLocale {
+		t.Fatalf("Expected default locale without config, got %v (%v)", config, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ConfigPath(repo)), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(ConfigPath(repo), []byte(`{"locale": "es"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err = LoadConfig(repo)
+	if err != nil || config.Locale != "es" {
+		t.Errorf("Expected locale es, got %v (%v)", config, err)
+	}
+}
+
+// TestCatalogsFormat checks that every built-in translation uses the same
+// verbs as its English message, so formatting never produces %!d(string=...)
+func TestCatalogsFormat(t *testing.T) {
+	for _, locale := range Supported() {
+		if locale == DefaultLocale {
+			continue
+		}
+		l, err := New(locale)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", locale, err)
+		}
+		for message, translation := range l.messages {
+			english := verbPattern.FindAllString(message, -1)
+			translated := verbPattern.FindAllString(translation, -1)
+			if fmt.Sprint(english) != fmt.Sprint(translated) {
+				t.Errorf("%s: %q uses verbs %v, translation %q uses %v", locale, message, english, translation, translated)
+			}
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// Package jobs runs tool calls in the background. Jobs and their results
+// are kept in a directory, so that clients can fetch them after a restart
+// of the server.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// States of a job
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// ErrInterrupted is the error of jobs that were running when the server
+// stopped. They are not run again since the tool may have changed files.
+var ErrInterrupted = errors.New("interrupted by a restart of the server; start the job again")
+
+// Job is a tool call running in the background
+type Job struct {
+	ID        string          `json:"id"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	State     string          `json:"state"`
+	Progress  *Progress       `json:"progress,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	// ResultBytes is the size of the result of a finished job
+	ResultBytes int       `json:"result_bytes,omitempty"`
+	Created     time.Time `json:"created"`
+	Started     time.Time `json:"started,omitzero"`
+	Finished    time.Time `json:"finished,omitzero"`
+}
+
+// Progress is what a running tool last reported about its work
+type Progress struct {
+	Done    int       `json:"done"`
+	Total   int       `json:"total,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// Terminal reports whether the job reached a state it does not leave
+func (j *Job) Terminal() bool {
+	return j.State == StateDone || j.State == StateFailed || j.State == StateCancelled
+}
+
+// RunFunc calls a tool and returns the text of its response
+type RunFunc func(ctx context.Context, tool string, arguments json.RawMessage) (string, error)
+
+// Queue runs jobs in the order they were started with a fixed number of
+// workers
+type Queue struct {
+	dir  string
+	run  RunFunc
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	jobs map[string]*Job
+	// pending are the IDs of the queued jobs, oldest first
+	pending []string
+	cancels map[string]context.CancelFunc
+	// finished is closed and replaced whenever a job finishes
+	finished chan struct{}
+}
+
+// Open loads the jobs kept in dir, creating it if needed, and runs the
+// queued ones. Jobs that were running are failed with ErrInterrupted.
+func Open(dir string, run RunFunc, workers int) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	q := &Queue{
+		dir:      dir,
+		run:      run,
+		ctx:      ctx,
+		stop:     stop,
+		jobs:     make(map[string]*Job),
+		cancels:  make(map[string]context.CancelFunc),
+		finished: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	var queued []*Job
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to read job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || !validID(job.ID) {
+			// Not a job of this version; leave the file alone
+			continue
+		}
+		switch job.State {
+		case StateRunning:
+			job.State = StateFailed
+			job.Error = ErrInterrupted.Error()
+			job.Finished = time.Now()
+			if err := q.save(&job); err != nil {
+				stop()
+				return nil, err
+			}
+		case StateQueued:
+			queued = append(queued, &job)
+		}
+		q.jobs[job.ID] = &job
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].Created.Before(queued[j].Created) })
+	for _, job := range queued {
+		q.pending = append(q.pending, job.ID)
+	}
+
+	for range max(workers, 1) {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q, nil
+}
+
+// Start queues a call of tool and returns its job
+func (q *Queue) Start(tool string, arguments json.RawMessage) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		return nil, errors.New("job queue is closed")
+	}
+	job := &Job{ID: newID(), Tool: tool, Arguments: arguments, State: StateQueued, Created: time.Now()}
+	if err := q.save(job); err != nil {
+		return nil, err
+	}
+	q.jobs[job.ID] = job
+	q.pending = append(q.pending, job.ID)
+	q.cond.Signal()
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// work runs queued jobs until the queue closes
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		job, ctx, cancel := q.next()
+		if job == nil {
+			return
+		}
+		result, err := q.run(ctx, job.Tool, job.Arguments)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		cancel()
+
+		q.mu.Lock()
+		q.finish(job, result, err)
+		q.mu.Unlock()
+	}
+}
+
+// next waits for a queued job and marks it running, or returns nil once
+// the queue closes
+func (q *Queue) next() (*Job, context.Context, context.CancelFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) == 0 && q.ctx.Err() == nil {
+		q.cond.Wait()
+	}
+	if q.ctx.Err() != nil {
+		return nil, nil, nil
+	}
+	job := q.jobs[q.pending[0]]
+	q.pending = q.pending[1:]
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	ctx = context.WithValue(ctx, reporterKey{}, func(progress Progress) { q.report(job.ID, progress) })
+	q.cancels[job.ID] = cancel
+	job.State = StateRunning
+	job.Started = time.Now()
+	_ = q.save(job)
+	return job, ctx, cancel
+}
+
+// finish records the outcome of a job. The caller holds q.mu. Jobs stopped
+// because the queue closed stay running on disk, so that the next Open
+// finds them interrupted.
+func (q *Queue) finish(job *Job, result string, err error) {
+	delete(q.cancels, job.ID)
+	if err != nil && q.ctx.Err() != nil {
+		return
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.State = StateCancelled
+	case err != nil:
+		job.State = StateFailed
+		job.Error = err.Error()
+	default:
+		if werr := writeFile(q.resultPath(job.ID), []byte(result)); werr != nil {
+			job.State = StateFailed
+			job.Error = fmt.Sprintf("failed to write job result: %v", werr)
+			break
+		}
+		job.State = StateDone
+		job.ResultBytes = len(result)
+	}
+	job.Finished = time.Now()
+	_ = q.save(job)
+	close(q.finished)
+	q.finished = make(chan struct{})
+}
+
+// report updates the progress of a running job
+func (q *Queue) report(id string, progress Progress) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.jobs[id]
+	if job == nil || job.State != StateRunning {
+		return
+	}
+	progress.Updated = time.Now()
+	job.Progress = &progress
+	_ = q.save(job)
+}
+
+// Get returns a job
+func (q *Queue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job %s not found; it may have expired", id)
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// List returns every job, the newest first
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].Created.Equal(jobs[j].Created) {
+			return jobs[i].Created.After(jobs[j].Created)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// Wait returns a job once it has finished, or as it is when ctx is done
+func (q *Queue) Wait(ctx context.Context, id string) (*Job, error) {
+	for {
+		q.mu.Lock()
+		job, ok := q.jobs[id]
+		finished := q.finished
+		var snapshot Job
+		if ok {
+			snapshot = *job
+		}
+		q.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("job %s not found; it may have expired", id)
+		}
+		if snapshot.Terminal() {
+			return &snapshot, nil
+		}
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			return &snapshot, nil
+		}
+	}
+}
+
+// Result returns the text of the response of a job that is done
+func (q *Queue) Result(id string) (string, error) {
+	job, err := q.Get(id)
+	if err != nil {
+		return "", err
+	}
+	switch job.State {
+	case StateDone:
+	case StateFailed:
+		return "", fmt.Errorf("job %s failed: %s", id, job.Error)
+	case StateCancelled:
+		return "", fmt.Errorf("job %s was cancelled", id)
+	default:
+		return "", fmt.Errorf("job %s is %s; wait for it to finish", id, job.State)
+	}
+	data, err := os.ReadFile(q.resultPath(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to read job result: %w", err)
+	}
+	return string(data), nil
+}
+
+// cancelWait bounds how long Cancel waits for a running tool to return
+const cancelWait = 5 * time.Second
+
+// Cancel stops a queued or running job and returns it once it stopped, or
+// after cancelWait if the tool is slow to notice
+func (q *Queue) Cancel(id string) (*Job, error) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return nil, fmt.Errorf("job %s not found; it may have expired", id)
+	}
+	if i := slices.Index(q.pending, id); i >= 0 {
+		q.pending = slices.Delete(q.pending, i, i+1)
+		q.finish(job, "", context.Canceled)
+	} else if cancel := q.cancels[id]; cancel != nil {
+		cancel()
+	}
+	q.mu.Unlock()
+
+	ctx, stop := context.WithTimeout(context.Background(), cancelWait)
+	defer stop()
+	return q.Wait(ctx, id)
+}
+
+// Prune removes finished jobs older than maxAge and returns how many were
+// removed
+func (q *Queue) Prune(maxAge time.Duration) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	removed := 0
+	for id, job := range q.jobs {
+		if !job.Terminal() || time.Since(job.Finished) < maxAge {
+			continue
+		}
+		if err := os.Remove(q.path(id)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove job: %w", err)
+		}
+		os.Remove(q.resultPath(id))
+		delete(q.jobs, id)
+		removed++
+	}
+	return removed, nil
+}
+
+// Close stops the running jobs and waits for them to return
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.stop()
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// save writes a job to its file. The caller holds q.mu.
+func (q *Queue) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	if err := writeFile(q.path(job.ID), data); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding a job
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+// resultPath returns the file holding the result of a job
+func (q *Queue) resultPath(id string) string {
+	return filepath.Join(q.dir, id+".result")
+}
+
+// writeFile replaces a file's content in one step, so that a crash leaves
+// either the old or the new content
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+type reporterKey struct{}
+
+// ReportProgress records the progress of the job ctx belongs to; it does
+// nothing outside of jobs
+func ReportProgress(ctx context.Context, done, total int, message string) {
+	if report, ok := ctx.Value(reporterKey{}).(func(Progress)); ok {
+		report(Progress{Done: done, Total: total, Message: message})
+	}
+}
+
+// newID returns a random job ID
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validID reports whether id looks like a job ID, so that IDs from clients
+// cannot name other files
+func validID(id string) bool {
+	return len(id) == 16 && strings.Trim(id, "0123456789abcdef") == ""
+}
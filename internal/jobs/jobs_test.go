@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	release := make(chan struct{})
+	run := func(ctx context.Context, tool string, arguments json.RawMessage) (string, error) {
+		switch tool {
+		case "echo":
+			ReportProgress(ctx, 1, 1, "echoed")
+			return string(arguments), nil
+		case "fail":
+			return "", errors.New("boom")
+		case "block":
+			select {
+			case <-release:
+				return "released", nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		return "", errors.New("unknown tool")
+	}
+	queue, err := Open(dir, run, 1)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+	ctx := context.Background()
+
+	job, err := queue.Start("echo", json.RawMessage(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	if !validID(job.ID) || job.State != StateQueued {
+		t.Errorf("Unexpected new job: %+v", job)
+	}
+	job, err = queue.Wait(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Failed to wait for job: %v", err)
+	}
+	if job.State != StateDone || job.ResultBytes != 7 || job.Progress == nil || job.Progress.Message != "echoed" || job.Started.IsZero() || job.Finished.IsZero() {
+		t.Errorf("Unexpected finished job: %+v", job)
+	}
+	if result, err := queue.Result(job.ID); err != nil || result != `{"a":1}` {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+
+	failed, _ := queue.Start("fail", nil)
+	failed, _ = queue.Wait(ctx, failed.ID)
+	if failed.State != StateFailed || failed.Error != "boom" {
+		t.Errorf("Unexpected failed job: %+v", failed)
+	}
+	if _, err := queue.Result(failed.ID); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the job's error, got %v", err)
+	}
+
+	// With one worker the second job waits for the first
+	blocked, _ := queue.Start("block", nil)
+	waiting, _ := queue.Start("echo", json.RawMessage(`"later"`))
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if job, _ := queue.Wait(timeout, waiting.ID); job.State != StateQueued {
+		t.Errorf("Expected the second job to be queued, got %+v", job)
+	}
+	if _, err := queue.Result(waiting.ID); err == nil || !strings.Contains(err.Error(), "is queued") {
+		t.Errorf("Expected an error for a queued job, got %v", err)
+	}
+	if job, err := queue.Cancel(blocked.ID); err != nil || job.State != StateCancelled {
+		t.Errorf("Expected a cancelled job, got %+v, %v", job, err)
+	}
+	if job, _ := queue.Wait(ctx, waiting.ID); job.State != StateDone {
+		t.Errorf("Expected the queued job to run after the cancel, got %+v", job)
+	}
+
+	if jobs := queue.List(); len(jobs) != 4 || jobs[0].ID != waiting.ID {
+		t.Errorf("Expected 4 jobs newest first, got %+v", jobs)
+	}
+	if _, err := queue.Get("nosuchjob"); err == nil {
+		t.Error("Expected error for an unknown job")
+	}
+	queue.Close()
+}
+
+func TestQueueRestart(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{}, 1)
+	block := func(ctx context.Context, tool string, arguments json.RawMessage) (string, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	queue, err := Open(dir, block, 1)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+	running, _ := queue.Start("scan", nil)
+	<-started
+	queued, _ := queue.Start("scan", json.RawMessage(`"next"`))
+	done, _ := queue.Start("scan", nil)
+	queue.Cancel(done.ID)
+	queue.Close()
+
+	// A new server runs the queued job and fails the interrupted one
+	run := func(ctx context.Context, tool string, arguments json.RawMessage) (string, error) {
+		return "ran " + string(arguments), nil
+	}
+	queue, err = Open(dir, run, 1)
+	if err != nil {
+		t.Fatalf("Failed to reopen queue: %v", err)
+	}
+	defer queue.Close()
+	if job, _ := queue.Get(running.ID); job.State != StateFailed || job.Error != ErrInterrupted.Error() {
+		t.Errorf("Expected the running job to be interrupted, got %+v", job)
+	}
+	if job, _ := queue.Wait(context.Background(), queued.ID); job.State != StateDone {
+		t.Errorf("Expected the queued job to run, got %+v", job)
+	}
+	if result, err := queue.Result(queued.ID); err != nil || result != `ran "next"` {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+	if job, _ := queue.Get(done.ID); job.State != StateCancelled {
+		t.Errorf("Expected the cancelled job to stay cancelled, got %+v", job)
+	}
+
+	if removed, err := queue.Prune(0); err != nil || removed != 3 {
+		t.Errorf("Expected 3 finished jobs to be pruned, got %d, %v", removed, err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected no files after pruning, got %d", len(files))
+	}
+}
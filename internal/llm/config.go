@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// defaultBaseURLs are the API endpoints of each provider
+var defaultBaseURLs = map[string]string{
+	ProviderOpenAI:    "https://api.openai.com/v1",
+	ProviderAnthropic: "https://api.anthropic.com",
+	ProviderOllama:    "http://localhost:11434",
+}
+
+// apiKeyVars are the environment variables each provider's key is read
+// from when SCOPE_LLM_API_KEY is not set
+var apiKeyVars = map[string]string{
+	ProviderOpenAI:    "OPENAI_API_KEY",
+	ProviderAnthropic: "ANTHROPIC_API_KEY",
+}
+
+// Config configures the language model native tools call
+type Config struct {
+	// Provider is openai (or any OpenAI-compatible API), anthropic or ollama
+	Provider string
+	Model    string
+	// BaseURL is the API endpoint, such as https://api.openai.com/v1 or
+	// the address of a local server
+	BaseURL string
+	APIKey  string
+	// MaxTokens caps the tokens of each completion
+	MaxTokens int
+	// Timeout bounds each request, retries excluded
+	Timeout time.Duration
+	// MaxRetries is how many times a request failing with a rate limit, a
+	// server error or a network error is retried
+	MaxRetries int
+}
+
+// ConfigFromEnv reads the configuration from SCOPE_LLM_PROVIDER,
+// SCOPE_LLM_MODEL, SCOPE_LLM_BASE_URL, SCOPE_LLM_API_KEY (or the provider's
+// usual variable, such as OPENAI_API_KEY), SCOPE_LLM_MAX_TOKENS,
+// SCOPE_LLM_TIMEOUT and SCOPE_LLM_MAX_RETRIES. It returns nil when no
+// provider is set.
+func ConfigFromEnv() (*Config, error) {
+	return configFrom(os.Getenv)
+}
+
+// configFrom implements ConfigFromEnv with getenv looking up variables
+func configFrom(getenv func(string) string) (*Config, error) {
+	provider := strings.ToLower(getenv("SCOPE_LLM_PROVIDER"))
+	if provider == "" {
+		return nil, nil
+	}
+	baseURL, ok := defaultBaseURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown SCOPE_LLM_PROVIDER %q (expected %s, %s or %s)", provider, ProviderOpenAI, ProviderAnthropic, ProviderOllama)
+	}
+
+	config := &Config{
+		Provider:   provider,
+		Model:      getenv("SCOPE_LLM_MODEL"),
+		BaseURL:    baseURL,
+		APIKey:     getenv("SCOPE_LLM_API_KEY"),
+		MaxTokens:  4096,
+		Timeout:    2 * time.Minute,
+		MaxRetries: 3,
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("SCOPE_LLM_MODEL is required with SCOPE_LLM_PROVIDER")
+	}
+	if value := getenv("SCOPE_LLM_BASE_URL"); value != "" {
+		if _, err := url.ParseRequestURI(value); err != nil {
+			return nil, fmt.Errorf("invalid SCOPE_LLM_BASE_URL: %w", err)
+		}
+		config.BaseURL = value
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.APIKey == "" && apiKeyVars[provider] != "" {
+		config.APIKey = getenv(apiKeyVars[provider])
+	}
+	if value := getenv("SCOPE_LLM_MAX_TOKENS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid SCOPE_LLM_MAX_TOKENS %q", value)
+		}
+		config.MaxTokens = n
+	}
+	if value := getenv("SCOPE_LLM_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid SCOPE_LLM_TIMEOUT %q", value)
+		}
+		config.Timeout = timeout
+	}
+	if value := getenv("SCOPE_LLM_MAX_RETRIES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid SCOPE_LLM_MAX_RETRIES %q", value)
+		}
+		config.MaxRetries = n
+	}
+	return config, nil
+}
@@ -0,0 +1,196 @@
+// Package llm calls language models for the tools that need one, such as
+// code_review. Providers speak the OpenAI chat completions API (which many
+// hosted and local servers implement), the Anthropic messages API or the
+// Ollama chat API. A Client retries failed requests and accounts for the
+// tokens used.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Roles of messages
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a turn of a conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request asks a model to continue a conversation
+type Request struct {
+	// System sets the model's instructions
+	System   string
+	Messages []Message
+	// MaxTokens caps the completion; zero uses the client's default
+	MaxTokens int
+}
+
+// Usage counts the tokens of requests and completions
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Response is a model's completion
+type Response struct {
+	Text  string `json:"text"`
+	Model string `json:"model"`
+	Usage Usage  `json:"usage"`
+}
+
+// Provider completes requests with a model of one API
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// StatusError is an API response with an error status
+type StatusError struct {
+	Code int
+	// Message is the error the API reported
+	Message string
+	// RetryAfter is how long the API asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Code, e.Message)
+}
+
+// Stats accounts for a client's calls
+type Stats struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Calls    int    `json:"calls"`
+	Failures int    `json:"failures,omitempty"`
+	Retries  int    `json:"retries,omitempty"`
+	Usage
+}
+
+// Client calls a provider with retries and token accounting
+type Client struct {
+	provider   Provider
+	maxTokens  int
+	maxRetries int
+	// backoff is the wait before the first retry, doubled for each next one
+	backoff time.Duration
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// New creates a client of the provider a configuration names
+func New(config *Config) (*Client, error) {
+	httpClient := &http.Client{Timeout: config.Timeout}
+	var provider Provider
+	switch config.Provider {
+	case ProviderOpenAI:
+		provider = &openAI{baseURL: config.BaseURL, apiKey: config.APIKey, model: config.Model, http: httpClient}
+	case ProviderAnthropic:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("the anthropic provider needs an API key")
+		}
+		provider = &anthropic{baseURL: config.BaseURL, apiKey: config.APIKey, model: config.Model, http: httpClient}
+	case ProviderOllama:
+		provider = &ollama{baseURL: config.BaseURL, model: config.Model, http: httpClient}
+	default:
+		return nil, fmt.Errorf("unknown provider %q", config.Provider)
+	}
+	client := NewClient(provider, config.MaxTokens, config.MaxRetries)
+	client.stats.Provider, client.stats.Model = config.Provider, config.Model
+	return client, nil
+}
+
+// NewClient wraps a provider, completing requests with up to maxTokens
+// tokens unless they ask for fewer, and retrying each up to maxRetries times
+func NewClient(provider Provider, maxTokens, maxRetries int) *Client {
+	return &Client{provider: provider, maxTokens: maxTokens, maxRetries: maxRetries, backoff: time.Second}
+}
+
+// Complete sends a request to the provider. Rate limits, server errors and
+// network errors are retried with exponential backoff, waiting as long as
+// the API asks when it says.
+func (c *Client) Complete(ctx context.Context, req Request) (*Response, error) {
+	if req.MaxTokens == 0 || c.maxTokens > 0 && req.MaxTokens > c.maxTokens {
+		req.MaxTokens = c.maxTokens
+	}
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.provider.Complete(ctx, req)
+		if err == nil {
+			c.record(func(stats *Stats) {
+				stats.Calls++
+				stats.InputTokens += resp.Usage.InputTokens
+				stats.OutputTokens += resp.Usage.OutputTokens
+			})
+			return resp, nil
+		}
+		if attempt == c.maxRetries || !retryable(ctx, err) {
+			c.record(func(stats *Stats) {
+				stats.Calls++
+				stats.Failures++
+			})
+			return nil, err
+		}
+
+		wait := backoff
+		var status *StatusError
+		if errors.As(err, &status) && status.RetryAfter > 0 {
+			wait = status.RetryAfter
+		}
+		backoff *= 2
+		c.record(func(stats *Stats) { stats.Retries++ })
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Stats returns the client's calls so far
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// record updates the stats
+func (c *Client) record(update func(*Stats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// retryable reports whether a failed request may succeed when sent again
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		response string
+		check    func(t *testing.T, r *http.Request, body map[string]any)
+	}{
+		{
+			provider: ProviderOpenAI,
+			path:     "/chat/completions",
+			response: `{"model":"gpt","choices":[{"message":{"role":"assistant","content":"looks good"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`,
+			check: func(t *testing.T, r *http.Request, body map[string]any) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					t.Errorf("Expected a bearer token, got %q", r.Header.Get("Authorization"))
+				}
+				if messages := body["messages"].([]any); len(messages) != 2 || messages[0].(map[string]any)["role"] != "system" {
+					t.Errorf("Expected the system prompt as the first message, got %v", messages)
+				}
+			},
+		},
+		{
+			provider: ProviderAnthropic,
+			path:     "/v1/messages",
+			response: `{"model":"claude","content":[{"type":"text","text":"looks "},{"type":"text","text":"good"}],"usage":{"input_tokens":12,"output_tokens":3}}`,
+			check: func(t *testing.T, r *http.Request, body map[string]any) {
+				if r.Header.Get("x-api-key") != "secret" || r.Header.Get("anthropic-version") == "" {
+					t.Errorf("Expected API key and version headers, got %v", r.Header)
+				}
+				if body["system"] != "be brief" || body["max_tokens"] != float64(100) {
+					t.Errorf("Unexpected request %v", body)
+				}
+			},
+		},
+		{
+			provider: ProviderOllama,
+			path:     "/api/chat",
+			response: `{"model":"llama","message":{"role":"assistant","content":"looks good"},"prompt_eval_count":12,"eval_count":3}`,
+			check: func(t *testing.T, r *http.Request, body map[string]any) {
+				if body["stream"] != false {
+					t.Errorf("Expected a non-streaming request, got %v", body)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != test.path {
+					http.NotFound(w, r)
+					return
+				}
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				test.check(t, r, body)
+				w.Write([]byte(test.response))
+			}))
+			defer server.Close()
+
+			client, err := New(&Config{Provider: test.provider, Model: "m", BaseURL: server.URL, APIKey: "secret", MaxTokens: 100, Timeout: time.Second})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			resp, err := client.Complete(context.Background(), Request{
+				System:   "be brief",
+				Messages: []Message{{Role: RoleUser, Content: "review this"}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to complete: %v", err)
+			}
+			if resp.Text != "looks good" || resp.Usage != (Usage{InputTokens: 12, OutputTokens: 3}) {
+				t.Errorf("Unexpected response %+v", resp)
+			}
+			if stats := client.Stats(); stats.Calls != 1 || stats.InputTokens != 12 || stats.OutputTokens != 3 || stats.Provider != test.provider {
+				t.Errorf("Unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"slow down"}}`))
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(&Config{Provider: ProviderOpenAI, Model: "m", BaseURL: server.URL, Timeout: time.Second, MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.backoff = time.Millisecond
+	if _, err := client.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}}); err != nil {
+		t.Fatalf("Expected the request to succeed after retries: %v", err)
+	}
+	if stats := client.Stats(); attempts != 3 || stats.Retries != 2 || stats.Calls != 1 || stats.Failures != 0 {
+		t.Errorf("Unexpected attempts %d and stats %+v", attempts, stats)
+	}
+
+	// Client errors are not retried
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"bad key"}}`))
+	})
+	_, err = client.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusUnauthorized || status.Message != "bad key" {
+		t.Errorf("Expected an unauthorized error, got %v", err)
+	}
+	if stats := client.Stats(); stats.Failures != 1 || stats.Retries != 2 {
+		t.Errorf("Expected one failure without retries, got %+v", stats)
+	}
+}
+
+func TestConfigFrom(t *testing.T) {
+	env := map[string]string{
+		"SCOPE_LLM_PROVIDER":    "anthropic",
+		"SCOPE_LLM_MODEL":       "claude",
+		"ANTHROPIC_API_KEY":     "secret",
+		"SCOPE_LLM_TIMEOUT":     "30s",
+		"SCOPE_LLM_MAX_RETRIES": "0",
+	}
+	config, err := configFrom(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if config.BaseURL != "https://api.anthropic.com" || config.APIKey != "secret" || config.Timeout != 30*time.Second || config.MaxRetries != 0 {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	if config, err := configFrom(func(string) string { return "" }); config != nil || err != nil {
+		t.Errorf("Expected no config without a provider, got %+v (%v)", config, err)
+	}
+	for _, bad := range []map[string]string{
+		{"SCOPE_LLM_PROVIDER": "other", "SCOPE_LLM_MODEL": "m"},
+		{"SCOPE_LLM_PROVIDER": "openai"},
+		{"SCOPE_LLM_PROVIDER": "ollama", "SCOPE_LLM_MODEL": "m", "SCOPE_LLM_MAX_TOKENS": "lots"},
+	} {
+		if _, err := configFrom(func(name string) string { return bad[name] }); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion is the version of the Anthropic API requests are made to
+const anthropicVersion = "2023-06-01"
+
+// openAI speaks the OpenAI chat completions API
+type openAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+func (p *openAI) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+	body := map[string]any{
+		"model":    p.model,
+		"messages": messages,
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := post(ctx, p.http, p.baseURL+"/chat/completions", headers, body, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("completion has no choices")
+	}
+	return &Response{
+		Text:  result.Choices[0].Message.Content,
+		Model: result.Model,
+		Usage: Usage{InputTokens: result.Usage.PromptTokens, OutputTokens: result.Usage.CompletionTokens},
+	}, nil
+}
+
+// anthropic speaks the Anthropic messages API
+type anthropic struct {
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+func (p *anthropic) Complete(ctx context.Context, req Request) (*Response, error) {
+	body := map[string]any{
+		"model":      p.model,
+		"messages":   req.Messages,
+		"max_tokens": req.MaxTokens,
+	}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var result struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := post(ctx, p.http, p.baseURL+"/v1/messages", headers, body, &result); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return &Response{
+		Text:  text.String(),
+		Model: result.Model,
+		Usage: Usage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens},
+	}, nil
+}
+
+// ollama speaks the chat API of a local Ollama server
+type ollama struct {
+	baseURL string
+	model   string
+	http    *http.Client
+}
+
+func (p *ollama) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+	body := map[string]any{
+		"model":    p.model,
+		"messages": messages,
+		"stream":   false,
+	}
+	if req.MaxTokens > 0 {
+		body["options"] = map[string]any{"num_predict": req.MaxTokens}
+	}
+
+	var result struct {
+		Model           string  `json:"model"`
+		Message         Message `json:"message"`
+		PromptEvalCount int     `json:"prompt_eval_count"`
+		EvalCount       int     `json:"eval_count"`
+	}
+	if err := post(ctx, p.http, p.baseURL+"/api/chat", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &Response{
+		Text:  result.Message.Content,
+		Model: result.Model,
+		Usage: Usage{InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount},
+	}, nil
+}
+
+// post sends body as JSON and decodes the JSON response into result.
+// Error statuses become a StatusError with the message the API reported.
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Code: resp.StatusCode, Message: errorMessage(payload), RetryAfter: retryAfter(resp.Header)}
+	}
+	if err := json.Unmarshal(payload, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message of an API error response, which the
+// providers nest as {"error": {"message": ...}} or give as {"error": "..."}
+func errorMessage(payload []byte) string {
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(payload, &nested) == nil && nested.Error.Message != "" {
+		return nested.Error.Message
+	}
+	var flat struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(payload, &flat) == nil && flat.Error != "" {
+		return flat.Error
+	}
+	message := strings.TrimSpace(string(payload))
+	if len(message) > 500 {
+		message = message[:500] + "..."
+	}
+	return message
+}
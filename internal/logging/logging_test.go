@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDInRecords(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	id := NewRequestID()
+	if len(id) != 16 || id == NewRequestID() {
+		t.Errorf("Expected random 16 character IDs, got %q", id)
+	}
+	ctx := WithRequestID(context.Background(), id)
+	if RequestID(ctx) != id || RequestID(context.Background()) != "" {
+		t.Errorf("Expected the ID only in the request's context")
+	}
+
+	logger.With("tool", "lookup_type").InfoContext(ctx, "Looking up type", "type", "Analyzer")
+	logger.Info("Starting server")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %q", buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Failed to parse record: %v", err)
+	}
+	if record[RequestIDKey] != id || record["tool"] != "lookup_type" || record["type"] != "Analyzer" {
+		t.Errorf("Expected the request ID with the record's attributes, got %v", record)
+	}
+	if strings.Contains(lines[1], RequestIDKey) {
+		t.Errorf("Expected no request ID outside a request, got %s", lines[1])
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "", "warn")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "level=WARN msg=shown") {
+		t.Errorf("Expected text records at warn and above, got %q", buf.String())
+	}
+
+	if _, err := New(&buf, "xml", ""); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+	if _, err := New(&buf, "", "loud"); err == nil {
+		t.Error("Expected error for an unknown level")
+	}
+}
@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Symbol describes a symbol resolved through gopls
+type Symbol struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Container string   `json:"container,omitempty"`
+	Location  Location `json:"location"`
+	Hover     string   `json:"hover,omitempty"`
+}
+
+// Bridge answers name-based queries (the form MCP tools use) with gopls,
+// translating them into position-based LSP requests
+type Bridge struct {
+	client *Client
+}
+
+// NewBridge creates a bridge over a connected client
+func NewBridge(client *Client) *Bridge {
+	return &Bridge{client: client}
+}
+
+// Close closes the underlying client
+func (b *Bridge) Close() error {
+	return b.client.Close()
+}
+
+// Lookup resolves a possibly qualified name ("Config", "analyzer.Config",
+// "Analyzer.Refresh") and returns its declaration with gopls's hover text
+func (b *Bridge) Lookup(ctx context.Context, name string) (*Symbol, error) {
+	sym, err := b.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Symbol{
+		Name:      sym.Name,
+		Kind:      KindName(sym.Kind),
+		Container: sym.ContainerName,
+		Location:  sym.Location,
+	}
+	if result.Hover, err = b.client.Hover(ctx, sym.Location); err != nil {
+		return nil, fmt.Errorf("hover failed: %w", err)
+	}
+	return result, nil
+}
+
+// FindUsages returns every reference to name, excluding its declaration
+func (b *Bridge) FindUsages(ctx context.Context, name string) ([]Location, error) {
+	sym, err := b.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := b.client.References(ctx, sym.Location, false)
+	if err != nil {
+		return nil, fmt.Errorf("references failed: %w", err)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].URI != locations[j].URI {
+			return locations[i].URI < locations[j].URI
+		}
+		if locations[i].Range.Start.Line != locations[j].Range.Start.Line {
+			return locations[i].Range.Start.Line < locations[j].Range.Start.Line
+		}
+		return locations[i].Range.Start.Character < locations[j].Range.Start.Character
+	})
+	return locations, nil
+}
+
+// Rename computes the per-file edits renaming name to newName. The edits
+// are not applied.
+func (b *Bridge) Rename(ctx context.Context, name, newName string) ([]FileEdit, error) {
+	sym, err := b.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := b.client.Rename(ctx, sym.Location, newName)
+	if err != nil {
+		return nil, fmt.Errorf("rename failed: %w", err)
+	}
+	return edit.FileEdits()
+}
+
+// resolve finds the single workspace symbol matching name
+func (b *Bridge) resolve(ctx context.Context, name string) (*SymbolInformation, error) {
+	qualifier, ident := name, name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		qualifier, ident = name[:i], name[i+1:]
+	} else {
+		qualifier = ""
+	}
+
+	symbols, err := b.client.Symbols(ctx, ident)
+	if err != nil {
+		return nil, fmt.Errorf("workspace symbol query failed: %w", err)
+	}
+
+	var matches []SymbolInformation
+	for _, sym := range symbols {
+		if matchesSymbol(sym, qualifier, ident) {
+			matches = append(matches, sym)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("symbol %s not found", name)
+	case 1:
+		return &matches[0], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, sym := range matches {
+		candidates[i] = qualifiedName(sym)
+	}
+	sort.Strings(candidates)
+	return nil, fmt.Errorf("%s is ambiguous; use one of: %s", name, strings.Join(candidates, ", "))
+}
+
+// matchesSymbol reports whether a workspace symbol is the one named by
+// qualifier and ident. gopls names methods and fields "Type.Member" and may
+// qualify names with their package, so a member's qualifier must match its
+// type (optionally package-qualified) while a top-level symbol's qualifier
+// matches its package.
+func matchesSymbol(sym SymbolInformation, qualifier, ident string) bool {
+	prefix, last := "", sym.Name
+	if i := strings.LastIndex(sym.Name, "."); i >= 0 {
+		prefix, last = sym.Name[:i], sym.Name[i+1:]
+	}
+	if last != ident {
+		return false
+	}
+	if qualifier == "" {
+		return true
+	}
+
+	scopes := []string{sym.ContainerName}
+	if prefix != "" {
+		scopes = []string{prefix, sym.ContainerName + "." + prefix}
+	}
+	for _, scope := range scopes {
+		if scope == qualifier || strings.HasSuffix(scope, "/"+qualifier) || strings.HasSuffix(scope, "."+qualifier) ||
+			path.Base(scope) == qualifier {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedName formats a symbol with its container for disambiguation
+func qualifiedName(sym SymbolInformation) string {
+	if sym.ContainerName == "" {
+		return sym.Name
+	}
+	return sym.ContainerName + "." + sym.Name
+}
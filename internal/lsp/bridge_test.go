@@ -0,0 +1,150 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeGopls serves canned responses for the requests the bridge makes
+type fakeGopls struct {
+	conn       *Conn
+	configured chan int
+}
+
+func (f *fakeGopls) handle(method string, params json.RawMessage) (interface{}, error) {
+	loc := func(file string, line, char int) Location {
+		return Location{URI: "file:///repo/" + file, Range: Range{Start: Position{Line: line, Character: char}, End: Position{Line: line, Character: char + 6}}}
+	}
+
+	switch method {
+	case "initialize":
+		// Ask the client for configuration the way gopls does
+		go func() {
+			var config []interface{}
+			f.conn.Call(context.Background(), "workspace/configuration", map[string]interface{}{"items": []interface{}{map[string]string{"section": "gopls"}}}, &config)
+			f.configured <- len(config)
+		}()
+		return map[string]interface{}{"capabilities": map[string]interface{}{}}, nil
+	case "workspace/symbol":
+		return []SymbolInformation{
+			{Name: "Config", Kind: 23, ContainerName: "example.com/m/alpha", Location: loc("alpha/alpha.go", 3, 5)},
+			{Name: "Config", Kind: 23, ContainerName: "example.com/m/beta", Location: loc("beta/beta.go", 7, 5)},
+			{Name: "Client.Config", Kind: 8, ContainerName: "example.com/m/beta", Location: loc("beta/beta.go", 12, 1)},
+			{Name: "ConfigLoader", Kind: 23, ContainerName: "example.com/m/alpha", Location: loc("alpha/alpha.go", 9, 5)},
+		}, nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		json.Unmarshal(params, &p)
+		return map[string]interface{}{"contents": map[string]string{"kind": "markdown", "value": fmt.Sprintf("type Config struct{} at %d", p.Position.Line)}}, nil
+	case "textDocument/references":
+		return []Location{loc("b.go", 9, 1), loc("a.go", 4, 2), loc("a.go", 1, 0)}, nil
+	case "textDocument/rename":
+		return map[string]interface{}{"changes": map[string][]TextEdit{
+			"file:///repo/alpha/alpha.go": {{Range: Range{Start: Position{Line: 3, Character: 5}, End: Position{Line: 3, Character: 11}}, NewText: "Settings"}},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unexpected method %s", method)
+	}
+}
+
+func newTestBridge(t *testing.T) (*Bridge, *fakeGopls) {
+	t.Helper()
+	clientSide, serverSide := net.Pipe()
+	fake := &fakeGopls{configured: make(chan int, 1)}
+	fake.conn = NewConn(serverSide, serverSide, fake.handle)
+
+	client, err := NewClient(context.Background(), clientSide, clientSide, clientSide, "/repo")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		serverSide.Close()
+	})
+	return NewBridge(client), fake
+}
+
+func TestBridge(t *testing.T) {
+	bridge, fake := newTestBridge(t)
+	ctx := context.Background()
+
+	if n := <-fake.configured; n != 1 {
+		t.Errorf("Expected one configuration item in the reply, got %d", n)
+	}
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		_, err := bridge.Lookup(ctx, "Config")
+		if err == nil || !strings.Contains(err.Error(), "example.com/m/alpha.Config") || !strings.Contains(err.Error(), "example.com/m/beta.Client.Config") {
+			t.Errorf("Expected ambiguity error listing candidates, got %v", err)
+		}
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		sym, err := bridge.Lookup(ctx, "beta.Config")
+		if err != nil {
+			t.Fatalf("Lookup failed: %v", err)
+		}
+		if sym.Kind != "struct" || sym.Container != "example.com/m/beta" || sym.Hover != "type Config struct{} at 7" {
+			t.Errorf("Unexpected symbol: %+v", sym)
+		}
+		if sym.Location.String() != "/repo/beta/beta.go:8:6" {
+			t.Errorf("Unexpected location: %s", sym.Location)
+		}
+
+		field, err := bridge.Lookup(ctx, "Client.Config")
+		if err != nil || field.Kind != "field" {
+			t.Errorf("Expected Client.Config field, got %+v, %v", field, err)
+		}
+
+		if _, err := bridge.Lookup(ctx, "gamma.Config"); err == nil {
+			t.Error("Expected not found error")
+		}
+	})
+
+	t.Run("FindUsages", func(t *testing.T) {
+		usages, err := bridge.FindUsages(ctx, "alpha.Config")
+		if err != nil {
+			t.Fatalf("FindUsages failed: %v", err)
+		}
+		var got []string
+		for _, loc := range usages {
+			got = append(got, loc.String())
+		}
+		if want := "/repo/a.go:2:1,/repo/a.go:5:3,/repo/b.go:10:2"; strings.Join(got, ",") != want {
+			t.Errorf("Expected sorted usages %s, got %v", want, got)
+		}
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		edits, err := bridge.Rename(ctx, "example.com/m/alpha.Config", "Settings")
+		if err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+		if len(edits) != 1 || edits[0].File != "/repo/alpha/alpha.go" || edits[0].Edits[0].NewText != "Settings" {
+			t.Errorf("Unexpected edits: %+v", edits)
+		}
+	})
+}
+
+func TestCallErrors(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	NewConn(serverSide, serverSide, func(method string, params json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("no such method")
+	})
+	conn := NewConn(clientSide, clientSide, nil)
+
+	err := conn.Call(context.Background(), "bogus", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no such method") {
+		t.Errorf("Expected server error, got %v", err)
+	}
+
+	serverSide.Close()
+	<-conn.Done()
+	if err := conn.Call(context.Background(), "bogus", nil, nil); err == nil {
+		t.Error("Expected error after the connection closed")
+	}
+}
@@ -0,0 +1,253 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Client is an LSP client for gopls, either spawned as a child process or
+// connected to a running instance
+type Client struct {
+	conn   *Conn
+	closer io.Closer
+	cmd    *exec.Cmd
+	root   string
+}
+
+// Spawn starts `gopls serve` (or binary when set) for the workspace at root
+// and initializes it
+func Spawn(ctx context.Context, root, binary string) (*Client, error) {
+	if binary == "" {
+		binary = "gopls"
+	}
+	cmd := exec.Command(binary, "serve")
+	cmd.Dir = root
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+
+	client, err := NewClient(ctx, stdout, stdin, stdin, root)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	client.cmd = cmd
+	return client, nil
+}
+
+// Dial connects to a gopls started with -listen. addr is host:port, or
+// unix;path for a Unix socket, matching gopls's -remote syntax.
+func Dial(ctx context.Context, addr, root string) (*Client, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix;"); ok {
+		network, addr = "unix", path
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gopls at %s: %w", addr, err)
+	}
+
+	client, err := NewClient(ctx, conn, conn, conn, root)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClient performs the initialize handshake over r and w for the
+// workspace at root. closer is closed when the client is closed.
+func NewClient(ctx context.Context, r io.Reader, w io.Writer, closer io.Closer, root string) (*Client, error) {
+	c := &Client{closer: closer, root: root}
+	c.conn = NewConn(r, w, c.handle)
+
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   PathToURI(root),
+		"workspaceFolders": []map[string]string{
+			{"uri": PathToURI(root), "name": root},
+		},
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"hover":  map[string]interface{}{"contentFormat": []string{"markdown", "plaintext"}},
+				"rename": map[string]interface{}{"prepareSupport": false},
+			},
+			"workspace": map[string]interface{}{
+				"workspaceEdit": map[string]interface{}{"documentChanges": true},
+				"configuration": true,
+			},
+		},
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return nil, fmt.Errorf("failed to initialize gopls: %w", err)
+	}
+	if err := c.conn.Notify("initialized", struct{}{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// handle answers the requests gopls sends to its client
+func (c *Client) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "workspace/configuration":
+		// One (empty) configuration per requested item
+		var req struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(params, &req)
+		return make([]interface{}, len(req.Items)), nil
+	default:
+		return nil, nil
+	}
+}
+
+// Symbols runs a workspace symbol query
+func (c *Client) Symbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	var symbols []SymbolInformation
+	err := c.conn.Call(ctx, "workspace/symbol", map[string]string{"query": query}, &symbols)
+	return symbols, err
+}
+
+// Hover returns the hover text for a position
+func (c *Client) Hover(ctx context.Context, loc Location) (string, error) {
+	var hover struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := c.conn.Call(ctx, "textDocument/hover", positionParams(loc), &hover); err != nil {
+		return "", err
+	}
+	if len(hover.Contents) == 0 {
+		return "", nil
+	}
+
+	var markup struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(hover.Contents, &markup); err == nil && markup.Value != "" {
+		return markup.Value, nil
+	}
+	var text string
+	if err := json.Unmarshal(hover.Contents, &text); err == nil {
+		return text, nil
+	}
+	return string(hover.Contents), nil
+}
+
+// Definition returns the locations defining the identifier at a position
+func (c *Client) Definition(ctx context.Context, loc Location) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/definition", positionParams(loc), &raw); err != nil {
+		return nil, err
+	}
+	return decodeLocations(raw)
+}
+
+// References returns the locations referencing the identifier at a position
+func (c *Client) References(ctx context.Context, loc Location, includeDeclaration bool) ([]Location, error) {
+	params := struct {
+		TextDocumentPositionParams
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}{TextDocumentPositionParams: positionParams(loc)}
+	params.Context.IncludeDeclaration = includeDeclaration
+
+	var locations []Location
+	err := c.conn.Call(ctx, "textDocument/references", params, &locations)
+	return locations, err
+}
+
+// Rename computes the edits renaming the identifier at a position
+func (c *Client) Rename(ctx context.Context, loc Location, newName string) (*WorkspaceEdit, error) {
+	params := struct {
+		TextDocumentPositionParams
+		NewName string `json:"newName"`
+	}{positionParams(loc), newName}
+
+	var edit WorkspaceEdit
+	if err := c.conn.Call(ctx, "textDocument/rename", params, &edit); err != nil {
+		return nil, err
+	}
+	return &edit, nil
+}
+
+// Close shuts gopls down (or disconnects from it) and releases its resources
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if c.cmd != nil {
+		c.conn.Call(ctx, "shutdown", nil, nil)
+		c.conn.Notify("exit", nil)
+	}
+	err := c.closer.Close()
+	if c.cmd != nil {
+		done := make(chan error, 1)
+		go func() { done <- c.cmd.Wait() }()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			c.cmd.Process.Kill()
+			<-done
+		}
+	}
+	return err
+}
+
+func positionParams(loc Location) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: loc.URI},
+		Position:     loc.Range.Start,
+	}
+}
+
+// decodeLocations decodes a Location, []Location, or []LocationLink result
+func decodeLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return nil, err
+		}
+		return []Location{loc}, nil
+	}
+
+	var items []struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locations := make([]Location, len(items))
+	for i, item := range items {
+		if item.TargetURI != "" {
+			locations[i] = Location{URI: item.TargetURI, Range: item.TargetSelectionRange}
+		} else {
+			locations[i] = item.Location
+		}
+	}
+	return locations, nil
+}
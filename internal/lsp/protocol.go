@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Position is a zero-based line and UTF-16 character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a half-open range between two positions
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range within a document
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// String formats the location as a one-based file:line:col
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", URIToPath(l.URI), l.Range.Start.Line+1, l.Range.Start.Character+1)
+}
+
+// TextDocumentIdentifier identifies a document by URI
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// TextDocumentPositionParams identifies a position within a document
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// SymbolInformation is a workspace/symbol result
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+// TextEdit replaces a range of a document with new text
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit is the result of a rename. Servers use either Changes or
+// DocumentChanges.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage     `json:"documentChanges,omitempty"`
+}
+
+// textDocumentEdit is the DocumentChanges entry for edits to one document
+type textDocumentEdit struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit             `json:"edits"`
+}
+
+// FileEdit holds the edits a rename makes to a single file
+type FileEdit struct {
+	File  string     `json:"file"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// FileEdits flattens a workspace edit into per-file edits sorted by file.
+// Resource operations such as file renames are not supported.
+func (e *WorkspaceEdit) FileEdits() ([]FileEdit, error) {
+	byFile := make(map[string][]TextEdit)
+	for uri, edits := range e.Changes {
+		byFile[URIToPath(uri)] = append(byFile[URIToPath(uri)], edits...)
+	}
+	for _, raw := range e.DocumentChanges {
+		var change textDocumentEdit
+		if err := json.Unmarshal(raw, &change); err != nil {
+			return nil, fmt.Errorf("invalid document change: %w", err)
+		}
+		if change.TextDocument.URI == "" {
+			return nil, fmt.Errorf("unsupported resource operation in workspace edit: %s", raw)
+		}
+		path := URIToPath(change.TextDocument.URI)
+		byFile[path] = append(byFile[path], change.Edits...)
+	}
+
+	result := make([]FileEdit, 0, len(byFile))
+	for file, edits := range byFile {
+		result = append(result, FileEdit{File: file, Edits: edits})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].File < result[j].File })
+	return result, nil
+}
+
+// Apply writes the edits to the file on disk
+func (e FileEdit) Apply() error {
+	content, err := os.ReadFile(e.File)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", e.File, err)
+	}
+	updated, err := ApplyEdits(content, e.Edits)
+	if err != nil {
+		return fmt.Errorf("failed to edit %s: %w", e.File, err)
+	}
+	info, err := os.Stat(e.File)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.File, updated, info.Mode())
+}
+
+// ApplyEdits applies non-overlapping text edits to content
+func ApplyEdits(content []byte, edits []TextEdit) ([]byte, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, len(edits))
+	for i, edit := range edits {
+		start, err := Offset(content, edit.Range.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := Offset(content, edit.Range.End)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid edit range %+v", edit.Range)
+		}
+		spans[i] = span{start, end, edit.NewText}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return nil, fmt.Errorf("overlapping edits at offset %d", s.start)
+		}
+		b.Write(content[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+	}
+	b.Write(content[last:])
+	return []byte(b.String()), nil
+}
+
+// Offset converts an LSP position into a byte offset within content
+func Offset(content []byte, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(string(content[offset:]), '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("line %d is beyond the end of the file", pos.Line+1)
+		}
+		offset += i + 1
+	}
+
+	for units := 0; units < pos.Character; {
+		if offset >= len(content) || content[offset] == '\n' {
+			return 0, fmt.Errorf("character %d is beyond the end of line %d", pos.Character, pos.Line+1)
+		}
+		r, size := utf8.DecodeRune(content[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset, nil
+}
+
+// PathToURI converts an absolute file path into a file:// URI
+func PathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// URIToPath converts a file:// URI into a file path; other URIs are returned unchanged
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// symbolKinds names the LSP SymbolKind values
+var symbolKinds = map[int]string{
+	1: "file", 2: "module", 3: "namespace", 4: "package", 5: "class", 6: "method",
+	7: "property", 8: "field", 9: "constructor", 10: "enum", 11: "interface",
+	12: "function", 13: "variable", 14: "constant", 15: "string", 16: "number",
+	17: "boolean", 18: "array", 19: "object", 20: "key", 21: "null",
+	22: "enum member", 23: "struct", 24: "event", 25: "operator", 26: "type parameter",
+}
+
+// KindName returns the name of an LSP SymbolKind
+func KindName(kind int) string {
+	if name, ok := symbolKinds[kind]; ok {
+		return name
+	}
+	return "other"
+}
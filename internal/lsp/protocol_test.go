@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyEdits(t *testing.T) {
+	content := []byte("package p\n\n// héllo 🙂 Old\nvar Old = 1\n")
+	edits := []TextEdit{
+		// 🙂 is two UTF-16 code units, so Old starts at character 12 on line 2
+		{Range: Range{Start: Position{Line: 2, Character: 12}, End: Position{Line: 2, Character: 15}}, NewText: "New"},
+		{Range: Range{Start: Position{Line: 3, Character: 4}, End: Position{Line: 3, Character: 7}}, NewText: "New"},
+	}
+
+	updated, err := ApplyEdits(content, edits)
+	if err != nil {
+		t.Fatalf("ApplyEdits failed: %v", err)
+	}
+	if want := "package p\n\n// héllo 🙂 New\nvar New = 1\n"; string(updated) != want {
+		t.Errorf("Expected %q, got %q", want, updated)
+	}
+
+	overlapping := append(edits, TextEdit{Range: Range{Start: Position{Line: 3, Character: 5}, End: Position{Line: 3, Character: 6}}})
+	if _, err := ApplyEdits(content, overlapping); err == nil {
+		t.Error("Expected error for overlapping edits")
+	}
+	if _, err := Offset(content, Position{Line: 10}); err == nil {
+		t.Error("Expected error for position beyond end of file")
+	}
+}
+
+func TestFileEdits(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(file, []byte("package a\n\nfunc Old() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	change, _ := json.Marshal(textDocumentEdit{
+		TextDocument: TextDocumentIdentifier{URI: PathToURI(file)},
+		Edits:        []TextEdit{{Range: Range{Start: Position{Line: 2, Character: 5}, End: Position{Line: 2, Character: 8}}, NewText: "New"}},
+	})
+	edit := &WorkspaceEdit{DocumentChanges: []json.RawMessage{change}}
+	fileEdits, err := edit.FileEdits()
+	if err != nil {
+		t.Fatalf("FileEdits failed: %v", err)
+	}
+	if len(fileEdits) != 1 || fileEdits[0].File != file {
+		t.Fatalf("Unexpected file edits: %+v", fileEdits)
+	}
+	if err := fileEdits[0].Apply(); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "package a\n\nfunc New() {}\n" {
+		t.Errorf("Unexpected content after apply: %q", data)
+	}
+
+	resourceOp := &WorkspaceEdit{DocumentChanges: []json.RawMessage{json.RawMessage(`{"kind":"rename","oldUri":"file:///a","newUri":"file:///b"}`)}}
+	if _, err := resourceOp.FileEdits(); err == nil {
+		t.Error("Expected error for resource operations")
+	}
+
+	if got := URIToPath(PathToURI("/tmp/with space/x.go")); got != "/tmp/with space/x.go" {
+		t.Errorf("URI round trip failed: %s", got)
+	}
+}
@@ -0,0 +1,355 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram buckets (in seconds) used for latency metrics
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	// Default is the registry exposed on the server's /metrics endpoint
+	Default = NewRegistry()
+
+	// ToolInvocations counts MCP tool calls by tool name and outcome ("ok" or "error")
+	ToolInvocations = Default.NewCounter("scope_tool_invocations_total", "Total number of MCP tool invocations", "tool", "status")
+
+	// ToolDuration tracks how long MCP tool calls take
+	ToolDuration = Default.NewHistogram("scope_tool_duration_seconds", "Duration of MCP tool invocations in seconds", DefaultBuckets, "tool")
+
+	// AnalyzerDuration tracks how long analyzer operations take
+	AnalyzerDuration = Default.NewHistogram("scope_analyzer_duration_seconds", "Duration of analyzer operations in seconds", DefaultBuckets, "operation")
+)
+
+func init() {
+	RegisterRuntimeMetrics(Default)
+}
+
+// Registry holds a set of metrics and renders them in the Prometheus text format
+type Registry struct {
+	metrics []metric
+	mu      sync.RWMutex
+}
+
+// metric is implemented by every metric type that can be exposed by a Registry
+type metric interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText renders all registered metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.RUnlock()
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name() < metrics[j].name()
+	})
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns an http.Handler serving the registry's metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Counter is a monotonically increasing value partitioned by label values
+type Counter struct {
+	metricName string
+	help       string
+	labels     []string
+	values     map[string]float64
+	mu         sync.Mutex
+}
+
+// NewCounter creates and registers a new counter
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		values:     make(map[string]float64),
+	}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter for the given label values by v
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := joinLabelValues(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+}
+
+// Value returns the current counter value for the given label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[joinLabelValues(labelValues)]
+}
+
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeHeader(w, c.metricName, c.help, "counter"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.values) {
+		labels := formatLabels(c.labels, splitLabelValues(key), "", "")
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.metricName, labels, formatFloat(c.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram samples observations into configurable buckets
+type Histogram struct {
+	metricName string
+	help       string
+	labels     []string
+	buckets    []float64
+	values     map[string]*histogramValue
+	mu         sync.Mutex
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates and registers a new histogram
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	h := &Histogram{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		buckets:    sorted,
+		values:     make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a single observation for the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := joinLabelValues(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// ObserveDuration records the time elapsed since start in seconds
+func (h *Histogram) ObserveDuration(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations recorded for the given label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[joinLabelValues(labelValues)]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := writeHeader(w, h.metricName, h.help, "histogram"); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hv := h.values[key]
+		values := splitLabelValues(key)
+		for i, bound := range h.buckets {
+			labels := formatLabels(h.labels, values, "le", formatFloat(bound))
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labels, hv.counts[i]); err != nil {
+				return err
+			}
+		}
+		labels := formatLabels(h.labels, values, "le", "+Inf")
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labels, hv.count); err != nil {
+			return err
+		}
+		labels = formatLabels(h.labels, values, "", "")
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labels, formatFloat(hv.sum)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labels, hv.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcMetric exposes a value computed at scrape time
+type funcMetric struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is computed by fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is computed by fn on every scrape
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, kind: "counter", fn: fn})
+}
+
+func (f *funcMetric) name() string { return f.metricName }
+
+func (f *funcMetric) write(w io.Writer) error {
+	if err := writeHeader(w, f.metricName, f.help, f.kind); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", f.metricName, formatFloat(f.fn()))
+	return err
+}
+
+// RegisterRuntimeMetrics registers gauges describing the process memory usage
+func RegisterRuntimeMetrics(r *Registry) {
+	r.NewGaugeFunc("scope_memory_alloc_bytes", "Bytes of allocated heap objects", func() float64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return float64(m.Alloc)
+	})
+	r.NewGaugeFunc("scope_memory_sys_bytes", "Total bytes of memory obtained from the OS", func() float64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return float64(m.Sys)
+	})
+	r.NewGaugeFunc("scope_goroutines", "Number of goroutines that currently exist", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// labelSeparator joins label values into a single map key
+const labelSeparator = "\xff"
+
+func joinLabelValues(values []string) string {
+	return strings.Join(values, labelSeparator)
+}
+
+func splitLabelValues(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, labelSeparator)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	return err
+}
+
+// formatLabels renders a label set, optionally appending an extra label such as "le"
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, value))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
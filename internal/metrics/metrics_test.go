@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_calls_total", "Test calls", "tool", "status")
+
+	counter.Inc("lookup_type", "ok")
+	counter.Inc("lookup_type", "ok")
+	counter.Add(3, "lookup_type", "error")
+	counter.Add(-1, "lookup_type", "error") // counters never decrease
+
+	if got := counter.Value("lookup_type", "ok"); got != 2 {
+		t.Errorf("Expected 2, got %v", got)
+	}
+	if got := counter.Value("lookup_type", "error"); got != 3 {
+		t.Errorf("Expected 3, got %v", got)
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE test_calls_total counter",
+		`test_calls_total{tool="lookup_type",status="error"} 3`,
+		`test_calls_total{tool="lookup_type",status="ok"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.NewHistogram("test_duration_seconds", "Test durations", []float64{1, 0.1}, "op")
+
+	histogram.Observe(0.05, "parse")
+	histogram.Observe(0.5, "parse")
+	histogram.Observe(5, "parse")
+
+	if got := histogram.Count("parse"); got != 3 {
+		t.Errorf("Expected 3 observations, got %d", got)
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{op="parse",le="0.1"} 1`,
+		`test_duration_seconds_bucket{op="parse",le="1"} 2`,
+		`test_duration_seconds_bucket{op="parse",le="+Inf"} 3`,
+		`test_duration_seconds_sum{op="parse"} 5.55`,
+		`test_duration_seconds_count{op="parse"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeFunc("test_gauge", "A test gauge", func() float64 { return 42 })
+	RegisterRuntimeMetrics(registry)
+
+	server := httptest.NewServer(registry.Handler())
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	for _, line := range []string{"test_gauge 42", "# TYPE scope_memory_alloc_bytes gauge", "scope_goroutines "} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("Expected response to contain %q, got:\n%s", line, body)
+		}
+	}
+}
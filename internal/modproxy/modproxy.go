@@ -0,0 +1,392 @@
+// Package modproxy looks up the documentation of packages the repository
+// does not contain in a Go module proxy. The module zip holding a package
+// is downloaded once and its sources are parsed for documentation only,
+// without type checking, so no local checkout or go command is needed.
+package modproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProxy is the proxy used when GOPROXY names none
+const DefaultProxy = "https://proxy.golang.org"
+
+// maxZipSize bounds the module zips downloaded, well below the 500 MB the
+// proxy protocol allows, since lookups only need a package's sources
+const maxZipSize = 64 << 20
+
+// errNotFound is returned for modules and versions the proxy does not have
+var errNotFound = errors.New("not found")
+
+// Symbol is the documentation of a package-level declaration found in a
+// module proxy
+type Symbol struct {
+	// Name is the declared name, or Type.Method for methods
+	Name string `json:"name"`
+	// Kind is type, func, method, const or var
+	Kind       string `json:"kind"`
+	ImportPath string `json:"import_path"`
+	Module     string `json:"module"`
+	Version    string `json:"version"`
+	Doc        string `json:"doc"`
+	// Declaration is the declaration's source without function bodies
+	Declaration string `json:"declaration"`
+	// Methods are the methods of a type and Funcs the functions returning it
+	Methods []Func `json:"methods,omitempty"`
+	Funcs   []Func `json:"funcs,omitempty"`
+	// URL is the symbol's page on pkg.go.dev
+	URL string `json:"url"`
+	// External is always set: the symbol was not analyzed in the
+	// repository, and references to it are not known
+	External bool `json:"external"`
+}
+
+// Func is a function or method of a type
+type Func struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc"`
+}
+
+// Client looks symbols up in a module proxy
+type Client struct {
+	proxy string
+	http  *http.Client
+
+	mu   sync.Mutex
+	pkgs map[string]*docPackage
+}
+
+// docPackage is a package parsed from a module zip
+type docPackage struct {
+	module  string
+	version string
+	fset    *token.FileSet
+	doc     *doc.Package
+}
+
+// NewClient creates a client of the proxy at the given base URL
+func NewClient(proxy string) *Client {
+	return &Client{
+		proxy: strings.TrimRight(proxy, "/"),
+		http:  &http.Client{Timeout: time.Minute},
+		pkgs:  make(map[string]*docPackage),
+	}
+}
+
+// ProxyFromEnv returns the first proxy URL GOPROXY lists, or DefaultProxy
+// when it lists none
+func ProxyFromEnv() string {
+	for _, entry := range strings.FieldsFunc(os.Getenv("GOPROXY"), func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.HasPrefix(entry, "https://") || strings.HasPrefix(entry, "http://") {
+			return entry
+		}
+	}
+	return DefaultProxy
+}
+
+// Proxy returns the base URL of the proxy
+func (c *Client) Proxy() string {
+	return c.proxy
+}
+
+// Lookup finds the declaration name (Name or Type.Method) in the package
+// importPath. The module is the longest prefix of the import path the proxy
+// knows. Its version is taken from versions, keyed by module path, such as
+// the requirements of the repository's go.mod, or else is the latest.
+func (c *Client) Lookup(ctx context.Context, importPath, name string, versions map[string]string) (*Symbol, error) {
+	pkg, err := c.load(ctx, importPath, versions)
+	if err != nil {
+		return nil, err
+	}
+	sym := pkg.lookup(name)
+	if sym == nil {
+		return nil, fmt.Errorf("%s not found in %s@%s", name, importPath, pkg.version)
+	}
+	sym.ImportPath = importPath
+	sym.Module = pkg.module
+	sym.Version = pkg.version
+	sym.URL = fmt.Sprintf("https://pkg.go.dev/%s@%s#%s", importPath, pkg.version, sym.Name)
+	sym.External = true
+	return sym, nil
+}
+
+// load returns the parsed package importPath, downloading its module
+func (c *Client) load(ctx context.Context, importPath string, versions map[string]string) (*docPackage, error) {
+	// Module paths start with a domain; the standard library is not served
+	if first, _, _ := strings.Cut(importPath, "/"); !strings.Contains(first, ".") {
+		return nil, fmt.Errorf("%s is not in a module a proxy serves", importPath)
+	}
+	for module := importPath; module != "." && module != "/"; module = path.Dir(module) {
+		version := versions[module]
+		if version == "" {
+			latest, err := c.latest(ctx, module)
+			if errors.Is(err, errNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			version = latest
+		}
+
+		key := importPath + "@" + version
+		c.mu.Lock()
+		pkg, ok := c.pkgs[key]
+		c.mu.Unlock()
+		if ok {
+			return pkg, nil
+		}
+		data, err := c.get(ctx, module, "@v/"+escape(version)+".zip")
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pkg, err = parseZip(data, module, version, importPath)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.pkgs[key] = pkg
+		c.mu.Unlock()
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("no module providing %s found in %s", importPath, c.proxy)
+}
+
+// latest returns the latest version of a module
+func (c *Client) latest(ctx context.Context, module string) (string, error) {
+	data, err := c.get(ctx, module, "@latest")
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Version string
+	}
+	if err := json.Unmarshal(data, &info); err != nil || info.Version == "" {
+		return "", fmt.Errorf("invalid version info for %s from %s", module, c.proxy)
+	}
+	return info.Version, nil
+}
+
+// get fetches a file of a module from the proxy
+func (c *Client) get(ctx context.Context, module, file string) ([]byte, error) {
+	url := c.proxy + "/" + escape(module) + "/" + file
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, errNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxZipSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(data) > maxZipSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", url, maxZipSize>>20)
+	}
+	return data, nil
+}
+
+// parseZip parses the non-test Go files of the package importPath from the
+// zip of its module
+func parseZip(data []byte, module, version, importPath string) (*docPackage, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip of %s@%s: %w", module, version, err)
+	}
+	dir := path.Join(module+"@"+version, strings.TrimPrefix(importPath, module))
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, entry := range archive.File {
+		if path.Dir(entry.Name) != dir || !strings.HasSuffix(entry.Name, ".go") || strings.HasSuffix(entry.Name, "_test.go") {
+			continue
+		}
+		r, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+		}
+		src, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name, err)
+		}
+		file, err := parser.ParseFile(fset, strings.TrimPrefix(entry.Name, dir+"/"), src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		// Commands and ignored files are not part of the package
+		if file.Name.Name == "main" || file.Name.Name == "documentation" {
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("package %s not found in %s@%s", importPath, module, version)
+	}
+	sort.Slice(files, func(i, j int) bool { return fset.File(files[i].Pos()).Name() < fset.File(files[j].Pos()).Name() })
+	docPkg, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documentation of %s: %w", importPath, err)
+	}
+	return &docPackage{module: module, version: version, fset: fset, doc: docPkg}, nil
+}
+
+// lookup finds a declaration in the package, or returns nil
+func (p *docPackage) lookup(name string) *Symbol {
+	typeName, method, isMethod := strings.Cut(name, ".")
+	for _, t := range p.doc.Types {
+		if t.Name != typeName {
+			if sym := p.lookupValue(name, t.Consts, t.Vars, t.Funcs); sym != nil && !isMethod {
+				return sym
+			}
+			continue
+		}
+		if isMethod {
+			for _, fn := range t.Methods {
+				if fn.Name == method {
+					return &Symbol{Name: name, Kind: "method", Doc: fn.Doc, Declaration: p.print(funcDecl(fn.Decl))}
+				}
+			}
+			return nil
+		}
+		sym := &Symbol{Name: name, Kind: "type", Doc: t.Doc, Declaration: p.print(genDecl(t.Decl))}
+		for _, fn := range t.Methods {
+			sym.Methods = append(sym.Methods, Func{Name: fn.Name, Signature: p.print(funcDecl(fn.Decl)), Doc: fn.Doc})
+		}
+		for _, fn := range t.Funcs {
+			sym.Funcs = append(sym.Funcs, Func{Name: fn.Name, Signature: p.print(funcDecl(fn.Decl)), Doc: fn.Doc})
+		}
+		return sym
+	}
+	if isMethod {
+		return nil
+	}
+	return p.lookupValue(name, p.doc.Consts, p.doc.Vars, p.doc.Funcs)
+}
+
+// lookupValue finds a function, constant or variable among those given
+func (p *docPackage) lookupValue(name string, consts, vars []*doc.Value, funcs []*doc.Func) *Symbol {
+	for _, fn := range funcs {
+		if fn.Name == name {
+			return &Symbol{Name: name, Kind: "func", Doc: fn.Doc, Declaration: p.print(funcDecl(fn.Decl))}
+		}
+	}
+	for _, group := range []struct {
+		kind   string
+		values []*doc.Value
+	}{{"const", consts}, {"var", vars}} {
+		for _, value := range group.values {
+			for _, valueName := range value.Names {
+				if valueName == name {
+					return &Symbol{Name: name, Kind: group.kind, Doc: value.Doc, Declaration: p.print(genDecl(value.Decl))}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// print formats a declaration
+func (p *docPackage) print(node ast.Node) string {
+	var buf bytes.Buffer
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := config.Fprint(&buf, p.fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// funcDecl returns a function declaration without its doc comment and body
+func funcDecl(decl *ast.FuncDecl) *ast.FuncDecl {
+	stripped := *decl
+	stripped.Doc, stripped.Body = nil, nil
+	return &stripped
+}
+
+// genDecl returns a declaration without its doc comment
+func genDecl(decl *ast.GenDecl) *ast.GenDecl {
+	stripped := *decl
+	stripped.Doc = nil
+	return &stripped
+}
+
+// escape escapes a module path or version for the proxy protocol, which
+// writes each capital letter as ! and the letter in lowercase
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Requirements returns the module versions the go.mod file at path
+// requires, keyed by module path
+func Requirements(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	requirements := make(map[string]string)
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		case !inBlock:
+			continue
+		}
+		if len(fields) >= 2 {
+			requirements[strings.Trim(fields[0], `"`)] = fields[1]
+		}
+	}
+	return requirements, nil
+}
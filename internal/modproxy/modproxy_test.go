@@ -0,0 +1,155 @@
+package modproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// moduleZip builds the zip of a module version from its files
+func moduleZip(t *testing.T, prefix string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(prefix + "/" + name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLookup(t *testing.T) {
+	zipV1 := moduleZip(t, "example.com/Shop@v1.2.0", map[string]string{
+		"go.mod": "module example.com/Shop\n",
+		"cart/cart.go": `// Package cart holds items
+package cart
+
+// Cart holds items
+type Cart struct {
+	Items []string
+}
+
+// New creates an empty cart
+func New() *Cart { return &Cart{} }
+
+// Add adds an item
+func (c *Cart) Add(item string) {
+	c.Items = append(c.Items, item)
+}
+
+// MaxItems limits carts
+const MaxItems = 10
+`,
+		"cart/cart_test.go": "package cart\n\nfunc TestHidden() {}\n",
+	})
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/example.com/!shop/@latest":
+			w.Write([]byte(`{"Version":"v1.3.0"}`))
+		case "/example.com/!shop/@v/v1.2.0.zip":
+			w.Write(zipV1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+	ctx := context.Background()
+	versions := map[string]string{"example.com/Shop": "v1.2.0"}
+
+	sym, err := client.Lookup(ctx, "example.com/Shop/cart", "Cart", versions)
+	if err != nil {
+		t.Fatalf("Failed to look up Cart: %v", err)
+	}
+	if !sym.External || sym.Kind != "type" || sym.Module != "example.com/Shop" || sym.Version != "v1.2.0" {
+		t.Errorf("Unexpected symbol: %+v", sym)
+	}
+	if sym.Doc != "Cart holds items\n" || !strings.Contains(sym.Declaration, "Items []string") {
+		t.Errorf("Unexpected documentation: %q %q", sym.Doc, sym.Declaration)
+	}
+	if len(sym.Methods) != 1 || sym.Methods[0].Signature != "func (c *Cart) Add(item string)" {
+		t.Errorf("Expected the Add method without its body, got %+v", sym.Methods)
+	}
+	if len(sym.Funcs) != 1 || sym.Funcs[0].Name != "New" {
+		t.Errorf("Expected the New constructor, got %+v", sym.Funcs)
+	}
+	if sym.URL != "https://pkg.go.dev/example.com/Shop/cart@v1.2.0#Cart" {
+		t.Errorf("Unexpected URL %s", sym.URL)
+	}
+
+	for name, kind := range map[string]string{"Cart.Add": "method", "New": "func", "MaxItems": "const"} {
+		if sym, err := client.Lookup(ctx, "example.com/Shop/cart", name, versions); err != nil || sym.Kind != kind {
+			t.Errorf("Expected %s to be a %s, got %+v (%v)", name, kind, sym, err)
+		}
+	}
+	if _, err := client.Lookup(ctx, "example.com/Shop/cart", "TestHidden", versions); err == nil {
+		t.Error("Expected test files to be left out")
+	}
+	zips := 0
+	for _, path := range requests {
+		if strings.HasSuffix(path, ".zip") {
+			zips++
+		}
+	}
+	if zips != 1 {
+		t.Errorf("Expected the module zip to be downloaded once, got %d downloads", zips)
+	}
+
+	// Without a required version the latest is used, which the proxy lacks
+	if _, err := client.Lookup(ctx, "example.com/Shop/cart", "Cart", nil); err == nil {
+		t.Error("Expected error for a missing module version")
+	}
+	if _, err := client.Lookup(ctx, "net/http", "Client", nil); err == nil {
+		t.Error("Expected error for a standard library package")
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.mod")
+	gomod := `module example.com/app
+
+go 1.21
+
+require github.com/single/mod v1.0.0
+
+require (
+	github.com/block/one v0.2.0 // indirect
+	"github.com/block/two" v2.0.0+incompatible
+)
+`
+	if err := os.WriteFile(path, []byte(gomod), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	requirements, err := Requirements(path)
+	if err != nil {
+		t.Fatalf("Failed to read requirements: %v", err)
+	}
+	want := map[string]string{
+		"github.com/single/mod": "v1.0.0",
+		"github.com/block/one":  "v0.2.0",
+		"github.com/block/two":  "v2.0.0+incompatible",
+	}
+	if len(requirements) != len(want) {
+		t.Errorf("Expected %v, got %v", want, requirements)
+	}
+	for module, version := range want {
+		if requirements[module] != version {
+			t.Errorf("Expected %s %s, got %q", module, version, requirements[module])
+		}
+	}
+}
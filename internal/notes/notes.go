@@ -0,0 +1,154 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/cache"
+)
+
+// Note is a piece of knowledge attached to a package, type, function, or member
+type Note struct {
+	Symbol  string    `json:"symbol"`
+	Text    string    `json:"text"`
+	Author  string    `json:"author,omitempty"`
+	Shared  bool      `json:"shared"`
+	Created time.Time `json:"created"`
+}
+
+// Store keeps notes across sessions. Local notes live in the cache; shared
+// notes are written to a JSON file in the repository so they can be
+// committed and reach other machines.
+type Store struct {
+	cache *cache.Cache
+	key   string
+	path  string
+	mu    sync.Mutex
+}
+
+// FilePath returns the location of a repository's shared notes file
+func FilePath(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "notes.json")
+}
+
+// NewStore creates a store keeping local notes in c, under the given key
+// namespace, and shared notes in the file at path
+func NewStore(c *cache.Cache, namespace, path string) *Store {
+	return &Store{cache: c, key: namespace + "notes", path: path}
+}
+
+// Add records a note. Shared notes are written to the notes file, others
+// to the cache.
+func (s *Store) Add(ctx context.Context, note Note) error {
+	if strings.TrimSpace(note.Text) == "" {
+		return fmt.Errorf("note text is empty")
+	}
+	if note.Created.IsZero() {
+		note.Created = time.Now().UTC()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if note.Shared {
+		notes, err := s.loadFile()
+		if err != nil {
+			return err
+		}
+		notes[note.Symbol] = append(notes[note.Symbol], note)
+		return s.saveFile(notes)
+	}
+
+	notes := s.loadCache(ctx)
+	notes[note.Symbol] = append(notes[note.Symbol], note)
+	return s.cache.Set(ctx, s.key, notes, 0)
+}
+
+// Get returns the notes attached to symbol, oldest first
+func (s *Store) Get(ctx context.Context, symbol string) ([]Note, error) {
+	all, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notes := all[symbol]
+	if notes == nil {
+		notes = []Note{}
+	}
+	return notes, nil
+}
+
+// All returns every note keyed by symbol, each list oldest first
+func (s *Store) All(ctx context.Context) (map[string][]Note, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	local := s.loadCache(ctx)
+	shared, err := s.loadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	for symbol, notes := range shared {
+		local[symbol] = append(local[symbol], notes...)
+	}
+	for _, notes := range local {
+		sort.SliceStable(notes, func(i, j int) bool { return notes[i].Created.Before(notes[j].Created) })
+	}
+	return local, nil
+}
+
+// loadCache reads the local notes
+func (s *Store) loadCache(ctx context.Context) map[string][]Note {
+	notes, ok := cache.NewTyped[map[string][]Note](s.cache).Get(ctx, s.key)
+	if !ok || notes == nil {
+		notes = make(map[string][]Note)
+	}
+	return notes
+}
+
+// loadFile reads the shared notes file, which may not exist yet
+func (s *Store) loadFile() (map[string][]Note, error) {
+	notes := make(map[string][]Note)
+	if s.path == "" {
+		return notes, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return notes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes file: %w", err)
+	}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("failed to parse notes file: %w", err)
+	}
+	return notes, nil
+}
+
+// saveFile writes the shared notes file, indented so diffs stay reviewable
+func (s *Store) saveFile(notes map[string][]Note) error {
+	if s.path == "" {
+		return fmt.Errorf("no notes file configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create notes directory: %w", err)
+	}
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal notes: %w", err)
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0644)
+}
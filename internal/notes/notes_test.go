@@ -0,0 +1,66 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TFMV/scope/internal/cache"
+)
+
+func TestStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	c, err := cache.New(filepath.Join(tmpDir, "cache"))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	path := FilePath(filepath.Join(tmpDir, "repo"))
+	store := NewStore(c, "repo/", path)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Add(context.Background(), Note{Symbol: "pkg.Config", Text: "shared note", Shared: true, Created: created.Add(time.Hour)}); err != nil {
+		t.Fatalf("Failed to add shared note: %v", err)
+	}
+	if err := store.Add(context.Background(), Note{Symbol: "pkg.Config", Text: "local note", Created: created}); err != nil {
+		t.Fatalf("Failed to add local note: %v", err)
+	}
+	if err := store.Add(context.Background(), Note{Symbol: "pkg.Config", Text: "  "}); err == nil {
+		t.Error("Expected error for empty note")
+	}
+
+	notes, err := store.Get(context.Background(), "pkg.Config")
+	if err != nil {
+		t.Fatalf("Failed to get notes: %v", err)
+	}
+	if len(notes) != 2 || notes[0].Text != "local note" || notes[1].Text != "shared note" {
+		t.Errorf("Expected local then shared note, got %+v", notes)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected shared notes file: %v", err)
+	}
+
+	// Local notes survive a new session through the cache file, and shared
+	// notes are visible to a store with an empty cache (another machine)
+	reopened, err := cache.New(filepath.Join(tmpDir, "cache"))
+	if err != nil {
+		t.Fatalf("Failed to reopen cache: %v", err)
+	}
+	if notes, err := NewStore(reopened, "repo/", path).Get(context.Background(), "pkg.Config"); err != nil || len(notes) != 2 {
+		t.Errorf("Expected 2 notes after reopening, got %+v, %v", notes, err)
+	}
+
+	other, err := cache.New(filepath.Join(tmpDir, "other"))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	notes, err = NewStore(other, "repo/", path).Get(context.Background(), "pkg.Config")
+	if err != nil || len(notes) != 1 || !notes[0].Shared {
+		t.Errorf("Expected only the shared note, got %+v, %v", notes, err)
+	}
+
+	if notes, err := store.Get(context.Background(), "pkg.Missing"); err != nil || len(notes) != 0 {
+		t.Errorf("Expected no notes, got %+v, %v", notes, err)
+	}
+}
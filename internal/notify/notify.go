@@ -0,0 +1,373 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/checks"
+)
+
+// maxListed caps the number of findings included in a notification message
+const maxListed = 20
+
+// Event describes new findings discovered by a background job
+type Event struct {
+	Source   string              `json:"source"`
+	Repo     string              `json:"repo"`
+	Time     time.Time           `json:"time"`
+	Findings []checks.Diagnostic `json:"findings"`
+}
+
+// Text renders the event as a short plain-text message
+func (e Event) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "scope %s found %d new problem(s) in %s", e.Source, len(e.Findings), e.Repo)
+	for i, d := range e.Findings {
+		if i == maxListed {
+			fmt.Fprintf(&b, "\n... and %d more", len(e.Findings)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", d)
+	}
+	return b.String()
+}
+
+// Notifier delivers events to an external system
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Threshold controls when findings of a check trigger a notification: at
+// least MinNew new findings at or above Severity. Disabled checks never notify.
+type Threshold struct {
+	Severity string `json:"severity,omitempty"`
+	MinNew   int    `json:"min_new,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// NotifierConfig configures a single notifier. Type is one of slack,
+// webhook, or email; the remaining fields apply to the types that use them.
+type NotifierConfig struct {
+	Type        string            `json:"type"`
+	URL         string            `json:"url,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	SMTPAddr    string            `json:"smtp_addr,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	PasswordEnv string            `json:"password_env,omitempty"`
+	From        string            `json:"from,omitempty"`
+	To          []string          `json:"to,omitempty"`
+}
+
+// Config holds the notifiers, per-check thresholds, and an optional file
+// recording already-notified findings across runs
+type Config struct {
+	Notifiers  []NotifierConfig     `json:"notifiers"`
+	Thresholds map[string]Threshold `json:"thresholds,omitempty"`
+	StateFile  string               `json:"state_file,omitempty"`
+}
+
+// ConfigPath returns the location of the notification configuration for a repository
+func ConfigPath(repoPath string) string {
+	return filepath.Join(repoPath, ".scope", "notify.json")
+}
+
+// LoadConfig reads a notification configuration. It returns nil without an
+// error when the file does not exist, meaning notifications are disabled.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notify config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse notify config: %w", err)
+	}
+	if config.StateFile != "" && !filepath.IsAbs(config.StateFile) {
+		config.StateFile = filepath.Join(filepath.Dir(path), config.StateFile)
+	}
+	return &config, nil
+}
+
+// New creates the notifier described by config
+func New(config NotifierConfig) (Notifier, error) {
+	switch config.Type {
+	case "slack":
+		if config.URL == "" {
+			return nil, fmt.Errorf("slack notifier requires a url")
+		}
+		return &Slack{URL: config.URL}, nil
+	case "webhook":
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook notifier requires a url")
+		}
+		return &Webhook{URL: config.URL, Headers: config.Headers}, nil
+	case "email":
+		if config.SMTPAddr == "" || config.From == "" || len(config.To) == 0 {
+			return nil, fmt.Errorf("email notifier requires smtp_addr, from, and to")
+		}
+		return &Email{
+			Addr:     config.SMTPAddr,
+			Username: config.Username,
+			Password: os.Getenv(config.PasswordEnv),
+			From:     config.From,
+			To:       config.To,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q (supported: slack, webhook, email)", config.Type)
+	}
+}
+
+// Dispatcher filters findings down to the new ones that cross their check's
+// threshold and sends them to every notifier
+type Dispatcher struct {
+	notifiers  []Notifier
+	thresholds map[string]Threshold
+	stateFile  string
+	mu         sync.Mutex
+	seen       map[string]bool
+}
+
+// NewDispatcher creates a dispatcher from a configuration, loading the
+// findings notified by previous runs when a state file is configured
+func NewDispatcher(config *Config) (*Dispatcher, error) {
+	d := &Dispatcher{
+		thresholds: config.Thresholds,
+		stateFile:  config.StateFile,
+		seen:       make(map[string]bool),
+	}
+	for _, nc := range config.Notifiers {
+		notifier, err := New(nc)
+		if err != nil {
+			return nil, err
+		}
+		d.notifiers = append(d.notifiers, notifier)
+	}
+
+	if d.stateFile != "" {
+		data, err := os.ReadFile(d.stateFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read notify state: %w", err)
+		}
+		if err == nil {
+			var keys []string
+			if err := json.Unmarshal(data, &keys); err != nil {
+				return nil, fmt.Errorf("failed to parse notify state: %w", err)
+			}
+			for _, key := range keys {
+				d.seen[key] = true
+			}
+		}
+	}
+	return d, nil
+}
+
+// Prime records findings as already seen without notifying, so a job's
+// first run does not report pre-existing problems as new
+func (d *Dispatcher) Prime(current []checks.Diagnostic) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, diag := range current {
+		d.seen[diag.String()] = true
+	}
+	return d.saveState()
+}
+
+// Dispatch compares the current findings of a job with those seen on the
+// previous call and notifies about new findings that cross their check's
+// threshold. It returns the findings that were notified.
+func (d *Dispatcher) Dispatch(ctx context.Context, source, repo string, current []checks.Diagnostic) ([]checks.Diagnostic, error) {
+	d.mu.Lock()
+	var added []checks.Diagnostic
+	next := make(map[string]bool, len(current))
+	for _, diag := range current {
+		key := diag.String()
+		next[key] = true
+		if !d.seen[key] {
+			added = append(added, diag)
+		}
+	}
+	d.seen = next
+	err := d.saveState()
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	findings := d.filter(added)
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	event := Event{Source: source, Repo: repo, Time: time.Now(), Findings: findings}
+	var errs []string
+	for _, notifier := range d.notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return findings, fmt.Errorf("notification failed: %s", strings.Join(errs, "; "))
+	}
+	return findings, nil
+}
+
+// filter keeps the findings of checks whose threshold is met
+func (d *Dispatcher) filter(findings []checks.Diagnostic) []checks.Diagnostic {
+	byCheck := make(map[string][]checks.Diagnostic)
+	for _, f := range findings {
+		threshold := d.thresholds[f.Check]
+		if threshold.Disabled || !meetsSeverity(f.Severity, threshold.Severity) {
+			continue
+		}
+		byCheck[f.Check] = append(byCheck[f.Check], f)
+	}
+
+	var result []checks.Diagnostic
+	for check, found := range byCheck {
+		minNew := d.thresholds[check].MinNew
+		if minNew < 1 {
+			minNew = 1
+		}
+		if len(found) >= minNew {
+			result = append(result, found...)
+		}
+	}
+	checks.Sort(result)
+	return result
+}
+
+// saveState records the current findings so later runs only report new ones
+func (d *Dispatcher) saveState() error {
+	if d.stateFile == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(d.seen))
+	for key := range d.seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create notify state directory: %w", err)
+	}
+	if err := os.WriteFile(d.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write notify state: %w", err)
+	}
+	return nil
+}
+
+// meetsSeverity reports whether severity is at or above the minimum. An empty
+// minimum accepts everything.
+func meetsSeverity(severity, minimum string) bool {
+	return minimum == "" || minimum == checks.SeverityWarning || severity == checks.SeverityError
+}
+
+// Slack posts events to a Slack incoming webhook
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify sends the event text as a Slack message
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, s.Client, s.URL, nil, map[string]string{"text": event.Text()})
+}
+
+// Webhook posts events as JSON to an arbitrary URL
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Notify sends the event as a JSON document
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, w.Client, w.URL, w.Headers, event)
+}
+
+// Email sends events through an SMTP server, authenticating with PLAIN auth
+// when a username is set
+type Email struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Notify sends the event text as an email
+func (e *Email) Notify(ctx context.Context, event Event) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		host := e.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: scope %s: %d new problem(s)\r\n", event.Source, len(event.Findings))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(event.Text(), "\n", "\r\n"))
+
+	send := e.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(e.Addr, auth, e.From, e.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// postJSON posts body as JSON to url and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification to %s failed: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TFMV/scope/internal/checks"
+)
+
+// recorder collects the bodies posted to a test server
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+	header http.Header
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	r.header = req.Header.Clone()
+}
+
+func TestDispatch(t *testing.T) {
+	slack := &recorder{}
+	slackServer := httptest.NewServer(slack)
+	defer slackServer.Close()
+	hook := &recorder{}
+	hookServer := httptest.NewServer(hook)
+	defer hookServer.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	config := &Config{
+		Notifiers: []NotifierConfig{
+			{Type: "slack", URL: slackServer.URL},
+			{Type: "webhook", URL: hookServer.URL, Headers: map[string]string{"X-Token": "secret"}},
+		},
+		Thresholds: map[string]Threshold{
+			"vet":        {Disabled: true},
+			"api-compat": {Severity: checks.SeverityError, MinNew: 2},
+		},
+		StateFile: stateFile,
+	}
+	d, err := NewDispatcher(config)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	build := checks.Diagnostic{File: "a.go", Line: 1, Message: "undefined: x", Check: "build", Severity: checks.SeverityError}
+	vet := checks.Diagnostic{File: "b.go", Line: 2, Message: "unreachable code", Check: "vet", Severity: checks.SeverityWarning}
+	compat := checks.Diagnostic{File: "c.go", Line: 3, Message: "func F was removed", Check: "api-compat", Severity: checks.SeverityError}
+
+	notified, err := d.Dispatch(context.Background(), "ci", "/repo", []checks.Diagnostic{build, vet, compat})
+	if err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if len(notified) != 1 || notified[0] != build {
+		t.Fatalf("Expected only the build finding to be notified, got %v", notified)
+	}
+	if len(slack.bodies) != 1 || !strings.Contains(slack.bodies[0], "undefined: x") {
+		t.Errorf("Unexpected slack messages: %v", slack.bodies)
+	}
+	var event Event
+	if len(hook.bodies) != 1 || json.Unmarshal([]byte(hook.bodies[0]), &event) != nil || event.Source != "ci" || len(event.Findings) != 1 {
+		t.Errorf("Unexpected webhook payloads: %v", hook.bodies)
+	}
+	if hook.header.Get("X-Token") != "secret" {
+		t.Errorf("Expected custom header to be sent, got %v", hook.header)
+	}
+
+	// A fresh dispatcher sharing the state file only reports what is new
+	d, err = NewDispatcher(config)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	compat2 := compat
+	compat2.Message = "func G was removed"
+	notified, err = d.Dispatch(context.Background(), "ci", "/repo", []checks.Diagnostic{build, compat, compat2})
+	if err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if len(notified) != 0 {
+		t.Errorf("Expected a single new api-compat finding to stay below the threshold, got %v", notified)
+	}
+
+	compat3 := compat
+	compat3.Message = "func H was removed"
+	compat4 := compat
+	compat4.Message = "func I was removed"
+	notified, err = d.Dispatch(context.Background(), "ci", "/repo", []checks.Diagnostic{build, compat3, compat4})
+	if err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if len(notified) != 2 {
+		t.Errorf("Expected two new api-compat findings to be notified, got %v", notified)
+	}
+}
+
+func TestDispatchReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d, err := NewDispatcher(&Config{Notifiers: []NotifierConfig{{Type: "webhook", URL: server.URL}}})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	if err := d.Prime([]checks.Diagnostic{{Message: "old", Check: "build"}}); err != nil {
+		t.Fatalf("Prime failed: %v", err)
+	}
+
+	_, err = d.Dispatch(context.Background(), "watch", "/repo", []checks.Diagnostic{{Message: "old", Check: "build"}})
+	if err != nil {
+		t.Errorf("Expected primed findings not to be notified, got %v", err)
+	}
+	_, err = d.Dispatch(context.Background(), "watch", "/repo", []checks.Diagnostic{{Message: "new", Check: "build"}})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected delivery failure, got %v", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	e := &Email{
+		Addr:     "smtp.example.com:587",
+		Username: "scope",
+		Password: "pw",
+		From:     "scope@example.com",
+		To:       []string{"team@example.com"},
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+			return nil
+		},
+	}
+
+	event := Event{Source: "watch", Repo: "/repo", Findings: []checks.Diagnostic{{File: "a.go", Line: 1, Message: "boom", Check: "test"}}}
+	if err := e.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotFrom != "scope@example.com" || len(gotTo) != 1 {
+		t.Errorf("Unexpected envelope: %s %s %v", gotAddr, gotFrom, gotTo)
+	}
+	for _, want := range []string{"Subject: scope watch: 1 new problem(s)", "a.go:1: [test] boom"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("Expected %q in message:\n%s", want, gotMsg)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if config, err := LoadConfig(filepath.Join(dir, "missing.json")); config != nil || err != nil {
+		t.Errorf("Expected nil config for missing file, got %v, %v", config, err)
+	}
+
+	path := filepath.Join(dir, "notify.json")
+	data := `{"notifiers":[{"type":"slack","url":"https://example.com"}],"state_file":"state.json"}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.StateFile != filepath.Join(dir, "state.json") {
+		t.Errorf("Expected state file relative to config, got %s", config.StateFile)
+	}
+
+	if _, err := NewDispatcher(&Config{Notifiers: []NotifierConfig{{Type: "pager"}}}); err == nil {
+		t.Error("Expected error for unknown notifier type")
+	}
+}
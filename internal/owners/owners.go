@@ -0,0 +1,211 @@
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Locations are the places a CODEOWNERS file is looked up, relative to the
+// repository root, in the order GitHub searches them
+var Locations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// Rule is a single CODEOWNERS line
+type Rule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+	Line    int      `json:"line"`
+
+	re *regexp.Regexp
+}
+
+// Matches reports whether the rule applies to a slash-separated path
+// relative to the repository root
+func (r *Rule) Matches(path string) bool {
+	return r.re.MatchString(path)
+}
+
+// Ruleset is a parsed CODEOWNERS file. As in GitHub, the last rule matching
+// a path determines its owners.
+type Ruleset struct {
+	Path  string `json:"path"`
+	Rules []Rule `json:"rules"`
+}
+
+// Find returns the path of the repository's CODEOWNERS file, or "" when it
+// has none
+func Find(repo string) string {
+	for _, location := range Locations {
+		path := filepath.Join(repo, filepath.FromSlash(location))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load finds and parses the repository's CODEOWNERS file. It returns nil
+// when the repository has none.
+func Load(repo string) (*Ruleset, error) {
+	path := Find(repo)
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CODEOWNERS: %w", err)
+	}
+	defer f.Close()
+
+	rules, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rules.Path = path
+	return rules, nil
+}
+
+// Parse reads CODEOWNERS rules. Blank lines and comments are skipped; a rule
+// without owners marks matching paths as unowned.
+func Parse(r io.Reader) (*Ruleset, error) {
+	rules := &Ruleset{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		pattern := strings.ReplaceAll(fields[0], `\#`, "#")
+		re, err := compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rule := Rule{Pattern: pattern, Owners: []string{}, Line: line, re: re}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+		rules.Rules = append(rules.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CODEOWNERS: %w", err)
+	}
+	return rules, nil
+}
+
+// Match returns the rule that determines the owners of a slash-separated
+// path relative to the repository root, or nil when no rule matches
+func (rs *Ruleset) Match(path string) *Rule {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rs.Rules) - 1; i >= 0; i-- {
+		if rs.Rules[i].Matches(path) {
+			return &rs.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Owners returns the owners of a path, or nil when it is unowned
+func (rs *Ruleset) Owners(path string) []string {
+	if rule := rs.Match(path); rule != nil {
+		return rule.Owners
+	}
+	return nil
+}
+
+// OwnersOf returns the sorted union of the owners of several paths, such as
+// the files of a package
+func (rs *Ruleset) OwnersOf(paths []string) []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, path := range paths {
+		for _, owner := range rs.Owners(path) {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// compile translates a CODEOWNERS pattern, which follows gitignore syntax
+// with GitHub's restrictions, into a regular expression over relative paths.
+// Patterns without a slash match at any depth, a leading slash anchors to
+// the root, a trailing slash matches only directories, and a pattern naming
+// a directory also matches everything beneath it unless its last element is
+// a wildcard ("docs/*" matches direct children only).
+func compile(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	anchored := strings.HasPrefix(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	if strings.Contains(p, "/") {
+		anchored = true
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i += 2
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+			i++
+		case p[i] == '?':
+			b.WriteString("[^/]")
+			i++
+		case p[i] == '\\' && i+1 < len(p):
+			b.WriteString(regexp.QuoteMeta(p[i+1 : i+2]))
+			i += 2
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+			i++
+		}
+	}
+
+	last := p[strings.LastIndex(p, "/")+1:]
+	switch {
+	case dirOnly:
+		b.WriteString("/.*")
+	case !strings.Contains(last, "*"):
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
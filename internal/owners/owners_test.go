@@ -0,0 +1,119 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const codeowners = `# Default owners
+*       @org/maintainers
+
+*.md    @org/docs  # inline comment
+/internal/lsp/ @alice
+apps/   @org/apps
+docs/*  docs@example.com
+/cmd/**/main.go @bob
+/vendor/
+`
+
+func TestMatch(t *testing.T) {
+	rules, err := Parse(strings.NewReader(codeowners))
+	if err != nil {
+		t.Fatalf("Failed to parse CODEOWNERS: %v", err)
+	}
+	if len(rules.Rules) != 7 {
+		t.Fatalf("Expected 7 rules, got %d", len(rules.Rules))
+	}
+
+	tests := []struct {
+		path   string
+		owners []string
+	}{
+		{"go.mod", []string{"@org/maintainers"}},
+		{"README.md", []string{"@org/docs"}},
+		{"internal/report/README.md", []string{"@org/docs"}},
+		{"internal/lsp/client.go", []string{"@alice"}},
+		{"internal/lsp/testdata/x.md", []string{"@alice"}},
+		{"pkg/internal/lsp/client.go", []string{"@org/maintainers"}},
+		{"apps/web/main.go", []string{"@org/apps"}},
+		{"services/apps/api.go", []string{"@org/apps"}},
+		{"docs/guide.txt", []string{"docs@example.com"}},
+		{"docs/build/guide.txt", []string{"@org/maintainers"}},
+		{"cmd/main.go", []string{"@bob"}},
+		{"cmd/scope/main.go", []string{"@bob"}},
+		{"cmd/scope/watch.go", []string{"@org/maintainers"}},
+		{"vendor/github.com/x/y.go", []string{}},
+	}
+	for _, tt := range tests {
+		if got := rules.Owners(tt.path); !reflect.DeepEqual(got, tt.owners) {
+			t.Errorf("Expected owners %v for %s, got %v", tt.owners, tt.path, got)
+		}
+	}
+
+	rule := rules.Match("internal/lsp/conn.go")
+	if rule == nil || rule.Line != 5 || rule.Pattern != "/internal/lsp/" {
+		t.Errorf("Expected /internal/lsp/ rule on line 5, got %+v", rule)
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	rules, err := Parse(strings.NewReader("/internal/ @alice\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse CODEOWNERS: %v", err)
+	}
+	if rule := rules.Match("cmd/scope/main.go"); rule != nil {
+		t.Errorf("Expected no rule, got %+v", rule)
+	}
+	if owners := rules.Owners("cmd/scope/main.go"); owners != nil {
+		t.Errorf("Expected no owners, got %v", owners)
+	}
+}
+
+func TestOwnersOf(t *testing.T) {
+	rules, err := Parse(strings.NewReader("*.go @b @a\n*_test.go @c @a\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse CODEOWNERS: %v", err)
+	}
+	got := rules.OwnersOf([]string{"x.go", "x_test.go"})
+	if want := []string{"@a", "@b", "@c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	rules, err := Load(tmpDir)
+	if err != nil || rules != nil {
+		t.Fatalf("Expected no rules without CODEOWNERS, got %v, %v", rules, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "CODEOWNERS"), []byte("* @root\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CODEOWNERS: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, ".github"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".github", "CODEOWNERS"), []byte("* @github\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CODEOWNERS: %v", err)
+	}
+
+	rules, err = Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load CODEOWNERS: %v", err)
+	}
+	if rules.Path != filepath.Join(tmpDir, ".github", "CODEOWNERS") {
+		t.Errorf("Expected .github/CODEOWNERS to take precedence, got %s", rules.Path)
+	}
+	if owners := rules.Owners("main.go"); !reflect.DeepEqual(owners, []string{"@github"}) {
+		t.Errorf("Expected @github, got %v", owners)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader("# ok\n/ @root\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+// Package policy enforces which files the editing tools may write. A policy
+// allows and denies paths by glob, caps the files one edit may touch and
+// can require every write to be previewed with a dry run first. Writes
+// breaking it fail with an Error listing each violation.
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is where a repository keeps its policy
+const File = ".scope/policy.json"
+
+// Rules of violations
+const (
+	RuleAllow         = "allow"
+	RuleDeny          = "deny"
+	RuleMaxFiles      = "max_files"
+	RuleRequireDryRun = "require_dry_run"
+)
+
+// previewTTL is how long a dry run stands as the preview of a write
+const previewTTL = time.Hour
+
+// Policy is the write policy of a repository
+type Policy struct {
+	// Allow lists the paths writes are limited to; empty allows every path
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists paths never written, even when allowed
+	Deny []string `json:"deny,omitempty"`
+	// MaxFiles caps the files one call may write; zero is no limit
+	MaxFiles int `json:"max_files,omitempty"`
+	// RequireDryRun refuses writes that were not previewed by the same call
+	// with dry_run first
+	RequireDryRun bool `json:"require_dry_run,omitempty"`
+}
+
+// Write is a tool call about to write files
+type Write struct {
+	Tool string
+	// Files are relative to the repository, with forward slashes
+	Files  []string
+	DryRun bool
+	// Previewed is set for writes the client has already seen, such as
+	// the files of a sandbox being confirmed
+	Previewed bool
+	// Change identifies the change for matching a write with its dry run,
+	// such as the tool's arguments without the dry-run flag. Without one a
+	// write can only be previewed by setting Previewed.
+	Change any
+}
+
+// Violation is one way a write breaks the policy
+type Violation struct {
+	Rule string `json:"rule"`
+	File string `json:"file,omitempty"`
+	// Pattern is the glob of an allow or deny violation
+	Pattern string `json:"pattern,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is a write refused by the policy. Its message holds the violations
+// as JSON so that clients can act on each one.
+type Error struct {
+	Tool       string      `json:"tool"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *Error) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("write refused by policy: %d violations", len(e.Violations))
+	}
+	return "write refused by policy: " + string(data)
+}
+
+// Load reads the policy of a repository. It returns nil when the
+// repository has none.
+func Load(repoPath string) (*Policy, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(File)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", File, err)
+	}
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("invalid pattern %q in %s", pattern, File)
+		}
+	}
+	if p.MaxFiles < 0 {
+		return nil, fmt.Errorf("invalid max_files %d in %s", p.MaxFiles, File)
+	}
+	return &p, nil
+}
+
+// Enforcer checks writes against a policy and remembers the dry runs that
+// preview them. A nil Enforcer allows every write.
+type Enforcer struct {
+	policy Policy
+
+	mu       sync.Mutex
+	previews map[string]time.Time
+}
+
+// NewEnforcer creates an enforcer of a policy
+func NewEnforcer(p Policy) *Enforcer {
+	return &Enforcer{policy: p, previews: make(map[string]time.Time)}
+}
+
+// Policy returns the policy enforced
+func (e *Enforcer) Policy() *Policy {
+	if e == nil {
+		return nil
+	}
+	p := e.policy
+	return &p
+}
+
+// Check returns an *Error when a write breaks the policy. Dry runs are
+// checked too, so that violations show before anything is written, and a
+// dry run passing the check previews the write of the same change.
+func (e *Enforcer) Check(w Write) error {
+	if e == nil {
+		return nil
+	}
+
+	var violations []Violation
+	files := make([]string, 0, len(w.Files))
+	seen := make(map[string]bool, len(w.Files))
+	for _, file := range w.Files {
+		file = path.Clean(filepath.ToSlash(file))
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		// The policy never allows changing itself
+		if file == File {
+			violations = append(violations, Violation{Rule: RuleDeny, File: file, Pattern: File, Message: fmt.Sprintf("%s is the write policy", file)})
+			continue
+		}
+		if pattern, ok := matchAny(e.policy.Deny, file); ok {
+			violations = append(violations, Violation{Rule: RuleDeny, File: file, Pattern: pattern, Message: fmt.Sprintf("%s is denied by %q", file, pattern)})
+			continue
+		}
+		if _, ok := matchAny(e.policy.Allow, file); len(e.policy.Allow) > 0 && !ok {
+			violations = append(violations, Violation{Rule: RuleAllow, File: file, Message: fmt.Sprintf("%s is not in an allowed path", file)})
+		}
+	}
+	if e.policy.MaxFiles > 0 && len(files) > e.policy.MaxFiles {
+		violations = append(violations, Violation{Rule: RuleMaxFiles, Message: fmt.Sprintf("%d files would be written; at most %d are allowed", len(files), e.policy.MaxFiles)})
+	}
+
+	var key string
+	if w.Change != nil {
+		var err error
+		if key, err = previewKey(w.Tool, files, w.Change); err != nil {
+			return err
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	for k, at := range e.previews {
+		if now.Sub(at) > previewTTL {
+			delete(e.previews, k)
+		}
+	}
+	if e.policy.RequireDryRun && !w.DryRun && !w.Previewed {
+		if _, ok := e.previews[key]; key == "" || !ok {
+			violations = append(violations, Violation{Rule: RuleRequireDryRun, Message: "run the same call with dry_run first"})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &Error{Tool: w.Tool, Violations: violations}
+	}
+	if key != "" {
+		if w.DryRun {
+			e.previews[key] = now
+		} else {
+			// A preview stands for one write
+			delete(e.previews, key)
+		}
+	}
+	return nil
+}
+
+// previewKey identifies a change of a tool to files
+func previewKey(tool string, files []string, change any) (string, error) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change: %w", err)
+	}
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%s\x00", tool, strings.Join(files, "\x00"))
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// matchAny returns the first pattern matching a file
+func matchAny(patterns []string, file string) (string, bool) {
+	for _, pattern := range patterns {
+		if Match(pattern, file) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Match reports whether a glob matches a file or a directory holding it. A
+// pattern without a slash, such as go.sum or *.pb.go, matches a file or
+// directory of that name anywhere; one with a slash, such as
+// .github/workflows or internal/**/testdata, matches from the repository
+// root. "*", "?" and "[...]" match within a path element and "**" any
+// number of elements.
+func Match(pattern, file string) bool {
+	segments := strings.Split(file, "/")
+	pattern = strings.Trim(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+	parts := strings.Split(pattern, "/")
+	for i := 1; i <= len(segments); i++ {
+		if matchSegments(parts, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path elements against pattern elements, where "**"
+// stands for any number of elements
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
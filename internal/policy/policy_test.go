@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"go.sum", "go.sum", true},
+		{"go.sum", "tools/go.sum", true},
+		{"*.pb.go", "api/v1/user.pb.go", true},
+		{"*.pb.go", "api/v1/user.go", false},
+		{".github/workflows", ".github/workflows/ci.yml", true},
+		{".github/workflows/", ".github/workflows/ci.yml", true},
+		{".github/workflows", "docs/.github/workflows/ci.yml", false},
+		{"internal/**", "internal/edit/edit.go", true},
+		{"internal/**/testdata", "internal/edit/testdata/a.go", true},
+		{"internal/*", "cmd/scope/main.go", false},
+		{"vendor", "vendor/example.com/x/x.go", true},
+	}
+	for _, test := range tests {
+		if got := Match(test.pattern, test.file); got != test.want {
+			t.Errorf("Match(%q, %q) = %v, expected %v", test.pattern, test.file, got, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	e := NewEnforcer(Policy{
+		Allow:    []string{"internal/**", "cmd/**"},
+		Deny:     []string{"go.sum", "internal/generated"},
+		MaxFiles: 2,
+	})
+
+	if err := e.Check(Write{Tool: "code_edit", Files: []string{"internal/edit/edit.go"}}); err != nil {
+		t.Errorf("Expected an allowed write to pass, got %v", err)
+	}
+
+	err := e.Check(Write{Tool: "rename", Files: []string{"internal/generated/x.go", "README.md", "cmd/scope/main.go", "internal/a.go"}})
+	var policyErr *Error
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected a policy error, got %v", err)
+	}
+	rules := make(map[string]Violation)
+	for _, v := range policyErr.Violations {
+		rules[v.Rule] = v
+	}
+	if len(policyErr.Violations) != 3 || rules[RuleDeny].File != "internal/generated/x.go" || rules[RuleDeny].Pattern != "internal/generated" ||
+		rules[RuleAllow].File != "README.md" || rules[RuleMaxFiles].Message == "" {
+		t.Errorf("Unexpected violations %+v", policyErr.Violations)
+	}
+	if policyErr.Tool != "rename" || !strings.Contains(err.Error(), `"rule":"max_files"`) {
+		t.Errorf("Expected the violations as JSON in the message, got %s", err)
+	}
+
+	if err := e.Check(Write{Tool: "code_edit", Files: []string{File}}); err == nil {
+		t.Error("Expected the policy file to be protected")
+	}
+
+	var nilEnforcer *Enforcer
+	if err := nilEnforcer.Check(Write{Tool: "code_edit", Files: []string{"go.sum"}}); err != nil {
+		t.Errorf("Expected no policy to allow every write, got %v", err)
+	}
+}
+
+func TestRequireDryRun(t *testing.T) {
+	e := NewEnforcer(Policy{RequireDryRun: true})
+	change := map[string]string{"code": "x"}
+	write := Write{Tool: "code_edit", Files: []string{"a.go"}, Change: change}
+
+	if err := e.Check(write); err == nil || !strings.Contains(err.Error(), RuleRequireDryRun) {
+		t.Errorf("Expected a write without a dry run to fail, got %v", err)
+	}
+	preview := write
+	preview.DryRun = true
+	if err := e.Check(preview); err != nil {
+		t.Fatalf("Expected the dry run to pass, got %v", err)
+	}
+	other := write
+	other.Change = map[string]string{"code": "y"}
+	if err := e.Check(other); err == nil {
+		t.Error("Expected a different change to need its own dry run")
+	}
+	if err := e.Check(write); err != nil {
+		t.Errorf("Expected the previewed write to pass, got %v", err)
+	}
+	if err := e.Check(write); err == nil {
+		t.Error("Expected a preview to stand for one write only")
+	}
+	if err := e.Check(Write{Tool: "confirm_edit", Files: []string{"a.go"}, Previewed: true}); err != nil {
+		t.Errorf("Expected a previewed write to pass, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	p, err := Load(dir)
+	if err != nil || p != nil {
+		t.Fatalf("Expected no policy, got %+v, %v", p, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".scope"), 0755); err != nil {
+		t.Fatalf("Failed to create .scope: %v", err)
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, ".scope", "policy.json"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+	write(`{"deny": ["go.sum", ".github/workflows"], "max_files": 5, "require_dry_run": true}`)
+	if p, err = Load(dir); err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if len(p.Deny) != 2 || p.MaxFiles != 5 || !p.RequireDryRun {
+		t.Errorf("Unexpected policy %+v", p)
+	}
+
+	write(`{"allow": ["[a-"]}`)
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+}
@@ -0,0 +1,555 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Profile is the part of a pprof profile needed to attribute samples to
+// functions: the sample types, the samples and the stacks they point to
+type Profile struct {
+	SampleTypes []ValueType
+	// DefaultSampleType is the sample type pprof shows unless told otherwise
+	DefaultSampleType string
+	Samples           []Sample
+	PeriodType        ValueType
+	Period            int64
+	DurationNanos     int64
+}
+
+// ValueType names a sample value and its unit, such as cpu in nanoseconds
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+// Sample is a stack with one value per sample type
+type Sample struct {
+	// Locations are the stack's frames, innermost first
+	Locations []*Location
+	Values    []int64
+}
+
+// Location is a program counter, with several lines when calls were inlined
+type Location struct {
+	ID uint64
+	// Lines are innermost first; all but the last were inlined into it
+	Lines []Line
+}
+
+// Line is a source line in a function
+type Line struct {
+	Function *Function
+	Line     int64
+}
+
+// Function is a function named in the profile
+type Function struct {
+	ID        uint64
+	Name      string
+	Filename  string
+	StartLine int64
+}
+
+// FunctionStat is a function's share of one sample type: Flat counts the
+// samples it was running, Cum those it was on the stack for
+type FunctionStat struct {
+	Name     string
+	Filename string
+	Flat     int64
+	Cum      int64
+}
+
+// Parse decodes a pprof profile, gzipped as pprof writes it or not
+func Parse(data []byte) (*Profile, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress profile: %w", err)
+		}
+		data, err = io.ReadAll(gz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress profile: %w", err)
+		}
+	}
+	var raw rawProfile
+	if err := raw.decode(data); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+	return raw.resolve()
+}
+
+// SampleIndex is the index of the values of sampleType in each sample, or
+// of the default sample type when sampleType is empty
+func (p *Profile) SampleIndex(sampleType string) (int, error) {
+	if len(p.SampleTypes) == 0 {
+		return 0, errors.New("profile has no sample types")
+	}
+	if sampleType == "" {
+		sampleType = p.DefaultSampleType
+		if sampleType == "" {
+			// pprof shows the last sample type by default
+			return len(p.SampleTypes) - 1, nil
+		}
+	}
+	var types []string
+	for i, valueType := range p.SampleTypes {
+		if valueType.Type == sampleType {
+			return i, nil
+		}
+		types = append(types, valueType.Type)
+	}
+	return 0, fmt.Errorf("profile has no sample type %s; it has %s", sampleType, strings.Join(types, ", "))
+}
+
+// Functions sums the values at index of every sample by function and sorts
+// the functions by flat value, then cumulative value. A function recursing
+// or inlined several times in a stack counts once towards its Cum.
+func (p *Profile) Functions(index int) ([]FunctionStat, int64) {
+	stats := make(map[*Function]*FunctionStat)
+	stat := func(fn *Function) *FunctionStat {
+		s := stats[fn]
+		if s == nil {
+			s = &FunctionStat{Name: fn.Name, Filename: fn.Filename}
+			stats[fn] = s
+		}
+		return s
+	}
+
+	var total int64
+	for _, sample := range p.Samples {
+		if index >= len(sample.Values) {
+			continue
+		}
+		value := sample.Values[index]
+		total += value
+		seen := make(map[*Function]bool)
+		for i, location := range sample.Locations {
+			for j, line := range location.Lines {
+				if line.Function == nil {
+					continue
+				}
+				if i == 0 && j == 0 {
+					stat(line.Function).Flat += value
+				}
+				if !seen[line.Function] {
+					seen[line.Function] = true
+					stat(line.Function).Cum += value
+				}
+			}
+		}
+	}
+
+	result := make([]FunctionStat, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Flat != result[j].Flat {
+			return result[i].Flat > result[j].Flat
+		}
+		if result[i].Cum != result[j].Cum {
+			return result[i].Cum > result[j].Cum
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, total
+}
+
+// SplitName splits a function name as the Go runtime writes it into its
+// package's import path and the function, with method receivers, closure
+// suffixes and type arguments removed: example.com/m/pkg.(*T).Run.func1
+// becomes example.com/m/pkg and T.Run. Closure reports whether the name
+// was that of a function literal inside the function.
+func SplitName(name string) (importPath, function string, closure bool) {
+	// Type arguments may contain dots and slashes of their own
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name, false
+	}
+	importPath = name[:slash+1+dot]
+	parts := strings.Split(name[slash+1+dot+1:], ".")
+	for len(parts) > 1 && isClosure(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+		closure = true
+	}
+	if len(parts) > 0 {
+		parts[0] = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(parts[0], "("), "*"), ")")
+	}
+	return importPath, strings.Join(parts, "."), closure
+}
+
+// isClosure reports whether part of a function name is one the compiler
+// gives to function literals and the wrappers of go and defer statements
+func isClosure(part string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if rest, ok := strings.CutPrefix(part, prefix); ok && rest != "" && strings.Trim(rest, "0123456789") == "" {
+			return true
+		}
+	}
+	// Closures of closures are numbered like func1.2
+	return part != "" && strings.Trim(part, "0123456789") == ""
+}
+
+// rawProfile is a profile as encoded, with strings and references as
+// indices and IDs
+type rawProfile struct {
+	sampleTypes       []rawValueType
+	samples           []rawSample
+	locations         []rawLocation
+	functions         []rawFunction
+	strings           []string
+	periodType        rawValueType
+	period            int64
+	durationNanos     int64
+	defaultSampleType int64
+}
+
+type rawValueType struct{ typ, unit int64 }
+
+type rawSample struct {
+	locationIDs []uint64
+	values      []int64
+}
+
+type rawLocation struct {
+	id    uint64
+	lines []rawLine
+}
+
+type rawLine struct {
+	functionID uint64
+	line       int64
+}
+
+type rawFunction struct {
+	id             uint64
+	name, filename int64
+	startLine      int64
+}
+
+// resolve replaces the string indices and IDs of the raw profile
+func (raw *rawProfile) resolve() (*Profile, error) {
+	str := func(i int64) (string, error) {
+		if i == 0 && len(raw.strings) == 0 {
+			return "", nil
+		}
+		if i < 0 || i >= int64(len(raw.strings)) {
+			return "", fmt.Errorf("string index %d out of range", i)
+		}
+		return raw.strings[i], nil
+	}
+	valueType := func(v rawValueType) (ValueType, error) {
+		typ, err := str(v.typ)
+		if err != nil {
+			return ValueType{}, err
+		}
+		unit, err := str(v.unit)
+		return ValueType{Type: typ, Unit: unit}, err
+	}
+
+	p := &Profile{Period: raw.period, DurationNanos: raw.durationNanos}
+	var err error
+	if p.PeriodType, err = valueType(raw.periodType); err != nil {
+		return nil, err
+	}
+	if p.DefaultSampleType, err = str(raw.defaultSampleType); err != nil {
+		return nil, err
+	}
+	for _, v := range raw.sampleTypes {
+		sampleType, err := valueType(v)
+		if err != nil {
+			return nil, err
+		}
+		p.SampleTypes = append(p.SampleTypes, sampleType)
+	}
+
+	functions := make(map[uint64]*Function, len(raw.functions))
+	for _, f := range raw.functions {
+		fn := &Function{ID: f.id, StartLine: f.startLine}
+		if fn.Name, err = str(f.name); err != nil {
+			return nil, err
+		}
+		if fn.Filename, err = str(f.filename); err != nil {
+			return nil, err
+		}
+		functions[f.id] = fn
+	}
+	locations := make(map[uint64]*Location, len(raw.locations))
+	for _, l := range raw.locations {
+		location := &Location{ID: l.id}
+		for _, line := range l.lines {
+			fn, ok := functions[line.functionID]
+			if !ok && line.functionID != 0 {
+				return nil, fmt.Errorf("location %d refers to unknown function %d", l.id, line.functionID)
+			}
+			location.Lines = append(location.Lines, Line{Function: fn, Line: line.line})
+		}
+		locations[l.id] = location
+	}
+	for _, s := range raw.samples {
+		if len(s.values) != len(p.SampleTypes) {
+			return nil, fmt.Errorf("sample has %d values for %d sample types", len(s.values), len(p.SampleTypes))
+		}
+		sample := Sample{Values: s.values}
+		for _, id := range s.locationIDs {
+			location, ok := locations[id]
+			if !ok {
+				return nil, fmt.Errorf("sample refers to unknown location %d", id)
+			}
+			sample.Locations = append(sample.Locations, location)
+		}
+		p.Samples = append(p.Samples, sample)
+	}
+	return p, nil
+}
+
+// Field numbers of profile.proto
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+func (raw *rawProfile) decode(data []byte) error {
+	return decodeMessage(data, func(field int, wire int, value uint64, buf []byte) error {
+		switch field {
+		case profileSampleType:
+			var v rawValueType
+			if err := v.decode(buf); err != nil {
+				return err
+			}
+			raw.sampleTypes = append(raw.sampleTypes, v)
+		case profileSample:
+			var s rawSample
+			if err := s.decode(buf); err != nil {
+				return err
+			}
+			raw.samples = append(raw.samples, s)
+		case profileLocation:
+			var l rawLocation
+			if err := l.decode(buf); err != nil {
+				return err
+			}
+			raw.locations = append(raw.locations, l)
+		case profileFunction:
+			var f rawFunction
+			if err := f.decode(buf); err != nil {
+				return err
+			}
+			raw.functions = append(raw.functions, f)
+		case profileStringTable:
+			raw.strings = append(raw.strings, string(buf))
+		case profileDurationNanos:
+			raw.durationNanos = int64(value)
+		case profilePeriodType:
+			return raw.periodType.decode(buf)
+		case profilePeriod:
+			raw.period = int64(value)
+		case profileDefaultSampleType:
+			raw.defaultSampleType = int64(value)
+		}
+		return nil
+	})
+}
+
+func (v *rawValueType) decode(data []byte) error {
+	return decodeMessage(data, func(field int, wire int, value uint64, buf []byte) error {
+		switch field {
+		case valueTypeType:
+			v.typ = int64(value)
+		case valueTypeUnit:
+			v.unit = int64(value)
+		}
+		return nil
+	})
+}
+
+func (s *rawSample) decode(data []byte) error {
+	return decodeMessage(data, func(field int, wire int, value uint64, buf []byte) error {
+		switch field {
+		case sampleLocationID:
+			return decodeRepeated(wire, value, buf, func(v uint64) { s.locationIDs = append(s.locationIDs, v) })
+		case sampleValue:
+			return decodeRepeated(wire, value, buf, func(v uint64) { s.values = append(s.values, int64(v)) })
+		}
+		return nil
+	})
+}
+
+func (l *rawLocation) decode(data []byte) error {
+	return decodeMessage(data, func(field int, wire int, value uint64, buf []byte) error {
+		switch field {
+		case locationID:
+			l.id = value
+		case locationLine:
+			var line rawLine
+			err := decodeMessage(buf, func(field int, wire int, value uint64, buf []byte) error {
+				switch field {
+				case lineFunctionID:
+					line.functionID = value
+				case lineLine:
+					line.line = int64(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			l.lines = append(l.lines, line)
+		}
+		return nil
+	})
+}
+
+func (f *rawFunction) decode(data []byte) error {
+	return decodeMessage(data, func(field int, wire int, value uint64, buf []byte) error {
+		switch field {
+		case functionID:
+			f.id = value
+		case functionName:
+			f.name = int64(value)
+		case functionFilename:
+			f.filename = int64(value)
+		case functionStartLine:
+			f.startLine = int64(value)
+		}
+		return nil
+	})
+}
+
+// Protocol buffer wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// decodeMessage calls fn for every field of a protocol buffer message with
+// the field's number and wire type, and its value for numeric fields or its
+// buf for length-delimited ones
+func decodeMessage(data []byte, fn func(field int, wire int, value uint64, buf []byte) error) error {
+	for len(data) > 0 {
+		key, n := varint(data)
+		if n == 0 {
+			return errors.New("truncated field key")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var value uint64
+		var buf []byte
+		switch wire {
+		case wireVarint:
+			value, n = varint(data)
+			if n == 0 {
+				return fmt.Errorf("truncated varint in field %d", field)
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated fixed64 in field %d", field)
+			}
+			for i := 7; i >= 0; i-- {
+				value = value<<8 | uint64(data[i])
+			}
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("truncated fixed32 in field %d", field)
+			}
+			for i := 3; i >= 0; i-- {
+				value = value<<8 | uint64(data[i])
+			}
+			data = data[4:]
+		case wireBytes:
+			length, n := varint(data)
+			if n == 0 || length > uint64(len(data)-n) {
+				return fmt.Errorf("truncated buf in field %d", field)
+			}
+			buf = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+		if err := fn(field, wire, value, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeRepeated calls fn for a repeated varint field, which is either a
+// single value or, packed, a run of varints
+func decodeRepeated(wire int, value uint64, data []byte, fn func(uint64)) error {
+	if wire != wireBytes {
+		fn(value)
+		return nil
+	}
+	for len(data) > 0 {
+		v, n := varint(data)
+		if n == 0 {
+			return errors.New("truncated packed varint")
+		}
+		fn(v)
+		data = data[n:]
+	}
+	return nil
+}
+
+// varint decodes a base 128 varint, returning its length or 0 when data
+// ends before it does
+func varint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,152 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+// encoder writes protocol buffer messages for building test profiles
+type encoder struct{ buf []byte }
+
+func (e *encoder) varint(field int, v uint64) *encoder {
+	e.key(field, wireVarint)
+	e.raw(v)
+	return e
+}
+
+func (e *encoder) bytes(field int, b []byte) *encoder {
+	e.key(field, wireBytes)
+	e.raw(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+	return e
+}
+
+func (e *encoder) packed(field int, values ...uint64) *encoder {
+	var p encoder
+	for _, v := range values {
+		p.raw(v)
+	}
+	return e.bytes(field, p.buf)
+}
+
+func (e *encoder) key(field, wire int) { e.raw(uint64(field)<<3 | uint64(wire)) }
+
+func (e *encoder) raw(v uint64) {
+	for v >= 0x80 {
+		e.buf = append(e.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	e.buf = append(e.buf, byte(v))
+}
+
+func message() *encoder { return &encoder{} }
+
+func TestParse(t *testing.T) {
+	strs := []string{"", "samples", "count", "cpu", "nanoseconds",
+		"example.com/m/pkg.(*Server).Handle", "example.com/m/pkg.parse", "example.com/m/pkg.parse.func1", "/src/pkg/server.go"}
+	p := message()
+	p.bytes(profileSampleType, message().varint(valueTypeType, 1).varint(valueTypeUnit, 2).buf)
+	p.bytes(profileSampleType, message().varint(valueTypeType, 3).varint(valueTypeUnit, 4).buf)
+	for id, name := range []uint64{5, 6, 7} {
+		p.bytes(profileFunction, message().varint(functionID, uint64(id+1)).varint(functionName, name).varint(functionFilename, 8).buf)
+	}
+	// Location 1 is the closure inlined into parse, 2 is Handle
+	p.bytes(profileLocation, message().varint(locationID, 1).
+		bytes(locationLine, message().varint(lineFunctionID, 3).varint(lineLine, 12).buf).
+		bytes(locationLine, message().varint(lineFunctionID, 2).varint(lineLine, 10).buf).buf)
+	p.bytes(profileLocation, message().varint(locationID, 2).
+		bytes(locationLine, message().varint(lineFunctionID, 1).varint(lineLine, 5).buf).buf)
+	// Packed and unpacked repeated fields are both valid
+	p.bytes(profileSample, message().packed(sampleLocationID, 1, 2).packed(sampleValue, 3, 30).buf)
+	p.bytes(profileSample, message().varint(sampleLocationID, 2).varint(sampleLocationID, 2).varint(sampleValue, 1).varint(sampleValue, 10).buf)
+	for _, s := range strs {
+		p.bytes(profileStringTable, []byte(s))
+	}
+	p.varint(profilePeriod, 10)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(p.buf)
+	w.Close()
+
+	prof, err := Parse(gz.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	if len(prof.SampleTypes) != 2 || prof.SampleTypes[1] != (ValueType{Type: "cpu", Unit: "nanoseconds"}) || prof.Period != 10 {
+		t.Errorf("Unexpected profile header: %+v", prof)
+	}
+
+	index, err := prof.SampleIndex("")
+	if err != nil || index != 1 {
+		t.Errorf("Expected the last sample type by default, got %d, %v", index, err)
+	}
+	if _, err := prof.SampleIndex("alloc_space"); err == nil || !strings.Contains(err.Error(), "samples, cpu") {
+		t.Errorf("Expected an error listing the sample types, got %v", err)
+	}
+
+	stats, total := prof.Functions(index)
+	if total != 40 {
+		t.Errorf("Expected a total of 40, got %d", total)
+	}
+	var got []string
+	for _, stat := range stats {
+		got = append(got, fmt.Sprintf("%s:%d/%d", stat.Name, stat.Flat, stat.Cum))
+	}
+	want := "example.com/m/pkg.parse.func1:30/30 example.com/m/pkg.(*Server).Handle:10/40 example.com/m/pkg.parse:0/30"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Unexpected functions:\n got %s\nwant %s", strings.Join(got, " "), want)
+	}
+
+	if _, err := Parse([]byte{0x0a, 0x05, 0x08}); err == nil {
+		t.Error("Expected error for a truncated profile")
+	}
+}
+
+func TestParseRuntimeProfile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		t.Fatalf("Failed to write heap profile: %v", err)
+	}
+	prof, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse heap profile: %v", err)
+	}
+	var types []string
+	for _, sampleType := range prof.SampleTypes {
+		types = append(types, sampleType.Type)
+	}
+	if strings.Join(types, ",") != "alloc_objects,alloc_space,inuse_objects,inuse_space" {
+		t.Errorf("Unexpected sample types: %v", types)
+	}
+	if index, err := prof.SampleIndex(""); err != nil || index != 3 {
+		t.Errorf("Expected inuse_space by default, got %d, %v", index, err)
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		name, importPath, function string
+		closure                    bool
+	}{
+		{"main.main", "main", "main", false},
+		{"example.com/m/pkg.(*Server).Handle", "example.com/m/pkg", "Server.Handle", false},
+		{"example.com/m/pkg.Server.String", "example.com/m/pkg", "Server.String", false},
+		{"example.com/m/pkg.parse.func1.2", "example.com/m/pkg", "parse", true},
+		{"example.com/m/pkg.(*Server).Serve.gowrap1", "example.com/m/pkg", "Server.Serve", true},
+		{"example.com/m/pkg.Map[go.shape.string,example.com/x.T]", "example.com/m/pkg", "Map", false},
+		{"example.com/m/pkg.(*List[...]).Push", "example.com/m/pkg", "List.Push", false},
+		{"example.com/m/v2/pkg.init.0", "example.com/m/v2/pkg", "init", true},
+		{"runtime.mallocgc", "runtime", "mallocgc", false},
+	}
+	for _, test := range tests {
+		importPath, function, closure := SplitName(test.name)
+		if importPath != test.importPath || function != test.function || closure != test.closure {
+			t.Errorf("SplitName(%q) = %q, %q, %v; expected %q, %q, %v", test.name, importPath, function, closure, test.importPath, test.function, test.closure)
+		}
+	}
+}
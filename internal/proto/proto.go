@@ -0,0 +1,552 @@
+// Package proto reads the declarations of Protocol Buffers files: their
+// package and Go package options, messages, enums and gRPC services. It
+// parses as much of the language as naming the declarations takes, and
+// skips field and option details.
+package proto
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// File is a parsed .proto file
+type File struct {
+	// Path is the name the file was parsed under
+	Path    string `json:"path"`
+	Syntax  string `json:"syntax,omitempty"`
+	Package string `json:"package,omitempty"`
+	// GoPackage is the go_package option, which may name the package after
+	// a semicolon
+	GoPackage string    `json:"go_package,omitempty"`
+	Imports   []string  `json:"imports,omitempty"`
+	Messages  []Message `json:"messages,omitempty"`
+	Enums     []Enum    `json:"enums,omitempty"`
+	Services  []Service `json:"services,omitempty"`
+}
+
+// Message is a message declaration. Nested messages have their enclosing
+// messages' names as a dotted prefix.
+type Message struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// Enum is an enum declaration, named like messages
+type Enum struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+}
+
+// Service is a gRPC service declaration
+type Service struct {
+	Name    string   `json:"name"`
+	Line    int      `json:"line"`
+	Methods []Method `json:"methods"`
+}
+
+// Method is an RPC of a service
+type Method struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+	// Request and Response are the message types as written
+	Request         string `json:"request"`
+	Response        string `json:"response"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+}
+
+// GoImportPath returns the import path of the go_package option, without
+// the package name
+func (f *File) GoImportPath() string {
+	importPath, _, _ := strings.Cut(f.GoPackage, ";")
+	return importPath
+}
+
+// GoPackageName returns the Go package name of the generated code: the
+// name given in go_package, or else the last element of its import path
+func (f *File) GoPackageName() string {
+	importPath, name, ok := strings.Cut(f.GoPackage, ";")
+	if ok {
+		return name
+	}
+	if importPath == "" {
+		return ""
+	}
+	return strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(importPath))
+}
+
+// Parse parses the source of a .proto file
+func Parse(filename string, src []byte) (*File, error) {
+	p := &parser{lexer: lexer{src: src, line: 1}, file: &File{Path: filename}}
+	p.next()
+	if err := p.parseFile(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", filename, p.tok.line, err)
+	}
+	return p.file, nil
+}
+
+// GoName converts a protobuf name to the Go identifier protoc-gen-go
+// generates for it. Dots of nested names become underscores.
+func GoName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '.' && i+1 < len(name) && isLower(name[i+1]):
+			// The dot of ".x" is dropped and x upper-cased
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || name[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isLower(name[i+1]):
+			// The underscore of "_x" is dropped and x upper-cased
+		case isDigit(c):
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isLower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokSymbol
+)
+
+type token struct {
+	kind int
+	text string
+	line int
+}
+
+// lexer splits a .proto source into tokens, dropping comments
+type lexer struct {
+	src  []byte
+	pos  int
+	line int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+	start, line := l.pos, l.line
+	c := l.src[l.pos]
+	switch {
+	case isLetter(c):
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: string(l.src[start:l.pos]), line: line}, nil
+	case c == '.' && l.pos+1 < len(l.src) && isLetter(l.src[l.pos+1]):
+		// A fully qualified type name such as .pkg.Message
+		l.pos++
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: string(l.src[start:l.pos]), line: line}, nil
+	case isDigit(c) || c == '-' || c == '+' || c == '.':
+		l.pos++
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: string(l.src[start:l.pos]), line: line}, nil
+	case c == '"' || c == '\'':
+		l.pos++
+		var b strings.Builder
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\n' {
+				return token{}, fmt.Errorf("unterminated string")
+			}
+			if l.src[l.pos] == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+			}
+			b.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("unterminated string")
+		}
+		l.pos++
+		return token{kind: tokString, text: b.String(), line: line}, nil
+	}
+	l.pos++
+	return token{kind: tokSymbol, text: string(c), line: line}, nil
+}
+
+// skipSpace skips white space and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '*':
+			l.pos += 2
+			for l.pos < len(l.src) && !(l.src[l.pos] == '*' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/') {
+				if l.src[l.pos] == '\n' {
+					l.line++
+				}
+				l.pos++
+			}
+			l.pos = min(l.pos+2, len(l.src))
+		default:
+			return
+		}
+	}
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+	file  *File
+}
+
+// next advances to the next token; lexical errors end the input
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	tok, err := p.lexer.next()
+	if err != nil {
+		p.err = err
+		tok = token{kind: tokEOF, line: p.lexer.line}
+	}
+	p.tok = tok
+}
+
+// is reports whether the current token is the symbol or keyword text
+func (p *parser) is(text string) bool {
+	return (p.tok.kind == tokSymbol || p.tok.kind == tokIdent) && p.tok.text == text
+}
+
+// expect consumes the symbol or keyword text
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.unexpected(text)
+	}
+	p.next()
+	return nil
+}
+
+// ident consumes an identifier and returns it
+func (p *parser) ident() (string, error) {
+	if p.tok.kind != tokIdent {
+		return "", p.unexpected("identifier")
+	}
+	text := p.tok.text
+	p.next()
+	return text, nil
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("expected %s, found end of file", want)
+	}
+	return fmt.Errorf("expected %s, found %q", want, p.tok.text)
+}
+
+func (p *parser) parseFile() error {
+	for p.tok.kind != tokEOF {
+		var err error
+		switch {
+		case p.is("syntax"), p.is("edition"):
+			p.next()
+			if err = p.expect("="); err == nil {
+				p.file.Syntax = p.tok.text
+				err = p.skipStatement()
+			}
+		case p.is("package"):
+			p.next()
+			if p.file.Package, err = p.ident(); err == nil {
+				err = p.expect(";")
+			}
+		case p.is("import"):
+			p.next()
+			if p.is("public") || p.is("weak") {
+				p.next()
+			}
+			if p.tok.kind != tokString {
+				return p.unexpected("import path")
+			}
+			p.file.Imports = append(p.file.Imports, p.tok.text)
+			p.next()
+			err = p.expect(";")
+		case p.is("option"):
+			err = p.parseOption()
+		case p.is("message"):
+			err = p.parseMessage("")
+		case p.is("enum"):
+			err = p.parseEnum("")
+		case p.is("service"):
+			err = p.parseService()
+		case p.is("extend"):
+			p.next()
+			if _, err = p.ident(); err == nil {
+				err = p.skipBlock()
+			}
+		case p.is(";"):
+			p.next()
+		default:
+			return p.unexpected("declaration")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return p.err
+}
+
+// parseOption reads a file option, keeping go_package
+func (p *parser) parseOption() error {
+	p.next()
+	name := p.tok.text
+	if err := p.skipUntil("="); err != nil {
+		return err
+	}
+	if name == "go_package" && p.tok.kind == tokString {
+		p.file.GoPackage = p.tok.text
+	}
+	return p.skipStatement()
+}
+
+func (p *parser) parseMessage(prefix string) error {
+	line := p.tok.line
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	full := prefix + name
+	p.file.Messages = append(p.file.Messages, Message{Name: full, Line: line})
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		if p.tok.kind == tokEOF {
+			return p.unexpected("}")
+		}
+		switch {
+		case p.is("message"):
+			err = p.parseMessage(full + ".")
+		case p.is("enum"):
+			err = p.parseEnum(full + ".")
+		case p.is("extend"), p.is("oneof"):
+			p.next()
+			if _, err = p.ident(); err == nil {
+				err = p.skipBlock()
+			}
+		case p.is(";"):
+			p.next()
+		default:
+			// Fields, map fields, options, reserved and extensions ranges
+			err = p.skipStatement()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseEnum(prefix string) error {
+	line := p.tok.line
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	p.file.Enums = append(p.file.Enums, Enum{Name: prefix + name, Line: line})
+	return p.skipBlock()
+}
+
+func (p *parser) parseService() error {
+	line := p.tok.line
+	p.next()
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	service := Service{Name: name, Line: line, Methods: []Method{}}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		if p.tok.kind == tokEOF {
+			return p.unexpected("}")
+		}
+		switch {
+		case p.is("rpc"):
+			method, err := p.parseMethod()
+			if err != nil {
+				return err
+			}
+			service.Methods = append(service.Methods, method)
+		case p.is(";"):
+			p.next()
+		default:
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+	p.next()
+	p.file.Services = append(p.file.Services, service)
+	return nil
+}
+
+// parseMethod reads rpc Name (stream Request) returns (stream Response)
+// followed by ; or an options block
+func (p *parser) parseMethod() (Method, error) {
+	method := Method{Line: p.tok.line}
+	p.next()
+	var err error
+	if method.Name, err = p.ident(); err != nil {
+		return method, err
+	}
+	if method.Request, method.ClientStreaming, err = p.parseMessageType(); err != nil {
+		return method, err
+	}
+	if err := p.expect("returns"); err != nil {
+		return method, err
+	}
+	if method.Response, method.ServerStreaming, err = p.parseMessageType(); err != nil {
+		return method, err
+	}
+	if p.is("{") {
+		return method, p.skipBlock()
+	}
+	return method, p.expect(";")
+}
+
+// parseMessageType reads ( [stream] Type )
+func (p *parser) parseMessageType() (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	stream := false
+	if p.is("stream") {
+		p.next()
+		// A message named stream
+		if p.is(")") {
+			p.next()
+			return "stream", false, nil
+		}
+		stream = true
+	}
+	name, err := p.ident()
+	if err != nil {
+		return "", false, err
+	}
+	return name, stream, p.expect(")")
+}
+
+// skipUntil skips tokens up to and including the symbol text
+func (p *parser) skipUntil(text string) error {
+	for !p.is(text) {
+		if p.tok.kind == tokEOF {
+			return p.unexpected(text)
+		}
+		p.next()
+	}
+	p.next()
+	return nil
+}
+
+// skipStatement skips to the end of a statement: its semicolon, or the
+// block it ends with
+func (p *parser) skipStatement() error {
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.unexpected(";")
+		case p.is(";"):
+			p.next()
+			return nil
+		case p.is("{"):
+			return p.skipBlock()
+		case p.is("["):
+			if err := p.skipBrackets(); err != nil {
+				return err
+			}
+		default:
+			p.next()
+		}
+	}
+}
+
+// skipBrackets skips an option list in brackets, whose values may hold
+// braces
+func (p *parser) skipBrackets() error {
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.unexpected("]")
+		case p.is("["):
+			depth++
+		case p.is("]"):
+			depth--
+			if depth == 0 {
+				p.next()
+				return nil
+			}
+		}
+		p.next()
+	}
+}
+
+// skipBlock skips to the brace closing the next block
+func (p *parser) skipBlock() error {
+	if err := p.skipUntil("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; p.next() {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.unexpected("}")
+		case p.is("{"):
+			depth++
+		case p.is("}"):
+			depth--
+		}
+	}
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,125 @@
+package proto
+
+import (
+	"strings"
+	"testing"
+)
+
+const greeter = `// Greeter service
+syntax = "proto3";
+
+package shop.v1;
+
+import "google/api/annotations.proto";
+import public "shop/v1/common.proto";
+
+option go_package = "example.com/shop/gen/shopv1;shopv1";
+option (custom.file_opt) = { name: "x" nested { a: 1 } };
+
+/* Requests
+   span lines */
+message HelloRequest {
+  string name = 1 [(validate.rules).string = {min_len: 1}];
+  map<string, int32> counts = 2;
+  oneof choice {
+    string a = 3;
+    int64 b = 4;
+  }
+  message Inner_detail {
+    enum Kind { KIND_UNSPECIFIED = 0; }
+  }
+  reserved 5 to 9;
+}
+
+message HelloReply { string message = 1; }
+
+enum Mood {
+  MOOD_UNSPECIFIED = 0;
+  HAPPY = 1 [deprecated = true];
+}
+
+service Greeter {
+  option deprecated = false;
+  rpc SayHello (HelloRequest) returns (HelloReply) {
+    option (google.api.http) = { get: "/v1/hello/{name}" };
+  }
+  rpc lots_of_replies(HelloRequest) returns (stream .shop.v1.HelloReply);
+  rpc Chat(stream HelloRequest) returns (stream HelloReply) {}
+}
+`
+
+func TestParse(t *testing.T) {
+	file, err := Parse("greeter.proto", []byte(greeter))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if file.Syntax != "proto3" || file.Package != "shop.v1" || file.GoPackage != "example.com/shop/gen/shopv1;shopv1" {
+		t.Errorf("Unexpected header: %+v", file)
+	}
+	if len(file.Imports) != 2 || file.Imports[1] != "shop/v1/common.proto" {
+		t.Errorf("Unexpected imports: %v", file.Imports)
+	}
+	if file.GoImportPath() != "example.com/shop/gen/shopv1" || file.GoPackageName() != "shopv1" {
+		t.Errorf("Unexpected Go package %s %s", file.GoImportPath(), file.GoPackageName())
+	}
+
+	var messages []string
+	for _, m := range file.Messages {
+		messages = append(messages, m.Name)
+	}
+	if got := strings.Join(messages, " "); got != "HelloRequest HelloRequest.Inner_detail HelloReply" {
+		t.Errorf("Unexpected messages: %s", got)
+	}
+	if file.Messages[0].Line != 14 {
+		t.Errorf("Expected HelloRequest on line 14, got %d", file.Messages[0].Line)
+	}
+	if len(file.Enums) != 2 || file.Enums[0].Name != "HelloRequest.Inner_detail.Kind" || file.Enums[1].Name != "Mood" {
+		t.Errorf("Unexpected enums: %+v", file.Enums)
+	}
+
+	if len(file.Services) != 1 || len(file.Services[0].Methods) != 3 {
+		t.Fatalf("Expected the Greeter service with 3 methods, got %+v", file.Services)
+	}
+	methods := file.Services[0].Methods
+	if m := methods[0]; m.Name != "SayHello" || m.Request != "HelloRequest" || m.Response != "HelloReply" || m.ClientStreaming || m.ServerStreaming || m.Line != 36 {
+		t.Errorf("Unexpected SayHello: %+v", m)
+	}
+	if m := methods[1]; m.Response != ".shop.v1.HelloReply" || m.ClientStreaming || !m.ServerStreaming {
+		t.Errorf("Unexpected lots_of_replies: %+v", m)
+	}
+	if m := methods[2]; !m.ClientStreaming || !m.ServerStreaming {
+		t.Errorf("Unexpected Chat: %+v", m)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"message Broken {",
+		"service S { rpc M(A) returns B; }",
+		`syntax = "proto3`,
+		"what is this;",
+	} {
+		if _, err := Parse("bad.proto", []byte(src)); err == nil {
+			t.Errorf("Expected error for %q", src)
+		}
+	}
+	if _, err := Parse("bad.proto", []byte("message A {\n  string a = 1;\n")); err == nil || !strings.HasPrefix(err.Error(), "bad.proto:3: ") {
+		t.Errorf("Expected the error at the end of the file, got %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"HelloRequest":              "HelloRequest",
+		"lots_of_replies":           "LotsOfReplies",
+		"HelloRequest.Inner_detail": "HelloRequest_InnerDetail",
+		"Outer.inner":               "OuterInner",
+		"_private":                  "XPrivate",
+		"v2_api":                    "V2Api",
+		"HTTPRule":                  "HTTPRule",
+	} {
+		if got := GoName(name); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package remote clones git repositories named by URL so that they can be
+// analyzed like a local checkout. Clones are shallow: only the latest
+// commit of the branch or tag is fetched, and updating a clone fetches the
+// new latest commit in place.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// scpLike matches scp-style addresses such as git@github.com:org/repo.git
+var scpLike = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/]`)
+
+// Repo is a shallow clone of a remote repository
+type Repo struct {
+	URL string `json:"url"`
+	// Ref is the branch or tag cloned; empty for the default branch
+	Ref string `json:"ref,omitempty"`
+	Dir string `json:"dir"`
+
+	mu sync.Mutex
+}
+
+// IsURL reports whether s names a remote repository rather than a local
+// directory: a URL with a scheme such as https://, ssh:// or file://, or an
+// scp-style address such as git@github.com:org/repo.git
+func IsURL(s string) bool {
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git://", "file://"} {
+		if strings.HasPrefix(s, scheme) {
+			return true
+		}
+	}
+	return scpLike.MatchString(s)
+}
+
+// Parse splits a repository URL from the branch or tag following a #, as
+// in https://github.com/org/repo#v1.2.0
+func Parse(s string) (url, ref string) {
+	url, ref, _ = strings.Cut(s, "#")
+	return url, ref
+}
+
+// Dir returns the directory under root a URL and ref are cloned into. The
+// name ends in the repository name for readability and starts with a hash
+// of the URL and ref, so that different sources never share a clone.
+func Dir(root, url, ref string) string {
+	sum := sha256.Sum256([]byte(url + "#" + ref))
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	name = name[strings.LastIndexAny(name, "/:")+1:]
+	return filepath.Join(root, hex.EncodeToString(sum[:6])+"-"+name)
+}
+
+// Clone returns the clone of url at ref in dir, cloning it first when dir
+// does not hold one. A dir holding a clone of another repository is an
+// error.
+func Clone(ctx context.Context, url, ref, dir string) (*Repo, error) {
+	repo := &Repo{URL: url, Ref: ref, Dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		origin, err := git(ctx, dir, "remote", "get-url", "origin")
+		if err != nil {
+			return nil, err
+		}
+		if origin != url {
+			return nil, fmt.Errorf("%s holds a clone of %s, not %s", dir, origin, url)
+		}
+		return repo, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	// Clone next to dir and move the clone in place once complete, so that
+	// an interrupted clone is never mistaken for a finished one
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	args := []string{"clone", "--quiet", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	if _, err := git(ctx, "", append(args, "--", url, tmp)...); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to replace %s: %w", dir, err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, fmt.Errorf("failed to move clone into place: %w", err)
+	}
+	return repo, nil
+}
+
+// Head returns the commit checked out in the clone
+func (r *Repo) Head(ctx context.Context) (string, error) {
+	return git(ctx, r.Dir, "rev-parse", "HEAD")
+}
+
+// Update fetches the latest commit of the clone's ref, or of the default
+// branch, and checks it out, discarding any change made to the clone. It
+// returns the commits checked out before and after.
+func (r *Repo) Update(ctx context.Context) (before, after string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if before, err = r.Head(ctx); err != nil {
+		return "", "", err
+	}
+	ref := r.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := git(ctx, r.Dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return before, before, err
+	}
+	if _, err := git(ctx, r.Dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return before, before, err
+	}
+	if after, err = r.Head(ctx); err != nil {
+		return before, before, err
+	}
+	return before, after, nil
+}
+
+// git runs a git command in dir and returns its trimmed standard output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never wait for credentials on a terminal nobody watches
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
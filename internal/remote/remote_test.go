@@ -0,0 +1,95 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commit writes files into repo and commits them
+func commit(t *testing.T, repo string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "change"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+}
+
+func TestCloneAndUpdate(t *testing.T) {
+	upstream := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", upstream).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, output)
+	}
+	commit(t, upstream, map[string]string{"go.mod": "module example.com/lib\n", "lib.go": "package lib\n"})
+
+	ctx := context.Background()
+	url := "file://" + upstream
+	dir := Dir(t.TempDir(), url, "")
+	if !strings.HasSuffix(dir, "-"+filepath.Base(upstream)) {
+		t.Errorf("Expected clone directory named after the repository, got %s", dir)
+	}
+	repo, err := Clone(ctx, url, "", dir)
+	if err != nil {
+		t.Fatalf("Failed to clone: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib.go")); err != nil {
+		t.Fatalf("Expected lib.go in the clone: %v", err)
+	}
+
+	commit(t, upstream, map[string]string{"new.go": "package lib\n\nfunc New() {}\n"})
+	before, after, err := repo.Update(ctx)
+	if err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if before == after {
+		t.Errorf("Expected the update to move HEAD, stayed at %s", before)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.go")); err != nil {
+		t.Errorf("Expected new.go after the update: %v", err)
+	}
+	if before, after, err := repo.Update(ctx); err != nil || before != after {
+		t.Errorf("Expected an update without upstream changes to keep HEAD, got %s -> %s (%v)", before, after, err)
+	}
+
+	// Reopening finds the existing clone
+	if _, err := Clone(ctx, url, "", dir); err != nil {
+		t.Errorf("Failed to reopen clone: %v", err)
+	}
+	if _, err := Clone(ctx, "file:///elsewhere", "", dir); err == nil {
+		t.Error("Expected error for a directory holding another repository")
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/org/repo": true,
+		"git@github.com:org/repo.git": true,
+		"ssh://git@example.com/repo":  true,
+		"file:///srv/repo":            true,
+		"/home/me/repo":               false,
+		"./repo":                      false,
+		"C:\\src\\repo":               false,
+	}
+	for s, want := range tests {
+		if got := IsURL(s); got != want {
+			t.Errorf("IsURL(%q) = %t, want %t", s, got, want)
+		}
+	}
+	if url, ref := Parse("https://github.com/org/repo#v1.2.0"); url != "https://github.com/org/repo" || ref != "v1.2.0" {
+		t.Errorf("Unexpected parse: %s %s", url, ref)
+	}
+}
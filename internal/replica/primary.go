@@ -0,0 +1,213 @@
+// Package replica keeps a standby Scope server warm. A primary publishes
+// snapshots of its analysis over HTTP together with incremental updates as
+// the repository changes; a standby follows them and serves queries from the
+// replicated snapshot, so failing over does not start from an empty index.
+package replica
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// Paths served by a primary
+const (
+	SnapshotPath = "/replica/snapshot"
+	UpdatesPath  = "/replica/updates"
+)
+
+// SequenceHeader carries the sequence number of a served snapshot
+const SequenceHeader = "X-Scope-Sequence"
+
+// maxWait bounds how long an updates request waits for a change
+const maxWait = time.Minute
+
+// Update is one published change to the primary's snapshot
+type Update struct {
+	Sequence int64                   `json:"sequence"`
+	Delta    *analyzer.SnapshotDelta `json:"delta"`
+}
+
+// UpdatesResponse answers an updates request: the updates after the
+// requested sequence, oldest first, and the primary's latest sequence
+type UpdatesResponse struct {
+	Sequence int64    `json:"sequence"`
+	Updates  []Update `json:"updates"`
+}
+
+// Source is what a primary publishes; *analyzer.Analyzer satisfies it
+type Source interface {
+	Snapshot(ctx context.Context) (*analyzer.Snapshot, error)
+	LastChange() time.Time
+}
+
+// Primary publishes snapshots and updates of a Source
+type Primary struct {
+	source Source
+	// history is how many updates are kept for standbys that fall behind
+	history int
+
+	mu       sync.RWMutex
+	snapshot *analyzer.Snapshot
+	encoded  []byte // snapshot as gzip-compressed JSON
+	sequence int64
+	updates  []Update
+	changed  chan struct{} // closed and replaced when a new sequence is published
+	version  time.Time     // LastChange of the source at the latest snapshot
+}
+
+// NewPrimary creates a primary for source keeping the given number of
+// updates for standbys to catch up with; older standbys reload the snapshot
+func NewPrimary(source Source, history int) *Primary {
+	if history <= 0 {
+		history = 64
+	}
+	return &Primary{source: source, history: history, changed: make(chan struct{})}
+}
+
+// Publish takes a snapshot of the source if it changed since the last one
+// and publishes it with the delta from the previous snapshot. It reports
+// whether a new sequence was published.
+func (p *Primary) Publish(ctx context.Context) (bool, error) {
+	version := p.source.LastChange()
+	p.mu.RLock()
+	unchanged := p.snapshot != nil && !version.After(p.version)
+	p.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	snap, err := p.source.Snapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := snap.Encode(&buf); err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.snapshot != nil {
+		delta, err := analyzer.DiffSnapshots(p.snapshot, snap)
+		if err != nil {
+			return false, err
+		}
+		if delta.Empty() {
+			p.version = version
+			return false, nil
+		}
+		p.updates = append(p.updates, Update{Sequence: p.sequence + 1, Delta: delta})
+		if len(p.updates) > p.history {
+			p.updates = p.updates[len(p.updates)-p.history:]
+		}
+	}
+	p.snapshot = snap
+	p.encoded = buf.Bytes()
+	p.version = version
+	p.sequence++
+	close(p.changed)
+	p.changed = make(chan struct{})
+	return true, nil
+}
+
+// Run publishes every interval until ctx is done
+func (p *Primary) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if published, err := p.Publish(ctx); err != nil {
+			slog.Warn("Failed to publish replica snapshot", "error", err)
+		} else if published {
+			slog.Debug("Published replica snapshot", "sequence", p.Sequence())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sequence returns the latest published sequence, 0 before the first snapshot
+func (p *Primary) Sequence() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.sequence
+}
+
+// Handler serves the snapshot and updates endpoints
+func (p *Primary) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SnapshotPath, p.serveSnapshot)
+	mux.HandleFunc(UpdatesPath, p.serveUpdates)
+	return mux
+}
+
+// serveSnapshot writes the latest snapshot as gzip-compressed JSON
+func (p *Primary) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	encoded, sequence := p.encoded, p.sequence
+	p.mu.RUnlock()
+	if encoded == nil {
+		http.Error(w, "no snapshot published yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set(SequenceHeader, strconv.FormatInt(sequence, 10))
+	w.Write(encoded)
+}
+
+// serveUpdates returns the updates after ?since=, waiting up to ?wait= for
+// one when the standby is current. A standby whose sequence is older than
+// the kept history gets 410 Gone and must reload the snapshot.
+func (p *Primary) serveUpdates(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		http.Error(w, "since must be a sequence number", http.StatusBadRequest)
+		return
+	}
+	wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+	if wait > maxWait {
+		wait = maxWait
+	}
+
+	p.mu.RLock()
+	sequence, changed := p.sequence, p.changed
+	p.mu.RUnlock()
+	if since >= sequence && wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	p.mu.RLock()
+	response := UpdatesResponse{Sequence: p.sequence, Updates: []Update{}}
+	oldest := p.sequence - int64(len(p.updates))
+	for _, update := range p.updates {
+		if update.Sequence > since {
+			response.Updates = append(response.Updates, update)
+		}
+	}
+	p.mu.RUnlock()
+
+	if since < oldest || since > response.Sequence {
+		http.Error(w, fmt.Sprintf("sequence %d is not available; reload the snapshot", since), http.StatusGone)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,207 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// errReload reports that the primary no longer has the updates a replica
+// needs and the snapshot must be fetched again
+var errReload = errors.New("replica is too far behind the primary")
+
+// Replica follows a primary and keeps a standby analyzer up to date
+type Replica struct {
+	primary string
+	client  *http.Client
+	// Failover is how long the primary may be unreachable before the
+	// replica promotes its analyzer and stops following
+	Failover time.Duration
+	// Wait is how long each updates request waits for a change
+	Wait time.Duration
+
+	analyzer *analyzer.Analyzer
+	snapshot *analyzer.Snapshot // repository-relative copy the updates apply to
+	sequence int64
+}
+
+// NewReplica creates a replica of the primary served at primaryURL
+func NewReplica(primaryURL string, failover time.Duration) *Replica {
+	return &Replica{
+		primary:  strings.TrimSuffix(primaryURL, "/"),
+		client:   &http.Client{},
+		Failover: failover,
+		Wait:     30 * time.Second,
+	}
+}
+
+// Start fetches the primary's snapshot and returns a standby analyzer for
+// repoPath serving it. Run keeps the analyzer up to date afterwards.
+func (r *Replica) Start(ctx context.Context, repoPath string, config *analyzer.Config) (*analyzer.Analyzer, error) {
+	snap, sequence, err := r.fetchSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	local, err := snap.Clone()
+	if err != nil {
+		return nil, err
+	}
+	a, err := analyzer.NewStandbyAnalyzer(repoPath, config, local)
+	if err != nil {
+		return nil, err
+	}
+	r.analyzer, r.snapshot, r.sequence = a, snap, sequence
+	slog.Info("Replicating repository", "repo", repoPath, "primary", r.primary, "sequence", sequence)
+	return a, nil
+}
+
+// Sequence returns the last primary sequence applied
+func (r *Replica) Sequence() int64 {
+	return r.sequence
+}
+
+// Run applies the primary's updates until ctx is done or the primary has
+// been unreachable for longer than Failover, in which case the analyzer is
+// promoted to analyze the repository itself and Run returns.
+func (r *Replica) Run(ctx context.Context) {
+	lastContact := time.Now()
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := r.sync(ctx)
+		if err == nil {
+			lastContact = time.Now()
+			backoff = time.Second
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errReload) {
+			err = r.reload(ctx)
+			if err == nil {
+				lastContact = time.Now()
+				continue
+			}
+		}
+
+		down := time.Since(lastContact)
+		if down >= r.Failover {
+			slog.Warn("Primary unreachable; promoting standby", "primary", r.primary, "down", down.Round(time.Second), "error", err)
+			r.analyzer.Promote()
+			return
+		}
+		slog.Warn("Failed to sync with primary", "primary", r.primary, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < r.Failover/4 {
+			backoff *= 2
+		}
+	}
+}
+
+// sync waits for and applies the next updates from the primary
+func (r *Replica) sync(ctx context.Context) error {
+	query := url.Values{
+		"since": {strconv.FormatInt(r.sequence, 10)},
+		"wait":  {r.Wait.String()},
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.Wait+30*time.Second)
+	defer cancel()
+	resp, err := r.get(ctx, UpdatesPath+"?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var updates UpdatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&updates); err != nil {
+		return fmt.Errorf("failed to decode updates: %w", err)
+	}
+	if len(updates.Updates) == 0 {
+		return nil
+	}
+	for _, update := range updates.Updates {
+		if update.Sequence != r.sequence+1 {
+			return fmt.Errorf("%w: expected sequence %d, got %d", errReload, r.sequence+1, update.Sequence)
+		}
+		if err := r.snapshot.Apply(update.Delta); err != nil {
+			return fmt.Errorf("%w: %v", errReload, err)
+		}
+		r.sequence = update.Sequence
+	}
+	return r.install()
+}
+
+// reload replaces the replicated snapshot with the primary's current one
+func (r *Replica) reload(ctx context.Context) error {
+	snap, sequence, err := r.fetchSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	r.snapshot, r.sequence = snap, sequence
+	slog.Info("Reloaded snapshot", "primary", r.primary, "sequence", sequence)
+	return r.install()
+}
+
+// install hands a copy of the replicated snapshot to the analyzer
+func (r *Replica) install() error {
+	local, err := r.snapshot.Clone()
+	if err != nil {
+		return err
+	}
+	r.analyzer.UpdateSnapshot(local)
+	return nil
+}
+
+// fetchSnapshot downloads the primary's latest snapshot
+func (r *Replica) fetchSnapshot(ctx context.Context) (*analyzer.Snapshot, int64, error) {
+	resp, err := r.get(ctx, SnapshotPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	sequence, err := strconv.ParseInt(resp.Header.Get(SequenceHeader), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("primary sent no snapshot sequence")
+	}
+	snap, err := analyzer.DecodeSnapshot(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return snap, sequence, nil
+}
+
+// get requests path from the primary and fails unless it answers 200 OK
+func (r *Replica) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.primary+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusGone {
+		return nil, fmt.Errorf("%w: %s", errReload, strings.TrimSpace(string(body)))
+	}
+	return nil, fmt.Errorf("primary returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
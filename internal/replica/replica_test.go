@@ -0,0 +1,189 @@
+package replica
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+// fakeSource publishes snapshots of the given types
+type fakeSource struct {
+	mu      sync.Mutex
+	types   []analyzer.TypeInfo
+	changed time.Time
+}
+
+func (s *fakeSource) set(types ...analyzer.TypeInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.types = types
+	s.changed = time.Now()
+}
+
+func (s *fakeSource) Snapshot(ctx context.Context) (*analyzer.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := &analyzer.Snapshot{
+		Version: analyzer.SchemaVersion,
+		Created: s.changed,
+		Result:  &analyzer.AnalysisResult{Types: append([]analyzer.TypeInfo(nil), s.types...)},
+		Index:   make(map[string][]int),
+	}
+	for i, typeInfo := range snap.Result.Types {
+		snap.Index[typeInfo.Name] = append(snap.Index[typeInfo.Name], i)
+	}
+	return snap, nil
+}
+
+func (s *fakeSource) LastChange() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+func storeType(name string) analyzer.TypeInfo {
+	return analyzer.TypeInfo{
+		Name:       name,
+		Kind:       "struct",
+		Package:    "store",
+		ImportPath: "example.com/app/store",
+		Position:   analyzer.Position{Filename: "store/store.go", Line: 1},
+	}
+}
+
+func TestReplication(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": "package store\n\n// Record is a stored record\ntype Record struct{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	source := &fakeSource{}
+	source.set(storeType("Record"))
+	primary := NewPrimary(source, 2)
+	server := httptest.NewServer(primary.Handler())
+	defer server.Close()
+
+	replica := NewReplica(server.URL, time.Minute)
+	replica.Wait = 10 * time.Millisecond
+	ctx := context.Background()
+	if _, err := replica.Start(ctx, repoDir, nil); err == nil {
+		t.Error("Expected error before the primary published a snapshot")
+	}
+
+	if published, err := primary.Publish(ctx); err != nil || !published {
+		t.Fatalf("Failed to publish snapshot: %v", err)
+	}
+	if published, _ := primary.Publish(ctx); published {
+		t.Error("Expected nothing to publish without changes")
+	}
+	standby, err := replica.Start(ctx, repoDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to start replica: %v", err)
+	}
+	defer standby.Close()
+	info, err := standby.LookupType(context.Background(), "store.Record")
+	if err != nil {
+		t.Fatalf("Failed to look up replicated type: %v", err)
+	}
+	if want := filepath.Join(repoDir, "store", "store.go"); info.Position.Filename != want {
+		t.Errorf("Expected position in %s, got %s", want, info.Position.Filename)
+	}
+
+	t.Run("Updates", func(t *testing.T) {
+		source.set(storeType("Record"), storeType("Entry"))
+		if _, err := primary.Publish(ctx); err != nil {
+			t.Fatalf("Failed to publish update: %v", err)
+		}
+		if err := replica.sync(ctx); err != nil {
+			t.Fatalf("Failed to sync: %v", err)
+		}
+		if replica.Sequence() != 2 {
+			t.Errorf("Expected sequence 2, got %d", replica.Sequence())
+		}
+		if _, err := standby.LookupType(context.Background(), "store.Entry"); err != nil {
+			t.Errorf("Expected replicated update, got %v", err)
+		}
+		// An up-to-date replica gets no updates when the wait expires
+		if err := replica.sync(ctx); err != nil || replica.Sequence() != 2 {
+			t.Errorf("Expected no updates, got sequence %d (%v)", replica.Sequence(), err)
+		}
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		// Publish more updates than the primary keeps
+		for _, name := range []string{"A", "B", "C"} {
+			source.set(storeType("Record"), storeType(name))
+			if _, err := primary.Publish(ctx); err != nil {
+				t.Fatalf("Failed to publish update: %v", err)
+			}
+		}
+		resp, err := http.Get(server.URL + UpdatesPath + "?since=2")
+		if err != nil {
+			t.Fatalf("Failed to request updates: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusGone {
+			t.Errorf("Expected 410 for a sequence older than the history, got %s", resp.Status)
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			replica.Run(runCtx)
+			close(done)
+		}()
+		waitFor(t, func() bool {
+			_, err := standby.LookupType(context.Background(), "store.C")
+			return err == nil
+		})
+		cancel()
+		<-done
+		if replica.Sequence() != primary.Sequence() {
+			t.Errorf("Expected sequence %d, got %d", primary.Sequence(), replica.Sequence())
+		}
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		server.Close()
+		replica.Failover = 0
+		replica.Run(ctx)
+
+		// The promoted analyzer replaces the snapshot with its own analysis
+		waitFor(t, func() bool {
+			_, err := standby.TypeHierarchy(context.Background(), "store.Record")
+			return err == nil
+		})
+		if _, err := standby.LookupType(context.Background(), "store.Entry"); err == nil {
+			t.Error("Expected the analysis to replace the replicated snapshot")
+		}
+	})
+}
+
+// waitFor polls until done returns true
+func waitFor(t *testing.T, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for replication")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,208 @@
+package report
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/TFMV/scope/internal/i18n"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Report is the value passed to templates: the kind and reference of the
+// result being rendered, the repository root for relative paths, and the
+// result itself
+type Report struct {
+	Kind      string
+	Ref       string
+	Root      string
+	Generated time.Time
+	Data      interface{}
+}
+
+// Renderer renders analysis results with named text templates. Built-in
+// templates can be overridden by user templates of the same name.
+type Renderer struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	localizer *i18n.Localizer
+}
+
+// NewRenderer loads the built-in templates followed by every *.tmpl file in
+// dirs; a template's name is its file name without the extension. Missing
+// directories are skipped.
+func NewRenderer(dirs ...string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template)}
+
+	entries, err := builtinTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in templates: %w", err)
+	}
+	for _, entry := range entries {
+		data, err := builtinTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in template %s: %w", entry.Name(), err)
+		}
+		if err := r.Add(templateName(entry.Name()), string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if err := r.LoadDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// LoadDir adds every *.tmpl file in dir, replacing templates with the same name
+func (r *Renderer) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list templates in %s: %w", dir, err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", file, err)
+		}
+		if err := r.Add(templateName(file), string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLocalizer selects the language of the t function in templates
+func (r *Renderer) SetLocalizer(localizer *i18n.Localizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.localizer = localizer
+}
+
+// currentLocalizer returns the localizer set with SetLocalizer, or nil for English
+func (r *Renderer) currentLocalizer() *i18n.Localizer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.localizer
+}
+
+// Add parses text and registers it under name
+func (r *Renderer) Add(name, text string) error {
+	funcs := Funcs()
+	funcs["t"] = func(format string, args ...interface{}) string {
+		return r.currentLocalizer().Sprintf(format, args...)
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[name] = tmpl
+	return nil
+}
+
+// Names returns the available template names in sorted order
+func (r *Renderer) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the named template against report
+func (r *Renderer) Render(w io.Writer, name string, report Report) error {
+	r.mu.RLock()
+	tmpl, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return r.currentLocalizer().Errorf("unknown template %q (available: %s)", name, strings.Join(r.Names(), ", "))
+	}
+	if report.Generated.IsZero() {
+		report.Generated = time.Now()
+	}
+	if err := tmpl.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return nil
+}
+
+// Funcs returns the helper functions available to every template. t formats
+// a message in English; a Renderer translates it into its locale.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"t":        fmt.Sprintf,
+		"join":     strings.Join,
+		"lower":    strings.ToLower,
+		"upper":    strings.ToUpper,
+		"trim":     strings.TrimSpace,
+		"synopsis": synopsis,
+		"indent":   indent,
+		"rel":      relativePath,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+			return string(data), err
+		},
+	}
+}
+
+// templateName strips the directory and extension from a template file name
+func templateName(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// synopsis returns the first sentence of a doc comment
+func synopsis(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	return text
+}
+
+// indent prefixes every non-empty line of text with n spaces
+func indent(n int, text string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// relativePath returns path relative to root when it lies inside it
+func relativePath(root, path string) string {
+	if root == "" || path == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/checks"
+	"github.com/TFMV/scope/internal/i18n"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	r, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	for _, name := range []string{"changelog", "onboarding", "review", "type"} {
+		found := false
+		for _, n := range r.Names() {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("Expected built-in template %q, got %v", name, r.Names())
+		}
+	}
+
+	var buf bytes.Buffer
+	err = r.Render(&buf, "review", Report{
+		Ref:  "main",
+		Root: "/repo",
+		Data: []checks.Diagnostic{
+			{File: "/repo/pkg/a.go", Line: 3, Message: "undefined: x", Check: "build", Severity: checks.SeverityError},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to render review: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"1 finding(s)", "`pkg/a.go:3`", "undefined: x", "Changes since `main`"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	err = r.Render(&buf, "type", Report{Data: &analyzer.TypeInfo{
+		Name:       "Client",
+		Kind:       "struct",
+		Package:    "lib",
+		ImportPath: "example.com/lib",
+		Doc:        "Client talks to the server. It is safe for concurrent use.",
+		Methods:    []analyzer.MethodInfo{{Name: "Close", Signature: "func() error", Doc: "Close releases resources."}},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to render type: %v", err)
+	}
+	output = buf.String()
+	for _, want := range []string{"# lib.Client", "`Close` `func() error`: Close releases resources."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in:\n%s", want, output)
+		}
+	}
+}
+
+func TestUserTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "review.tmpl"), []byte("{{len .Data}} issues for {{.Ref}}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.tmpl"), []byte("{{upper .Kind}}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	r, err := NewRenderer(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.Render(&buf, "review", Report{Ref: "HEAD", Data: []checks.Diagnostic{{}, {}}}); err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if buf.String() != "2 issues for HEAD" {
+		t.Errorf("Expected user template to override built-in, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.Render(&buf, "summary", Report{Kind: "repository"}); err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if buf.String() != "REPOSITORY" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+
+	if err := r.Render(&buf, "nope", Report{}); err == nil || !strings.Contains(err.Error(), "available:") {
+		t.Errorf("Expected unknown template error listing names, got %v", err)
+	}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	r, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	localizer, err := i18n.New("de")
+	if err != nil {
+		t.Fatalf("Failed to create localizer: %v", err)
+	}
+	r.SetLocalizer(localizer)
+
+	var buf bytes.Buffer
+	if err := r.Render(&buf, "review", Report{Data: []checks.Diagnostic{{Message: "x", Check: "lint", Severity: checks.SeverityWarning}}}); err != nil {
+		t.Fatalf("Failed to render review: %v", err)
+	}
+	for _, want := range []string{"# Review-Bericht", "1 Befund(e):", "**warning** (lint): x"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, buf.String())
+		}
+	}
+
+	if err := r.Render(&buf, "nope", Report{}); err == nil || !strings.Contains(err.Error(), "unbekannte Vorlage") {
+		t.Errorf("Expected localized unknown template error, got %v", err)
+	}
+}
+
+func TestSynopsis(t *testing.T) {
+	tests := map[string]string{
+		"Foo does a thing. More detail.":   "Foo does a thing.",
+		"Foo does\na thing\n\nSecond para": "Foo does a thing",
+		"":                                 "",
+	}
+	for input, want := range tests {
+		if got := synopsis(input); got != want {
+			t.Errorf("synopsis(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
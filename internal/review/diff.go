@@ -0,0 +1,217 @@
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// devNull is the name diffs give the missing side of an added or deleted file
+const devNull = "/dev/null"
+
+// FileDiff is the part of a unified diff changing one file
+type FileDiff struct {
+	// OldName and NewName are relative to the repository, without the a/ and
+	// b/ prefixes git adds; the name of a missing side is /dev/null
+	OldName string
+	NewName string
+	Hunks   []Hunk
+}
+
+// Hunk is a changed region of a file
+type Hunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+	Lines              []Line
+}
+
+// Line is a line of a hunk: ' ' kept, '-' removed or '+' added
+type Line struct {
+	Kind byte
+	Text string
+}
+
+// ParseDiff parses the files of a unified diff, as git diff and diff -u
+// print it. Text around the diff, such as a commit message, is ignored;
+// text that is not a diff yields no files.
+func ParseDiff(diff string) ([]FileDiff, error) {
+	var files []FileDiff
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var pending string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			pending = diffName(line[4:])
+		case strings.HasPrefix(line, "+++ ") && pending != "":
+			files = append(files, FileDiff{OldName: pending, NewName: diffName(line[4:])})
+			pending = ""
+		case strings.HasPrefix(line, "@@ ") && len(files) > 0:
+			hunk, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if err := readHunk(scanner, &hunk); err != nil {
+				return nil, fmt.Errorf("%s: %w", files[len(files)-1].NewName, err)
+			}
+			files[len(files)-1].Hunks = append(files[len(files)-1].Hunks, hunk)
+		default:
+			pending = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+	return files, nil
+}
+
+// diffName returns the file name of a --- or +++ line, without a timestamp
+// and the a/ or b/ prefix
+func diffName(name string) string {
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+	if name == devNull {
+		return name
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		name = name[2:]
+	}
+	return name
+}
+
+// parseHunkHeader parses a line such as "@@ -12,7 +12,8 @@ func Name() {"
+func parseHunkHeader(line string) (Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q", line)
+	}
+	var hunk Hunk
+	var err error
+	if hunk.OldStart, hunk.OldCount, err = hunkRange(fields[1][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	if hunk.NewStart, hunk.NewCount, err = hunkRange(fields[2][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	return hunk, nil
+}
+
+// hunkRange parses "start,count" or "start", which means a count of one
+func hunkRange(text string) (start, count int, err error) {
+	startText, countText, found := strings.Cut(text, ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	if !found {
+		return start, 1, nil
+	}
+	count, err = strconv.Atoi(countText)
+	return start, count, err
+}
+
+// readHunk reads the lines of a hunk until both sides have the lengths its
+// header gives
+func readHunk(scanner *bufio.Scanner, hunk *Hunk) error {
+	oldLeft, newLeft := hunk.OldCount, hunk.NewCount
+	for oldLeft > 0 || newLeft > 0 {
+		if !scanner.Scan() {
+			return fmt.Errorf("hunk at line %d is truncated", hunk.NewStart)
+		}
+		line := scanner.Text()
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file"
+			continue
+		}
+		kind := byte(' ')
+		if line != "" {
+			// Some tools strip the space of empty context lines
+			kind, line = line[0], line[1:]
+		}
+		switch kind {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		default:
+			return fmt.Errorf("unexpected line %q in hunk at line %d", string(kind)+line, hunk.NewStart)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return fmt.Errorf("hunk at line %d is longer than its header says", hunk.NewStart)
+		}
+		hunk.Lines = append(hunk.Lines, Line{Kind: kind, Text: line})
+	}
+	return nil
+}
+
+// apply applies the hunks of a diff to lines, which hold the old side, or
+// the new side when reverse is set, and returns the other side. Each hunk
+// is looked for at the line its header gives first, then at growing
+// distances from it, as patch does.
+func apply(lines []string, hunks []Hunk, reverse bool) ([]string, error) {
+	var out []string
+	cursor, offset := 0, 0
+	for _, hunk := range hunks {
+		from, to := byte('-'), byte('+')
+		start, count := hunk.OldStart, hunk.OldCount
+		if reverse {
+			from, to = to, from
+			start, count = hunk.NewStart, hunk.NewCount
+		}
+		var want, replace []string
+		for _, line := range hunk.Lines {
+			if line.Kind != to {
+				want = append(want, line.Text)
+			}
+			if line.Kind != from {
+				replace = append(replace, line.Text)
+			}
+		}
+
+		// A side of length zero starts after the given line
+		at := start - 1
+		if count == 0 {
+			at = start
+		}
+		match := findLines(lines, want, at+offset, cursor)
+		if match < 0 {
+			return nil, fmt.Errorf("hunk at line %d does not apply", start)
+		}
+		offset = match - at
+		out = append(out, lines[cursor:match]...)
+		out = append(out, replace...)
+		cursor = match + len(want)
+	}
+	return append(out, lines[cursor:]...), nil
+}
+
+// findLines returns the index of want in lines closest to at and not
+// before notBefore, or -1
+func findLines(lines, want []string, at, notBefore int) int {
+	matches := func(i int) bool {
+		if i < notBefore || i+len(want) > len(lines) {
+			return false
+		}
+		for j, line := range want {
+			if strings.TrimRight(lines[i+j], "\r") != strings.TrimRight(line, "\r") {
+				return false
+			}
+		}
+		return true
+	}
+	for distance := 0; distance <= len(lines); distance++ {
+		if matches(at - distance) {
+			return at - distance
+		}
+		if distance > 0 && matches(at+distance) {
+			return at + distance
+		}
+	}
+	return -1
+}
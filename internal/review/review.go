@@ -0,0 +1,416 @@
+// Package review summarizes the Go declarations a unified diff changes. It
+// rebuilds both sides of each changed file from the diff and the file in
+// the repository, matches their top-level declarations, and tells changes
+// that alter the code from those that only touch formatting or comments.
+package review
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File statuses
+const (
+	FileAdded    = "added"
+	FileDeleted  = "deleted"
+	FileModified = "modified"
+)
+
+// Declaration changes
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+	// Formatting changes touch only layout or comments
+	Formatting = "formatting"
+)
+
+// Annotations of a declaration change
+const (
+	// ChangedSignature marks a function or method whose parameters, results,
+	// type parameters or receiver changed
+	ChangedSignature = "changed-signature"
+	// NewExportedAPI marks an added exported declaration outside test files
+	NewExportedAPI = "new-exported-api"
+	// RemovedExportedAPI marks a removed exported declaration outside test
+	// files
+	RemovedExportedAPI = "removed-exported-api"
+	// RemovedErrorHandling marks a declaration with fewer nil checks of
+	// errors than before
+	RemovedErrorHandling = "removed-error-handling"
+)
+
+// Summary describes the Go files a diff changes
+type Summary struct {
+	Files []FileSummary `json:"files"`
+}
+
+// FileSummary describes the declarations a diff changes in one file
+type FileSummary struct {
+	Filename     string       `json:"filename"`
+	Status       string       `json:"status"`
+	Declarations []DeclChange `json:"declarations"`
+	// Error explains why the file could not be summarized, such as a diff
+	// that applies to neither side of the file in the repository
+	Error string `json:"error,omitempty"`
+}
+
+// DeclChange is a changed top-level declaration
+type DeclChange struct {
+	// Name is the declared name, Type.Method for methods, or the names of a
+	// var or const spec separated by commas
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Change string `json:"change"`
+	// Line is on the new side, or on the old side for removals
+	Line        int      `json:"line"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// Summarize summarizes the Go files a unified diff changes. Files that
+// exist on both sides are read from repoPath, holding either side of the
+// diff: it is first taken to be the new side, as for a diff of the working
+// tree, then the old one, as for a proposed patch. It returns nil when the
+// text has no diff of Go files.
+func Summarize(repoPath, diff string) (*Summary, error) {
+	files, err := ParseDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+	var summary *Summary
+	for _, file := range files {
+		name := file.NewName
+		if name == devNull {
+			name = file.OldName
+		}
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if summary == nil {
+			summary = &Summary{}
+		}
+		summary.Files = append(summary.Files, summarizeFile(repoPath, name, file))
+	}
+	return summary, nil
+}
+
+// summarizeFile rebuilds both sides of a changed file and compares their
+// declarations
+func summarizeFile(repoPath, name string, file FileDiff) FileSummary {
+	summary := FileSummary{Filename: name, Status: FileModified, Declarations: []DeclChange{}}
+	var oldSrc, newSrc []string
+	var err error
+	switch {
+	case file.OldName == devNull:
+		summary.Status = FileAdded
+		newSrc, err = apply(nil, file.Hunks, false)
+	case file.NewName == devNull:
+		summary.Status = FileDeleted
+		oldSrc, err = apply(nil, file.Hunks, true)
+	default:
+		oldSrc, newSrc, err = rebuild(repoPath, file)
+	}
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+
+	testFile := strings.HasSuffix(name, "_test.go")
+	oldDecls, err := declarations(name, oldSrc, testFile)
+	if err != nil {
+		summary.Error = fmt.Sprintf("old side: %v", err)
+		return summary
+	}
+	newDecls, err := declarations(name, newSrc, testFile)
+	if err != nil {
+		summary.Error = fmt.Sprintf("new side: %v", err)
+		return summary
+	}
+	summary.Declarations = compare(oldDecls, newDecls)
+	return summary
+}
+
+// rebuild returns both sides of a file changed on both sides, reading the
+// one the repository holds
+func rebuild(repoPath string, file FileDiff) (oldSrc, newSrc []string, err error) {
+	for _, name := range []string{file.NewName, file.OldName} {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, nil, fmt.Errorf("%s is outside the repository", name)
+		}
+	}
+
+	if lines, err := readLines(filepath.Join(repoPath, filepath.FromSlash(file.NewName))); err == nil {
+		if oldSrc, err := apply(lines, file.Hunks, true); err == nil {
+			return oldSrc, lines, nil
+		}
+	}
+	lines, err := readLines(filepath.Join(repoPath, filepath.FromSlash(file.OldName)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", file.OldName, err)
+	}
+	newSrc, err = apply(lines, file.Hunks, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the diff applies to neither the old nor the new side of %s: %w", file.OldName, err)
+	}
+	return lines, newSrc, nil
+}
+
+// readLines reads a file as lines without their line endings
+func readLines(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// decl is a top-level declaration of one side of a file
+type decl struct {
+	name     string
+	kind     string
+	line     int
+	exported bool
+	// tokens is the declaration without layout and comments
+	tokens string
+	// source is its text, which differs from the other side's when only
+	// the layout or comments changed
+	source string
+	// signature lists the types of a function's receiver, type parameters,
+	// parameters and results, leaving out their names
+	signature string
+	// errChecks counts the comparisons of errors with nil
+	errChecks int
+}
+
+// declarations parses one side of a file and returns its declarations by
+// name. A side that does not exist has none.
+func declarations(filename string, lines []string, testFile bool) (map[string]decl, error) {
+	decls := make(map[string]decl)
+	if lines == nil {
+		return decls, nil
+	}
+	src := []byte(strings.Join(lines, "\n") + "\n")
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	text := func(node ast.Node) []byte {
+		return src[fset.Position(node.Pos()).Offset:fset.Position(node.End()).Offset]
+	}
+	add := func(key, kind string, node ast.Node, doc *ast.CommentGroup, exported bool) {
+		start := fset.Position(node.Pos()).Offset
+		if doc != nil {
+			start = fset.Position(doc.Pos()).Offset
+		}
+		d := decl{
+			name:      key,
+			kind:      kind,
+			line:      fset.Position(node.Pos()).Line,
+			exported:  exported && !testFile,
+			tokens:    tokens(text(node)),
+			source:    string(src[start:fset.Position(node.End()).Offset]),
+			errChecks: errChecks(node),
+		}
+		if fn, ok := node.(*ast.FuncDecl); ok {
+			var sig []string
+			for _, list := range []*ast.FieldList{fn.Recv, fn.Type.TypeParams, fn.Type.Params, fn.Type.Results} {
+				sig = append(sig, "(")
+				if list != nil {
+					for _, field := range list.List {
+						for range max(len(field.Names), 1) {
+							sig = append(sig, tokens(text(field.Type)))
+						}
+					}
+				}
+				sig = append(sig, ")")
+			}
+			d.signature = strings.Join(sig, " ")
+		}
+		decls[key] = d
+	}
+	for _, node := range file.Decls {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			if recv := receiverType(node); recv != "" {
+				add(recv+"."+node.Name.Name, "method", node, node.Doc, token.IsExported(recv) && node.Name.IsExported())
+			} else if node.Recv == nil && node.Name.Name != "init" && node.Name.Name != "_" {
+				add(node.Name.Name, "func", node, node.Doc, node.Name.IsExported())
+			}
+		case *ast.GenDecl:
+			for _, spec := range node.Specs {
+				// The doc comment of an unparenthesized declaration belongs
+				// to the GenDecl
+				doc := node.Doc
+				if node.Lparen.IsValid() {
+					doc = nil
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Doc != nil {
+						doc = spec.Doc
+					}
+					add(spec.Name.Name, "type", spec, doc, spec.Name.IsExported())
+				case *ast.ValueSpec:
+					var names []string
+					exported := false
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+						exported = exported || name.IsExported()
+					}
+					if spec.Doc != nil {
+						doc = spec.Doc
+					}
+					add(strings.Join(names, ", "), node.Tok.String(), spec, doc, exported)
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// compare lists the declarations added, removed and changed between two
+// sides, ordered by line
+func compare(oldDecls, newDecls map[string]decl) []DeclChange {
+	changes := []DeclChange{}
+	for name, old := range oldDecls {
+		if _, ok := newDecls[name]; ok {
+			continue
+		}
+		change := DeclChange{Name: name, Kind: old.kind, Change: Removed, Line: old.line}
+		if old.exported {
+			change.Annotations = append(change.Annotations, RemovedExportedAPI)
+		}
+		if old.errChecks > 0 {
+			change.Annotations = append(change.Annotations, RemovedErrorHandling)
+		}
+		changes = append(changes, change)
+	}
+	for name, current := range newDecls {
+		change := DeclChange{Name: name, Kind: current.kind, Line: current.line}
+		old, ok := oldDecls[name]
+		switch {
+		case !ok:
+			change.Change = Added
+			if current.exported {
+				change.Annotations = append(change.Annotations, NewExportedAPI)
+			}
+		case old.source == current.source:
+			continue
+		case old.tokens == current.tokens:
+			change.Change = Formatting
+		default:
+			change.Change = Modified
+			if old.signature != current.signature {
+				change.Annotations = append(change.Annotations, ChangedSignature)
+			}
+			if current.errChecks < old.errChecks {
+				change.Annotations = append(change.Annotations, RemovedErrorHandling)
+			}
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Line != changes[j].Line {
+			return changes[i].Line < changes[j].Line
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// tokens returns Go source as its tokens separated by spaces, leaving out
+// comments, automatically inserted semicolons and trailing commas, so two
+// texts differing only in layout and comments give the same result
+func tokens(src []byte) string {
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", -1, len(src)), src, nil, 0)
+	var out []string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		if (tok == token.RPAREN || tok == token.RBRACE || tok == token.RBRACK) && len(out) > 0 && out[len(out)-1] == "," {
+			out = out[:len(out)-1]
+		}
+		if lit == "" {
+			lit = tok.String()
+		}
+		out = append(out, lit)
+	}
+	return strings.Join(out, " ")
+}
+
+// errChecks counts the comparisons with nil of identifiers named err or
+// ending in Err or err, such as if err != nil
+func errChecks(node ast.Node) int {
+	count := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		bin, ok := n.(*ast.BinaryExpr)
+		if !ok || (bin.Op != token.NEQ && bin.Op != token.EQL) {
+			return true
+		}
+		if isNil(bin.Y) && isErrName(bin.X) || isNil(bin.X) && isErrName(bin.Y) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// isNil reports whether expr is the identifier nil
+func isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// isErrName reports whether expr names an error by convention
+func isErrName(expr ast.Expr) bool {
+	var name string
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		name = expr.Name
+	case *ast.SelectorExpr:
+		name = expr.Sel.Name
+	default:
+		return false
+	}
+	return name == "err" || strings.HasSuffix(name, "Err") || strings.HasSuffix(name, "err")
+}
+
+// receiverType returns the name of a method's receiver type
+func receiverType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
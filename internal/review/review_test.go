@@ -0,0 +1,156 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TFMV/scope/internal/edit"
+)
+
+const oldStore = `package store
+
+import "errors"
+
+// Store holds values
+type Store struct {
+	values map[string]string
+}
+
+// Get returns a value
+func (s *Store) Get(key string) (string, error) {
+	v, ok := s.values[key]
+	if !ok {
+		return "", errors.New("missing")
+	}
+	return v, nil
+}
+
+func (s *Store) Load(path string) error {
+	data, err := read(path)
+	if err != nil {
+		return err
+	}
+	s.values = data
+	return nil
+}
+
+func read(path string) (map[string]string, error) { return nil, nil }
+
+func Legacy() {}
+`
+
+const newStore = `package store
+
+import "errors"
+
+// Store holds the values
+type Store struct {
+	values map[string]string
+}
+
+// Get returns a value
+func (s *Store) Get(ctx Context, key string) (string, error) {
+	v, ok := s.values[key]
+	if !ok {
+		return "", errors.New("missing")
+	}
+	return v, nil
+}
+
+func (s *Store) Load(path string) error {
+	data, _ := read(path)
+	s.values = data
+	return nil
+}
+
+func read(path string) (
+	map[string]string,
+	error,
+) {
+	return nil, nil
+}
+
+// Context is passed to Get
+type Context struct{}
+`
+
+func TestSummarize(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		onDisk string
+	}{
+		{"working tree", newStore},
+		{"proposed patch", oldStore},
+	} {
+		repoDir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(repoDir, "store"), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoDir, "store", "store.go"), []byte(tc.onDisk), 0644); err != nil {
+			t.Fatalf("Failed to write store.go: %v", err)
+		}
+
+		diff := "Rework the store\n\n" + edit.Unified("store/store.go", []byte(oldStore), []byte(newStore)) +
+			"--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-old\n+new\n" +
+			"--- /dev/null\n+++ b/store/cache.go\n@@ -0,0 +1,3 @@\n+package store\n+\n+func NewCache() {}\n"
+		summary, err := Summarize(repoDir, diff)
+		if err != nil {
+			t.Fatalf("Failed to summarize diff (%s): %v", tc.name, err)
+		}
+		if summary == nil || len(summary.Files) != 2 {
+			t.Fatalf("Expected the two Go files (%s), got %+v", tc.name, summary)
+		}
+		store := summary.Files[0]
+		if store.Error != "" || store.Status != FileModified {
+			t.Fatalf("Expected store.go to be summarized (%s), got %+v", tc.name, store)
+		}
+		got := make(map[string]DeclChange)
+		for _, change := range store.Declarations {
+			got[change.Name] = change
+		}
+		want := map[string]DeclChange{
+			"Store":      {Name: "Store", Kind: "type", Change: Formatting, Line: 6},
+			"Store.Get":  {Name: "Store.Get", Kind: "method", Change: Modified, Line: 11, Annotations: []string{ChangedSignature}},
+			"Store.Load": {Name: "Store.Load", Kind: "method", Change: Modified, Line: 19, Annotations: []string{RemovedErrorHandling}},
+			"read":       {Name: "read", Kind: "func", Change: Formatting, Line: 25},
+			"Legacy":     {Name: "Legacy", Kind: "func", Change: Removed, Line: 30, Annotations: []string{RemovedExportedAPI}},
+			"Context":    {Name: "Context", Kind: "type", Change: Added, Line: 33, Annotations: []string{NewExportedAPI}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected declarations (%s)\n%+v\ngot\n%+v", tc.name, want, got)
+		}
+
+		cache := summary.Files[1]
+		if cache.Status != FileAdded || len(cache.Declarations) != 1 || cache.Declarations[0].Name != "NewCache" {
+			t.Errorf("Expected NewCache in an added file (%s), got %+v", tc.name, cache)
+		}
+	}
+}
+
+func TestSummarizeMismatch(t *testing.T) {
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "store.go"), []byte("package store\n\nfunc Other() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write store.go: %v", err)
+	}
+	summary, err := Summarize(repoDir, edit.Unified("store.go", []byte(oldStore), []byte(newStore)))
+	if err != nil {
+		t.Fatalf("Failed to summarize diff: %v", err)
+	}
+	if len(summary.Files) != 1 || !strings.Contains(summary.Files[0].Error, "applies to neither") {
+		t.Errorf("Expected an error for a diff that does not apply, got %+v", summary)
+	}
+
+	if summary, err := Summarize(repoDir, "just some text"); err != nil || summary != nil {
+		t.Errorf("Expected no summary for text without a diff, got %+v, %v", summary, err)
+	}
+	if _, err := Summarize(repoDir, "--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@\n-a\n"); err == nil {
+		t.Error("Expected an error for a truncated hunk")
+	}
+	summary, err = Summarize(repoDir, "--- a/../x.go\n+++ b/../x.go\n@@ -1 +1 @@\n-a\n+b\n")
+	if err != nil || !strings.Contains(summary.Files[0].Error, "outside the repository") {
+		t.Errorf("Expected paths outside the repository to be refused, got %+v, %v", summary, err)
+	}
+}
@@ -0,0 +1,301 @@
+// Package sandbox keeps copies of a repository in which edits are made and
+// checked before they reach the working tree. Only the files edited in a
+// sandbox are written back, and only when nobody changed them in the
+// working tree meanwhile.
+package sandbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sandbox is a copy of the repository with the edits made in it
+type Sandbox struct {
+	ID string `json:"id"`
+	// Dir is the root of the copy
+	Dir     string    `json:"-"`
+	Created time.Time `json:"created"`
+
+	repo string
+	mu   sync.Mutex
+	// originals holds the hash of each edited file as it was copied
+	originals map[string][sha256.Size]byte
+}
+
+// Store holds the sandboxes of a repository
+type Store struct {
+	repo string
+	dir  string
+
+	mu        sync.Mutex
+	sandboxes map[string]*Sandbox
+}
+
+// NewStore returns a store copying repo into directories under dir
+func NewStore(repo, dir string) *Store {
+	return &Store{repo: repo, dir: dir, sandboxes: make(map[string]*Sandbox)}
+}
+
+// Create copies the repository, leaving out .git, into a new sandbox
+func (s *Store) Create() (*Sandbox, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	id := newID()
+	sb := &Sandbox{
+		ID:        id,
+		Dir:       filepath.Join(s.dir, id),
+		Created:   time.Now(),
+		repo:      s.repo,
+		originals: make(map[string][sha256.Size]byte),
+	}
+	if err := copyTree(s.repo, sb.Dir); err != nil {
+		os.RemoveAll(sb.Dir)
+		return nil, fmt.Errorf("failed to copy repository into sandbox: %w", err)
+	}
+
+	s.mu.Lock()
+	s.sandboxes[id] = sb
+	s.mu.Unlock()
+	return sb, nil
+}
+
+// Get returns a sandbox by ID
+func (s *Store) Get(id string) (*Sandbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sb, ok := s.sandboxes[id]
+	if !ok {
+		return nil, fmt.Errorf("sandbox %s not found", id)
+	}
+	return sb, nil
+}
+
+// List returns the sandboxes, oldest first
+func (s *Store) List() []*Sandbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Sandbox, 0, len(s.sandboxes))
+	for _, sb := range s.sandboxes {
+		list = append(list, sb)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list
+}
+
+// Promote writes the files edited in a sandbox to the repository and
+// removes the sandbox. Nothing is written when any of them changed in the
+// repository since the sandbox was created; the sandbox is kept then.
+func (s *Store) Promote(id string) ([]string, error) {
+	sb, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	promoted, err := sb.promote()
+	if err != nil {
+		return nil, err
+	}
+	return promoted, s.Discard(id)
+}
+
+// Discard removes a sandbox without writing anything to the repository
+func (s *Store) Discard(id string) error {
+	s.mu.Lock()
+	sb, ok := s.sandboxes[id]
+	delete(s.sandboxes, id)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("sandbox %s not found", id)
+	}
+	if err := os.RemoveAll(sb.Dir); err != nil {
+		return fmt.Errorf("failed to remove sandbox %s: %w", id, err)
+	}
+	return nil
+}
+
+// Close removes every sandbox
+func (s *Store) Close() {
+	for _, sb := range s.List() {
+		s.Discard(sb.ID)
+	}
+}
+
+// Path returns the copy of a file given relative to the repository
+func (sb *Sandbox) Path(rel string) string {
+	return filepath.Join(sb.Dir, rel)
+}
+
+// Track records a file as edited in the sandbox. It must be called before
+// the file's first edit, while the copy still matches what the repository
+// held.
+func (sb *Sandbox) Track(rel string) error {
+	rel = filepath.Clean(rel)
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the repository", rel)
+	}
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if _, ok := sb.originals[rel]; ok {
+		return nil
+	}
+	data, err := os.ReadFile(sb.Path(rel))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	sb.originals[rel] = sha256.Sum256(data)
+	return nil
+}
+
+// Files returns the files edited in the sandbox, relative to the
+// repository
+func (sb *Sandbox) Files() []string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	files := make([]string, 0, len(sb.originals))
+	for rel := range sb.originals {
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// promote writes the edited files that differ from the repository to it
+func (sb *Sandbox) promote() ([]string, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	type write struct {
+		rel  string
+		data []byte
+		perm fs.FileMode
+	}
+	var writes []write
+	for rel, original := range sb.originals {
+		target := filepath.Join(sb.repo, rel)
+		current, err := os.ReadFile(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		if sha256.Sum256(current) != original {
+			return nil, fmt.Errorf("%s changed in the repository since the sandbox was created; discard the sandbox and edit again", rel)
+		}
+		data, err := os.ReadFile(sb.Path(rel))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		if bytes.Equal(data, current) {
+			continue
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+		}
+		writes = append(writes, write{rel, data, info.Mode().Perm()})
+	}
+
+	sort.Slice(writes, func(i, j int) bool { return writes[i].rel < writes[j].rel })
+	promoted := []string{}
+	for _, w := range writes {
+		if err := writeFile(filepath.Join(sb.repo, w.rel), w.data, w.perm); err != nil {
+			return promoted, err
+		}
+		promoted = append(promoted, filepath.ToSlash(w.rel))
+	}
+	return promoted, nil
+}
+
+// copyTree copies the regular files, directories and symbolic links under
+// src to dst, leaving out .git
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			if d.Name() == ".git" && path != src {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		// Sockets, pipes and devices are left out
+		return nil
+	})
+}
+
+// copyFile copies a regular file
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeFile replaces a file atomically
+func writeFile(filename string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// newID returns a random sandbox ID
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return repo
+}
+
+func TestPromote(t *testing.T) {
+	repo := writeRepo(t, map[string]string{
+		"go.mod":        "module example.com/m\n",
+		"a/a.go":        "package a\n",
+		"b/b.go":        "package b\n",
+		".git/HEAD":     "ref: refs/heads/main\n",
+		"a/testdata/x":  "x",
+		"untouched.txt": "same",
+	})
+	store := NewStore(repo, t.TempDir())
+	defer store.Close()
+
+	sb, err := store.Create()
+	if err != nil {
+		t.Fatalf("Failed to create sandbox: %v", err)
+	}
+	if _, err := os.Stat(sb.Path(".git")); !os.IsNotExist(err) {
+		t.Errorf("Expected .git to be left out, got %v", err)
+	}
+	if data, err := os.ReadFile(sb.Path("a/testdata/x")); err != nil || string(data) != "x" {
+		t.Errorf("Expected the tree to be copied, got %q, %v", data, err)
+	}
+	if err := sb.Track("../outside.go"); err == nil {
+		t.Error("Expected error for a file outside the repository")
+	}
+
+	for _, rel := range []string{"a/a.go", "b/b.go"} {
+		if err := sb.Track(rel); err != nil {
+			t.Fatalf("Failed to track %s: %v", rel, err)
+		}
+	}
+	os.WriteFile(sb.Path("a/a.go"), []byte("package a\n\nvar X = 1\n"), 0644)
+	if files := sb.Files(); !slices.Equal(files, []string{"a/a.go", "b/b.go"}) {
+		t.Errorf("Unexpected files: %v", files)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repo, "a/a.go")); string(data) != "package a\n" {
+		t.Errorf("Expected the repository to be unchanged before promotion, got %q", data)
+	}
+
+	promoted, err := store.Promote(sb.ID)
+	if err != nil {
+		t.Fatalf("Failed to promote sandbox: %v", err)
+	}
+	if !slices.Equal(promoted, []string{"a/a.go"}) {
+		t.Errorf("Expected only the changed file to be promoted, got %v", promoted)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repo, "a/a.go")); string(data) != "package a\n\nvar X = 1\n" {
+		t.Errorf("Unexpected promoted file: %q", data)
+	}
+	if _, err := store.Get(sb.ID); err == nil {
+		t.Error("Expected the promoted sandbox to be removed")
+	}
+	if _, err := os.Stat(sb.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the sandbox directory to be removed, got %v", err)
+	}
+}
+
+func TestPromoteConflict(t *testing.T) {
+	repo := writeRepo(t, map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+	store := NewStore(repo, t.TempDir())
+	defer store.Close()
+
+	sb, err := store.Create()
+	if err != nil {
+		t.Fatalf("Failed to create sandbox: %v", err)
+	}
+	sb.Track("a.go")
+	sb.Track("b.go")
+	os.WriteFile(sb.Path("a.go"), []byte("package a\n\n// sandbox\n"), 0644)
+	os.WriteFile(sb.Path("b.go"), []byte("package a\n\n// sandbox\n"), 0644)
+	// Someone edits the working tree meanwhile
+	os.WriteFile(filepath.Join(repo, "b.go"), []byte("package a\n\n// user\n"), 0644)
+
+	if _, err := store.Promote(sb.ID); err == nil || !strings.Contains(err.Error(), "b.go changed") {
+		t.Errorf("Expected a conflict on b.go, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repo, "a.go")); string(data) != "package a\n" {
+		t.Errorf("Expected nothing to be written on a conflict, got %q", data)
+	}
+	if _, err := store.Get(sb.ID); err != nil {
+		t.Errorf("Expected the sandbox to be kept after a conflict: %v", err)
+	}
+
+	if err := store.Discard(sb.ID); err != nil {
+		t.Fatalf("Failed to discard sandbox: %v", err)
+	}
+	if err := store.Discard(sb.ID); err == nil {
+		t.Error("Expected error for a discarded sandbox")
+	}
+	if len(store.List()) != 0 {
+		t.Error("Expected no sandboxes after discarding")
+	}
+}
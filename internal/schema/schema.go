@@ -0,0 +1,205 @@
+// Package schema derives JSON Schemas (draft 2020-12) from Go types, following
+// the rules encoding/json marshals them by, so clients can validate the
+// output of tools against the types that produce it
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema. Type is a string, or a list of strings for types
+// that may be null.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType      = reflect.TypeFor[time.Time]()
+)
+
+// Generator derives schemas, collecting named struct types as definitions
+// referred to with $ref, which also lets recursive types refer to
+// themselves. Definitions are named after the package and type, such as
+// analyzer.TypeInfo.
+type Generator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+// NewGenerator creates a Generator without definitions
+func NewGenerator() *Generator {
+	return &Generator{defs: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// Defs returns the definitions of the named struct types the schemas
+// generated so far refer to, for the $defs of the document holding them
+func (g *Generator) Defs() map[string]*Schema {
+	return g.defs
+}
+
+// Name returns the definition name of a named struct type
+func Name(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// Schema returns the schema of the JSON encoding of a value of type t. A
+// pointer at the top level is taken to be non-nil.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return g.schema(t)
+}
+
+// schema returns the schema of type t at any depth
+func (g *Generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler):
+		// Custom encodings can be anything
+		return &Schema{}
+	case t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are base64 strings
+			return nullable(&Schema{Type: "string"})
+		}
+		return nullable(&Schema{Type: "array", Items: g.schema(t.Elem())})
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = Name(t)
+			g.names[t] = name
+			g.defs[name] = &Schema{}
+			*g.defs[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/$defs/" + name}
+	}
+	// Interfaces hold anything; channels and functions do not encode
+	return &Schema{}
+}
+
+// nullable allows a schema to be null as well
+func nullable(s *Schema) *Schema {
+	if typ, ok := s.Type.(string); ok {
+		s.Type = []string{typ, "null"}
+		return s
+	}
+	if s.Ref == "" && s.Type == nil {
+		// Already anything
+		return s
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+// object returns the schema of a struct's fields, including those of
+// embedded structs without a JSON name. Fields without omitempty are
+// required, except those promoted through embedded pointers, which are
+// missing when the pointer is nil.
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(s, t, true)
+	return s
+}
+
+// fields adds the fields of struct type t to the object schema s
+func (g *Generator) fields(s *Schema, t reflect.Type, required bool) {
+	var embedded []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, field)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+		fs := g.schema(field.Type)
+		if hasOption(opts, "string") {
+			fs = &Schema{Type: "string"}
+		}
+		s.Properties[name] = fs
+		if required && !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	// Fields of embedded structs are shadowed by the outer ones
+	for _, field := range embedded {
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			g.fields(s, ft.Elem(), false)
+		} else {
+			g.fields(s, ft, required)
+		}
+	}
+}
+
+// hasOption reports whether a comma-separated list of JSON tag options
+// holds option
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name     string    `json:"name"`
+	Children []*node   `json:"children,omitempty"`
+	Parent   *node     `json:"parent"`
+	Created  time.Time `json:"created"`
+	Count    int64     `json:"count,string"`
+	Skipped  string    `json:"-"`
+	hidden   string
+}
+
+type base struct {
+	ID   int    `json:"id"`
+	Name string `json:"base_name"`
+}
+
+type extra struct {
+	Note string `json:"note"`
+}
+
+type wrapper struct {
+	base
+	*extra
+	Name   string            `json:"base_name"`
+	Labels map[string]string `json:"labels"`
+	Data   []byte            `json:"data"`
+	Value  any               `json:"value"`
+	Inline struct {
+		Flag bool `json:"flag"`
+	} `json:"inline"`
+}
+
+func TestSchema(t *testing.T) {
+	g := NewGenerator()
+	root := g.Schema(reflect.TypeFor[*node]())
+	if root.Ref != "#/$defs/schema.node" {
+		t.Fatalf("Expected a reference to schema.node, got %+v", root)
+	}
+	def := g.Defs()["schema.node"]
+	if def == nil || def.Type != "object" {
+		t.Fatalf("Expected an object definition, got %+v", def)
+	}
+	if len(def.Properties) != 5 {
+		t.Errorf("Expected 5 properties, got %v", keys(def.Properties))
+	}
+	if !reflect.DeepEqual(def.Required, []string{"name", "parent", "created", "count"}) {
+		t.Errorf("Expected all but children to be required, got %v", def.Required)
+	}
+	children := def.Properties["children"]
+	if !reflect.DeepEqual(children.Type, []string{"array", "null"}) || len(children.Items.AnyOf) != 2 || children.Items.AnyOf[0].Ref != root.Ref {
+		t.Errorf("Expected a nullable array of nullable references, got %s", encode(t, children))
+	}
+	if created := def.Properties["created"]; created.Type != "string" || created.Format != "date-time" {
+		t.Errorf("Expected a date-time string, got %s", encode(t, created))
+	}
+	if count := def.Properties["count"]; count.Type != "string" {
+		t.Errorf("Expected the string option to encode a string, got %s", encode(t, count))
+	}
+
+	g.Schema(reflect.TypeFor[wrapper]())
+	w := g.Defs()["schema.wrapper"]
+	if !reflect.DeepEqual(keys(w.Properties), []string{"base_name", "data", "id", "inline", "labels", "note", "value"}) {
+		t.Errorf("Expected promoted and shadowed fields, got %v", keys(w.Properties))
+	}
+	if !reflect.DeepEqual(w.Required, []string{"base_name", "labels", "data", "value", "inline", "id"}) {
+		t.Errorf("Expected fields of the embedded pointer to be optional, got %v", w.Required)
+	}
+	if data := w.Properties["data"]; !reflect.DeepEqual(data.Type, []string{"string", "null"}) {
+		t.Errorf("Expected bytes to be a nullable string, got %s", encode(t, data))
+	}
+	if labels := w.Properties["labels"]; labels.AdditionalProperties == nil || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("Expected a map of strings, got %s", encode(t, labels))
+	}
+	if inline := w.Properties["inline"]; inline.Ref != "" || inline.Properties["flag"] == nil {
+		t.Errorf("Expected an anonymous struct inline, got %s", encode(t, inline))
+	}
+	if value := w.Properties["value"]; value.Type != nil || value.Ref != "" {
+		t.Errorf("Expected any value, got %s", encode(t, value))
+	}
+	if _, ok := g.Defs()["schema.base"]; ok {
+		t.Error("Expected embedded structs to be inlined, not defined")
+	}
+}
+
+func keys(m map[string]*Schema) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func encode(t *testing.T, s *Schema) string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,487 @@
+package seccheck
+
+import (
+	"crypto/tls"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// allRules are the rules Scan runs by default
+var allRules = []Rule{
+	{
+		ID:          "hardcoded-credentials",
+		Description: "String literals that look like credentials: values assigned to password, secret, token or key names, and well-known key and token formats",
+		Remediation: "Load the secret at run time from the environment, a secret manager or a file outside the repository, and rotate the exposed value",
+		check:       checkCredentials,
+	},
+	{
+		ID:          "weak-random",
+		Description: "math/rand used for tokens, keys, passwords, nonces or other secrets",
+		Remediation: "Use crypto/rand (rand.Read, rand.Text or rand.Int) for anything an attacker must not predict",
+		check:       checkWeakRandom,
+	},
+	{
+		ID:          "command-injection",
+		Description: "os/exec commands whose program, or shell script run with -c, is not a constant",
+		Remediation: "Run a fixed program with arguments passed separately instead of through a shell, and validate variable input against an allow list",
+		check:       checkCommands,
+	},
+	{
+		ID:          "sql-concatenation",
+		Description: "SQL queries built with string concatenation or fmt.Sprintf and passed to Query, Exec or Prepare",
+		Remediation: "Pass variable values as query parameters (? or $1 placeholders) instead of formatting them into the SQL text",
+		check:       checkSQL,
+	},
+	{
+		ID:          "insecure-tls",
+		Description: "tls.Config values disabling certificate verification, allowing TLS versions before 1.2 or enabling insecure cipher suites",
+		Remediation: "Keep certificate verification on (trust a private CA through RootCAs instead), set MinVersion to tls.VersionTLS12 or later and use the default cipher suites",
+		check:       checkTLS,
+	},
+}
+
+// credentialName matches identifiers and keys that name a secret
+var credentialName = regexp.MustCompile(`(?i)(passw(or)?d|pwd|secret|token|api_?key|access_?key|private_?key|credential)`)
+
+// notCredential matches names that mention a secret without holding one,
+// such as TokenURL or passwordField
+var notCredential = regexp.MustCompile(`(?i)(url|uri|endpoint|path|file|dir|type|name|field|header|env|var|len|length|size|format|prefix|suffix|pattern|regex|param|key_?id|label|prompt|message|msg)s?$`)
+
+// credentialFormats match well-known credential formats anywhere
+var credentialFormats = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key ID", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY( BLOCK)?-----`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+// checkCredentials reports credential formats in string literals and
+// literal values given to credential names
+func checkCredentials(p *pass) {
+	reported := make(map[*ast.BasicLit]bool)
+	named := func(name string, value ast.Expr) {
+		lit, ok := ast.Unparen(value).(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || reported[lit] || !credentialName.MatchString(name) || notCredential.MatchString(name) {
+			return
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil || !looksLikeSecret(s) {
+			return
+		}
+		reported[lit] = true
+		p.report(lit.Pos(), SeverityHigh, "%s is set to a hardcoded value", name)
+	}
+
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					named(name.Name, n.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					named(exprName(lhs), n.Rhs[i])
+				}
+			}
+		case *ast.KeyValueExpr:
+			key := exprName(n.Key)
+			if lit, ok := n.Key.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				key, _ = strconv.Unquote(lit.Value)
+			}
+			named(key, n.Value)
+		case *ast.BinaryExpr:
+			if n.Op == token.EQL || n.Op == token.NEQ {
+				named(exprName(n.X), n.Y)
+				named(exprName(n.Y), n.X)
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || reported[lit] {
+			return true
+		}
+		for _, format := range credentialFormats {
+			if format.pattern.MatchString(lit.Value) {
+				reported[lit] = true
+				p.report(lit.Pos(), SeverityHigh, "string literal contains a %s", format.name)
+				break
+			}
+		}
+		return true
+	})
+}
+
+// looksLikeSecret reports whether a literal could be a real secret rather
+// than an empty value, a placeholder or a reference to one
+func looksLikeSecret(s string) bool {
+	if len(s) < 8 || strings.ContainsAny(s, " \t\n") {
+		return false
+	}
+	if strings.Contains(s, "${") || strings.Contains(s, "{{") || strings.HasPrefix(s, "$") {
+		return false
+	}
+	// Environment variable names such as DB_PASSWORD
+	if strings.ToUpper(s) == s && strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") == "" {
+		return false
+	}
+	lower := strings.ToLower(s)
+	for _, placeholder := range []string{"changeme", "example", "placeholder", "xxxxxxxx", "redacted", "your-", "your_", "<"} {
+		if strings.Contains(lower, placeholder) {
+			return false
+		}
+	}
+	return true
+}
+
+// exprName returns the name an identifier or selector refers to
+func exprName(expr ast.Expr) string {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
+// secretName matches names of functions and variables producing secrets
+var secretName = regexp.MustCompile(`(?i)(token|secret|passw|key|nonce|salt|session|otp|csrf|auth|crypt|reset|invite|api|cred)`)
+
+// checkWeakRandom reports math/rand calls in functions and assignments
+// whose names say they produce a secret
+func checkWeakRandom(p *pass) {
+	var stack []ast.Node
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fn := calledFunc(p.info, call)
+		if fn == nil || fn.Pkg() == nil || (fn.Pkg().Path() != "math/rand" && fn.Pkg().Path() != "math/rand/v2") {
+			return true
+		}
+		if purpose := secretPurpose(stack); purpose != "" {
+			p.report(call.Pos(), SeverityHigh, "%s.%s generates %s; math/rand output is predictable", fn.Pkg().Name(), fn.Name(), purpose)
+		}
+		return true
+	})
+}
+
+// secretPurpose returns the name of the enclosing assignment target or
+// function when it names a secret, innermost first
+func secretPurpose(stack []ast.Node) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var names []string
+		switch n := stack[i].(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				names = append(names, exprName(lhs))
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				names = append(names, name.Name)
+			}
+		case *ast.FuncDecl:
+			names = append(names, n.Name.Name)
+		}
+		for _, name := range names {
+			if name != "" && secretName.MatchString(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// calledFunc returns the function or method a call invokes, if static
+func calledFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[fun].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		fn, _ := info.Uses[fun.Sel].(*types.Func)
+		return fn
+	}
+	return nil
+}
+
+// isConstant reports whether expr has a constant value
+func isConstant(info *types.Info, expr ast.Expr) bool {
+	return info.Types[expr].Value != nil
+}
+
+// constantString returns the value of a constant string expression
+func constantString(info *types.Info, expr ast.Expr) (string, bool) {
+	value := info.Types[expr].Value
+	if value == nil || value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(value), true
+}
+
+// shells are programs that run their -c argument as a script
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true,
+	"cmd": true, "cmd.exe": true, "powershell": true, "powershell.exe": true, "pwsh": true,
+}
+
+// checkCommands reports exec.Command calls running a variable program or a
+// variable shell script
+func checkCommands(p *pass) {
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fn := calledFunc(p.info, call)
+		if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "os/exec" {
+			return true
+		}
+		args := call.Args
+		switch fn.Name() {
+		case "Command":
+		case "CommandContext":
+			if len(args) == 0 {
+				return true
+			}
+			args = args[1:]
+		default:
+			return true
+		}
+		if len(args) == 0 {
+			return true
+		}
+
+		program, ok := constantString(p.info, args[0])
+		if !ok {
+			p.report(args[0].Pos(), SeverityMedium, "exec.%s runs a program that is not a constant", fn.Name())
+			return true
+		}
+		if !shells[strings.ToLower(program[strings.LastIndexAny(program, `/\`)+1:])] {
+			return true
+		}
+		for i := 1; i < len(args); i++ {
+			flag, ok := constantString(p.info, args[i])
+			if !ok {
+				if call.Ellipsis.IsValid() && i == len(args)-1 {
+					p.report(args[i].Pos(), SeverityMedium, "exec.%s runs %s with variable arguments", fn.Name(), program)
+				}
+				return true
+			}
+			if flag != "-c" && !strings.EqualFold(flag, "/c") && !strings.EqualFold(flag, "-command") {
+				continue
+			}
+			if i+1 < len(args) && !isConstant(p.info, args[i+1]) {
+				p.report(args[i+1].Pos(), SeverityHigh, "exec.%s runs a %s script built from variable input", fn.Name(), program)
+			}
+			return true
+		}
+		return true
+	})
+}
+
+// sqlMethods are the methods taking SQL text, with the index of the query
+// argument for database/sql
+var sqlMethods = map[string]int{
+	"Query": 0, "QueryRow": 0, "Exec": 0, "Prepare": 0,
+	"QueryContext": 1, "QueryRowContext": 1, "ExecContext": 1, "PrepareContext": 1,
+}
+
+// checkSQL reports queries built by concatenation or formatting passed to
+// database/sql, or to query methods of types from unresolved packages such
+// as sqlx or pgx
+func checkSQL(p *pass) {
+	// Local variables holding queries built from variable parts
+	built := make(map[types.Object]bool)
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok || len(n.Lhs) != len(n.Rhs) {
+					continue
+				}
+				obj := p.info.ObjectOf(ident)
+				if obj == nil {
+					continue
+				}
+				if isBuiltString(p.info, n.Rhs[i]) || (n.Tok == token.ADD_ASSIGN && !isConstant(p.info, n.Rhs[i])) {
+					built[obj] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) && isBuiltString(p.info, n.Values[i]) {
+					if obj := p.info.ObjectOf(name); obj != nil {
+						built[obj] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		index, ok := sqlMethods[sel.Sel.Name]
+		if !ok {
+			return true
+		}
+
+		var candidates []ast.Expr
+		switch {
+		case isSQLReceiver(p.info, sel.X):
+			if index < len(call.Args) {
+				candidates = call.Args[index : index+1]
+			}
+		case p.info.TypeOf(sel.X) == nil || p.info.TypeOf(sel.X) == types.Typ[types.Invalid]:
+			// Query methods of other libraries may take a context first
+			candidates = call.Args[:min(2, len(call.Args))]
+		}
+		for _, arg := range candidates {
+			unparen := ast.Unparen(arg)
+			ident, isIdent := unparen.(*ast.Ident)
+			if isBuiltString(p.info, unparen) || (isIdent && built[p.info.ObjectOf(ident)]) {
+				p.report(arg.Pos(), SeverityHigh, "SQL passed to %s is built from variable strings", sel.Sel.Name)
+				break
+			}
+		}
+		return true
+	})
+}
+
+// isSQLReceiver reports whether expr is a database/sql value such as a
+// *sql.DB, *sql.Tx or *sql.Conn
+func isSQLReceiver(info *types.Info, expr ast.Expr) bool {
+	t := info.TypeOf(expr)
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "database/sql"
+}
+
+// isBuiltString reports whether expr concatenates a string with a variable
+// part or formats one with fmt.Sprintf
+func isBuiltString(info *types.Info, expr ast.Expr) bool {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD || isConstant(info, expr) {
+			return false
+		}
+		_, left := constantString(info, expr.X)
+		_, right := constantString(info, expr.Y)
+		return left || right || isBuiltString(info, expr.X) || isBuiltString(info, expr.Y)
+	case *ast.CallExpr:
+		fn := calledFunc(info, expr)
+		if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "fmt" || fn.Name() != "Sprintf" {
+			return false
+		}
+		for _, arg := range expr.Args[1:] {
+			if !isConstant(info, arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insecureSuites are the names of the cipher suites crypto/tls considers insecure
+var insecureSuites = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		names[suite.Name] = true
+	}
+	return names
+}()
+
+// checkTLS reports tls.Config literals and assignments weakening TLS
+func checkTLS(p *pass) {
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			if !isTLSConfig(p.info.TypeOf(n)) {
+				return true
+			}
+			for _, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					checkTLSField(p, exprName(kv.Key), kv.Value)
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				sel, ok := lhs.(*ast.SelectorExpr)
+				if ok && i < len(n.Rhs) && len(n.Lhs) == len(n.Rhs) && isTLSConfig(p.info.TypeOf(sel.X)) {
+					checkTLSField(p, sel.Sel.Name, n.Rhs[i])
+				}
+			}
+		}
+		return true
+	})
+}
+
+// checkTLSField reports a tls.Config field set to an insecure value
+func checkTLSField(p *pass, field string, value ast.Expr) {
+	switch field {
+	case "InsecureSkipVerify":
+		if v := p.info.Types[value].Value; v != nil && constant.BoolVal(v) {
+			p.report(value.Pos(), SeverityHigh, "InsecureSkipVerify disables certificate verification, allowing man-in-the-middle attacks")
+		}
+	case "MinVersion", "MaxVersion":
+		v := p.info.Types[value].Value
+		if v == nil {
+			return
+		}
+		if version, ok := constant.Uint64Val(v); ok && version != 0 && version < tls.VersionTLS12 {
+			p.report(value.Pos(), SeverityMedium, "%s allows %s, which has known weaknesses", field, tls.VersionName(uint16(version)))
+		}
+	case "CipherSuites":
+		lit, ok := ast.Unparen(value).(*ast.CompositeLit)
+		if !ok {
+			return
+		}
+		for _, elt := range lit.Elts {
+			if name := exprName(elt); insecureSuites[name] {
+				p.report(elt.Pos(), SeverityMedium, "cipher suite %s is insecure", name)
+			}
+		}
+	}
+}
+
+// isTLSConfig reports whether t is tls.Config or a pointer to it
+func isTLSConfig(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "crypto/tls" && named.Obj().Name() == "Config"
+}
@@ -0,0 +1,284 @@
+// Package seccheck runs a curated set of security checks over Go syntax
+// trees: hardcoded credentials, math/rand used for secrets, commands built
+// from variable input, SQL built by string concatenation and insecure TLS
+// configurations. Packages are type checked against the standard library
+// only, which is all the checks need to recognize the APIs involved.
+package seccheck
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity levels, from most to least urgent
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// severityRank orders severities for filtering and sorting
+var severityRank = map[string]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3}
+
+// Finding is a single security issue
+type Finding struct {
+	// File is relative to the scanned directory when Scan reports it
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Remediation says how to fix the issue
+	Remediation string `json:"remediation"`
+}
+
+// Rule is a security check
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Remediation is the hint attached to every finding of the rule
+	Remediation string `json:"remediation"`
+
+	check func(p *pass)
+}
+
+// Options selects what Scan reports
+type Options struct {
+	// Files restricts the scan to these files, relative to the directory
+	// or absolute; empty scans every non-test Go file in the tree
+	Files []string
+	// Rules restricts the scan to the rules with these IDs; empty runs all
+	Rules []string
+	// MinSeverity drops findings below this severity; empty keeps all
+	MinSeverity string
+}
+
+// Rules returns the available rules, ordered by ID
+func Rules() []Rule {
+	rules := append([]Rule(nil), allRules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// pass is one rule inspecting one file
+type pass struct {
+	rule     *Rule
+	fset     *token.FileSet
+	info     *types.Info
+	file     *ast.File
+	findings []Finding
+}
+
+// report records a finding of the pass's rule at pos
+func (p *pass) report(pos token.Pos, severity, format string, args ...interface{}) {
+	position := p.fset.Position(pos)
+	p.findings = append(p.findings, Finding{
+		File:        position.Filename,
+		Line:        position.Line,
+		Column:      position.Column,
+		Rule:        p.rule.ID,
+		Severity:    severity,
+		Message:     fmt.Sprintf(format, args...),
+		Remediation: p.rule.Remediation,
+	})
+}
+
+// CheckFile runs the given rules, or all rules when none are given, over a
+// type-checked file
+func CheckFile(fset *token.FileSet, info *types.Info, file *ast.File, rules ...Rule) []Finding {
+	if len(rules) == 0 {
+		rules = allRules
+	}
+	var findings []Finding
+	for i := range rules {
+		p := &pass{rule: &rules[i], fset: fset, info: info, file: file}
+		rules[i].check(p)
+		findings = append(findings, p.findings...)
+	}
+	return findings
+}
+
+// Scan runs the security checks over the Go files under dir. Test files,
+// testdata, vendor and hidden directories are skipped, since test fixtures
+// routinely hold fake credentials. Findings are ordered by file and line.
+func Scan(ctx context.Context, dir string, opts Options) ([]Finding, error) {
+	rules, err := selectRules(opts.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MinSeverity != "" && severityRank[opts.MinSeverity] == 0 {
+		return nil, fmt.Errorf("unknown severity %q (expected high, medium or low)", opts.MinSeverity)
+	}
+
+	pkgFiles, err := scanFiles(dir, opts.Files)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(pkgFiles))
+	for pkgDir := range pkgFiles {
+		dirs = append(dirs, pkgDir)
+	}
+	sort.Strings(dirs)
+
+	imp := stdImporter{importer.Default()}
+	findings := []Finding{}
+	for _, pkgDir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := scanPackage(pkgDir, pkgFiles[pkgDir], imp, rules)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range found {
+			if severityRank[finding.Severity] >= severityRank[opts.MinSeverity] {
+				if rel, err := filepath.Rel(dir, finding.File); err == nil && !strings.HasPrefix(rel, "..") {
+					finding.File = filepath.ToSlash(rel)
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, nil
+}
+
+// selectRules returns the rules with the given IDs, or all rules
+func selectRules(ids []string) ([]Rule, error) {
+	if len(ids) == 0 {
+		return allRules, nil
+	}
+	var rules []Rule
+	for _, id := range ids {
+		found := false
+		for _, rule := range allRules {
+			if rule.ID == id {
+				rules = append(rules, rule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown security rule %q", id)
+		}
+	}
+	return rules, nil
+}
+
+// scanFiles groups the files to report on by package directory
+func scanFiles(dir string, files []string) (map[string][]string, error) {
+	pkgFiles := make(map[string][]string)
+	if len(files) == 0 {
+		err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				name := entry.Name()
+				if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isScanFile(path) {
+				pkgFiles[filepath.Dir(path)] = append(pkgFiles[filepath.Dir(path)], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+	}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if isScanFile(file) {
+			pkgFiles[filepath.Dir(file)] = append(pkgFiles[filepath.Dir(file)], file)
+		}
+	}
+	return pkgFiles, nil
+}
+
+// isScanFile reports whether path is a Go source file covered by the scan
+func isScanFile(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
+
+// scanPackage type checks the package in pkgDir and checks the given files
+func scanPackage(pkgDir string, report []string, imp types.Importer, rules []Rule) ([]Finding, error) {
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, entry := range entries {
+		path := filepath.Join(pkgDir, entry.Name())
+		if entry.IsDir() || !isScanFile(path) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, file)
+	}
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	_, _ = conf.Check(parsed[0].Name.Name, fset, parsed, info) // Errors from unresolved imports are expected
+
+	wanted := make(map[string]bool, len(report))
+	for _, file := range report {
+		wanted[file] = true
+	}
+	var findings []Finding
+	for _, file := range parsed {
+		if wanted[fset.Position(file.Pos()).Filename] {
+			findings = append(findings, CheckFile(fset, info, file, rules...)...)
+		}
+	}
+	return findings, nil
+}
+
+// stdImporter imports standard library packages only. Other imports stay
+// unresolved; the checks only need to recognize standard library APIs.
+type stdImporter struct {
+	imp types.Importer
+}
+
+// Import implements types.Importer
+func (s stdImporter) Import(path string) (*types.Package, error) {
+	if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+		return nil, fmt.Errorf("%s is not a standard library package", path)
+	}
+	return s.imp.Import(path)
+}
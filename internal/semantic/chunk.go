@@ -0,0 +1,127 @@
+package semantic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// maxChunkBytes caps the source text of a declaration that is embedded and
+// kept; the rest of longer declarations is dropped
+const maxChunkBytes = 4096
+
+// Chunk is a top-level declaration of a file, with its doc comment: the
+// unit the index embeds and searches return
+type Chunk struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"` // func, method, type, const or var
+	Package string `json:"package"`
+	Line    int    `json:"line"`
+	EndLine int    `json:"end_line"`
+	Text    string `json:"text"`
+	// Hash identifies what is embedded, so that a declaration that did
+	// not change keeps its vector when its file does
+	Hash   string `json:"hash"`
+	Vector Vector `json:"vector,omitempty"`
+}
+
+// ChunkFile splits a Go file into its top-level declarations. Each type of
+// a grouped type declaration is a chunk of its own, while grouped constants
+// and variables make one chunk. A file with syntax errors yields the
+// declarations that parsed.
+func ChunkFile(filename string, src []byte) []Chunk {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil || file.Name == nil {
+		return nil
+	}
+
+	var chunks []Chunk
+	add := func(name, kind string, doc *ast.CommentGroup, node ast.Node) {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		from, to := fset.Position(start), fset.Position(node.End())
+		if !from.IsValid() || !to.IsValid() || from.Offset > to.Offset || to.Offset > len(src) {
+			return
+		}
+		text := src[from.Offset:to.Offset]
+		if len(text) > maxChunkBytes {
+			text = text[:maxChunkBytes]
+		}
+		chunk := Chunk{
+			Name:    name,
+			Kind:    kind,
+			Package: file.Name.Name,
+			Line:    fset.Position(node.Pos()).Line,
+			EndLine: to.Line,
+			Text:    string(text),
+		}
+		sum := sha256.Sum256([]byte(chunk.embedText()))
+		chunk.Hash = hex.EncodeToString(sum[:])
+		chunks = append(chunks, chunk)
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				add(receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, "method", d.Doc, d)
+			} else {
+				add(d.Name.Name, "func", d.Doc, d)
+			}
+		case *ast.GenDecl:
+			switch d.Tok {
+			case token.TYPE:
+				for _, spec := range d.Specs {
+					spec := spec.(*ast.TypeSpec)
+					if len(d.Specs) == 1 {
+						add(spec.Name.Name, "type", d.Doc, d)
+					} else {
+						add(spec.Name.Name, "type", spec.Doc, spec)
+					}
+				}
+			case token.CONST, token.VAR:
+				var names []string
+				for _, spec := range d.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						names = append(names, name.Name)
+					}
+				}
+				add(strings.Join(names, ", "), d.Tok.String(), d.Doc, d)
+			}
+		}
+	}
+	return chunks
+}
+
+// embedText is what is embedded for a chunk: its package and name, which
+// the text may not spell out, and the text
+func (c *Chunk) embedText() string {
+	return c.Package + " " + c.Name + "\n" + c.Text
+}
+
+// receiverName returns the name of a method's receiver type, without
+// pointer or type parameters
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return "?"
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkFile(t *testing.T) {
+	src := `package retry
+
+import "time"
+
+// MaxAttempts bounds retries
+const MaxAttempts = 3
+
+var (
+	backoff = time.Second
+	jitter  = true
+)
+
+// Policy decides when to retry
+type Policy struct{}
+
+type (
+	// Clock tells the time
+	Clock interface{ Now() time.Time }
+	Timer struct{}
+)
+
+// Next returns the wait before the next attempt
+func (p *Policy) Next(attempt int) time.Duration { return backoff }
+
+func Do(f func() error) error { return f() }
+`
+	chunks := ChunkFile("retry.go", []byte(src))
+	var got []string
+	for _, chunk := range chunks {
+		got = append(got, chunk.Kind+" "+chunk.Name)
+		if chunk.Package != "retry" || chunk.Hash == "" {
+			t.Errorf("Expected package and hash of %s, got %+v", chunk.Name, chunk)
+		}
+	}
+	want := "const MaxAttempts|var backoff, jitter|type Policy|type Clock|type Timer|method Policy.Next|func Do"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("Expected chunks %s, got %s", want, strings.Join(got, "|"))
+	}
+
+	// Doc comments are part of the text; lines are those of the declaration
+	next := chunks[5]
+	if !strings.HasPrefix(next.Text, "// Next returns") || next.Line != 23 || next.EndLine != 23 {
+		t.Errorf("Expected the doc comment and line 23, got %+v", next)
+	}
+	if clock := chunks[3]; !strings.HasPrefix(clock.Text, "// Clock tells") {
+		t.Errorf("Expected the doc comment of a grouped type, got %q", clock.Text)
+	}
+
+	// A file with syntax errors yields what parsed
+	broken := ChunkFile("broken.go", []byte("package retry\n\nfunc Good() {}\n\nfunc Bad( {\n"))
+	if len(broken) == 0 || broken[0].Name != "Good" {
+		t.Errorf("Expected the declarations before the error, got %+v", broken)
+	}
+}
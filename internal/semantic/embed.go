@@ -0,0 +1,204 @@
+package semantic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultDims is the number of dimensions of the HashEmbedder the server
+// uses
+const DefaultDims = 512
+
+// Embedder turns texts into unit vectors whose dot product measures how
+// related the texts are
+type Embedder interface {
+	// Name identifies the embedder and its dimensions. An index persisted
+	// with another embedder is discarded, since its vectors do not compare.
+	Name() string
+	Embed(ctx context.Context, texts []string) ([]Vector, error)
+}
+
+// Vector is an embedding. It is persisted as the base64 of its
+// little-endian float32 components.
+type Vector []float32
+
+// Dot returns the dot product of two vectors, the cosine of their angle
+// for unit vectors. Vectors of different dimensions do not compare.
+func (v Vector) Dot(w Vector) float64 {
+	if len(v) != len(w) {
+		return 0
+	}
+	var sum float64
+	for i := range v {
+		sum += float64(v[i]) * float64(w[i])
+	}
+	return sum
+}
+
+// MarshalJSON encodes the vector as base64
+func (v Vector) MarshalJSON() ([]byte, error) {
+	data := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(x))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(data))
+}
+
+// UnmarshalJSON decodes a vector encoded by MarshalJSON
+func (v *Vector) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(data)%4 != 0 {
+		return fmt.Errorf("vector of %d bytes", len(data))
+	}
+	*v = make(Vector, len(data)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return nil
+}
+
+// HashEmbedder embeds texts without a model, by hashing the words of their
+// identifiers and comments into a fixed number of dimensions. Identifiers
+// are split into words (HTTPServer into http and server), so texts relate
+// when they share vocabulary: a query for "retry backoff" finds
+// declarations naming retries and their backoff, whatever their casing.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates an embedder of dims dimensions
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Name implements Embedder
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", e.dims)
+}
+
+// Embed implements Embedder. Each word adds to the dimension it hashes to,
+// with a sign taken from the hash so that collisions tend to cancel out,
+// and with a weight growing with the logarithm of its count. A text
+// without words embeds as the zero vector, which relates to nothing.
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([]Vector, error) {
+	vectors := make([]Vector, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		counts := make(map[string]int)
+		for _, word := range words(text) {
+			counts[word]++
+		}
+		v := make(Vector, e.dims)
+		for word, count := range counts {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			weight := float32(1 + math.Log(float64(count)))
+			if sum>>63 == 1 {
+				weight = -weight
+			}
+			v[sum%uint64(e.dims)] += weight
+		}
+		normalize(v)
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+// normalize scales a vector to unit length, leaving the zero vector alone
+func normalize(v Vector) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// stopWords are words too common in Go source to relate texts
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "be": true, "by": true,
+	"for": true, "if": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "with": true,
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "err": true, "func": true, "go": true, "interface": true,
+	"map": true, "nil": true, "package": true, "range": true, "return": true, "struct": true,
+	"switch": true, "type": true, "var": true,
+}
+
+// words splits a text into lower-case words: the words of each identifier
+// and, for identifiers of several words, the whole identifier. Plurals are
+// reduced to the singular.
+func words(text string) []string {
+	var result []string
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, token := range tokens {
+		parts := splitIdentifier(token)
+		if len(parts) > 1 {
+			result = append(result, strings.ToLower(token))
+		}
+		for _, part := range parts {
+			word := singular(strings.ToLower(part))
+			if len(word) > 1 && !stopWords[word] {
+				result = append(result, word)
+			}
+		}
+	}
+	return result
+}
+
+// splitIdentifier splits an identifier at changes of case and between
+// letters and digits: parseHTTPResponse2 yields parse, HTTP, Response and 2
+func splitIdentifier(s string) []string {
+	runes := []rune(s)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		lowerToUpper := unicode.IsLower(prev) && unicode.IsUpper(cur)
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		digitChange := unicode.IsDigit(prev) != unicode.IsDigit(cur)
+		if lowerToUpper || acronymEnd || digitChange {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// singular strips the plural ending of a word
+func singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
@@ -0,0 +1,54 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	got := words("func parseHTTPResponse2(retries int) // the Retry policies")
+	// Keywords, stop words and single characters are dropped
+	want := []string{"parsehttpresponse2", "parse", "http", "response", "retry", "int", "retry", "policy"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	embedder := NewHashEmbedder(256)
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"retry backoff",
+		"// RetryPolicy computes the backoff between retries",
+		"func WriteFile(path string, data []byte)",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	query, related, unrelated, empty := vectors[0], vectors[1], vectors[2], vectors[3]
+	if norm := query.Dot(query); math.Abs(norm-1) > 1e-5 {
+		t.Errorf("Expected a unit vector, got a norm of %f", norm)
+	}
+	if query.Dot(related) <= query.Dot(unrelated) {
+		t.Errorf("Expected the retry policy closer to the query than WriteFile: %f <= %f", query.Dot(related), query.Dot(unrelated))
+	}
+	if query.Dot(empty) != 0 {
+		t.Errorf("Expected an empty text to relate to nothing, got %f", query.Dot(empty))
+	}
+
+	// Vectors survive persistence exactly
+	data, err := json.Marshal(related)
+	if err != nil {
+		t.Fatalf("Failed to marshal vector: %v", err)
+	}
+	var decoded Vector
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal vector: %v", err)
+	}
+	if !slices.Equal(decoded, related) {
+		t.Error("Expected the decoded vector to equal the original")
+	}
+}
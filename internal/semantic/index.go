@@ -0,0 +1,521 @@
+// Package semantic searches the declarations of a repository by meaning.
+// Go files are split into their top-level declarations, which are embedded
+// into vectors; a query is embedded the same way and answered with the
+// declarations closest to it. The index is updated file by file as files
+// change, re-embedding only the declarations that changed, and is
+// persisted as an append-only log so that a restart only embeds what
+// changed in the meantime.
+package semantic
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/watch"
+)
+
+// logVersion identifies the format of persisted logs; logs of other
+// versions are discarded
+const logVersion = 1
+
+// minStale is how many superseded records a log keeps before compaction
+// is considered; below it, rewriting the log costs more than it saves
+const minStale = 64
+
+// snippetLines is how many lines of a declaration a result shows
+const snippetLines = 8
+
+// Index holds the embedded declarations of the Go files of a repository
+type Index struct {
+	root     string
+	path     string
+	embedder Embedder
+
+	mu    sync.RWMutex
+	files map[string]fileEntry // By slash-separated path relative to root
+	log   *os.File
+	// stale counts the records of the log superseded by later ones, which
+	// compaction drops
+	stale int
+}
+
+// fileEntry is what the index holds about a file
+type fileEntry struct {
+	Hash   string // SHA-256 of the content
+	Chunks []Chunk
+}
+
+// header is the first line of a log
+type header struct {
+	Version  int    `json:"version"`
+	Embedder string `json:"embedder"`
+}
+
+// record is a line of a log after the header: the declarations of a file,
+// or its removal
+type record struct {
+	File    string  `json:"file"`
+	Hash    string  `json:"hash,omitempty"`
+	Chunks  []Chunk `json:"chunks,omitempty"`
+	Removed bool    `json:"removed,omitempty"`
+}
+
+// Changes counts what an update did
+type Changes struct {
+	// Updated counts the files that were added or whose content changed
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	// Embedded counts the declarations embedded; declarations that did
+	// not change keep their vectors
+	Embedded int `json:"embedded"`
+}
+
+// Stats describes the content of an index
+type Stats struct {
+	Files        int `json:"files"`
+	Declarations int `json:"declarations"`
+	// Stale counts the superseded records of the log
+	Stale int `json:"stale"`
+}
+
+// Result is a declaration matching a query
+type Result struct {
+	File    string  `json:"file"`
+	Line    int     `json:"line"`
+	EndLine int     `json:"end_line"`
+	Package string  `json:"package"`
+	Name    string  `json:"name"`
+	Kind    string  `json:"kind"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// Open loads the index of the repository at root persisted in the log at
+// path, creating the log when there is none. A log of another version or
+// embedder is discarded. Records after a damaged line, such as one cut
+// short by a crash, are dropped. An empty path keeps the index in memory.
+func Open(root, path string, embedder Embedder) (*Index, error) {
+	x := &Index{root: root, path: path, embedder: embedder, files: make(map[string]fileEntry)}
+	if path == "" {
+		return x, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	complete, err := x.replay()
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		// Rewrite the log from what could be read
+		if err := x.Compact(); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	x.log, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index log: %w", err)
+	}
+	return x, nil
+}
+
+// replay reads the log into the index, reporting whether it was read to
+// the end; a missing log, or one of another version or embedder, was not
+func (x *Index) replay() (bool, error) {
+	file, err := os.Open(x.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open index log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return false, nil
+	}
+	var head header
+	if json.Unmarshal(line, &head) != nil || head.Version != logVersion || head.Embedder != x.embedder.Name() {
+		return false, nil
+	}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return true, nil
+		}
+		var rec record
+		if err != nil || json.Unmarshal(line, &rec) != nil || rec.File == "" {
+			return false, nil
+		}
+		x.apply(rec)
+	}
+}
+
+// apply records a file's declarations or removal in memory. It must be
+// called with mu held, or before the index is shared.
+func (x *Index) apply(rec record) {
+	if _, ok := x.files[rec.File]; ok {
+		x.stale++
+	}
+	if rec.Removed {
+		// Nothing needs the removal once the file's records are dropped
+		delete(x.files, rec.File)
+		x.stale++
+		return
+	}
+	x.files[rec.File] = fileEntry{Hash: rec.Hash, Chunks: rec.Chunks}
+}
+
+// Update brings the index up to date with the given files, absolute or
+// relative to the root: files that changed are chunked again and files
+// that no longer exist are removed. Declarations whose text did not change
+// keep their vectors; only the others are embedded. Files other than Go
+// files, and files whose content did not change, are skipped.
+func (x *Index) Update(ctx context.Context, files []string) (Changes, error) {
+	var changes Changes
+	var errs []error
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+		if err := x.updateFile(ctx, file, &changes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := x.compactIfStale(); err != nil {
+		errs = append(errs, err)
+	}
+	return changes, errors.Join(errs...)
+}
+
+// updateFile updates the index with one file
+func (x *Index) updateFile(ctx context.Context, file string, changes *Changes) error {
+	abs := file
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(x.root, file)
+	}
+	rel, err := filepath.Rel(x.root, abs)
+	if err != nil || !strings.HasSuffix(abs, ".go") || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	x.mu.RLock()
+	previous, indexed := x.files[rel]
+	x.mu.RUnlock()
+
+	src, err := os.ReadFile(abs)
+	if os.IsNotExist(err) {
+		if !indexed {
+			return nil
+		}
+		if err := x.commit(record{File: rel, Removed: true}); err != nil {
+			return err
+		}
+		changes.Removed++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	sum := sha256.Sum256(src)
+	hash := hex.EncodeToString(sum[:])
+	if indexed && previous.Hash == hash {
+		return nil
+	}
+
+	chunks := ChunkFile(abs, src)
+	vectors := make(map[string]Vector)
+	for _, chunk := range previous.Chunks {
+		vectors[chunk.Hash] = chunk.Vector
+	}
+	var texts []string
+	var missing []int
+	for i := range chunks {
+		if vector, ok := vectors[chunks[i].Hash]; ok {
+			chunks[i].Vector = vector
+		} else {
+			texts = append(texts, chunks[i].embedText())
+			missing = append(missing, i)
+		}
+	}
+	if len(texts) > 0 {
+		embedded, err := x.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed %s: %w", rel, err)
+		}
+		for j, i := range missing {
+			chunks[i].Vector = embedded[j]
+		}
+	}
+
+	if err := x.commit(record{File: rel, Hash: hash, Chunks: chunks}); err != nil {
+		return err
+	}
+	changes.Updated++
+	changes.Embedded += len(texts)
+	return nil
+}
+
+// commit applies a record and appends it to the log
+func (x *Index) commit(rec record) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.apply(rec)
+	if x.log == nil {
+		return nil
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := x.log.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write index log: %w", err)
+	}
+	return nil
+}
+
+// Sync brings the index up to date with the whole repository: every Go
+// file is checked for changes and files no longer found are removed.
+// Paths containing any of the exclude patterns are skipped.
+func (x *Index) Sync(ctx context.Context, exclude []string) (Changes, error) {
+	found := make(map[string]bool)
+	var files []string
+	err := filepath.Walk(x.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, pattern := range exclude {
+			if strings.Contains(path, pattern) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".go") {
+			files = append(files, path)
+			if rel, err := filepath.Rel(x.root, path); err == nil {
+				found[filepath.ToSlash(rel)] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Changes{}, fmt.Errorf("failed to walk %s: %w", x.root, err)
+	}
+
+	x.mu.RLock()
+	for file := range x.files {
+		if !found[file] {
+			files = append(files, file)
+		}
+	}
+	x.mu.RUnlock()
+	return x.Update(ctx, files)
+}
+
+// Watch syncs the index and then polls the repository every interval,
+// updating the index with the files that changed, until ctx is cancelled.
+// onUpdate is called with the outcome of the sync and of each update.
+func (x *Index) Watch(ctx context.Context, interval time.Duration, exclude []string, onUpdate func(Changes, error)) error {
+	watcher := watch.New(x.root, interval, exclude)
+	// Prime the watcher first, so that changes made during the sync are
+	// picked up by the first poll
+	if _, err := watcher.Scan(); err != nil {
+		return err
+	}
+	changes, err := x.Sync(ctx, exclude)
+	if onUpdate != nil {
+		onUpdate(changes, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			changed, err := watcher.Scan()
+			if err != nil {
+				return err
+			}
+			if len(changed) == 0 {
+				continue
+			}
+			changes, err := x.Update(ctx, changed)
+			if onUpdate != nil {
+				onUpdate(changes, err)
+			}
+		}
+	}
+}
+
+// Search returns the declarations closest to the query, best first, at
+// most limit of them. Declarations sharing nothing with the query are
+// left out.
+func (x *Index) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	vectors, err := x.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	q := vectors[0]
+
+	x.mu.RLock()
+	var results []Result
+	for file, entry := range x.files {
+		for _, chunk := range entry.Chunks {
+			score := q.Dot(chunk.Vector)
+			if score <= 0 {
+				continue
+			}
+			results = append(results, Result{
+				File:    file,
+				Line:    chunk.Line,
+				EndLine: chunk.EndLine,
+				Package: chunk.Package,
+				Name:    chunk.Name,
+				Kind:    chunk.Kind,
+				Score:   math.Round(score*1000) / 1000,
+				Snippet: snippet(chunk.Text),
+			})
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		return results[i].Line < results[j].Line
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// snippet returns the first lines of a declaration
+func snippet(text string) string {
+	lines := strings.SplitN(text, "\n", snippetLines+1)
+	if len(lines) > snippetLines {
+		lines = append(lines[:snippetLines], "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Stats describes the content of the index
+func (x *Index) Stats() Stats {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	stats := Stats{Files: len(x.files), Stale: x.stale}
+	for _, entry := range x.files {
+		stats.Declarations += len(entry.Chunks)
+	}
+	return stats
+}
+
+// compactIfStale compacts the log once it holds more superseded records
+// than current ones
+func (x *Index) compactIfStale() error {
+	x.mu.RLock()
+	stale := x.stale > minStale && x.stale > len(x.files)
+	x.mu.RUnlock()
+	if !stale {
+		return nil
+	}
+	return x.Compact()
+}
+
+// Compact rewrites the log with one record per file, dropping superseded
+// records. The new log replaces the old one in one step, so that a crash
+// leaves either of them.
+func (x *Index) Compact() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.path == "" {
+		x.stale = 0
+		return nil
+	}
+
+	tmp := x.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact index log: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	err = encoder.Encode(header{Version: logVersion, Embedder: x.embedder.Name()})
+	names := make([]string, 0, len(x.files))
+	for name := range x.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err != nil {
+			break
+		}
+		entry := x.files[name]
+		err = encoder.Encode(record{File: name, Hash: entry.Hash, Chunks: entry.Chunks})
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, x.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact index log: %w", err)
+	}
+
+	if x.log != nil {
+		x.log.Close()
+	}
+	x.log, err = os.OpenFile(x.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		x.log = nil
+		return fmt.Errorf("failed to open index log: %w", err)
+	}
+	x.stale = 0
+	return nil
+}
+
+// Close closes the log
+func (x *Index) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.log == nil {
+		return nil
+	}
+	err := x.log.Close()
+	x.log = nil
+	return err
+}
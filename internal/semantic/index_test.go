@@ -0,0 +1,203 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes a file of a test repository
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory of %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	repo := t.TempDir()
+	logPath := filepath.Join(t.TempDir(), "index.log")
+	writeFile(t, repo, "retry/retry.go", `package retry
+
+// Backoff returns the wait before retrying an attempt
+func Backoff(attempt int) int { return attempt * 2 }
+
+// MaxRetries bounds the retries of a request
+const MaxRetries = 3
+`)
+	writeFile(t, repo, "store/store.go", `package store
+
+// WriteFile saves data to a file on disk
+func WriteFile(path string, data []byte) error { return nil }
+`)
+	writeFile(t, repo, "vendor/dep/dep.go", "package dep\n\nfunc Retry() {}\n")
+	ctx := context.Background()
+
+	x, err := Open(repo, logPath, NewHashEmbedder(256))
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	changes, err := x.Sync(ctx, []string{"vendor/"})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if changes.Updated != 2 || changes.Embedded != 3 {
+		t.Errorf("Expected 2 files and 3 declarations embedded, got %+v", changes)
+	}
+
+	results, err := x.Search(ctx, "retry backoff", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Name != "Backoff" || results[0].File != "retry/retry.go" || results[0].Line != 4 {
+		t.Fatalf("Expected Backoff first, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Name == "Retry" {
+			t.Errorf("Expected excluded files to be left out, got %+v", result)
+		}
+	}
+
+	// Changing one declaration re-embeds only that one
+	writeFile(t, repo, "retry/retry.go", `package retry
+
+// Backoff returns the wait before retrying an attempt
+func Backoff(attempt int) int { return attempt * 2 }
+
+// MaxRetries bounds the retries of a request
+const MaxRetries = 5
+`)
+	changes, err = x.Update(ctx, []string{filepath.Join(repo, "retry/retry.go"), filepath.Join(repo, "go.mod")})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if changes.Updated != 1 || changes.Embedded != 1 {
+		t.Errorf("Expected 1 declaration embedded, got %+v", changes)
+	}
+
+	// A file that was only touched is not chunked again
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(repo, "store/store.go"), later, later)
+	if changes, err = x.Update(ctx, []string{"store/store.go"}); err != nil || changes != (Changes{}) {
+		t.Errorf("Expected no changes for a touched file, got %+v, %v", changes, err)
+	}
+
+	// A restart embeds only what changed in the meantime
+	if err := x.Close(); err != nil {
+		t.Fatalf("Failed to close index: %v", err)
+	}
+	if err := os.Remove(filepath.Join(repo, "store/store.go")); err != nil {
+		t.Fatalf("Failed to remove store.go: %v", err)
+	}
+	x, err = Open(repo, logPath, NewHashEmbedder(256))
+	if err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	defer x.Close()
+	if stats := x.Stats(); stats.Files != 2 || stats.Declarations != 3 || stats.Stale != 1 {
+		t.Errorf("Expected the persisted index, got %+v", stats)
+	}
+	changes, err = x.Sync(ctx, []string{"vendor/"})
+	if err != nil || changes != (Changes{Removed: 1}) {
+		t.Errorf("Expected only the removal, got %+v, %v", changes, err)
+	}
+	if results, _ := x.Search(ctx, "write file to disk", 10); len(results) != 0 {
+		t.Errorf("Expected the removed file to be gone, got %+v", results)
+	}
+}
+
+func TestIndexCompaction(t *testing.T) {
+	repo := t.TempDir()
+	logPath := filepath.Join(t.TempDir(), "index.log")
+	ctx := context.Background()
+	x, err := Open(repo, logPath, NewHashEmbedder(64))
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	defer x.Close()
+
+	// Rewriting a file supersedes its records until compaction drops them
+	for i := range minStale + 2 {
+		writeFile(t, repo, "counter.go", fmt.Sprintf("package counter\n\n// Count is %d\nconst Count = %d\n", i, i))
+		if _, err := x.Update(ctx, []string{"counter.go"}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if stats := x.Stats(); stats.Files != 1 || stats.Stale >= minStale {
+		t.Errorf("Expected the log to be compacted, got %+v", stats)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines >= minStale {
+		t.Errorf("Expected a compacted log, got %d lines", lines)
+	}
+
+	// A record cut short by a crash is dropped; the rest is kept
+	x.Close()
+	if err := os.WriteFile(logPath, append(data, `{"file":"other.go","ha`...), 0644); err != nil {
+		t.Fatalf("Failed to damage log: %v", err)
+	}
+	x, err = Open(repo, logPath, NewHashEmbedder(64))
+	if err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	if stats := x.Stats(); stats.Files != 1 || stats.Declarations != 1 {
+		t.Errorf("Expected the intact records, got %+v", stats)
+	}
+	x.Close()
+
+	// A log of another embedder is discarded
+	x, err = Open(repo, logPath, NewHashEmbedder(128))
+	if err != nil {
+		t.Fatalf("Failed to reopen index: %v", err)
+	}
+	if stats := x.Stats(); stats.Files != 0 {
+		t.Errorf("Expected an empty index, got %+v", stats)
+	}
+}
+
+func TestIndexWatch(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, repo, "shapes.go", "package shapes\n\n// Circle is round\ntype Circle struct{}\n")
+	x, err := Open(repo, "", NewHashEmbedder(256))
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Changes, 10)
+	go x.Watch(ctx, 10*time.Millisecond, nil, func(changes Changes, err error) {
+		if err != nil {
+			t.Errorf("Update failed: %v", err)
+		}
+		updates <- changes
+	})
+	if changes := <-updates; changes.Updated != 1 {
+		t.Fatalf("Expected the sync to index the file, got %+v", changes)
+	}
+
+	writeFile(t, repo, "polygons.go", "package shapes\n\n// Triangle has three sides\ntype Triangle struct{}\n")
+	select {
+	case changes := <-updates:
+		if changes.Updated != 1 || changes.Embedded != 1 {
+			t.Errorf("Expected the new file to be indexed, got %+v", changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The new file was not indexed")
+	}
+	results, err := x.Search(ctx, "triangle", 1)
+	if err != nil || len(results) != 1 || results[0].Name != "Triangle" {
+		t.Errorf("Expected Triangle, got %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+	"github.com/TFMV/scope/internal/watch"
+)
+
+// Pin is a symbol the client asked to keep in its working set, together with
+// its most recently resolved definition
+type Pin struct {
+	Name       string             `json:"name"`
+	Definition *analyzer.TypeInfo `json:"definition,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	PinnedAt   time.Time          `json:"pinned_at"`
+	Refreshed  time.Time          `json:"refreshed"`
+}
+
+// PinSet holds the symbols pinned during a session. Definitions are resolved
+// when pinned and re-resolved whenever the repository changes, so they stay
+// warm and current for the whole session.
+type PinSet struct {
+	analyzer *analyzer.Analyzer
+	mu       sync.RWMutex
+	pins     map[string]*Pin
+}
+
+// NewPinSet creates an empty pin set backed by an analyzer
+func NewPinSet(a *analyzer.Analyzer) *PinSet {
+	return &PinSet{
+		analyzer: a,
+		pins:     make(map[string]*Pin),
+	}
+}
+
+// Pin adds a symbol to the set and returns its resolved pin. Symbols that
+// cannot be resolved are rejected.
+func (p *PinSet) Pin(ctx context.Context, name string) (*Pin, error) {
+	definition, err := p.analyzer.LookupType(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	pin, ok := p.pins[name]
+	if !ok {
+		pin = &Pin{Name: name, PinnedAt: now}
+		p.pins[name] = pin
+	}
+	pin.Definition = definition
+	pin.Error = ""
+	pin.Refreshed = now
+	copied := *pin
+	return &copied, nil
+}
+
+// Unpin removes a symbol from the set
+func (p *PinSet) Unpin(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pins[name]; !ok {
+		return fmt.Errorf("%s is not pinned", name)
+	}
+	delete(p.pins, name)
+	return nil
+}
+
+// List returns the pinned symbols sorted by name
+func (p *PinSet) List() []Pin {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	pins := make([]Pin, 0, len(p.pins))
+	for _, pin := range p.pins {
+		pins = append(pins, *pin)
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Name < pins[j].Name })
+	return pins
+}
+
+// Len returns the number of pinned symbols
+func (p *PinSet) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.pins)
+}
+
+// Refresh re-resolves every pinned definition. Symbols that no longer
+// resolve stay pinned with their last definition and an error, so they
+// recover once the code compiles again.
+func (p *PinSet) Refresh(ctx context.Context) {
+	p.mu.RLock()
+	names := make([]string, 0, len(p.pins))
+	for name := range p.pins {
+		names = append(names, name)
+	}
+	p.mu.RUnlock()
+
+	for _, name := range names {
+		definition, err := p.analyzer.LookupType(ctx, name)
+
+		p.mu.Lock()
+		if pin, ok := p.pins[name]; ok {
+			pin.Refreshed = time.Now()
+			if err != nil {
+				pin.Error = err.Error()
+			} else {
+				pin.Definition = definition
+				pin.Error = ""
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// setError records an error on every pin, keeping their last definitions
+func (p *PinSet) setError(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pin := range p.pins {
+		pin.Error = message
+	}
+}
+
+// Watch polls root for source changes while symbols are pinned, refreshing
+// the analyzer and the pinned definitions when files change. It returns when
+// ctx is cancelled.
+func (p *PinSet) Watch(ctx context.Context, root string, interval time.Duration, exclude []string) error {
+	watcher := watch.New(root, interval, exclude)
+	return watcher.Run(ctx, func(changed []string) {
+		if p.Len() == 0 {
+			return
+		}
+		if err := p.analyzer.Refresh(ctx); err != nil {
+			p.setError(fmt.Sprintf("analyzer refresh failed: %v", err))
+			return
+		}
+		p.Refresh(ctx)
+	})
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TFMV/scope/internal/analyzer"
+)
+
+func TestPinSet(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "shapes.go")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	write("package shapes\n\n// Circle is round\ntype Circle struct {\n\tRadius float64\n}\n")
+
+	a, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	pins := NewPinSet(a)
+
+	pin, err := pins.Pin(context.Background(), "Circle")
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if pin.Definition == nil || len(pin.Definition.Fields) != 1 {
+		t.Fatalf("Expected resolved definition, got %+v", pin)
+	}
+	if _, err := pins.Pin(context.Background(), "Square"); err == nil {
+		t.Error("Expected error pinning an unknown symbol")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pins.Watch(ctx, dir, 10*time.Millisecond, nil)
+
+	// Let the watcher take its initial snapshot before changing the file
+	time.Sleep(50 * time.Millisecond)
+	write("package shapes\n\n// Circle is round\ntype Circle struct {\n\tRadius float64\n\tCenter [2]float64\n}\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list := pins.List()
+		if len(list) == 1 && list[0].Definition != nil && len(list[0].Definition.Fields) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Pinned definition was not refreshed: %+v", list)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := pins.Unpin("Circle"); err != nil {
+		t.Fatalf("Unpin failed: %v", err)
+	}
+	if err := pins.Unpin("Circle"); err == nil {
+		t.Error("Expected error unpinning a symbol that is not pinned")
+	}
+	if pins.Len() != 0 {
+		t.Errorf("Expected no pins, got %d", pins.Len())
+	}
+}
+
+func TestRefreshKeepsLastDefinition(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(file, []byte("package a\n\ntype Thing struct{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	a, err := analyzer.NewAnalyzer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	pins := NewPinSet(a)
+	if _, err := pins.Pin(context.Background(), "Thing"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	if err := os.WriteFile(file, []byte("package a\n\ntype Other struct{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := a.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	pins.Refresh(context.Background())
+
+	list := pins.List()
+	if len(list) != 1 || list[0].Error == "" || list[0].Definition == nil || list[0].Definition.Name != "Thing" {
+		t.Errorf("Expected the pin to keep its last definition with an error, got %+v", list)
+	}
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Output formats of tool responses
+const (
+	FormatJSON     = "json"
+	FormatIndented = "indented"
+)
+
+// Preferences are sticky defaults set once per session. Tools opt in per
+// argument with a session struct tag naming the preference, such as
+// `session:"package"`; the preference fills the argument when a call leaves
+// it unset.
+type Preferences struct {
+	// Package is the default package qualifier (import path or package
+	// name) of tools scoped to a package
+	Package string `json:"package,omitempty"`
+	// ExportedOnly leaves unexported declarations out of tools that list
+	// them
+	ExportedOnly bool `json:"exported_only,omitempty"`
+	// Limit is the default maximum number of results
+	Limit int `json:"limit,omitempty"`
+	// Format is json (compact, the default) or indented
+	Format string `json:"format,omitempty"`
+}
+
+// Validate checks the values of the preferences
+func (p Preferences) Validate() error {
+	if p.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	switch p.Format {
+	case "", FormatJSON, FormatIndented:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (expected %s or %s)", p.Format, FormatJSON, FormatIndented)
+}
+
+// PreferenceSet holds the preferences of the session
+type PreferenceSet struct {
+	mu    sync.RWMutex
+	prefs Preferences
+}
+
+// NewPreferenceSet creates a preference set with nothing set
+func NewPreferenceSet() *PreferenceSet {
+	return &PreferenceSet{}
+}
+
+// Get returns the current preferences
+func (s *PreferenceSet) Get() Preferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.prefs
+}
+
+// Update sets the non-zero preferences of update and clears those named in
+// unset by their JSON names, returning the result. Nothing changes when the
+// result is invalid.
+func (s *PreferenceSet) Update(update Preferences, unset []string) (Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.prefs
+	nextValue := reflect.ValueOf(&next).Elem()
+	for _, name := range unset {
+		field, ok := preferenceField(name)
+		if !ok {
+			return s.prefs, fmt.Errorf("unknown preference %q", name)
+		}
+		nextValue.FieldByIndex(field.Index).SetZero()
+	}
+	updateValue := reflect.ValueOf(update)
+	for i := range updateValue.NumField() {
+		if value := updateValue.Field(i); !value.IsZero() {
+			nextValue.Field(i).Set(value)
+		}
+	}
+	if err := next.Validate(); err != nil {
+		return s.prefs, err
+	}
+	s.prefs = next
+	return next, nil
+}
+
+// Apply fills the unset fields of the struct args points to from the
+// preferences named by their session tags, and returns the names of the
+// preferences it applied
+func (s *PreferenceSet) Apply(args any) []string {
+	v := reflect.ValueOf(args)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	prefs := reflect.ValueOf(s.Get())
+
+	var applied []string
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Tag.Get("session")
+		if name == "" || !v.Field(i).IsZero() {
+			continue
+		}
+		field, ok := preferenceField(name)
+		if !ok {
+			continue
+		}
+		value := prefs.FieldByIndex(field.Index)
+		if value.IsZero() || !value.Type().AssignableTo(v.Field(i).Type()) {
+			continue
+		}
+		v.Field(i).Set(value)
+		applied = append(applied, name)
+	}
+	return applied
+}
+
+// preferenceField finds the field of Preferences with the given JSON name
+func preferenceField(name string) (reflect.StructField, bool) {
+	t := reflect.TypeFor[Preferences]()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPreferenceSet(t *testing.T) {
+	prefs := NewPreferenceSet()
+	got, err := prefs.Update(Preferences{Package: "cache", Limit: 10}, nil)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got != (Preferences{Package: "cache", Limit: 10}) {
+		t.Errorf("Unexpected preferences: %+v", got)
+	}
+
+	// Unset values keep their preference; cleared ones are reset first
+	got, err = prefs.Update(Preferences{ExportedOnly: true}, []string{"limit"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got != (Preferences{Package: "cache", ExportedOnly: true}) {
+		t.Errorf("Unexpected preferences: %+v", got)
+	}
+
+	for _, tt := range []struct {
+		update Preferences
+		unset  []string
+	}{
+		{Preferences{Format: "xml"}, nil},
+		{Preferences{Limit: -1}, nil},
+		{Preferences{}, []string{"repository"}},
+	} {
+		if _, err := prefs.Update(tt.update, tt.unset); err == nil {
+			t.Errorf("Expected an error for %+v clearing %v", tt.update, tt.unset)
+		}
+	}
+	if prefs.Get() != got {
+		t.Errorf("Expected a failed update to change nothing, got %+v", prefs.Get())
+	}
+
+	type args struct {
+		Package  string `session:"package"`
+		Exported bool   `session:"exported_only"`
+		Limit    int    `session:"limit"`
+		Pattern  string `session:"package_pattern"`
+		Other    string
+	}
+	a := args{Package: "analyzer"}
+	applied := prefs.Apply(&a)
+	if a != (args{Package: "analyzer", Exported: true}) {
+		t.Errorf("Expected only unset arguments with set preferences to be filled, got %+v", a)
+	}
+	if !slices.Equal(applied, []string{"exported_only"}) {
+		t.Errorf("Expected exported_only to be applied, got %v", applied)
+	}
+	if applied := prefs.Apply(a); applied != nil {
+		t.Errorf("Expected nothing applied to a non-pointer, got %v", applied)
+	}
+}
@@ -0,0 +1,167 @@
+// Package spill keeps tool results that are too large to return in one
+// message. The full text is written to a file and read back in chunks with a
+// continuation cursor.
+package spill
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// URIScheme prefixes the resource URI of a spilled result
+const URIScheme = "scope://spill/"
+
+// Entry describes a spilled result
+type Entry struct {
+	ID   string `json:"id"`
+	URI  string `json:"resource"`
+	Path string `json:"file"`
+	Size int    `json:"total_bytes"`
+}
+
+// Store writes spilled results to a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir, creating the directory if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Write saves text and returns its entry. Entries are named by content, so
+// spilling the same result twice reuses the file.
+func (s *Store) Write(text string) (*Entry, error) {
+	sum := sha256.Sum256([]byte(text))
+	id := hex.EncodeToString(sum[:8])
+	entry := &Entry{ID: id, URI: URIScheme + id, Path: s.path(id), Size: len(text)}
+
+	if info, err := os.Stat(entry.Path); err == nil && info.Size() == int64(len(text)) {
+		// Touch the file so Prune keeps it
+		now := time.Now()
+		_ = os.Chtimes(entry.Path, now, now)
+		return entry, nil
+	}
+	tmp := entry.Path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write spilled result: %w", err)
+	}
+	if err := os.Rename(tmp, entry.Path); err != nil {
+		return nil, fmt.Errorf("failed to write spilled result: %w", err)
+	}
+	return entry, nil
+}
+
+// Load returns the full text of a spilled result
+func (s *Store) Load(id string) (string, error) {
+	if !validID(id) {
+		return "", fmt.Errorf("invalid spill id %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("spilled result %s not found; it may have expired", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read spilled result: %w", err)
+	}
+	return string(data), nil
+}
+
+// Read returns up to limit bytes of a spilled result starting at cursor,
+// and the cursor of the next chunk, or "" after the last chunk. Chunks never
+// split a UTF-8 sequence.
+func (s *Store) Read(cursor string, limit int) (string, string, error) {
+	id, offset, err := ParseCursor(cursor)
+	if err != nil {
+		return "", "", err
+	}
+	text, err := s.Load(id)
+	if err != nil {
+		return "", "", err
+	}
+	if offset > len(text) {
+		return "", "", fmt.Errorf("cursor %s is past the end of the result", cursor)
+	}
+
+	chunk := Truncate(text[offset:], limit)
+	end := offset + len(chunk)
+	if end >= len(text) {
+		return chunk, "", nil
+	}
+	return chunk, Cursor(id, end), nil
+}
+
+// Prune removes spilled results older than maxAge and returns how many were
+// removed
+func (s *Store) Prune(maxAge time.Duration) (int, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.txt"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list spilled results: %w", err)
+	}
+	removed := 0
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.Remove(file); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file holding a spilled result
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".txt")
+}
+
+// Cursor encodes the position of the next chunk of a spilled result
+func Cursor(id string, offset int) string {
+	return id + ":" + strconv.Itoa(offset)
+}
+
+// ParseCursor decodes a cursor returned by Read or Cursor
+func ParseCursor(cursor string) (string, int, error) {
+	id, offset, ok := strings.Cut(cursor, ":")
+	n, err := strconv.Atoi(offset)
+	if !ok || err != nil || n < 0 || !validID(id) {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return id, n, nil
+}
+
+// Truncate returns the longest prefix of text of at most limit bytes that
+// does not end inside a UTF-8 sequence
+func Truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+// validID reports whether id looks like an id produced by Write, so that a
+// cursor can never name a file outside the store
+func validID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package spill
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	text := strings.Repeat("héllo ", 10)
+	entry, err := store.Write(text)
+	if err != nil {
+		t.Fatalf("Failed to spill: %v", err)
+	}
+	if entry.Size != len(text) || entry.URI != URIScheme+entry.ID {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if again, err := store.Write(text); err != nil || again.ID != entry.ID {
+		t.Errorf("Expected identical results to share an entry, got %+v (%v)", again, err)
+	}
+
+	// Reassemble the result chunk by chunk
+	var got strings.Builder
+	cursor := Cursor(entry.ID, 0)
+	for i := 0; cursor != ""; i++ {
+		if i > len(text) {
+			t.Fatal("Cursor never reached the end")
+		}
+		chunk, next, err := store.Read(cursor, 7)
+		if err != nil {
+			t.Fatalf("Failed to read chunk: %v", err)
+		}
+		if len(chunk) > 7 || !strings.HasPrefix(text[got.Len():], chunk) {
+			t.Fatalf("Unexpected chunk %q", chunk)
+		}
+		got.WriteString(chunk)
+		cursor = next
+	}
+	if got.String() != text {
+		t.Errorf("Expected %q, got %q", text, got.String())
+	}
+
+	for _, cursor := range []string{"", "nope", entry.ID, "../../etc/passwd:0", entry.ID + ":-1", entry.ID + ":9999"} {
+		if _, _, err := store.Read(cursor, 10); err == nil {
+			t.Errorf("Expected error for cursor %q", cursor)
+		}
+	}
+	if _, _, err := store.Read(Cursor("0123456789abcdef", 0), 10); err == nil {
+		t.Error("Expected error for a missing result")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("héllo", 2); got != "h" {
+		t.Errorf("Expected truncation before a multi-byte rune, got %q", got)
+	}
+	if got := Truncate("héllo", 3); got != "hé" {
+		t.Errorf("Expected hé, got %q", got)
+	}
+	if got := Truncate("abc", 10); got != "abc" {
+		t.Errorf("Expected short text unchanged, got %q", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	old, err := store.Write("old")
+	if err != nil {
+		t.Fatalf("Failed to spill: %v", err)
+	}
+	if _, err := store.Write("new"); err != nil {
+		t.Fatalf("Failed to spill: %v", err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(old.Path, past, past); err != nil {
+		t.Fatalf("Failed to age file: %v", err)
+	}
+
+	removed, err := store.Prune(time.Hour)
+	if err != nil || removed != 1 {
+		t.Errorf("Expected one pruned result, got %d (%v)", removed, err)
+	}
+	if _, err := store.Load(old.ID); err == nil {
+		t.Error("Expected pruned result to be gone")
+	}
+}
@@ -2,7 +2,7 @@ package tools
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 )
@@ -15,31 +15,78 @@ type ToolConfig struct {
 	Args        []string          `json:"args"`
 	Env         map[string]string `json:"env"`
 	Timeout     int               `json:"timeout"` // in seconds
+	// WorkDir is the directory the tool runs in. Relative paths are resolved
+	// against the tool manager's base directory (the analyzed repository),
+	// which is also the default.
+	WorkDir string `json:"work_dir,omitempty"`
+	// InheritEnv lists the server environment variables passed to the tool
+	// besides PATH and HOME, which are always passed. A trailing * matches
+	// a prefix (GO*). Env entries take precedence.
+	InheritEnv []string `json:"inherit_env,omitempty"`
+	// MaxOutput caps the captured output in bytes; longer output is
+	// truncated. Zero means unlimited.
+	MaxOutput int `json:"max_output,omitempty"`
+	// NoNetwork runs the tool without network access, in a new network
+	// namespace (Linux only)
+	NoNetwork bool `json:"no_network,omitempty"`
+	// MaxConcurrency is how many calls of the tool may run at once; further
+	// calls queue. Zero means one.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// RateLimit caps the calls started per minute; calls over the limit
+	// queue until the window allows them. Zero means unlimited.
+	RateLimit int `json:"rate_limit,omitempty"`
+	// QueueTimeout is how long a call may wait in the queue, in seconds,
+	// before failing with ErrQueueTimeout. Zero waits as long as the
+	// caller's context allows.
+	QueueTimeout int `json:"queue_timeout,omitempty"`
+	// CacheTTL is how long, in seconds, a successful output is reused for
+	// calls with the same input instead of running the tool again. Zero
+	// disables caching.
+	CacheTTL int `json:"cache_ttl,omitempty"`
 }
 
 // ToolsConfig represents the configuration for all tools
 type ToolsConfig struct {
 	Tools []ToolConfig `json:"tools"`
+	// Pipelines chain the tools into sequences run by one call
+	Pipelines []PipelineConfig `json:"pipelines,omitempty"`
 }
 
-// LoadToolsConfig loads the tools configuration from a JSON file
-func LoadToolsConfig(configPath string) (*ToolsConfig, error) {
-	// Default config path if none provided
+// Validate checks the configuration's pipelines
+func (c *ToolsConfig) Validate() error {
+	for _, pipeline := range c.Pipelines {
+		if err := pipeline.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfigPath resolves the tools configuration file LoadToolsConfig reads
+// for configPath: ~/.featherhead/tools.json when it is empty, and
+// tools.json inside it when it is a directory
+func ConfigPath(configPath string) (string, error) {
 	if configPath == "" {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			return nil, err
-		}
-		configPath = filepath.Join(homeDir, ".featherhead", "tools.json")
-	} else {
-		// If configPath is a directory, append tools.json
-		if info, err := os.Stat(configPath); err == nil && info.IsDir() {
-			configPath = filepath.Join(configPath, "tools.json")
+			return "", err
 		}
+		return filepath.Join(homeDir, ".featherhead", "tools.json"), nil
+	}
+	if info, err := os.Stat(configPath); err == nil && info.IsDir() {
+		return filepath.Join(configPath, "tools.json"), nil
+	}
+	return configPath, nil
+}
+
+// LoadToolsConfig loads the tools configuration from a JSON file
+func LoadToolsConfig(configPath string) (*ToolsConfig, error) {
+	configPath, err := ConfigPath(configPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Debug log the final path
-	fmt.Printf("Loading tools config from: %s\n", configPath)
+	slog.Debug("Loading tools config", "path", configPath)
 
 	// Create default config if it doesn't exist
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -88,6 +135,12 @@ func LoadToolsConfig(configPath string) (*ToolsConfig, error) {
 	}
 
 	// Read existing config
+	return ReadToolsConfig(configPath)
+}
+
+// ReadToolsConfig reads an existing tools configuration file. Unlike
+// LoadToolsConfig it never creates one.
+func ReadToolsConfig(configPath string) (*ToolsConfig, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, err
@@ -97,6 +150,9 @@ func LoadToolsConfig(configPath string) (*ToolsConfig, error) {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueTimeout is returned when a call waited longer than the tool's
+// queue timeout for a free slot or for the rate limit to allow it
+var ErrQueueTimeout = errors.New("timed out waiting in the tool queue")
+
+// rateWindow is the period RateLimit counts calls over
+const rateWindow = time.Minute
+
+// rateLimiter admits at most max calls per window, using a sliding log of
+// the start times of recent calls
+type rateLimiter struct {
+	max    int
+	window time.Duration
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+// newRateLimiter returns a limiter for max calls per window, or nil when
+// max is not positive
+func newRateLimiter(max int, window time.Duration) *rateLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &rateLimiter{max: max, window: window}
+}
+
+// wait blocks until a call may start and records it, or returns the
+// context's error. A nil limiter admits every call.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		for len(l.starts) > 0 && now.Sub(l.starts[0]) >= l.window {
+			l.starts = l.starts[1:]
+		}
+		if len(l.starts) < l.max {
+			l.starts = append(l.starts, now)
+			l.mu.Unlock()
+			return nil
+		}
+		delay := l.starts[0].Add(l.window).Sub(now)
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire waits for a free slot and for the rate limit, within the queue
+// timeout and the caller's context. It returns a function releasing the slot.
+func (t *Tool) acquire(ctx context.Context) (func(), error) {
+	if t.config.QueueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(t.config.QueueTimeout)*time.Second, ErrQueueTimeout)
+		defer cancel()
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, queueError(ctx)
+	}
+	release := func() { <-t.slots }
+
+	if err := t.limiter.wait(ctx); err != nil {
+		release()
+		return nil, queueError(ctx)
+	}
+	return release, nil
+}
+
+// queueError reports why waiting in the queue stopped: ErrQueueTimeout
+// when the queue timeout expired, the context's error otherwise
+func queueError(ctx context.Context) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrQueueTimeout) {
+		return cause
+	}
+	return ctx.Err()
+}
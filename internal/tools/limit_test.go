@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	run := func(config ToolConfig, calls int) time.Duration {
+		tool := NewTool(config)
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < calls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tool.Execute(context.Background(), ""); err != nil {
+					t.Errorf("Execute failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+
+	sleep := ToolConfig{Name: "sleep", Command: "sleep", Args: []string{"0.3"}}
+	if elapsed := run(sleep, 2); elapsed < 600*time.Millisecond {
+		t.Errorf("Expected calls to serialize by default, took %v", elapsed)
+	}
+	sleep.MaxConcurrency = 2
+	if elapsed := run(sleep, 2); elapsed >= 600*time.Millisecond {
+		t.Errorf("Expected two calls to run at once, took %v", elapsed)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	tool := NewTool(ToolConfig{Name: "sleep", Command: "sleep", Args: []string{"2"}, QueueTimeout: 1})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tool.Execute(context.Background(), "")
+	}()
+	// Let the first call take the only slot
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := tool.Execute(context.Background(), "")
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Expected ErrQueueTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tool.Execute(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled call to stop waiting, got %v", err)
+	}
+	<-done
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, 200*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(ctx); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the third call to wait for the window, took %v", elapsed)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	limiter.wait(ctx)
+	if err := limiter.wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with its context, got %v", err)
+	}
+
+	if newRateLimiter(0, time.Minute) != nil {
+		t.Error("Expected no limiter without a limit")
+	}
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TFMV/scope/internal/tracing"
+)
+
+// PipelineConfig is a named sequence of tools run as one call, each step's
+// output feeding the next
+type PipelineConfig struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Steps       []PipelineStep `json:"steps"`
+}
+
+// PipelineStep runs one tool of a pipeline
+type PipelineStep struct {
+	// Name identifies the step's output to later steps; it defaults to the
+	// tool's name
+	Name string `json:"name,omitempty"`
+	Tool string `json:"tool"`
+	// Input is a text/template rendering the tool's input from the
+	// pipeline's .Input, the .Previous step's output and the outputs of
+	// earlier steps by name, as in {{.Steps.search}}. Empty passes the
+	// previous output on, or the pipeline's input to the first step.
+	Input string `json:"input,omitempty"`
+}
+
+// PipelineData is what a step's input template is rendered with
+type PipelineData struct {
+	Input    string
+	Previous string
+	Steps    map[string]string
+}
+
+// PipelineResult is the outcome of a pipeline run
+type PipelineResult struct {
+	Pipeline string       `json:"pipeline"`
+	Steps    []StepResult `json:"steps"`
+	// Output is the last step's output
+	Output string `json:"output"`
+}
+
+// StepResult is the input and output of one step of a pipeline run
+type StepResult struct {
+	Name     string `json:"name"`
+	Tool     string `json:"tool"`
+	Input    string `json:"input"`
+	Output   string `json:"output"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// pipelineFuncs are the functions input templates may call
+var pipelineFuncs = template.FuncMap{
+	"trim":  strings.TrimSpace,
+	"lines": func(s string) []string { return strings.Split(strings.TrimRight(s, "\n"), "\n") },
+}
+
+// stepName returns the name a step's output is known by
+func (s PipelineStep) stepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Tool
+}
+
+// Validate checks that the pipeline has steps, that step names are unique
+// and that every input template parses. Whether the tools exist is checked
+// when the pipeline runs, since tools may be reloaded in the meantime.
+func (p PipelineConfig) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("pipeline name is required")
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("pipeline %s has no steps", p.Name)
+	}
+	seen := make(map[string]bool)
+	for i, step := range p.Steps {
+		if step.Tool == "" {
+			return fmt.Errorf("pipeline %s step %d has no tool", p.Name, i+1)
+		}
+		name := step.stepName()
+		if seen[name] {
+			return fmt.Errorf("pipeline %s has more than one step named %s", p.Name, name)
+		}
+		seen[name] = true
+		if _, err := parseStepInput(step); err != nil {
+			return fmt.Errorf("pipeline %s step %s: %w", p.Name, name, err)
+		}
+	}
+	return nil
+}
+
+// parseStepInput parses a step's input template; nil means the step takes
+// the previous output as it is
+func parseStepInput(step PipelineStep) (*template.Template, error) {
+	if step.Input == "" {
+		return nil, nil
+	}
+	return template.New(step.stepName()).Funcs(pipelineFuncs).Option("missingkey=error").Parse(step.Input)
+}
+
+// RegisterPipeline registers a pipeline, replacing any pipeline of the
+// same name
+func (tm *ToolManager) RegisterPipeline(config PipelineConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.pipelines[config.Name] = config
+	return nil
+}
+
+// GetPipeline returns a pipeline by name
+func (tm *ToolManager) GetPipeline(name string) (PipelineConfig, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	pipeline, ok := tm.pipelines[name]
+	return pipeline, ok
+}
+
+// ListPipelines returns the names of all registered pipelines
+func (tm *ToolManager) ListPipelines() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	pipelines := make([]string, 0, len(tm.pipelines))
+	for name := range tm.pipelines {
+		pipelines = append(pipelines, name)
+	}
+	return pipelines
+}
+
+// ExecutePipeline runs the named pipeline's steps in order with input as
+// the pipeline's input. The first failing step stops the run; the result
+// then holds the steps run so far, the last with its error.
+func (tm *ToolManager) ExecutePipeline(ctx context.Context, name, input string) (*PipelineResult, error) {
+	pipeline, ok := tm.GetPipeline(name)
+	if !ok {
+		return nil, fmt.Errorf("pipeline %s not found", name)
+	}
+	ctx, span := tracing.Start(ctx, "pipeline "+name, tracing.KindInternal, tracing.String("scope.pipeline", name))
+	defer span.End()
+
+	result := &PipelineResult{Pipeline: name, Steps: []StepResult{}}
+	data := PipelineData{Input: input, Previous: input, Steps: make(map[string]string)}
+	for _, step := range pipeline.Steps {
+		stepResult, err := tm.executeStep(ctx, step, data)
+		result.Steps = append(result.Steps, stepResult)
+		if err != nil {
+			err = fmt.Errorf("pipeline %s step %s: %w", name, stepResult.Name, err)
+			span.SetError(err)
+			return result, err
+		}
+		data.Previous = stepResult.Output
+		data.Steps[stepResult.Name] = stepResult.Output
+	}
+	result.Output = data.Previous
+	return result, nil
+}
+
+// executeStep renders a step's input and runs its tool
+func (tm *ToolManager) executeStep(ctx context.Context, step PipelineStep, data PipelineData) (StepResult, error) {
+	stepResult := StepResult{Name: step.stepName(), Tool: step.Tool, Input: data.Previous}
+	fail := func(err error) (StepResult, error) {
+		stepResult.Error = err.Error()
+		return stepResult, err
+	}
+
+	tmpl, err := parseStepInput(step)
+	if err != nil {
+		return fail(err)
+	}
+	if tmpl != nil {
+		var input strings.Builder
+		if err := tmpl.Execute(&input, data); err != nil {
+			return fail(fmt.Errorf("failed to render input: %w", err))
+		}
+		stepResult.Input = input.String()
+	}
+	tool, ok := tm.GetTool(step.Tool)
+	if !ok {
+		return fail(fmt.Errorf("tool %s not found", step.Tool))
+	}
+
+	start := time.Now()
+	output, err := tool.Execute(ctx, stepResult.Input)
+	stepResult.Duration = time.Since(start).String()
+	if err != nil {
+		return fail(err)
+	}
+	stepResult.Output = output
+	return stepResult, nil
+}
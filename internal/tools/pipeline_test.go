@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExecutePipeline(t *testing.T) {
+	tm := NewToolManager()
+	tm.RegisterTool(ToolConfig{Name: "upper", Command: "tr", Args: []string{"a-z", "A-Z"}})
+	tm.RegisterTool(ToolConfig{Name: "count", Command: "wc", Args: []string{"-l"}})
+	err := tm.RegisterPipeline(PipelineConfig{
+		Name: "shout",
+		Steps: []PipelineStep{
+			{Tool: "upper"},
+			{Name: "both", Tool: "upper", Input: "{{.Input}}|{{.Previous}}"},
+			{Tool: "count", Input: "{{range lines .Steps.upper}}{{.}}\n{{end}}{{.Steps.both}}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register pipeline: %v", err)
+	}
+
+	result, err := tm.ExecutePipeline(context.Background(), "shout", "one\ntwo")
+	if err != nil {
+		t.Fatalf("ExecutePipeline failed: %v", err)
+	}
+	if len(result.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %+v", result.Steps)
+	}
+	if result.Steps[0].Input != "one\ntwo" || result.Steps[0].Output != "ONE\nTWO" {
+		t.Errorf("Expected the first step to get the pipeline's input, got %+v", result.Steps[0])
+	}
+	if result.Steps[1].Output != "ONE\nTWO|ONE\nTWO" {
+		t.Errorf("Expected the second step to get the rendered input, got %+v", result.Steps[1])
+	}
+	if strings.TrimSpace(result.Output) != "4" {
+		t.Errorf("Expected the last step's output, got %q", result.Output)
+	}
+
+	// A failing step stops the pipeline
+	tm.RegisterTool(ToolConfig{Name: "fail", Command: "false"})
+	if err := tm.RegisterPipeline(PipelineConfig{Name: "broken", Steps: []PipelineStep{{Tool: "fail"}, {Tool: "upper"}}}); err != nil {
+		t.Fatalf("Failed to register pipeline: %v", err)
+	}
+	result, err = tm.ExecutePipeline(context.Background(), "broken", "")
+	if err == nil || !strings.Contains(err.Error(), "step fail") {
+		t.Errorf("Expected the failing step in the error, got %v", err)
+	}
+	if result == nil || len(result.Steps) != 1 || result.Steps[0].Error == "" {
+		t.Errorf("Expected only the failed step, got %+v", result)
+	}
+
+	if _, err := tm.ExecutePipeline(context.Background(), "missing", ""); err == nil {
+		t.Error("Expected error for an unknown pipeline")
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	invalid := map[string]PipelineConfig{
+		"no name":        {Steps: []PipelineStep{{Tool: "a"}}},
+		"no steps":       {Name: "p"},
+		"no tool":        {Name: "p", Steps: []PipelineStep{{Input: "x"}}},
+		"duplicate step": {Name: "p", Steps: []PipelineStep{{Tool: "a"}, {Tool: "a"}}},
+		"bad template":   {Name: "p", Steps: []PipelineStep{{Tool: "a", Input: "{{.Input"}}},
+	}
+	for name, pipeline := range invalid {
+		if err := pipeline.Validate(); err == nil {
+			t.Errorf("Expected %s to be invalid", name)
+		}
+	}
+	valid := PipelineConfig{Name: "p", Steps: []PipelineStep{{Tool: "a"}, {Name: "again", Tool: "a", Input: "{{.Steps.a}}"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid pipeline, got %v", err)
+	}
+}
+
+func TestReloadPipelines(t *testing.T) {
+	tm := NewToolManager()
+	result := tm.Apply(&ToolsConfig{
+		Tools:     []ToolConfig{{Name: "echo", Command: "echo"}},
+		Pipelines: []PipelineConfig{{Name: "p", Steps: []PipelineStep{{Tool: "echo"}}}},
+	})
+	if !result.Changed() || len(result.Pipelines) != 1 {
+		t.Errorf("Expected the pipeline to be registered, got %+v", result)
+	}
+	result = tm.Apply(&ToolsConfig{Tools: []ToolConfig{{Name: "echo", Command: "echo"}}})
+	if !result.Changed() {
+		t.Error("Expected removing the pipeline to count as a change")
+	}
+	if _, ok := tm.GetPipeline("p"); ok {
+		t.Error("Expected the pipeline to be removed")
+	}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ReloadResult lists the tools a configuration reload changed
+type ReloadResult struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+	// Pipelines are the pipelines registered after the reload
+	Pipelines []string `json:"pipelines,omitempty"`
+
+	pipelinesChanged bool
+}
+
+// Changed reports whether the reload added, updated or removed a tool or
+// changed the pipelines
+func (r *ReloadResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Removed) > 0 || r.pipelinesChanged
+}
+
+// Apply makes the registered tools match config: tools it adds are
+// registered, tools whose configuration changed are replaced and tools it
+// no longer lists are unregistered. When a name appears more than once the
+// last entry wins, as with RegisterTool. The pipelines are replaced by the
+// configuration's, which the caller has validated.
+func (tm *ToolManager) Apply(config *ToolsConfig) *ReloadResult {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	result := &ReloadResult{Added: []string{}, Updated: []string{}, Removed: []string{}, Unchanged: []string{}}
+	wanted := make(map[string]ToolConfig)
+	for _, toolConfig := range config.Tools {
+		wanted[toolConfig.Name] = tm.resolve(toolConfig)
+	}
+
+	for name, toolConfig := range wanted {
+		current, ok := tm.tools[name]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, name)
+		case reflect.DeepEqual(current.config, toolConfig):
+			result.Unchanged = append(result.Unchanged, name)
+			continue
+		default:
+			result.Updated = append(result.Updated, name)
+		}
+		tm.tools[name] = NewTool(toolConfig)
+	}
+	for name := range tm.tools {
+		if _, ok := wanted[name]; !ok {
+			delete(tm.tools, name)
+			result.Removed = append(result.Removed, name)
+		}
+	}
+
+	pipelines := make(map[string]PipelineConfig)
+	for _, pipeline := range config.Pipelines {
+		pipelines[pipeline.Name] = pipeline
+	}
+	for name := range pipelines {
+		result.Pipelines = append(result.Pipelines, name)
+	}
+	result.pipelinesChanged = !reflect.DeepEqual(tm.pipelines, pipelines)
+	tm.pipelines = pipelines
+
+	for _, names := range [][]string{result.Added, result.Updated, result.Removed, result.Unchanged, result.Pipelines} {
+		sort.Strings(names)
+	}
+	return result
+}
+
+// Reload reads the tools configuration at configPath and applies it. A
+// missing or malformed file, or one with an invalid pipeline, leaves the
+// registered tools untouched.
+func (tm *ToolManager) Reload(configPath string) (*ReloadResult, error) {
+	config, err := ReadToolsConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload tools config: %w", err)
+	}
+	return tm.Apply(config), nil
+}
+
+// WatchConfig polls the tools configuration at configPath every interval
+// and reloads it when its modification time or size changes, calling
+// onReload with the outcome of each reload. It returns when ctx is
+// cancelled.
+func (tm *ToolManager) WatchConfig(ctx context.Context, configPath string, interval time.Duration, onReload func(*ReloadResult, error)) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	last, _ := os.Stat(configPath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(configPath)
+			if err != nil {
+				// The file may be replaced by an editor; wait for it to reappear
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			result, err := tm.Reload(configPath)
+			if onReload != nil {
+				onReload(result, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeToolsConfig(t *testing.T, path string, tools ...ToolConfig) {
+	t.Helper()
+	data, err := json.Marshal(ToolsConfig{Tools: tools})
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "tools.json")
+	tm := NewToolManager()
+	tm.SetBaseDir(dir)
+	tm.RegisterTool(ToolConfig{Name: "keep", Command: "echo"})
+	tm.RegisterTool(ToolConfig{Name: "change", Command: "echo", Args: []string{"old"}})
+	tm.RegisterTool(ToolConfig{Name: "drop", Command: "echo"})
+
+	writeToolsConfig(t, configPath,
+		ToolConfig{Name: "keep", Command: "echo"},
+		ToolConfig{Name: "change", Command: "echo", Args: []string{"new"}},
+		ToolConfig{Name: "add", Command: "echo"},
+	)
+	result, err := tm.Reload(configPath)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	want := &ReloadResult{
+		Added:     []string{"add"},
+		Updated:   []string{"change"},
+		Removed:   []string{"drop"},
+		Unchanged: []string{"keep"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+	if _, ok := tm.GetTool("drop"); ok {
+		t.Error("Expected removed tool to be unregistered")
+	}
+	tool, ok := tm.GetTool("change")
+	if !ok {
+		t.Fatal("Expected changed tool to stay registered")
+	}
+	if output, err := tool.Execute(context.Background(), ""); err != nil || output != "new\n" {
+		t.Errorf("Expected the updated configuration to run, got %q (%v)", output, err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := tm.Reload(configPath); err == nil {
+		t.Error("Expected error for a malformed config")
+	}
+	if _, ok := tm.GetTool("add"); !ok {
+		t.Error("Expected a failed reload to keep the registered tools")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "tools.json")
+	writeToolsConfig(t, configPath, ToolConfig{Name: "first", Command: "echo"})
+
+	tm := NewToolManager()
+	tm.RegisterTool(ToolConfig{Name: "first", Command: "echo"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan *ReloadResult, 1)
+	go tm.WatchConfig(ctx, configPath, 10*time.Millisecond, func(result *ReloadResult, err error) {
+		if err != nil {
+			t.Errorf("Reload failed: %v", err)
+			return
+		}
+		reloads <- result
+	})
+
+	// Give the watcher time to record the initial state, and make sure the
+	// rewrite changes the size even on coarse modification times
+	time.Sleep(50 * time.Millisecond)
+	writeToolsConfig(t, configPath, ToolConfig{Name: "second", Command: "echo"})
+
+	select {
+	case result := <-reloads:
+		if !reflect.DeepEqual(result.Added, []string{"second"}) || !reflect.DeepEqual(result.Removed, []string{"first"}) {
+			t.Errorf("Expected first to be replaced by second, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the watcher to reload the changed config")
+	}
+}
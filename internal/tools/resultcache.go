@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// maxCachedResults bounds the results a tool keeps; the entry closest to
+// expiring is dropped to make room
+const maxCachedResults = 256
+
+// resultCache keeps the successful outputs of a tool by input hash until
+// they expire
+type resultCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]cachedResult
+}
+
+// cachedResult is the output of one call
+type cachedResult struct {
+	output  string
+	expires time.Time
+}
+
+// newResultCache returns a cache keeping results for ttl, or nil when ttl
+// is not positive
+func newResultCache(ttl time.Duration) *resultCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &resultCache{ttl: ttl, entries: make(map[[sha256.Size]byte]cachedResult)}
+}
+
+// get returns the unexpired output cached for input. A nil cache holds
+// nothing.
+func (c *resultCache) get(input string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	key := sha256.Sum256([]byte(input))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.output, true
+}
+
+// put caches the output for input. A nil cache discards it.
+func (c *resultCache) put(input, output string) {
+	if c == nil {
+		return
+	}
+	key := sha256.Sum256([]byte(input))
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCachedResults {
+		var oldest [sha256.Size]byte
+		var oldestExpires time.Time
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			} else if oldestExpires.IsZero() || entry.expires.Before(oldestExpires) {
+				oldest, oldestExpires = k, entry.expires
+			}
+		}
+		if len(c.entries) >= maxCachedResults {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedResult{output: output, expires: now.Add(c.ttl)}
+}
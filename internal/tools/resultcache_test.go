@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedExecute(t *testing.T) {
+	// The tool counts its runs in a file
+	tool := NewTool(ToolConfig{
+		Name:     "counter",
+		Command:  "sh",
+		Args:     []string{"-c", "echo run >> runs && wc -l < runs"},
+		WorkDir:  t.TempDir(),
+		CacheTTL: 60,
+	})
+	run := func(input string) string {
+		t.Helper()
+		output, err := tool.Execute(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return strings.TrimSpace(output)
+	}
+
+	if got := run("query"); got != "1" {
+		t.Errorf("Expected the first run, got %q", got)
+	}
+	if got := run("query"); got != "1" {
+		t.Errorf("Expected the cached output of the first run, got %q", got)
+	}
+	if got := run("other query"); got != "2" {
+		t.Errorf("Expected another input to run the tool, got %q", got)
+	}
+
+	// Without a TTL every call runs
+	tool = NewTool(ToolConfig{Name: "counter", Command: "sh", Args: tool.config.Args, WorkDir: tool.config.WorkDir})
+	if got := run("query"); got != "3" {
+		t.Errorf("Expected an uncached run, got %q", got)
+	}
+
+	// Failures are not cached
+	tool = NewTool(ToolConfig{Name: "fail", Command: "sh", Args: []string{"-c", "echo run >> runs; exit 1"}, WorkDir: tool.config.WorkDir, CacheTTL: 60})
+	for i := 0; i < 2; i++ {
+		if _, err := tool.Execute(context.Background(), "query"); err == nil {
+			t.Error("Expected the failing tool to fail every time")
+		}
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	if newResultCache(0) != nil {
+		t.Error("Expected no cache without a TTL")
+	}
+
+	cache := newResultCache(50 * time.Millisecond)
+	cache.put("input", "output")
+	if output, ok := cache.get("input"); !ok || output != "output" {
+		t.Errorf("Expected the cached output, got %q, %v", output, ok)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := cache.get("input"); ok {
+		t.Error("Expected the output to expire")
+	}
+
+	cache = newResultCache(time.Minute)
+	for i := 0; i <= maxCachedResults; i++ {
+		cache.put(fmt.Sprint(i), "output")
+	}
+	if len(cache.entries) != maxCachedResults {
+		t.Errorf("Expected at most %d results, got %d", maxCachedResults, len(cache.entries))
+	}
+	if _, ok := cache.get(fmt.Sprint(maxCachedResults)); !ok {
+		t.Error("Expected the newest result to be kept")
+	}
+}
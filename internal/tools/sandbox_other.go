@@ -0,0 +1,15 @@
+//go:build !linux
+
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// isolateNetwork is not supported outside Linux; tools asking for it fail
+// rather than run with network access
+func isolateNetwork(cmd *exec.Cmd) error {
+	return fmt.Errorf("no_network sandboxing is not supported on %s", runtime.GOOS)
+}
@@ -1,30 +1,67 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/TFMV/scope/internal/tracing"
 )
 
 // Tool represents a single tool that can be executed
 type Tool struct {
-	config ToolConfig
-	mu     sync.Mutex
+	config  ToolConfig
+	slots   chan struct{} // One entry per running call
+	limiter *rateLimiter
+	results *resultCache // Nil unless CacheTTL is set
 }
 
 // NewTool creates a new tool instance
 func NewTool(config ToolConfig) *Tool {
+	concurrency := config.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
 	return &Tool{
-		config: config,
+		config:  config,
+		slots:   make(chan struct{}, concurrency),
+		limiter: newRateLimiter(config.RateLimit, rateWindow),
+		results: newResultCache(time.Duration(config.CacheTTL) * time.Second),
 	}
 }
 
-// Execute runs the tool with the given input
+// Execute runs the tool with the given input on its standard input. Calls
+// beyond the tool's concurrency or rate limit queue until they may start;
+// the timeout only covers the run itself. With a CacheTTL, a successful
+// output is reused for calls with the same input until it expires, without
+// queueing.
 func (t *Tool) Execute(ctx context.Context, input string) (string, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	ctx, span := tracing.Start(ctx, "exec "+t.config.Name, tracing.KindClient,
+		tracing.String("scope.tool", t.config.Name), tracing.String("process.command", t.config.Command))
+	defer span.End()
+
+	if output, ok := t.results.get(input); ok {
+		span.SetAttributes(tracing.Bool("scope.cached", true))
+		return output, nil
+	}
+	output, err := t.run(ctx, input)
+	span.SetError(err)
+	return output, err
+}
+
+// run runs the tool's command once a slot is free, caching its output
+func (t *Tool) run(ctx context.Context, input string) (string, error) {
+	release, err := t.acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("tool %s not started: %w", t.config.Name, err)
+	}
+	defer release()
 
 	// Set timeout if specified
 	if t.config.Timeout > 0 {
@@ -35,19 +72,99 @@ func (t *Tool) Execute(ctx context.Context, input string) (string, error) {
 
 	// Create command with context
 	cmd := exec.CommandContext(ctx, t.config.Command, t.config.Args...)
-
-	// Set environment variables
-	for k, v := range t.config.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	cmd.Dir = t.config.WorkDir
+	cmd.Env = t.environ()
+	cmd.Stdin = strings.NewReader(input)
+	if t.config.NoNetwork {
+		if err := isolateNetwork(cmd); err != nil {
+			return "", err
+		}
 	}
 
 	// Execute command
-	output, err := cmd.CombinedOutput()
-	if err != nil {
+	output := &limitedBuffer{limit: t.config.MaxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("tool execution failed: %v", err)
 	}
 
-	return string(output), nil
+	result := output.String()
+	if output.truncated > 0 {
+		result = fmt.Sprintf("%s\n[output truncated: %d bytes omitted]\n", result, output.truncated)
+	}
+	t.results.put(input, result)
+	return result, nil
+}
+
+// defaultInheritEnv lists the server variables every tool inherits, so
+// commands are found and tools reading files under the home directory work
+// without an inherit_env entry
+var defaultInheritEnv = []string{"PATH", "HOME"}
+
+// environ builds the tool's environment: the allowed server variables
+// followed by the configured ones. It is never nil, since exec.Cmd gives a
+// nil Env the whole server environment.
+func (t *Tool) environ() []string {
+	env := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := t.config.Env[name]; overridden {
+			continue
+		}
+		if inherits(defaultInheritEnv, name) || inherits(t.config.InheritEnv, name) {
+			env = append(env, kv)
+		}
+	}
+	for k, v := range t.config.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// inherits reports whether the allowlist admits the variable name
+func inherits(allowlist []string, name string) bool {
+	for _, pattern := range allowlist {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBuffer keeps the first limit bytes written to it and counts the
+// rest. A zero limit keeps everything.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated int
+}
+
+// Write implements io.Writer. It never fails, so the tool is not killed by a
+// broken pipe when its output is truncated.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buf.Write(p)
+	}
+	n := len(p)
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		if room < 0 {
+			room = 0
+		}
+		b.truncated += len(p) - room
+		p = p[:room]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// String returns the kept output
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
 }
 
 // GetName returns the tool's name
@@ -62,22 +179,53 @@ func (t *Tool) GetDescription() string {
 
 // ToolManager manages all available tools
 type ToolManager struct {
-	tools map[string]*Tool
-	mu    sync.RWMutex
+	tools     map[string]*Tool
+	pipelines map[string]PipelineConfig
+	baseDir   string
+	mu        sync.RWMutex
 }
 
 // NewToolManager creates a new tool manager
 func NewToolManager() *ToolManager {
 	return &ToolManager{
-		tools: make(map[string]*Tool),
+		tools:     make(map[string]*Tool),
+		pipelines: make(map[string]PipelineConfig),
 	}
 }
 
-// RegisterTool registers a new tool
+// SetBaseDir sets the directory tools run in by default and against which
+// relative WorkDir settings are resolved. It applies to tools registered
+// afterwards.
+func (tm *ToolManager) SetBaseDir(dir string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.baseDir = dir
+}
+
+// RegisterTool registers a new tool, replacing any tool of the same name
 func (tm *ToolManager) RegisterTool(config ToolConfig) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
-	tm.tools[config.Name] = NewTool(config)
+	tm.tools[config.Name] = NewTool(tm.resolve(config))
+}
+
+// UnregisterTool removes a tool and reports whether it was registered.
+// Executions already running finish normally.
+func (tm *ToolManager) UnregisterTool(name string) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	_, ok := tm.tools[name]
+	delete(tm.tools, name)
+	return ok
+}
+
+// resolve applies the base directory to a tool configuration; callers hold
+// the lock
+func (tm *ToolManager) resolve(config ToolConfig) ToolConfig {
+	if tm.baseDir != "" && !filepath.IsAbs(config.WorkDir) {
+		config.WorkDir = filepath.Join(tm.baseDir, config.WorkDir)
+	}
+	return config
 }
 
 // GetTool returns a tool by name
@@ -2,6 +2,10 @@ package tools
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -161,3 +165,118 @@ func TestToolWithInvalidCommand(t *testing.T) {
 		t.Error("Expected error for invalid command, got nil")
 	}
 }
+
+func TestToolWorkDir(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(baseDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	tm := NewToolManager()
+	tm.SetBaseDir(baseDir)
+	tm.RegisterTool(ToolConfig{Name: "default_dir", Command: "pwd"})
+	tm.RegisterTool(ToolConfig{Name: "relative_dir", Command: "pwd", WorkDir: "sub"})
+
+	for name, want := range map[string]string{
+		"default_dir":  baseDir,
+		"relative_dir": filepath.Join(baseDir, "sub"),
+	} {
+		tool, _ := tm.GetTool(name)
+		output, err := tool.Execute(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Execute %s failed: %v", name, err)
+		}
+		if strings.TrimSpace(output) != want {
+			t.Errorf("Expected %s to run in %s, got %s", name, want, output)
+		}
+	}
+}
+
+func TestToolInheritEnv(t *testing.T) {
+	t.Setenv("SCOPE_ALLOWED", "yes")
+	t.Setenv("SCOPE_PREFIX_A", "a")
+	t.Setenv("SCOPE_SECRET", "no")
+
+	tool := NewTool(ToolConfig{
+		Name:       "env_inherit",
+		Command:    "sh",
+		Args:       []string{"-c", "echo $SCOPE_ALLOWED $SCOPE_PREFIX_A $SCOPE_SECRET $SCOPE_OVERRIDE"},
+		Env:        map[string]string{"SCOPE_OVERRIDE": "set"},
+		InheritEnv: []string{"SCOPE_ALLOWED", "SCOPE_PREFIX_*"},
+	})
+	output, err := tool.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if output != "yes a set\n" {
+		t.Errorf("Expected 'yes a set', got %q", output)
+	}
+
+	// Without env or inherit_env, only PATH and HOME are inherited
+	t.Setenv("HOME", "/home/scope")
+	t.Setenv("PATH", "/usr/bin:/bin")
+	bare := NewTool(ToolConfig{
+		Name:    "env_bare",
+		Command: "sh",
+		Args:    []string{"-c", "echo \"[$SCOPE_SECRET] $HOME $PATH\""},
+	})
+	output, err = bare.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if output != "[] /home/scope /usr/bin:/bin\n" {
+		t.Errorf("Expected only PATH and HOME, got %q", output)
+	}
+}
+
+func TestToolMaxOutput(t *testing.T) {
+	tool := NewTool(ToolConfig{
+		Name:      "long_output",
+		Command:   "sh",
+		Args:      []string{"-c", "printf 0123456789"},
+		MaxOutput: 4,
+	})
+	output, err := tool.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.HasPrefix(output, "0123\n") || !strings.Contains(output, "6 bytes omitted") {
+		t.Errorf("Expected truncated output, got %q", output)
+	}
+}
+
+func TestToolNoNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network sandboxing requires Linux")
+	}
+	tool := NewTool(ToolConfig{
+		Name:      "sandboxed",
+		Command:   "cat",
+		Args:      []string{"/proc/net/dev"},
+		NoNetwork: true,
+	})
+	output, err := tool.Execute(context.Background(), "")
+	if err != nil {
+		t.Skipf("User namespaces unavailable: %v", err)
+	}
+	// Only the loopback interface exists in a new network namespace
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], "lo:") {
+		t.Errorf("Expected only the loopback interface, got:\n%s", output)
+	}
+}
+
+func TestUnregisterTool(t *testing.T) {
+	tm := NewToolManager()
+	tm.RegisterTool(ToolConfig{Name: "echo", Command: "echo"})
+
+	if !tm.UnregisterTool("echo") {
+		t.Error("Expected echo to be unregistered")
+	}
+	if _, ok := tm.GetTool("echo"); ok {
+		t.Error("Expected echo to be gone after unregistering")
+	}
+	if tm.UnregisterTool("echo") {
+		t.Error("Expected unregistering a missing tool to report false")
+	}
+}
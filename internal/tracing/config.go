@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures the export of spans
+type Config struct {
+	// Endpoint is the URL spans are posted to, such as
+	// http://localhost:4318/v1/traces
+	Endpoint string
+	// Headers are sent with every export, such as an API key
+	Headers map[string]string
+	// Timeout bounds each export request
+	Timeout time.Duration
+	// Resource describes Scope to the collector; service.name is always set
+	Resource map[string]string
+	// SampleRatio is the fraction of traces recorded, from 0 to 1
+	SampleRatio float64
+	// Interval is the longest spans wait before they are exported
+	Interval time.Duration
+	// BatchSize is the most spans sent in one request; a full batch is
+	// exported without waiting for Interval
+	BatchSize int
+	// QueueSize is the most spans waiting for export; spans ending while
+	// the queue is full are dropped
+	QueueSize int
+	// OnError is called with export failures; nil ignores them
+	OnError func(error)
+}
+
+// DefaultServiceName is the service.name of spans unless OTEL_SERVICE_NAME
+// or OTEL_RESOURCE_ATTRIBUTES set another
+const DefaultServiceName = "scope"
+
+// ConfigFromEnv reads the configuration from the OpenTelemetry environment
+// variables. Tracing is enabled by OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, used
+// as is, or OTEL_EXPORTER_OTLP_ENDPOINT, to which /v1/traces is appended; it
+// returns nil when neither is set, or when OTEL_SDK_DISABLED is true or
+// OTEL_TRACES_EXPORTER is none. Only the http/json protocol is supported.
+func ConfigFromEnv() (*Config, error) {
+	return configFrom(os.Getenv)
+}
+
+// configFrom implements ConfigFromEnv with getenv looking up variables
+func configFrom(getenv func(string) string) (*Config, error) {
+	if strings.EqualFold(getenv("OTEL_SDK_DISABLED"), "true") {
+		return nil, nil
+	}
+	switch exporter := getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "otlp":
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q; only otlp is supported", exporter)
+	}
+
+	config := &Config{
+		Endpoint:    getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		Headers:     make(map[string]string),
+		Timeout:     10 * time.Second,
+		Resource:    make(map[string]string),
+		SampleRatio: 1,
+		Interval:    5 * time.Second,
+		BatchSize:   512,
+		QueueSize:   2048,
+	}
+	if config.Endpoint == "" {
+		base := getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil, nil
+		}
+		config.Endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if _, err := url.ParseRequestURI(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+
+	protocol := first(getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"), getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	if protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q; only http/json is supported", protocol)
+	}
+
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		if err := parsePairs(getenv(name), config.Headers); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	if err := parsePairs(getenv("OTEL_RESOURCE_ATTRIBUTES"), config.Resource); err != nil {
+		return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+	if name := getenv("OTEL_SERVICE_NAME"); name != "" {
+		config.Resource["service.name"] = name
+	}
+	if config.Resource["service.name"] == "" {
+		config.Resource["service.name"] = DefaultServiceName
+	}
+
+	var err error
+	timeout := first(getenv("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT"), getenv("OTEL_EXPORTER_OTLP_TIMEOUT"))
+	if config.Timeout, err = millis("OTLP timeout", timeout, config.Timeout); err != nil {
+		return nil, err
+	}
+	if config.Interval, err = millis("OTEL_BSP_SCHEDULE_DELAY", getenv("OTEL_BSP_SCHEDULE_DELAY"), config.Interval); err != nil {
+		return nil, err
+	}
+	if config.BatchSize, err = positive("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", getenv("OTEL_BSP_MAX_EXPORT_BATCH_SIZE"), config.BatchSize); err != nil {
+		return nil, err
+	}
+	if config.QueueSize, err = positive("OTEL_BSP_MAX_QUEUE_SIZE", getenv("OTEL_BSP_MAX_QUEUE_SIZE"), config.QueueSize); err != nil {
+		return nil, err
+	}
+	config.BatchSize = min(config.BatchSize, config.QueueSize)
+
+	if config.SampleRatio, err = sampleRatio(getenv("OTEL_TRACES_SAMPLER"), getenv("OTEL_TRACES_SAMPLER_ARG")); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// sampleRatio returns the fraction of traces a sampler records. Spans
+// always start in Scope, so the parent-based samplers behave like the
+// samplers they fall back to for root spans.
+func sampleRatio(sampler, arg string) (float64, error) {
+	switch strings.TrimPrefix(sampler, "parentbased_") {
+	case "", "always_on":
+		return 1, nil
+	case "always_off":
+		return 0, nil
+	case "traceidratio":
+		if arg == "" {
+			return 1, nil
+		}
+		ratio, err := strconv.ParseFloat(arg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return 0, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG %q; expected a ratio from 0 to 1", arg)
+		}
+		return ratio, nil
+	}
+	return 0, fmt.Errorf("unsupported OTEL_TRACES_SAMPLER %q", sampler)
+}
+
+// parsePairs adds the comma-separated key=value pairs of s, whose values
+// may be percent-encoded, to pairs
+func parsePairs(s string, pairs map[string]string) error {
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid value of %s: %w", key, err)
+		}
+		pairs[key] = decoded
+	}
+	return nil
+}
+
+// millis parses a duration given in milliseconds, returning def for an
+// empty value
+func millis(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q; expected a positive number of milliseconds", name, value)
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
+// positive parses a positive integer, returning def for an empty value
+func positive(name, value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q; expected a positive number", name, value)
+	}
+	return n, nil
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		for _, vars := range []map[string]string{
+			{},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318", "OTEL_SDK_DISABLED": "true"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318", "OTEL_TRACES_EXPORTER": "none"},
+		} {
+			config, err := configFrom(env(vars))
+			if err != nil || config != nil {
+				t.Errorf("Expected tracing to be disabled for %v, got %+v, %v", vars, config, err)
+			}
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		config, err := configFrom(env(map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318/"}))
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		if config.Endpoint != "http://localhost:4318/v1/traces" {
+			t.Errorf("Expected the traces path appended, got %s", config.Endpoint)
+		}
+		if config.Resource["service.name"] != DefaultServiceName || config.SampleRatio != 1 {
+			t.Errorf("Expected default service name and sampling, got %+v", config)
+		}
+		if config.Timeout != 10*time.Second || config.Interval != 5*time.Second || config.BatchSize != 512 || config.QueueSize != 2048 {
+			t.Errorf("Expected default export settings, got %+v", config)
+		}
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		config, err := configFrom(env(map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://ignored:4318",
+			"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://collector.example.com/traces",
+			"OTEL_EXPORTER_OTLP_PROTOCOL":        "http/json",
+			"OTEL_EXPORTER_OTLP_HEADERS":         "api-key=one,x-team=tools",
+			"OTEL_EXPORTER_OTLP_TRACES_HEADERS":  "api-key=two%20three",
+			"OTEL_EXPORTER_OTLP_TIMEOUT":         "2500",
+			"OTEL_RESOURCE_ATTRIBUTES":           "service.name=from-resource,deployment.environment=dev",
+			"OTEL_SERVICE_NAME":                  "scope-dev",
+			"OTEL_TRACES_SAMPLER":                "parentbased_traceidratio",
+			"OTEL_TRACES_SAMPLER_ARG":            "0.1",
+			"OTEL_BSP_SCHEDULE_DELAY":            "1000",
+			"OTEL_BSP_MAX_EXPORT_BATCH_SIZE":     "100",
+			"OTEL_BSP_MAX_QUEUE_SIZE":            "50",
+		}))
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		if config.Endpoint != "https://collector.example.com/traces" {
+			t.Errorf("Expected the traces endpoint as is, got %s", config.Endpoint)
+		}
+		if config.Headers["api-key"] != "two three" || config.Headers["x-team"] != "tools" {
+			t.Errorf("Expected decoded headers with traces overrides, got %v", config.Headers)
+		}
+		if config.Resource["service.name"] != "scope-dev" || config.Resource["deployment.environment"] != "dev" {
+			t.Errorf("Expected OTEL_SERVICE_NAME to win, got %v", config.Resource)
+		}
+		if config.Timeout != 2500*time.Millisecond || config.Interval != time.Second {
+			t.Errorf("Expected millisecond durations, got %v and %v", config.Timeout, config.Interval)
+		}
+		if config.SampleRatio != 0.1 {
+			t.Errorf("Expected sample ratio 0.1, got %v", config.SampleRatio)
+		}
+		if config.QueueSize != 50 || config.BatchSize != 50 {
+			t.Errorf("Expected the batch size capped by the queue, got %d and %d", config.BatchSize, config.QueueSize)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		base := "http://localhost:4318"
+		for _, vars := range []map[string]string{
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_TRACES_EXPORTER": "zipkin"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_TRACES_SAMPLER": "traceidratio", "OTEL_TRACES_SAMPLER_ARG": "2"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_TRACES_SAMPLER": "jaeger_remote"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_EXPORTER_OTLP_HEADERS": "novalue"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": base, "OTEL_EXPORTER_OTLP_TIMEOUT": "soon"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT": "not a url"},
+		} {
+			if _, err := configFrom(env(vars)); err == nil {
+				t.Errorf("Expected an error for %v", vars)
+			}
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// instrumentationScope names Scope as the source of its spans
+const instrumentationScope = "github.com/TFMV/scope"
+
+// spanRecord is an ended span waiting for export
+type spanRecord struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+	end      time.Time
+	attrs    []Attr
+	err      string
+}
+
+// exporter batches ended spans and posts them to the OTLP endpoint in the
+// background
+type exporter struct {
+	config   Config
+	client   *http.Client
+	resource []keyValue
+
+	mu      sync.Mutex
+	queue   []spanRecord
+	dropped int
+
+	full     chan struct{} // Signals a full batch
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newExporter creates an exporter and starts its background loop
+func newExporter(config Config) *exporter {
+	e := &exporter{
+		config: config,
+		client: &http.Client{},
+		full:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	keys := make([]string, 0, len(config.Resource))
+	for key := range config.Resource {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		e.resource = append(e.resource, attribute(String(key, config.Resource[key])))
+	}
+	go e.run()
+	return e
+}
+
+// enqueue queues a span for export, dropping it when the queue is full
+func (e *exporter) enqueue(record spanRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= e.config.QueueSize {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, record)
+	if len(e.queue) >= e.config.BatchSize {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports queued spans every Interval, or as soon as a batch is full,
+// until shutdown
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.full:
+		case <-e.stop:
+			return
+		}
+		e.flush(context.Background())
+	}
+}
+
+// shutdown stops the background loop and exports what is left
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.flush(ctx)
+}
+
+// flush exports the queued spans in batches, reporting failures to
+// OnError. It returns the first failure.
+func (e *exporter) flush(ctx context.Context) error {
+	var firstErr error
+	report := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+		if e.config.OnError != nil {
+			e.config.OnError(err)
+		}
+	}
+	for {
+		e.mu.Lock()
+		if e.dropped > 0 {
+			report(fmt.Errorf("dropped %d spans: export queue full", e.dropped))
+			e.dropped = 0
+		}
+		n := min(len(e.queue), e.config.BatchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+		if n == 0 {
+			return firstErr
+		}
+		if err := e.export(ctx, batch); err != nil {
+			report(fmt.Errorf("failed to export %d spans: %w", n, err))
+		}
+	}
+}
+
+// export posts a batch of spans to the endpoint
+func (e *exporter) export(ctx context.Context, batch []spanRecord) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// exportRequest is an OTLP ExportTraceServiceRequest in its JSON encoding
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// otlpSpan is a span in the OTLP JSON encoding, which writes IDs in hex and
+// 64-bit integers as strings
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              Kind       `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+// status is the OTLP span status; code 2 is an error
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// request builds the export request of a batch
+func (e *exporter) request(batch []spanRecord) exportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, record := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(record.traceID[:]),
+			SpanID:            hex.EncodeToString(record.spanID[:]),
+			Name:              record.name,
+			Kind:              record.kind,
+			StartTimeUnixNano: strconv.FormatInt(record.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(record.end.UnixNano(), 10),
+		}
+		if record.parentID != ([8]byte{}) {
+			span.ParentSpanID = hex.EncodeToString(record.parentID[:])
+		}
+		for _, attr := range record.attrs {
+			span.Attributes = append(span.Attributes, attribute(attr))
+		}
+		if record.err != "" {
+			span.Status = status{Code: 2, Message: record.err}
+		}
+		spans = append(spans, span)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: e.resource},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: instrumentationScope}, Spans: spans}},
+	}}}
+}
+
+// attribute encodes an attribute; values of other types become strings
+func attribute(attr Attr) keyValue {
+	kv := keyValue{Key: attr.Key}
+	switch v := attr.Value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		kv.Value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &s
+	case float64:
+		kv.Value.DoubleValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	default:
+		s := fmt.Sprint(v)
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
@@ -0,0 +1,212 @@
+// Package tracing records spans for tool calls, analyzer phases and external
+// commands, and exports them to an OpenTelemetry collector as OTLP over
+// HTTP, encoded as JSON. It is configured with the standard OTEL_*
+// environment variables and records nothing until a Tracer is installed
+// with SetDefault.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind is the OTLP span kind: who the traced work was done for
+type Kind int
+
+const (
+	// KindInternal is work within Scope, such as an analyzer phase
+	KindInternal Kind = 1
+	// KindServer is a request Scope serves, such as an MCP tool call
+	KindServer Kind = 2
+	// KindClient is a request Scope makes, such as running an external command
+	KindClient Kind = 3
+)
+
+// Attr is a span attribute. Values are strings, ints, int64s, float64s or
+// bools.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int) Attr {
+	return Attr{Key: key, Value: int64(value)}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attr {
+	return Attr{Key: key, Value: value}
+}
+
+// Span is an operation being traced. A nil Span, returned while tracing is
+// disabled, ignores every call, so callers need not check.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	sampled  bool
+	name     string
+	kind     Kind
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []Attr
+	err   string
+	ended bool
+}
+
+// Tracer creates spans and hands the sampled ones to its exporter when they
+// end
+type Tracer struct {
+	exporter *exporter
+	ratio    float64
+}
+
+// NewTracer creates a Tracer exporting as configured. Its exporter runs
+// until Shutdown.
+func NewTracer(config Config) *Tracer {
+	return &Tracer{exporter: newExporter(config), ratio: config.SampleRatio}
+}
+
+// defaultTracer is the Tracer of Start; nil while tracing is disabled
+var defaultTracer atomic.Pointer[Tracer]
+
+// SetDefault installs the Tracer used by Start. A nil Tracer disables
+// tracing.
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// FromContext returns the span ctx carries, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts a span with the default Tracer, as a child of the span ctx
+// carries, and returns a context carrying the new span. It returns ctx and
+// a nil Span while tracing is disabled.
+func Start(ctx context.Context, name string, kind Kind, attrs ...Attr) (context.Context, *Span) {
+	t := defaultTracer.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.Start(ctx, name, kind, attrs...)
+}
+
+// Start starts a span as a child of the span ctx carries, or as the root of
+// a new trace, which is sampled according to the Tracer's ratio. Children
+// follow the sampling decision of their root.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind, attrs ...Attr) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	binary.BigEndian.PutUint64(span.spanID[:], nonZero())
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = parent.sampled
+	} else {
+		binary.BigEndian.PutUint64(span.traceID[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(span.traceID[8:], nonZero())
+		span.sampled = sampled(span.traceID, t.ratio)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// nonZero returns a random number other than zero, which OTLP reserves for
+// invalid IDs
+func nonZero() uint64 {
+	for {
+		if n := rand.Uint64(); n != 0 {
+			return n
+		}
+	}
+}
+
+// sampled decides from its ID whether a trace is recorded, so that the
+// same fraction of traces is kept whatever their length
+func sampled(traceID [16]byte, ratio float64) bool {
+	switch {
+	case ratio >= 1:
+		return true
+	case ratio <= 0:
+		return false
+	}
+	return binary.BigEndian.Uint64(traceID[8:])>>1 < uint64(ratio*(1<<63))
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span as failed with err. A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span and, when its trace is sampled, queues it for export.
+// Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	record := spanRecord{
+		traceID:  s.traceID,
+		spanID:   s.spanID,
+		parentID: s.parentID,
+		name:     s.name,
+		kind:     s.kind,
+		start:    s.start,
+		end:      end,
+		attrs:    append([]Attr(nil), s.attrs...),
+		err:      s.err,
+	}
+	s.mu.Unlock()
+
+	if s.sampled {
+		s.tracer.exporter.enqueue(record)
+	}
+}
+
+// Shutdown stops the Tracer's exporter after exporting the spans that
+// ended so far, giving up when ctx is done
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.exporter.shutdown(ctx)
+}
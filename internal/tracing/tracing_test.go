@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the export requests it receives
+type collector struct {
+	mu       sync.Mutex
+	requests []exportRequest
+	headers  []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req exportRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header.Clone())
+}
+
+func (c *collector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var spans []otlpSpan
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func testConfig(endpoint string) Config {
+	return Config{
+		Endpoint:    endpoint,
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+		Timeout:     time.Second,
+		Resource:    map[string]string{"service.name": "scope-test"},
+		SampleRatio: 1,
+		Interval:    time.Hour,
+		BatchSize:   10,
+		QueueSize:   100,
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := NewTracer(testConfig(server.URL + "/v1/traces"))
+	ctx, root := tracer.Start(context.Background(), "tool lookup_type", KindServer, String("scope.tool", "lookup_type"))
+	_, child := tracer.Start(ctx, "typecheck", KindInternal)
+	child.SetAttributes(Int("packages", 3), Bool("lazy", true))
+	child.SetError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	typecheck, tool := spans[0], spans[1]
+	if tool.Name != "tool lookup_type" || tool.Kind != KindServer || tool.ParentSpanID != "" {
+		t.Errorf("Expected a root server span, got %+v", tool)
+	}
+	if typecheck.TraceID != tool.TraceID || typecheck.ParentSpanID != tool.SpanID {
+		t.Errorf("Expected typecheck to be a child of the tool span, got %+v", typecheck)
+	}
+	if len(tool.TraceID) != 32 || len(tool.SpanID) != 16 {
+		t.Errorf("Expected hex IDs, got %s and %s", tool.TraceID, tool.SpanID)
+	}
+	if typecheck.Status.Code != 2 || typecheck.Status.Message != "boom" {
+		t.Errorf("Expected an error status, got %+v", typecheck.Status)
+	}
+	if len(typecheck.Attributes) != 2 || typecheck.Attributes[0].Value.IntValue == nil || *typecheck.Attributes[0].Value.IntValue != "3" {
+		t.Errorf("Expected packages=3, got %+v", typecheck.Attributes)
+	}
+	start, _ := strconv.ParseInt(tool.StartTimeUnixNano, 10, 64)
+	end, _ := strconv.ParseInt(tool.EndTimeUnixNano, 10, 64)
+	if start == 0 || end < start {
+		t.Errorf("Expected the span to end after it starts, got %s and %s", tool.StartTimeUnixNano, tool.EndTimeUnixNano)
+	}
+
+	resource := c.requests[0].ResourceSpans[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || *resource[0].Value.StringValue != "scope-test" {
+		t.Errorf("Expected service.name scope-test, got %+v", resource)
+	}
+	if got := c.headers[0].Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Expected the configured header, got %q", got)
+	}
+}
+
+func TestExportBatches(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	config := testConfig(server.URL)
+	config.BatchSize = 2
+	config.QueueSize = 3
+	var mu sync.Mutex
+	var errs []error
+	config.OnError = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	tracer := NewTracer(config)
+	// Stop the background loop so that the queue fills up
+	tracer.exporter.stopOnce.Do(func() { close(tracer.exporter.stop) })
+	<-tracer.exporter.done
+	for range 5 {
+		_, span := tracer.Start(context.Background(), "span", KindInternal)
+		span.End()
+	}
+	if err := tracer.Shutdown(context.Background()); err == nil {
+		t.Errorf("Expected dropped spans to be reported")
+	}
+
+	if len(c.requests) != 2 {
+		t.Errorf("Expected the 3 queued spans in 2 batches, got %d requests", len(c.requests))
+	}
+	if len(c.spans()) != 3 {
+		t.Errorf("Expected 3 spans, got %d", len(c.spans()))
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected one error for the dropped spans, got %v", errs)
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(testConfig(server.URL))
+	_, span := tracer.Start(context.Background(), "span", KindInternal)
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err == nil {
+		t.Errorf("Expected the collector's error")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	config := testConfig(server.URL)
+	config.SampleRatio = 0
+	tracer := NewTracer(config)
+	ctx, root := tracer.Start(context.Background(), "root", KindServer)
+	_, child := tracer.Start(ctx, "child", KindInternal)
+	child.End()
+	root.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	if len(c.requests) != 0 {
+		t.Errorf("Expected nothing exported, got %d requests", len(c.requests))
+	}
+
+	var id [16]byte
+	id[8] = 0x80
+	if sampled(id, 0.25) || !sampled([16]byte{}, 0.25) {
+		t.Errorf("Expected sampling by the low half of the trace ID")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+	SetDefault(nil)
+	got, span := Start(ctx, "span", KindInternal, String("key", "value"))
+	if span != nil || got != ctx {
+		t.Fatalf("Expected no span while tracing is disabled")
+	}
+	// A nil span ignores every call
+	span.SetAttributes(Int("n", 1))
+	span.SetError(errors.New("ignored"))
+	span.End()
+}
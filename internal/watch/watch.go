@@ -0,0 +1,119 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Watcher polls a directory tree for changes to Go source and module files
+type Watcher struct {
+	root     string
+	interval time.Duration
+	exclude  []string
+	state    map[string]fileState
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// New creates a Watcher for root that polls every interval, skipping paths
+// containing any of the exclude patterns
+func New(root string, interval time.Duration, exclude []string) *Watcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &Watcher{
+		root:     root,
+		interval: interval,
+		exclude:  exclude,
+		state:    make(map[string]fileState),
+	}
+}
+
+// Scan walks the tree and returns the files that were added, modified, or
+// removed since the previous scan. The first scan reports every watched file.
+func (w *Watcher) Scan() ([]string, error) {
+	current := make(map[string]fileState)
+	err := filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files can disappear between listing and stat; ignore them
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		for _, pattern := range w.exclude {
+			if strings.Contains(path, pattern) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if info.IsDir() || !isWatched(path) {
+			return nil
+		}
+
+		current[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, st := range current {
+		if prev, ok := w.state[path]; !ok || !prev.modTime.Equal(st.modTime) || prev.size != st.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.state {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	w.state = current
+	return changed, nil
+}
+
+// Run primes the watcher and then calls onChange with the changed files after
+// every poll that detects a change, until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context, onChange func(changed []string)) error {
+	if _, err := w.Scan(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			changed, err := w.Scan()
+			if err != nil {
+				return err
+			}
+			if len(changed) > 0 {
+				onChange(changed)
+			}
+		}
+	}
+}
+
+// isWatched reports whether a file affects builds
+func isWatched(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, ".go") || base == "go.mod" || base == "go.sum"
+}
@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherScan(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scope-watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	goFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(goFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, "vendor"), 0755); err != nil {
+		t.Fatalf("Failed to create vendor dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "vendor", "dep.go"), []byte("package dep\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	w := New(tempDir, time.Millisecond, []string{"vendor"})
+
+	// The first scan reports every watched file
+	changed, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != goFile {
+		t.Errorf("Expected only %s on first scan, got %v", goFile, changed)
+	}
+
+	// No changes
+	changed, err = w.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Expected no changes, got %v", changed)
+	}
+
+	// Modify, add, and remove files
+	if err := os.WriteFile(goFile, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	added := filepath.Join(tempDir, "util.go")
+	if err := os.WriteFile(added, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	changed, err = w.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("Expected 2 changes, got %v", changed)
+	}
+
+	if err := os.Remove(added); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	changed, err = w.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != added {
+		t.Errorf("Expected removal of %s, got %v", added, changed)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scope-watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	w := New(tempDir, 5*time.Millisecond, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := make(chan []string, 1)
+	go w.Run(ctx, func(changed []string) {
+		select {
+		case changes <- changed:
+		default:
+		}
+	})
+
+	// Give the watcher time to prime before creating a file
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(tempDir, "new.go"), []byte("package p\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	select {
+	case changed := <-changes:
+		if len(changed) != 1 {
+			t.Errorf("Expected 1 changed file, got %v", changed)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for change notification")
+	}
+}